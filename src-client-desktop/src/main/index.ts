@@ -1,4 +1,4 @@
-import { join } from "node:path"
+import { join, resolve } from "node:path"
 import { electronApp, is, optimizer } from "@electron-toolkit/utils"
 import {
   app,
@@ -118,6 +118,24 @@ const store = new Store<StoreSchema>({
 let mainWindow: BrowserWindow | null = null
 let tray: Tray | null = null
 
+// Deep links (lobby://magic-link?server=...&token=...) opened from a sign-in
+// email. The latest one waits here until the renderer takes it.
+const DEEP_LINK_SCHEME = "lobby"
+let pendingDeepLink: string | null = null
+
+function findDeepLink(argv: string[]): string | undefined {
+  return argv.find((arg) => arg.startsWith(`${DEEP_LINK_SCHEME}://`))
+}
+
+function handleDeepLink(url: string): void {
+  pendingDeepLink = url
+  if (mainWindow) {
+    mainWindow.show()
+    mainWindow.focus()
+    mainWindow.webContents.send("deeplink:open")
+  }
+}
+
 function getValidBounds(): WindowBounds | null {
   const saved = store.get("windowBounds")
   if (!saved) return null
@@ -213,16 +231,34 @@ const gotTheLock = ALLOW_MULTIPLE || app.requestSingleInstanceLock()
 if (!gotTheLock) {
   app.quit()
 } else {
-  app.on("second-instance", () => {
+  app.on("second-instance", (_event, argv) => {
     if (mainWindow) {
       if (mainWindow.isMinimized()) {
         mainWindow.restore()
       }
       mainWindow.focus()
     }
+    const deepLink = findDeepLink(argv)
+    if (deepLink) handleDeepLink(deepLink)
   })
 }
 
+// Separate dev instances would fight over the scheme, so only the default one registers
+if (!INSTANCE_ID) {
+  if (process.defaultApp && process.argv.length >= 2) {
+    app.setAsDefaultProtocolClient(DEEP_LINK_SCHEME, process.execPath, [resolve(process.argv[1])])
+  } else {
+    app.setAsDefaultProtocolClient(DEEP_LINK_SCHEME)
+  }
+}
+
+// macOS delivers deep links as open-url, also when they launch the app
+app.on("open-url", (event, url) => {
+  event.preventDefault()
+  handleDeepLink(url)
+})
+pendingDeepLink = findDeepLink(process.argv) ?? null
+
 app.whenReady().then(() => {
   electronApp.setAppUserModelId(INSTANCE_ID ? `com.lobby.instance-${INSTANCE_ID}` : "com.lobby")
 
@@ -321,6 +357,12 @@ app.whenReady().then(() => {
     }
   })
 
+  ipcMain.handle("deeplink:take-pending", () => {
+    const url = pendingDeepLink
+    pendingDeepLink = null
+    return url
+  })
+
   ipcMain.handle("theme:set-native", (_event, mode: "light" | "dark") => {
     nativeTheme.themeSource = mode
   })
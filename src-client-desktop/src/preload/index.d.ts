@@ -27,6 +27,12 @@ interface LobbyAPI {
     remove: (id: string) => Promise<{ success: boolean }>
   }
 
+  // Deep links opened from outside the app, such as sign-in email links
+  deepLink: {
+    takePending: () => Promise<string | null>
+    onOpen: (callback: () => void) => () => void
+  }
+
   // Theme (native window decorations)
   theme: {
     setNativeMode: (mode: ThemeMode) => Promise<void>
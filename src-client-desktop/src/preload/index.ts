@@ -35,6 +35,16 @@ const api = {
       ipcRenderer.invoke("storage:servers:remove", { id })
   },
 
+  // Deep links opened from outside the app, such as sign-in email links
+  deepLink: {
+    takePending: (): Promise<string | null> => ipcRenderer.invoke("deeplink:take-pending"),
+    onOpen: (callback: () => void) => {
+      const handler = () => callback()
+      ipcRenderer.on("deeplink:open", handler)
+      return () => ipcRenderer.removeListener("deeplink:open", handler)
+    }
+  },
+
   // Theme (native window decorations)
   theme: {
     setNativeMode: (mode: ThemeMode): Promise<void> => ipcRenderer.invoke("theme:set-native", mode)
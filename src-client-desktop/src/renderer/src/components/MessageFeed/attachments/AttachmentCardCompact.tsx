@@ -1,4 +1,4 @@
-import type { Component } from "solid-js"
+import { type Component, Show } from "solid-js"
 import type { MessageAttachment } from "../../../../../shared/types"
 import { formatBytes } from "../../../lib/files"
 import { type AttachmentViewerKind, getAttachmentKindLabel } from "./attachmentKinds"
@@ -39,6 +39,16 @@ const AttachmentCardCompact: Component<AttachmentCardCompactProps> = (props) =>
           </div>
         </div>
       </div>
+      <Show when={props.attachment.previewText}>
+        {(text) => (
+          <pre
+            data-language={props.attachment.previewLanguage}
+            class="mt-2 max-h-48 overflow-hidden whitespace-pre-wrap break-all rounded border border-border bg-black/30 px-2 py-1.5 font-mono text-xs text-text-secondary"
+          >
+            {text()}
+          </pre>
+        )}
+      </Show>
     </button>
   )
 }
@@ -49,6 +49,11 @@ const AttachmentCardMedia: Component<AttachmentCardMediaProps> = (props) => {
         <div class="absolute right-2 top-2 rounded border border-white/25 bg-black/55 px-2 py-0.5 text-[10px] font-semibold tracking-wide text-white/90">
           {label()}
         </div>
+        <Show when={(props.attachment.previewFrameCount ?? 0) > 1}>
+          <div class="absolute left-2 top-2 rounded border border-white/25 bg-black/55 px-2 py-0.5 text-[10px] font-semibold tracking-wide text-white/90">
+            ANIMATED
+          </div>
+        </Show>
 
         <Show when={props.viewerKind === "video"}>
           <div class="absolute inset-0 flex items-center justify-center">
@@ -42,6 +42,8 @@ const ServerSettings: Component = () => {
       if (error instanceof ApiError && error.code === "PAYLOAD_TOO_LARGE") {
         const maxBytes = getUploadMaxBytes()
         setServerImageError(formatUploadTooLargeMessage(maxBytes, "Image"))
+      } else if (error instanceof ApiError && error.code === "SERVER_MANAGE_FORBIDDEN") {
+        setServerImageError("Only admins can change the server image")
       } else {
         setServerImageError("Failed to upload server image")
       }
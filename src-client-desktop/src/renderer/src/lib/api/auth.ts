@@ -1,8 +1,12 @@
 import type { User } from "../../../../shared/types"
 import { apiRequest, normalizeUrl, publicRequest } from "./client"
 import type {
+  AccountDeletion,
   APIError,
   AuthResponse,
+  InvitePreview,
+  MagicCodeChallenge,
+  MagicCodeRequest,
   RefreshResponse,
   ServerInfo,
   UpdateUserRequest,
@@ -10,28 +14,86 @@ import type {
 } from "./types"
 import { ApiError } from "./types"
 
-// Get server info (public endpoint)
-export async function getServerInfo(serverUrl: string): Promise<ServerInfo> {
-  return publicRequest<ServerInfo>(serverUrl, "/api/v1/server/info")
+// Get server info (public endpoint). With an access token, uploadMaxBytes is
+// the signed-in user's role limit.
+export async function getServerInfo(
+  serverUrl: string,
+  accessToken?: string | null
+): Promise<ServerInfo> {
+  return publicRequest<ServerInfo>(serverUrl, "/api/v1/server/info", accessToken)
+}
+
+// Resolve an invite code to a server preview (public endpoint)
+export async function getInvitePreview(serverUrl: string, code: string): Promise<InvitePreview> {
+  return publicRequest<InvitePreview>(serverUrl, `/api/v1/invites/${encodeURIComponent(code)}`)
 }
 
-// Request a magic code to be sent to email
+// Request a magic code to be sent to email, solving the server's
+// proof-of-work challenge first when it asks for one
 export async function requestMagicCode(serverUrl: string, email: string): Promise<void> {
+  const body: MagicCodeRequest = { email }
+  const challenge = await publicRequest<MagicCodeChallenge>(
+    serverUrl,
+    "/api/v1/auth/login/challenge"
+  )
+  if (challenge.required && challenge.challenge) {
+    // The server normalizes the address before checking the solution
+    const normalized = email.trim().toLowerCase()
+    body.challenge = challenge.challenge
+    body.nonce = await solveChallenge(challenge.challenge, normalized, challenge.bits ?? 0)
+  }
   await apiRequest<void>(serverUrl, "/api/v1/auth/login/magic-code", {
     method: "POST",
-    body: { email }
+    body
   })
 }
 
+async function solveChallenge(challenge: string, email: string, bits: number): Promise<string> {
+  const encoder = new TextEncoder()
+  for (let n = 0; ; n++) {
+    const nonce = String(n)
+    const digest = new Uint8Array(
+      await crypto.subtle.digest("SHA-256", encoder.encode(`${challenge}:${email}:${nonce}`))
+    )
+    if (leadingZeroBits(digest) >= bits) {
+      return nonce
+    }
+  }
+}
+
+function leadingZeroBits(digest: Uint8Array): number {
+  let count = 0
+  for (const byte of digest) {
+    if (byte !== 0) {
+      return count + Math.clz32(byte) - 24
+    }
+    count += 8
+  }
+  return count
+}
+
 // Verify magic code and get auth tokens
 export async function verifyMagicCode(
   serverUrl: string,
   email: string,
-  code: string
+  code: string,
+  inviteCode?: string
 ): Promise<VerifyMagicCodeResponse> {
   return apiRequest<VerifyMagicCodeResponse>(serverUrl, "/api/v1/auth/login/magic-code/verify", {
     method: "POST",
-    body: { email, code }
+    body: { email, code, inviteCode }
+  })
+}
+
+// Verify a signed magic link token from the login email
+export async function verifyMagicLink(
+  serverUrl: string,
+  token: string,
+  inviteCode?: string
+): Promise<VerifyMagicCodeResponse> {
+  return apiRequest<VerifyMagicCodeResponse>(serverUrl, "/api/v1/auth/login/magic-link", {
+    method: "POST",
+    body: { token, inviteCode }
   })
 }
 
@@ -114,3 +176,11 @@ export async function leaveServer(serverUrl: string): Promise<void> {
     method: "DELETE"
   })
 }
+
+// Leave server and schedule the account's data for deletion. Signing in
+// again before deleteAfter restores the account.
+export async function deleteAccount(serverUrl: string): Promise<AccountDeletion> {
+  return apiRequest<AccountDeletion>(serverUrl, "/api/v1/users/me/deletion", {
+    method: "POST"
+  })
+}
@@ -0,0 +1,8 @@
+import { apiRequestCurrentServer } from "./client"
+import type { BootstrapResponse } from "./types"
+
+// Everything the first render needs in one request; replaces the separate
+// server info, profile, channel, drafts, mutes, and history calls
+export async function getBootstrap(): Promise<BootstrapResponse> {
+  return apiRequestCurrentServer<BootstrapResponse>("/api/v1/bootstrap")
+}
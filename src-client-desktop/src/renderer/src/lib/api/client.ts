@@ -79,11 +79,19 @@ export async function apiRequestMultipartCurrentServer<T>(
 /**
  * Make an unauthenticated request (for public endpoints like server info)
  */
-export async function publicRequest<T>(serverUrl: string, endpoint: string): Promise<T> {
+export async function publicRequest<T>(
+  serverUrl: string,
+  endpoint: string,
+  accessToken?: string | null
+): Promise<T> {
   const url = `${normalizeUrl(serverUrl)}${endpoint}`
+  const headers: Record<string, string> = { "Content-Type": "application/json" }
+  if (accessToken) {
+    headers.Authorization = `Bearer ${accessToken}`
+  }
   const response = await fetch(url, {
     cache: "no-store",
-    headers: { "Content-Type": "application/json" }
+    headers
   })
   return handleResponse<T>(response)
 }
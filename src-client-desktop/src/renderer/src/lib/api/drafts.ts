@@ -0,0 +1,14 @@
+import { apiRequestCurrentServer } from "./client"
+import type { MessageDraft } from "./types"
+
+export async function listDrafts(): Promise<MessageDraft[]> {
+  return apiRequestCurrentServer<MessageDraft[]>("/api/v1/drafts")
+}
+
+// Empty content clears the draft; other sessions receive DRAFT_UPDATE
+export async function saveDraft(channelId: number, content: string): Promise<MessageDraft> {
+  return apiRequestCurrentServer<MessageDraft>(`/api/v1/drafts/${channelId}`, {
+    method: "PUT",
+    body: { content }
+  })
+}
@@ -0,0 +1,21 @@
+import { apiRequestCurrentServer } from "./client"
+import type { EmailChange, EmailChangeAddress } from "./types"
+
+// Sends a confirmation code to both the current and the new address
+export async function requestEmailChange(newEmail: string): Promise<EmailChange> {
+  return apiRequestCurrentServer<EmailChange>("/api/v1/users/me/email", {
+    method: "POST",
+    body: { newEmail }
+  })
+}
+
+// The email changes, and other devices are signed out, once both codes are confirmed
+export async function confirmEmailChange(
+  address: EmailChangeAddress,
+  code: string
+): Promise<EmailChange> {
+  return apiRequestCurrentServer<EmailChange>("/api/v1/users/me/email/confirm", {
+    method: "POST",
+    body: { address, code }
+  })
+}
@@ -0,0 +1,20 @@
+import { apiRequestCurrentServer } from "./client"
+import type { UserMute } from "./types"
+
+export async function listMutes(): Promise<UserMute[]> {
+  return apiRequestCurrentServer<UserMute[]>("/api/v1/users/me/mutes")
+}
+
+// The server stops sending the user's messages and typing to this account;
+// history requests still include their messages
+export async function muteUser(userId: string): Promise<void> {
+  await apiRequestCurrentServer<void>(`/api/v1/users/me/mutes/${encodeURIComponent(userId)}`, {
+    method: "PUT"
+  })
+}
+
+export async function unmuteUser(userId: string): Promise<void> {
+  await apiRequestCurrentServer<void>(`/api/v1/users/me/mutes/${encodeURIComponent(userId)}`, {
+    method: "DELETE"
+  })
+}
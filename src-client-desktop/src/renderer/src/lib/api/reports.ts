@@ -0,0 +1,14 @@
+import { apiRequestCurrentServer } from "./client"
+import type { Report, ReportTargetType } from "./types"
+
+// Reporting the same target again while the first report is open returns it unchanged
+export async function createReport(
+  targetType: ReportTargetType,
+  targetId: string,
+  reason = ""
+): Promise<Report> {
+  return apiRequestCurrentServer<Report>("/api/v1/reports", {
+    method: "POST",
+    body: { targetType, targetId, reason }
+  })
+}
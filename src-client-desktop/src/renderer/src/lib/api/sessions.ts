@@ -0,0 +1,13 @@
+import { apiRequestCurrentServer } from "./client"
+import type { UserSession } from "./types"
+
+export async function listSessions(): Promise<UserSession[]> {
+  return apiRequestCurrentServer<UserSession[]>("/api/v1/users/me/sessions")
+}
+
+// Signs the device out; its refresh token stops working immediately
+export async function revokeSession(sessionId: string): Promise<void> {
+  await apiRequestCurrentServer<void>(`/api/v1/users/me/sessions/${encodeURIComponent(sessionId)}`, {
+    method: "DELETE"
+  })
+}
@@ -1,4 +1,5 @@
 import type { User } from "../../../../shared/types"
+import type { AttachmentViolation, MemberState } from "../ws/types"
 
 // Auth response from login/verify endpoints
 export interface AuthResponse {
@@ -29,10 +30,153 @@ export interface RefreshResponse {
 }
 
 // Server info from /server/info
+export interface ServerLink {
+  label: string
+  url: string
+}
+
 export interface ServerInfo {
   name: string
   iconUrl?: string
   uploadMaxBytes?: number
+  avatarMaxBytes?: number
+  maxAttachmentsPerMessage?: number // 0 means no cap
+  description?: string
+  rulesSummary?: string
+  contactEmail?: string
+  socialLinks?: ServerLink[]
+}
+
+export interface MessageDraft {
+  channelId: number
+  content: string
+  updatedAt: string
+}
+
+// Text channel settings from GET /api/v1/channel
+export interface ChannelSettings {
+  name: string
+  topic: string
+  description: string
+  private: boolean
+  archived: boolean
+  slowModeSeconds: number
+  updatedAt: string
+}
+
+export interface NotificationSettings {
+  level: "all" | "mentions" | "muted"
+  suppressEveryone: boolean
+  suppressRoles: boolean
+}
+
+export interface MessageAttachmentResponse {
+  id: string
+  name: string
+  mimeType: string
+  size: number
+  url: string
+  previewUrl?: string
+  previewWidth?: number
+  previewHeight?: number
+  previewText?: string
+  previewLanguage?: string
+  previewFrameCount?: number
+  previewDurationMs?: number
+  scanStatus?: string
+  audioDurationMs?: number
+  audioWaveform?: string
+}
+
+export interface MessageResponse {
+  id: string
+  authorId: string
+  authorName: string
+  authorAvatarUrl?: string
+  authorAvatarAnimatedUrl?: string
+  authorBot?: boolean
+  content: string
+  attachments?: MessageAttachmentResponse[]
+  embeds?: MessageEmbedResponse[]
+  createdAt: string
+}
+
+export interface MessageEmbedResponse {
+  url: string
+  proxyUrl?: string
+}
+
+// GET /api/v1/bootstrap: everything needed for the first render in one request
+export interface BootstrapResponse {
+  server: ServerInfo
+  user: User
+  members: MemberState[] // Same shape as READY members (snake_case)
+  channel: ChannelSettings
+  notifications: NotificationSettings
+  drafts: MessageDraft[]
+  mutes: UserMute[]
+  messages: MessageResponse[] // Newest first; empty without channel access
+}
+
+export type VoiceSelfTestStatus = "pass" | "fail" | "skip"
+
+// POST /api/v1/voice/selftest (admin only)
+export interface VoiceSelfTestResponse {
+  ok: boolean // False when any check failed
+  checks: { name: string; status: VoiceSelfTestStatus; detail: string }[] // peer, port_range, public_ip, stun, turn
+  candidates: { type: string; protocol: string; address: string; port: number }[]
+  durationMs: number
+}
+
+export interface UserSession {
+  id: string
+  deviceName?: string
+  userAgent?: string
+  ipAddress?: string
+  createdAt: string
+  lastUsedAt: string
+  current: boolean
+}
+
+export interface UserMute {
+  userId: string
+  createdAt: string
+}
+
+export interface AccountDeletion {
+  deleteAfter: string
+}
+
+export type EmailChangeAddress = "current" | "new"
+
+export interface EmailChange {
+  newEmail: string
+  currentConfirmed: boolean
+  newConfirmed: boolean
+  completed: boolean
+  expiresAt: string
+}
+
+export type ReportTargetType = "message" | "user"
+
+export interface Report {
+  id: string
+  reporterId: string
+  targetType: ReportTargetType
+  targetId: string
+  reason: string
+  resolvedBy: string | null
+  resolvedAt: string | null
+  createdAt: string
+}
+
+export interface InvitePreview {
+  code: string
+  serverName: string
+  iconUrl?: string
+  memberCount: number
+  onlineCount: number
+  expiresAt: string | null
 }
 
 export interface ChatUploadResponse {
@@ -45,7 +189,28 @@ export interface ChatUploadResponse {
     url: string
     width: number
     height: number
+    frameCount?: number
+    durationMs?: number
+  }
+  textPreview?: {
+    text: string
+    language: string
   }
+  audio?: {
+    durationMs: number
+    waveform?: string
+  }
+  scanStatus?: string
+}
+
+export interface UploadPrecheckResponse {
+  maxBytes: number
+}
+
+// Field-level validation failure
+export interface APIFieldError {
+  field: string
+  message: string
 }
 
 // API error response
@@ -53,18 +218,31 @@ export interface APIError {
   error: {
     code: string
     message: string
+    fields?: APIFieldError[]
+    attachments?: AttachmentViolation[]
   }
 }
 
+// Proof-of-work puzzle to solve before requesting a magic code
+export interface MagicCodeChallenge {
+  required: boolean
+  challenge?: string
+  bits?: number
+  expiresAt?: string
+}
+
 // Request magic code payload
 export interface MagicCodeRequest {
   email: string
+  challenge?: string
+  nonce?: string // Solves challenge: SHA-256 of "challenge:email:nonce" has `bits` leading zero bits
 }
 
 // Verify magic code payload
 export interface VerifyMagicCodeRequest {
   email: string
   code: string
+  deviceName?: string
 }
 
 // Update user payload
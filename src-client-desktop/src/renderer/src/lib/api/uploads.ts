@@ -1,13 +1,34 @@
 import type { User } from "../../../../shared/types"
-import { apiRequestMultipartCurrentServer } from "./client"
-import type { ChatUploadResponse, ServerInfo } from "./types"
+import { apiRequestCurrentServer, apiRequestMultipartCurrentServer } from "./client"
+import type { ChatUploadResponse, ServerInfo, UploadPrecheckResponse } from "./types"
 
-function createUploadForm(file: File): FormData {
+// Crop rectangle in source pixels, or a focal point with x/y between 0 and 1
+export type ImageCrop =
+  | { x: number; y: number; width: number; height: number }
+  | { focusX: number; focusY: number }
+
+function createUploadForm(file: File, crop?: ImageCrop): FormData {
   const form = new FormData()
   form.append("file", file)
+  if (crop && "width" in crop) {
+    form.append("cropX", String(Math.round(crop.x)))
+    form.append("cropY", String(Math.round(crop.y)))
+    form.append("cropWidth", String(Math.round(crop.width)))
+    form.append("cropHeight", String(Math.round(crop.height)))
+  } else if (crop) {
+    form.append("focusX", String(crop.focusX))
+    form.append("focusY", String(crop.focusY))
+  }
   return form
 }
 
+export async function precheckChatAttachment(file: File): Promise<UploadPrecheckResponse> {
+  return apiRequestCurrentServer<UploadPrecheckResponse>("/api/v1/uploads/precheck", {
+    method: "POST",
+    body: { name: file.name, size: file.size, mimeType: file.type }
+  })
+}
+
 export async function uploadChatAttachment(file: File): Promise<ChatUploadResponse> {
   return apiRequestMultipartCurrentServer<ChatUploadResponse>(
     "/api/v1/uploads/chat",
@@ -16,18 +37,18 @@ export async function uploadChatAttachment(file: File): Promise<ChatUploadRespon
   )
 }
 
-export async function uploadAvatar(file: File): Promise<User> {
+export async function uploadAvatar(file: File, crop?: ImageCrop): Promise<User> {
   return apiRequestMultipartCurrentServer<User>(
     "/api/v1/users/me/avatar",
-    createUploadForm(file),
+    createUploadForm(file, crop),
     "POST"
   )
 }
 
-export async function uploadServerImage(file: File): Promise<ServerInfo> {
+export async function uploadServerImage(file: File, crop?: ImageCrop): Promise<ServerInfo> {
   return apiRequestMultipartCurrentServer<ServerInfo>(
     "/api/v1/server/image",
-    createUploadForm(file),
+    createUploadForm(file, crop),
     "POST"
   )
 }
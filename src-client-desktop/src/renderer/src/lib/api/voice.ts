@@ -0,0 +1,9 @@
+import { apiRequestCurrentServer } from "./client"
+import type { VoiceSelfTestResponse } from "./types"
+
+// Admin only; takes up to 10s while candidates are gathered
+export async function runVoiceSelfTest(): Promise<VoiceSelfTestResponse> {
+  return apiRequestCurrentServer<VoiceSelfTestResponse>("/api/v1/voice/selftest", {
+    method: "POST"
+  })
+}
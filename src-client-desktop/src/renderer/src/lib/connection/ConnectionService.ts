@@ -309,12 +309,14 @@ class ConnectionService {
           const updates: Partial<User> = {
             username: member.username,
             avatarUrl: member.avatar_url,
+            avatarAnimatedUrl: member.avatar_animated_url,
             status: member.status,
             inVoice: member.in_voice ?? false,
             voiceMuted: member.muted ?? false,
             voiceDeafened: member.deafened ?? false,
             voiceSpeaking: false,
             isStreaming: member.streaming ?? false,
+            isBot: member.bot ?? false,
             createdAt: member.created_at
           }
 
@@ -327,12 +329,14 @@ class ConnectionService {
             id: member.id,
             username: member.username,
             avatarUrl: member.avatar_url,
+            avatarAnimatedUrl: member.avatar_animated_url,
             status: member.status,
             inVoice: member.in_voice ?? false,
             voiceMuted: member.muted ?? false,
             voiceDeafened: member.deafened ?? false,
             voiceSpeaking: false,
             isStreaming: member.streaming ?? false,
+            isBot: member.bot ?? false,
             createdAt: member.created_at
           })
         })
@@ -360,12 +364,14 @@ class ConnectionService {
             id: member.id,
             username: member.username,
             avatarUrl: member.avatar_url,
+            avatarAnimatedUrl: member.avatar_animated_url,
             status: member.status,
             inVoice: member.in_voice ?? false,
             voiceMuted: member.muted ?? false,
             voiceDeafened: member.deafened ?? false,
             voiceSpeaking: false,
             isStreaming: member.streaming ?? false,
+            isBot: member.bot ?? false,
             createdAt: member.created_at
           }
         ])
@@ -384,7 +390,8 @@ class ConnectionService {
       wsManager.on("user_update", (payload) => {
         this.resolvers?.onUserUpdate(payload.id, {
           username: payload.username || "",
-          avatarUrl: payload.avatar_url
+          avatarUrl: payload.avatar_url,
+          avatarAnimatedUrl: payload.avatar_animated_url
         })
         this.emit("user_update", payload)
       })
@@ -623,7 +630,8 @@ class ConnectionService {
 
   private async refreshServerInfo(server: ServerEntry, generation: number): Promise<void> {
     try {
-      const info = await apiGetServerInfo(server.url)
+      const accessToken = (await hasStoredSession(server.url)) ? await getValidToken() : null
+      const info = await apiGetServerInfo(server.url, accessToken)
       if (this.connectGeneration !== generation) {
         return
       }
@@ -870,6 +878,8 @@ class ConnectionService {
       const profileUpdates: Partial<User> = {}
       if (updates.username !== undefined) profileUpdates.username = updates.username
       if (updates.avatarUrl !== undefined) profileUpdates.avatarUrl = updates.avatarUrl
+      if (updates.avatarAnimatedUrl !== undefined)
+        profileUpdates.avatarAnimatedUrl = updates.avatarAnimatedUrl
       if (updates.email !== undefined) profileUpdates.email = updates.email
       this.resolvers?.onUserUpdate(userId, profileUpdates)
     }
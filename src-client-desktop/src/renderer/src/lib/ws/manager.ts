@@ -1,8 +1,10 @@
 import { createConnectivitySignal } from "@solid-primitives/connectivity"
 import { createEffect, createRoot } from "solid-js"
-import { onTokenRefresh } from "../auth/token-manager"
+import { onTokenRefresh, refreshToken } from "../auth/token-manager"
 import { createLogger } from "../logger"
 import {
+  type ChannelUpdatePayload,
+  type CommandAckPayload,
   type ErrorPayload,
   type HelloPayload,
   type InvalidSessionPayload,
@@ -34,6 +36,16 @@ import {
 
 const log = createLogger("WS")
 
+// Video codecs the server forwards, in our order of preference
+const SUPPORTED_VIDEO_CODECS = ["video/VP9", "video/VP8", "video/H264"]
+
+function videoCodecs(): string[] {
+  const decodable = RTCRtpReceiver.getCapabilities("video")?.codecs ?? []
+  return SUPPORTED_VIDEO_CODECS.filter((mimeType) =>
+    decodable.some((codec) => codec.mimeType.toLowerCase() === mimeType.toLowerCase())
+  )
+}
+
 type EventCallback<T extends WSClientEventType> = (data: WSClientEvents[T]) => void
 
 export interface WSDisconnectInfo {
@@ -79,6 +91,8 @@ class WebSocketManager {
       "error",
       "server_error",
       "screen_share_update",
+      "channel_update",
+      "command_ack",
       "network_status_change"
     ]
     for (const type of eventTypes) {
@@ -278,11 +292,18 @@ class WebSocketManager {
     this.sendDispatch(WSCommandType.Typing, {})
   }
 
+  /**
+   * Clear our typing indicator before the server's TTL does
+   */
+  sendTypingStop(): void {
+    this.sendDispatch(WSCommandType.TypingStop, {})
+  }
+
   /**
    * Join voice channel
    */
   joinVoice(muted?: boolean, deafened?: boolean): void {
-    this.sendDispatch(WSCommandType.VoiceJoin, { muted, deafened })
+    this.sendDispatch(WSCommandType.VoiceJoin, { muted, deafened, video_codecs: videoCodecs() })
   }
 
   /**
@@ -320,7 +341,12 @@ class WebSocketManager {
   /**
    * Send voice state update (mute/deafen/speaking)
    */
-  sendVoiceState(state: { muted?: boolean; deafened?: boolean; speaking?: boolean }): void {
+  sendVoiceState(state: {
+    muted?: boolean
+    deafened?: boolean
+    speaking?: boolean
+    push_to_talk?: boolean
+  }): void {
     this.sendDispatch(WSCommandType.VoiceStateSet, state)
   }
 
@@ -341,8 +367,11 @@ class WebSocketManager {
   /**
    * Subscribe to a user's screen share
    */
-  subscribeScreenShare(streamerId: string): void {
-    this.sendDispatch(WSCommandType.ScreenShareSubscribe, { streamer_id: streamerId })
+  subscribeScreenShare(streamerId: string, maxBitrateKbps?: number): void {
+    this.sendDispatch(WSCommandType.ScreenShareSubscribe, {
+      streamer_id: streamerId,
+      max_bitrate_kbps: maxBitrateKbps
+    })
   }
 
   /**
@@ -518,6 +547,19 @@ class WebSocketManager {
         this.emit("screen_share_update", message.d as ScreenShareUpdatePayload)
         break
 
+      case WSEventType.ChannelUpdate:
+        this.emit("channel_update", message.d as ChannelUpdatePayload)
+        break
+
+      case WSEventType.CommandAck:
+        this.emit("command_ack", message.d as CommandAckPayload)
+        break
+
+      case WSEventType.AuthExpiring:
+        // The refreshed token reaches updateToken, which re-identifies
+        refreshToken().catch((error) => log.warn("Token refresh before expiry failed:", error))
+        break
+
       default:
         log.info("Unknown dispatch type:", message.t)
     }
@@ -6,7 +6,22 @@ export enum WSOpCode {
   // Lifecycle ops (Server -> Client)
   Hello = 1,
   Ready = 2,
-  InvalidSession = 3
+  InvalidSession = 3,
+
+  // Request/response ops (lobby.bot.v1 subprotocol only)
+  Request = 4,
+  Response = 5,
+
+  // Latency probes (either direction)
+  Heartbeat = 6,
+  HeartbeatAck = 7,
+
+  // Command outcome (Server -> Client) for DISPATCH commands sent with an id
+  Ack = 8,
+  Nack = 9,
+
+  // Server -> Client with the "batch" capability: d is an array of messages to handle in order
+  Batch = 10
 }
 
 // Exact client/server WS protocol version.
@@ -30,7 +45,20 @@ export enum WSEventType {
   UserJoined = "USER_JOINED",
   UserLeft = "USER_LEFT",
   Error = "ERROR",
-  ScreenShareUpdate = "SCREEN_SHARE_UPDATE"
+  ScreenShareUpdate = "SCREEN_SHARE_UPDATE",
+  ChannelUpdate = "CHANNEL_UPDATE",
+  CommandAck = "COMMAND_ACK",
+  MessagesPurged = "MESSAGES_PURGED",
+  Notification = "NOTIFICATION",
+  AutomodAlert = "AUTOMOD_ALERT",
+  ModAlert = "MOD_ALERT",
+  DraftUpdate = "DRAFT_UPDATE",
+  Resumed = "RESUMED",
+  AuthExpiring = "AUTH_EXPIRING",
+  RateLimitStatus = "RATE_LIMIT_STATUS",
+  VideoState = "VIDEO_STATE",
+  RecordingState = "RECORDING_STATE",
+  VoiceStats = "VOICE_STATS"
 }
 
 // Command types (Client -> Server via DISPATCH)
@@ -39,6 +67,7 @@ export enum WSCommandType {
   PresenceSet = "PRESENCE_SET",
   MessageSend = "MESSAGE_SEND",
   Typing = "TYPING",
+  TypingStop = "TYPING_STOP",
   VoiceJoin = "VOICE_JOIN",
   VoiceLeave = "VOICE_LEAVE",
   RtcOffer = "RTC_OFFER",
@@ -48,7 +77,26 @@ export enum WSCommandType {
   ScreenShareStart = "SCREEN_SHARE_START",
   ScreenShareStop = "SCREEN_SHARE_STOP",
   ScreenShareSubscribe = "SCREEN_SHARE_SUBSCRIBE",
-  ScreenShareUnsubscribe = "SCREEN_SHARE_UNSUBSCRIBE"
+  ScreenShareUnsubscribe = "SCREEN_SHARE_UNSUBSCRIBE",
+  VoiceRelayStart = "VOICE_RELAY_START",
+  Resume = "RESUME",
+  RateLimitStatus = "RATE_LIMIT_STATUS",
+  CameraStart = "CAMERA_START",
+  CameraStop = "CAMERA_STOP",
+  CameraSubscribe = "CAMERA_SUBSCRIBE",
+  CameraUnsubscribe = "CAMERA_UNSUBSCRIBE",
+  RecordingStart = "RECORDING_START",
+  RecordingStop = "RECORDING_STOP",
+  VoiceWhisperStart = "VOICE_WHISPER_START",
+  VoiceWhisperStop = "VOICE_WHISPER_STOP"
+}
+
+// Request types (Client -> Server via REQUEST, lobby.bot.v1 subprotocol only)
+export const WS_BOT_SUBPROTOCOL = "lobby.bot.v1"
+
+export enum WSRequestType {
+  HistoryGet = "HISTORY_GET",
+  MembersGet = "MEMBERS_GET"
 }
 
 // Base WebSocket message
@@ -56,6 +104,8 @@ export interface WSMessage<T = unknown> {
   op: WSOpCode
   t?: string // Event/command type (only for DISPATCH)
   d?: T
+  s?: number // Event seq; absent on direct replies, which RESUME does not replay
+  id?: string // Command correlation ID, answered with ACK or NACK
 }
 
 // Server -> Client payloads
@@ -66,12 +116,23 @@ export interface MemberState {
   id: string
   username: string
   avatar_url?: string
+  avatar_animated_url?: string // GIF or WebP; avatar_url is its still frame
   status: "online" | "idle" | "dnd" | "offline"
+  status_text?: string
+  status_emoji?: string
   in_voice: boolean
   muted: boolean
   deafened: boolean
+  server_muted: boolean
+  server_deafened: boolean
+  push_to_talk: boolean
+  relay?: boolean // Degraded mode: audio relayed over the websocket
+  priority_speaker?: boolean // Lower other voice audio while this user speaks
   streaming: boolean
+  camera?: boolean
+  role: "member" | "moderator" | "admin"
   created_at: string // ISO 8601
+  bot?: boolean
 }
 
 export interface ReadyPayload {
@@ -82,29 +143,56 @@ export interface ReadyPayload {
     username: string
     email: string
     avatar_url?: string
+    avatar_animated_url?: string
+    role: "member" | "moderator" | "admin"
     created_at?: string
     updated_at?: string
+    bot?: boolean
   }
   members: MemberState[]
+  channel?: ChannelInfo
+  capabilities?: WSCapability[] // Enabled from IDENTIFY
+  recording?: RecordingStatePayload // set while voice is being recorded
+}
+
+// d of HEARTBEAT and HEARTBEAT_ACK; ts is the sender's Date.now(), echoed in the ack
+export interface HeartbeatPayload {
+  ts: number
+  server_ts?: number // Server acks only
 }
 
 export interface InvalidSessionPayload {
   resumable: boolean
 }
 
+export interface ResumedPayload {
+  session_id: string
+  replayed: number
+}
+
 export interface MessageCreatePayload {
   id: string
   author: {
     id: string
     username?: string
     avatar_url?: string
+    avatar_animated_url?: string
+    bot?: boolean
   }
   content: string
   attachments?: MessageAttachment[]
+  embeds?: MessageEmbed[]
   created_at: string // ISO 8601
+  edited_at?: string // ISO 8601
   nonce?: string
 }
 
+// An external image linked in the message; proxy_url is set when the server proxies media
+export interface MessageEmbed {
+  url: string
+  proxy_url?: string
+}
+
 export interface MessageAttachment {
   id: string
   name: string
@@ -114,11 +202,20 @@ export interface MessageAttachment {
   preview_url?: string
   preview_width?: number
   preview_height?: number
+  preview_text?: string
+  preview_language?: string
+  preview_frame_count?: number
+  preview_duration_ms?: number
+  scan_status?: string
+  audio_duration_ms?: number
+  audio_waveform?: string
 }
 
 export interface PresenceUpdatePayload {
   user_id: string
   status: "online" | "idle" | "dnd" | "offline"
+  status_text?: string
+  status_emoji?: string
 }
 
 export interface TypingStartPayload {
@@ -135,6 +232,7 @@ export interface UserUpdatePayload {
   id: string
   username?: string
   avatar_url?: string
+  avatar_animated_url?: string
 }
 
 export interface ServerUpdatePayload {
@@ -142,13 +240,56 @@ export interface ServerUpdatePayload {
   icon_url?: string
 }
 
+export interface ChannelInfo {
+  name: string
+  topic: string
+  description: string
+  private: boolean
+  archived: boolean
+  slow_mode_seconds: number
+}
+
+export interface ChannelUpdatePayload extends ChannelInfo {
+  updated_by?: string
+}
+
 // Client -> Server payloads (via DISPATCH)
 
 export interface IdentifyPayload {
   token: string
   presence?: {
     status: "online" | "idle" | "dnd"
+    status_text?: string
+    status_emoji?: string
   }
+  include_archived?: boolean
+  intents?: WSIntent[] // Omit to receive every event
+  capabilities?: WSCapability[]
+}
+
+// Optional protocol features; the server ignores unknown ones
+export type WSCapability = "batch"
+
+// Event classes a session can limit itself to in IDENTIFY
+export type WSIntent =
+  | "messages"
+  | "typing"
+  | "presence"
+  | "members"
+  | "voice"
+  | "voice_speaking"
+  | "screen_share"
+  | "channel"
+  | "server"
+  | "notifications"
+  | "moderation"
+  | "drafts"
+  | "video"
+
+export interface ResumePayload {
+  token: string
+  session_id: string
+  seq: number // Last seq received
 }
 
 export interface MessageSendPayload {
@@ -159,6 +300,9 @@ export interface MessageSendPayload {
 
 export interface PresenceSetPayload {
   status: "online" | "idle" | "dnd" | "offline"
+  status_text?: string // Omit to keep, "" to clear (max 128 characters)
+  status_emoji?: string // Omit to keep, "" to clear (max 32 bytes, no spaces)
+  nonce?: string // Echoed in COMMAND_ACK / ERROR
 }
 
 export interface VoiceStateUpdatePayload {
@@ -166,11 +310,19 @@ export interface VoiceStateUpdatePayload {
   in_voice: boolean
   muted: boolean
   deafened: boolean
+  server_muted: boolean
+  server_deafened: boolean
+  push_to_talk: boolean
+  relay?: boolean // Degraded mode: audio relayed over the websocket
+  priority_speaker?: boolean // Lower other voice audio while this user speaks
+  whisper_to?: string[] // Set while this user's audio only reaches these users
 }
 
 export interface VoiceJoinPayload {
   muted?: boolean
   deafened?: boolean
+  nonce?: string
+  video_codecs?: string[] // MIME types we can send and decode, preferred first; omitted means video/VP9 only
 }
 
 // RTC Payload types
@@ -183,6 +335,23 @@ export interface ICEServerInfo {
 
 export interface RtcReadyPayload {
   ice_servers: ICEServerInfo[]
+  // Set when the server hands voice to an external SFU; connect there
+  // instead, no RTC_OFFER follows
+  external?: ExternalSFUInfo
+  // Instance serving voice media, when the server is clustered
+  node?: VoiceNodeInfo
+}
+
+export interface VoiceNodeInfo {
+  id: string
+  region?: string
+}
+
+export interface ExternalSFUInfo {
+  provider: "livekit"
+  url: string
+  token: string
+  room: string
 }
 
 export interface RtcOfferPayload {
@@ -203,6 +372,8 @@ export interface VoiceStateSetPayload {
   muted?: boolean
   deafened?: boolean
   speaking?: boolean
+  push_to_talk?: boolean // Exempts unmutes from the toggle cooldown
+  nonce?: string // Echoed in COMMAND_ACK / ERROR
 }
 
 export interface UserJoinedPayload {
@@ -213,6 +384,47 @@ export interface UserLeftPayload {
   user_id: string
 }
 
+export interface MessagesPurgedPayload {
+  author_id: string
+}
+
+// Sent to all of the draft owner's sessions; empty content means cleared
+export interface DraftUpdatePayload {
+  channel_id: number
+  content: string
+  updated_at: string // ISO 8601
+}
+
+// Sent only to moderators and admins
+export interface AutomodAlertPayload {
+  rule_id: string
+  action: "block" | "flag" | "delete_warn"
+  matched: string
+  message_id?: string // Set when the message was delivered (flag)
+  author: MessageCreatePayload["author"]
+  content: string
+}
+
+// Sent only to moderators and admins, when moderation.report_alerts is enabled
+export interface ModAlertPayload {
+  kind: "report"
+  report_id: string
+  reporter_id: string
+  target_type: "message" | "user"
+  target_id: string
+  reason: string
+  report_count: number // Open reports on the target, including this one
+}
+
+// Sent only to the owner of the matching notification rule
+export interface NotificationPayload {
+  rule_id?: string
+  channel_id: number
+  mention?: "user" | "role" | "everyone" | "here"
+  keyword?: string
+  message: MessageCreatePayload
+}
+
 export interface VoiceSpeakingPayload {
   user_id: string
   speaking: boolean
@@ -223,11 +435,146 @@ export interface ErrorPayload {
   message: string
   nonce?: string
   retry_after?: number // Unix ms timestamp
+  rate_limit?: RateLimitStatus // The bucket that rejected the command
+  attachments?: AttachmentViolation[] // Every attachment that kept a message from sending
+}
+
+// One attachment and the limit it broke. MESSAGE_SEND errors give the id,
+// REST upload errors the name and kind.
+export interface AttachmentViolation {
+  id?: string
+  name?: string
+  kind?: "chat_attachment" | "avatar" | "server_image"
+  reason: "too_many" | "too_large" | "unavailable"
+  limit?: number // The count or byte cap
+  size?: number
+}
+
+// One per-connection command budget: limit commands per window_ms, and with
+// cooldown_ms, reaching limit blocks the bucket for that long
+export interface RateLimitStatus {
+  bucket: "message_send" | "voice_join" | "voice_toggle" | "rtc_signaling" | "screen_share_signaling"
+  limit: number
+  window_ms: number
+  cooldown_ms?: number
+  remaining: number
+  reset_at?: number // Unix ms when the oldest counted command leaves the window or the cooldown ends
+}
+
+// Reply to the RATE_LIMIT_STATUS command
+export interface RateLimitStatusPayload {
+  buckets: RateLimitStatus[]
+}
+
+// Only sent when the command carried a nonce
+export interface CommandAckPayload {
+  command: string
+  nonce: string
+}
+
+// Re-IDENTIFY with a refreshed token before expires_at (Unix ms)
+export interface AuthExpiringPayload {
+  expires_at: number
 }
 
+export interface AckPayload {
+  id: string
+  command: string
+}
+
+// Replaces the ERROR the command would otherwise have caused
+export interface NackPayload {
+  id: string
+  command: string
+  code: string
+  message: string
+  retry_after?: number
+  rate_limit?: RateLimitStatus
+}
+
+// Exactly one of result and error is set
+export interface ResponsePayload<T = unknown> {
+  request_id: string
+  result?: T
+  error?: ErrorPayload
+}
+
+export interface HistoryGetPayload {
+  request_id: string
+  before?: string
+  limit?: number // Defaults to 50
+}
+
+export interface HistoryResult {
+  messages: MessageCreatePayload[] // Newest first
+}
+
+// since 0 (or a cursor that is too old) returns a full snapshot
+export interface MembersGetPayload {
+  request_id: string
+  since?: number
+}
+
+export interface MembersResult {
+  seq: number
+  full: boolean
+  members: MemberState[]
+  removed?: string[] // Deactivated users
+}
+
+// Layout hint for a video source: spotlight shows one large view, grid tiles
+// it alongside the voice participants
+export type VideoLayout = "grid" | "spotlight"
+
 export interface ScreenShareUpdatePayload {
   user_id: string
   streaming: boolean
+  layout?: VideoLayout // "spotlight" while streaming
+}
+
+// Camera video arrives on subscribed peers as a track with id "camera"
+export interface VideoStatePayload {
+  user_id: string
+  source: "camera"
+  enabled: boolean
+  width?: number
+  height?: number
+  layout?: VideoLayout // "grid" while enabled
+}
+
+export interface CameraStartPayload {
+  width?: number
+  height?: number
+}
+
+// Recipients must be in voice; lasts until VOICE_WHISPER_STOP or leaving voice
+export interface VoiceWhisperStartPayload {
+  recipient_ids: string[]
+}
+
+export interface CameraSubscribePayload {
+  publisher_id: string
+}
+
+// Sent to everyone when a voice recording starts or stops, so voice
+// participants can be told they are being recorded
+export interface RecordingStatePayload {
+  active: boolean
+  recording_id: string
+  started_by?: string
+  started_at?: string
+}
+
+// Sent to each voice user every few seconds with the quality of their
+// connection to the server. Loss is a fraction from 0 to 1
+export interface VoiceStatsPayload {
+  rtt_ms: number
+  jitter_ms: number
+  uplink_loss: number
+  downlink_loss: number
+  upload_kbps: number
+  download_kbps: number
+  quality: "good" | "fair" | "poor"
 }
 
 // WebSocket connection states
@@ -256,6 +603,8 @@ export type WSClientEventType =
   | "error"
   | "server_error"
   | "screen_share_update"
+  | "channel_update"
+  | "command_ack"
   | "network_status_change"
 
 export interface WSClientEvents {
@@ -280,5 +629,7 @@ export interface WSClientEvents {
   error: Error
   server_error: ErrorPayload
   screen_share_update: ScreenShareUpdatePayload
+  channel_update: ChannelUpdatePayload
+  command_ack: CommandAckPayload
   network_status_change: { online: boolean }
 }
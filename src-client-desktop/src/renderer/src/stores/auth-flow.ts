@@ -16,9 +16,10 @@ import {
   getServerInfo as apiGetServerInfo,
   registerAccount as apiRegisterAccount,
   requestMagicCode as apiRequestMagicCode,
-  verifyMagicCode as apiVerifyMagicCode
+  verifyMagicCode as apiVerifyMagicCode,
+  verifyMagicLink as apiVerifyMagicLink
 } from "../lib/api/auth"
-import type { ServerInfo } from "../lib/api/types"
+import type { ServerInfo, VerifyMagicCodeResponse } from "../lib/api/types"
 import type { AuthFlowStep } from "../lib/auth/types"
 import { createLogger } from "../lib/logger"
 
@@ -190,11 +191,62 @@ async function startEmailAuth(email: string): Promise<boolean> {
  * Returns AuthResult if existing user, null if new user (registration needed).
  */
 async function verifyMagicCode(code: string): Promise<AuthResult | null> {
+  return finishMagicVerify(() => apiVerifyMagicCode(serverUrl(), pendingEmail(), code))
+}
+
+/**
+ * Redeem the link from the login email: pasted into the code field, or the
+ * lobby://magic-link deep link its page opens. The token is only ever sent to
+ * the server the user asked for a code.
+ * Returns AuthResult on success, null on failure or if registration is needed.
+ */
+async function verifyMagicLink(link: string): Promise<AuthResult | null> {
+  const token = magicLinkToken(link)
+  if (!token) {
+    setAuthError("Invalid link")
+    return null
+  }
+  return finishMagicVerify(() => apiVerifyMagicLink(serverUrl(), token))
+}
+
+/**
+ * Redeem a deep link opened from outside the app. Ignored unless the app is
+ * waiting for a code from the server the link names.
+ */
+async function openMagicDeepLink(link: string): Promise<AuthResult | null> {
+  if (step() !== "code-input") return null
+  try {
+    const server = new URL(link).searchParams.get("server") ?? ""
+    if (toServerDedupeKey(server) !== toServerDedupeKey(serverUrl())) {
+      setAuthError("This link is for another server")
+      return null
+    }
+  } catch {
+    return null
+  }
+  return verifyMagicLink(link)
+}
+
+// Extract the token from a magic link, or null if it isn't one
+function magicLinkToken(text: string): string | null {
+  try {
+    const url = new URL(text.trim())
+    const isDeepLink = url.protocol === "lobby:" && url.hostname === "magic-link"
+    if (!isDeepLink && !url.pathname.endsWith("/auth/login/magic-link")) return null
+    return url.searchParams.get("token")
+  } catch {
+    return null
+  }
+}
+
+async function finishMagicVerify(
+  verify: () => Promise<VerifyMagicCodeResponse>
+): Promise<AuthResult | null> {
   setIsLoading(true)
   setAuthError(null)
 
   try {
-    const result = await apiVerifyMagicCode(serverUrl(), pendingEmail(), code)
+    const result = await verify()
 
     if (result.next === "register") {
       setPendingRegistrationToken(result.registrationToken)
@@ -310,6 +362,8 @@ export function useAuthFlow() {
     connectToServer,
     startEmailAuth,
     verifyMagicCode,
+    verifyMagicLink,
+    openMagicDeepLink,
     completeRegistration,
     goBack,
     setStep: (s: AuthFlowStep) => {
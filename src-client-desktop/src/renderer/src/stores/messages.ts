@@ -1,7 +1,7 @@
 import { createMemo, createResource, createRoot, createSignal } from "solid-js"
 import type { Message, MessageAttachment } from "../../../shared/types"
 import { apiRequest, apiRequestCurrentServer } from "../lib/api/client"
-import { ApiError } from "../lib/api/types"
+import { ApiError, type MessageResponse } from "../lib/api/types"
 import { uploadChatAttachment } from "../lib/api/uploads"
 import { connectionService } from "../lib/connection"
 import { ERROR_CODES, getErrorMessage } from "../lib/errors/user-messages"
@@ -22,27 +22,6 @@ function fileTooLargeMessage(maxBytes: number | null): string {
   return formatUploadTooLargeMessage(maxBytes, "File")
 }
 
-interface MessageAttachmentResponse {
-  id: string
-  name: string
-  mimeType: string
-  size: number
-  url: string
-  previewUrl?: string
-  previewWidth?: number
-  previewHeight?: number
-}
-
-interface MessageResponse {
-  id: string
-  authorId: string
-  authorName: string
-  authorAvatarUrl?: string
-  content: string
-  attachments?: MessageAttachmentResponse[]
-  createdAt: string
-}
-
 type DraftAttachmentStatus = "uploading" | "ready" | "failed"
 
 export interface DraftAttachment {
@@ -57,6 +36,13 @@ export interface DraftAttachment {
   previewUrl?: string
   previewWidth?: number
   previewHeight?: number
+  previewText?: string
+  previewLanguage?: string
+  previewFrameCount?: number
+  previewDurationMs?: number
+  scanStatus?: string
+  audioDurationMs?: number
+  audioWaveform?: string
   error?: string
 }
 
@@ -73,6 +59,20 @@ function toMessageAttachment(attachment: {
   preview_width?: number
   previewHeight?: number
   preview_height?: number
+  previewText?: string
+  preview_text?: string
+  previewLanguage?: string
+  preview_language?: string
+  previewFrameCount?: number
+  preview_frame_count?: number
+  previewDurationMs?: number
+  preview_duration_ms?: number
+  scanStatus?: string
+  scan_status?: string
+  audioDurationMs?: number
+  audio_duration_ms?: number
+  audioWaveform?: string
+  audio_waveform?: string
 }): MessageAttachment {
   return {
     id: attachment.id,
@@ -82,7 +82,14 @@ function toMessageAttachment(attachment: {
     url: attachment.url,
     previewUrl: attachment.previewUrl ?? attachment.preview_url,
     previewWidth: attachment.previewWidth ?? attachment.preview_width,
-    previewHeight: attachment.previewHeight ?? attachment.preview_height
+    previewHeight: attachment.previewHeight ?? attachment.preview_height,
+    previewText: attachment.previewText ?? attachment.preview_text,
+    previewLanguage: attachment.previewLanguage ?? attachment.preview_language,
+    previewFrameCount: attachment.previewFrameCount ?? attachment.preview_frame_count,
+    previewDurationMs: attachment.previewDurationMs ?? attachment.preview_duration_ms,
+    scanStatus: attachment.scanStatus ?? attachment.scan_status,
+    audioDurationMs: attachment.audioDurationMs ?? attachment.audio_duration_ms,
+    audioWaveform: attachment.audioWaveform ?? attachment.audio_waveform
   }
 }
 
@@ -93,8 +100,11 @@ function toMessage(msg: MessageResponse): Message {
     authorId: msg.authorId,
     authorName: msg.authorName,
     authorAvatarUrl: msg.authorAvatarUrl,
+    authorAvatarAnimatedUrl: msg.authorAvatarAnimatedUrl,
+    authorBot: msg.authorBot,
     content: msg.content,
     attachments: (msg.attachments ?? []).map(toMessageAttachment),
+    embeds: msg.embeds,
     timestamp: msg.createdAt
   }
 }
@@ -223,6 +233,13 @@ async function uploadDraftAttachment(localId: string, file: File): Promise<void>
               previewUrl: uploaded.preview?.url,
               previewWidth: uploaded.preview?.width,
               previewHeight: uploaded.preview?.height,
+              previewText: uploaded.textPreview?.text,
+              previewLanguage: uploaded.textPreview?.language,
+              previewFrameCount: uploaded.preview?.frameCount,
+              previewDurationMs: uploaded.preview?.durationMs,
+              scanStatus: uploaded.scanStatus,
+              audioDurationMs: uploaded.audio?.durationMs,
+              audioWaveform: uploaded.audio?.waveform,
               file: undefined,
               error: undefined
             }
@@ -388,8 +405,11 @@ connectionService.on("message_create", (payload: MessageCreatePayload) => {
     authorId: payload.author.id,
     authorName: payload.author.username ?? "Unknown",
     authorAvatarUrl: payload.author.avatar_url,
+    authorAvatarAnimatedUrl: payload.author.avatar_animated_url,
+    authorBot: payload.author.bot,
     content: payload.content,
     attachments: payloadAttachments,
+    embeds: payload.embeds?.map((embed) => ({ url: embed.url, proxyUrl: embed.proxy_url })),
     timestamp: payload.created_at
   }
 
@@ -435,7 +455,14 @@ function sendMessage(serverId: string, content: string): boolean {
       url: attachment.url || "",
       previewUrl: attachment.previewUrl,
       previewWidth: attachment.previewWidth,
-      previewHeight: attachment.previewHeight
+      previewHeight: attachment.previewHeight,
+      previewText: attachment.previewText,
+      previewLanguage: attachment.previewLanguage,
+      previewFrameCount: attachment.previewFrameCount,
+      previewDurationMs: attachment.previewDurationMs,
+      scanStatus: attachment.scanStatus,
+      audioDurationMs: attachment.audioDurationMs,
+      audioWaveform: attachment.audioWaveform
     }))
   const attachmentIDs = attachmentModels.map((attachment) => attachment.id)
 
@@ -445,6 +472,7 @@ function sendMessage(serverId: string, content: string): boolean {
     authorId: currentUserValue.id,
     authorName: currentUserValue.username,
     authorAvatarUrl: currentUserValue.avatarUrl,
+    authorAvatarAnimatedUrl: currentUserValue.avatarAnimatedUrl,
     content,
     attachments: attachmentModels,
     timestamp: new Date().toISOString()
@@ -53,6 +53,12 @@ function sendTyping(): void {
   }
 }
 
+function sendTypingStop(): void {
+  if (connectionService.getSession()?.status === "connected") {
+    wsManager.sendTypingStop()
+  }
+}
+
 // Subscribe to events
 connectionService.on("typing_start", handleTypingStart)
 connectionService.on("typing_stop", handleTypingStop)
@@ -61,6 +67,7 @@ connectionService.onLifecycle("typing_clear", clearTypingUsers)
 export function useTyping() {
   return {
     typingUsers,
-    sendTyping
+    sendTyping,
+    sendTypingStop
   }
 }
@@ -211,6 +211,14 @@ async function handleRtcReady(payload: RtcReadyPayload): Promise<void> {
     return
   }
 
+  if (payload.external) {
+    log.error("Server uses an external SFU, which this client can't connect to", {
+      provider: payload.external.provider
+    })
+    cleanupVoiceStartupFailure("external-sfu")
+    return
+  }
+
   const iceServers: RTCIceServer[] = (payload.ice_servers ?? []).map((server) => ({
     urls: server.urls,
     username: server.username,
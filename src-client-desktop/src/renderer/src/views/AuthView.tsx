@@ -1,8 +1,17 @@
 import { useNavigate } from "@solidjs/router"
 import { TbOutlineArrowLeft } from "solid-icons/tb"
-import { type Component, createEffect, createSignal, Match, Show, Switch } from "solid-js"
+import {
+  type Component,
+  createEffect,
+  createSignal,
+  Match,
+  onCleanup,
+  onMount,
+  Show,
+  Switch
+} from "solid-js"
 import Button from "../components/shared/Button"
-import { useAuthFlow } from "../stores/auth-flow"
+import { type AuthResult, useAuthFlow } from "../stores/auth-flow"
 import { useConnection } from "../stores/connection"
 
 const Spinner: Component = () => (
@@ -56,6 +65,28 @@ const AuthView: Component = () => {
     }
   }
 
+  const finishCodeLogin = async (result: AuthResult | null) => {
+    if (result) {
+      await connection.onAuthSuccess(
+        result.user,
+        result.serverUrl,
+        result.serverInfo,
+        result.tokens
+      )
+      const server = connection.currentServer()
+      if (server) navigate(`/server/${server.id}`)
+    }
+  }
+
+  // The page behind a sign-in email link opens lobby://magic-link
+  const takeDeepLink = async () => {
+    const link = await window.api.deepLink.takePending()
+    if (link) authFlow.openMagicDeepLink(link).then(finishCodeLogin)
+  }
+  onMount(takeDeepLink)
+  onCleanup(window.api.deepLink.onOpen(takeDeepLink))
+
+
   const getTitle = () => {
     switch (authFlow.step()) {
       case "server-url":
@@ -243,18 +274,14 @@ const AuthView: Component = () => {
                       setCodeInput(digits)
                       e.currentTarget.value = digits
                       if (digits.length === 6) {
-                        authFlow.verifyMagicCode(digits).then(async (result) => {
-                          if (result) {
-                            await connection.onAuthSuccess(
-                              result.user,
-                              result.serverUrl,
-                              result.serverInfo,
-                              result.tokens
-                            )
-                            const server = connection.currentServer()
-                            if (server) navigate(`/server/${server.id}`)
-                          }
-                        })
+                        authFlow.verifyMagicCode(digits).then(finishCodeLogin)
+                      }
+                    }}
+                    onPaste={(e) => {
+                      const text = e.clipboardData?.getData("text") ?? ""
+                      if (/^https?:\/\//i.test(text.trim())) {
+                        e.preventDefault()
+                        authFlow.verifyMagicLink(text).then(finishCodeLogin)
                       }
                     }}
                     placeholder="000000"
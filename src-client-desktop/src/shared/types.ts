@@ -3,6 +3,7 @@ export interface User {
   id: string
   username: string
   avatarUrl?: string
+  avatarAnimatedUrl?: string // GIF or WebP; avatarUrl is its still frame
   email?: string
   createdAt?: string // ISO 8601
 
@@ -13,6 +14,7 @@ export interface User {
   voiceDeafened: boolean
   voiceSpeaking: boolean
   isStreaming?: boolean
+  isBot?: boolean
 }
 
 export interface Server {
@@ -29,11 +31,20 @@ export interface Message {
   authorId: string
   authorName: string
   authorAvatarUrl?: string
+  authorAvatarAnimatedUrl?: string
+  authorBot?: boolean
   content: string
   attachments?: MessageAttachment[]
+  embeds?: MessageEmbed[]
   timestamp: string
 }
 
+// An external image linked in the message, with the server's proxied copy
+export interface MessageEmbed {
+  url: string
+  proxyUrl?: string
+}
+
 export interface MessageAttachment {
   id: string
   name: string
@@ -43,6 +54,13 @@ export interface MessageAttachment {
   previewUrl?: string
   previewWidth?: number
   previewHeight?: number
+  previewText?: string
+  previewLanguage?: string
+  previewFrameCount?: number
+  previewDurationMs?: number
+  scanStatus?: string
+  audioDurationMs?: number
+  audioWaveform?: string
 }
 
 export interface VoiceParticipant {
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+
+	"lobby/internal/blob"
+	"lobby/internal/config"
+	"lobby/internal/db"
+)
+
+const blobUsage = `usage: lobby blob reconcile [-dry-run] [-config <path>]
+
+Compares blob rows against the files under the blob root. Files no row
+refers to are deleted once older than an hour; rows whose files are gone
+get blobs.missing_since. The server also runs this once a day.`
+
+// runBlobCommand handles `lobby blob ...` and returns the exit code.
+func runBlobCommand(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 || args[0] != "reconcile" {
+		fmt.Fprintln(stderr, blobUsage)
+		return 2
+	}
+
+	flags := flag.NewFlagSet("reconcile", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	configPath := flags.String("config", "config.yaml", "path to config file")
+	dryRun := flags.Bool("dry-run", false, "report without deleting files or flagging rows")
+	if err := flags.Parse(args[1:]); err != nil {
+		return 2
+	}
+
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		fmt.Fprintf(stderr, "loading config: %v\n", err)
+		return 1
+	}
+	database, err := db.Open(cfg.Database.Path)
+	if err != nil {
+		fmt.Fprintf(stderr, "opening database: %v\n", err)
+		return 1
+	}
+	defer database.Close()
+	blobService, err := blob.NewService(cfg.Storage.BlobRoot, cfg.Storage.UploadLimits().Max())
+	if err != nil {
+		fmt.Fprintf(stderr, "opening blob storage: %v\n", err)
+		return 1
+	}
+
+	report, err := blob.NewReconciler(database.Queries(), blobService).Reconcile(context.Background(), *dryRun)
+	if err != nil {
+		fmt.Fprintf(stderr, "reconciling blobs: %v\n", err)
+		return 1
+	}
+	printReconcileReport(stdout, report, *dryRun)
+	return 0
+}
+
+func printReconcileReport(w io.Writer, report *blob.ReconcileReport, dryRun bool) {
+	deleted, flagged, cleared := "deleted", "flagged", "cleared"
+	if dryRun {
+		deleted, flagged, cleared = "would delete", "would flag", "would clear"
+	}
+	fmt.Fprintf(w, "checked %d blob rows and %d files\n", report.Blobs, report.Files)
+	fmt.Fprintf(w, "orphaned files: %d (%d bytes), %s\n", report.OrphanedFiles, report.OrphanedBytes, deleted)
+	fmt.Fprintf(w, "missing files: %d; %s %d newly missing blobs, %s %d recovered\n", report.MissingFiles, flagged, report.NewlyMissing, cleared, report.Recovered)
+}
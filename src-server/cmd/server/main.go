@@ -4,6 +4,7 @@ import (
 	"context"
 	"flag"
 	"log/slog"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
@@ -12,15 +13,24 @@ import (
 
 	"lobby/internal/api"
 	"lobby/internal/blob"
+	"lobby/internal/clock"
 	"lobby/internal/config"
 	"lobby/internal/db"
 	"lobby/internal/email"
+	"lobby/internal/logging"
+	"lobby/internal/proxyproto"
+	"lobby/internal/sfu"
 )
 
 func main() {
-	slog.SetDefault(slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{
-		Level: slog.LevelInfo,
-	})))
+	if len(os.Args) > 1 && os.Args[1] == "user" {
+		os.Exit(runUserCommand(os.Args[2:], os.Stdout, os.Stderr))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "blob" {
+		os.Exit(runBlobCommand(os.Args[2:], os.Stdout, os.Stderr))
+	}
+
+	logging.Setup(os.Stdout)
 
 	configPath := flag.String("config", "config.yaml", "path to config file")
 	flag.Parse()
@@ -30,6 +40,15 @@ func main() {
 		slog.Error("failed to load config", "error", err)
 		os.Exit(1)
 	}
+	// Both levels were validated when loading the config
+	level, _ := logging.ParseLevel(cfg.Server.LogLevel)
+	logging.SetLevel("", level)
+	if cfg.SFU.LogLevel != "" {
+		sfuLevel, _ := logging.ParseLevel(cfg.SFU.LogLevel)
+		for _, component := range sfu.LogComponents() {
+			logging.SetLevel(component, sfuLevel)
+		}
+	}
 
 	slog.Info("starting server", "name", cfg.Server.Name)
 
@@ -41,14 +60,14 @@ func main() {
 	defer database.Close()
 	slog.Info("database opened", "path", cfg.Database.Path)
 
-	blobService, err := blob.NewService(cfg.Storage.BlobRoot, cfg.Storage.UploadMaxBytes)
+	blobService, err := blob.NewService(cfg.Storage.BlobRoot, cfg.Storage.UploadLimits().Max())
 	if err != nil {
 		slog.Error("failed to initialize blob storage", "error", err)
 		os.Exit(1)
 	}
-	slog.Info("blob storage initialized", "root", cfg.Storage.BlobRoot, "upload_max_bytes", cfg.Storage.UploadMaxBytes)
+	slog.Info("blob storage initialized", "root", cfg.Storage.BlobRoot, "upload_max_bytes", blobService.MaxUploadBytes())
 
-	cleanupService := db.NewCleanupService(database.Queries())
+	cleanupService := db.NewCleanupService(database, blobService)
 	blobCleanupService := blob.NewCleanupService(database.Queries(), blobService)
 	cleanupCtx, cleanupCancel := context.WithCancel(context.Background())
 	go cleanupService.Start(cleanupCtx)
@@ -68,6 +87,7 @@ func main() {
 		database,
 		emailService,
 		blobService,
+		clock.Real,
 	)
 	if err != nil {
 		slog.Error("failed to create server", "error", err)
@@ -80,9 +100,24 @@ func main() {
 		Handler: server,
 	}
 
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		slog.Error("failed to listen", "error", err, "addr", addr)
+		os.Exit(1)
+	}
+	if cfg.Server.ProxyProtocol {
+		ipResolver, err := api.NewClientIPResolver(cfg.Server.TrustedProxyCIDRs)
+		if err != nil {
+			slog.Error("failed to configure proxy protocol", "error", err)
+			os.Exit(1)
+		}
+		listener = proxyproto.NewListener(listener, ipResolver.IsTrustedProxy)
+		slog.Info("proxy protocol enabled", "trusted_proxy_cidrs", cfg.Server.TrustedProxyCIDRs)
+	}
+
 	go func() {
 		slog.Info("server listening", "addr", addr, "base_url", cfg.Server.BaseURL)
-		if err := httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		if err := httpServer.Serve(listener); err != nil && err != http.ErrServerClosed {
 			slog.Error("server failed", "error", err)
 			os.Exit(1)
 		}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+
+	"lobby/internal/config"
+	"lobby/internal/db"
+	sqldb "lobby/internal/db/sqlc"
+	"lobby/internal/models"
+)
+
+const userUsage = `usage: lobby user create-admin -email <address> [-username <name>] [-config <path>]
+
+Creates an admin account for email, or promotes the existing account.
+Run it before starting the server, or restart afterwards so the member
+list picks up the change.`
+
+var cliUsernameRegex = regexp.MustCompile(`^[a-zA-Z0-9_-]{3,32}$`)
+
+// runUserCommand handles `lobby user ...` and returns the exit code.
+func runUserCommand(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 || args[0] != "create-admin" {
+		fmt.Fprintln(stderr, userUsage)
+		return 2
+	}
+
+	flags := flag.NewFlagSet("create-admin", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	configPath := flags.String("config", "config.yaml", "path to config file")
+	email := flags.String("email", "", "email address of the admin")
+	username := flags.String("username", "", "username for a new account (defaults to the email's local part)")
+	if err := flags.Parse(args[1:]); err != nil {
+		return 2
+	}
+	if strings.TrimSpace(*email) == "" {
+		fmt.Fprintln(stderr, userUsage)
+		return 2
+	}
+
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		fmt.Fprintf(stderr, "loading config: %v\n", err)
+		return 1
+	}
+	database, err := db.Open(cfg.Database.Path)
+	if err != nil {
+		fmt.Fprintf(stderr, "opening database: %v\n", err)
+		return 1
+	}
+	defer database.Close()
+
+	user, created, err := createAdmin(context.Background(), database, *email, *username)
+	if err != nil {
+		fmt.Fprintf(stderr, "creating admin: %v\n", err)
+		return 1
+	}
+	if created {
+		fmt.Fprintf(stdout, "created admin %s (%s, %s); sign in with a magic code sent to that address\n", user.Username, user.ID, user.Email)
+	} else {
+		fmt.Fprintf(stdout, "promoted %s (%s, %s) to admin\n", user.Username, user.ID, user.Email)
+	}
+	return 0
+}
+
+// createAdmin promotes the account registered to email, or creates it with
+// username when there is none. It reports whether the account is new.
+func createAdmin(ctx context.Context, database *db.DB, email, username string) (*models.User, bool, error) {
+	email = strings.ToLower(strings.TrimSpace(email))
+	if _, _, ok := strings.Cut(email, "@"); !ok {
+		return nil, false, fmt.Errorf("%q is not an email address", email)
+	}
+
+	tx, err := database.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, false, fmt.Errorf("starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+	qtx := database.Queries().WithTx(tx)
+
+	now := time.Now().UTC()
+	user := &models.User{Email: email, Role: models.RoleAdmin}
+	created := false
+
+	existing, err := qtx.GetUserByEmail(ctx, email)
+	switch {
+	case err == nil:
+		if existing.Bot {
+			return nil, false, fmt.Errorf("%s belongs to a bot account", email)
+		}
+		user.ID = existing.ID
+		user.Username = existing.Username
+	case errors.Is(err, sql.ErrNoRows):
+		username = strings.TrimSpace(username)
+		if username == "" {
+			username, _, _ = strings.Cut(email, "@")
+		}
+		if !cliUsernameRegex.MatchString(username) {
+			return nil, false, fmt.Errorf("username %q must be 3-32 letters, numbers, underscores, or hyphens; pass -username", username)
+		}
+		count, err := qtx.CountUsersByUsername(ctx, username)
+		if err != nil {
+			return nil, false, fmt.Errorf("checking username: %w", err)
+		}
+		if count > 0 {
+			return nil, false, fmt.Errorf("username %q is taken; pass -username", username)
+		}
+
+		user.ID, err = db.GenerateID("usr")
+		if err != nil {
+			return nil, false, fmt.Errorf("generating user id: %w", err)
+		}
+		user.Username = username
+		if err := qtx.CreateUser(ctx, sqldb.CreateUserParams{
+			ID:        user.ID,
+			Username:  username,
+			Email:     email,
+			CreatedAt: now,
+		}); err != nil {
+			return nil, false, fmt.Errorf("creating user: %w", err)
+		}
+		created = true
+	default:
+		return nil, false, fmt.Errorf("looking up user: %w", err)
+	}
+
+	if _, err := qtx.SetUserRole(ctx, sqldb.SetUserRoleParams{
+		Role:      models.RoleAdmin,
+		UpdatedAt: &now,
+		ID:        user.ID,
+	}); err != nil {
+		return nil, false, fmt.Errorf("setting role: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, false, fmt.Errorf("committing: %w", err)
+	}
+	return user, created, nil
+}
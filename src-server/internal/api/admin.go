@@ -0,0 +1,118 @@
+package api
+
+import (
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"lobby/internal/clock"
+	"lobby/internal/db"
+	sqldb "lobby/internal/db/sqlc"
+	"lobby/internal/ws"
+)
+
+type AdminHandler struct {
+	queries *sqldb.Queries
+	hub     *ws.Hub
+	jobs    *adminJobs
+	clock   clock.Clock
+}
+
+func NewAdminHandler(queries *sqldb.Queries, hub *ws.Hub) *AdminHandler {
+	return &AdminHandler{
+		queries: queries,
+		hub:     hub,
+		jobs:    newAdminJobs(),
+		clock:   clock.Real,
+	}
+}
+
+// SetClock replaces the clock behind inactivity pruning and session
+// revocation jobs.
+func (h *AdminHandler) SetClock(c clock.Clock) {
+	h.clock = c
+}
+
+type RegistrationDomainResponse struct {
+	Domain    string    `json:"domain"`
+	CreatedBy *string   `json:"createdBy"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// GET /api/v1/admin/registration/domains
+func (h *AdminHandler) ListRegistrationDomains(w http.ResponseWriter, r *http.Request) {
+	rows, err := h.queries.ListRegistrationDomains(r.Context())
+	if err != nil {
+		slog.Error("error listing registration domains", "error", err)
+		internalError(w)
+		return
+	}
+
+	domains := make([]RegistrationDomainResponse, 0, len(rows))
+	for _, row := range rows {
+		domains = append(domains, RegistrationDomainResponse(row))
+	}
+	writeJSON(w, http.StatusOK, domains)
+}
+
+// PUT /api/v1/admin/registration/domains/{domain}
+func (h *AdminHandler) AddRegistrationDomain(w http.ResponseWriter, r *http.Request) {
+	domain, ok := registrationDomainParam(w, r)
+	if !ok {
+		return
+	}
+
+	actorID := GetUserID(r)
+	err := h.queries.CreateRegistrationDomain(r.Context(), sqldb.CreateRegistrationDomainParams{
+		Domain:    domain,
+		CreatedBy: &actorID,
+		CreatedAt: time.Now().UTC(),
+	})
+	if db.IsUniqueConstraintError(err) {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	if err != nil {
+		slog.Error("error adding registration domain", "error", err, "domain", domain)
+		internalError(w)
+		return
+	}
+
+	slog.Info("registration domain added", "domain", domain, "by", actorID)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// DELETE /api/v1/admin/registration/domains/{domain}
+func (h *AdminHandler) RemoveRegistrationDomain(w http.ResponseWriter, r *http.Request) {
+	domain, ok := registrationDomainParam(w, r)
+	if !ok {
+		return
+	}
+
+	rowsAffected, err := h.queries.DeleteRegistrationDomain(r.Context(), domain)
+	if err != nil {
+		slog.Error("error removing registration domain", "error", err, "domain", domain)
+		internalError(w)
+		return
+	}
+	if rowsAffected == 0 {
+		notFound(w, "Domain is not on the allowlist")
+		return
+	}
+
+	slog.Info("registration domain removed", "domain", domain, "by", GetUserID(r))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// registrationDomainParam reads and normalizes the {domain} URL parameter.
+func registrationDomainParam(w http.ResponseWriter, r *http.Request) (string, bool) {
+	domain := strings.ToLower(strings.TrimSpace(chi.URLParam(r, "domain")))
+	if err := requestValidator.Var(domain, "fqdn,max=253"); err != nil {
+		badRequest(w, "invalid domain")
+		return "", false
+	}
+	return domain, true
+}
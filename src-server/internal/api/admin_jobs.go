@@ -0,0 +1,309 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"lobby/internal/db"
+	sqldb "lobby/internal/db/sqlc"
+	"lobby/internal/models"
+)
+
+const (
+	AdminJobAssignRole     = "assign_role"
+	AdminJobPruneInactive  = "prune_inactive"
+	AdminJobRevokeSessions = "revoke_sessions"
+
+	AdminJobRunning   = "running"
+	AdminJobCompleted = "completed"
+	AdminJobFailed    = "failed"
+
+	// adminJobRetention bounds how many jobs are kept for polling; the oldest
+	// finished jobs are dropped first.
+	adminJobRetention = 50
+)
+
+var errAdminJobUserNotFound = errors.New("user not found")
+
+type AssignRoleJobRequest struct {
+	UserIDs []string `json:"userIds" validate:"required,min=1,max=500,dive,required,max=64"`
+	Role    string   `json:"role" validate:"required"`
+}
+
+type PruneInactiveJobRequest struct {
+	InactiveDays int `json:"inactiveDays" validate:"required,min=1,max=3650"`
+}
+
+type AdminJobResponse struct {
+	ID         string     `json:"id"`
+	Kind       string     `json:"kind"`
+	Status     string     `json:"status"`
+	Total      int        `json:"total"`
+	Processed  int        `json:"processed"`
+	Failed     int        `json:"failed"`
+	Error      string     `json:"error,omitempty"`
+	CreatedBy  string     `json:"createdBy"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	FinishedAt *time.Time `json:"finishedAt"`
+}
+
+// adminJobs tracks bulk admin jobs in memory. Jobs do not survive a restart;
+// only one may run at a time so bulk writes never interleave.
+type adminJobs struct {
+	mu    sync.Mutex
+	jobs  map[string]*AdminJobResponse
+	order []string
+}
+
+func newAdminJobs() *adminJobs {
+	return &adminJobs{jobs: make(map[string]*AdminJobResponse)}
+}
+
+// POST /api/v1/admin/jobs/assign-role
+func (h *AdminHandler) StartAssignRoleJob(w http.ResponseWriter, r *http.Request) {
+	var req AssignRoleJobRequest
+	if err := decodeAndValidate(r.Body, &req); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+	if !models.IsValidRole(req.Role) {
+		badRequest(w, "invalid role")
+		return
+	}
+
+	actorID := GetUserID(r)
+	userIDs := make([]string, 0, len(req.UserIDs))
+	seen := make(map[string]struct{}, len(req.UserIDs))
+	for _, userID := range req.UserIDs {
+		if userID == actorID {
+			badRequest(w, "You cannot change your own role")
+			return
+		}
+		if _, ok := seen[userID]; ok {
+			continue
+		}
+		seen[userID] = struct{}{}
+		userIDs = append(userIDs, userID)
+	}
+
+	h.startJob(w, actorID, AdminJobAssignRole, func(context.Context) ([]string, error) {
+		return userIDs, nil
+	}, func(ctx context.Context, userID string) error {
+		now := time.Now().UTC()
+		rowsAffected, err := h.queries.SetUserRole(ctx, sqldb.SetUserRoleParams{
+			Role:      req.Role,
+			UpdatedAt: &now,
+			ID:        userID,
+		})
+		if err != nil {
+			return err
+		}
+		if rowsAffected == 0 {
+			return errAdminJobUserNotFound
+		}
+		// The websocket caches the role from IDENTIFY; reconnecting picks up
+		// the new one.
+		h.closeClient(userID)
+		return nil
+	})
+}
+
+// POST /api/v1/admin/jobs/prune-inactive
+func (h *AdminHandler) StartPruneInactiveJob(w http.ResponseWriter, r *http.Request) {
+	var req PruneInactiveJobRequest
+	if err := decodeAndValidate(r.Body, &req); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+
+	// Activity is the latest login/refresh or message; moderators and admins
+	// are never pruned.
+	cutoff := h.clock.Now().UTC().AddDate(0, 0, -req.InactiveDays)
+	h.startJob(w, GetUserID(r), AdminJobPruneInactive, func(ctx context.Context) ([]string, error) {
+		return h.queries.ListInactiveMemberIDs(ctx, cutoff)
+	}, func(ctx context.Context, userID string) error {
+		deactivated, err := deactivateMember(ctx, h.queries, userID, h.clock.Now().UTC())
+		if err != nil {
+			return err
+		}
+		if deactivated && h.hub != nil {
+			disconnectMember(h.hub, userID)
+		}
+		return nil
+	})
+}
+
+// POST /api/v1/admin/jobs/revoke-sessions
+func (h *AdminHandler) StartRevokeSessionsJob(w http.ResponseWriter, r *http.Request) {
+	h.startJob(w, GetUserID(r), AdminJobRevokeSessions, h.queries.ListActiveUserIDs, func(ctx context.Context, userID string) error {
+		now := h.clock.Now().UTC()
+		if err := h.queries.RevokeAllRefreshTokensForUser(ctx, sqldb.RevokeAllRefreshTokensForUserParams{
+			RevokedAt: &now,
+			UserID:    userID,
+		}); err != nil {
+			return err
+		}
+		if _, err := h.queries.IncrementUserSessionVersion(ctx, sqldb.IncrementUserSessionVersionParams{
+			UpdatedAt: &now,
+			ID:        userID,
+		}); err != nil {
+			return err
+		}
+		h.closeClient(userID)
+		return nil
+	})
+}
+
+// GET /api/v1/admin/jobs
+func (h *AdminHandler) ListJobs(w http.ResponseWriter, r *http.Request) {
+	h.jobs.mu.Lock()
+	jobs := make([]AdminJobResponse, 0, len(h.jobs.order))
+	for i := len(h.jobs.order) - 1; i >= 0; i-- {
+		jobs = append(jobs, *h.jobs.jobs[h.jobs.order[i]])
+	}
+	h.jobs.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, jobs)
+}
+
+// GET /api/v1/admin/jobs/{jobID}
+func (h *AdminHandler) GetJob(w http.ResponseWriter, r *http.Request) {
+	job, ok := h.jobs.get(chi.URLParam(r, "jobID"))
+	if !ok {
+		notFound(w, "Job not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, job)
+}
+
+// startJob registers a job and runs it in the background, applying apply to
+// every user returned by list. Per-user failures are counted and skipped;
+// only a failing list fails the whole job. Responds 202 with the new job.
+func (h *AdminHandler) startJob(
+	w http.ResponseWriter,
+	actorID string,
+	kind string,
+	list func(context.Context) ([]string, error),
+	apply func(context.Context, string) error,
+) {
+	jobID, err := db.GenerateID("job")
+	if err != nil {
+		slog.Error("error generating admin job id", "error", err)
+		internalError(w)
+		return
+	}
+
+	job, ok := h.jobs.start(&AdminJobResponse{
+		ID:        jobID,
+		Kind:      kind,
+		Status:    AdminJobRunning,
+		CreatedBy: actorID,
+		CreatedAt: time.Now().UTC(),
+	})
+	if !ok {
+		conflict(w, "Another admin job is already running")
+		return
+	}
+
+	slog.Info("admin job started", "job_id", jobID, "kind", kind, "by", actorID)
+	go h.runJob(jobID, list, apply)
+	writeJSON(w, http.StatusAccepted, job)
+}
+
+func (h *AdminHandler) runJob(jobID string, list func(context.Context) ([]string, error), apply func(context.Context, string) error) {
+	ctx := context.Background()
+
+	userIDs, err := list(ctx)
+	if err != nil {
+		slog.Error("error listing admin job targets", "error", err, "job_id", jobID)
+		h.jobs.update(jobID, func(job *AdminJobResponse) {
+			job.Status = AdminJobFailed
+			job.Error = "Failed to load target users"
+		})
+		return
+	}
+	h.jobs.update(jobID, func(job *AdminJobResponse) {
+		job.Total = len(userIDs)
+	})
+
+	for _, userID := range userIDs {
+		err := apply(ctx, userID)
+		if err != nil && !errors.Is(err, errAdminJobUserNotFound) {
+			slog.Error("error applying admin job", "error", err, "job_id", jobID, "user_id", userID)
+		}
+		h.jobs.update(jobID, func(job *AdminJobResponse) {
+			job.Processed++
+			if err != nil {
+				job.Failed++
+			}
+		})
+	}
+
+	h.jobs.update(jobID, func(job *AdminJobResponse) {
+		job.Status = AdminJobCompleted
+	})
+	slog.Info("admin job finished", "job_id", jobID, "total", len(userIDs))
+}
+
+func (h *AdminHandler) closeClient(userID string) {
+	if h.hub == nil {
+		return
+	}
+	if client := h.hub.GetClient(userID); client != nil {
+		client.Close()
+	}
+}
+
+// start registers job unless another job is still running, returning a copy.
+func (j *adminJobs) start(job *AdminJobResponse) (AdminJobResponse, bool) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	for _, existing := range j.jobs {
+		if existing.Status == AdminJobRunning {
+			return AdminJobResponse{}, false
+		}
+	}
+
+	j.jobs[job.ID] = job
+	j.order = append(j.order, job.ID)
+	if len(j.order) > adminJobRetention {
+		delete(j.jobs, j.order[0])
+		j.order = j.order[1:]
+	}
+	return *job, true
+}
+
+func (j *adminJobs) get(jobID string) (AdminJobResponse, bool) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	job, ok := j.jobs[jobID]
+	if !ok {
+		return AdminJobResponse{}, false
+	}
+	return *job, true
+}
+
+// update applies fn under the lock and stamps FinishedAt once the job leaves
+// the running state.
+func (j *adminJobs) update(jobID string, fn func(*AdminJobResponse)) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	job, ok := j.jobs[jobID]
+	if !ok {
+		return
+	}
+	fn(job)
+	if job.Status != AdminJobRunning && job.FinishedAt == nil {
+		now := time.Now().UTC()
+		job.FinishedAt = &now
+	}
+}
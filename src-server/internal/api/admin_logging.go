@@ -0,0 +1,66 @@
+package api
+
+import (
+	"log/slog"
+	"net/http"
+
+	"lobby/internal/logging"
+)
+
+type LogLevelsResponse struct {
+	Level      string            `json:"level"`
+	Components map[string]string `json:"components"`
+}
+
+// SetLogLevelRequest sets the base level, or Component's own level. An empty
+// Level with a Component makes it follow the base level again.
+type SetLogLevelRequest struct {
+	Component string `json:"component" validate:"max=64"`
+	Level     string `json:"level"`
+}
+
+// GET /api/v1/admin/log-levels
+func (h *AdminHandler) GetLogLevels(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, logLevelsResponse())
+}
+
+// PUT /api/v1/admin/log-levels
+// Changes last until restart; server.log_level and sfu.logLevel apply again
+// after it.
+func (h *AdminHandler) SetLogLevel(w http.ResponseWriter, r *http.Request) {
+	var req SetLogLevelRequest
+	if err := decodeAndValidate(r.Body, &req); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+
+	if req.Level == "" {
+		if req.Component == "" {
+			badRequest(w, "level is required")
+			return
+		}
+		logging.ClearLevel(req.Component)
+	} else {
+		level, err := logging.ParseLevel(req.Level)
+		if err != nil {
+			badRequest(w, "level must be one of debug, info, warn, error")
+			return
+		}
+		logging.SetLevel(req.Component, level)
+	}
+
+	slog.Info("log level changed", "log_component", req.Component, "level", req.Level, "by", GetUserID(r))
+	writeJSON(w, http.StatusOK, logLevelsResponse())
+}
+
+func logLevelsResponse() LogLevelsResponse {
+	base, components := logging.Levels()
+	resp := LogLevelsResponse{
+		Level:      logging.LevelName(base),
+		Components: make(map[string]string, len(components)),
+	}
+	for component, level := range components {
+		resp.Components[component] = logging.LevelName(level)
+	}
+	return resp
+}
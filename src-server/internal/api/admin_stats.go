@@ -0,0 +1,91 @@
+package api
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"lobby/internal/ws"
+)
+
+const (
+	defaultTopMediaLimit = 10
+	maxTopMediaLimit     = 50
+)
+
+type MediaDownloadStat struct {
+	ID               string     `json:"id"`
+	Kind             string     `json:"kind"`
+	Name             string     `json:"name"`
+	MimeType         string     `json:"mimeType"`
+	Size             int64      `json:"size"`
+	MessageID        *string    `json:"messageId,omitempty"`
+	Downloads        int64      `json:"downloads"`
+	LastDownloadedAt *time.Time `json:"lastDownloadedAt,omitempty"`
+	CreatedAt        time.Time  `json:"createdAt"`
+}
+
+type AdminStatsResponse struct {
+	MediaDownloads int64               `json:"mediaDownloads"`
+	TopMedia       []MediaDownloadStat `json:"topMedia"`
+	Commands       []ws.CommandStat    `json:"commands"`
+	Latencies      []ws.ClientLatency  `json:"latencies"`
+}
+
+// GET /api/v1/admin/stats
+func (h *AdminHandler) GetStats(w http.ResponseWriter, r *http.Request) {
+	limit := defaultTopMediaLimit
+	if raw := strings.TrimSpace(r.URL.Query().Get("limit")); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed < 1 || parsed > maxTopMediaLimit {
+			badRequest(w, fmt.Sprintf("Query parameter 'limit' must be between 1 and %d", maxTopMediaLimit))
+			return
+		}
+		limit = parsed
+	}
+
+	total, err := h.queries.SumBlobDownloads(r.Context())
+	if err != nil {
+		slog.Error("error summing media downloads", "error", err)
+		internalError(w)
+		return
+	}
+	rows, err := h.queries.ListTopDownloadedBlobs(r.Context(), int64(limit))
+	if err != nil {
+		slog.Error("error listing top downloaded media", "error", err)
+		internalError(w)
+		return
+	}
+
+	topMedia := make([]MediaDownloadStat, 0, len(rows))
+	for _, row := range rows {
+		topMedia = append(topMedia, MediaDownloadStat{
+			ID:               row.ID,
+			Kind:             row.Kind,
+			Name:             row.OriginalName,
+			MimeType:         row.MimeType,
+			Size:             row.SizeBytes,
+			MessageID:        row.MessageID,
+			Downloads:        row.DownloadCount,
+			LastDownloadedAt: row.LastDownloadedAt,
+			CreatedAt:        row.CreatedAt,
+		})
+	}
+
+	commands := []ws.CommandStat{}
+	latencies := []ws.ClientLatency{}
+	if h.hub != nil {
+		commands = h.hub.CommandStats()
+		latencies = h.hub.ClientLatencies()
+	}
+
+	writeJSON(w, http.StatusOK, AdminStatsResponse{
+		MediaDownloads: total,
+		TopMedia:       topMedia,
+		Commands:       commands,
+		Latencies:      latencies,
+	})
+}
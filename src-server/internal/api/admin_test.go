@@ -0,0 +1,229 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"lobby/internal/auth"
+	sqldb "lobby/internal/db/sqlc"
+	"lobby/internal/logging"
+	"lobby/internal/models"
+)
+
+func registrationDomainRequest(handler http.HandlerFunc, method, domain string) *httptest.ResponseRecorder {
+	return serveRequest(handler, newAuthedRequest(method, "/api/v1/admin/registration/domains/"+domain, "", "usr_admin", map[string]string{"domain": domain}))
+}
+
+func TestRegistrationDomainLifecycle(t *testing.T) {
+	database := openTestDB(t)
+	if err := database.Queries().CreateUser(context.Background(), sqldb.CreateUserParams{
+		ID:        "usr_admin",
+		Username:  "admin",
+		Email:     "admin@example.com",
+		CreatedAt: time.Now().UTC(),
+	}); err != nil {
+		t.Fatalf("CreateUser() error = %v", err)
+	}
+	handler := NewAdminHandler(database.Queries(), nil)
+
+	if rr := registrationDomainRequest(handler.AddRegistrationDomain, http.MethodPut, "Example.COM"); rr.Code != http.StatusNoContent {
+		t.Fatalf("add status = %d, want %d, body=%q", rr.Code, http.StatusNoContent, rr.Body.String())
+	}
+	if rr := registrationDomainRequest(handler.AddRegistrationDomain, http.MethodPut, "example.com"); rr.Code != http.StatusNoContent {
+		t.Fatalf("repeat add status = %d, want %d, body=%q", rr.Code, http.StatusNoContent, rr.Body.String())
+	}
+	if rr := registrationDomainRequest(handler.AddRegistrationDomain, http.MethodPut, "not_a_domain"); rr.Code != http.StatusBadRequest {
+		t.Fatalf("invalid add status = %d, want %d, body=%q", rr.Code, http.StatusBadRequest, rr.Body.String())
+	}
+
+	rr := httptest.NewRecorder()
+	handler.ListRegistrationDomains(rr, httptest.NewRequest(http.MethodGet, "/api/v1/admin/registration/domains", nil))
+	var domains []RegistrationDomainResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &domains); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v, body=%q", err, rr.Body.String())
+	}
+	if len(domains) != 1 || domains[0].Domain != "example.com" {
+		t.Fatalf("unexpected domains: %+v", domains)
+	}
+
+	if rr := registrationDomainRequest(handler.RemoveRegistrationDomain, http.MethodDelete, "example.com"); rr.Code != http.StatusNoContent {
+		t.Fatalf("remove status = %d, want %d, body=%q", rr.Code, http.StatusNoContent, rr.Body.String())
+	}
+	if rr := registrationDomainRequest(handler.RemoveRegistrationDomain, http.MethodDelete, "example.com"); rr.Code != http.StatusNotFound {
+		t.Fatalf("repeat remove status = %d, want %d, body=%q", rr.Code, http.StatusNotFound, rr.Body.String())
+	}
+}
+
+func TestVerifyMagicCodeEnforcesRegistrationMode(t *testing.T) {
+	tests := []struct {
+		name     string
+		mode     string
+		email    string
+		wantCode int
+	}{
+		{name: "open", mode: models.RegistrationOpen, email: "new@other.org", wantCode: http.StatusOK},
+		{name: "invite only", mode: models.RegistrationInviteOnly, email: "new@example.com", wantCode: http.StatusForbidden},
+		{name: "allowlisted domain", mode: models.RegistrationAllowlist, email: "new@example.com", wantCode: http.StatusOK},
+		{name: "unlisted domain", mode: models.RegistrationAllowlist, email: "new@other.org", wantCode: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			database := openTestDB(t)
+			queries := database.Queries()
+			now := time.Now().UTC()
+			if err := queries.CreateRegistrationDomain(context.Background(), sqldb.CreateRegistrationDomainParams{
+				Domain:    "example.com",
+				CreatedAt: now,
+			}); err != nil {
+				t.Fatalf("CreateRegistrationDomain() error = %v", err)
+			}
+			if err := queries.CreateMagicCode(context.Background(), sqldb.CreateMagicCodeParams{
+				ID:        "mgc_1",
+				Email:     tt.email,
+				CodeHash:  auth.HashMagicCode(tt.email, "123456"),
+				ExpiresAt: now.Add(time.Minute),
+				CreatedAt: now,
+			}); err != nil {
+				t.Fatalf("CreateMagicCode() error = %v", err)
+			}
+
+			handler := NewAuthHandler(database, queries, nil, nil, nil, time.Minute, tt.mode, "", nil, nil)
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/login/magic-code/verify",
+				strings.NewReader(`{"email":"`+tt.email+`","code":"123456"}`))
+			rr := httptest.NewRecorder()
+			handler.VerifyMagicCode(rr, req)
+
+			if rr.Code != tt.wantCode {
+				t.Fatalf("status = %d, want %d, body=%q", rr.Code, tt.wantCode, rr.Body.String())
+			}
+			if tt.wantCode == http.StatusForbidden && !strings.Contains(rr.Body.String(), ErrCodeRegistrationClosed) {
+				t.Fatalf("body = %q, want %s", rr.Body.String(), ErrCodeRegistrationClosed)
+			}
+		})
+	}
+}
+
+func waitForAdminJob(t *testing.T, handler *AdminHandler, jobID string) AdminJobResponse {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		job, ok := handler.jobs.get(jobID)
+		if !ok {
+			t.Fatalf("job %s not found", jobID)
+		}
+		if job.Status != AdminJobRunning {
+			return job
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("job %s did not finish", jobID)
+	return AdminJobResponse{}
+}
+
+func TestPruneInactiveJobDeactivatesIdleMembers(t *testing.T) {
+	database := openTestDB(t)
+	queries := database.Queries()
+	now := time.Now().UTC()
+	old := now.AddDate(0, 0, -90)
+	for _, user := range []sqldb.CreateUserParams{
+		{ID: "usr_admin", Username: "admin", Email: "admin@example.com", CreatedAt: old},
+		{ID: "usr_idle", Username: "idle", Email: "idle@example.com", CreatedAt: old},
+		{ID: "usr_chatty", Username: "chatty", Email: "chatty@example.com", CreatedAt: old},
+		{ID: "usr_new", Username: "new", Email: "new@example.com", CreatedAt: now},
+	} {
+		if err := queries.CreateUser(context.Background(), user); err != nil {
+			t.Fatalf("CreateUser() error = %v", err)
+		}
+	}
+	if _, err := queries.SetUserRole(context.Background(), sqldb.SetUserRoleParams{
+		Role:      models.RoleAdmin,
+		UpdatedAt: &now,
+		ID:        "usr_admin",
+	}); err != nil {
+		t.Fatalf("SetUserRole() error = %v", err)
+	}
+	if err := queries.CreateMessage(context.Background(), sqldb.CreateMessageParams{
+		ID:        "msg_1",
+		AuthorID:  "usr_chatty",
+		Content:   "still here",
+		CreatedAt: now,
+	}); err != nil {
+		t.Fatalf("CreateMessage() error = %v", err)
+	}
+	handler := NewAdminHandler(queries, nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/admin/jobs/prune-inactive", strings.NewReader(`{"inactiveDays":30}`))
+	rr := httptest.NewRecorder()
+	handler.StartPruneInactiveJob(rr, req.WithContext(context.WithValue(req.Context(), userIDKey, "usr_admin")))
+	if rr.Code != http.StatusAccepted {
+		t.Fatalf("status = %d, want %d, body=%q", rr.Code, http.StatusAccepted, rr.Body.String())
+	}
+	var job AdminJobResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &job); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v, body=%q", err, rr.Body.String())
+	}
+
+	job = waitForAdminJob(t, handler, job.ID)
+	if job.Status != AdminJobCompleted || job.Total != 1 || job.Processed != 1 || job.Failed != 0 || job.FinishedAt == nil {
+		t.Fatalf("unexpected job: %+v", job)
+	}
+	for userID, wantActive := range map[string]bool{"usr_admin": true, "usr_idle": false, "usr_chatty": true, "usr_new": true} {
+		user, err := queries.GetUserByID(context.Background(), userID)
+		if err != nil {
+			t.Fatalf("GetUserByID(%s) error = %v", userID, err)
+		}
+		if active := user.DeactivatedAt == nil; active != wantActive {
+			t.Fatalf("%s active = %v, want %v", userID, active, wantActive)
+		}
+	}
+}
+
+func TestAssignRoleJobRejectsSelf(t *testing.T) {
+	handler := NewAdminHandler(openTestDB(t).Queries(), nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/admin/jobs/assign-role",
+		strings.NewReader(`{"userIds":["usr_other","usr_admin"],"role":"member"}`))
+	rr := httptest.NewRecorder()
+	handler.StartAssignRoleJob(rr, req.WithContext(context.WithValue(req.Context(), userIDKey, "usr_admin")))
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want %d, body=%q", rr.Code, http.StatusBadRequest, rr.Body.String())
+	}
+}
+
+func TestSetLogLevel(t *testing.T) {
+	handler := NewAdminHandler(nil, nil)
+	t.Cleanup(func() { logging.ClearLevel("sfu") })
+	put := func(body string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		handler.SetLogLevel(rr, httptest.NewRequest(http.MethodPut, "/api/v1/admin/log-levels", strings.NewReader(body)))
+		return rr
+	}
+
+	rr := put(`{"component":"sfu","level":"debug"}`)
+	var resp LogLevelsResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v, body=%q", err, rr.Body.String())
+	}
+	if rr.Code != http.StatusOK || resp.Components["sfu"] != "debug" {
+		t.Fatalf("status = %d, levels = %+v, want sfu at debug", rr.Code, resp)
+	}
+
+	if rr := put(`{"component":"sfu","level":"verbose"}`); rr.Code != http.StatusBadRequest {
+		t.Fatalf("unknown level status = %d, want %d", rr.Code, http.StatusBadRequest)
+	}
+	if rr := put(`{"level":""}`); rr.Code != http.StatusBadRequest {
+		t.Fatalf("clearing the base level status = %d, want %d", rr.Code, http.StatusBadRequest)
+	}
+
+	put(`{"component":"sfu","level":""}`)
+	if _, components := logging.Levels(); len(components) != 0 {
+		t.Fatalf("components = %v after clearing sfu", components)
+	}
+}
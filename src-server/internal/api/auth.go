@@ -6,12 +6,16 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"html/template"
 	"log/slog"
 	"net/http"
+	"net/url"
+	"strconv"
 	"strings"
 	"time"
 
 	"lobby/internal/auth"
+	"lobby/internal/clock"
 	"lobby/internal/db"
 	sqldb "lobby/internal/db/sqlc"
 	"lobby/internal/email"
@@ -19,14 +23,29 @@ import (
 	"lobby/internal/ws"
 )
 
+// Per-address magic code throttling; see reserveMagicCodeSend.
+const (
+	defaultMagicCodeDailyLimit = 10
+	magicCodeThrottleWindow    = 24 * time.Hour
+	magicCodeBackoffBase       = 30 * time.Second
+	magicCodeBackoffMax        = time.Hour
+)
+
 type AuthHandler struct {
 	database     *db.DB
 	queries      *sqldb.Queries
 	jwtService   *auth.JWTService
 	magicService *auth.MagicCodeService
-	emailService *email.SMTPService
+	emailService email.Sender
 	magicCodeTTL time.Duration
+	registration string
+	baseURL      string
 	hub          *ws.Hub
+	ipResolver   *ClientIPResolver
+	clock        clock.Clock
+	challenges   *auth.ChallengeService
+	dailyCodes   int
+	firstAdmin   bool
 }
 
 func NewAuthHandler(
@@ -34,10 +53,16 @@ func NewAuthHandler(
 	queries *sqldb.Queries,
 	jwtService *auth.JWTService,
 	magicService *auth.MagicCodeService,
-	emailService *email.SMTPService,
+	emailService email.Sender,
 	magicCodeTTL time.Duration,
+	registrationMode string,
+	baseURL string,
 	hub *ws.Hub,
+	ipResolver *ClientIPResolver,
 ) *AuthHandler {
+	if ipResolver == nil {
+		ipResolver, _ = NewClientIPResolver(nil)
+	}
 	return &AuthHandler{
 		database:     database,
 		queries:      queries,
@@ -45,12 +70,78 @@ func NewAuthHandler(
 		magicService: magicService,
 		emailService: emailService,
 		magicCodeTTL: magicCodeTTL,
+		registration: registrationMode,
+		baseURL:      strings.TrimRight(baseURL, "/"),
 		hub:          hub,
+		ipResolver:   ipResolver,
+		clock:        clock.Real,
+		dailyCodes:   defaultMagicCodeDailyLimit,
+	}
+}
+
+// SetClock replaces the clock that decides whether codes and refresh tokens
+// have expired.
+func (h *AuthHandler) SetClock(c clock.Clock) {
+	h.clock = c
+}
+
+// SetMagicCodeDailyLimit caps how many codes one address can be sent per
+// throttle window.
+func (h *AuthHandler) SetMagicCodeDailyLimit(limit int) {
+	if limit > 0 {
+		h.dailyCodes = limit
+	}
+}
+
+// SetFirstUserAdmin makes the first non-bot account to register an admin,
+// so a fresh install has someone who can manage it.
+func (h *AuthHandler) SetFirstUserAdmin(enabled bool) {
+	h.firstAdmin = enabled
+}
+
+// SetChallengeService requires magic code requests to carry a solved
+// proof-of-work challenge when challenges is enabled.
+func (h *AuthHandler) SetChallengeService(challenges *auth.ChallengeService) {
+	h.challenges = challenges
+}
+
+// GET /api/v1/auth/login/challenge
+type MagicCodeChallengeResponse struct {
+	Required  bool       `json:"required"`
+	Challenge string     `json:"challenge,omitempty"`
+	Bits      int        `json:"bits,omitempty"`
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+}
+
+// GetMagicCodeChallenge issues the puzzle a client solves before requesting a
+// magic code. Required is false when the server does not ask for one.
+func (h *AuthHandler) GetMagicCodeChallenge(w http.ResponseWriter, r *http.Request) {
+	if !h.challenges.Enabled() {
+		writeJSON(w, http.StatusOK, MagicCodeChallengeResponse{Required: false})
+		return
 	}
+
+	challenge, err := h.challenges.Issue()
+	if err != nil {
+		slog.Error("error issuing magic code challenge", "error", err)
+		internalError(w)
+		return
+	}
+	writeJSON(w, http.StatusOK, MagicCodeChallengeResponse{
+		Required:  true,
+		Challenge: challenge.Token,
+		Bits:      challenge.Bits,
+		ExpiresAt: &challenge.ExpiresAt,
+	})
 }
 
+// POST /api/v1/auth/login/magic-code
+// Challenge and Nonce are required when GET /api/v1/auth/login/challenge
+// reports one; the nonce must solve it for the normalized email.
 type MagicCodeRequest struct {
-	Email string `json:"email" validate:"required,max=254"`
+	Email     string `json:"email" validate:"required,max=254"`
+	Challenge string `json:"challenge,omitempty" validate:"max=256"`
+	Nonce     string `json:"nonce,omitempty" validate:"max=64"`
 }
 
 type MagicCodeResponse struct {
@@ -60,7 +151,7 @@ type MagicCodeResponse struct {
 func (h *AuthHandler) RequestMagicCode(w http.ResponseWriter, r *http.Request) {
 	var req MagicCodeRequest
 	if err := decodeAndValidate(r.Body, &req); err != nil {
-		badRequest(w, err.Error())
+		writeDecodeError(w, err)
 		return
 	}
 
@@ -73,6 +164,24 @@ func (h *AuthHandler) RequestMagicCode(w http.ResponseWriter, r *http.Request) {
 		badRequest(w, "invalid email format")
 		return
 	}
+	if h.challenges.Enabled() {
+		if err := h.challenges.Verify(req.Challenge, req.Email, req.Nonce); err != nil {
+			writeError(w, http.StatusForbidden, ErrCodeChallengeFailed, "Solve a new challenge and try again")
+			return
+		}
+	}
+
+	retryAt, err := h.reserveMagicCodeSend(r.Context(), req.Email)
+	if err != nil {
+		slog.Error("error checking magic code throttle", "error", err)
+		internalError(w)
+		return
+	}
+	if !retryAt.IsZero() {
+		w.Header().Set("Retry-After", strconv.Itoa(retryAfterSeconds(retryAt.Sub(h.clock.Now()))))
+		writeError(w, http.StatusTooManyRequests, ErrCodeRateLimited, "Too many login codes requested for this address")
+		return
+	}
 
 	code, err := h.magicService.GenerateCode()
 	if err != nil {
@@ -103,7 +212,8 @@ func (h *AuthHandler) RequestMagicCode(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := h.emailService.SendMagicCode(req.Email, code, h.magicCodeTTL); err != nil {
+	link := h.baseURL + "/api/v1/auth/login/magic-link?token=" + url.QueryEscape(h.magicService.LinkToken(magicCodeID))
+	if err := h.emailService.SendMagicCode(req.Email, code, link, h.magicCodeTTL); err != nil {
 		slog.Error("error sending magic code email", "error", err)
 		// Intentionally not returning error to client - prevents email enumeration attacks.
 	}
@@ -113,10 +223,68 @@ func (h *AuthHandler) RequestMagicCode(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// reserveMagicCodeSend records a code sent to email, or returns when the
+// next one may be sent if the address is throttled. Each code in a window
+// doubles the wait before the next, and the window has a hard cap, so
+// requests spread across many IPs still cannot flood one inbox.
+func (h *AuthHandler) reserveMagicCodeSend(ctx context.Context, email string) (time.Time, error) {
+	tx, err := h.database.BeginTx(ctx, nil)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+	qtx := h.queries.WithTx(tx)
+
+	now := h.clock.Now().UTC()
+	emailHash := auth.HashEmail(email)
+	throttle, err := qtx.GetMagicCodeThrottle(ctx, emailHash)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return time.Time{}, fmt.Errorf("loading throttle: %w", err)
+	}
+	if errors.Is(err, sql.ErrNoRows) || !now.Before(throttle.WindowEndsAt) {
+		throttle = sqldb.MagicCodeThrottle{
+			EmailHash:     emailHash,
+			WindowEndsAt:  now.Add(magicCodeThrottleWindow),
+			NextAllowedAt: now,
+		}
+	}
+	if now.Before(throttle.NextAllowedAt) {
+		return throttle.NextAllowedAt, nil
+	}
+	if throttle.SentCount >= int64(h.dailyCodes) {
+		return throttle.WindowEndsAt, nil
+	}
+
+	throttle.SentCount++
+	if err := qtx.UpsertMagicCodeThrottle(ctx, sqldb.UpsertMagicCodeThrottleParams{
+		EmailHash:     emailHash,
+		WindowEndsAt:  throttle.WindowEndsAt,
+		SentCount:     throttle.SentCount,
+		NextAllowedAt: now.Add(magicCodeBackoff(throttle.SentCount)),
+	}); err != nil {
+		return time.Time{}, fmt.Errorf("saving throttle: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return time.Time{}, fmt.Errorf("committing: %w", err)
+	}
+	return time.Time{}, nil
+}
+
+// magicCodeBackoff is the wait after the sent-th code in a window.
+func magicCodeBackoff(sent int64) time.Duration {
+	backoff := magicCodeBackoffBase
+	for i := int64(1); i < sent && backoff < magicCodeBackoffMax; i++ {
+		backoff *= 2
+	}
+	return min(backoff, magicCodeBackoffMax)
+}
+
 // POST /api/v1/auth/login/magic-code/verify
 type VerifyMagicCodeRequest struct {
-	Email string `json:"email" validate:"required,max=254"`
-	Code  string `json:"code" validate:"required,len=6,numeric"`
+	Email      string `json:"email" validate:"required,max=254"`
+	Code       string `json:"code" validate:"required,len=6,numeric"`
+	InviteCode string `json:"inviteCode" validate:"omitempty,max=64"`
+	DeviceName string `json:"deviceName" validate:"omitempty,max=64"`
 }
 
 type AuthResponse struct {
@@ -142,12 +310,15 @@ type RefreshResponse struct {
 type RegisterRequest struct {
 	RegistrationToken string `json:"registrationToken" validate:"required"`
 	Username          string `json:"username" validate:"required,min=3,max=32"`
+	DeviceName        string `json:"deviceName" validate:"omitempty,max=64"`
 }
 
 func (h *AuthHandler) VerifyMagicCode(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "no-store")
+
 	var req VerifyMagicCodeRequest
 	if err := decodeAndValidate(r.Body, &req); err != nil {
-		badRequest(w, err.Error())
+		writeDecodeError(w, err)
 		return
 	}
 
@@ -172,33 +343,138 @@ func (h *AuthHandler) VerifyMagicCode(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	valid := subtle.ConstantTimeCompare([]byte(auth.HashMagicCode(req.Email, req.Code)), []byte(magicCode.CodeHash)) == 1
+	if !h.consumeMagicCode(w, r, magicCode, valid, "Invalid code") {
+		return
+	}
+
+	h.completeMagicLogin(w, r, magicCode.Email, req.InviteCode, req.DeviceName)
+}
+
+// desktopLinkScheme is the URL scheme the desktop app registers with the OS.
+const desktopLinkScheme = "lobby"
+
+// GET /api/v1/auth/login/magic-link?token=
+//
+// ShowMagicLink is where the emailed link lands in a browser. It never
+// redeems the link, since mail scanners fetch links before the user does;
+// it hands the token to the desktop app through a lobby://magic-link deep
+// link instead, and the app redeems it with VerifyMagicLink.
+func (h *AuthHandler) ShowMagicLink(w http.ResponseWriter, r *http.Request) {
+	token := strings.TrimSpace(r.URL.Query().Get("token"))
+	_, valid := h.magicService.ParseLinkToken(token)
+	var page magicLinkPageData
+	if valid {
+		appLink := url.URL{
+			Scheme:   desktopLinkScheme,
+			Host:     "magic-link",
+			RawQuery: url.Values{"server": {h.baseURL}, "token": {token}}.Encode(),
+		}
+		// Built here from a validated token, so it is safe to emit unescaped
+		page.AppLink = template.URL(appLink.String())
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Referrer-Policy", "no-referrer")
+	w.Header().Set("Content-Security-Policy", "default-src 'none'")
+	status := http.StatusOK
+	if !valid {
+		status = http.StatusBadRequest
+	}
+	w.WriteHeader(status)
+	if err := magicLinkPage.Execute(w, page); err != nil {
+		slog.Debug("error writing magic link page", "error", err)
+	}
+}
+
+type magicLinkPageData struct {
+	AppLink template.URL
+}
+
+var magicLinkPage = template.Must(template.New("magic-link").Parse(`<!doctype html>
+<html lang="en">
+<head><meta charset="utf-8"><meta name="viewport" content="width=device-width, initial-scale=1"><title>Sign in to Lobby</title>
+{{with .AppLink}}<meta http-equiv="refresh" content="0;url={{.}}">{{end}}</head>
+<body>
+{{with .AppLink}}<h1>Opening Lobby</h1>
+<p>If Lobby doesn't open, <a href="{{.}}">open it here</a>. You can also paste this page's address into the code field in Lobby, or type the 6-digit code from the email.</p>
+<p>The link works only once.</p>
+{{else}}<h1>Invalid sign-in link</h1>
+<p>Request a new code from Lobby.</p>
+{{end}}</body>
+</html>
+`))
+
+type VerifyMagicLinkRequest struct {
+	Token      string `json:"token" validate:"required,max=512"`
+	InviteCode string `json:"inviteCode" validate:"omitempty,max=64"`
+	DeviceName string `json:"deviceName" validate:"omitempty,max=64"`
+}
+
+// POST /api/v1/auth/login/magic-link
+func (h *AuthHandler) VerifyMagicLink(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "no-store")
+
+	var req VerifyMagicLinkRequest
+	if err := decodeAndValidate(r.Body, &req); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+
+	codeID, ok := h.magicService.ParseLinkToken(strings.TrimSpace(req.Token))
+	if !ok {
+		writeError(w, http.StatusUnauthorized, ErrCodeAuthFailed, "Invalid link")
+		return
+	}
+	magicCode, err := h.queries.GetUnusedMagicCodeByID(r.Context(), codeID)
+	if errors.Is(err, sql.ErrNoRows) {
+		writeError(w, http.StatusUnauthorized, ErrCodeAuthFailed, "Link is invalid or has already been used")
+		return
+	}
+	if err != nil {
+		slog.Error("error finding magic code", "error", err)
+		internalError(w)
+		return
+	}
+
+	if !h.consumeMagicCode(w, r, magicCode, true, "Invalid link") {
+		return
+	}
+
+	h.completeMagicLogin(w, r, magicCode.Email, strings.TrimSpace(req.InviteCode), strings.TrimSpace(req.DeviceName))
+}
+
+// consumeMagicCode counts an attempt against magicCode and marks it used if
+// valid and unexpired. Otherwise it writes the error and returns false;
+// invalidMessage describes a failed check.
+func (h *AuthHandler) consumeMagicCode(w http.ResponseWriter, r *http.Request, magicCode sqldb.MagicCode, valid bool, invalidMessage string) bool {
 	newAttempts, err := h.queries.IncrementMagicCodeAttempts(r.Context(), sqldb.IncrementMagicCodeAttemptsParams{
 		ID:          magicCode.ID,
 		MaxAttempts: int64(auth.MaxAttempts),
 	})
 	if errors.Is(err, sql.ErrNoRows) {
 		writeError(w, http.StatusUnauthorized, ErrCodeAuthFailed, "Too many attempts")
-		return
+		return false
 	}
 	if err != nil {
 		slog.Error("error incrementing attempts", "error", err)
 		internalError(w)
-		return
+		return false
 	}
 	if newAttempts > int64(auth.MaxAttempts) {
 		writeError(w, http.StatusUnauthorized, ErrCodeAuthFailed, "Too many attempts")
-		return
+		return false
 	}
 
-	expectedHash := auth.HashMagicCode(req.Email, req.Code)
-	if subtle.ConstantTimeCompare([]byte(expectedHash), []byte(magicCode.CodeHash)) != 1 {
-		writeError(w, http.StatusUnauthorized, ErrCodeAuthFailed, "Invalid code")
-		return
+	if !valid {
+		writeError(w, http.StatusUnauthorized, ErrCodeAuthFailed, invalidMessage)
+		return false
 	}
 
-	if time.Now().After(magicCode.ExpiresAt) {
+	if h.clock.Now().After(magicCode.ExpiresAt) {
 		writeError(w, http.StatusUnauthorized, ErrCodeAuthExpired, "Code has expired")
-		return
+		return false
 	}
 
 	usedAt := time.Now().UTC()
@@ -209,15 +485,68 @@ func (h *AuthHandler) VerifyMagicCode(w http.ResponseWriter, r *http.Request) {
 	if err != nil {
 		slog.Error("error marking code used", "error", err)
 		internalError(w)
-		return
+		return false
 	}
 	if rowsAffected == 0 {
 		writeError(w, http.StatusUnauthorized, ErrCodeAuthFailed, "Code has already been used")
+		return false
+	}
+	// The owner proved access to the inbox, so lift the backoff.
+	if err := h.queries.DeleteMagicCodeThrottle(r.Context(), auth.HashEmail(magicCode.Email)); err != nil {
+		slog.Warn("error clearing magic code throttle", "error", err)
+	}
+
+	return true
+}
+
+// completeMagicLogin finishes a verified magic code or link for email: it
+// either signs the user in or, for a new address, issues a registration token.
+func (h *AuthHandler) completeMagicLogin(w http.ResponseWriter, r *http.Request, email, inviteCode, deviceName string) {
+	bans, err := h.queries.CountBansForIdentity(r.Context(), sqldb.CountBansForIdentityParams{
+		EmailHash: auth.HashEmail(email),
+	})
+	if err != nil {
+		slog.Error("error checking ban list", "error", err)
+		internalError(w)
+		return
+	}
+	if bans > 0 {
+		writeError(w, http.StatusForbidden, ErrCodeBanned, "You are banned from this server")
 		return
 	}
 
-	userRow, err := h.queries.GetUserByEmail(r.Context(), magicCode.Email)
+	userRow, err := h.queries.GetUserByEmail(r.Context(), email)
 	if errors.Is(err, sql.ErrNoRows) {
+		// A valid invite admits the new account regardless of registration mode;
+		// it is consumed at register time.
+		var registrationInvite *string
+		if code := strings.TrimSpace(inviteCode); code != "" {
+			now := time.Now().UTC()
+			if _, inviteErr := h.queries.GetUsableInvite(r.Context(), sqldb.GetUsableInviteParams{
+				Code: code,
+				Now:  &now,
+			}); errors.Is(inviteErr, sql.ErrNoRows) {
+				writeError(w, http.StatusForbidden, ErrCodeInviteInvalid, "Invite is invalid or has expired")
+				return
+			} else if inviteErr != nil {
+				slog.Error("error loading invite", "error", inviteErr)
+				internalError(w)
+				return
+			}
+			registrationInvite = &code
+		} else {
+			allowed, allowErr := h.registrationAllowed(r.Context(), email)
+			if allowErr != nil {
+				slog.Error("error checking registration allowlist", "error", allowErr)
+				internalError(w)
+				return
+			}
+			if !allowed {
+				writeError(w, http.StatusForbidden, ErrCodeRegistrationClosed, "Registration is closed for this email address")
+				return
+			}
+		}
+
 		registrationToken, tokenErr := auth.GenerateOpaqueToken(32)
 		if tokenErr != nil {
 			slog.Error("error generating registration token", "error", tokenErr)
@@ -235,11 +564,12 @@ func (h *AuthHandler) VerifyMagicCode(w http.ResponseWriter, r *http.Request) {
 		}
 
 		tokenErr = h.queries.CreateRegistrationToken(r.Context(), sqldb.CreateRegistrationTokenParams{
-			ID:        registrationTokenID,
-			Email:     magicCode.Email,
-			TokenHash: registrationTokenHash,
-			ExpiresAt: registrationExpiresAt.UTC(),
-			CreatedAt: time.Now().UTC(),
+			ID:         registrationTokenID,
+			Email:      email,
+			TokenHash:  registrationTokenHash,
+			ExpiresAt:  registrationExpiresAt.UTC(),
+			CreatedAt:  time.Now().UTC(),
+			InviteCode: registrationInvite,
 		})
 		if tokenErr != nil {
 			slog.Error("error storing registration token", "error", tokenErr)
@@ -265,7 +595,7 @@ func (h *AuthHandler) VerifyMagicCode(w http.ResponseWriter, r *http.Request) {
 	wasReactivated := false
 	if user.DeactivatedAt != nil {
 		updatedAt := time.Now().UTC()
-		rowsAffected, err = h.queries.ReactivateUser(r.Context(), sqldb.ReactivateUserParams{
+		rowsAffected, err := h.queries.ReactivateUser(r.Context(), sqldb.ReactivateUserParams{
 			UpdatedAt: &updatedAt,
 			ID:        user.ID,
 		})
@@ -288,11 +618,17 @@ func (h *AuthHandler) VerifyMagicCode(w http.ResponseWriter, r *http.Request) {
 		}
 		user = modelUserFromDBUser(userRow)
 		wasReactivated = true
+
+		// The purge skips active users, so a failure here only delays
+		// clearing the request.
+		if _, err := h.queries.DeleteAccountDeletion(r.Context(), user.ID); err != nil {
+			slog.Warn("error cancelling account deletion", "error", err, "user_id", user.ID)
+		}
 	}
 
 	if wasReactivated {
 		updatedAt := time.Now().UTC()
-		rowsAffected, err = h.queries.IncrementUserSessionVersion(r.Context(), sqldb.IncrementUserSessionVersionParams{
+		rowsAffected, err := h.queries.IncrementUserSessionVersion(r.Context(), sqldb.IncrementUserSessionVersionParams{
 			UpdatedAt: &updatedAt,
 			ID:        user.ID,
 		})
@@ -316,7 +652,7 @@ func (h *AuthHandler) VerifyMagicCode(w http.ResponseWriter, r *http.Request) {
 		user = modelUserFromDBUser(userRow)
 	}
 
-	authResponse, err := h.generateAuthResponse(r.Context(), user)
+	authResponse, err := h.generateAuthResponse(r, user, deviceName)
 	if err != nil {
 		slog.Error("error issuing auth tokens", "error", err, "user_id", user.ID)
 		internalError(w)
@@ -333,11 +669,29 @@ func (h *AuthHandler) VerifyMagicCode(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// registrationAllowed reports whether a new account may be created for email
+// under the configured registration mode. Existing accounts, including
+// deactivated ones, are never subject to it.
+func (h *AuthHandler) registrationAllowed(ctx context.Context, email string) (bool, error) {
+	switch h.registration {
+	case models.RegistrationInviteOnly:
+		return false, nil
+	case models.RegistrationAllowlist:
+		_, domain, ok := strings.Cut(email, "@")
+		if !ok {
+			return false, nil
+		}
+		count, err := h.queries.CountRegistrationDomains(ctx, domain)
+		return count > 0, err
+	}
+	return true, nil
+}
+
 // POST /api/v1/auth/register
 func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
 	var req RegisterRequest
 	if err := decodeAndValidate(r.Body, &req); err != nil {
-		badRequest(w, err.Error())
+		writeDecodeError(w, err)
 		return
 	}
 
@@ -382,8 +736,24 @@ func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	userID, err := db.GenerateID("usr")
+	if err != nil {
+		slog.Error("error generating user id", "error", err)
+		internalError(w)
+		return
+	}
+
+	tx, err := h.database.BeginTx(r.Context(), nil)
+	if err != nil {
+		slog.Error("error starting registration transaction", "error", err)
+		internalError(w)
+		return
+	}
+	defer tx.Rollback()
+	qtx := h.queries.WithTx(tx)
+
 	now = time.Now().UTC()
-	if _, err := h.queries.ConsumeValidRegistrationToken(r.Context(), sqldb.ConsumeValidRegistrationTokenParams{
+	if _, err := qtx.ConsumeValidRegistrationToken(r.Context(), sqldb.ConsumeValidRegistrationTokenParams{
 		UsedAt:    &now,
 		TokenHash: registrationTokenHash,
 		Now:       now,
@@ -397,15 +767,24 @@ func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	userID, err := db.GenerateID("usr")
-	if err != nil {
-		slog.Error("error generating user id", "error", err)
-		internalError(w)
-		return
+	if registrationToken.InviteCode != nil {
+		rowsAffected, err := qtx.UseInvite(r.Context(), sqldb.UseInviteParams{
+			Code: *registrationToken.InviteCode,
+			Now:  &now,
+		})
+		if err != nil {
+			slog.Error("error using invite", "error", err)
+			internalError(w)
+			return
+		}
+		if rowsAffected == 0 {
+			writeError(w, http.StatusForbidden, ErrCodeInviteInvalid, "Invite is invalid or has expired")
+			return
+		}
 	}
 
 	createdAt := time.Now().UTC()
-	err = h.queries.CreateUser(r.Context(), sqldb.CreateUserParams{
+	err = qtx.CreateUser(r.Context(), sqldb.CreateUserParams{
 		ID:        userID,
 		Username:  username,
 		Email:     email,
@@ -421,16 +800,48 @@ func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	role := models.RoleMember
+	if h.firstAdmin {
+		// Counting after the insert, inside the transaction, means only one
+		// of two concurrent first registrations sees itself alone.
+		humans, err := qtx.CountHumanUsers(r.Context())
+		if err != nil {
+			slog.Error("error counting users", "error", err)
+			internalError(w)
+			return
+		}
+		if humans == 1 {
+			if _, err := qtx.SetUserRole(r.Context(), sqldb.SetUserRoleParams{
+				Role:      models.RoleAdmin,
+				UpdatedAt: &createdAt,
+				ID:        userID,
+			}); err != nil {
+				slog.Error("error promoting first user", "error", err, "user_id", userID)
+				internalError(w)
+				return
+			}
+			role = models.RoleAdmin
+			slog.Info("first user registered as admin", "user_id", userID)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		slog.Error("error committing registration transaction", "error", err)
+		internalError(w)
+		return
+	}
+
 	user := &models.User{
 		ID:             userID,
 		Username:       username,
 		Email:          email,
+		Role:           role,
 		CreatedAt:      createdAt,
 		UpdatedAt:      nil,
 		SessionVersion: 1,
 	}
 
-	authResponse, err := h.generateAuthResponse(r.Context(), user)
+	authResponse, err := h.generateAuthResponse(r, user, req.DeviceName)
 	if err != nil {
 		slog.Error("error issuing auth tokens", "error", err, "user_id", user.ID)
 		internalError(w)
@@ -449,7 +860,7 @@ type RefreshRequest struct {
 func (h *AuthHandler) Refresh(w http.ResponseWriter, r *http.Request) {
 	var req RefreshRequest
 	if err := decodeAndValidate(r.Body, &req); err != nil {
-		badRequest(w, err.Error())
+		writeDecodeError(w, err)
 		return
 	}
 
@@ -470,7 +881,7 @@ func (h *AuthHandler) Refresh(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if time.Now().After(refreshToken.ExpiresAt) {
+	if h.clock.Now().After(refreshToken.ExpiresAt) {
 		writeError(w, http.StatusUnauthorized, ErrCodeAuthExpired, "Refresh token has expired")
 		return
 	}
@@ -487,14 +898,27 @@ func (h *AuthHandler) Refresh(w http.ResponseWriter, r *http.Request) {
 	}
 	user := modelUserFromDBUser(userRow)
 
-	tokenPair, newRefreshHash, err := h.jwtService.GenerateTokenPair(user)
+	// Tokens issued before sessions were tracked join a new session here.
+	sessionID, newSession := "", refreshToken.SessionID == nil
+	if newSession {
+		sessionID, err = db.GenerateID("ses")
+		if err != nil {
+			slog.Error("error generating session id", "error", err)
+			internalError(w)
+			return
+		}
+	} else {
+		sessionID = *refreshToken.SessionID
+	}
+
+	tokenPair, newRefreshHash, err := h.jwtService.GenerateTokenPair(user, sessionID)
 	if err != nil {
 		slog.Error("error generating refreshed token pair", "error", err)
 		internalError(w)
 		return
 	}
 
-	if err := h.rotateRefreshToken(r.Context(), refreshToken.ID, user.ID, newRefreshHash, h.jwtService.RefreshTokenExpiry()); err != nil {
+	if err := h.rotateRefreshToken(r, refreshToken.ID, user.ID, sessionID, newSession, newRefreshHash, h.jwtService.RefreshTokenExpiry()); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			writeError(w, http.StatusUnauthorized, ErrCodeAuthFailed, "Refresh token has already been used")
 			return
@@ -552,8 +976,16 @@ func (h *AuthHandler) Logout(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusOK, map[string]string{"message": "Logged out successfully"})
 }
 
-func (h *AuthHandler) generateAuthResponse(ctx context.Context, user *models.User) (*AuthResponse, error) {
-	tokenPair, refreshHash, err := h.jwtService.GenerateTokenPair(user)
+// generateAuthResponse starts a new login session named deviceName and
+// issues its first token pair.
+func (h *AuthHandler) generateAuthResponse(r *http.Request, user *models.User, deviceName string) (*AuthResponse, error) {
+	ctx := r.Context()
+	sessionID, err := db.GenerateID("ses")
+	if err != nil {
+		return nil, fmt.Errorf("generating session ID: %w", err)
+	}
+
+	tokenPair, refreshHash, err := h.jwtService.GenerateTokenPair(user, sessionID)
 	if err != nil {
 		return nil, err
 	}
@@ -563,18 +995,43 @@ func (h *AuthHandler) generateAuthResponse(ctx context.Context, user *models.Use
 		return nil, fmt.Errorf("generating refresh token ID: %w", err)
 	}
 
+	tx, err := h.database.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("starting session transaction: %w", err)
+	}
+	defer tx.Rollback()
+	qtx := h.queries.WithTx(tx)
+
+	now := time.Now().UTC()
+	userAgent, ipAddress := h.sessionClient(r)
+	if err := qtx.CreateUserSession(ctx, sqldb.CreateUserSessionParams{
+		ID:         sessionID,
+		UserID:     user.ID,
+		DeviceName: optionalString(deviceName),
+		UserAgent:  userAgent,
+		IpAddress:  ipAddress,
+		CreatedAt:  now,
+	}); err != nil {
+		return nil, fmt.Errorf("creating session: %w", err)
+	}
+
 	refreshExpiry := h.jwtService.RefreshTokenExpiry()
-	err = h.queries.CreateRefreshToken(ctx, sqldb.CreateRefreshTokenParams{
+	err = qtx.CreateRefreshToken(ctx, sqldb.CreateRefreshTokenParams{
 		ID:        refreshTokenID,
 		UserID:    user.ID,
 		TokenHash: refreshHash,
 		ExpiresAt: refreshExpiry.UTC(),
-		CreatedAt: time.Now().UTC(),
+		CreatedAt: now,
+		SessionID: &sessionID,
 	})
 	if err != nil {
 		return nil, err
 	}
 
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("committing session: %w", err)
+	}
+
 	return &AuthResponse{
 		User:         user,
 		AccessToken:  tokenPair.AccessToken,
@@ -583,13 +1040,19 @@ func (h *AuthHandler) generateAuthResponse(ctx context.Context, user *models.Use
 	}, nil
 }
 
+// rotateRefreshToken replaces the consumed refresh token with a new one in
+// the same session, creating the session first if newSession is set, and
+// records the client as the session's latest use.
 func (h *AuthHandler) rotateRefreshToken(
-	ctx context.Context,
+	r *http.Request,
 	consumedTokenID string,
 	userID string,
+	sessionID string,
+	newSession bool,
 	newTokenHash string,
 	newExpiresAt time.Time,
 ) error {
+	ctx := r.Context()
 	tx, err := h.database.BeginTx(ctx, nil)
 	if err != nil {
 		return fmt.Errorf("starting refresh token rotation transaction: %w", err)
@@ -610,6 +1073,27 @@ func (h *AuthHandler) rotateRefreshToken(
 		return sql.ErrNoRows
 	}
 
+	userAgent, ipAddress := h.sessionClient(r)
+	if newSession {
+		err = qtx.CreateUserSession(ctx, sqldb.CreateUserSessionParams{
+			ID:        sessionID,
+			UserID:    userID,
+			UserAgent: userAgent,
+			IpAddress: ipAddress,
+			CreatedAt: now,
+		})
+	} else {
+		err = qtx.TouchUserSession(ctx, sqldb.TouchUserSessionParams{
+			LastUsedAt: now,
+			UserAgent:  userAgent,
+			IpAddress:  ipAddress,
+			ID:         sessionID,
+		})
+	}
+	if err != nil {
+		return fmt.Errorf("recording session use: %w", err)
+	}
+
 	newID, err := db.GenerateID("rft")
 	if err != nil {
 		return fmt.Errorf("generating rotated refresh token ID: %w", err)
@@ -621,6 +1105,7 @@ func (h *AuthHandler) rotateRefreshToken(
 		TokenHash: newTokenHash,
 		ExpiresAt: newExpiresAt.UTC(),
 		CreatedAt: now,
+		SessionID: &sessionID,
 	})
 	if err != nil {
 		return fmt.Errorf("creating rotated refresh token: %w", err)
@@ -633,6 +1118,15 @@ func (h *AuthHandler) rotateRefreshToken(
 	return nil
 }
 
+// sessionClient describes the client behind r for the session list.
+func (h *AuthHandler) sessionClient(r *http.Request) (userAgent, ipAddress *string) {
+	agent := strings.TrimSpace(r.UserAgent())
+	if len(agent) > maxSessionUserAgentLength {
+		agent = agent[:maxSessionUserAgentLength]
+	}
+	return optionalString(agent), optionalString(h.ipResolver.Resolve(r))
+}
+
 func (h *AuthHandler) broadcastUserJoined(user *models.User) {
 	if user == nil {
 		return
@@ -640,15 +1134,16 @@ func (h *AuthHandler) broadcastUserJoined(user *models.User) {
 
 	h.hub.BroadcastDispatch(ws.EventUserJoined, ws.UserJoinedPayload{
 		Member: ws.MemberState{
-			ID:        user.ID,
-			Username:  user.Username,
-			Avatar:    user.GetAvatarURL(),
-			Status:    "offline",
-			InVoice:   false,
-			Muted:     false,
-			Deafened:  false,
-			Streaming: false,
-			CreatedAt: user.CreatedAt,
+			ID:             user.ID,
+			Username:       user.Username,
+			Avatar:         user.GetAvatarURL(),
+			AvatarAnimated: user.GetAvatarAnimatedURL(),
+			Status:         "offline",
+			InVoice:        false,
+			Muted:          false,
+			Deafened:       false,
+			Streaming:      false,
+			CreatedAt:      user.CreatedAt,
 		},
 	})
 }
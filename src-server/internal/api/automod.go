@@ -0,0 +1,130 @@
+package api
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"lobby/internal/db"
+	sqldb "lobby/internal/db/sqlc"
+	"lobby/internal/models"
+)
+
+// maxAutomodRules caps the server-wide automod rule count; every rule runs
+// against every message.
+const maxAutomodRules = 100
+
+type CreateAutomodRuleRequest struct {
+	Kind    string `json:"kind" validate:"required"`
+	Pattern string `json:"pattern" validate:"max=256"`
+	Action  string `json:"action" validate:"required"`
+}
+
+type AutomodRuleResponse struct {
+	ID        string    `json:"id"`
+	Kind      string    `json:"kind"`
+	Pattern   string    `json:"pattern"`
+	Action    string    `json:"action"`
+	CreatedBy *string   `json:"createdBy"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// GET /api/v1/moderation/automod/rules
+func (h *ModerationHandler) ListAutomodRules(w http.ResponseWriter, r *http.Request) {
+	rows, err := h.queries.ListAutomodRules(r.Context())
+	if err != nil {
+		slog.Error("error listing automod rules", "error", err)
+		internalError(w)
+		return
+	}
+
+	rules := make([]AutomodRuleResponse, 0, len(rows))
+	for _, row := range rows {
+		rules = append(rules, AutomodRuleResponse(row))
+	}
+	writeJSON(w, http.StatusOK, rules)
+}
+
+// POST /api/v1/moderation/automod/rules
+func (h *ModerationHandler) CreateAutomodRule(w http.ResponseWriter, r *http.Request) {
+	var req CreateAutomodRuleRequest
+	if err := decodeAndValidate(r.Body, &req); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+
+	compiled, err := models.CompileAutomodRule("", req.Kind, req.Pattern, req.Action)
+	if err != nil {
+		badRequest(w, "invalid kind, pattern, or action")
+		return
+	}
+
+	count, err := h.queries.CountAutomodRules(r.Context())
+	if err != nil {
+		slog.Error("error counting automod rules", "error", err)
+		internalError(w)
+		return
+	}
+	if count >= maxAutomodRules {
+		conflict(w, "Automod rule limit reached")
+		return
+	}
+
+	ruleID, err := db.GenerateID("amr")
+	if err != nil {
+		slog.Error("error generating automod rule id", "error", err)
+		internalError(w)
+		return
+	}
+	actorID := GetUserID(r)
+	rule := sqldb.AutomodRule{
+		ID:        ruleID,
+		Kind:      compiled.Kind,
+		Pattern:   compiled.Pattern,
+		Action:    compiled.Action,
+		CreatedBy: &actorID,
+		CreatedAt: time.Now().UTC(),
+	}
+	if err := h.queries.CreateAutomodRule(r.Context(), sqldb.CreateAutomodRuleParams(rule)); err != nil {
+		slog.Error("error creating automod rule", "error", err)
+		internalError(w)
+		return
+	}
+	h.reloadAutomodRules(r)
+
+	slog.Info("automod rule created", "rule_id", ruleID, "kind", rule.Kind, "action", rule.Action, "by", actorID)
+	writeJSON(w, http.StatusCreated, AutomodRuleResponse(rule))
+}
+
+// DELETE /api/v1/moderation/automod/rules/{ruleID}
+func (h *ModerationHandler) DeleteAutomodRule(w http.ResponseWriter, r *http.Request) {
+	ruleID := chi.URLParam(r, "ruleID")
+
+	rowsAffected, err := h.queries.DeleteAutomodRule(r.Context(), ruleID)
+	if err != nil {
+		slog.Error("error deleting automod rule", "error", err, "rule_id", ruleID)
+		internalError(w)
+		return
+	}
+	if rowsAffected == 0 {
+		notFound(w, "Automod rule not found")
+		return
+	}
+	h.reloadAutomodRules(r)
+
+	slog.Info("automod rule deleted", "rule_id", ruleID, "by", GetUserID(r))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// reloadAutomodRules refreshes the hub's rule cache. The change is already
+// committed, so a failed reload is logged rather than reported.
+func (h *ModerationHandler) reloadAutomodRules(r *http.Request) {
+	if h.hub == nil {
+		return
+	}
+	if err := h.hub.ReloadAutomodRules(r.Context()); err != nil {
+		slog.Error("error reloading automod rules", "error", err)
+	}
+}
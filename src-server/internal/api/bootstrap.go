@@ -0,0 +1,127 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"lobby/internal/models"
+	"lobby/internal/ws"
+)
+
+// BootstrapHandler serves everything a client needs to render its first
+// screen in one request, reusing the loaders behind the individual endpoints.
+type BootstrapHandler struct {
+	serverInfo *ServerInfoHandler
+	users      *UserHandler
+	channel    *ChannelHandler
+	messages   *MessageHandler
+	drafts     *DraftHandler
+	hub        *ws.Hub
+}
+
+func NewBootstrapHandler(
+	serverInfo *ServerInfoHandler,
+	users *UserHandler,
+	channel *ChannelHandler,
+	messages *MessageHandler,
+	drafts *DraftHandler,
+	hub *ws.Hub,
+) *BootstrapHandler {
+	return &BootstrapHandler{
+		serverInfo: serverInfo,
+		users:      users,
+		channel:    channel,
+		messages:   messages,
+		drafts:     drafts,
+		hub:        hub,
+	}
+}
+
+// BootstrapResponse combines GET /server/info, /users/me, /channel,
+// /channel/notifications, /drafts, /users/me/mutes, and the first page of
+// /messages with the current member list.
+type BootstrapResponse struct {
+	Server        ServerInfoResponse           `json:"server"`
+	User          *models.User                 `json:"user"`
+	Members       []ws.MemberState             `json:"members"`
+	Channel       ChannelResponse              `json:"channel"`
+	Notifications NotificationSettingsResponse `json:"notifications"`
+	Drafts        []DraftResponse              `json:"drafts"`
+	Mutes         []MuteResponse               `json:"mutes"`
+	// Messages is the newest history page, empty when the caller cannot
+	// access the channel.
+	Messages []*models.Message `json:"messages"`
+}
+
+// GET /api/v1/bootstrap
+func (h *BootstrapHandler) GetBootstrap(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := GetUserID(r)
+	role := GetUserRole(r)
+
+	userRow, err := h.users.queries.GetActiveUserByID(ctx, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		notFound(w, "User not found")
+		return
+	}
+	if err != nil {
+		slog.Error("error finding user", "error", err)
+		internalError(w)
+		return
+	}
+	resp := BootstrapResponse{
+		User:     modelUserFromDBUser(userRow),
+		Members:  []ws.MemberState{},
+		Messages: []*models.Message{},
+	}
+
+	if resp.Server, err = h.serverInfo.loadInfo(ctx, role); err != nil {
+		slog.Error("error loading server settings", "error", err)
+		internalError(w)
+		return
+	}
+	channelRow, err := h.channel.queries.GetTextChannel(ctx)
+	if err != nil {
+		slog.Error("error loading text channel", "error", err)
+		internalError(w)
+		return
+	}
+	resp.Channel = channelResponseFromDB(channelRow)
+	if resp.Notifications, err = h.channel.loadNotificationSettings(ctx, userID); err != nil {
+		slog.Error("error loading notification settings", "error", err, "user_id", userID)
+		internalError(w)
+		return
+	}
+	if resp.Drafts, err = h.drafts.loadDrafts(ctx, userID); err != nil {
+		slog.Error("error listing drafts", "error", err)
+		internalError(w)
+		return
+	}
+	if resp.Mutes, err = h.users.loadMutes(ctx, userID); err != nil {
+		slog.Error("error listing mutes", "error", err, "user_id", userID)
+		internalError(w)
+		return
+	}
+
+	allowed, err := canAccessTextChannel(ctx, h.messages.queries, userID, role)
+	if err != nil {
+		slog.Error("error checking text channel access", "error", err)
+		internalError(w)
+		return
+	}
+	if allowed {
+		if resp.Messages, err = h.messages.loadHistory(ctx, role, "", defaultMessageHistoryLimit); err != nil {
+			slog.Error("error loading message history", "error", err)
+			internalError(w)
+			return
+		}
+	}
+
+	if h.hub != nil {
+		resp.Members = h.hub.GetMemberSnapshot()
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
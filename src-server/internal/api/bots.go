@@ -0,0 +1,310 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"lobby/internal/auth"
+	"lobby/internal/db"
+	sqldb "lobby/internal/db/sqlc"
+	"lobby/internal/models"
+	"lobby/internal/ws"
+)
+
+// botEmailDomain gives bot accounts a unique email that can never receive a
+// magic code, so bots can only authenticate with API tokens.
+const botEmailDomain = "bots.invalid"
+
+type BotResponse struct {
+	ID        string    `json:"id"`
+	Username  string    `json:"username"`
+	CreatedAt time.Time `json:"createdAt"`
+	Active    bool      `json:"active"`
+}
+
+type CreateBotRequest struct {
+	Username string `json:"username" validate:"required,min=3,max=32"`
+}
+
+type BotTokenResponse struct {
+	ID         string     `json:"id"`
+	Name       string     `json:"name"`
+	Scopes     []string   `json:"scopes"`
+	CreatedBy  *string    `json:"createdBy"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	LastUsedAt *time.Time `json:"lastUsedAt,omitempty"`
+	RevokedAt  *time.Time `json:"revokedAt,omitempty"`
+}
+
+// CreateBotTokenResponse is the only response that carries the plaintext
+// token; the server keeps just its hash.
+type CreateBotTokenResponse struct {
+	BotTokenResponse
+	Token string `json:"token"`
+}
+
+type CreateBotTokenRequest struct {
+	Name   string   `json:"name" validate:"required,max=64"`
+	Scopes []string `json:"scopes" validate:"required,min=1,max=8,dive,required"`
+}
+
+func botResponse(row sqldb.User) BotResponse {
+	return BotResponse{
+		ID:        row.ID,
+		Username:  row.Username,
+		CreatedAt: row.CreatedAt,
+		Active:    row.DeactivatedAt == nil,
+	}
+}
+
+func botTokenResponse(row sqldb.BotToken) BotTokenResponse {
+	return BotTokenResponse{
+		ID:         row.ID,
+		Name:       row.Name,
+		Scopes:     models.ParseBotScopes(row.Scopes),
+		CreatedBy:  row.CreatedBy,
+		CreatedAt:  row.CreatedAt,
+		LastUsedAt: row.LastUsedAt,
+		RevokedAt:  row.RevokedAt,
+	}
+}
+
+// GET /api/v1/admin/bots
+func (h *AdminHandler) ListBots(w http.ResponseWriter, r *http.Request) {
+	rows, err := h.queries.ListBotUsers(r.Context())
+	if err != nil {
+		slog.Error("error listing bots", "error", err)
+		internalError(w)
+		return
+	}
+
+	bots := make([]BotResponse, 0, len(rows))
+	for _, row := range rows {
+		bots = append(bots, botResponse(row))
+	}
+	writeJSON(w, http.StatusOK, bots)
+}
+
+// POST /api/v1/admin/bots
+func (h *AdminHandler) CreateBot(w http.ResponseWriter, r *http.Request) {
+	var req CreateBotRequest
+	if err := decodeAndValidate(r.Body, &req); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+
+	username := strings.TrimSpace(req.Username)
+	if !usernameRegex.MatchString(username) {
+		badRequest(w, "Username must be 3-32 characters and contain only letters, numbers, underscores, and hyphens")
+		return
+	}
+
+	count, err := h.queries.CountUsersByUsername(r.Context(), username)
+	if err != nil {
+		slog.Error("error checking username availability", "error", err)
+		internalError(w)
+		return
+	}
+	if count > 0 {
+		conflict(w, "Username already taken")
+		return
+	}
+
+	botID, err := db.GenerateID("usr")
+	if err != nil {
+		slog.Error("error generating bot id", "error", err)
+		internalError(w)
+		return
+	}
+
+	err = h.queries.CreateBotUser(r.Context(), sqldb.CreateBotUserParams{
+		ID:        botID,
+		Username:  username,
+		Email:     botID + "@" + botEmailDomain,
+		CreatedAt: time.Now().UTC(),
+	})
+	if db.IsUniqueConstraintError(err) {
+		conflict(w, "Username already taken")
+		return
+	}
+	if err != nil {
+		slog.Error("error creating bot", "error", err)
+		internalError(w)
+		return
+	}
+
+	bot, err := h.queries.GetUserByID(r.Context(), botID)
+	if err != nil {
+		slog.Error("error loading created bot", "error", err, "bot_id", botID)
+		internalError(w)
+		return
+	}
+
+	if h.hub != nil {
+		h.hub.BroadcastDispatch(ws.EventUserJoined, ws.UserJoinedPayload{
+			Member: ws.MemberState{
+				ID:        bot.ID,
+				Username:  bot.Username,
+				Status:    "offline",
+				Role:      bot.Role,
+				CreatedAt: bot.CreatedAt,
+				Bot:       true,
+			},
+		})
+	}
+
+	slog.Info("bot created", "bot_id", botID, "username", username, "by", GetUserID(r))
+	writeJSON(w, http.StatusCreated, botResponse(bot))
+}
+
+// GET /api/v1/admin/bots/{botID}/tokens
+func (h *AdminHandler) ListBotTokens(w http.ResponseWriter, r *http.Request) {
+	bot, ok := h.loadBot(w, r)
+	if !ok {
+		return
+	}
+
+	rows, err := h.queries.ListBotTokens(r.Context(), bot.ID)
+	if err != nil {
+		slog.Error("error listing bot tokens", "error", err, "bot_id", bot.ID)
+		internalError(w)
+		return
+	}
+
+	tokens := make([]BotTokenResponse, 0, len(rows))
+	for _, row := range rows {
+		tokens = append(tokens, botTokenResponse(row))
+	}
+	writeJSON(w, http.StatusOK, tokens)
+}
+
+// POST /api/v1/admin/bots/{botID}/tokens
+func (h *AdminHandler) CreateBotToken(w http.ResponseWriter, r *http.Request) {
+	var req CreateBotTokenRequest
+	if err := decodeAndValidate(r.Body, &req); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+
+	scopes := make([]string, 0, len(req.Scopes))
+	seen := make(map[string]bool, len(req.Scopes))
+	for _, scope := range req.Scopes {
+		if !models.IsValidBotScope(scope) {
+			badRequest(w, "Unknown scope: "+scope)
+			return
+		}
+		if !seen[scope] {
+			seen[scope] = true
+			scopes = append(scopes, scope)
+		}
+	}
+
+	bot, ok := h.loadBot(w, r)
+	if !ok {
+		return
+	}
+	if bot.DeactivatedAt != nil {
+		notFound(w, "Bot not found")
+		return
+	}
+
+	token, err := auth.GenerateBotToken()
+	if err != nil {
+		slog.Error("error generating bot token", "error", err)
+		internalError(w)
+		return
+	}
+	tokenID, err := db.GenerateID("btk")
+	if err != nil {
+		slog.Error("error generating bot token id", "error", err)
+		internalError(w)
+		return
+	}
+
+	actorID := GetUserID(r)
+	row := sqldb.BotToken{
+		ID:        tokenID,
+		BotID:     bot.ID,
+		Name:      strings.TrimSpace(req.Name),
+		TokenHash: auth.HashBotToken(token),
+		Scopes:    models.FormatBotScopes(scopes),
+		CreatedBy: &actorID,
+		CreatedAt: time.Now().UTC(),
+	}
+	if err := h.queries.CreateBotToken(r.Context(), sqldb.CreateBotTokenParams{
+		ID:        row.ID,
+		BotID:     row.BotID,
+		Name:      row.Name,
+		TokenHash: row.TokenHash,
+		Scopes:    row.Scopes,
+		CreatedBy: row.CreatedBy,
+		CreatedAt: row.CreatedAt,
+	}); err != nil {
+		slog.Error("error creating bot token", "error", err, "bot_id", bot.ID)
+		internalError(w)
+		return
+	}
+
+	slog.Info("bot token created", "bot_id", bot.ID, "token_id", tokenID, "scopes", row.Scopes, "by", actorID)
+	writeJSON(w, http.StatusCreated, CreateBotTokenResponse{
+		BotTokenResponse: botTokenResponse(row),
+		Token:            token,
+	})
+}
+
+// DELETE /api/v1/admin/bots/{botID}/tokens/{tokenID}
+func (h *AdminHandler) RevokeBotToken(w http.ResponseWriter, r *http.Request) {
+	bot, ok := h.loadBot(w, r)
+	if !ok {
+		return
+	}
+
+	tokenID := chi.URLParam(r, "tokenID")
+	now := time.Now().UTC()
+	rowsAffected, err := h.queries.RevokeBotToken(r.Context(), sqldb.RevokeBotTokenParams{
+		RevokedAt: &now,
+		ID:        tokenID,
+		BotID:     bot.ID,
+	})
+	if err != nil {
+		slog.Error("error revoking bot token", "error", err, "bot_id", bot.ID, "token_id", tokenID)
+		internalError(w)
+		return
+	}
+	if rowsAffected == 0 {
+		notFound(w, "Token not found")
+		return
+	}
+
+	// The gateway does not track which token a connection used, so the bot
+	// reconnects with whichever tokens it still holds.
+	if h.hub != nil {
+		if client := h.hub.GetClient(bot.ID); client != nil {
+			client.Close()
+		}
+	}
+
+	slog.Info("bot token revoked", "bot_id", bot.ID, "token_id", tokenID, "by", GetUserID(r))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// loadBot resolves the {botID} URL parameter to a bot account.
+func (h *AdminHandler) loadBot(w http.ResponseWriter, r *http.Request) (sqldb.User, bool) {
+	bot, err := h.queries.GetUserByID(r.Context(), chi.URLParam(r, "botID"))
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && !bot.Bot) {
+		notFound(w, "Bot not found")
+		return sqldb.User{}, false
+	}
+	if err != nil {
+		slog.Error("error loading bot", "error", err)
+		internalError(w)
+		return sqldb.User{}, false
+	}
+	return bot, true
+}
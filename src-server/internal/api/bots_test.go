@@ -0,0 +1,84 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"lobby/internal/auth"
+	sqldb "lobby/internal/db/sqlc"
+)
+
+func botRequest(handler http.HandlerFunc, method, body string, params map[string]string) *httptest.ResponseRecorder {
+	return serveRequest(handler, newAuthedRequest(method, "/api/v1/admin/bots", body, "usr_admin", params))
+}
+
+func TestBotTokenLifecycle(t *testing.T) {
+	database := openTestDB(t)
+	if err := database.Queries().CreateUser(context.Background(), sqldb.CreateUserParams{
+		ID:        "usr_admin",
+		Username:  "admin",
+		Email:     "admin@example.com",
+		CreatedAt: time.Now().UTC(),
+	}); err != nil {
+		t.Fatalf("CreateUser() error = %v", err)
+	}
+	handler := NewAdminHandler(database.Queries(), nil)
+
+	rr := botRequest(handler.CreateBot, http.MethodPost, `{"username":"helper"}`, nil)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("create bot status = %d, want %d, body=%q", rr.Code, http.StatusCreated, rr.Body.String())
+	}
+	var bot BotResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &bot); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v", err)
+	}
+	if rr := botRequest(handler.CreateBot, http.MethodPost, `{"username":"helper"}`, nil); rr.Code != http.StatusConflict {
+		t.Fatalf("duplicate bot status = %d, want %d", rr.Code, http.StatusConflict)
+	}
+
+	botParams := map[string]string{"botID": bot.ID}
+	if rr := botRequest(handler.CreateBotToken, http.MethodPost, `{"name":"ci","scopes":["admin:all"]}`, botParams); rr.Code != http.StatusBadRequest {
+		t.Fatalf("unknown scope status = %d, want %d", rr.Code, http.StatusBadRequest)
+	}
+	if rr := botRequest(handler.CreateBotToken, http.MethodPost, `{"name":"ci","scopes":["messages:read"]}`, map[string]string{"botID": "usr_admin"}); rr.Code != http.StatusNotFound {
+		t.Fatalf("token for human status = %d, want %d", rr.Code, http.StatusNotFound)
+	}
+
+	rr = botRequest(handler.CreateBotToken, http.MethodPost, `{"name":"ci","scopes":["messages:read","messages:write","messages:read"]}`, botParams)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("create token status = %d, want %d, body=%q", rr.Code, http.StatusCreated, rr.Body.String())
+	}
+	var created CreateBotTokenResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &created); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v", err)
+	}
+	if !auth.IsBotToken(created.Token) || len(created.Scopes) != 2 {
+		t.Fatalf("unexpected created token: %+v", created)
+	}
+
+	row, err := database.Queries().GetActiveBotTokenByHash(context.Background(), auth.HashBotToken(created.Token))
+	if err != nil || row.BotID != bot.ID {
+		t.Fatalf("GetActiveBotTokenByHash() = %+v, %v", row, err)
+	}
+
+	rr = botRequest(handler.ListBotTokens, http.MethodGet, "", botParams)
+	if strings.Contains(rr.Body.String(), created.Token) {
+		t.Fatal("token list leaked the plaintext token")
+	}
+
+	tokenParams := map[string]string{"botID": bot.ID, "tokenID": created.ID}
+	if rr := botRequest(handler.RevokeBotToken, http.MethodDelete, "", tokenParams); rr.Code != http.StatusNoContent {
+		t.Fatalf("revoke status = %d, want %d, body=%q", rr.Code, http.StatusNoContent, rr.Body.String())
+	}
+	if rr := botRequest(handler.RevokeBotToken, http.MethodDelete, "", tokenParams); rr.Code != http.StatusNotFound {
+		t.Fatalf("repeat revoke status = %d, want %d", rr.Code, http.StatusNotFound)
+	}
+	if _, err := database.Queries().GetActiveBotTokenByHash(context.Background(), auth.HashBotToken(created.Token)); err == nil {
+		t.Fatal("expected revoked token to stop resolving")
+	}
+}
@@ -0,0 +1,419 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"lobby/internal/constants"
+	"lobby/internal/db"
+	sqldb "lobby/internal/db/sqlc"
+	"lobby/internal/models"
+	"lobby/internal/ws"
+)
+
+const textChannelID = constants.TextChannelID
+
+type ChannelHandler struct {
+	database *db.DB
+	queries  *sqldb.Queries
+	hub      *ws.Hub
+}
+
+func NewChannelHandler(database *db.DB, queries *sqldb.Queries, hub *ws.Hub) *ChannelHandler {
+	return &ChannelHandler{database: database, queries: queries, hub: hub}
+}
+
+type ChannelResponse struct {
+	Name        string    `json:"name"`
+	Topic       string    `json:"topic"`
+	Description string    `json:"description"`
+	Private     bool      `json:"private"`
+	Archived    bool      `json:"archived"`
+	SlowMode    int64     `json:"slowModeSeconds"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+}
+
+type UpdateChannelRequest struct {
+	Name        *string `json:"name" validate:"omitnil,max=64"`
+	Topic       *string `json:"topic" validate:"omitnil,max=256"`
+	Description *string `json:"description" validate:"omitnil,max=1024"`
+	Private     *bool   `json:"private"`
+	Archived    *bool   `json:"archived"`
+	SlowMode    *int64  `json:"slowModeSeconds" validate:"omitnil,min=0,max=21600"`
+}
+
+type NotificationSettingsResponse struct {
+	Level            string `json:"level"`
+	SuppressEveryone bool   `json:"suppressEveryone"` // ignore @everyone and @here
+	SuppressRoles    bool   `json:"suppressRoles"`    // ignore role mentions such as @moderators
+}
+
+// UpdateNotificationSettingsRequest changes only the fields it sets.
+type UpdateNotificationSettingsRequest struct {
+	Level            string `json:"level" validate:"omitempty,oneof=all mentions muted"`
+	SuppressEveryone *bool  `json:"suppressEveryone"`
+	SuppressRoles    *bool  `json:"suppressRoles"`
+}
+
+type ChannelMembersResponse struct {
+	UserIDs []string `json:"userIds"`
+}
+
+func channelResponseFromDB(row sqldb.TextChannel) ChannelResponse {
+	return ChannelResponse{
+		Name:        row.Name,
+		Topic:       row.Topic,
+		Description: row.Description,
+		Private:     row.Private,
+		Archived:    row.Archived,
+		SlowMode:    row.SlowModeSeconds,
+		UpdatedAt:   row.UpdatedAt,
+	}
+}
+
+// canAccessTextChannel applies the private channel ACL for a REST caller.
+func canAccessTextChannel(ctx context.Context, queries *sqldb.Queries, userID, role string) (bool, error) {
+	channel, err := queries.GetTextChannel(ctx)
+	if err != nil {
+		return false, err
+	}
+	if !channel.Private {
+		return true, nil
+	}
+	count, err := queries.CountTextChannelMember(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+	return models.CanAccessTextChannel(role, channel.Private, count > 0), nil
+}
+
+// GET /api/v1/channel
+func (h *ChannelHandler) GetChannel(w http.ResponseWriter, r *http.Request) {
+	row, err := h.queries.GetTextChannel(r.Context())
+	if errors.Is(err, sql.ErrNoRows) {
+		notFound(w, "Channel not found")
+		return
+	}
+	if err != nil {
+		slog.Error("error loading text channel", "error", err)
+		internalError(w)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, channelResponseFromDB(row))
+}
+
+// PATCH /api/v1/channel
+func (h *ChannelHandler) UpdateChannel(w http.ResponseWriter, r *http.Request) {
+	userID := GetUserID(r)
+	if userID == "" {
+		unauthorized(w, "User not found in context")
+		return
+	}
+
+	var req UpdateChannelRequest
+	if err := decodeAndValidate(r.Body, &req); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+
+	current, err := h.queries.GetTextChannel(r.Context())
+	if errors.Is(err, sql.ErrNoRows) {
+		notFound(w, "Channel not found")
+		return
+	}
+	if err != nil {
+		slog.Error("error loading text channel", "error", err)
+		internalError(w)
+		return
+	}
+
+	if req.Private != nil && !models.RoleAtLeast(GetUserRole(r), models.RoleModerator) {
+		forbidden(w, "Only moderators can change channel privacy")
+		return
+	}
+	if req.SlowMode != nil && !models.RoleAtLeast(GetUserRole(r), models.RoleModerator) {
+		forbidden(w, "Only moderators can change slow mode")
+		return
+	}
+	if req.Archived != nil && *req.Archived != current.Archived {
+		if !*req.Archived && !models.RoleAtLeast(GetUserRole(r), models.RoleAdmin) {
+			forbidden(w, "Only admins can unarchive the channel")
+			return
+		}
+		if *req.Archived && !models.RoleAtLeast(GetUserRole(r), models.RoleModerator) {
+			forbidden(w, "Only moderators can archive the channel")
+			return
+		}
+	}
+
+	params := sqldb.UpdateTextChannelParams{
+		Name:        current.Name,
+		Topic:       current.Topic,
+		Description: current.Description,
+		UpdatedBy:   &userID,
+		UpdatedAt:   time.Now().UTC(),
+	}
+	if req.Name != nil {
+		name := strings.TrimSpace(*req.Name)
+		if name == "" {
+			badRequest(w, "Channel name cannot be empty")
+			return
+		}
+		params.Name = name
+	}
+	if req.Topic != nil {
+		params.Topic = strings.TrimSpace(*req.Topic)
+	}
+	if req.Description != nil {
+		params.Description = strings.TrimSpace(*req.Description)
+	}
+	privateChanged := req.Private != nil && *req.Private != current.Private
+	archivedChanged := req.Archived != nil && *req.Archived != current.Archived
+	slowModeChanged := req.SlowMode != nil && *req.SlowMode != current.SlowModeSeconds
+	metadataChanged := params.Name != current.Name || params.Topic != current.Topic || params.Description != current.Description
+	if metadataChanged && !models.RoleAtLeast(GetUserRole(r), models.RoleModerator) {
+		forbidden(w, "Only moderators can edit the channel name, topic and description")
+		return
+	}
+
+	// An archived channel is read-only until it is unarchived.
+	if current.Archived && !archivedChanged && (metadataChanged || privateChanged || slowModeChanged) {
+		conflict(w, "Channel is archived")
+		return
+	}
+
+	if !metadataChanged && !privateChanged && !archivedChanged && !slowModeChanged {
+		writeJSON(w, http.StatusOK, channelResponseFromDB(current))
+		return
+	}
+
+	tx, err := h.database.BeginTx(r.Context(), nil)
+	if err != nil {
+		slog.Error("error starting channel update transaction", "error", err)
+		internalError(w)
+		return
+	}
+	defer tx.Rollback()
+
+	qtx := h.queries.WithTx(tx)
+
+	if metadataChanged {
+		if _, err := qtx.UpdateTextChannel(r.Context(), params); err != nil {
+			slog.Error("error updating text channel", "error", err, "user_id", userID)
+			internalError(w)
+			return
+		}
+	}
+	if privateChanged {
+		if _, err := qtx.SetTextChannelPrivate(r.Context(), sqldb.SetTextChannelPrivateParams{
+			Private:   *req.Private,
+			UpdatedBy: &userID,
+			UpdatedAt: params.UpdatedAt,
+		}); err != nil {
+			slog.Error("error updating text channel privacy", "error", err, "user_id", userID)
+			internalError(w)
+			return
+		}
+	}
+	if slowModeChanged {
+		if _, err := qtx.SetTextChannelSlowMode(r.Context(), sqldb.SetTextChannelSlowModeParams{
+			SlowModeSeconds: *req.SlowMode,
+			UpdatedBy:       &userID,
+			UpdatedAt:       params.UpdatedAt,
+		}); err != nil {
+			slog.Error("error updating text channel slow mode", "error", err, "user_id", userID)
+			internalError(w)
+			return
+		}
+	}
+	if archivedChanged {
+		if _, err := qtx.SetTextChannelArchived(r.Context(), sqldb.SetTextChannelArchivedParams{
+			Archived:  *req.Archived,
+			UpdatedBy: &userID,
+			UpdatedAt: params.UpdatedAt,
+		}); err != nil {
+			slog.Error("error updating text channel archive state", "error", err, "user_id", userID)
+			internalError(w)
+			return
+		}
+	}
+
+	updated, err := qtx.GetTextChannel(r.Context())
+	if err != nil {
+		slog.Error("error loading text channel", "error", err)
+		internalError(w)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		slog.Error("error committing channel update", "error", err)
+		internalError(w)
+		return
+	}
+
+	if privateChanged || archivedChanged || slowModeChanged {
+		h.reloadChannelAccess(r)
+	}
+
+	if h.hub != nil {
+		h.hub.BroadcastDispatch(ws.EventChannelUpdate, ws.ChannelUpdatePayload{
+			ChannelInfo: *ws.NewChannelInfo(updated),
+			UpdatedBy:   userID,
+		})
+	}
+
+	writeJSON(w, http.StatusOK, channelResponseFromDB(updated))
+}
+
+// GET /api/v1/channel/notifications
+func (h *ChannelHandler) GetNotificationSettings(w http.ResponseWriter, r *http.Request) {
+	userID := GetUserID(r)
+
+	settings, err := h.loadNotificationSettings(r.Context(), userID)
+	if err != nil {
+		slog.Error("error loading notification settings", "error", err, "user_id", userID)
+		internalError(w)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, settings)
+}
+
+// PUT /api/v1/channel/notifications
+func (h *ChannelHandler) UpdateNotificationSettings(w http.ResponseWriter, r *http.Request) {
+	userID := GetUserID(r)
+
+	var req UpdateNotificationSettingsRequest
+	if err := decodeAndValidate(r.Body, &req); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+	if req.Level == "" && req.SuppressEveryone == nil && req.SuppressRoles == nil {
+		badRequest(w, "level, suppressEveryone, or suppressRoles is required")
+		return
+	}
+
+	settings, err := h.loadNotificationSettings(r.Context(), userID)
+	if err != nil {
+		slog.Error("error loading notification settings", "error", err, "user_id", userID)
+		internalError(w)
+		return
+	}
+	if req.Level != "" {
+		settings.Level = req.Level
+	}
+	if req.SuppressEveryone != nil {
+		settings.SuppressEveryone = *req.SuppressEveryone
+	}
+	if req.SuppressRoles != nil {
+		settings.SuppressRoles = *req.SuppressRoles
+	}
+
+	if err := h.queries.UpsertChannelNotificationSettings(r.Context(), sqldb.UpsertChannelNotificationSettingsParams{
+		UserID:           userID,
+		ChannelID:        textChannelID,
+		Level:            settings.Level,
+		SuppressEveryone: settings.SuppressEveryone,
+		SuppressRoles:    settings.SuppressRoles,
+		UpdatedAt:        time.Now().UTC(),
+	}); err != nil {
+		slog.Error("error saving notification settings", "error", err, "user_id", userID)
+		internalError(w)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, settings)
+}
+
+// loadNotificationSettings returns userID's settings for the text channel,
+// falling back to the defaults when none are stored.
+func (h *ChannelHandler) loadNotificationSettings(ctx context.Context, userID string) (NotificationSettingsResponse, error) {
+	row, err := h.queries.GetChannelNotificationSettings(ctx, sqldb.GetChannelNotificationSettingsParams{
+		UserID:    userID,
+		ChannelID: textChannelID,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return NotificationSettingsResponse{Level: models.NotificationAll}, nil
+	}
+	if err != nil {
+		return NotificationSettingsResponse{}, err
+	}
+	return NotificationSettingsResponse(row), nil
+}
+
+// GET /api/v1/channel/members
+func (h *ChannelHandler) ListMembers(w http.ResponseWriter, r *http.Request) {
+	userIDs, err := h.queries.ListTextChannelMemberIDs(r.Context())
+	if err != nil {
+		slog.Error("error listing text channel members", "error", err)
+		internalError(w)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, ChannelMembersResponse{UserIDs: userIDs})
+}
+
+// PUT /api/v1/channel/members/{userID}
+func (h *ChannelHandler) AddMember(w http.ResponseWriter, r *http.Request) {
+	actorID := GetUserID(r)
+	targetID := chi.URLParam(r, "userID")
+
+	if _, err := h.queries.GetActiveUserByID(r.Context(), targetID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			notFound(w, "User not found")
+			return
+		}
+		slog.Error("error finding user", "error", err)
+		internalError(w)
+		return
+	}
+
+	if err := h.queries.AddTextChannelMember(r.Context(), sqldb.AddTextChannelMemberParams{
+		UserID:  targetID,
+		AddedBy: &actorID,
+		AddedAt: time.Now().UTC(),
+	}); err != nil {
+		slog.Error("error adding text channel member", "error", err, "user_id", targetID)
+		internalError(w)
+		return
+	}
+
+	h.reloadChannelAccess(r)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// DELETE /api/v1/channel/members/{userID}
+func (h *ChannelHandler) RemoveMember(w http.ResponseWriter, r *http.Request) {
+	targetID := chi.URLParam(r, "userID")
+
+	rowsAffected, err := h.queries.RemoveTextChannelMember(r.Context(), targetID)
+	if err != nil {
+		slog.Error("error removing text channel member", "error", err, "user_id", targetID)
+		internalError(w)
+		return
+	}
+	if rowsAffected == 0 {
+		notFound(w, "User is not a channel member")
+		return
+	}
+
+	h.reloadChannelAccess(r)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *ChannelHandler) reloadChannelAccess(r *http.Request) {
+	if h.hub == nil {
+		return
+	}
+	if err := h.hub.ReloadChannelAccess(r.Context()); err != nil {
+		slog.Error("error reloading text channel access", "error", err)
+	}
+}
@@ -0,0 +1,363 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	sqldb "lobby/internal/db/sqlc"
+	"lobby/internal/models"
+)
+
+func TestGetChannelReturnsDefaultChannel(t *testing.T) {
+	database := openTestDB(t)
+	handler := NewChannelHandler(database, database.Queries(), nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/channel", nil)
+	rr := httptest.NewRecorder()
+
+	handler.GetChannel(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d, body=%q", rr.Code, http.StatusOK, rr.Body.String())
+	}
+
+	var resp ChannelResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v, body=%q", err, rr.Body.String())
+	}
+	if resp.Name != "general" {
+		t.Fatalf("name = %q, want %q", resp.Name, "general")
+	}
+}
+
+func TestUpdateChannelRejectsInvalidInput(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+	}{
+		{name: "blank_name", body: `{"name":"   "}`},
+		{name: "topic_too_long", body: `{"topic":"` + strings.Repeat("a", 257) + `"}`},
+		{name: "unknown_field", body: `{"color":"red"}`},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			database := openTestDB(t)
+			handler := NewChannelHandler(database, database.Queries(), nil)
+
+			req := httptest.NewRequest(http.MethodPatch, "/api/v1/channel", strings.NewReader(tc.body))
+			req = req.WithContext(context.WithValue(req.Context(), userIDKey, "usr_self"))
+			rr := httptest.NewRecorder()
+
+			handler.UpdateChannel(rr, req)
+
+			if rr.Code != http.StatusBadRequest {
+				t.Fatalf("status = %d, want %d, body=%q", rr.Code, http.StatusBadRequest, rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestUpdateChannelUnchangedSkipsWrite(t *testing.T) {
+	database := openTestDB(t)
+	handler := NewChannelHandler(database, database.Queries(), nil)
+
+	req := httptest.NewRequest(http.MethodPatch, "/api/v1/channel", strings.NewReader(`{"name":" general "}`))
+	req = req.WithContext(context.WithValue(req.Context(), userIDKey, "usr_self"))
+	rr := httptest.NewRecorder()
+
+	handler.UpdateChannel(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d, body=%q", rr.Code, http.StatusOK, rr.Body.String())
+	}
+
+	row, err := database.Queries().GetTextChannel(context.Background())
+	if err != nil {
+		t.Fatalf("GetTextChannel() error = %v", err)
+	}
+	if row.UpdatedBy != nil {
+		t.Fatalf("updated_by = %q, want nil", *row.UpdatedBy)
+	}
+}
+
+func TestUpdateChannelPrivateRequiresModerator(t *testing.T) {
+	database := openTestDB(t)
+	handler := NewChannelHandler(database, database.Queries(), nil)
+
+	req := httptest.NewRequest(http.MethodPatch, "/api/v1/channel", strings.NewReader(`{"private":true}`))
+	ctx := context.WithValue(req.Context(), userIDKey, "usr_self")
+	ctx = context.WithValue(ctx, userRoleKey, models.RoleMember)
+	req = req.WithContext(ctx)
+	rr := httptest.NewRecorder()
+
+	handler.UpdateChannel(rr, req)
+
+	if rr.Code != http.StatusForbidden {
+		t.Fatalf("status = %d, want %d, body=%q", rr.Code, http.StatusForbidden, rr.Body.String())
+	}
+}
+
+func TestGetHistoryPrivateChannelAccess(t *testing.T) {
+	database := openTestDB(t)
+	queries := database.Queries()
+	now := time.Now().UTC()
+
+	for _, user := range []sqldb.CreateUserParams{
+		{ID: "usr_invited", Username: "alice", Email: "alice@example.com", CreatedAt: now},
+		{ID: "usr_outsider", Username: "bob", Email: "bob@example.com", CreatedAt: now},
+	} {
+		if err := queries.CreateUser(context.Background(), user); err != nil {
+			t.Fatalf("CreateUser() error = %v", err)
+		}
+	}
+	if _, err := queries.SetTextChannelPrivate(context.Background(), sqldb.SetTextChannelPrivateParams{
+		Private:   true,
+		UpdatedAt: now,
+	}); err != nil {
+		t.Fatalf("SetTextChannelPrivate() error = %v", err)
+	}
+	if err := queries.AddTextChannelMember(context.Background(), sqldb.AddTextChannelMemberParams{
+		UserID:  "usr_invited",
+		AddedAt: now,
+	}); err != nil {
+		t.Fatalf("AddTextChannelMember() error = %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		userID string
+		role   string
+		want   int
+	}{
+		{name: "invited_member", userID: "usr_invited", role: models.RoleMember, want: http.StatusOK},
+		{name: "outsider_member", userID: "usr_outsider", role: models.RoleMember, want: http.StatusForbidden},
+		{name: "outsider_moderator", userID: "usr_outsider", role: models.RoleModerator, want: http.StatusOK},
+	}
+
+	handler := NewMessageHandler(queries, "http://localhost", nil)
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/messages", nil)
+			ctx := context.WithValue(req.Context(), userIDKey, tc.userID)
+			ctx = context.WithValue(ctx, userRoleKey, tc.role)
+			req = req.WithContext(ctx)
+			rr := httptest.NewRecorder()
+
+			handler.GetHistory(rr, req)
+
+			if rr.Code != tc.want {
+				t.Fatalf("status = %d, want %d, body=%q", rr.Code, tc.want, rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestUpdateChannelMetadataRequiresModerator(t *testing.T) {
+	database := openTestDB(t)
+	if err := database.Queries().CreateUser(context.Background(), sqldb.CreateUserParams{
+		ID:        "usr_self",
+		Username:  "alice",
+		Email:     "alice@example.com",
+		CreatedAt: time.Now().UTC(),
+	}); err != nil {
+		t.Fatalf("CreateUser() error = %v", err)
+	}
+	handler := NewChannelHandler(database, database.Queries(), nil)
+
+	patch := func(body, role string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPatch, "/api/v1/channel", strings.NewReader(body))
+		ctx := context.WithValue(req.Context(), userIDKey, "usr_self")
+		ctx = context.WithValue(ctx, userRoleKey, role)
+		rr := httptest.NewRecorder()
+		handler.UpdateChannel(rr, req.WithContext(ctx))
+		return rr
+	}
+
+	for _, body := range []string{`{"name":"lounge"}`, `{"topic":"new"}`, `{"description":"about"}`} {
+		if rr := patch(body, models.RoleMember); rr.Code != http.StatusForbidden {
+			t.Fatalf("member %s status = %d, want %d, body=%q", body, rr.Code, http.StatusForbidden, rr.Body.String())
+		}
+	}
+	if rr := patch(`{"topic":"new"}`, models.RoleModerator); rr.Code != http.StatusOK {
+		t.Fatalf("moderator topic status = %d, want %d, body=%q", rr.Code, http.StatusOK, rr.Body.String())
+	}
+}
+
+func TestUpdateChannelArchiveLifecycle(t *testing.T) {
+	database := openTestDB(t)
+	if err := database.Queries().CreateUser(context.Background(), sqldb.CreateUserParams{
+		ID:        "usr_self",
+		Username:  "alice",
+		Email:     "alice@example.com",
+		CreatedAt: time.Now().UTC(),
+	}); err != nil {
+		t.Fatalf("CreateUser() error = %v", err)
+	}
+	handler := NewChannelHandler(database, database.Queries(), nil)
+
+	patch := func(body, role string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPatch, "/api/v1/channel", strings.NewReader(body))
+		ctx := context.WithValue(req.Context(), userIDKey, "usr_self")
+		ctx = context.WithValue(ctx, userRoleKey, role)
+		rr := httptest.NewRecorder()
+		handler.UpdateChannel(rr, req.WithContext(ctx))
+		return rr
+	}
+
+	if rr := patch(`{"archived":true}`, models.RoleModerator); rr.Code != http.StatusOK {
+		t.Fatalf("archive status = %d, want %d, body=%q", rr.Code, http.StatusOK, rr.Body.String())
+	}
+	if rr := patch(`{"topic":"new"}`, models.RoleAdmin); rr.Code != http.StatusConflict {
+		t.Fatalf("edit archived status = %d, want %d, body=%q", rr.Code, http.StatusConflict, rr.Body.String())
+	}
+	if rr := patch(`{"archived":false}`, models.RoleModerator); rr.Code != http.StatusForbidden {
+		t.Fatalf("moderator unarchive status = %d, want %d, body=%q", rr.Code, http.StatusForbidden, rr.Body.String())
+	}
+
+	rr := patch(`{"archived":false}`, models.RoleAdmin)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("admin unarchive status = %d, want %d, body=%q", rr.Code, http.StatusOK, rr.Body.String())
+	}
+	var resp ChannelResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v, body=%q", err, rr.Body.String())
+	}
+	if resp.Archived {
+		t.Fatal("archived = true, want false")
+	}
+}
+
+func TestUpdateChannelSlowMode(t *testing.T) {
+	database := openTestDB(t)
+	if err := database.Queries().CreateUser(context.Background(), sqldb.CreateUserParams{
+		ID:        "usr_self",
+		Username:  "alice",
+		Email:     "alice@example.com",
+		CreatedAt: time.Now().UTC(),
+	}); err != nil {
+		t.Fatalf("CreateUser() error = %v", err)
+	}
+	handler := NewChannelHandler(database, database.Queries(), nil)
+
+	patch := func(body, role string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPatch, "/api/v1/channel", strings.NewReader(body))
+		ctx := context.WithValue(req.Context(), userIDKey, "usr_self")
+		ctx = context.WithValue(ctx, userRoleKey, role)
+		rr := httptest.NewRecorder()
+		handler.UpdateChannel(rr, req.WithContext(ctx))
+		return rr
+	}
+
+	if rr := patch(`{"slowModeSeconds":30}`, models.RoleMember); rr.Code != http.StatusForbidden {
+		t.Fatalf("member status = %d, want %d, body=%q", rr.Code, http.StatusForbidden, rr.Body.String())
+	}
+	if rr := patch(`{"slowModeSeconds":-1}`, models.RoleModerator); rr.Code != http.StatusBadRequest {
+		t.Fatalf("negative status = %d, want %d, body=%q", rr.Code, http.StatusBadRequest, rr.Body.String())
+	}
+
+	rr := patch(`{"slowModeSeconds":30}`, models.RoleModerator)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("moderator status = %d, want %d, body=%q", rr.Code, http.StatusOK, rr.Body.String())
+	}
+	var resp ChannelResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v, body=%q", err, rr.Body.String())
+	}
+	if resp.SlowMode != 30 {
+		t.Fatalf("slowModeSeconds = %d, want 30", resp.SlowMode)
+	}
+}
+
+func TestChannelNotificationSettings(t *testing.T) {
+	database := openTestDB(t)
+	if err := database.Queries().CreateUser(context.Background(), sqldb.CreateUserParams{
+		ID:        "usr_self",
+		Username:  "alice",
+		Email:     "alice@example.com",
+		CreatedAt: time.Now().UTC(),
+	}); err != nil {
+		t.Fatalf("CreateUser() error = %v", err)
+	}
+	handler := NewChannelHandler(database, database.Queries(), nil)
+
+	withUser := func(req *http.Request) *http.Request {
+		return req.WithContext(context.WithValue(req.Context(), userIDKey, "usr_self"))
+	}
+	getLevel := func() string {
+		rr := httptest.NewRecorder()
+		handler.GetNotificationSettings(rr, withUser(httptest.NewRequest(http.MethodGet, "/api/v1/channel/notifications", nil)))
+		if rr.Code != http.StatusOK {
+			t.Fatalf("GET status = %d, want %d, body=%q", rr.Code, http.StatusOK, rr.Body.String())
+		}
+		var resp NotificationSettingsResponse
+		if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("json.Unmarshal() error = %v, body=%q", err, rr.Body.String())
+		}
+		return resp.Level
+	}
+
+	if level := getLevel(); level != models.NotificationAll {
+		t.Fatalf("default level = %q, want %q", level, models.NotificationAll)
+	}
+
+	rr := httptest.NewRecorder()
+	handler.UpdateNotificationSettings(rr, withUser(httptest.NewRequest(http.MethodPut, "/api/v1/channel/notifications", strings.NewReader(`{"level":"loud"}`))))
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("invalid level status = %d, want %d, body=%q", rr.Code, http.StatusBadRequest, rr.Body.String())
+	}
+
+	rr = httptest.NewRecorder()
+	handler.UpdateNotificationSettings(rr, withUser(httptest.NewRequest(http.MethodPut, "/api/v1/channel/notifications", strings.NewReader(`{"level":"mentions"}`))))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("PUT status = %d, want %d, body=%q", rr.Code, http.StatusOK, rr.Body.String())
+	}
+	if level := getLevel(); level != models.NotificationMentions {
+		t.Fatalf("level = %q, want %q", level, models.NotificationMentions)
+	}
+}
+
+func TestChannelNotificationSettingsSuppression(t *testing.T) {
+	database := openTestDB(t)
+	if err := database.Queries().CreateUser(context.Background(), sqldb.CreateUserParams{
+		ID:        "usr_self",
+		Username:  "alice",
+		Email:     "alice@example.com",
+		CreatedAt: time.Now().UTC(),
+	}); err != nil {
+		t.Fatalf("CreateUser() error = %v", err)
+	}
+	handler := NewChannelHandler(database, database.Queries(), nil)
+
+	put := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPut, "/api/v1/channel/notifications", strings.NewReader(body))
+		rr := httptest.NewRecorder()
+		handler.UpdateNotificationSettings(rr, req.WithContext(context.WithValue(req.Context(), userIDKey, "usr_self")))
+		return rr
+	}
+
+	if rr := put(`{}`); rr.Code != http.StatusBadRequest {
+		t.Fatalf("empty update status = %d, want %d", rr.Code, http.StatusBadRequest)
+	}
+	if rr := put(`{"level":"mentions"}`); rr.Code != http.StatusOK {
+		t.Fatalf("level update status = %d, body=%q", rr.Code, rr.Body.String())
+	}
+
+	rr := put(`{"suppressEveryone":true}`)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("suppress update status = %d, body=%q", rr.Code, rr.Body.String())
+	}
+	var resp NotificationSettingsResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v, body=%q", err, rr.Body.String())
+	}
+	want := NotificationSettingsResponse{Level: models.NotificationMentions, SuppressEveryone: true}
+	if resp != want {
+		t.Fatalf("settings = %+v, want %+v (partial update keeps the level)", resp, want)
+	}
+}
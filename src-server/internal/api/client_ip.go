@@ -9,7 +9,8 @@ import (
 
 // ClientIPResolver resolves the client IP address for security decisions
 // (rate limiting, abuse controls). It only trusts forwarding headers when the
-// immediate peer is in a trusted proxy CIDR.
+// immediate peer is in a trusted proxy CIDR. Forwarded (RFC 7239) wins over
+// X-Forwarded-For, which wins over X-Real-IP.
 type ClientIPResolver struct {
 	trustedProxyNets []*net.IPNet
 }
@@ -51,7 +52,10 @@ func (r *ClientIPResolver) Resolve(req *http.Request) string {
 		return "unknown"
 	}
 
-	if r.isTrustedProxy(peerIP) {
+	if r.IsTrustedProxy(peerIP) {
+		if forwarded := parseForwarded(req.Header.Values("Forwarded")); forwarded != nil {
+			return forwarded.String()
+		}
 		if forwarded := parseForwardedFor(req.Header.Get("X-Forwarded-For")); forwarded != nil {
 			return forwarded.String()
 		}
@@ -63,7 +67,8 @@ func (r *ClientIPResolver) Resolve(req *http.Request) string {
 	return peerIP.String()
 }
 
-func (r *ClientIPResolver) isTrustedProxy(ip net.IP) bool {
+// IsTrustedProxy reports whether ip is in a trusted proxy CIDR.
+func (r *ClientIPResolver) IsTrustedProxy(ip net.IP) bool {
 	if ip == nil {
 		return false
 	}
@@ -90,6 +95,30 @@ func parseForwardedFor(header string) net.IP {
 	return nil
 }
 
+// parseForwarded returns the first valid for= address in RFC 7239 Forwarded
+// headers. Obfuscated identifiers ("_hidden") and "unknown" are skipped.
+func parseForwarded(headers []string) net.IP {
+	for _, header := range headers {
+		for _, element := range strings.Split(header, ",") {
+			for _, pair := range strings.Split(element, ";") {
+				key, value, ok := strings.Cut(strings.TrimSpace(pair), "=")
+				if !ok || !strings.EqualFold(key, "for") {
+					continue
+				}
+				value = strings.Trim(strings.TrimSpace(value), `"`)
+				if strings.HasPrefix(value, "[") && strings.HasSuffix(value, "]") {
+					value = value[1 : len(value)-1]
+				}
+				if ip := parseIP(value); ip != nil {
+					return ip
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
 func parseIPFromRemoteAddr(remoteAddr string) net.IP {
 	host, _, err := net.SplitHostPort(remoteAddr)
 	if err == nil {
@@ -51,3 +51,31 @@ func TestClientIPResolverTrustedProxyFallsBackToRealIP(t *testing.T) {
 		t.Fatalf("Resolve() = %q, want %q", got, "198.51.100.10")
 	}
 }
+
+func TestClientIPResolverTrustedProxyPrefersForwarded(t *testing.T) {
+	resolver, err := NewClientIPResolver([]string{"172.30.0.10/32"})
+	if err != nil {
+		t.Fatalf("NewClientIPResolver error: %v", err)
+	}
+
+	tests := []struct {
+		forwarded string
+		want      string
+	}{
+		{forwarded: "for=198.51.100.20;proto=https, for=172.30.0.10", want: "198.51.100.20"},
+		{forwarded: `For="[2001:db8:cafe::17]:4711"`, want: "2001:db8:cafe::17"},
+		{forwarded: `for="[2001:db8:cafe::17]"`, want: "2001:db8:cafe::17"},
+		{forwarded: "for=_hidden, for=unknown, for=198.51.100.21", want: "198.51.100.21"},
+		{forwarded: "for=_hidden", want: "198.51.100.9"},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", "http://localhost/test", nil)
+		req.RemoteAddr = "172.30.0.10:12345"
+		req.Header.Set("Forwarded", tt.forwarded)
+		req.Header.Set("X-Forwarded-For", "198.51.100.9")
+
+		if got := resolver.Resolve(req); got != tt.want {
+			t.Errorf("Resolve() with Forwarded %q = %q, want %q", tt.forwarded, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package api
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+	"unicode/utf8"
+
+	"github.com/go-chi/chi/v5"
+
+	"lobby/internal/constants"
+	sqldb "lobby/internal/db/sqlc"
+	"lobby/internal/ws"
+)
+
+type DraftHandler struct {
+	queries *sqldb.Queries
+	hub     *ws.Hub
+}
+
+func NewDraftHandler(queries *sqldb.Queries, hub *ws.Hub) *DraftHandler {
+	return &DraftHandler{queries: queries, hub: hub}
+}
+
+type UpdateDraftRequest struct {
+	Content *string `json:"content" validate:"required"`
+}
+
+type DraftResponse struct {
+	ChannelID int64     `json:"channelId"`
+	Content   string    `json:"content"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// GET /api/v1/drafts
+func (h *DraftHandler) ListDrafts(w http.ResponseWriter, r *http.Request) {
+	drafts, err := h.loadDrafts(r.Context(), GetUserID(r))
+	if err != nil {
+		slog.Error("error listing drafts", "error", err)
+		internalError(w)
+		return
+	}
+	writeJSON(w, http.StatusOK, drafts)
+}
+
+func (h *DraftHandler) loadDrafts(ctx context.Context, userID string) ([]DraftResponse, error) {
+	rows, err := h.queries.ListMessageDraftsByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	drafts := make([]DraftResponse, 0, len(rows))
+	for _, row := range rows {
+		drafts = append(drafts, DraftResponse{
+			ChannelID: row.ChannelID,
+			Content:   row.Content,
+			UpdatedAt: row.UpdatedAt,
+		})
+	}
+	return drafts, nil
+}
+
+// PUT /api/v1/drafts/{channel}
+//
+// Empty content clears the draft. Every save is pushed to the user's other
+// sessions as DRAFT_UPDATE.
+func (h *DraftHandler) UpdateDraft(w http.ResponseWriter, r *http.Request) {
+	channelID, err := strconv.ParseInt(chi.URLParam(r, "channel"), 10, 64)
+	if err != nil || channelID != textChannelID {
+		notFound(w, "Channel not found")
+		return
+	}
+
+	var req UpdateDraftRequest
+	if err := decodeAndValidate(r.Body, &req); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+	if utf8.RuneCountInString(*req.Content) > constants.MessageMaxContentLength {
+		badRequest(w, "content exceeds maximum length")
+		return
+	}
+
+	userID := GetUserID(r)
+	draft := DraftResponse{
+		ChannelID: channelID,
+		Content:   *req.Content,
+		UpdatedAt: time.Now().UTC(),
+	}
+	if draft.Content == "" {
+		err = h.queries.DeleteMessageDraft(r.Context(), sqldb.DeleteMessageDraftParams{
+			UserID:    userID,
+			ChannelID: channelID,
+		})
+	} else {
+		err = h.queries.UpsertMessageDraft(r.Context(), sqldb.UpsertMessageDraftParams{
+			UserID:    userID,
+			ChannelID: channelID,
+			Content:   draft.Content,
+			UpdatedAt: draft.UpdatedAt,
+		})
+	}
+	if err != nil {
+		slog.Error("error saving draft", "error", err, "user_id", userID)
+		internalError(w)
+		return
+	}
+
+	if h.hub != nil {
+		h.hub.PublishToUser(userID, ws.EventDraftUpdate, ws.DraftUpdatePayload{
+			ChannelID: draft.ChannelID,
+			Content:   draft.Content,
+			UpdatedAt: draft.UpdatedAt.Format(time.RFC3339Nano),
+		})
+	}
+	writeJSON(w, http.StatusOK, draft)
+}
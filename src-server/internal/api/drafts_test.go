@@ -0,0 +1,70 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	sqldb "lobby/internal/db/sqlc"
+)
+
+func draftRequest(handler *DraftHandler, channel, body string) *httptest.ResponseRecorder {
+	return serveRequest(handler.UpdateDraft, newAuthedRequest(http.MethodPut, "/api/v1/drafts/"+channel, body, "usr_1", map[string]string{"channel": channel}))
+}
+
+func listDrafts(t *testing.T, handler *DraftHandler) []DraftResponse {
+	t.Helper()
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/drafts", nil)
+	rr := httptest.NewRecorder()
+	handler.ListDrafts(rr, req.WithContext(context.WithValue(req.Context(), userIDKey, "usr_1")))
+	var drafts []DraftResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &drafts); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v, body=%q", err, rr.Body.String())
+	}
+	return drafts
+}
+
+func TestDraftSaveAndClear(t *testing.T) {
+	database := openTestDB(t)
+	if err := database.Queries().CreateUser(context.Background(), sqldb.CreateUserParams{
+		ID:        "usr_1",
+		Username:  "alice",
+		Email:     "alice@example.com",
+		CreatedAt: time.Now().UTC(),
+	}); err != nil {
+		t.Fatalf("CreateUser() error = %v", err)
+	}
+	handler := NewDraftHandler(database.Queries(), nil)
+
+	if rr := draftRequest(handler, "2", `{"content":"hi"}`); rr.Code != http.StatusNotFound {
+		t.Fatalf("unknown channel status = %d, want %d", rr.Code, http.StatusNotFound)
+	}
+	if rr := draftRequest(handler, "1", `{}`); rr.Code != http.StatusBadRequest {
+		t.Fatalf("missing content status = %d, want %d", rr.Code, http.StatusBadRequest)
+	}
+	if rr := draftRequest(handler, "1", `{"content":"`+strings.Repeat("a", 8001)+`"}`); rr.Code != http.StatusBadRequest {
+		t.Fatalf("oversized status = %d, want %d", rr.Code, http.StatusBadRequest)
+	}
+
+	if rr := draftRequest(handler, "1", `{"content":"half-written"}`); rr.Code != http.StatusOK {
+		t.Fatalf("save status = %d, want %d, body=%q", rr.Code, http.StatusOK, rr.Body.String())
+	}
+	if rr := draftRequest(handler, "1", `{"content":"half-written thought"}`); rr.Code != http.StatusOK {
+		t.Fatalf("update status = %d, want %d, body=%q", rr.Code, http.StatusOK, rr.Body.String())
+	}
+	if drafts := listDrafts(t, handler); len(drafts) != 1 || drafts[0].Content != "half-written thought" || drafts[0].ChannelID != 1 {
+		t.Fatalf("unexpected drafts: %+v", drafts)
+	}
+
+	if rr := draftRequest(handler, "1", `{"content":""}`); rr.Code != http.StatusOK {
+		t.Fatalf("clear status = %d, want %d, body=%q", rr.Code, http.StatusOK, rr.Body.String())
+	}
+	if drafts := listDrafts(t, handler); len(drafts) != 0 {
+		t.Fatalf("expected no drafts, got %+v", drafts)
+	}
+}
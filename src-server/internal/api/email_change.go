@@ -0,0 +1,328 @@
+package api
+
+import (
+	"crypto/subtle"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"lobby/internal/auth"
+	"lobby/internal/db"
+	sqldb "lobby/internal/db/sqlc"
+)
+
+// POST /api/v1/users/me/email
+type EmailChangeRequest struct {
+	NewEmail string `json:"newEmail" validate:"required,max=254"`
+}
+
+// POST /api/v1/users/me/email/confirm
+// Address is "current" or "new", naming the inbox the code was sent to.
+type ConfirmEmailChangeRequest struct {
+	Address string `json:"address" validate:"required,oneof=current new"`
+	Code    string `json:"code" validate:"required,len=6,numeric"`
+}
+
+type EmailChangeResponse struct {
+	NewEmail         string    `json:"newEmail"`
+	CurrentConfirmed bool      `json:"currentConfirmed"`
+	NewConfirmed     bool      `json:"newConfirmed"`
+	Completed        bool      `json:"completed"`
+	ExpiresAt        time.Time `json:"expiresAt"`
+}
+
+// RequestEmailChange starts a change of the caller's email by sending a code
+// to both the current and the new address. It replaces any pending change.
+func (h *AuthHandler) RequestEmailChange(w http.ResponseWriter, r *http.Request) {
+	userID := GetUserID(r)
+	if userID == "" {
+		unauthorized(w, "User not found in context")
+		return
+	}
+
+	var req EmailChangeRequest
+	if err := decodeAndValidate(r.Body, &req); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+	newEmail := strings.ToLower(strings.TrimSpace(req.NewEmail))
+	if err := requestValidator.Var(newEmail, "required,email,max=254"); err != nil {
+		badRequest(w, "invalid email format")
+		return
+	}
+
+	user, err := h.queries.GetActiveUserByID(r.Context(), userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		notFound(w, "User not found")
+		return
+	}
+	if err != nil {
+		slog.Error("error finding user", "error", err, "user_id", userID)
+		internalError(w)
+		return
+	}
+	if newEmail == user.Email {
+		badRequest(w, "New email must differ from the current one")
+		return
+	}
+	if !h.emailAvailable(w, r, newEmail) {
+		return
+	}
+
+	currentCode, err := h.magicService.GenerateCode()
+	if err != nil {
+		slog.Error("error generating email change code", "error", err)
+		internalError(w)
+		return
+	}
+	newCode, err := h.magicService.GenerateCode()
+	if err != nil {
+		slog.Error("error generating email change code", "error", err)
+		internalError(w)
+		return
+	}
+	changeID, err := db.GenerateID("ec")
+	if err != nil {
+		slog.Error("error generating email change id", "error", err)
+		internalError(w)
+		return
+	}
+
+	expiresAt := h.magicService.ExpiresAt().UTC()
+	tx, err := h.database.BeginTx(r.Context(), nil)
+	if err != nil {
+		slog.Error("error starting email change transaction", "error", err)
+		internalError(w)
+		return
+	}
+	defer tx.Rollback()
+	qtx := h.queries.WithTx(tx)
+	if err := qtx.DeleteEmailChangesForUser(r.Context(), userID); err != nil {
+		slog.Error("error clearing pending email change", "error", err, "user_id", userID)
+		internalError(w)
+		return
+	}
+	if err := qtx.CreateEmailChange(r.Context(), sqldb.CreateEmailChangeParams{
+		ID:              changeID,
+		UserID:          userID,
+		NewEmail:        newEmail,
+		CurrentCodeHash: auth.HashMagicCode(user.Email, currentCode),
+		NewCodeHash:     auth.HashMagicCode(newEmail, newCode),
+		ExpiresAt:       expiresAt,
+		CreatedAt:       time.Now().UTC(),
+	}); err != nil {
+		slog.Error("error storing email change", "error", err, "user_id", userID)
+		internalError(w)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		slog.Error("error committing email change", "error", err, "user_id", userID)
+		internalError(w)
+		return
+	}
+
+	// Unlike login codes, failing to deliver is reported: the change cannot
+	// complete without both codes and the caller already knows the account.
+	if err := h.emailService.SendEmailChangeCode(user.Email, currentCode, newEmail, h.magicCodeTTL); err != nil {
+		slog.Error("error sending email change code", "error", err, "user_id", userID)
+		internalError(w)
+		return
+	}
+	if err := h.emailService.SendEmailChangeCode(newEmail, newCode, newEmail, h.magicCodeTTL); err != nil {
+		slog.Error("error sending email change code", "error", err, "user_id", userID)
+		internalError(w)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, EmailChangeResponse{
+		NewEmail:  newEmail,
+		ExpiresAt: expiresAt,
+	})
+}
+
+// ConfirmEmailChange checks a code from one of the two addresses. Once both
+// are confirmed the email is swapped and every other session is revoked.
+func (h *AuthHandler) ConfirmEmailChange(w http.ResponseWriter, r *http.Request) {
+	userID := GetUserID(r)
+	if userID == "" {
+		unauthorized(w, "User not found in context")
+		return
+	}
+
+	var req ConfirmEmailChangeRequest
+	if err := decodeAndValidate(r.Body, &req); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+
+	change, err := h.queries.GetEmailChangeForUser(r.Context(), userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		notFound(w, "No pending email change")
+		return
+	}
+	if err != nil {
+		slog.Error("error finding email change", "error", err, "user_id", userID)
+		internalError(w)
+		return
+	}
+	user, err := h.queries.GetActiveUserByID(r.Context(), userID)
+	if err != nil {
+		slog.Error("error finding user", "error", err, "user_id", userID)
+		internalError(w)
+		return
+	}
+
+	// Wrong codes answer 400, not 401, so clients do not mistake them for an
+	// expired session and refresh.
+	newAttempts, err := h.queries.IncrementEmailChangeAttempts(r.Context(), sqldb.IncrementEmailChangeAttemptsParams{
+		ID:          change.ID,
+		MaxAttempts: int64(auth.MaxAttempts),
+	})
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && newAttempts > int64(auth.MaxAttempts)) {
+		writeError(w, http.StatusBadRequest, ErrCodeAuthFailed, "Too many attempts")
+		return
+	}
+	if err != nil {
+		slog.Error("error incrementing email change attempts", "error", err)
+		internalError(w)
+		return
+	}
+	if h.clock.Now().After(change.ExpiresAt) {
+		writeError(w, http.StatusBadRequest, ErrCodeAuthExpired, "Code has expired")
+		return
+	}
+
+	address, codeHash := user.Email, change.CurrentCodeHash
+	if req.Address == "new" {
+		address, codeHash = change.NewEmail, change.NewCodeHash
+	}
+	if subtle.ConstantTimeCompare([]byte(auth.HashMagicCode(address, req.Code)), []byte(codeHash)) != 1 {
+		writeError(w, http.StatusBadRequest, ErrCodeAuthFailed, "Invalid code")
+		return
+	}
+
+	confirmedAt := time.Now().UTC()
+	if req.Address == "new" {
+		_, err = h.queries.ConfirmEmailChangeNew(r.Context(), sqldb.ConfirmEmailChangeNewParams{
+			ConfirmedAt: &confirmedAt,
+			ID:          change.ID,
+		})
+		if err == nil && change.NewConfirmedAt == nil {
+			change.NewConfirmedAt = &confirmedAt
+		}
+	} else {
+		_, err = h.queries.ConfirmEmailChangeCurrent(r.Context(), sqldb.ConfirmEmailChangeCurrentParams{
+			ConfirmedAt: &confirmedAt,
+			ID:          change.ID,
+		})
+		if err == nil && change.CurrentConfirmedAt == nil {
+			change.CurrentConfirmedAt = &confirmedAt
+		}
+	}
+	if err != nil {
+		slog.Error("error confirming email change", "error", err, "user_id", userID)
+		internalError(w)
+		return
+	}
+
+	resp := EmailChangeResponse{
+		NewEmail:         change.NewEmail,
+		CurrentConfirmed: change.CurrentConfirmedAt != nil,
+		NewConfirmed:     change.NewConfirmedAt != nil,
+		ExpiresAt:        change.ExpiresAt,
+	}
+	if !resp.CurrentConfirmed || !resp.NewConfirmed {
+		writeJSON(w, http.StatusOK, resp)
+		return
+	}
+
+	if !h.emailAvailable(w, r, change.NewEmail) {
+		return
+	}
+	if !h.completeEmailChange(w, r, userID, change.NewEmail) {
+		return
+	}
+	resp.Completed = true
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// emailAvailable writes a conflict and returns false when another account
+// already uses email.
+func (h *AuthHandler) emailAvailable(w http.ResponseWriter, r *http.Request, email string) bool {
+	_, err := h.queries.GetUserByEmail(r.Context(), email)
+	if err == nil {
+		conflict(w, "Email is already in use")
+		return false
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		slog.Error("error checking email availability", "error", err)
+		internalError(w)
+		return false
+	}
+	return true
+}
+
+// completeEmailChange swaps the email and revokes every session but the
+// caller's, so a stolen session cannot outlive the change.
+func (h *AuthHandler) completeEmailChange(w http.ResponseWriter, r *http.Request, userID, newEmail string) bool {
+	ctx := r.Context()
+	sessionID := GetSessionID(r)
+	now := time.Now().UTC()
+
+	tx, err := h.database.BeginTx(ctx, nil)
+	if err != nil {
+		slog.Error("error starting email change transaction", "error", err)
+		internalError(w)
+		return false
+	}
+	defer tx.Rollback()
+	qtx := h.queries.WithTx(tx)
+
+	if _, err := qtx.UpdateUserEmail(ctx, sqldb.UpdateUserEmailParams{
+		Email:     newEmail,
+		UpdatedAt: &now,
+		ID:        userID,
+	}); err != nil {
+		slog.Error("error updating user email", "error", err, "user_id", userID)
+		internalError(w)
+		return false
+	}
+	if err := qtx.DeleteEmailChangesForUser(ctx, userID); err != nil {
+		slog.Error("error clearing email change", "error", err, "user_id", userID)
+		internalError(w)
+		return false
+	}
+	if err := qtx.RevokeRefreshTokensExceptSession(ctx, sqldb.RevokeRefreshTokensExceptSessionParams{
+		RevokedAt:     &now,
+		UserID:        userID,
+		KeepSessionID: &sessionID,
+	}); err != nil {
+		slog.Error("error revoking refresh tokens after email change", "error", err, "user_id", userID)
+		internalError(w)
+		return false
+	}
+	if err := qtx.RevokeOtherUserSessions(ctx, sqldb.RevokeOtherUserSessionsParams{
+		RevokedAt: &now,
+		UserID:    userID,
+		KeepID:    sessionID,
+	}); err != nil {
+		slog.Error("error revoking sessions after email change", "error", err, "user_id", userID)
+		internalError(w)
+		return false
+	}
+	if err := tx.Commit(); err != nil {
+		slog.Error("error committing email change", "error", err, "user_id", userID)
+		internalError(w)
+		return false
+	}
+
+	if h.hub != nil {
+		if client := h.hub.GetClient(userID); client != nil && client.LoginSessionID() != sessionID {
+			client.Close()
+		}
+	}
+	return true
+}
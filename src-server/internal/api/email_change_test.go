@@ -0,0 +1,107 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"lobby/internal/auth"
+	sqldb "lobby/internal/db/sqlc"
+	"lobby/internal/models"
+)
+
+func TestEmailChangeRequiresBothAddresses(t *testing.T) {
+	database := openTestDB(t)
+	queries := database.Queries()
+	now := time.Now().UTC()
+	for _, user := range []sqldb.CreateUserParams{
+		{ID: "usr_1", Username: "alice", Email: "alice@example.com", CreatedAt: now},
+		{ID: "usr_2", Username: "bob", Email: "bob@example.com", CreatedAt: now},
+	} {
+		if err := queries.CreateUser(context.Background(), user); err != nil {
+			t.Fatalf("CreateUser() error = %v", err)
+		}
+	}
+	jwtService := auth.NewJWTService("test-secret", time.Minute, time.Hour)
+	magicService := auth.NewMagicCodeService(time.Minute, "test-secret")
+	authHandler := NewAuthHandler(database, queries, jwtService, magicService, nil, time.Minute, models.RegistrationOpen, "", nil, nil)
+	middleware := NewAuthMiddleware(jwtService, queries)
+	user := &models.User{ID: "usr_1", Username: "alice", SessionVersion: 1}
+
+	login := func() *AuthResponse {
+		resp, err := authHandler.generateAuthResponse(httptest.NewRequest(http.MethodPost, "/", nil), user, "")
+		if err != nil {
+			t.Fatalf("generateAuthResponse() error = %v", err)
+		}
+		return resp
+	}
+	current, other := login(), login()
+	send := func(handler http.HandlerFunc, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/users/me/email", strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+current.AccessToken)
+		rr := httptest.NewRecorder()
+		middleware.RequireAuth(handler).ServeHTTP(rr, req)
+		return rr
+	}
+
+	if rr := send(authHandler.RequestEmailChange, `{"newEmail":"Bob@example.com"}`); rr.Code != http.StatusConflict {
+		t.Fatalf("taken email status = %d, want %d", rr.Code, http.StatusConflict)
+	}
+	if rr := send(authHandler.RequestEmailChange, `{"newEmail":"alice@example.com"}`); rr.Code != http.StatusBadRequest {
+		t.Fatalf("same email status = %d, want %d", rr.Code, http.StatusBadRequest)
+	}
+
+	if err := queries.CreateEmailChange(context.Background(), sqldb.CreateEmailChangeParams{
+		ID:              "ec_1",
+		UserID:          "usr_1",
+		NewEmail:        "alice@new.example",
+		CurrentCodeHash: auth.HashMagicCode("alice@example.com", "111111"),
+		NewCodeHash:     auth.HashMagicCode("alice@new.example", "222222"),
+		ExpiresAt:       now.Add(time.Minute),
+		CreatedAt:       now,
+	}); err != nil {
+		t.Fatalf("CreateEmailChange() error = %v", err)
+	}
+
+	if rr := send(authHandler.ConfirmEmailChange, `{"address":"new","code":"111111"}`); rr.Code != http.StatusBadRequest {
+		t.Fatalf("wrong address code status = %d, want %d", rr.Code, http.StatusBadRequest)
+	}
+	rr := send(authHandler.ConfirmEmailChange, `{"address":"current","code":"111111"}`)
+	var resp EmailChangeResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v, body=%q", err, rr.Body.String())
+	}
+	if !resp.CurrentConfirmed || resp.NewConfirmed || resp.Completed {
+		t.Fatalf("after current confirm = %+v", resp)
+	}
+	if row, err := queries.GetUserByID(context.Background(), "usr_1"); err != nil || row.Email != "alice@example.com" {
+		t.Fatalf("email after one confirm = %q, %v", row.Email, err)
+	}
+
+	rr = send(authHandler.ConfirmEmailChange, `{"address":"new","code":"222222"}`)
+	if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v, body=%q", err, rr.Body.String())
+	}
+	if !resp.Completed {
+		t.Fatalf("after both confirms = %+v, want completed", resp)
+	}
+	if row, err := queries.GetUserByID(context.Background(), "usr_1"); err != nil || row.Email != "alice@new.example" {
+		t.Fatalf("email after both confirms = %q, %v", row.Email, err)
+	}
+
+	refresh := func(token string) int {
+		rr := httptest.NewRecorder()
+		authHandler.Refresh(rr, httptest.NewRequest(http.MethodPost, "/api/v1/auth/refresh", strings.NewReader(`{"refreshToken":"`+token+`"}`)))
+		return rr.Code
+	}
+	if code := refresh(other.RefreshToken); code != http.StatusUnauthorized {
+		t.Fatalf("other session refresh status = %d, want %d", code, http.StatusUnauthorized)
+	}
+	if code := refresh(current.RefreshToken); code != http.StatusOK {
+		t.Fatalf("current session refresh status = %d, want %d", code, http.StatusOK)
+	}
+}
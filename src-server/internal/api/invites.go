@@ -0,0 +1,204 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"lobby/internal/auth"
+	sqldb "lobby/internal/db/sqlc"
+	"lobby/internal/mediaurl"
+	"lobby/internal/ws"
+)
+
+// invitePathPrefix is the public path invite URLs point at; clients parse the
+// code from it and carry it into magic-code verify.
+const invitePathPrefix = "/invite/"
+
+type InviteHandler struct {
+	queries    *sqldb.Queries
+	hub        *ws.Hub
+	serverName string
+	baseURL    string
+}
+
+func NewInviteHandler(queries *sqldb.Queries, hub *ws.Hub, serverName string, baseURL string) *InviteHandler {
+	return &InviteHandler{
+		queries:    queries,
+		hub:        hub,
+		serverName: serverName,
+		baseURL:    baseURL,
+	}
+}
+
+type CreateInviteRequest struct {
+	MaxUses          *int64 `json:"maxUses" validate:"omitnil,min=1,max=1000"`
+	ExpiresInSeconds *int64 `json:"expiresInSeconds" validate:"omitnil,min=60,max=2592000"`
+}
+
+type InviteResponse struct {
+	Code      string     `json:"code"`
+	URL       string     `json:"url"`
+	CreatedBy *string    `json:"createdBy"`
+	MaxUses   *int64     `json:"maxUses"`
+	Uses      int64      `json:"uses"`
+	ExpiresAt *time.Time `json:"expiresAt"`
+	CreatedAt time.Time  `json:"createdAt"`
+}
+
+type InvitePreviewResponse struct {
+	Code        string     `json:"code"`
+	ServerName  string     `json:"serverName"`
+	IconURL     string     `json:"iconUrl,omitempty"`
+	MemberCount int        `json:"memberCount"`
+	OnlineCount int        `json:"onlineCount"`
+	ExpiresAt   *time.Time `json:"expiresAt"`
+}
+
+func (h *InviteHandler) inviteResponse(invite sqldb.Invite) InviteResponse {
+	return InviteResponse{
+		Code:      invite.Code,
+		URL:       strings.TrimRight(strings.TrimSpace(h.baseURL), "/") + invitePathPrefix + invite.Code,
+		CreatedBy: invite.CreatedBy,
+		MaxUses:   invite.MaxUses,
+		Uses:      invite.Uses,
+		ExpiresAt: invite.ExpiresAt,
+		CreatedAt: invite.CreatedAt,
+	}
+}
+
+// GET /api/v1/invites
+func (h *InviteHandler) ListInvites(w http.ResponseWriter, r *http.Request) {
+	now := time.Now().UTC()
+	rows, err := h.queries.ListActiveInvites(r.Context(), &now)
+	if err != nil {
+		slog.Error("error listing invites", "error", err)
+		internalError(w)
+		return
+	}
+
+	invites := make([]InviteResponse, 0, len(rows))
+	for _, row := range rows {
+		invites = append(invites, h.inviteResponse(row))
+	}
+	writeJSON(w, http.StatusOK, invites)
+}
+
+// POST /api/v1/invites
+func (h *InviteHandler) CreateInvite(w http.ResponseWriter, r *http.Request) {
+	var req CreateInviteRequest
+	if err := decodeAndValidate(r.Body, &req); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+
+	code, err := auth.GenerateOpaqueToken(5)
+	if err != nil {
+		slog.Error("error generating invite code", "error", err)
+		internalError(w)
+		return
+	}
+
+	actorID := GetUserID(r)
+	createdAt := time.Now().UTC()
+	invite := sqldb.Invite{
+		Code:      code,
+		CreatedBy: &actorID,
+		MaxUses:   req.MaxUses,
+		CreatedAt: createdAt,
+	}
+	if req.ExpiresInSeconds != nil {
+		expiresAt := createdAt.Add(time.Duration(*req.ExpiresInSeconds) * time.Second)
+		invite.ExpiresAt = &expiresAt
+	}
+
+	if err := h.queries.CreateInvite(r.Context(), sqldb.CreateInviteParams{
+		Code:      invite.Code,
+		CreatedBy: invite.CreatedBy,
+		MaxUses:   invite.MaxUses,
+		ExpiresAt: invite.ExpiresAt,
+		CreatedAt: invite.CreatedAt,
+	}); err != nil {
+		slog.Error("error creating invite", "error", err)
+		internalError(w)
+		return
+	}
+
+	slog.Info("invite created", "code", code, "by", actorID)
+	writeJSON(w, http.StatusCreated, h.inviteResponse(invite))
+}
+
+// DELETE /api/v1/invites/{code}
+func (h *InviteHandler) RevokeInvite(w http.ResponseWriter, r *http.Request) {
+	code := chi.URLParam(r, "code")
+	revokedAt := time.Now().UTC()
+
+	rowsAffected, err := h.queries.RevokeInvite(r.Context(), sqldb.RevokeInviteParams{
+		RevokedAt: &revokedAt,
+		Code:      code,
+	})
+	if err != nil {
+		slog.Error("error revoking invite", "error", err, "code", code)
+		internalError(w)
+		return
+	}
+	if rowsAffected == 0 {
+		notFound(w, "Invite not found")
+		return
+	}
+
+	slog.Info("invite revoked", "code", code, "by", GetUserID(r))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GET /api/v1/invites/{code}
+//
+// Unauthenticated preview shown before sign-in.
+func (h *InviteHandler) GetInvitePreview(w http.ResponseWriter, r *http.Request) {
+	now := time.Now().UTC()
+	invite, err := h.queries.GetUsableInvite(r.Context(), sqldb.GetUsableInviteParams{
+		Code: chi.URLParam(r, "code"),
+		Now:  &now,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		writeError(w, http.StatusNotFound, ErrCodeInviteInvalid, "Invite is invalid or has expired")
+		return
+	}
+	if err != nil {
+		slog.Error("error loading invite", "error", err)
+		internalError(w)
+		return
+	}
+
+	preview := InvitePreviewResponse{
+		Code:       invite.Code,
+		ServerName: h.serverName,
+		ExpiresAt:  invite.ExpiresAt,
+	}
+	settings, err := h.queries.GetServerSettings(r.Context())
+	if err == nil {
+		if settings.IconBlobID != nil {
+			preview.IconURL = mediaurl.Blob(h.baseURL, *settings.IconBlobID)
+		}
+	} else if !errors.Is(err, sql.ErrNoRows) {
+		slog.Error("error loading server settings", "error", err)
+		internalError(w)
+		return
+	}
+	if h.hub != nil {
+		members := h.hub.GetMemberSnapshot()
+		preview.MemberCount = len(members)
+		for _, member := range members {
+			if member.Status != "offline" {
+				preview.OnlineCount++
+			}
+		}
+	}
+
+	writeJSON(w, http.StatusOK, preview)
+}
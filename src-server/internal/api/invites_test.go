@@ -0,0 +1,97 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"lobby/internal/auth"
+	sqldb "lobby/internal/db/sqlc"
+	"lobby/internal/models"
+)
+
+func invitePreviewRequest(handler *InviteHandler, code string) *httptest.ResponseRecorder {
+	return serveRequest(handler.GetInvitePreview, newAuthedRequest(http.MethodGet, "/api/v1/invites/"+code, "", "", map[string]string{"code": code}))
+}
+
+func TestInviteAdmitsRegistrationWhenInviteOnly(t *testing.T) {
+	database := openTestDB(t)
+	queries := database.Queries()
+	now := time.Now().UTC()
+	if err := queries.CreateUser(context.Background(), sqldb.CreateUserParams{
+		ID:        "usr_mod",
+		Username:  "mod",
+		Email:     "mod@example.com",
+		CreatedAt: now,
+	}); err != nil {
+		t.Fatalf("CreateUser() error = %v", err)
+	}
+	inviteHandler := NewInviteHandler(queries, nil, "Lobby", "https://lobby.example.com/")
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/invites", strings.NewReader(`{"maxUses":1}`))
+	rr := httptest.NewRecorder()
+	inviteHandler.CreateInvite(rr, req.WithContext(context.WithValue(req.Context(), userIDKey, "usr_mod")))
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("create status = %d, want %d, body=%q", rr.Code, http.StatusCreated, rr.Body.String())
+	}
+	var invite InviteResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &invite); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v, body=%q", err, rr.Body.String())
+	}
+	if invite.URL != "https://lobby.example.com/invite/"+invite.Code {
+		t.Fatalf("url = %q", invite.URL)
+	}
+
+	rr = invitePreviewRequest(inviteHandler, invite.Code)
+	if rr.Code != http.StatusOK || !strings.Contains(rr.Body.String(), `"serverName":"Lobby"`) {
+		t.Fatalf("preview status = %d, body=%q", rr.Code, rr.Body.String())
+	}
+	if rr := invitePreviewRequest(inviteHandler, "missing"); rr.Code != http.StatusNotFound {
+		t.Fatalf("missing preview status = %d, want %d", rr.Code, http.StatusNotFound)
+	}
+
+	if err := queries.CreateMagicCode(context.Background(), sqldb.CreateMagicCodeParams{
+		ID:        "mgc_1",
+		Email:     "new@example.com",
+		CodeHash:  auth.HashMagicCode("new@example.com", "123456"),
+		ExpiresAt: now.Add(time.Minute),
+		CreatedAt: now,
+	}); err != nil {
+		t.Fatalf("CreateMagicCode() error = %v", err)
+	}
+	authHandler := NewAuthHandler(database, queries, nil, nil, nil, time.Minute, models.RegistrationInviteOnly, "", nil, nil)
+	req = httptest.NewRequest(http.MethodPost, "/api/v1/auth/login/magic-code/verify",
+		strings.NewReader(`{"email":"new@example.com","code":"123456","inviteCode":"`+invite.Code+`"}`))
+	rr = httptest.NewRecorder()
+	authHandler.VerifyMagicCode(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("verify status = %d, want %d, body=%q", rr.Code, http.StatusOK, rr.Body.String())
+	}
+	var verify VerifyMagicCodeResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &verify); err != nil || verify.Next != "register" {
+		t.Fatalf("unexpected verify response: %q", rr.Body.String())
+	}
+
+	// Exhaust the invite before registering; the registration token must
+	// survive the failed attempt.
+	if _, err := queries.UseInvite(context.Background(), sqldb.UseInviteParams{Code: invite.Code, Now: &now}); err != nil {
+		t.Fatalf("UseInvite() error = %v", err)
+	}
+	req = httptest.NewRequest(http.MethodPost, "/api/v1/auth/register",
+		strings.NewReader(`{"registrationToken":"`+verify.RegistrationToken+`","username":"newbie"}`))
+	rr = httptest.NewRecorder()
+	authHandler.Register(rr, req)
+	if rr.Code != http.StatusForbidden || !strings.Contains(rr.Body.String(), ErrCodeInviteInvalid) {
+		t.Fatalf("register status = %d, body=%q", rr.Code, rr.Body.String())
+	}
+	if _, err := queries.GetValidRegistrationToken(context.Background(), sqldb.GetValidRegistrationTokenParams{
+		TokenHash: auth.HashRegistrationToken(verify.RegistrationToken),
+		Now:       time.Now().UTC(),
+	}); err != nil {
+		t.Fatalf("GetValidRegistrationToken() error = %v, want token to remain unused", err)
+	}
+}
@@ -0,0 +1,89 @@
+package api
+
+import (
+	"context"
+	"html"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"lobby/internal/auth"
+	sqldb "lobby/internal/db/sqlc"
+	"lobby/internal/models"
+)
+
+func TestVerifyMagicLink(t *testing.T) {
+	database := openTestDB(t)
+	queries := database.Queries()
+	now := time.Now().UTC()
+	if err := queries.CreateMagicCode(context.Background(), sqldb.CreateMagicCodeParams{
+		ID:        "mgc_1",
+		Email:     "new@example.com",
+		CodeHash:  auth.HashMagicCode("new@example.com", "123456"),
+		ExpiresAt: now.Add(time.Minute),
+		CreatedAt: now,
+	}); err != nil {
+		t.Fatalf("CreateMagicCode() error = %v", err)
+	}
+
+	magicService := auth.NewMagicCodeService(time.Minute, "test-secret")
+	handler := NewAuthHandler(database, queries, nil, magicService, nil, time.Minute, models.RegistrationOpen, "https://lobby.example.com", nil, nil)
+	verify := func(token string) *httptest.ResponseRecorder {
+		body := `{"token":"` + token + `"}`
+		rr := httptest.NewRecorder()
+		handler.VerifyMagicLink(rr, httptest.NewRequest(http.MethodPost, "/api/v1/auth/login/magic-link", strings.NewReader(body)))
+		return rr
+	}
+	show := func(token string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		handler.ShowMagicLink(rr, httptest.NewRequest(http.MethodGet, "/api/v1/auth/login/magic-link?token="+url.QueryEscape(token), nil))
+		return rr
+	}
+
+	token := magicService.LinkToken("mgc_1")
+	tampered := token[:len(token)-1] + "0"
+	if strings.HasSuffix(token, "0") {
+		tampered = token[:len(token)-1] + "1"
+	}
+	if rr := verify(tampered); rr.Code != http.StatusUnauthorized {
+		t.Fatalf("tampered link status = %d, want %d", rr.Code, http.StatusUnauthorized)
+	}
+	if rr := verify(auth.NewMagicCodeService(time.Minute, "other-secret").LinkToken("mgc_1")); rr.Code != http.StatusUnauthorized {
+		t.Fatalf("foreign link status = %d, want %d", rr.Code, http.StatusUnauthorized)
+	}
+
+	// Opening the link, as a mail scanner would, must not redeem it.
+	for range 2 {
+		rr := show(token)
+		if rr.Code != http.StatusOK || !strings.HasPrefix(rr.Header().Get("Content-Type"), "text/html") {
+			t.Fatalf("link page status = %d, content type %q, want an HTML page", rr.Code, rr.Header().Get("Content-Type"))
+		}
+		if got := rr.Header().Get("Cache-Control"); got != "no-store" {
+			t.Fatalf("link page Cache-Control = %q, want no-store", got)
+		}
+		if strings.Contains(rr.Body.String(), "accessToken") {
+			t.Fatalf("link page body = %q, want no tokens", rr.Body.String())
+		}
+		appLink := "lobby://magic-link?" + url.Values{"server": {"https://lobby.example.com"}, "token": {token}}.Encode()
+		if !strings.Contains(rr.Body.String(), html.EscapeString(appLink)) {
+			t.Fatalf("link page body = %q, want a deep link to %s", rr.Body.String(), appLink)
+		}
+	}
+	if rr := show(tampered); rr.Code != http.StatusBadRequest {
+		t.Fatalf("tampered link page status = %d, want %d", rr.Code, http.StatusBadRequest)
+	}
+
+	rr := verify(token)
+	if got := rr.Header().Get("Cache-Control"); got != "no-store" {
+		t.Fatalf("verify Cache-Control = %q, want no-store", got)
+	}
+	if rr.Code != http.StatusOK || !strings.Contains(rr.Body.String(), `"next":"register"`) {
+		t.Fatalf("link status = %d, body=%q, want registration", rr.Code, rr.Body.String())
+	}
+	if rr := verify(token); rr.Code != http.StatusUnauthorized {
+		t.Fatalf("reused link status = %d, want %d", rr.Code, http.StatusUnauthorized)
+	}
+}
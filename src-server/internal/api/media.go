@@ -4,27 +4,59 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"log/slog"
 	"net/http"
+	"net/url"
 	"os"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 
 	"lobby/internal/blob"
 	sqldb "lobby/internal/db/sqlc"
+	"lobby/internal/mediaurl"
 )
 
+// immutableCacheControl lets anyone cache media for good; a blob ID never
+// names other bytes.
+const immutableCacheControl = "public, max-age=31536000, immutable"
+
 type MediaHandler struct {
 	queries *sqldb.Queries
 	blobs   *blob.Service
+
+	// refererOrigins lists the sites allowed to embed media; nil disables
+	// hotlink protection.
+	refererOrigins []string
+
+	// signer, when set, requires chat attachments to be fetched with a
+	// signed URL or an access token.
+	signer *mediaurl.Signer
 }
 
-func NewMediaHandler(queries *sqldb.Queries, blobs *blob.Service) *MediaHandler {
-	return &MediaHandler{queries: queries, blobs: blobs}
+// NewMediaHandler serves /media. With hotlink protection on, only
+// refererOrigins (plus loopback) may refer media requests.
+func NewMediaHandler(queries *sqldb.Queries, blobs *blob.Service, hotlinkProtection bool, refererOrigins []string) *MediaHandler {
+	h := &MediaHandler{queries: queries, blobs: blobs}
+	if hotlinkProtection {
+		h.refererOrigins = append([]string{}, refererOrigins...)
+	}
+	return h
+}
+
+// SetSigner requires chat attachments to be fetched with a URL signed by
+// signer, or with an access token. Avatars and server images stay public.
+func (h *MediaHandler) SetSigner(signer *mediaurl.Signer) {
+	h.signer = signer
 }
 
 func (h *MediaHandler) GetBlob(w http.ResponseWriter, r *http.Request) {
+	if !h.allowReferer(w, r) {
+		return
+	}
+
 	blobID := strings.TrimSpace(chi.URLParam(r, "blobID"))
 	if blobID == "" {
 		notFound(w, "Media not found")
@@ -40,6 +72,14 @@ func (h *MediaHandler) GetBlob(w http.ResponseWriter, r *http.Request) {
 		internalError(w)
 		return
 	}
+	if isInfected(row) {
+		notFound(w, "Media not found")
+		return
+	}
+	cacheControl, ok := h.authorize(w, r, row.ID, row.Kind)
+	if !ok {
+		return
+	}
 
 	file, err := h.blobs.Open(row.StoragePath)
 	if errors.Is(err, os.ErrNotExist) {
@@ -52,8 +92,19 @@ func (h *MediaHandler) GetBlob(w http.ResponseWriter, r *http.Request) {
 	}
 	defer file.Close()
 
-	w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
-	w.Header().Set("ETag", fmt.Sprintf("\"%s\"", row.ID))
+	etag := fmt.Sprintf("\"%s\"", row.ID)
+	if countsAsDownload(r, etag) {
+		now := time.Now().UTC()
+		if err := h.queries.IncrementBlobDownloadCount(r.Context(), sqldb.IncrementBlobDownloadCountParams{
+			DownloadedAt: &now,
+			ID:           row.ID,
+		}); err != nil {
+			slog.Warn("failed to count media download", "blob_id", row.ID, "error", err)
+		}
+	}
+
+	w.Header().Set("Cache-Control", cacheControl)
+	w.Header().Set("ETag", etag)
 	w.Header().Set("Content-Type", row.MimeType)
 
 	fileName := sanitizeDispositionFilename(row.OriginalName)
@@ -69,6 +120,10 @@ func (h *MediaHandler) GetBlob(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *MediaHandler) GetBlobPreview(w http.ResponseWriter, r *http.Request) {
+	if !h.allowReferer(w, r) {
+		return
+	}
+
 	blobID := strings.TrimSpace(chi.URLParam(r, "blobID"))
 	if blobID == "" {
 		notFound(w, "Media preview not found")
@@ -85,6 +140,14 @@ func (h *MediaHandler) GetBlobPreview(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if isInfected(row) {
+		notFound(w, "Media preview not found")
+		return
+	}
+	cacheControl, ok := h.authorize(w, r, row.ID, row.Kind)
+	if !ok {
+		return
+	}
 	if row.PreviewStoragePath == nil || row.PreviewMimeType == nil {
 		notFound(w, "Media preview not found")
 		return
@@ -101,7 +164,7 @@ func (h *MediaHandler) GetBlobPreview(w http.ResponseWriter, r *http.Request) {
 	}
 	defer file.Close()
 
-	w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+	w.Header().Set("Cache-Control", cacheControl)
 	w.Header().Set("ETag", fmt.Sprintf("\"%s-preview\"", row.ID))
 	w.Header().Set("Content-Type", *row.PreviewMimeType)
 
@@ -111,6 +174,87 @@ func (h *MediaHandler) GetBlobPreview(w http.ResponseWriter, r *http.Request) {
 	http.ServeContent(w, r, row.OriginalName, row.CreatedAt, file)
 }
 
+// allowReferer rejects media requests referred by a site outside
+// refererOrigins. Requests without Origin or Referer pass, since desktop
+// clients, direct visits, and privacy settings all omit them.
+func (h *MediaHandler) allowReferer(w http.ResponseWriter, r *http.Request) bool {
+	if h.refererOrigins == nil {
+		return true
+	}
+
+	origin := strings.TrimSpace(r.Header.Get("Origin"))
+	if origin == "" || origin == "null" {
+		origin = refererOrigin(r.Header.Get("Referer"))
+	}
+	if origin == "" || isOriginAllowed(origin, h.refererOrigins) {
+		return true
+	}
+
+	slog.Debug("media hotlink rejected", "origin", origin, "path", r.URL.Path)
+	forbidden(w, "Media cannot be embedded from this site")
+	return false
+}
+
+// authorize checks a request for a chat attachment when signed URLs are on:
+// it needs an unexpired signature or an authenticated user. It returns the
+// Cache-Control to answer with, which keeps signed responses out of shared
+// caches and drops them from the browser's when the signature expires.
+func (h *MediaHandler) authorize(w http.ResponseWriter, r *http.Request, blobID, kind string) (string, bool) {
+	if h.signer == nil || kind != string(blob.KindChatAttachment) {
+		return immutableCacheControl, true
+	}
+	if remaining, ok := h.signer.Verify(blobID, r.URL.Query()); ok {
+		return fmt.Sprintf("private, max-age=%d", int(remaining.Seconds())), true
+	}
+	if GetUserID(r) != "" {
+		return "private, max-age=31536000, immutable", true
+	}
+
+	if r.URL.Query().Has(mediaurl.SignatureParam) {
+		forbidden(w, "Media link has expired")
+	} else {
+		unauthorized(w, "Media link must be signed")
+	}
+	return "", false
+}
+
+// isInfected reports whether row is a quarantined upload, which is kept for
+// review but never served.
+func isInfected(row sqldb.GetBlobByIDRow) bool {
+	return row.ScanStatus != nil && *row.ScanStatus == blob.ScanStatusInfected
+}
+
+// mediaRefererOrigins lists the sites that may embed media: the server
+// itself, the origins allowed to open websockets, and any extra referers.
+func mediaRefererOrigins(baseURL string, allowedOrigins, allowedReferers []string) []string {
+	origins := make([]string, 0, 1+len(allowedOrigins)+len(allowedReferers))
+	if origin := refererOrigin(baseURL); origin != "" {
+		origins = append(origins, origin)
+	}
+	origins = append(origins, allowedOrigins...)
+	return append(origins, allowedReferers...)
+}
+
+// refererOrigin reduces a Referer URL to its scheme://host[:port] origin.
+func refererOrigin(referer string) string {
+	u, err := url.Parse(strings.TrimSpace(referer))
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return ""
+	}
+	return u.Scheme + "://" + u.Host
+}
+
+// countsAsDownload reports whether a GET fetches the file rather than
+// revalidating it or seeking within it, so range requests from media players
+// count once.
+func countsAsDownload(r *http.Request, etag string) bool {
+	if r.Method != http.MethodGet || r.Header.Get("If-None-Match") == etag {
+		return false
+	}
+	rangeHeader := strings.TrimSpace(r.Header.Get("Range"))
+	return rangeHeader == "" || strings.HasPrefix(rangeHeader, "bytes=0-")
+}
+
 func sanitizeDispositionFilename(name string) string {
 	name = strings.TrimSpace(name)
 	if name == "" {
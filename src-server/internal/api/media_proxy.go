@@ -0,0 +1,176 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"lobby/internal/mediaurl"
+)
+
+const (
+	// mediaProxyMaxRedirects bounds the redirects followed for one image.
+	mediaProxyMaxRedirects = 4
+	// mediaProxyCacheControl lets clients keep a proxied image for a day;
+	// the host may change it, unlike a blob.
+	mediaProxyCacheControl = "public, max-age=86400"
+)
+
+var errMediaProxyAddress = errors.New("address is not public")
+
+// MediaProxyHandler serves external images from the server's origin, so the
+// image host sees the server rather than each client. It fetches only URLs
+// signed by proxy, and only images up to maxBytes.
+type MediaProxyHandler struct {
+	proxy    *mediaurl.Proxy
+	client   *http.Client
+	maxBytes int64
+
+	// allowPrivate lets tests fetch from loopback servers.
+	allowPrivate bool
+}
+
+func NewMediaProxyHandler(proxy *mediaurl.Proxy, maxBytes int64, timeout time.Duration) *MediaProxyHandler {
+	h := &MediaProxyHandler{proxy: proxy, maxBytes: maxBytes}
+	dialer := &net.Dialer{Timeout: timeout, Control: h.checkDialAddress}
+	h.client = &http.Client{
+		Timeout: timeout,
+		// No environment proxy: the dialer must see the image host's address.
+		Transport: &http.Transport{
+			DialContext:           dialer.DialContext,
+			TLSHandshakeTimeout:   timeout,
+			ResponseHeaderTimeout: timeout,
+			MaxIdleConnsPerHost:   2,
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) > mediaProxyMaxRedirects {
+				return fmt.Errorf("stopped after %d redirects", mediaProxyMaxRedirects)
+			}
+			if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+				return fmt.Errorf("redirect to unsupported scheme %q", req.URL.Scheme)
+			}
+			return nil
+		},
+	}
+	return h
+}
+
+// GET /media/proxy/{signature}?url=
+func (h *MediaProxyHandler) GetImage(w http.ResponseWriter, r *http.Request) {
+	target := r.URL.Query().Get(mediaurl.ProxyURLParam)
+	if target == "" || !h.proxy.Verify(chi.URLParam(r, "signature"), target) {
+		forbidden(w, "Image link is not signed by this server")
+		return
+	}
+	u, err := url.Parse(target)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		badRequest(w, "Image link must be an http or https URL")
+		return
+	}
+
+	req, err := http.NewRequestWithContext(r.Context(), http.MethodGet, u.String(), nil)
+	if err != nil {
+		badRequest(w, "Image link must be an http or https URL")
+		return
+	}
+	req.Header.Set("Accept", "image/*")
+	req.Header.Set("User-Agent", "Lobby media proxy")
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		slog.Debug("media proxy fetch failed", "url", target, "error", err)
+		writeError(w, http.StatusBadGateway, ErrCodeNotFound, "Image could not be fetched")
+		return
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		writeError(w, http.StatusBadGateway, ErrCodeNotFound, "Image could not be fetched")
+		return
+	}
+	// SVG can carry script, so it is not served from the server's origin.
+	mimeType := strings.ToLower(strings.TrimSpace(strings.Split(resp.Header.Get("Content-Type"), ";")[0]))
+	if !strings.HasPrefix(mimeType, "image/") || mimeType == "image/svg+xml" {
+		writeError(w, http.StatusBadGateway, ErrCodeNotFound, "Link is not an image")
+		return
+	}
+	if resp.ContentLength > h.maxBytes {
+		payloadTooLarge(w, "Image exceeds the proxy size limit")
+		return
+	}
+
+	// Read it all first, so an oversized image without a Content-Length
+	// still gets an error rather than a truncated body.
+	data, err := io.ReadAll(io.LimitReader(resp.Body, h.maxBytes+1))
+	if err != nil {
+		writeError(w, http.StatusBadGateway, ErrCodeNotFound, "Image could not be fetched")
+		return
+	}
+	if int64(len(data)) > h.maxBytes {
+		payloadTooLarge(w, "Image exceeds the proxy size limit")
+		return
+	}
+
+	w.Header().Set("Content-Type", mimeType)
+	w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+	w.Header().Set("Content-Disposition", "inline")
+	w.Header().Set("Content-Security-Policy", "default-src 'none'")
+	w.Header().Set("Cache-Control", mediaProxyCacheControl)
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(data)
+}
+
+// checkDialAddress keeps the proxy from reaching the server's own network:
+// it refuses loopback, private, link-local and other non-public addresses,
+// after DNS resolution and on every redirect.
+func (h *MediaProxyHandler) checkDialAddress(_, address string, _ syscall.RawConn) error {
+	if h.allowPrivate {
+		return nil
+	}
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip, err := netip.ParseAddr(host)
+	if err != nil {
+		return err
+	}
+	if !isPublicAddr(ip.Unmap()) {
+		return fmt.Errorf("%w: %s", errMediaProxyAddress, ip)
+	}
+	return nil
+}
+
+// nonPublicPrefixes are global unicast in name only: carrier-grade NAT
+// space, "this network", and the NAT64 and 6to4 prefixes, which embed an
+// IPv4 address a gateway may forward to the server's own network.
+var nonPublicPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("64:ff9b::/96"),
+	netip.MustParsePrefix("64:ff9b:1::/48"),
+	netip.MustParsePrefix("2002::/16"),
+}
+
+func isPublicAddr(ip netip.Addr) bool {
+	if !ip.IsGlobalUnicast() || ip.IsPrivate() || ip.IsLoopback() || ip.IsLinkLocalUnicast() {
+		return false
+	}
+	for _, prefix := range nonPublicPrefixes {
+		if prefix.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,126 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"lobby/internal/mediaurl"
+)
+
+// mediaProxyRequest fetches proxyURL through a router, which fills in the
+// {signature} path parameter.
+func mediaProxyRequest(handler http.HandlerFunc, proxyURL string) *httptest.ResponseRecorder {
+	router := chi.NewRouter()
+	router.Get(mediaurl.ProxyPathPrefix+"{signature}", handler)
+	return serveRequest(router.ServeHTTP, httptest.NewRequest(http.MethodGet, proxyURL, nil))
+}
+
+func TestMediaProxyServesSignedImages(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/cat.png":
+			w.Header().Set("Content-Type", "image/png")
+			_, _ = w.Write([]byte("png bytes"))
+		case "/big.png":
+			w.Header().Set("Content-Type", "image/png")
+			_, _ = w.Write([]byte(strings.Repeat("x", 64)))
+		case "/logo.svg":
+			w.Header().Set("Content-Type", "image/svg+xml")
+			_, _ = w.Write([]byte("<svg></svg>"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer upstream.Close()
+
+	proxy := mediaurl.NewProxy("test-secret")
+	handler := NewMediaProxyHandler(proxy, 32, 5*time.Second)
+	handler.allowPrivate = true
+
+	rr := mediaProxyRequest(handler.GetImage, proxy.URL("", upstream.URL+"/cat.png"))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("signed image status = %d, want %d: %s", rr.Code, http.StatusOK, rr.Body.String())
+	}
+	if got := rr.Body.String(); got != "png bytes" {
+		t.Fatalf("body = %q, want %q", got, "png bytes")
+	}
+	if got := rr.Header().Get("Content-Type"); got != "image/png" {
+		t.Fatalf("Content-Type = %q, want image/png", got)
+	}
+	if got := rr.Header().Get("Content-Security-Policy"); got != "default-src 'none'" {
+		t.Fatalf("Content-Security-Policy = %q", got)
+	}
+
+	forged := mediaurl.ProxyPathPrefix + "forged?" + url.Values{mediaurl.ProxyURLParam: {upstream.URL + "/cat.png"}}.Encode()
+	if rr := mediaProxyRequest(handler.GetImage, forged); rr.Code != http.StatusForbidden {
+		t.Fatalf("forged signature status = %d, want %d", rr.Code, http.StatusForbidden)
+	}
+
+	if rr := mediaProxyRequest(handler.GetImage, proxy.URL("", upstream.URL+"/logo.svg")); rr.Code != http.StatusBadGateway {
+		t.Fatalf("svg status = %d, want %d", rr.Code, http.StatusBadGateway)
+	}
+	if rr := mediaProxyRequest(handler.GetImage, proxy.URL("", upstream.URL+"/missing.png")); rr.Code != http.StatusBadGateway {
+		t.Fatalf("missing image status = %d, want %d", rr.Code, http.StatusBadGateway)
+	}
+	if rr := mediaProxyRequest(handler.GetImage, proxy.URL("", upstream.URL+"/big.png")); rr.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("oversized image status = %d, want %d", rr.Code, http.StatusRequestEntityTooLarge)
+	}
+}
+
+func TestMediaProxyRefusesPrivateAddresses(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		_, _ = w.Write([]byte("png bytes"))
+	}))
+	defer upstream.Close()
+
+	proxy := mediaurl.NewProxy("test-secret")
+	handler := NewMediaProxyHandler(proxy, 1024, 5*time.Second)
+
+	rr := mediaProxyRequest(handler.GetImage, proxy.URL("", upstream.URL+"/cat.png"))
+	if rr.Code != http.StatusBadGateway {
+		t.Fatalf("loopback image status = %d, want %d", rr.Code, http.StatusBadGateway)
+	}
+}
+
+func TestIsPublicAddr(t *testing.T) {
+	tests := map[string]bool{
+		"93.184.215.14":         true,
+		"2606:2800:21f:cb07::1": true,
+		"127.0.0.1":             false,
+		"10.1.2.3":              false,
+		"100.64.0.1":            false,
+		"0.1.2.3":               false,
+		"64:ff9b::a00:1":        false, // NAT64 of 10.0.0.1
+		"2002:a00:1::1":         false, // 6to4 of 10.0.0.1
+		"fd00::1":               false,
+		"fe80::1":               false,
+	}
+	for addr, want := range tests {
+		if got := isPublicAddr(netip.MustParseAddr(addr)); got != want {
+			t.Fatalf("isPublicAddr(%s) = %v, want %v", addr, got, want)
+		}
+	}
+}
+
+func TestMediaProxyImageLinks(t *testing.T) {
+	content := `<p><a href="https://example.com/a.PNG">a</a> ` +
+		`<a href="https://example.com/page">page</a> ` +
+		`<a href="https://example.com/a.PNG">again</a> ` +
+		`<a href="ftp://example.com/b.png">ftp</a> ` +
+		`<a href="http://example.com/c.webp?size=large&amp;v=2">c</a></p>`
+
+	got := mediaurl.ImageLinks(content)
+	want := []string{"https://example.com/a.PNG", "http://example.com/c.webp?size=large&v=2"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("ImageLinks() = %v, want %v", got, want)
+	}
+}
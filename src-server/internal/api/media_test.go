@@ -0,0 +1,201 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"lobby/internal/blob"
+	"lobby/internal/clock"
+	sqldb "lobby/internal/db/sqlc"
+	"lobby/internal/mediaurl"
+)
+
+func seedMediaBlob(t *testing.T, queries *sqldb.Queries, blobs *blob.Service) string {
+	t.Helper()
+
+	now := time.Now().UTC()
+	if err := queries.CreateUser(context.Background(), sqldb.CreateUserParams{
+		ID:        "usr_1",
+		Username:  "alice",
+		Email:     "alice@example.com",
+		CreatedAt: now,
+	}); err != nil {
+		t.Fatalf("CreateUser() error = %v", err)
+	}
+	stored, err := blobs.Save(context.Background(), blob.KindChatAttachment, "notes.txt", strings.NewReader("hello media"))
+	if err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	if err := queries.CreateBlob(context.Background(), sqldb.CreateBlobParams{
+		ID:           stored.ID,
+		Kind:         string(stored.Kind),
+		UploadedBy:   "usr_1",
+		StoragePath:  stored.StoragePath,
+		MimeType:     stored.MimeType,
+		SizeBytes:    stored.SizeBytes,
+		OriginalName: stored.OriginalName,
+		CreatedAt:    now,
+	}); err != nil {
+		t.Fatalf("CreateBlob() error = %v", err)
+	}
+	return stored.ID
+}
+
+func mediaRequest(handler http.HandlerFunc, blobID string, headers map[string]string) *httptest.ResponseRecorder {
+	req := newAuthedRequest(http.MethodGet, "/media/"+blobID, "", "", map[string]string{"blobID": blobID})
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+	return serveRequest(handler, req)
+}
+
+func TestMediaHotlinkProtection(t *testing.T) {
+	database := openTestDB(t)
+	blobs, err := blob.NewService(t.TempDir(), 1024)
+	if err != nil {
+		t.Fatalf("blob.NewService() error = %v", err)
+	}
+	blobID := seedMediaBlob(t, database.Queries(), blobs)
+	origins := mediaRefererOrigins("https://chat.example.com/app", nil, []string{"https://wiki.example.com"})
+	handler := NewMediaHandler(database.Queries(), blobs, true, origins)
+
+	tests := []struct {
+		name    string
+		headers map[string]string
+		want    int
+	}{
+		{name: "no referer", want: http.StatusOK},
+		{name: "own site", headers: map[string]string{"Referer": "https://chat.example.com/channels/1"}, want: http.StatusOK},
+		{name: "allowed referer", headers: map[string]string{"Referer": "https://wiki.example.com/page"}, want: http.StatusOK},
+		{name: "loopback", headers: map[string]string{"Referer": "http://localhost:5173/"}, want: http.StatusOK},
+		{name: "other site", headers: map[string]string{"Referer": "https://forum.example.org/thread"}, want: http.StatusForbidden},
+		{name: "other origin", headers: map[string]string{"Origin": "https://forum.example.org"}, want: http.StatusForbidden},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if rr := mediaRequest(handler.GetBlob, blobID, tc.headers); rr.Code != tc.want {
+				t.Fatalf("status = %d, want %d, body=%q", rr.Code, tc.want, rr.Body.String())
+			}
+		})
+	}
+
+	unprotected := NewMediaHandler(database.Queries(), blobs, false, origins)
+	if rr := mediaRequest(unprotected.GetBlob, blobID, map[string]string{"Referer": "https://forum.example.org/thread"}); rr.Code != http.StatusOK {
+		t.Fatalf("unprotected status = %d, want %d", rr.Code, http.StatusOK)
+	}
+}
+
+func TestMediaSignedURLs(t *testing.T) {
+	database := openTestDB(t)
+	blobs, err := blob.NewService(t.TempDir(), 1024)
+	if err != nil {
+		t.Fatalf("blob.NewService() error = %v", err)
+	}
+	blobID := seedMediaBlob(t, database.Queries(), blobs)
+	fake := clock.NewFake(time.Unix(1700000000, 0))
+	signer := mediaurl.NewSigner("test-secret-that-is-long-enough-1234", time.Hour)
+	signer.SetClock(fake)
+	handler := NewMediaHandler(database.Queries(), blobs, false, nil)
+	handler.SetSigner(signer)
+
+	router := chi.NewRouter()
+	router.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Authorization") != "" {
+				r = r.WithContext(context.WithValue(r.Context(), userIDKey, "usr_1"))
+			}
+			next.ServeHTTP(w, r)
+		})
+	})
+	router.Get("/media/{blobID}", handler.GetBlob)
+	get := func(target string, headers map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		for key, value := range headers {
+			req.Header.Set(key, value)
+		}
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	signed := signer.Blob("", blobID)
+	rr := get(signed, nil)
+	if rr.Code != http.StatusOK || rr.Body.String() != "hello media" {
+		t.Fatalf("signed status = %d, body=%q", rr.Code, rr.Body.String())
+	}
+	if cc := rr.Header().Get("Cache-Control"); !strings.HasPrefix(cc, "private, max-age=") {
+		t.Fatalf("signed Cache-Control = %q, want private", cc)
+	}
+	if signer.Blob("", blobID) != signed {
+		t.Fatalf("signing again moments later gave a new URL")
+	}
+	if rr := get(mediaurl.Blob("", blobID), nil); rr.Code != http.StatusUnauthorized {
+		t.Fatalf("unsigned status = %d, want %d", rr.Code, http.StatusUnauthorized)
+	}
+	if rr := get(mediaurl.Blob("", blobID), map[string]string{"Authorization": "Bearer token"}); rr.Code != http.StatusOK {
+		t.Fatalf("authenticated status = %d, want %d", rr.Code, http.StatusOK)
+	}
+	// Stretching the expiry breaks the signature
+	u, _ := url.Parse(signed)
+	query := u.Query()
+	query.Set(mediaurl.ExpiresParam, "9999999999")
+	if rr := get(u.Path+"?"+query.Encode(), nil); rr.Code != http.StatusForbidden {
+		t.Fatalf("tampered status = %d, want %d", rr.Code, http.StatusForbidden)
+	}
+
+	fake.Advance(2 * time.Hour)
+	if rr := get(signed, nil); rr.Code != http.StatusForbidden {
+		t.Fatalf("expired status = %d, want %d", rr.Code, http.StatusForbidden)
+	}
+	if rr := get(signer.Blob("", blobID), nil); rr.Code != http.StatusOK {
+		t.Fatalf("freshly signed status = %d, want %d", rr.Code, http.StatusOK)
+	}
+}
+
+func TestMediaDownloadsFeedAdminStats(t *testing.T) {
+	database := openTestDB(t)
+	blobs, err := blob.NewService(t.TempDir(), 1024)
+	if err != nil {
+		t.Fatalf("blob.NewService() error = %v", err)
+	}
+	blobID := seedMediaBlob(t, database.Queries(), blobs)
+	handler := NewMediaHandler(database.Queries(), blobs, false, nil)
+
+	for _, headers := range []map[string]string{
+		nil,
+		{"Range": "bytes=0-3"},
+		{"Range": "bytes=4-"},                 // seeking, not a new download
+		{"If-None-Match": `"` + blobID + `"`}, // revalidation
+	} {
+		if rr := mediaRequest(handler.GetBlob, blobID, headers); rr.Code >= http.StatusBadRequest {
+			t.Fatalf("GetBlob(%v) status = %d", headers, rr.Code)
+		}
+	}
+
+	rr := httptest.NewRecorder()
+	NewAdminHandler(database.Queries(), nil).GetStats(rr, httptest.NewRequest(http.MethodGet, "/api/v1/admin/stats?limit=5", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("stats status = %d, body=%q", rr.Code, rr.Body.String())
+	}
+	var stats AdminStatsResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &stats); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v", err)
+	}
+	if stats.MediaDownloads != 2 || len(stats.TopMedia) != 1 || stats.TopMedia[0].ID != blobID || stats.TopMedia[0].Downloads != 2 {
+		t.Fatalf("unexpected stats: %+v", stats)
+	}
+
+	rr = httptest.NewRecorder()
+	NewAdminHandler(database.Queries(), nil).GetStats(rr, httptest.NewRequest(http.MethodGet, "/api/v1/admin/stats?limit=500", nil))
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("oversized limit status = %d, want %d", rr.Code, http.StatusBadRequest)
+	}
+}
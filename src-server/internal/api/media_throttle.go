@@ -0,0 +1,140 @@
+package api
+
+import (
+	"net/http"
+	"sync"
+
+	"golang.org/x/time/rate"
+)
+
+// mediaThrottleChunk is the most written to a client between limiter waits.
+const mediaThrottleChunk = 32 << 10
+
+// MediaThrottle limits /media responses, so one client mass-downloading
+// attachments can't take the uplink voice needs. Each download is held to
+// connectionRate, all of them together to the global rate, and downloads
+// past the concurrency caps get a 429. A zero setting is unlimited.
+type MediaThrottle struct {
+	connectionRate int64
+	global         *rate.Limiter
+	maxConcurrent  int
+	maxPerClient   int
+	ipResolver     *ClientIPResolver
+	mu             sync.Mutex
+	active         int
+	activeByClient map[string]int
+}
+
+func NewMediaThrottle(connectionBytesPerSec, globalBytesPerSec int64, maxConcurrent, maxPerClient int, ipResolver *ClientIPResolver) *MediaThrottle {
+	if ipResolver == nil {
+		ipResolver, _ = NewClientIPResolver(nil)
+	}
+	t := &MediaThrottle{
+		connectionRate: connectionBytesPerSec,
+		maxConcurrent:  maxConcurrent,
+		maxPerClient:   maxPerClient,
+		ipResolver:     ipResolver,
+		activeByClient: make(map[string]int),
+	}
+	if globalBytesPerSec > 0 {
+		t.global = newByteLimiter(globalBytesPerSec)
+	}
+	return t
+}
+
+func newByteLimiter(bytesPerSec int64) *rate.Limiter {
+	return rate.NewLimiter(rate.Limit(bytesPerSec), int(min(bytesPerSec, mediaThrottleChunk)))
+}
+
+func (t *MediaThrottle) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		client := t.ipResolver.Resolve(r)
+		if !t.acquire(client) {
+			w.Header().Set("Retry-After", "1")
+			writeError(w, http.StatusTooManyRequests, ErrCodeRateLimited, "Too many downloads at once")
+			return
+		}
+		defer t.release(client)
+
+		if t.connectionRate <= 0 && t.global == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		tw := &throttledWriter{ResponseWriter: w, r: r, global: t.global}
+		if t.connectionRate > 0 {
+			tw.connection = newByteLimiter(t.connectionRate)
+		}
+		next.ServeHTTP(tw, r)
+	})
+}
+
+func (t *MediaThrottle) acquire(client string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.maxConcurrent > 0 && t.active >= t.maxConcurrent {
+		return false
+	}
+	if t.maxPerClient > 0 && t.activeByClient[client] >= t.maxPerClient {
+		return false
+	}
+	t.active++
+	t.activeByClient[client]++
+	return true
+}
+
+func (t *MediaThrottle) release(client string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.active--
+	if t.activeByClient[client] <= 1 {
+		delete(t.activeByClient, client)
+	} else {
+		t.activeByClient[client]--
+	}
+}
+
+// throttledWriter waits on its limiters before each chunk of the body. A
+// client that disconnects ends the wait with the request's context.
+type throttledWriter struct {
+	http.ResponseWriter
+	r          *http.Request
+	connection *rate.Limiter
+	global     *rate.Limiter
+}
+
+func (w *throttledWriter) Write(p []byte) (int, error) {
+	written := 0
+	for len(p) > 0 {
+		n := min(len(p), w.chunkSize())
+		for _, limiter := range []*rate.Limiter{w.connection, w.global} {
+			if limiter == nil {
+				continue
+			}
+			if err := limiter.WaitN(w.r.Context(), n); err != nil {
+				return written, err
+			}
+		}
+		m, err := w.ResponseWriter.Write(p[:n])
+		written += m
+		if err != nil {
+			return written, err
+		}
+		p = p[n:]
+	}
+	return written, nil
+}
+
+// chunkSize keeps each wait within every limiter's burst.
+func (w *throttledWriter) chunkSize() int {
+	size := mediaThrottleChunk
+	for _, limiter := range []*rate.Limiter{w.connection, w.global} {
+		if limiter != nil {
+			size = min(size, limiter.Burst())
+		}
+	}
+	return size
+}
+
+func (w *throttledWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,77 @@
+package api
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestMediaThrottleCapsConcurrentDownloadsPerClient(t *testing.T) {
+	throttle := NewMediaThrottle(0, 0, 0, 1, nil)
+	started := make(chan struct{})
+	finish := make(chan struct{})
+	handler := throttle.Middleware(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		close(started)
+		<-finish
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	first := make(chan int)
+	go func() {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/media/blb_1", nil))
+		first <- rr.Code
+	}()
+	<-started
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/media/blb_2", nil))
+	if rr.Code != http.StatusTooManyRequests {
+		t.Fatalf("second download status = %d, want %d", rr.Code, http.StatusTooManyRequests)
+	}
+	if rr.Header().Get("Retry-After") == "" {
+		t.Fatal("second download has no Retry-After header")
+	}
+
+	other := httptest.NewRequest(http.MethodGet, "/media/blb_3", nil)
+	other.RemoteAddr = "192.0.2.7:1234"
+	rr = httptest.NewRecorder()
+	handler = throttle.Middleware(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	handler.ServeHTTP(rr, other)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("other client status = %d, want %d", rr.Code, http.StatusOK)
+	}
+
+	close(finish)
+	if code := <-first; code != http.StatusOK {
+		t.Fatalf("first download status = %d, want %d", code, http.StatusOK)
+	}
+	if len(throttle.activeByClient) != 0 || throttle.active != 0 {
+		t.Fatalf("downloads still counted after finishing: active = %d, by client = %v", throttle.active, throttle.activeByClient)
+	}
+}
+
+func TestMediaThrottleLimitsConnectionRate(t *testing.T) {
+	throttle := NewMediaThrottle(16<<10, 0, 0, 0, nil)
+	body := bytes.Repeat([]byte("x"), 24<<10)
+	handler := throttle.Middleware(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write(body)
+	}))
+
+	start := time.Now()
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/media/blb_1", nil))
+	elapsed := time.Since(start)
+
+	if rr.Body.Len() != len(body) {
+		t.Fatalf("body length = %d, want %d", rr.Body.Len(), len(body))
+	}
+	// The first 16 KiB is the burst; the remaining 8 KiB takes half a second.
+	if elapsed < 400*time.Millisecond {
+		t.Fatalf("download took %v, want it throttled to about 500ms", elapsed)
+	}
+}
@@ -4,41 +4,48 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"log/slog"
 	"net/http"
 	"strconv"
 	"strings"
-	"time"
 
 	"lobby/internal/constants"
+	"lobby/internal/db"
 	sqldb "lobby/internal/db/sqlc"
+	"lobby/internal/history"
 	"lobby/internal/mediaurl"
 	"lobby/internal/models"
 )
 
 const defaultMessageHistoryLimit = 50
 
-type historyMessageRow struct {
-	ID              string
-	AuthorID        string
-	AuthorName      string
-	AuthorAvatarURL *string
-	Content         string
-	CreatedAt       time.Time
-	EditedAt        *time.Time
-}
-
 type MessageHandler struct {
-	queries *sqldb.Queries
-	baseURL string
+	queries   *sqldb.Queries
+	baseURL   string
+	wordMask  *models.WordMask
+	mediaURLs *mediaurl.Signer
+	// mediaProxy, when set, adds proxied embeds for linked images.
+	mediaProxy *mediaurl.Proxy
 }
 
-func NewMessageHandler(queries *sqldb.Queries, baseURL string) *MessageHandler {
+func NewMessageHandler(queries *sqldb.Queries, baseURL string, wordMask *models.WordMask) *MessageHandler {
 	return &MessageHandler{
-		queries: queries,
-		baseURL: baseURL,
+		queries:  queries,
+		baseURL:  baseURL,
+		wordMask: wordMask,
 	}
 }
 
+// SetMediaSigner makes history sign the chat attachment URLs it returns.
+func (h *MessageHandler) SetMediaSigner(signer *mediaurl.Signer) {
+	h.mediaURLs = signer
+}
+
+// SetMediaProxy makes history carry proxied embeds for linked images.
+func (h *MessageHandler) SetMediaProxy(proxy *mediaurl.Proxy) {
+	h.mediaProxy = proxy
+}
+
 func (h *MessageHandler) GetHistory(w http.ResponseWriter, r *http.Request) {
 	limit, beforeID, validationMessage, ok := parseHistoryQuery(r)
 	if !ok {
@@ -46,36 +53,64 @@ func (h *MessageHandler) GetHistory(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	rows, err := h.listHistoryRows(r.Context(), beforeID, int64(limit))
+	allowed, err := canAccessTextChannel(r.Context(), h.queries, GetUserID(r), GetUserRole(r))
 	if err != nil {
+		slog.Error("error checking text channel access", "error", err)
 		internalError(w)
 		return
 	}
+	if !allowed {
+		forbidden(w, "You do not have access to this channel")
+		return
+	}
 
-	attachmentsByMessageID, err := h.listAttachmentsByMessageID(r.Context(), rows)
+	messages, err := h.loadHistory(r.Context(), GetUserRole(r), beforeID, limit)
 	if err != nil {
 		internalError(w)
 		return
 	}
 
-	messages := make([]*models.Message, 0, len(rows))
-	for _, row := range rows {
-		messages = append(messages, &models.Message{
-			ID:              row.ID,
-			AuthorID:        row.AuthorID,
-			AuthorName:      row.AuthorName,
-			AuthorAvatarURL: row.AuthorAvatarURL,
-			Content:         row.Content,
-			Attachments:     attachmentsByMessageID[row.ID],
-			CreatedAt:       row.CreatedAt,
-			EditedAt:        row.EditedAt,
-		})
-	}
-
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(messages)
 }
 
+// loadHistory returns up to limit messages before beforeID, or the newest
+// ones when beforeID is empty, as a caller with role sees them. It does not
+// check channel access.
+func (h *MessageHandler) loadHistory(ctx context.Context, role, beforeID string, limit int) ([]*models.Message, error) {
+	messages, err := history.Load(ctx, h.queries, h.baseURL, h.mediaURLs, beforeID, int64(limit))
+	if err != nil {
+		return nil, err
+	}
+
+	// Moderators and admins see masked words as written.
+	maskContent := !models.RoleAtLeast(role, models.RoleModerator)
+
+	for _, message := range messages {
+		if maskContent {
+			message.Content = h.wordMask.Mask(message.Content)
+		}
+		message.Embeds = h.messageEmbeds(message.Content)
+	}
+	return messages, nil
+}
+
+// messageEmbeds returns the proxied images linked in content.
+func (h *MessageHandler) messageEmbeds(content string) []models.MessageEmbed {
+	if h.mediaProxy == nil {
+		return nil
+	}
+	links := mediaurl.ImageLinks(content)
+	if len(links) == 0 {
+		return nil
+	}
+	embeds := make([]models.MessageEmbed, 0, len(links))
+	for _, link := range links {
+		embeds = append(embeds, models.MessageEmbed{URL: link, ProxyURL: h.mediaProxy.URL(h.baseURL, link)})
+	}
+	return embeds
+}
+
 func parseHistoryQuery(r *http.Request) (int, string, string, bool) {
 	limitStr := strings.TrimSpace(r.URL.Query().Get("limit"))
 	beforeID := strings.TrimSpace(r.URL.Query().Get("before"))
@@ -104,131 +139,17 @@ func isValidMessageID(id string) bool {
 		return false
 	}
 
-	hexPart := strings.TrimPrefix(id, "msg_")
-	if len(hexPart) != constants.IDRandomBytes*2 {
-		return false
-	}
-
-	for _, r := range hexPart {
-		if (r < '0' || r > '9') && (r < 'a' || r > 'f') {
-			return false
+	suffix := strings.TrimPrefix(id, "msg_")
+	switch len(suffix) {
+	case constants.IDRandomBytes * 2:
+		for _, r := range suffix {
+			if (r < '0' || r > '9') && (r < 'a' || r > 'f') {
+				return false
+			}
 		}
+		return true
+	case db.SortableIDLength:
+		return db.IsSortableIDSuffix(suffix)
 	}
-
-	return true
-}
-
-func (h *MessageHandler) listHistoryRows(ctx context.Context, beforeID string, limitRows int64) ([]historyMessageRow, error) {
-	if beforeID != "" {
-		rows, err := h.queries.ListMessageHistoryBefore(ctx, sqldb.ListMessageHistoryBeforeParams{
-			BeforeID:  beforeID,
-			LimitRows: limitRows,
-		})
-		if err != nil {
-			return nil, err
-		}
-
-		mapped := make([]historyMessageRow, 0, len(rows))
-		for _, row := range rows {
-			mapped = append(mapped, historyMessageRow{
-				ID:              row.ID,
-				AuthorID:        row.AuthorID,
-				AuthorName:      row.AuthorName,
-				AuthorAvatarURL: row.AuthorAvatarUrl,
-				Content:         row.Content,
-				CreatedAt:       row.CreatedAt,
-				EditedAt:        row.EditedAt,
-			})
-		}
-
-		return mapped, nil
-	}
-
-	rows, err := h.queries.ListMessageHistory(ctx, limitRows)
-	if err != nil {
-		return nil, err
-	}
-
-	mapped := make([]historyMessageRow, 0, len(rows))
-	for _, row := range rows {
-		mapped = append(mapped, historyMessageRow{
-			ID:              row.ID,
-			AuthorID:        row.AuthorID,
-			AuthorName:      row.AuthorName,
-			AuthorAvatarURL: row.AuthorAvatarUrl,
-			Content:         row.Content,
-			CreatedAt:       row.CreatedAt,
-			EditedAt:        row.EditedAt,
-		})
-	}
-
-	return mapped, nil
-}
-
-func (h *MessageHandler) listAttachmentsByMessageID(ctx context.Context, rows []historyMessageRow) (map[string][]models.MessageAttachment, error) {
-	attachmentsByMessageID := make(map[string][]models.MessageAttachment, len(rows))
-	if len(rows) == 0 {
-		return attachmentsByMessageID, nil
-	}
-
-	messageIDs := make([]*string, 0, len(rows))
-	for _, row := range rows {
-		messageID := row.ID
-		messageIDs = append(messageIDs, &messageID)
-	}
-
-	attachments, err := h.queries.ListMessageAttachmentsByMessageIDs(ctx, messageIDs)
-	if err != nil {
-		return nil, err
-	}
-
-	for _, attachment := range attachments {
-		if attachment.MessageID == nil || *attachment.MessageID == "" {
-			continue
-		}
-
-		mapped := h.modelAttachment(
-			attachment.ID,
-			attachment.OriginalName,
-			attachment.MimeType,
-			attachment.SizeBytes,
-			attachment.PreviewStoragePath,
-			attachment.PreviewWidth,
-			attachment.PreviewHeight,
-		)
-		messageID := *attachment.MessageID
-		attachmentsByMessageID[messageID] = append(attachmentsByMessageID[messageID], mapped)
-	}
-
-	return attachmentsByMessageID, nil
-}
-
-func (h *MessageHandler) modelAttachment(
-	id string,
-	originalName string,
-	mimeType string,
-	sizeBytes int64,
-	previewStoragePath *string,
-	previewWidth *int64,
-	previewHeight *int64,
-) models.MessageAttachment {
-	mapped := models.MessageAttachment{
-		ID:       id,
-		Name:     originalName,
-		MimeType: mimeType,
-		Size:     sizeBytes,
-		URL:      mediaurl.Blob(h.baseURL, id),
-	}
-
-	if previewStoragePath != nil {
-		mapped.PreviewURL = mediaurl.BlobPreview(h.baseURL, id)
-	}
-	if previewWidth != nil {
-		mapped.PreviewWidth = *previewWidth
-	}
-	if previewHeight != nil {
-		mapped.PreviewHeight = *previewHeight
-	}
-
-	return mapped
+	return false
 }
@@ -84,6 +84,10 @@ func TestIsValidMessageID(t *testing.T) {
 		{name: "wrong_length", id: "msg_0123456789abcdef", want: false},
 		{name: "uppercase_hex", id: "msg_0123456789ABCDEF01234567", want: false},
 		{name: "non_hex", id: "msg_0123456789abcdef0123456g", want: false},
+		{name: "sortable", id: "msg_01hz3v8k2q7m4n6p9r0s1t2v3w", want: true},
+		{name: "sortable_uppercase", id: "msg_01HZ3V8K2Q7M4N6P9R0S1T2V3W", want: false},
+		{name: "sortable_excluded_letter", id: "msg_01hz3v8k2q7m4n6p9r0s1t2v3u", want: false},
+		{name: "sortable_overflow", id: "msg_81hz3v8k2q7m4n6p9r0s1t2v3w", want: false},
 	}
 
 	for _, tt := range tests {
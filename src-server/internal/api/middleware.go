@@ -9,11 +9,16 @@ import (
 
 	"lobby/internal/auth"
 	sqldb "lobby/internal/db/sqlc"
+	"lobby/internal/models"
 )
 
 type contextKey string
 
-const userIDKey contextKey = "userID"
+const (
+	userIDKey    contextKey = "userID"
+	userRoleKey  contextKey = "userRole"
+	sessionIDKey contextKey = "sessionID"
+)
 
 type AuthMiddleware struct {
 	jwtService *auth.JWTService
@@ -26,45 +31,91 @@ func NewAuthMiddleware(jwtService *auth.JWTService, queries *sqldb.Queries) *Aut
 
 func (m *AuthMiddleware) RequireAuth(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		authHeader := r.Header.Get("Authorization")
-		if authHeader == "" {
-			unauthorized(w, "Authorization header required")
+		authed, status, message := m.authenticate(r)
+		if authed == nil {
+			if status == http.StatusInternalServerError {
+				internalError(w)
+			} else {
+				unauthorized(w, message)
+			}
 			return
 		}
+		next.ServeHTTP(w, authed)
+	})
+}
 
-		parts := strings.SplitN(authHeader, " ", 2)
-		if len(parts) != 2 || strings.ToLower(parts[0]) != "bearer" {
-			unauthorized(w, "Invalid authorization header format")
-			return
+// OptionalAuth identifies the caller like RequireAuth when a valid bearer
+// token is sent, and otherwise serves the request anonymously.
+func (m *AuthMiddleware) OptionalAuth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if authed, _, _ := m.authenticate(r); authed != nil {
+			r = authed
 		}
+		next.ServeHTTP(w, r)
+	})
+}
 
-		token := parts[1]
-		claims, err := m.jwtService.ValidateAccessToken(token)
-		if err != nil {
-			unauthorized(w, "Invalid or expired token")
-			return
-		}
+// authenticate validates the bearer token on r and returns r with the user in
+// its context. On failure it returns nil with the status and message to send.
+func (m *AuthMiddleware) authenticate(r *http.Request) (*http.Request, int, string) {
+	authHeader := r.Header.Get("Authorization")
+	if authHeader == "" {
+		return nil, http.StatusUnauthorized, "Authorization header required"
+	}
 
-		row, err := m.queries.GetActiveUserByID(r.Context(), claims.UserID)
-		if err != nil {
-			if errors.Is(err, sql.ErrNoRows) {
-				unauthorized(w, "User not found")
-				return
-			}
-			internalError(w)
-			return
+	parts := strings.SplitN(authHeader, " ", 2)
+	if len(parts) != 2 || strings.ToLower(parts[0]) != "bearer" {
+		return nil, http.StatusUnauthorized, "Invalid authorization header format"
+	}
+
+	token := parts[1]
+	claims, err := m.jwtService.ValidateAccessToken(token)
+	if err != nil {
+		return nil, http.StatusUnauthorized, "Invalid or expired token"
+	}
+
+	row, err := m.queries.GetActiveUserByID(r.Context(), claims.UserID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, http.StatusUnauthorized, "User not found"
 		}
+		return nil, http.StatusInternalServerError, ""
+	}
 
-		user := modelUserFromDBUser(row)
+	user := modelUserFromDBUser(row)
 
-		if claims.SessionVersion != user.SessionVersion {
-			unauthorized(w, "Session invalidated")
-			return
+	if claims.SessionVersion != user.SessionVersion {
+		return nil, http.StatusUnauthorized, "Session invalidated"
+	}
+
+	if claims.SessionID != "" {
+		session, err := m.queries.GetActiveUserSession(r.Context(), claims.SessionID)
+		if errors.Is(err, sql.ErrNoRows) || (err == nil && session.UserID != claims.UserID) {
+			return nil, http.StatusUnauthorized, "Session revoked"
+		}
+		if err != nil {
+			return nil, http.StatusInternalServerError, ""
 		}
+	}
 
-		ctx := context.WithValue(r.Context(), userIDKey, claims.UserID)
-		next.ServeHTTP(w, r.WithContext(ctx))
-	})
+	ctx := context.WithValue(r.Context(), userIDKey, claims.UserID)
+	ctx = context.WithValue(ctx, userRoleKey, user.Role)
+	ctx = context.WithValue(ctx, sessionIDKey, claims.SessionID)
+	return r.WithContext(ctx), 0, ""
+}
+
+// RequireRole rejects requests whose authenticated user ranks below role.
+// Must be mounted after RequireAuth.
+func RequireRole(role string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !models.RoleAtLeast(GetUserRole(r), role) {
+				forbidden(w, "Insufficient permissions")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
 }
 
 func GetUserID(r *http.Request) string {
@@ -75,3 +126,23 @@ func GetUserID(r *http.Request) string {
 	}
 	return ""
 }
+
+func GetUserRole(r *http.Request) string {
+	if v := r.Context().Value(userRoleKey); v != nil {
+		if role, ok := v.(string); ok {
+			return role
+		}
+	}
+	return ""
+}
+
+// GetSessionID returns the login session of the request's access token, or ""
+// for tokens issued before sessions were tracked.
+func GetSessionID(r *http.Request) string {
+	if v := r.Context().Value(sessionIDKey); v != nil {
+		if sessionID, ok := v.(string); ok {
+			return sessionID
+		}
+	}
+	return ""
+}
@@ -0,0 +1,334 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"lobby/internal/auth"
+	"lobby/internal/blob"
+	"lobby/internal/clock"
+	"lobby/internal/db"
+	sqldb "lobby/internal/db/sqlc"
+	"lobby/internal/models"
+	"lobby/internal/ws"
+)
+
+type ModerationHandler struct {
+	database *db.DB
+	queries  *sqldb.Queries
+	blobs    *blob.Service
+	hub      *ws.Hub
+	clock    clock.Clock
+}
+
+func NewModerationHandler(database *db.DB, queries *sqldb.Queries, blobs *blob.Service, hub *ws.Hub) *ModerationHandler {
+	return &ModerationHandler{
+		database: database,
+		queries:  queries,
+		blobs:    blobs,
+		hub:      hub,
+		clock:    clock.Real,
+	}
+}
+
+// SetClock replaces the clock behind timeout expiry, bans and session
+// revocation.
+func (h *ModerationHandler) SetClock(c clock.Clock) {
+	h.clock = c
+}
+
+type KickRequest struct {
+	PurgeMessages bool `json:"purgeMessages"`
+}
+
+type BanRequest struct {
+	Reason        string `json:"reason" validate:"max=512"`
+	PurgeMessages bool   `json:"purgeMessages"`
+}
+
+type BanResponse struct {
+	UserID    string    `json:"userId"`
+	Username  string    `json:"username"`
+	Reason    string    `json:"reason"`
+	BannedBy  *string   `json:"bannedBy"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+type VoiceModerationRequest struct {
+	ServerMuted    *bool `json:"serverMuted"`
+	ServerDeafened *bool `json:"serverDeafened"`
+}
+
+type ModerationResponse struct {
+	PurgedMessages int64 `json:"purgedMessages"`
+}
+
+// POST /api/v1/moderation/kick/{userID}
+func (h *ModerationHandler) Kick(w http.ResponseWriter, r *http.Request) {
+	var req KickRequest
+	if err := decodeAndValidate(r.Body, &req); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+
+	target, ok := h.loadTarget(w, r)
+	if !ok {
+		return
+	}
+	if target.DeactivatedAt != nil {
+		notFound(w, "User not found")
+		return
+	}
+
+	result, ok := h.removeTarget(w, r, target.ID, req.PurgeMessages, nil)
+	if !ok {
+		return
+	}
+
+	slog.Info("user kicked", "user_id", target.ID, "by", GetUserID(r), "purged_messages", result.PurgedMessages)
+	writeJSON(w, http.StatusOK, result)
+}
+
+// GET /api/v1/moderation/bans
+func (h *ModerationHandler) ListBans(w http.ResponseWriter, r *http.Request) {
+	rows, err := h.queries.ListBans(r.Context())
+	if err != nil {
+		slog.Error("error listing bans", "error", err)
+		internalError(w)
+		return
+	}
+
+	bans := make([]BanResponse, 0, len(rows))
+	for _, row := range rows {
+		bans = append(bans, BanResponse{
+			UserID:    row.UserID,
+			Username:  row.Username,
+			Reason:    row.Reason,
+			BannedBy:  row.BannedBy,
+			CreatedAt: row.CreatedAt,
+		})
+	}
+	writeJSON(w, http.StatusOK, bans)
+}
+
+// PUT /api/v1/moderation/bans/{userID}
+func (h *ModerationHandler) Ban(w http.ResponseWriter, r *http.Request) {
+	var req BanRequest
+	if err := decodeAndValidate(r.Body, &req); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+
+	target, ok := h.loadTarget(w, r)
+	if !ok {
+		return
+	}
+
+	actorID := GetUserID(r)
+	ban := sqldb.CreateBanParams{
+		UserID:    target.ID,
+		EmailHash: auth.HashEmail(target.Email),
+		Reason:    req.Reason,
+		BannedBy:  &actorID,
+		CreatedAt: h.clock.Now().UTC(),
+	}
+	banID, err := db.GenerateID("ban")
+	if err != nil {
+		slog.Error("error generating ban id", "error", err)
+		internalError(w)
+		return
+	}
+	ban.ID = banID
+
+	result, ok := h.removeTarget(w, r, target.ID, req.PurgeMessages, &ban)
+	if !ok {
+		return
+	}
+
+	slog.Info("user banned", "user_id", target.ID, "by", actorID, "purged_messages", result.PurgedMessages)
+	writeJSON(w, http.StatusOK, result)
+}
+
+// DELETE /api/v1/moderation/bans/{userID}
+func (h *ModerationHandler) Unban(w http.ResponseWriter, r *http.Request) {
+	targetID := chi.URLParam(r, "userID")
+
+	rowsAffected, err := h.queries.DeleteBanByUserID(r.Context(), targetID)
+	if err != nil {
+		slog.Error("error deleting ban", "error", err, "user_id", targetID)
+		internalError(w)
+		return
+	}
+	if rowsAffected == 0 {
+		notFound(w, "User is not banned")
+		return
+	}
+
+	slog.Info("user unbanned", "user_id", targetID, "by", GetUserID(r))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// PATCH /api/v1/moderation/voice/{userID}
+func (h *ModerationHandler) UpdateVoice(w http.ResponseWriter, r *http.Request) {
+	var req VoiceModerationRequest
+	if err := decodeAndValidate(r.Body, &req); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+	if req.ServerMuted == nil && req.ServerDeafened == nil {
+		badRequest(w, "serverMuted or serverDeafened is required")
+		return
+	}
+
+	target, ok := h.loadTarget(w, r)
+	if !ok {
+		return
+	}
+	if target.DeactivatedAt != nil {
+		notFound(w, "User not found")
+		return
+	}
+
+	h.hub.SetServerVoiceState(target.ID, req.ServerMuted, req.ServerDeafened)
+
+	slog.Info("user voice moderated", "user_id", target.ID, "by", GetUserID(r),
+		"server_muted", req.ServerMuted, "server_deafened", req.ServerDeafened)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// DELETE /api/v1/moderation/voice/{userID}
+func (h *ModerationHandler) DisconnectVoice(w http.ResponseWriter, r *http.Request) {
+	target, ok := h.loadTarget(w, r)
+	if !ok {
+		return
+	}
+
+	if !h.hub.DisconnectUserFromVoice(target.ID) {
+		notFound(w, "User is not in voice")
+		return
+	}
+
+	slog.Info("user disconnected from voice", "user_id", target.ID, "by", GetUserID(r))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// loadTarget resolves the {userID} URL parameter and rejects targets the
+// caller may not moderate: themselves and anyone of equal or higher role.
+func (h *ModerationHandler) loadTarget(w http.ResponseWriter, r *http.Request) (sqldb.User, bool) {
+	targetID := chi.URLParam(r, "userID")
+	if targetID == GetUserID(r) {
+		badRequest(w, "You cannot moderate yourself")
+		return sqldb.User{}, false
+	}
+
+	target, err := h.queries.GetUserByID(r.Context(), targetID)
+	if errors.Is(err, sql.ErrNoRows) {
+		notFound(w, "User not found")
+		return sqldb.User{}, false
+	}
+	if err != nil {
+		slog.Error("error finding user", "error", err)
+		internalError(w)
+		return sqldb.User{}, false
+	}
+
+	if models.RoleAtLeast(target.Role, GetUserRole(r)) {
+		forbidden(w, "You cannot moderate a user with an equal or higher role")
+		return sqldb.User{}, false
+	}
+	return target, true
+}
+
+// removeTarget optionally purges the user's messages, then disconnects them.
+// With ban it also records the ban and deactivates the account, which a kick
+// leaves alone: a kicked user may reconnect at once. Writes the error
+// response on failure.
+func (h *ModerationHandler) removeTarget(
+	w http.ResponseWriter,
+	r *http.Request,
+	userID string,
+	purgeMessages bool,
+	ban *sqldb.CreateBanParams,
+) (ModerationResponse, bool) {
+	tx, err := h.database.BeginTx(r.Context(), nil)
+	if err != nil {
+		slog.Error("error starting moderation transaction", "error", err)
+		internalError(w)
+		return ModerationResponse{}, false
+	}
+	defer tx.Rollback()
+	qtx := h.queries.WithTx(tx)
+
+	deactivated := false
+	if ban != nil {
+		if err := qtx.CreateBan(r.Context(), *ban); err != nil {
+			if db.IsUniqueConstraintError(err) {
+				conflict(w, "User is already banned")
+				return ModerationResponse{}, false
+			}
+			slog.Error("error creating ban", "error", err, "user_id", userID)
+			internalError(w)
+			return ModerationResponse{}, false
+		}
+		if deactivated, err = deactivateMember(r.Context(), qtx, userID, ban.CreatedAt); err != nil {
+			slog.Error("error deactivating user", "error", err, "user_id", userID)
+			internalError(w)
+			return ModerationResponse{}, false
+		}
+	}
+
+	var result ModerationResponse
+	var purgedBlobs []sqldb.ListChatBlobsByMessageAuthorRow
+	if purgeMessages {
+		purgedBlobs, err = qtx.ListChatBlobsByMessageAuthor(r.Context(), userID)
+		if err != nil {
+			slog.Error("error listing purged attachments", "error", err, "user_id", userID)
+			internalError(w)
+			return ModerationResponse{}, false
+		}
+		result.PurgedMessages, err = qtx.DeleteMessagesByAuthor(r.Context(), userID)
+		if err != nil {
+			slog.Error("error purging messages", "error", err, "user_id", userID)
+			internalError(w)
+			return ModerationResponse{}, false
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		slog.Error("error committing moderation transaction", "error", err, "user_id", userID)
+		internalError(w)
+		return ModerationResponse{}, false
+	}
+
+	h.deleteBlobFiles(purgedBlobs)
+	if h.hub != nil {
+		if ban == nil || deactivated {
+			disconnectMember(h.hub, userID)
+		}
+		if result.PurgedMessages > 0 {
+			h.hub.BroadcastChannelDispatch(ws.EventMessagesPurged, ws.MessagesPurgedPayload{AuthorID: userID}, nil)
+		}
+	}
+	return result, true
+}
+
+func (h *ModerationHandler) deleteBlobFiles(rows []sqldb.ListChatBlobsByMessageAuthorRow) {
+	if h.blobs == nil {
+		return
+	}
+	for _, row := range rows {
+		if row.PreviewStoragePath != nil {
+			if err := h.blobs.Delete(*row.PreviewStoragePath); err != nil {
+				slog.Warn("error deleting purged attachment preview", "error", err, "blob_id", row.ID)
+			}
+		}
+		if err := h.blobs.Delete(row.StoragePath); err != nil {
+			slog.Warn("error deleting purged attachment file", "error", err, "blob_id", row.ID)
+		}
+	}
+}
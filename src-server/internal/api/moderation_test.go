@@ -0,0 +1,163 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"lobby/internal/auth"
+	"lobby/internal/db"
+	sqldb "lobby/internal/db/sqlc"
+	"lobby/internal/models"
+)
+
+func seedModerationUsers(t *testing.T, database *db.DB) {
+	t.Helper()
+
+	now := time.Now().UTC()
+	queries := database.Queries()
+	for _, user := range []sqldb.CreateUserParams{
+		{ID: "usr_mod", Username: "mod", Email: "mod@example.com", CreatedAt: now},
+		{ID: "usr_mod2", Username: "mod2", Email: "mod2@example.com", CreatedAt: now},
+		{ID: "usr_member", Username: "member", Email: "member@example.com", CreatedAt: now},
+	} {
+		if err := queries.CreateUser(context.Background(), user); err != nil {
+			t.Fatalf("CreateUser() error = %v", err)
+		}
+	}
+	for _, userID := range []string{"usr_mod", "usr_mod2"} {
+		if _, err := queries.SetUserRole(context.Background(), sqldb.SetUserRoleParams{
+			Role:      models.RoleModerator,
+			UpdatedAt: &now,
+			ID:        userID,
+		}); err != nil {
+			t.Fatalf("SetUserRole() error = %v", err)
+		}
+	}
+}
+
+func moderationRequest(handler http.HandlerFunc, method, targetID, body string) *httptest.ResponseRecorder {
+	req := newAuthedRequest(method, "/api/v1/moderation/"+targetID, body, "usr_mod", map[string]string{"userID": targetID})
+	return serveRequest(handler, withRole(req, models.RoleModerator))
+}
+
+func TestModerationBanLifecycle(t *testing.T) {
+	database := openTestDB(t)
+	queries := database.Queries()
+	seedModerationUsers(t, database)
+	if err := queries.CreateMessage(context.Background(), sqldb.CreateMessageParams{
+		ID:        "msg_1",
+		AuthorID:  "usr_member",
+		Content:   "spam",
+		CreatedAt: time.Now().UTC(),
+	}); err != nil {
+		t.Fatalf("CreateMessage() error = %v", err)
+	}
+	handler := NewModerationHandler(database, queries, nil, nil)
+
+	rr := moderationRequest(handler.Ban, http.MethodPut, "usr_member", `{"reason":"spam","purgeMessages":true}`)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("ban status = %d, want %d, body=%q", rr.Code, http.StatusOK, rr.Body.String())
+	}
+	var resp ModerationResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v, body=%q", err, rr.Body.String())
+	}
+	if resp.PurgedMessages != 1 {
+		t.Fatalf("purgedMessages = %d, want 1", resp.PurgedMessages)
+	}
+	if _, err := queries.GetActiveUserByID(context.Background(), "usr_member"); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("GetActiveUserByID() error = %v, want sql.ErrNoRows", err)
+	}
+
+	if rr := moderationRequest(handler.Ban, http.MethodPut, "usr_member", `{}`); rr.Code != http.StatusConflict {
+		t.Fatalf("second ban status = %d, want %d, body=%q", rr.Code, http.StatusConflict, rr.Body.String())
+	}
+
+	if rr := moderationRequest(handler.Unban, http.MethodDelete, "usr_member", ""); rr.Code != http.StatusNoContent {
+		t.Fatalf("unban status = %d, want %d, body=%q", rr.Code, http.StatusNoContent, rr.Body.String())
+	}
+	if rr := moderationRequest(handler.Unban, http.MethodDelete, "usr_member", ""); rr.Code != http.StatusNotFound {
+		t.Fatalf("second unban status = %d, want %d, body=%q", rr.Code, http.StatusNotFound, rr.Body.String())
+	}
+}
+
+func TestModerationRejectsSelfAndEqualRole(t *testing.T) {
+	database := openTestDB(t)
+	seedModerationUsers(t, database)
+	handler := NewModerationHandler(database, database.Queries(), nil, nil)
+
+	if rr := moderationRequest(handler.Kick, http.MethodPost, "usr_mod", `{}`); rr.Code != http.StatusBadRequest {
+		t.Fatalf("self kick status = %d, want %d, body=%q", rr.Code, http.StatusBadRequest, rr.Body.String())
+	}
+	if rr := moderationRequest(handler.Kick, http.MethodPost, "usr_mod2", `{}`); rr.Code != http.StatusForbidden {
+		t.Fatalf("moderator kick status = %d, want %d, body=%q", rr.Code, http.StatusForbidden, rr.Body.String())
+	}
+	if rr := moderationRequest(handler.Kick, http.MethodPost, "usr_member", `{}`); rr.Code != http.StatusOK {
+		t.Fatalf("member kick status = %d, want %d, body=%q", rr.Code, http.StatusOK, rr.Body.String())
+	}
+	// A kick only disconnects: the account and its sessions stay usable.
+	if _, err := database.Queries().GetActiveUserByID(context.Background(), "usr_member"); err != nil {
+		t.Fatalf("GetActiveUserByID() after kick error = %v", err)
+	}
+	if rr := moderationRequest(handler.Kick, http.MethodPost, "usr_member", `{}`); rr.Code != http.StatusOK {
+		t.Fatalf("repeat kick status = %d, want %d, body=%q", rr.Code, http.StatusOK, rr.Body.String())
+	}
+}
+
+func TestVerifyMagicCodeRejectsBannedEmail(t *testing.T) {
+	database := openTestDB(t)
+	queries := database.Queries()
+	seedModerationUsers(t, database)
+
+	now := time.Now().UTC()
+	if err := queries.CreateBan(context.Background(), sqldb.CreateBanParams{
+		ID:        "ban_1",
+		UserID:    "usr_member",
+		EmailHash: auth.HashEmail("member@example.com"),
+		CreatedAt: now,
+	}); err != nil {
+		t.Fatalf("CreateBan() error = %v", err)
+	}
+	if err := queries.CreateMagicCode(context.Background(), sqldb.CreateMagicCodeParams{
+		ID:        "mgc_1",
+		Email:     "member@example.com",
+		CodeHash:  auth.HashMagicCode("member@example.com", "123456"),
+		ExpiresAt: now.Add(time.Minute),
+		CreatedAt: now,
+	}); err != nil {
+		t.Fatalf("CreateMagicCode() error = %v", err)
+	}
+
+	handler := NewAuthHandler(database, queries, nil, nil, nil, time.Minute, models.RegistrationOpen, "", nil, nil)
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/login/magic-code/verify",
+		strings.NewReader(`{"email":"Member@example.com","code":"123456"}`))
+	rr := httptest.NewRecorder()
+	handler.VerifyMagicCode(rr, req)
+
+	if rr.Code != http.StatusForbidden || !strings.Contains(rr.Body.String(), ErrCodeBanned) {
+		t.Fatalf("status = %d, want %d with %s, body=%q", rr.Code, http.StatusForbidden, ErrCodeBanned, rr.Body.String())
+	}
+}
+
+func TestModerationUpdateVoiceValidatesRequest(t *testing.T) {
+	database := openTestDB(t)
+	seedModerationUsers(t, database)
+	handler := NewModerationHandler(database, database.Queries(), nil, nil)
+
+	if rr := moderationRequest(handler.UpdateVoice, http.MethodPatch, "usr_member", `{}`); rr.Code != http.StatusBadRequest {
+		t.Fatalf("empty update status = %d, want %d, body=%q", rr.Code, http.StatusBadRequest, rr.Body.String())
+	}
+	if rr := moderationRequest(handler.UpdateVoice, http.MethodPatch, "usr_mod2", `{"serverMuted":true}`); rr.Code != http.StatusForbidden {
+		t.Fatalf("moderator mute status = %d, want %d, body=%q", rr.Code, http.StatusForbidden, rr.Body.String())
+	}
+	if rr := moderationRequest(handler.DisconnectVoice, http.MethodDelete, "usr_missing", ""); rr.Code != http.StatusNotFound {
+		t.Fatalf("missing user disconnect status = %d, want %d, body=%q", rr.Code, http.StatusNotFound, rr.Body.String())
+	}
+}
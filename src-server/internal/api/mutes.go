@@ -0,0 +1,122 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	sqldb "lobby/internal/db/sqlc"
+)
+
+type MuteResponse struct {
+	UserID    string    `json:"userId"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// GET /api/v1/users/me/mutes
+func (h *UserHandler) ListMutes(w http.ResponseWriter, r *http.Request) {
+	userID := GetUserID(r)
+	if userID == "" {
+		unauthorized(w, "User not found in context")
+		return
+	}
+
+	mutes, err := h.loadMutes(r.Context(), userID)
+	if err != nil {
+		slog.Error("error listing mutes", "error", err, "user_id", userID)
+		internalError(w)
+		return
+	}
+	writeJSON(w, http.StatusOK, mutes)
+}
+
+func (h *UserHandler) loadMutes(ctx context.Context, userID string) ([]MuteResponse, error) {
+	rows, err := h.queries.ListUserMutes(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	mutes := make([]MuteResponse, 0, len(rows))
+	for _, row := range rows {
+		mutes = append(mutes, MuteResponse{UserID: row.MutedUserID, CreatedAt: row.CreatedAt})
+	}
+	return mutes, nil
+}
+
+// PUT /api/v1/users/me/mutes/{userID}
+// Hides the user's messages and typing from the caller's websocket stream.
+// History requests still return their messages.
+func (h *UserHandler) MuteUser(w http.ResponseWriter, r *http.Request) {
+	userID := GetUserID(r)
+	if userID == "" {
+		unauthorized(w, "User not found in context")
+		return
+	}
+
+	targetID := chi.URLParam(r, "userID")
+	if targetID == userID {
+		badRequest(w, "You cannot mute yourself")
+		return
+	}
+	if _, err := h.queries.GetUserByID(r.Context(), targetID); errors.Is(err, sql.ErrNoRows) {
+		notFound(w, "User not found")
+		return
+	} else if err != nil {
+		slog.Error("error loading muted user", "error", err, "user_id", targetID)
+		internalError(w)
+		return
+	}
+
+	if err := h.queries.AddUserMute(r.Context(), sqldb.AddUserMuteParams{
+		UserID:      userID,
+		MutedUserID: targetID,
+		CreatedAt:   time.Now().UTC(),
+	}); err != nil {
+		slog.Error("error adding mute", "error", err, "user_id", userID)
+		internalError(w)
+		return
+	}
+	h.reloadMutes(r, userID)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// DELETE /api/v1/users/me/mutes/{userID}
+func (h *UserHandler) UnmuteUser(w http.ResponseWriter, r *http.Request) {
+	userID := GetUserID(r)
+	if userID == "" {
+		unauthorized(w, "User not found in context")
+		return
+	}
+
+	removed, err := h.queries.DeleteUserMute(r.Context(), sqldb.DeleteUserMuteParams{
+		UserID:      userID,
+		MutedUserID: chi.URLParam(r, "userID"),
+	})
+	if err != nil {
+		slog.Error("error removing mute", "error", err, "user_id", userID)
+		internalError(w)
+		return
+	}
+	if removed == 0 {
+		notFound(w, "Mute not found")
+		return
+	}
+	h.reloadMutes(r, userID)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *UserHandler) reloadMutes(r *http.Request, userID string) {
+	if h.hub == nil {
+		return
+	}
+	if err := h.hub.ReloadUserMutes(r.Context(), userID); err != nil {
+		slog.Error("error reloading mutes", "error", err, "user_id", userID)
+	}
+}
@@ -0,0 +1,151 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"lobby/internal/db"
+	sqldb "lobby/internal/db/sqlc"
+)
+
+// maxNotificationRules caps how many rules one user may keep.
+const maxNotificationRules = 25
+
+type NotificationRuleHandler struct {
+	queries *sqldb.Queries
+}
+
+func NewNotificationRuleHandler(queries *sqldb.Queries) *NotificationRuleHandler {
+	return &NotificationRuleHandler{queries: queries}
+}
+
+type CreateNotificationRuleRequest struct {
+	Keyword   string  `json:"keyword" validate:"max=100"`
+	AuthorID  *string `json:"authorId" validate:"omitnil,max=64"`
+	ChannelID *int64  `json:"channelId"`
+}
+
+type NotificationRuleResponse struct {
+	ID        string    `json:"id"`
+	Keyword   string    `json:"keyword"`
+	AuthorID  *string   `json:"authorId"`
+	ChannelID *int64    `json:"channelId"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+func notificationRuleResponse(rule sqldb.NotificationRule) NotificationRuleResponse {
+	return NotificationRuleResponse{
+		ID:        rule.ID,
+		Keyword:   rule.Keyword,
+		AuthorID:  rule.AuthorID,
+		ChannelID: rule.ChannelID,
+		CreatedAt: rule.CreatedAt,
+	}
+}
+
+// GET /api/v1/notifications/rules
+func (h *NotificationRuleHandler) ListRules(w http.ResponseWriter, r *http.Request) {
+	rows, err := h.queries.ListNotificationRulesByUser(r.Context(), GetUserID(r))
+	if err != nil {
+		slog.Error("error listing notification rules", "error", err)
+		internalError(w)
+		return
+	}
+
+	rules := make([]NotificationRuleResponse, 0, len(rows))
+	for _, row := range rows {
+		rules = append(rules, notificationRuleResponse(row))
+	}
+	writeJSON(w, http.StatusOK, rules)
+}
+
+// POST /api/v1/notifications/rules
+func (h *NotificationRuleHandler) CreateRule(w http.ResponseWriter, r *http.Request) {
+	var req CreateNotificationRuleRequest
+	if err := decodeAndValidate(r.Body, &req); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+
+	req.Keyword = strings.TrimSpace(req.Keyword)
+	if req.AuthorID != nil && *req.AuthorID == "" {
+		req.AuthorID = nil
+	}
+	if req.Keyword == "" && req.AuthorID == nil {
+		badRequest(w, "keyword or authorId is required")
+		return
+	}
+	if req.ChannelID != nil && *req.ChannelID != textChannelID {
+		notFound(w, "Channel not found")
+		return
+	}
+
+	userID := GetUserID(r)
+	if req.AuthorID != nil {
+		if _, err := h.queries.GetActiveUserByID(r.Context(), *req.AuthorID); errors.Is(err, sql.ErrNoRows) {
+			notFound(w, "Author not found")
+			return
+		} else if err != nil {
+			slog.Error("error finding notification rule author", "error", err)
+			internalError(w)
+			return
+		}
+	}
+
+	count, err := h.queries.CountNotificationRulesByUser(r.Context(), userID)
+	if err != nil {
+		slog.Error("error counting notification rules", "error", err, "user_id", userID)
+		internalError(w)
+		return
+	}
+	if count >= maxNotificationRules {
+		conflict(w, "Notification rule limit reached")
+		return
+	}
+
+	ruleID, err := db.GenerateID("ntr")
+	if err != nil {
+		slog.Error("error generating notification rule id", "error", err)
+		internalError(w)
+		return
+	}
+	rule := sqldb.NotificationRule{
+		ID:        ruleID,
+		UserID:    userID,
+		Keyword:   req.Keyword,
+		AuthorID:  req.AuthorID,
+		ChannelID: req.ChannelID,
+		CreatedAt: time.Now().UTC(),
+	}
+	if err := h.queries.CreateNotificationRule(r.Context(), sqldb.CreateNotificationRuleParams(rule)); err != nil {
+		slog.Error("error creating notification rule", "error", err, "user_id", userID)
+		internalError(w)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, notificationRuleResponse(rule))
+}
+
+// DELETE /api/v1/notifications/rules/{ruleID}
+func (h *NotificationRuleHandler) DeleteRule(w http.ResponseWriter, r *http.Request) {
+	rowsAffected, err := h.queries.DeleteNotificationRule(r.Context(), sqldb.DeleteNotificationRuleParams{
+		ID:     chi.URLParam(r, "ruleID"),
+		UserID: GetUserID(r),
+	})
+	if err != nil {
+		slog.Error("error deleting notification rule", "error", err)
+		internalError(w)
+		return
+	}
+	if rowsAffected == 0 {
+		notFound(w, "Notification rule not found")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
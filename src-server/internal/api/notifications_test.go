@@ -0,0 +1,60 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	sqldb "lobby/internal/db/sqlc"
+)
+
+func notificationRuleRequest(handler http.HandlerFunc, method, userID, ruleID, body string) *httptest.ResponseRecorder {
+	return serveRequest(handler, newAuthedRequest(method, "/api/v1/notifications/rules/"+ruleID, body, userID, map[string]string{"ruleID": ruleID}))
+}
+
+func TestNotificationRuleLifecycle(t *testing.T) {
+	database := openTestDB(t)
+	now := time.Now().UTC()
+	for _, user := range []sqldb.CreateUserParams{
+		{ID: "usr_1", Username: "alice", Email: "alice@example.com", CreatedAt: now},
+		{ID: "usr_2", Username: "bob", Email: "bob@example.com", CreatedAt: now},
+	} {
+		if err := database.Queries().CreateUser(context.Background(), user); err != nil {
+			t.Fatalf("CreateUser() error = %v", err)
+		}
+	}
+	handler := NewNotificationRuleHandler(database.Queries())
+
+	for body, want := range map[string]int{
+		`{}`:                                 http.StatusBadRequest,
+		`{"keyword":"   "}`:                  http.StatusBadRequest,
+		`{"keyword":"deploy","channelId":2}`: http.StatusNotFound,
+		`{"authorId":"usr_missing"}`:         http.StatusNotFound,
+	} {
+		if rr := notificationRuleRequest(handler.CreateRule, http.MethodPost, "usr_1", "", body); rr.Code != want {
+			t.Fatalf("create %s status = %d, want %d, body=%q", body, rr.Code, want, rr.Body.String())
+		}
+	}
+
+	rr := notificationRuleRequest(handler.CreateRule, http.MethodPost, "usr_1", "", `{"keyword":" deploy ","authorId":"usr_2","channelId":1}`)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("create status = %d, want %d, body=%q", rr.Code, http.StatusCreated, rr.Body.String())
+	}
+	var rule NotificationRuleResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &rule); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v, body=%q", err, rr.Body.String())
+	}
+	if rule.Keyword != "deploy" || rule.AuthorID == nil || *rule.AuthorID != "usr_2" {
+		t.Fatalf("unexpected rule: %+v", rule)
+	}
+
+	if rr := notificationRuleRequest(handler.DeleteRule, http.MethodDelete, "usr_2", rule.ID, ""); rr.Code != http.StatusNotFound {
+		t.Fatalf("foreign delete status = %d, want %d", rr.Code, http.StatusNotFound)
+	}
+	if rr := notificationRuleRequest(handler.DeleteRule, http.MethodDelete, "usr_1", rule.ID, ""); rr.Code != http.StatusNoContent {
+		t.Fatalf("delete status = %d, want %d, body=%q", rr.Code, http.StatusNoContent, rr.Body.String())
+	}
+}
@@ -1,22 +1,48 @@
 package api
 
 import (
+	"context"
+	"log/slog"
 	"math"
 	"net/http"
 	"strconv"
 	"time"
 
 	"github.com/go-chi/httprate"
+
+	"lobby/internal/clock"
+	sqldb "lobby/internal/db/sqlc"
 )
 
+// rateLimitStoreTimeout bounds each call to a shared rate limit store.
+const rateLimitStoreTimeout = 2 * time.Second
+
 // RateLimiter is a thin wrapper around chi/httprate configuration.
 type RateLimiter struct {
+	name         string
 	requestLimit int
 	windowLength time.Duration
+	store        RateLimitStore
+	clock        clock.Clock
+}
+
+// NewRateLimiter creates a limiter. name keeps its counts apart from other
+// limiters sharing a store.
+func NewRateLimiter(name string, limit int, window time.Duration) *RateLimiter {
+	return &RateLimiter{name: name, requestLimit: limit, windowLength: window, clock: clock.Real}
+}
+
+// SetClock replaces the clock that places requests in windows. Must be called
+// before RateLimitMiddleware.
+func (l *RateLimiter) SetClock(c clock.Clock) {
+	l.clock = c
 }
 
-func NewRateLimiter(limit int, window time.Duration) *RateLimiter {
-	return &RateLimiter{requestLimit: limit, windowLength: window}
+// SetStore keeps the limiter's windows in store instead of process memory,
+// so they survive restarts and are shared between instances. Must be called
+// before RateLimitMiddleware.
+func (l *RateLimiter) SetStore(store RateLimitStore) {
+	l.store = store
 }
 
 func RateLimitMiddleware(limiter *RateLimiter, ipResolver *ClientIPResolver) func(http.Handler) http.Handler {
@@ -26,9 +52,7 @@ func RateLimitMiddleware(limiter *RateLimiter, ipResolver *ClientIPResolver) fun
 
 	retryAfter := retryAfterSeconds(limiter.windowLength)
 
-	middleware := httprate.Limit(
-		limiter.requestLimit,
-		limiter.windowLength,
+	options := []httprate.Option{
 		httprate.WithKeyFuncs(func(r *http.Request) (string, error) {
 			return ipResolver.Resolve(r), nil
 		}),
@@ -36,9 +60,14 @@ func RateLimitMiddleware(limiter *RateLimiter, ipResolver *ClientIPResolver) fun
 			w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
 			writeError(w, http.StatusTooManyRequests, ErrCodeRateLimited, "")
 		}),
-	)
+	}
+	var counter httprate.LimitCounter = httprate.NewLocalLimitCounter(limiter.windowLength)
+	if limiter.store != nil {
+		counter = &storeLimitCounter{store: limiter.store, name: limiter.name}
+	}
+	options = append(options, httprate.WithLimitCounter(&clockLimitCounter{counter: counter, clock: limiter.clock}))
 
-	return middleware
+	return httprate.Limit(limiter.requestLimit, limiter.windowLength, options...)
 }
 
 func retryAfterSeconds(window time.Duration) int {
@@ -48,3 +77,130 @@ func retryAfterSeconds(window time.Duration) int {
 	}
 	return seconds
 }
+
+// clockLimitCounter places requests in windows read from a clock.Clock rather
+// than the system clock httprate uses. Get returns the sliding-window
+// estimate as the current count with no previous count, so httprate's own
+// interpolation, which also reads the system clock, has nothing to scale.
+type clockLimitCounter struct {
+	counter httprate.LimitCounter
+	clock   clock.Clock
+	window  time.Duration
+}
+
+func (c *clockLimitCounter) Config(requestLimit int, windowLength time.Duration) {
+	c.window = windowLength
+	c.counter.Config(requestLimit, windowLength)
+}
+
+func (c *clockLimitCounter) Increment(key string, currentWindow time.Time) error {
+	return c.IncrementBy(key, currentWindow, 1)
+}
+
+func (c *clockLimitCounter) IncrementBy(key string, _ time.Time, amount int) error {
+	return c.counter.IncrementBy(key, c.clock.Now().UTC().Truncate(c.window), amount)
+}
+
+func (c *clockLimitCounter) Get(key string, _, _ time.Time) (int, int, error) {
+	now := c.clock.Now().UTC()
+	currentWindow := now.Truncate(c.window)
+	curr, prev, err := c.counter.Get(key, currentWindow, currentWindow.Add(-c.window))
+	if err != nil {
+		return 0, 0, err
+	}
+	remaining := float64(c.window-now.Sub(currentWindow)) / float64(c.window)
+	return curr + int(math.Round(float64(prev)*remaining)), 0, nil
+}
+
+// RateLimitStore keeps sliding-window request counts outside the process.
+// cluster.RedisBackplane implements it, as does the SQLite store from
+// NewSQLiteRateLimitStore.
+type RateLimitStore interface {
+	// IncrementRateWindow adds amount to key's count in the window starting
+	// at window. The count may be dropped once ttl has passed.
+	IncrementRateWindow(ctx context.Context, key string, window time.Time, amount int, ttl time.Duration) error
+	// RateWindowCounts returns key's counts in the current and previous windows.
+	RateWindowCounts(ctx context.Context, key string, current, previous time.Time) (int, int, error)
+}
+
+// storeLimitCounter adapts a RateLimitStore to httprate. Store failures are
+// logged and let requests through, so an unreachable store never locks
+// everyone out.
+type storeLimitCounter struct {
+	store  RateLimitStore
+	name   string
+	window time.Duration
+}
+
+func (c *storeLimitCounter) Config(_ int, windowLength time.Duration) {
+	c.window = windowLength
+}
+
+func (c *storeLimitCounter) Increment(key string, currentWindow time.Time) error {
+	return c.IncrementBy(key, currentWindow, 1)
+}
+
+func (c *storeLimitCounter) IncrementBy(key string, currentWindow time.Time, amount int) error {
+	ctx, cancel := context.WithTimeout(context.Background(), rateLimitStoreTimeout)
+	defer cancel()
+
+	// The previous window is still read for the sliding estimate.
+	if err := c.store.IncrementRateWindow(ctx, c.name+":"+key, currentWindow, amount, 2*c.window); err != nil {
+		slog.Error("error incrementing rate limit window", "component", "ratelimit", "limiter", c.name, "error", err)
+	}
+	return nil
+}
+
+func (c *storeLimitCounter) Get(key string, currentWindow, previousWindow time.Time) (int, int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), rateLimitStoreTimeout)
+	defer cancel()
+
+	curr, prev, err := c.store.RateWindowCounts(ctx, c.name+":"+key, currentWindow, previousWindow)
+	if err != nil {
+		slog.Error("error reading rate limit windows", "component", "ratelimit", "limiter", c.name, "error", err)
+		return 0, 0, nil
+	}
+	return curr, prev, nil
+}
+
+// sqliteRateLimitStore keeps rate limit windows in rate_limit_windows. The
+// cleanup service prunes expired rows.
+type sqliteRateLimitStore struct {
+	queries *sqldb.Queries
+}
+
+// NewSQLiteRateLimitStore returns a RateLimitStore backed by the database,
+// which keeps limits across restarts of a single instance.
+func NewSQLiteRateLimitStore(queries *sqldb.Queries) RateLimitStore {
+	return &sqliteRateLimitStore{queries: queries}
+}
+
+func (s *sqliteRateLimitStore) IncrementRateWindow(ctx context.Context, key string, window time.Time, amount int, ttl time.Duration) error {
+	return s.queries.IncrementRateLimitWindow(ctx, sqldb.IncrementRateLimitWindowParams{
+		LimitKey:    key,
+		WindowStart: window.Unix(),
+		Count:       int64(amount),
+		ExpiresAt:   window.Add(ttl).UTC(),
+	})
+}
+
+func (s *sqliteRateLimitStore) RateWindowCounts(ctx context.Context, key string, current, previous time.Time) (int, int, error) {
+	rows, err := s.queries.ListRateLimitWindows(ctx, sqldb.ListRateLimitWindowsParams{
+		LimitKey: key,
+		Since:    previous.Unix(),
+	})
+	if err != nil {
+		return 0, 0, err
+	}
+
+	var curr, prev int
+	for _, row := range rows {
+		switch row.WindowStart {
+		case current.Unix():
+			curr = int(row.Count)
+		case previous.Unix():
+			prev = int(row.Count)
+		}
+	}
+	return curr, prev, nil
+}
@@ -1,8 +1,12 @@
 package api
 
 import (
+	"net/http"
+	"net/http/httptest"
 	"testing"
 	"time"
+
+	"lobby/internal/clock"
 )
 
 func TestRetryAfterSeconds(t *testing.T) {
@@ -26,3 +30,79 @@ func TestRetryAfterSeconds(t *testing.T) {
 		})
 	}
 }
+
+func TestSQLiteRateLimitStoreOutlivesLimiter(t *testing.T) {
+	store := NewSQLiteRateLimitStore(openTestDB(t).Queries())
+	ok := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	handler := func(name string) http.Handler {
+		limiter := NewRateLimiter(name, 2, time.Hour)
+		limiter.SetStore(store)
+		return RateLimitMiddleware(limiter, nil)(ok)
+	}
+	status := func(h http.Handler) int {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/", nil)
+		req.RemoteAddr = "192.0.2.1:1234"
+		h.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	verify := handler("verify")
+	for i := 0; i < 2; i++ {
+		if got := status(verify); got != http.StatusNoContent {
+			t.Fatalf("request %d status = %d, want %d", i+1, got, http.StatusNoContent)
+		}
+	}
+	if got := status(verify); got != http.StatusTooManyRequests {
+		t.Fatalf("over-limit status = %d, want %d", got, http.StatusTooManyRequests)
+	}
+
+	// A fresh limiter, as after a restart, still sees the stored window.
+	if got := status(handler("verify")); got != http.StatusTooManyRequests {
+		t.Fatalf("restarted limiter status = %d, want %d", got, http.StatusTooManyRequests)
+	}
+	if got := status(handler("refresh")); got != http.StatusNoContent {
+		t.Fatalf("other limiter status = %d, want %d", got, http.StatusNoContent)
+	}
+}
+
+func TestRateLimiterFollowsClock(t *testing.T) {
+	clk := clock.NewFake(time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC))
+	limiter := NewRateLimiter("verify", 2, time.Minute)
+	limiter.SetClock(clk)
+	handler := RateLimitMiddleware(limiter, nil)(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	status := func() int {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/", nil)
+		req.RemoteAddr = "192.0.2.1:1234"
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	for i := 0; i < 2; i++ {
+		if got := status(); got != http.StatusNoContent {
+			t.Fatalf("request %d status = %d, want %d", i+1, got, http.StatusNoContent)
+		}
+	}
+	if got := status(); got != http.StatusTooManyRequests {
+		t.Fatalf("over-limit status = %d, want %d", got, http.StatusTooManyRequests)
+	}
+
+	// Half a window later the previous window still counts for half.
+	clk.Advance(90 * time.Second)
+	if got := status(); got != http.StatusNoContent {
+		t.Fatalf("sliding window status = %d, want %d", got, http.StatusNoContent)
+	}
+	if got := status(); got != http.StatusTooManyRequests {
+		t.Fatalf("sliding window over-limit status = %d, want %d", got, http.StatusTooManyRequests)
+	}
+
+	clk.Advance(2 * time.Minute)
+	if got := status(); got != http.StatusNoContent {
+		t.Fatalf("status after the windows passed = %d, want %d", got, http.StatusNoContent)
+	}
+}
@@ -0,0 +1,221 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"lobby/internal/db"
+	sqldb "lobby/internal/db/sqlc"
+	"lobby/internal/ws"
+)
+
+const (
+	ReportTargetMessage = "message"
+	ReportTargetUser    = "user"
+)
+
+type ReportHandler struct {
+	queries *sqldb.Queries
+	hub     *ws.Hub
+	alerts  bool
+}
+
+// NewReportHandler creates the report handler. With alerts set, every new
+// report is dispatched to online moderators as MOD_ALERT.
+func NewReportHandler(queries *sqldb.Queries, hub *ws.Hub, alerts bool) *ReportHandler {
+	return &ReportHandler{queries: queries, hub: hub, alerts: alerts}
+}
+
+type CreateReportRequest struct {
+	TargetType string `json:"targetType" validate:"required,oneof=message user"`
+	TargetID   string `json:"targetId" validate:"required,max=64"`
+	Reason     string `json:"reason" validate:"max=512"`
+}
+
+type ReportResponse struct {
+	ID         string     `json:"id"`
+	ReporterID string     `json:"reporterId"`
+	TargetType string     `json:"targetType"`
+	TargetID   string     `json:"targetId"`
+	Reason     string     `json:"reason"`
+	ResolvedBy *string    `json:"resolvedBy"`
+	ResolvedAt *time.Time `json:"resolvedAt"`
+	CreatedAt  time.Time  `json:"createdAt"`
+}
+
+func reportResponse(report sqldb.Report) ReportResponse {
+	return ReportResponse{
+		ID:         report.ID,
+		ReporterID: report.ReporterID,
+		TargetType: report.TargetType,
+		TargetID:   report.TargetID,
+		Reason:     report.Reason,
+		ResolvedBy: report.ResolvedBy,
+		ResolvedAt: report.ResolvedAt,
+		CreatedAt:  report.CreatedAt,
+	}
+}
+
+// POST /api/v1/reports
+//
+// Reporting the same target again while the first report is open returns the
+// existing report with 200 instead of creating a new one.
+func (h *ReportHandler) CreateReport(w http.ResponseWriter, r *http.Request) {
+	var req CreateReportRequest
+	if err := decodeAndValidate(r.Body, &req); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+
+	reporterID := GetUserID(r)
+	targetUserID := req.TargetID
+	if req.TargetType == ReportTargetMessage {
+		message, err := h.queries.GetMessageByID(r.Context(), req.TargetID)
+		if errors.Is(err, sql.ErrNoRows) {
+			notFound(w, "Message not found")
+			return
+		}
+		if err != nil {
+			slog.Error("error loading reported message", "error", err, "message_id", req.TargetID)
+			internalError(w)
+			return
+		}
+		targetUserID = message.AuthorID
+	} else {
+		if _, err := h.queries.GetUserByID(r.Context(), req.TargetID); errors.Is(err, sql.ErrNoRows) {
+			notFound(w, "User not found")
+			return
+		} else if err != nil {
+			slog.Error("error loading reported user", "error", err, "user_id", req.TargetID)
+			internalError(w)
+			return
+		}
+	}
+	if targetUserID == reporterID {
+		badRequest(w, "You cannot report yourself")
+		return
+	}
+
+	reportID, err := db.GenerateID("rpt")
+	if err != nil {
+		slog.Error("error generating report id", "error", err)
+		internalError(w)
+		return
+	}
+	report := sqldb.Report{
+		ID:         reportID,
+		ReporterID: reporterID,
+		TargetType: req.TargetType,
+		TargetID:   req.TargetID,
+		Reason:     req.Reason,
+		CreatedAt:  time.Now().UTC(),
+	}
+
+	rowsAffected, err := h.queries.CreateReport(r.Context(), sqldb.CreateReportParams{
+		ID:         report.ID,
+		ReporterID: report.ReporterID,
+		TargetType: report.TargetType,
+		TargetID:   report.TargetID,
+		Reason:     report.Reason,
+		CreatedAt:  report.CreatedAt,
+	})
+	if err != nil {
+		slog.Error("error creating report", "error", err)
+		internalError(w)
+		return
+	}
+	if rowsAffected == 0 {
+		existing, err := h.queries.GetOpenReport(r.Context(), sqldb.GetOpenReportParams{
+			ReporterID: reporterID,
+			TargetType: req.TargetType,
+			TargetID:   req.TargetID,
+		})
+		if err != nil {
+			slog.Error("error loading existing report", "error", err)
+			internalError(w)
+			return
+		}
+		writeJSON(w, http.StatusOK, reportResponse(existing))
+		return
+	}
+
+	slog.Info("report created", "report_id", report.ID, "target_type", report.TargetType, "target_id", report.TargetID, "by", reporterID)
+	if h.alerts && h.hub != nil {
+		h.publishAlert(r, report)
+	}
+	writeJSON(w, http.StatusCreated, reportResponse(report))
+}
+
+func (h *ReportHandler) publishAlert(r *http.Request, report sqldb.Report) {
+	count, err := h.queries.CountOpenReportsForTarget(r.Context(), sqldb.CountOpenReportsForTargetParams{
+		TargetType: report.TargetType,
+		TargetID:   report.TargetID,
+	})
+	if err != nil {
+		slog.Error("error counting reports", "error", err, "report_id", report.ID)
+		count = 1
+	}
+	h.hub.PublishToModerators(ws.EventModAlert, ws.ModAlertPayload{
+		Kind:        "report",
+		ReportID:    report.ID,
+		ReporterID:  report.ReporterID,
+		TargetType:  report.TargetType,
+		TargetID:    report.TargetID,
+		Reason:      report.Reason,
+		ReportCount: count,
+	})
+}
+
+// GET /api/v1/moderation/reports
+func (h *ModerationHandler) ListReports(w http.ResponseWriter, r *http.Request) {
+	rows, err := h.queries.ListOpenReports(r.Context())
+	if err != nil {
+		slog.Error("error listing reports", "error", err)
+		internalError(w)
+		return
+	}
+
+	reports := make([]ReportResponse, 0, len(rows))
+	for _, row := range rows {
+		reports = append(reports, reportResponse(row))
+	}
+	writeJSON(w, http.StatusOK, reports)
+}
+
+// POST /api/v1/moderation/reports/{reportID}/resolve
+//
+// Resolves every open report on the same target, not just reportID.
+func (h *ModerationHandler) ResolveReport(w http.ResponseWriter, r *http.Request) {
+	report, err := h.queries.GetReportByID(r.Context(), chi.URLParam(r, "reportID"))
+	if errors.Is(err, sql.ErrNoRows) {
+		notFound(w, "Report not found")
+		return
+	}
+	if err != nil {
+		slog.Error("error loading report", "error", err)
+		internalError(w)
+		return
+	}
+
+	actorID := GetUserID(r)
+	now := time.Now().UTC()
+	resolved, err := h.queries.ResolveReportsForTarget(r.Context(), sqldb.ResolveReportsForTargetParams{
+		ResolvedBy: &actorID,
+		ResolvedAt: &now,
+		TargetType: report.TargetType,
+		TargetID:   report.TargetID,
+	})
+	if err != nil {
+		slog.Error("error resolving reports", "error", err, "report_id", report.ID)
+		internalError(w)
+		return
+	}
+
+	slog.Info("reports resolved", "target_type", report.TargetType, "target_id", report.TargetID, "count", resolved, "by", actorID)
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,98 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	sqldb "lobby/internal/db/sqlc"
+)
+
+func reportRequest(handler *ReportHandler, reporterID, body string) *httptest.ResponseRecorder {
+	return serveRequest(handler.CreateReport, newAuthedRequest(http.MethodPost, "/api/v1/reports", body, reporterID, nil))
+}
+
+func TestReportDedupAndResolve(t *testing.T) {
+	database := openTestDB(t)
+	queries := database.Queries()
+	seedModerationUsers(t, database)
+	if err := queries.CreateMessage(context.Background(), sqldb.CreateMessageParams{
+		ID:        "msg_1",
+		AuthorID:  "usr_member",
+		Content:   "spam",
+		CreatedAt: time.Now().UTC(),
+	}); err != nil {
+		t.Fatalf("CreateMessage() error = %v", err)
+	}
+	handler := NewReportHandler(queries, nil, true)
+
+	if rr := reportRequest(handler, "usr_member", `{"targetType":"message","targetId":"msg_1"}`); rr.Code != http.StatusBadRequest {
+		t.Fatalf("self report status = %d, want %d", rr.Code, http.StatusBadRequest)
+	}
+	if rr := reportRequest(handler, "usr_mod", `{"targetType":"message","targetId":"msg_missing"}`); rr.Code != http.StatusNotFound {
+		t.Fatalf("missing message status = %d, want %d", rr.Code, http.StatusNotFound)
+	}
+	if rr := reportRequest(handler, "usr_mod", `{"targetType":"channel","targetId":"1"}`); rr.Code != http.StatusBadRequest {
+		t.Fatalf("invalid target type status = %d, want %d", rr.Code, http.StatusBadRequest)
+	}
+
+	rr := reportRequest(handler, "usr_mod", `{"targetType":"message","targetId":"msg_1","reason":"spam"}`)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("create status = %d, want %d, body=%q", rr.Code, http.StatusCreated, rr.Body.String())
+	}
+	var first ReportResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &first); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v", err)
+	}
+
+	rr = reportRequest(handler, "usr_mod", `{"targetType":"message","targetId":"msg_1","reason":"again"}`)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("duplicate status = %d, want %d", rr.Code, http.StatusOK)
+	}
+	var duplicate ReportResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &duplicate); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v", err)
+	}
+	if duplicate.ID != first.ID || duplicate.Reason != "spam" {
+		t.Fatalf("duplicate = %+v, want existing report %q", duplicate, first.ID)
+	}
+	if rr := reportRequest(handler, "usr_mod2", `{"targetType":"message","targetId":"msg_1"}`); rr.Code != http.StatusCreated {
+		t.Fatalf("second reporter status = %d, want %d", rr.Code, http.StatusCreated)
+	}
+
+	modHandler := NewModerationHandler(database, queries, nil, nil)
+	reports := listReports(t, modHandler)
+	if len(reports) != 2 {
+		t.Fatalf("open reports = %d, want 2", len(reports))
+	}
+
+	if rr := resolveReportRequest(modHandler, first.ID); rr.Code != http.StatusNoContent {
+		t.Fatalf("resolve status = %d, want %d, body=%q", rr.Code, http.StatusNoContent, rr.Body.String())
+	}
+	if reports := listReports(t, modHandler); len(reports) != 0 {
+		t.Fatalf("expected every report on the target resolved, got %+v", reports)
+	}
+
+	// Once resolved, the same reporter may report the target again.
+	if rr := reportRequest(handler, "usr_mod", `{"targetType":"message","targetId":"msg_1"}`); rr.Code != http.StatusCreated {
+		t.Fatalf("re-report status = %d, want %d", rr.Code, http.StatusCreated)
+	}
+}
+
+func resolveReportRequest(handler *ModerationHandler, reportID string) *httptest.ResponseRecorder {
+	return serveRequest(handler.ResolveReport, newAuthedRequest(http.MethodPost, "/api/v1/moderation/reports/"+reportID+"/resolve", "", "usr_mod", map[string]string{"reportID": reportID}))
+}
+
+func listReports(t *testing.T, handler *ModerationHandler) []ReportResponse {
+	t.Helper()
+
+	rr := moderationRequest(handler.ListReports, http.MethodGet, "", "")
+	var reports []ReportResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &reports); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v, body=%q", err, rr.Body.String())
+	}
+	return reports
+}
@@ -0,0 +1,55 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxQueueTime discards X-Request-Start values too old to be a real queue
+// delay (clock skew, replayed headers).
+const maxQueueTime = time.Hour
+
+// requestQueueTime returns how long a request waited between the proxy and
+// this server, from the X-Request-Start header set by HAProxy, nginx, or
+// Traefik ("t=<timestamp>", or a bare timestamp). Integer timestamps may be in
+// seconds, milliseconds, microseconds, or nanoseconds; the unit is inferred
+// from magnitude. Fractional timestamps are seconds. The header is only used
+// for logging, so it is read from any peer.
+func requestQueueTime(r *http.Request, now time.Time) (time.Duration, bool) {
+	value := strings.TrimPrefix(strings.TrimSpace(r.Header.Get("X-Request-Start")), "t=")
+	if value == "" {
+		return 0, false
+	}
+
+	var start time.Time
+	if strings.Contains(value, ".") {
+		seconds, err := strconv.ParseFloat(value, 64)
+		if err != nil || seconds <= 0 {
+			return 0, false
+		}
+		start = time.Unix(0, int64(seconds*float64(time.Second)))
+	} else {
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || n <= 0 {
+			return 0, false
+		}
+		switch {
+		case n < 1e11:
+			start = time.Unix(n, 0)
+		case n < 1e14:
+			start = time.UnixMilli(n)
+		case n < 1e17:
+			start = time.UnixMicro(n)
+		default:
+			start = time.Unix(0, n)
+		}
+	}
+
+	queue := now.Sub(start)
+	if queue < 0 || queue > maxQueueTime {
+		return 0, false
+	}
+	return queue, true
+}
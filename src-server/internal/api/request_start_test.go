@@ -0,0 +1,37 @@
+package api
+
+import (
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestRequestQueueTime(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	start := now.Add(-250 * time.Millisecond)
+
+	tests := []struct {
+		header string
+		want   time.Duration
+		ok     bool
+	}{
+		{header: "t=" + strconv.FormatInt(start.UnixMicro(), 10), want: 250 * time.Millisecond, ok: true},
+		{header: "t=" + strconv.FormatInt(start.UnixMilli(), 10), want: 250 * time.Millisecond, ok: true},
+		{header: strconv.FormatInt(start.UnixNano(), 10), want: 250 * time.Millisecond, ok: true},
+		{header: "t=1699999999.750", want: 250 * time.Millisecond, ok: true},
+		{header: "t=" + strconv.FormatInt(now.Add(time.Minute).UnixMilli(), 10)},
+		{header: "t=" + strconv.FormatInt(now.Add(-2*time.Hour).Unix(), 10)},
+		{header: "t=garbage"},
+		{header: ""},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", "http://localhost/test", nil)
+		req.Header.Set("X-Request-Start", tt.header)
+
+		got, ok := requestQueueTime(req, now)
+		if ok != tt.ok || got.Round(time.Millisecond) != tt.want {
+			t.Errorf("requestQueueTime(%q) = %v, %v, want %v, %v", tt.header, got, ok, tt.want, tt.ok)
+		}
+	}
+}
@@ -5,18 +5,25 @@ import (
 	"net/http"
 
 	"lobby/internal/constants"
+	"lobby/internal/ws"
 )
 
 const (
-	ErrCodeAuthFailed        = constants.ErrCodeAuthFailed
-	ErrCodeAuthExpired       = constants.ErrCodeAuthExpired
-	ErrCodeRateLimited       = constants.ErrCodeRateLimited
-	ErrCodeInvalidRequest    = constants.ErrCodeInvalidRequest
-	ErrCodePayloadTooLarge   = constants.ErrCodePayloadTooLarge
-	ErrCodeNotFound          = constants.ErrCodeNotFound
-	ErrCodeConflict          = constants.ErrCodeConflict
-	ErrCodeInternal          = constants.ErrCodeInternal
-	ErrCodeAttachmentInvalid = constants.ErrCodeAttachmentInvalid
+	ErrCodeAuthFailed         = constants.ErrCodeAuthFailed
+	ErrCodeAuthExpired        = constants.ErrCodeAuthExpired
+	ErrCodeRateLimited        = constants.ErrCodeRateLimited
+	ErrCodeInvalidRequest     = constants.ErrCodeInvalidRequest
+	ErrCodePayloadTooLarge    = constants.ErrCodePayloadTooLarge
+	ErrCodeNotFound           = constants.ErrCodeNotFound
+	ErrCodeConflict           = constants.ErrCodeConflict
+	ErrCodeInternal           = constants.ErrCodeInternal
+	ErrCodeAttachmentInvalid  = constants.ErrCodeAttachmentInvalid
+	ErrCodeForbidden          = constants.ErrCodeForbidden
+	ErrCodeBanned             = constants.ErrCodeBanned
+	ErrCodeRegistrationClosed = constants.ErrCodeRegistrationClosed
+	ErrCodeInviteInvalid      = constants.ErrCodeInviteInvalid
+	ErrCodeServerManageDenied = constants.ErrCodeServerManageDenied
+	ErrCodeChallengeFailed    = constants.ErrCodeChallengeFailed
 )
 
 type ErrorResponse struct {
@@ -24,8 +31,11 @@ type ErrorResponse struct {
 }
 
 type ErrorDetail struct {
-	Code    string `json:"code"`
-	Message string `json:"message"`
+	Code    string       `json:"code"`
+	Message string       `json:"message"`
+	Fields  []FieldError `json:"fields,omitempty"`
+	// Attachments names the files that broke an upload limit.
+	Attachments []ws.AttachmentViolation `json:"attachments,omitempty"`
 }
 
 func writeJSON(w http.ResponseWriter, status int, data any) {
@@ -51,6 +61,10 @@ func unauthorized(w http.ResponseWriter, message string) {
 	writeError(w, http.StatusUnauthorized, ErrCodeAuthFailed, message)
 }
 
+func forbidden(w http.ResponseWriter, message string) {
+	writeError(w, http.StatusForbidden, ErrCodeForbidden, message)
+}
+
 func notFound(w http.ResponseWriter, message string) {
 	writeError(w, http.StatusNotFound, ErrCodeNotFound, message)
 }
@@ -63,6 +77,16 @@ func payloadTooLarge(w http.ResponseWriter, message string) {
 	writeError(w, http.StatusRequestEntityTooLarge, ErrCodePayloadTooLarge, message)
 }
 
+func fileTooLarge(w http.ResponseWriter, violation ws.AttachmentViolation) {
+	writeJSON(w, http.StatusRequestEntityTooLarge, ErrorResponse{
+		Error: ErrorDetail{
+			Code:        ErrCodePayloadTooLarge,
+			Message:     "File exceeds maximum upload size",
+			Attachments: []ws.AttachmentViolation{violation},
+		},
+	})
+}
+
 func internalError(w http.ResponseWriter) {
 	writeError(w, http.StatusInternalServerError, ErrCodeInternal, "An internal error occurred")
 }
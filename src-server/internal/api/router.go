@@ -1,6 +1,7 @@
 package api
 
 import (
+	"context"
 	"fmt"
 	"log/slog"
 	"net/http"
@@ -12,48 +13,129 @@ import (
 
 	"lobby/internal/auth"
 	"lobby/internal/blob"
+	"lobby/internal/clock"
+	"lobby/internal/cluster"
 	"lobby/internal/config"
 	"lobby/internal/db"
 	"lobby/internal/email"
+	"lobby/internal/mediaurl"
+	"lobby/internal/models"
+	"lobby/internal/mq"
 	"lobby/internal/ws"
 )
 
 type Server struct {
-	router *chi.Mux
-	config *config.Config
-	hub    *ws.Hub
+	router      *chi.Mux
+	config      *config.Config
+	hub         *ws.Hub
+	eventStream *mq.Publisher
 }
 
 func NewServer(
 	cfg *config.Config,
 	database *db.DB,
-	emailService *email.SMTPService,
+	emailService email.Sender,
 	blobService *blob.Service,
+	clk clock.Clock,
 ) (*Server, error) {
 	if blobService == nil {
 		return nil, fmt.Errorf("blob service is required")
 	}
 
 	queries := database.Queries()
-	uploadRequestLimitBytes := cfg.Storage.UploadMaxBytes + (1 << 20) // include multipart envelope overhead
-
-	magicCodeLimiter := NewRateLimiter(5, time.Minute)
-	verifyLimiter := NewRateLimiter(5, time.Minute)
-	refreshLimiter := NewRateLimiter(30, time.Minute)
+	uploadLimits := cfg.Storage.UploadLimits()
+
+	magicCodeLimiter := NewRateLimiter("magic-code", 5, time.Minute)
+	verifyLimiter := NewRateLimiter("verify", 5, time.Minute)
+	refreshLimiter := NewRateLimiter("refresh", 30, time.Minute)
+	challengeLimiter := NewRateLimiter("challenge", 30, time.Minute)
+	invitePreviewLimiter := NewRateLimiter("invite-preview", 30, time.Minute)
+	wsUpgradeLimiter := NewRateLimiter("ws-upgrade", 10, time.Minute)
+	for _, limiter := range []*RateLimiter{magicCodeLimiter, verifyLimiter, refreshLimiter, challengeLimiter, invitePreviewLimiter, wsUpgradeLimiter} {
+		limiter.SetClock(clk)
+	}
 
 	jwtService := auth.NewJWTService(
 		cfg.Auth.JWTSecret,
 		cfg.Auth.AccessTokenTTL,
 		cfg.Auth.RefreshTokenTTL,
 	)
-	magicService := auth.NewMagicCodeService(cfg.Auth.MagicCodeTTL)
+	magicService := auth.NewMagicCodeService(cfg.Auth.MagicCodeTTL, cfg.Auth.JWTSecret)
+	if err := jwtService.SetAlgorithm(cfg.Auth.JWTAlgorithm, cfg.Auth.JWTKeyFile); err != nil {
+		return nil, fmt.Errorf("configuring jwt signing: %w", err)
+	}
+	jwtService.SetLeeway(cfg.Auth.JWTLeeway)
+	jwtService.SetClock(clk)
+	magicService.SetClock(clk)
 
-	hub, err := ws.NewHub(jwtService, database, queries, &cfg.SFU, cfg.Server.BaseURL)
+	hub, err := ws.NewHub(jwtService, database, queries, &cfg.SFU, cfg.Permissions, cfg.Server.BaseURL)
 	if err != nil {
 		return nil, fmt.Errorf("initializing hub: %w", err)
 	}
+	var backplane *cluster.RedisBackplane
+	if cfg.Cluster.RedisAddr != "" {
+		backplane = cluster.NewRedisBackplane(cluster.RedisOptions{
+			Addr:     cfg.Cluster.RedisAddr,
+			Password: cfg.Cluster.RedisPassword,
+			Prefix:   cfg.Cluster.KeyPrefix,
+		})
+		pingCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		err := backplane.Ping(pingCtx)
+		cancel()
+		if err != nil {
+			return nil, fmt.Errorf("connecting to cluster backplane: %w", err)
+		}
+		hub.AttachBackplane(backplane)
+		hub.ConfigureVoiceCluster(cfg.Cluster.Region, cfg.Cluster.CascadeVoice)
+	}
+
+	var rateLimitStore RateLimitStore
+	switch cfg.Server.RateLimitStore {
+	case "sqlite":
+		rateLimitStore = NewSQLiteRateLimitStore(queries)
+	case "redis":
+		rateLimitStore = backplane
+	}
+	if rateLimitStore != nil {
+		for _, limiter := range []*RateLimiter{magicCodeLimiter, verifyLimiter, refreshLimiter, challengeLimiter, invitePreviewLimiter, wsUpgradeLimiter} {
+			limiter.SetStore(rateLimitStore)
+		}
+		slog.Info("rate limits stored outside the process", "component", "ratelimit", "store", cfg.Server.RateLimitStore)
+	}
+	wordMask := models.NewWordMask(cfg.Moderation.MaskedWords)
+	hub.SetWordMask(wordMask)
+	hub.SetConnectionLimits(cfg.Server.WebSocket.MaxAuthenticatedPerIP, cfg.Server.WebSocket.MaxVoiceSessionsPerIP)
+	hub.SetAutoPresence(cfg.Server.WebSocket.IdleAfter, cfg.Server.WebSocket.OfflineAfter)
+	hub.SetRateLimits(cfg.Server.WebSocket.RateLimits)
+	hub.SetUploadLimits(uploadLimits)
+	hub.SetClock(clk)
+	hub.SetIDFormat(cfg.Database.IDFormat)
+	hub.SetRecording(blobService, cfg.Recording)
+	var mediaSigner *mediaurl.Signer
+	if cfg.Storage.SignedMediaURLs {
+		mediaSigner = mediaurl.NewSigner(cfg.Auth.JWTSecret, cfg.Storage.MediaURLTTL)
+		mediaSigner.SetClock(clk)
+	}
+	hub.SetMediaSigner(mediaSigner)
+	var mediaProxy *mediaurl.Proxy
+	if cfg.Storage.MediaProxy.Enabled {
+		mediaProxy = mediaurl.NewProxy(cfg.Auth.JWTSecret)
+	}
+	hub.SetMediaProxy(mediaProxy)
+	blobService.SetIDFormat(cfg.Database.IDFormat)
+	var eventStream *mq.Publisher
+	if cfg.EventStream.NATSURL != "" {
+		eventStream = mq.NewPublisher(cfg.EventStream)
+		hub.Events().Subscribe(eventStream)
+		slog.Info("event stream enabled", "component", "mq", "subject_prefix", cfg.EventStream.SubjectPrefix)
+	}
 	go hub.Run()
 
+	ipResolver, err := NewClientIPResolver(cfg.Server.TrustedProxyCIDRs)
+	if err != nil {
+		return nil, fmt.Errorf("initializing client IP resolver: %w", err)
+	}
+
 	authHandler := NewAuthHandler(
 		database,
 		queries,
@@ -61,16 +143,39 @@ func NewServer(
 		magicService,
 		emailService,
 		cfg.Auth.MagicCodeTTL,
+		cfg.Auth.RegistrationMode,
+		cfg.Server.BaseURL,
 		hub,
+		ipResolver,
 	)
-	userHandler := NewUserHandler(queries, hub)
+	authHandler.SetClock(clk)
+	authHandler.SetMagicCodeDailyLimit(cfg.Auth.MagicCodeDailyLimit)
+	authHandler.SetFirstUserAdmin(cfg.Auth.FirstUserAdmin)
+	if cfg.Auth.MagicCodePoWBits > 0 {
+		challenges := auth.NewChallengeService(cfg.Auth.JWTSecret, cfg.Auth.MagicCodePoWBits, cfg.Auth.MagicCodeTTL)
+		challenges.SetClock(clk)
+		authHandler.SetChallengeService(challenges)
+	}
+	userHandler := NewUserHandler(queries, hub, cfg.Auth.AccountDeletionGrace)
+	userHandler.SetClock(clk)
+	channelHandler := NewChannelHandler(database, queries, hub)
+	moderationHandler := NewModerationHandler(database, queries, blobService, hub)
+	moderationHandler.SetClock(clk)
+	adminHandler := NewAdminHandler(queries, hub)
+	adminHandler.SetClock(clk)
+	voiceHandler := NewVoiceHandler(hub)
+	notificationRuleHandler := NewNotificationRuleHandler(queries)
+	draftHandler := NewDraftHandler(queries, hub)
+	reportHandler := NewReportHandler(queries, hub, cfg.Moderation.ReportAlerts)
+	inviteHandler := NewInviteHandler(queries, hub, cfg.Server.Name, cfg.Server.BaseURL)
 	serverInfoHandler := NewServerInfoHandler(
 		cfg.Server.Name,
 		cfg.Server.BaseURL,
-		cfg.Storage.UploadMaxBytes,
+		uploadLimits,
 		queries,
 	)
-	messageHandler := NewMessageHandler(queries, cfg.Server.BaseURL)
+	messageHandler := NewMessageHandler(queries, cfg.Server.BaseURL, wordMask)
+	bootstrapHandler := NewBootstrapHandler(serverInfoHandler, userHandler, channelHandler, messageHandler, draftHandler, hub)
 	uploadHandler := NewUploadHandler(
 		database,
 		queries,
@@ -78,17 +183,39 @@ func NewServer(
 		hub,
 		cfg.Server.Name,
 		cfg.Server.BaseURL,
-		uploadRequestLimitBytes,
+		uploadLimits,
 	)
-	mediaHandler := NewMediaHandler(queries, blobService)
+	mediaHandler := NewMediaHandler(
+		queries,
+		blobService,
+		cfg.Storage.HotlinkProtection,
+		mediaRefererOrigins(cfg.Server.BaseURL, cfg.Server.WebSocket.AllowedOrigins, cfg.Storage.AllowedReferers),
+	)
+	messageHandler.SetMediaSigner(mediaSigner)
+	messageHandler.SetMediaProxy(mediaProxy)
+	uploadHandler.SetClock(clk)
+	uploadHandler.SetMediaSigner(mediaSigner)
+	if cfg.Storage.Scan.ClamdAddress != "" {
+		scanner, err := blob.NewClamdScanner(cfg.Storage.Scan.ClamdAddress, cfg.Storage.Scan.Timeout)
+		if err != nil {
+			return nil, fmt.Errorf("initializing virus scanner: %w", err)
+		}
+		uploadHandler.SetScanner(scanner, cfg.Storage.Scan.OnInfected == "quarantine", cfg.Storage.Scan.FailOpen)
+	}
+	mediaHandler.SetSigner(mediaSigner)
+	if err := blob.SetHEIFDecodeCommand(cfg.Storage.HEIFDecodeCommand); err != nil {
+		return nil, fmt.Errorf("initializing heif decoding: %w", err)
+	}
+	mimeRules := make(map[blob.Kind]blob.MimeRules, len(cfg.Storage.MimeTypes))
+	for kind, rules := range cfg.Storage.MimeTypes {
+		mimeRules[blob.Kind(kind)] = blob.MimeRules{Allow: rules.Allow, Deny: rules.Deny}
+	}
+	if err := blobService.SetMimeRules(mimeRules); err != nil {
+		return nil, fmt.Errorf("storage.mime_types: %w", err)
+	}
 	healthHandler := NewHealthHandler(database)
 
 	authMiddleware := NewAuthMiddleware(jwtService, queries)
-	ipResolver, err := NewClientIPResolver(cfg.Server.TrustedProxyCIDRs)
-	if err != nil {
-		return nil, fmt.Errorf("initializing client IP resolver: %w", err)
-	}
-
 	wsHandler := NewWebSocketHandler(hub, cfg.Server.WebSocket, ipResolver)
 
 	r := chi.NewRouter()
@@ -98,11 +225,18 @@ func NewServer(
 	r.Use(securityHeadersMiddleware)
 
 	r.Get("/health", healthHandler.Check)
-	r.Get("/media/{blobID}/preview", mediaHandler.GetBlobPreview)
-	r.Get("/media/{blobID}", mediaHandler.GetBlob)
+	bandwidth := cfg.Storage.MediaBandwidth
+	mediaThrottle := NewMediaThrottle(bandwidth.ConnectionBytesPerSec, bandwidth.GlobalBytesPerSec, bandwidth.MaxConcurrent, bandwidth.MaxConcurrentPerIP, ipResolver)
+	r.With(mediaThrottle.Middleware, authMiddleware.OptionalAuth).Get("/media/{blobID}/preview", mediaHandler.GetBlobPreview)
+	r.With(mediaThrottle.Middleware, authMiddleware.OptionalAuth).Get("/media/{blobID}", mediaHandler.GetBlob)
+	if mediaProxy != nil {
+		mediaProxyHandler := NewMediaProxyHandler(mediaProxy, cfg.Storage.MediaProxy.MaxBytes, cfg.Storage.MediaProxy.Timeout)
+		r.With(mediaThrottle.Middleware).Get("/media/proxy/{signature}", mediaProxyHandler.GetImage)
+	}
 
 	r.Route("/api/v1", func(r chi.Router) {
-		r.Get("/server/info", serverInfoHandler.GetInfo)
+		r.With(authMiddleware.OptionalAuth).Get("/server/info", serverInfoHandler.GetInfo)
+		r.With(authMiddleware.RequireAuth).Get("/bootstrap", bootstrapHandler.GetBootstrap)
 
 		r.Route("/server", func(r chi.Router) {
 			r.Group(func(r chi.Router) {
@@ -113,8 +247,11 @@ func NewServer(
 
 		r.Route("/auth", func(r chi.Router) {
 			r.Use(maxBodySizeMiddleware(1 << 20)) // 1 MB
+			r.With(RateLimitMiddleware(challengeLimiter, ipResolver)).Get("/login/challenge", authHandler.GetMagicCodeChallenge)
 			r.With(RateLimitMiddleware(magicCodeLimiter, ipResolver)).Post("/login/magic-code", authHandler.RequestMagicCode)
 			r.With(RateLimitMiddleware(verifyLimiter, ipResolver)).Post("/login/magic-code/verify", authHandler.VerifyMagicCode)
+			r.Get("/login/magic-link", authHandler.ShowMagicLink)
+			r.With(RateLimitMiddleware(verifyLimiter, ipResolver)).Post("/login/magic-link", authHandler.VerifyMagicLink)
 			r.With(RateLimitMiddleware(verifyLimiter, ipResolver)).Post("/register", authHandler.Register)
 			r.With(RateLimitMiddleware(refreshLimiter, ipResolver)).Post("/refresh", authHandler.Refresh)
 
@@ -130,6 +267,109 @@ func NewServer(
 			r.Post("/me/avatar", uploadHandler.UploadAvatar)
 			r.With(maxBodySizeMiddleware(1<<20)).Patch("/me", userHandler.UpdateMe)
 			r.Delete("/me", userHandler.LeaveMe)
+			r.Post("/me/deletion", userHandler.DeleteMe)
+			r.Get("/me/sessions", userHandler.ListSessions)
+			r.Delete("/me/sessions/{sessionID}", userHandler.RevokeSession)
+			r.Get("/me/mutes", userHandler.ListMutes)
+			r.Put("/me/mutes/{userID}", userHandler.MuteUser)
+			r.Delete("/me/mutes/{userID}", userHandler.UnmuteUser)
+			r.With(maxBodySizeMiddleware(1<<20), RateLimitMiddleware(magicCodeLimiter, ipResolver)).Post("/me/email", authHandler.RequestEmailChange)
+			r.With(maxBodySizeMiddleware(1<<20), RateLimitMiddleware(verifyLimiter, ipResolver)).Post("/me/email/confirm", authHandler.ConfirmEmailChange)
+		})
+
+		r.Route("/channel", func(r chi.Router) {
+			r.Use(authMiddleware.RequireAuth)
+			r.Get("/", channelHandler.GetChannel)
+			r.With(maxBodySizeMiddleware(1<<20)).Patch("/", channelHandler.UpdateChannel)
+			r.Get("/notifications", channelHandler.GetNotificationSettings)
+			r.With(maxBodySizeMiddleware(1<<20)).Put("/notifications", channelHandler.UpdateNotificationSettings)
+
+			r.Group(func(r chi.Router) {
+				r.Use(RequireRole(models.RoleModerator))
+				r.Get("/members", channelHandler.ListMembers)
+				r.Put("/members/{userID}", channelHandler.AddMember)
+				r.Delete("/members/{userID}", channelHandler.RemoveMember)
+			})
+		})
+
+		r.Route("/notifications/rules", func(r chi.Router) {
+			r.Use(authMiddleware.RequireAuth)
+			r.Get("/", notificationRuleHandler.ListRules)
+			r.With(maxBodySizeMiddleware(1<<20)).Post("/", notificationRuleHandler.CreateRule)
+			r.Delete("/{ruleID}", notificationRuleHandler.DeleteRule)
+		})
+
+		r.Route("/drafts", func(r chi.Router) {
+			r.Use(authMiddleware.RequireAuth)
+			r.Get("/", draftHandler.ListDrafts)
+			r.With(maxBodySizeMiddleware(1<<20)).Put("/{channel}", draftHandler.UpdateDraft)
+		})
+
+		r.Route("/reports", func(r chi.Router) {
+			r.Use(authMiddleware.RequireAuth)
+			r.With(maxBodySizeMiddleware(1<<20)).Post("/", reportHandler.CreateReport)
+		})
+
+		r.Route("/invites", func(r chi.Router) {
+			r.With(RateLimitMiddleware(invitePreviewLimiter, ipResolver)).Get("/{code}", inviteHandler.GetInvitePreview)
+
+			r.Group(func(r chi.Router) {
+				r.Use(authMiddleware.RequireAuth)
+				r.Use(RequireRole(models.RoleModerator))
+				r.Get("/", inviteHandler.ListInvites)
+				r.With(maxBodySizeMiddleware(1<<20)).Post("/", inviteHandler.CreateInvite)
+				r.Delete("/{code}", inviteHandler.RevokeInvite)
+			})
+		})
+
+		r.Route("/moderation", func(r chi.Router) {
+			r.Use(authMiddleware.RequireAuth)
+			r.Use(RequireRole(models.RoleModerator))
+			r.Use(maxBodySizeMiddleware(1 << 20))
+			r.Post("/kick/{userID}", moderationHandler.Kick)
+			r.Get("/bans", moderationHandler.ListBans)
+			r.Put("/bans/{userID}", moderationHandler.Ban)
+			r.Delete("/bans/{userID}", moderationHandler.Unban)
+			r.Patch("/voice/{userID}", moderationHandler.UpdateVoice)
+			r.Delete("/voice/{userID}", moderationHandler.DisconnectVoice)
+			r.Get("/timeouts", moderationHandler.ListTimeouts)
+			r.Put("/timeouts/{userID}", moderationHandler.Timeout)
+			r.Delete("/timeouts/{userID}", moderationHandler.RemoveTimeout)
+			r.Get("/automod/rules", moderationHandler.ListAutomodRules)
+			r.Post("/automod/rules", moderationHandler.CreateAutomodRule)
+			r.Delete("/automod/rules/{ruleID}", moderationHandler.DeleteAutomodRule)
+			r.Get("/reports", moderationHandler.ListReports)
+			r.Post("/reports/{reportID}/resolve", moderationHandler.ResolveReport)
+		})
+
+		r.Route("/voice", func(r chi.Router) {
+			r.Use(authMiddleware.RequireAuth)
+			r.Use(RequireRole(models.RoleAdmin))
+			r.Post("/selftest", voiceHandler.SelfTest)
+			r.Get("/routes", voiceHandler.ListRoutes)
+			r.Get("/stats", voiceHandler.ListStats)
+		})
+
+		r.Route("/admin", func(r chi.Router) {
+			r.Use(authMiddleware.RequireAuth)
+			r.Use(RequireRole(models.RoleAdmin))
+			r.Get("/registration/domains", adminHandler.ListRegistrationDomains)
+			r.Put("/registration/domains/{domain}", adminHandler.AddRegistrationDomain)
+			r.Delete("/registration/domains/{domain}", adminHandler.RemoveRegistrationDomain)
+			r.Get("/jobs", adminHandler.ListJobs)
+			r.Get("/jobs/{jobID}", adminHandler.GetJob)
+			r.With(maxBodySizeMiddleware(1<<20)).Post("/jobs/assign-role", adminHandler.StartAssignRoleJob)
+			r.With(maxBodySizeMiddleware(1<<20)).Post("/jobs/prune-inactive", adminHandler.StartPruneInactiveJob)
+			r.Post("/jobs/revoke-sessions", adminHandler.StartRevokeSessionsJob)
+			r.Get("/stats", adminHandler.GetStats)
+			r.Get("/log-levels", adminHandler.GetLogLevels)
+			r.With(maxBodySizeMiddleware(1<<20)).Put("/log-levels", adminHandler.SetLogLevel)
+			r.With(maxBodySizeMiddleware(1<<20)).Patch("/server/profile", adminHandler.UpdateServerProfile)
+			r.Get("/bots", adminHandler.ListBots)
+			r.With(maxBodySizeMiddleware(1<<20)).Post("/bots", adminHandler.CreateBot)
+			r.Get("/bots/{botID}/tokens", adminHandler.ListBotTokens)
+			r.With(maxBodySizeMiddleware(1<<20)).Post("/bots/{botID}/tokens", adminHandler.CreateBotToken)
+			r.Delete("/bots/{botID}/tokens/{tokenID}", adminHandler.RevokeBotToken)
 		})
 
 		r.Route("/messages", func(r chi.Router) {
@@ -140,16 +380,22 @@ func NewServer(
 		r.Route("/uploads", func(r chi.Router) {
 			r.Use(authMiddleware.RequireAuth)
 			r.Post("/chat", uploadHandler.UploadChatAttachment)
+			r.With(maxBodySizeMiddleware(1<<20)).Post("/precheck", uploadHandler.PrecheckUpload)
+			r.With(maxBodySizeMiddleware(1<<20)).Post("/sessions", uploadHandler.CreateUploadSession)
+			r.Get("/sessions/{sessionID}", uploadHandler.GetUploadSession)
+			r.Patch("/sessions/{sessionID}", uploadHandler.UploadChunk)
+			r.Post("/sessions/{sessionID}/finalize", uploadHandler.FinalizeUploadSession)
+			r.Delete("/sessions/{sessionID}", uploadHandler.DeleteUploadSession)
 		})
 	})
 
-	wsUpgradeLimiter := NewRateLimiter(10, time.Minute)
 	r.With(RateLimitMiddleware(wsUpgradeLimiter, ipResolver)).Get("/ws", wsHandler.ServeWS)
 
 	return &Server{
-		router: r,
-		config: cfg,
-		hub:    hub,
+		router:      r,
+		config:      cfg,
+		hub:         hub,
+		eventStream: eventStream,
 	}, nil
 }
 
@@ -159,6 +405,11 @@ func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
 func (s *Server) Shutdown() {
 	s.hub.Shutdown()
+	if s.eventStream != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		s.eventStream.Close(ctx)
+	}
 }
 
 func corsMiddleware(allowedOrigins []string) func(http.Handler) http.Handler {
@@ -176,7 +427,8 @@ func corsMiddleware(allowedOrigins []string) func(http.Handler) http.Handler {
 				w.Header().Set("Access-Control-Allow-Origin", origin)
 				w.Header().Add("Vary", "Origin")
 				w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
-				w.Header().Set("Access-Control-Allow-Headers", "Authorization, Content-Type")
+				w.Header().Set("Access-Control-Allow-Headers", "Authorization, Content-Type, "+uploadOffsetHeader)
+				w.Header().Set("Access-Control-Expose-Headers", uploadOffsetHeader)
 			}
 
 			if r.Method == http.MethodOptions {
@@ -229,15 +481,20 @@ func securityHeadersMiddleware(next http.Handler) http.Handler {
 func slogRequestLogger(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		start := time.Now()
+		queue, hasQueue := requestQueueTime(r, start)
 		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
 		next.ServeHTTP(ww, r)
-		slog.Info("http request",
+		attrs := []any{
 			"method", r.Method,
 			"path", r.URL.Path,
 			"status", ww.Status(),
 			"bytes", ww.BytesWritten(),
 			"duration", time.Since(start).String(),
 			"remote", r.RemoteAddr,
-		)
+		}
+		if hasQueue {
+			attrs = append(attrs, "queue", queue.String())
+		}
+		slog.Info("http request", attrs...)
 	})
 }
@@ -1,6 +1,7 @@
 package api
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"log/slog"
@@ -8,20 +9,21 @@ import (
 
 	sqldb "lobby/internal/db/sqlc"
 	"lobby/internal/mediaurl"
+	"lobby/internal/models"
 )
 
 type ServerInfoHandler struct {
 	serverName string
 	baseURL    string
-	uploadMax  int64
+	limits     models.UploadLimits
 	queries    *sqldb.Queries
 }
 
-func NewServerInfoHandler(name string, baseURL string, uploadMax int64, queries *sqldb.Queries) *ServerInfoHandler {
+func NewServerInfoHandler(name string, baseURL string, limits models.UploadLimits, queries *sqldb.Queries) *ServerInfoHandler {
 	return &ServerInfoHandler{
 		serverName: name,
 		baseURL:    baseURL,
-		uploadMax:  uploadMax,
+		limits:     limits,
 		queries:    queries,
 	}
 }
@@ -30,25 +32,51 @@ type ServerInfoResponse struct {
 	Name           string `json:"name"`
 	IconURL        string `json:"iconUrl,omitempty"`
 	UploadMaxBytes int64  `json:"uploadMaxBytes"`
+	AvatarMaxBytes int64  `json:"avatarMaxBytes"`
+	// MaxAttachments is the most attachments one message may have; 0 means
+	// no cap.
+	MaxAttachments int `json:"maxAttachmentsPerMessage"`
+	ServerProfile
 }
 
 // GET /api/v1/server/info
+// UploadMaxBytes is the caller's limit when authenticated, otherwise a new
+// member's.
 func (h *ServerInfoHandler) GetInfo(w http.ResponseWriter, r *http.Request) {
+	info, err := h.loadInfo(r.Context(), GetUserRole(r))
+	if err != nil {
+		slog.Error("error loading server settings", "error", err)
+		internalError(w)
+		return
+	}
+	writeJSON(w, http.StatusOK, info)
+}
+
+// loadInfo builds the server info shown to a caller with role, or to an
+// anonymous caller when role is empty.
+func (h *ServerInfoHandler) loadInfo(ctx context.Context, role string) (ServerInfoResponse, error) {
 	iconURL := ""
-	settings, err := h.queries.GetServerSettings(r.Context())
+	var profile ServerProfile
+	settings, err := h.queries.GetServerSettings(ctx)
 	if err == nil {
 		if settings.IconBlobID != nil {
 			iconURL = mediaurl.Blob(h.baseURL, *settings.IconBlobID)
 		}
+		profile = serverProfileFromSettings(settings)
 	} else if !errors.Is(err, sql.ErrNoRows) {
-		slog.Error("error loading server settings", "error", err)
-		internalError(w)
-		return
+		return ServerInfoResponse{}, err
+	}
+
+	if role == "" {
+		role = models.RoleMember
 	}
 
-	writeJSON(w, http.StatusOK, ServerInfoResponse{
+	return ServerInfoResponse{
 		Name:           h.serverName,
 		IconURL:        iconURL,
-		UploadMaxBytes: h.uploadMax,
-	})
+		UploadMaxBytes: h.limits.ForRole(role),
+		AvatarMaxBytes: h.limits.ForAvatar(role),
+		MaxAttachments: h.limits.MaxAttachments,
+		ServerProfile:  profile,
+	}, nil
 }
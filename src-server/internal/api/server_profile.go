@@ -0,0 +1,98 @@
+package api
+
+import (
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	sqldb "lobby/internal/db/sqlc"
+	"lobby/internal/models"
+)
+
+// ServerProfile is the admin-editable "about this server" metadata.
+type ServerProfile struct {
+	Description  string              `json:"description,omitempty"`
+	RulesSummary string              `json:"rulesSummary,omitempty"`
+	ContactEmail string              `json:"contactEmail,omitempty"`
+	SocialLinks  []models.ServerLink `json:"socialLinks,omitempty"`
+}
+
+type ServerLinkRequest struct {
+	Label string `json:"label" validate:"required,max=32"`
+	URL   string `json:"url" validate:"required,max=512,http_url"`
+}
+
+// PATCH /api/v1/admin/server/profile
+// Omitted fields are left unchanged; empty values clear them.
+type UpdateServerProfileRequest struct {
+	Description  *string              `json:"description" validate:"omitnil,max=1000"`
+	RulesSummary *string              `json:"rulesSummary" validate:"omitnil,max=2000"`
+	ContactEmail *string              `json:"contactEmail" validate:"omitnil,max=254"`
+	SocialLinks  *[]ServerLinkRequest `json:"socialLinks" validate:"omitnil,max=10,dive"`
+}
+
+func serverProfileFromSettings(settings sqldb.ServerSetting) ServerProfile {
+	return ServerProfile{
+		Description:  settings.Description,
+		RulesSummary: settings.RulesSummary,
+		ContactEmail: settings.ContactEmail,
+		SocialLinks:  models.ParseServerLinks(settings.SocialLinks),
+	}
+}
+
+func (h *AdminHandler) UpdateServerProfile(w http.ResponseWriter, r *http.Request) {
+	var req UpdateServerProfileRequest
+	if err := decodeAndValidate(r.Body, &req); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+
+	settings, err := h.queries.GetServerSettings(r.Context())
+	if err != nil {
+		slog.Error("error loading server settings", "error", err)
+		internalError(w)
+		return
+	}
+	profile := serverProfileFromSettings(settings)
+
+	if req.Description != nil {
+		profile.Description = strings.TrimSpace(*req.Description)
+	}
+	if req.RulesSummary != nil {
+		profile.RulesSummary = strings.TrimSpace(*req.RulesSummary)
+	}
+	if req.ContactEmail != nil {
+		email := strings.TrimSpace(*req.ContactEmail)
+		if email != "" {
+			if err := requestValidator.Var(email, "email"); err != nil {
+				badRequest(w, "invalid email format")
+				return
+			}
+		}
+		profile.ContactEmail = email
+	}
+	if req.SocialLinks != nil {
+		profile.SocialLinks = make([]models.ServerLink, 0, len(*req.SocialLinks))
+		for _, link := range *req.SocialLinks {
+			profile.SocialLinks = append(profile.SocialLinks, models.ServerLink{
+				Label: strings.TrimSpace(link.Label),
+				URL:   strings.TrimSpace(link.URL),
+			})
+		}
+	}
+
+	if _, err := h.queries.UpdateServerProfile(r.Context(), sqldb.UpdateServerProfileParams{
+		Description:  profile.Description,
+		RulesSummary: profile.RulesSummary,
+		ContactEmail: profile.ContactEmail,
+		SocialLinks:  models.FormatServerLinks(profile.SocialLinks),
+		UpdatedAt:    time.Now().UTC(),
+	}); err != nil {
+		slog.Error("error updating server profile", "error", err)
+		internalError(w)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, profile)
+}
@@ -0,0 +1,67 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"lobby/internal/models"
+)
+
+func TestServerProfileAppearsInServerInfo(t *testing.T) {
+	database := openTestDB(t)
+	admin := NewAdminHandler(database.Queries(), nil)
+	info := NewServerInfoHandler("Lobby", "https://chat.example.com", models.UploadLimits{Default: 1024}, database.Queries())
+
+	patch := func(body string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		admin.UpdateServerProfile(rr, httptest.NewRequest(http.MethodPatch, "/api/v1/admin/server/profile", strings.NewReader(body)))
+		return rr
+	}
+
+	for _, body := range []string{
+		`{"contactEmail":"not-an-email"}`,
+		`{"socialLinks":[{"label":"Site","url":"javascript:alert(1)"}]}`,
+		`{"socialLinks":[{"label":"","url":"https://example.com"}]}`,
+	} {
+		if rr := patch(body); rr.Code != http.StatusBadRequest {
+			t.Fatalf("PATCH %s status = %d, want %d", body, rr.Code, http.StatusBadRequest)
+		}
+	}
+
+	if rr := patch(`{"description":" A friendly place ","contactEmail":"admin@example.com","socialLinks":[{"label":"Site","url":"https://example.com"}]}`); rr.Code != http.StatusOK {
+		t.Fatalf("PATCH status = %d, body=%q", rr.Code, rr.Body.String())
+	}
+	// Omitted fields keep their values.
+	if rr := patch(`{"rulesSummary":"Be kind."}`); rr.Code != http.StatusOK {
+		t.Fatalf("partial PATCH status = %d, body=%q", rr.Code, rr.Body.String())
+	}
+
+	rr := httptest.NewRecorder()
+	info.GetInfo(rr, httptest.NewRequest(http.MethodGet, "/api/v1/server/info", nil))
+	var resp ServerInfoResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v", err)
+	}
+	want := ServerProfile{
+		Description:  "A friendly place",
+		RulesSummary: "Be kind.",
+		ContactEmail: "admin@example.com",
+		SocialLinks:  []models.ServerLink{{Label: "Site", URL: "https://example.com"}},
+	}
+	if resp.Description != want.Description || resp.RulesSummary != want.RulesSummary || resp.ContactEmail != want.ContactEmail ||
+		len(resp.SocialLinks) != 1 || resp.SocialLinks[0] != want.SocialLinks[0] {
+		t.Fatalf("server info profile = %+v, want %+v", resp.ServerProfile, want)
+	}
+
+	if rr := patch(`{"socialLinks":[],"contactEmail":""}`); rr.Code != http.StatusOK {
+		t.Fatalf("clearing PATCH status = %d", rr.Code)
+	}
+	rr = httptest.NewRecorder()
+	info.GetInfo(rr, httptest.NewRequest(http.MethodGet, "/api/v1/server/info", nil))
+	if strings.Contains(rr.Body.String(), "socialLinks") || strings.Contains(rr.Body.String(), "contactEmail") {
+		t.Fatalf("expected cleared fields to be omitted, body=%q", rr.Body.String())
+	}
+}
@@ -0,0 +1,116 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	sqldb "lobby/internal/db/sqlc"
+)
+
+const maxSessionUserAgentLength = 256
+
+type SessionResponse struct {
+	ID         string    `json:"id"`
+	DeviceName *string   `json:"deviceName,omitempty"`
+	UserAgent  *string   `json:"userAgent,omitempty"`
+	IPAddress  *string   `json:"ipAddress,omitempty"`
+	CreatedAt  time.Time `json:"createdAt"`
+	LastUsedAt time.Time `json:"lastUsedAt"`
+	Current    bool      `json:"current"`
+}
+
+// GET /api/v1/users/me/sessions
+func (h *UserHandler) ListSessions(w http.ResponseWriter, r *http.Request) {
+	userID := GetUserID(r)
+	if userID == "" {
+		unauthorized(w, "User not found in context")
+		return
+	}
+
+	rows, err := h.queries.ListActiveUserSessions(r.Context(), sqldb.ListActiveUserSessionsParams{
+		UserID: userID,
+		Now:    time.Now().UTC(),
+	})
+	if err != nil {
+		slog.Error("error listing sessions", "error", err, "user_id", userID)
+		internalError(w)
+		return
+	}
+
+	currentID := GetSessionID(r)
+	sessions := make([]SessionResponse, 0, len(rows))
+	for _, row := range rows {
+		sessions = append(sessions, SessionResponse{
+			ID:         row.ID,
+			DeviceName: row.DeviceName,
+			UserAgent:  row.UserAgent,
+			IPAddress:  row.IpAddress,
+			CreatedAt:  row.CreatedAt,
+			LastUsedAt: row.LastUsedAt,
+			Current:    row.ID == currentID,
+		})
+	}
+	writeJSON(w, http.StatusOK, sessions)
+}
+
+// DELETE /api/v1/users/me/sessions/{sessionID}
+func (h *UserHandler) RevokeSession(w http.ResponseWriter, r *http.Request) {
+	userID := GetUserID(r)
+	if userID == "" {
+		unauthorized(w, "User not found in context")
+		return
+	}
+
+	sessionID := chi.URLParam(r, "sessionID")
+	session, err := h.queries.GetActiveUserSession(r.Context(), sessionID)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && session.UserID != userID) {
+		notFound(w, "Session not found")
+		return
+	}
+	if err != nil {
+		slog.Error("error loading session", "error", err, "session_id", sessionID)
+		internalError(w)
+		return
+	}
+
+	// Refresh tokens go first so a failure part way leaves the session
+	// unable to renew rather than listed but dead.
+	now := time.Now().UTC()
+	if err := h.queries.RevokeRefreshTokensForSession(r.Context(), sqldb.RevokeRefreshTokensForSessionParams{
+		RevokedAt: &now,
+		SessionID: &session.ID,
+	}); err != nil {
+		slog.Error("error revoking session refresh tokens", "error", err, "session_id", session.ID)
+		internalError(w)
+		return
+	}
+	if _, err := h.queries.RevokeUserSession(r.Context(), sqldb.RevokeUserSessionParams{
+		RevokedAt: &now,
+		ID:        session.ID,
+		UserID:    userID,
+	}); err != nil {
+		slog.Error("error revoking session", "error", err, "session_id", session.ID)
+		internalError(w)
+		return
+	}
+
+	if h.hub != nil {
+		if client := h.hub.GetClient(userID); client != nil && client.LoginSessionID() == session.ID {
+			client.Close()
+		}
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func optionalString(value string) *string {
+	if value == "" {
+		return nil
+	}
+	return &value
+}
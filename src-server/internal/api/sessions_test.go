@@ -0,0 +1,112 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"lobby/internal/auth"
+	sqldb "lobby/internal/db/sqlc"
+	"lobby/internal/models"
+)
+
+// authedRequest calls handler through RequireAuth with accessToken, the way
+// a client would.
+func authedRequest(middleware *AuthMiddleware, handler http.HandlerFunc, method, accessToken string, params map[string]string) *httptest.ResponseRecorder {
+	req := newAuthedRequest(method, "/api/v1/users/me/sessions", "", "", params)
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	return serveRequest(middleware.RequireAuth(handler).ServeHTTP, req)
+}
+
+func TestUserSessionLifecycle(t *testing.T) {
+	database := openTestDB(t)
+	queries := database.Queries()
+	if err := queries.CreateUser(context.Background(), sqldb.CreateUserParams{
+		ID:        "usr_1",
+		Username:  "alice",
+		Email:     "alice@example.com",
+		CreatedAt: time.Now().UTC(),
+	}); err != nil {
+		t.Fatalf("CreateUser() error = %v", err)
+	}
+	jwtService := auth.NewJWTService("test-secret", time.Minute, time.Hour)
+	authHandler := NewAuthHandler(database, queries, jwtService, nil, nil, time.Minute, models.RegistrationOpen, "", nil, nil)
+	userHandler := NewUserHandler(queries, nil, time.Hour)
+	middleware := NewAuthMiddleware(jwtService, queries)
+	user := &models.User{ID: "usr_1", Username: "alice", SessionVersion: 1}
+
+	login := func(deviceName, userAgent string) *AuthResponse {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/login/magic-code/verify", nil)
+		req.Header.Set("User-Agent", userAgent)
+		resp, err := authHandler.generateAuthResponse(req, user, deviceName)
+		if err != nil {
+			t.Fatalf("generateAuthResponse() error = %v", err)
+		}
+		return resp
+	}
+	laptop := login("Laptop", "Lobby/1.0 (Windows)")
+	phone := login("", "Lobby/1.0 (Android)")
+
+	refreshReq := httptest.NewRequest(http.MethodPost, "/api/v1/auth/refresh", strings.NewReader(`{"refreshToken":"`+phone.RefreshToken+`"}`))
+	refreshReq.Header.Set("User-Agent", "Lobby/1.1 (Android)")
+	rr := httptest.NewRecorder()
+	authHandler.Refresh(rr, refreshReq)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("refresh status = %d, body=%q", rr.Code, rr.Body.String())
+	}
+	var refreshed RefreshResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &refreshed); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v", err)
+	}
+
+	rr = authedRequest(middleware, userHandler.ListSessions, http.MethodGet, laptop.AccessToken, nil)
+	var sessions []SessionResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &sessions); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v, body=%q", err, rr.Body.String())
+	}
+	if len(sessions) != 2 {
+		t.Fatalf("sessions = %+v, want 2 (rotation keeps the session)", sessions)
+	}
+	var laptopSession, phoneSession SessionResponse
+	for _, session := range sessions {
+		if session.Current {
+			laptopSession = session
+		} else {
+			phoneSession = session
+		}
+	}
+	if laptopSession.DeviceName == nil || *laptopSession.DeviceName != "Laptop" {
+		t.Fatalf("current session = %+v, want the laptop", laptopSession)
+	}
+	if phoneSession.UserAgent == nil || *phoneSession.UserAgent != "Lobby/1.1 (Android)" || phoneSession.IPAddress == nil {
+		t.Fatalf("phone session = %+v, want the refresh's client details", phoneSession)
+	}
+
+	if rr := authedRequest(middleware, userHandler.RevokeSession, http.MethodDelete, laptop.AccessToken, map[string]string{"sessionID": "ses_unknown"}); rr.Code != http.StatusNotFound {
+		t.Fatalf("revoke unknown status = %d, want %d", rr.Code, http.StatusNotFound)
+	}
+	if rr := authedRequest(middleware, userHandler.RevokeSession, http.MethodDelete, laptop.AccessToken, map[string]string{"sessionID": phoneSession.ID}); rr.Code != http.StatusNoContent {
+		t.Fatalf("revoke status = %d, want %d, body=%q", rr.Code, http.StatusNoContent, rr.Body.String())
+	}
+
+	if rr := authedRequest(middleware, userHandler.ListSessions, http.MethodGet, refreshed.AccessToken, nil); rr.Code != http.StatusUnauthorized {
+		t.Fatalf("revoked access token status = %d, want %d", rr.Code, http.StatusUnauthorized)
+	}
+	rr = httptest.NewRecorder()
+	authHandler.Refresh(rr, httptest.NewRequest(http.MethodPost, "/api/v1/auth/refresh", strings.NewReader(`{"refreshToken":"`+refreshed.RefreshToken+`"}`)))
+	if rr.Code != http.StatusUnauthorized {
+		t.Fatalf("revoked refresh status = %d, want %d", rr.Code, http.StatusUnauthorized)
+	}
+
+	rr = authedRequest(middleware, userHandler.ListSessions, http.MethodGet, laptop.AccessToken, nil)
+	if err := json.Unmarshal(rr.Body.Bytes(), &sessions); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v", err)
+	}
+	if len(sessions) != 1 || sessions[0].ID != laptopSession.ID {
+		t.Fatalf("sessions after revoke = %+v", sessions)
+	}
+}
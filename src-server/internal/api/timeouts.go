@@ -0,0 +1,126 @@
+package api
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	sqldb "lobby/internal/db/sqlc"
+)
+
+type TimeoutRequest struct {
+	DurationSeconds int64  `json:"durationSeconds" validate:"required,min=60,max=2419200"` // up to 28 days
+	Reason          string `json:"reason" validate:"max=512"`
+}
+
+type TimeoutResponse struct {
+	UserID    string    `json:"userId"`
+	Reason    string    `json:"reason"`
+	CreatedBy *string   `json:"createdBy"`
+	ExpiresAt time.Time `json:"expiresAt"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// GET /api/v1/moderation/timeouts
+func (h *ModerationHandler) ListTimeouts(w http.ResponseWriter, r *http.Request) {
+	rows, err := h.queries.ListActiveUserTimeouts(r.Context(), h.clock.Now().UTC())
+	if err != nil {
+		slog.Error("error listing timeouts", "error", err)
+		internalError(w)
+		return
+	}
+
+	timeouts := make([]TimeoutResponse, 0, len(rows))
+	for _, row := range rows {
+		timeouts = append(timeouts, TimeoutResponse{
+			UserID:    row.UserID,
+			Reason:    row.Reason,
+			CreatedBy: row.CreatedBy,
+			ExpiresAt: row.ExpiresAt,
+			CreatedAt: row.CreatedAt,
+		})
+	}
+	writeJSON(w, http.StatusOK, timeouts)
+}
+
+// PUT /api/v1/moderation/timeouts/{userID}
+//
+// Replaces any existing timeout. A timed-out user cannot send messages or join
+// voice until it expires, and is removed from voice immediately.
+func (h *ModerationHandler) Timeout(w http.ResponseWriter, r *http.Request) {
+	var req TimeoutRequest
+	if err := decodeAndValidate(r.Body, &req); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+
+	target, ok := h.loadTarget(w, r)
+	if !ok {
+		return
+	}
+	if target.DeactivatedAt != nil {
+		notFound(w, "User not found")
+		return
+	}
+
+	actorID := GetUserID(r)
+	createdAt := h.clock.Now().UTC()
+	timeout := TimeoutResponse{
+		UserID:    target.ID,
+		Reason:    req.Reason,
+		CreatedBy: &actorID,
+		ExpiresAt: createdAt.Add(time.Duration(req.DurationSeconds) * time.Second),
+		CreatedAt: createdAt,
+	}
+	if err := h.queries.UpsertUserTimeout(r.Context(), sqldb.UpsertUserTimeoutParams{
+		UserID:    timeout.UserID,
+		Reason:    timeout.Reason,
+		CreatedBy: timeout.CreatedBy,
+		ExpiresAt: timeout.ExpiresAt,
+		CreatedAt: timeout.CreatedAt,
+	}); err != nil {
+		slog.Error("error creating timeout", "error", err, "user_id", target.ID)
+		internalError(w)
+		return
+	}
+
+	if h.hub != nil {
+		if err := h.hub.ReloadUserTimeouts(r.Context()); err != nil {
+			slog.Error("error reloading user timeouts", "error", err)
+		}
+		h.hub.DisconnectUserFromVoice(target.ID)
+	}
+
+	slog.Info("user timed out", "user_id", target.ID, "by", actorID, "expires_at", timeout.ExpiresAt)
+	writeJSON(w, http.StatusOK, timeout)
+}
+
+// DELETE /api/v1/moderation/timeouts/{userID}
+func (h *ModerationHandler) RemoveTimeout(w http.ResponseWriter, r *http.Request) {
+	targetID := chi.URLParam(r, "userID")
+
+	rowsAffected, err := h.queries.DeleteUserTimeout(r.Context(), sqldb.DeleteUserTimeoutParams{
+		UserID: targetID,
+		Now:    h.clock.Now().UTC(),
+	})
+	if err != nil {
+		slog.Error("error removing timeout", "error", err, "user_id", targetID)
+		internalError(w)
+		return
+	}
+	if rowsAffected == 0 {
+		notFound(w, "User is not timed out")
+		return
+	}
+
+	if h.hub != nil {
+		if err := h.hub.ReloadUserTimeouts(r.Context()); err != nil {
+			slog.Error("error reloading user timeouts", "error", err)
+		}
+	}
+
+	slog.Info("user timeout removed", "user_id", targetID, "by", GetUserID(r))
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,48 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+func TestModerationTimeoutLifecycle(t *testing.T) {
+	database := openTestDB(t)
+	seedModerationUsers(t, database)
+	handler := NewModerationHandler(database, database.Queries(), nil, nil)
+
+	if rr := moderationRequest(handler.Timeout, http.MethodPut, "usr_member", `{"durationSeconds":10}`); rr.Code != http.StatusBadRequest {
+		t.Fatalf("short timeout status = %d, want %d", rr.Code, http.StatusBadRequest)
+	}
+	if rr := moderationRequest(handler.Timeout, http.MethodPut, "usr_mod2", `{"durationSeconds":600}`); rr.Code != http.StatusForbidden {
+		t.Fatalf("peer timeout status = %d, want %d", rr.Code, http.StatusForbidden)
+	}
+
+	rr := moderationRequest(handler.Timeout, http.MethodPut, "usr_member", `{"durationSeconds":600,"reason":"cool off"}`)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("timeout status = %d, want %d, body=%q", rr.Code, http.StatusOK, rr.Body.String())
+	}
+	var timeout TimeoutResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &timeout); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v", err)
+	}
+	if got := timeout.ExpiresAt.Sub(timeout.CreatedAt); got.Seconds() != 600 {
+		t.Fatalf("timeout duration = %v, want 600s", got)
+	}
+
+	rr = moderationRequest(handler.ListTimeouts, http.MethodGet, "", "")
+	var timeouts []TimeoutResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &timeouts); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v", err)
+	}
+	if len(timeouts) != 1 || timeouts[0].UserID != "usr_member" || timeouts[0].Reason != "cool off" {
+		t.Fatalf("unexpected timeouts: %+v", timeouts)
+	}
+
+	if rr := moderationRequest(handler.RemoveTimeout, http.MethodDelete, "usr_member", ""); rr.Code != http.StatusNoContent {
+		t.Fatalf("remove status = %d, want %d", rr.Code, http.StatusNoContent)
+	}
+	if rr := moderationRequest(handler.RemoveTimeout, http.MethodDelete, "usr_member", ""); rr.Code != http.StatusNotFound {
+		t.Fatalf("second remove status = %d, want %d", rr.Code, http.StatusNotFound)
+	}
+}
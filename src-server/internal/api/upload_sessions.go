@@ -0,0 +1,313 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"lobby/internal/blob"
+	sqldb "lobby/internal/db/sqlc"
+	"lobby/internal/ws"
+)
+
+// Resumable uploads follow the shape of tus: create a session with the
+// file's name and size, PATCH its bytes in chunks starting at the offset the
+// server reports, then finalize it into a chat attachment. A chunk that is
+// cut off keeps what arrived, so a client resumes from GET's offset rather
+// than restarting. Type and size are checked again at finalize, as for a
+// single-request upload.
+const (
+	// uploadSessionTTL is how long a session may take to be finalized.
+	uploadSessionTTL = 24 * time.Hour
+
+	// maxUploadSessionsPerUser caps the unfinished sessions one user holds.
+	maxUploadSessionsPerUser = 5
+
+	// uploadOffsetHeader carries the offset a chunk starts at, and in
+	// responses the offset the next one must start at.
+	uploadOffsetHeader = "Upload-Offset"
+)
+
+type CreateUploadSessionRequest struct {
+	Name     string `json:"name" validate:"required,max=255"`
+	Size     int64  `json:"size" validate:"required,min=1"`
+	MimeType string `json:"mimeType" validate:"max=255"`
+}
+
+type UploadSessionResponse struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	Size      int64     `json:"size"`
+	Offset    int64     `json:"offset"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+func uploadSessionResponse(session sqldb.UploadSession) UploadSessionResponse {
+	return UploadSessionResponse{
+		ID:        session.ID,
+		Name:      session.OriginalName,
+		Size:      session.SizeBytes,
+		Offset:    session.ReceivedBytes,
+		ExpiresAt: session.ExpiresAt,
+	}
+}
+
+// POST /api/v1/uploads/sessions
+func (h *UploadHandler) CreateUploadSession(w http.ResponseWriter, r *http.Request) {
+	userID := GetUserID(r)
+	if userID == "" {
+		unauthorized(w, "User not found in context")
+		return
+	}
+
+	var req CreateUploadSessionRequest
+	if err := decodeAndValidate(r.Body, &req); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+	if strings.TrimSpace(req.Name) == "" {
+		badRequest(w, "File name is required")
+		return
+	}
+	if !handleBlobSaveError(w, h.blobs.Precheck(blob.KindChatAttachment, req.Size, req.MimeType)) {
+		return
+	}
+	if maxBytes := h.uploadLimit(r, blob.KindChatAttachment); req.Size > maxBytes {
+		fileTooLarge(w, ws.AttachmentViolation{
+			Name:   req.Name,
+			Kind:   string(blob.KindChatAttachment),
+			Reason: ws.AttachmentTooLarge,
+			Limit:  maxBytes,
+			Size:   req.Size,
+		})
+		return
+	}
+
+	now := h.clock.Now().UTC()
+	open, err := h.queries.CountUploadSessionsForUser(r.Context(), sqldb.CountUploadSessionsForUserParams{
+		UserID: userID,
+		Now:    now,
+	})
+	if err != nil {
+		slog.Error("error counting upload sessions", "error", err, "user_id", userID)
+		internalError(w)
+		return
+	}
+	if open >= maxUploadSessionsPerUser {
+		writeError(w, http.StatusTooManyRequests, ErrCodeRateLimited, "Too many unfinished uploads")
+		return
+	}
+
+	id, err := h.blobs.NewUploadSessionID()
+	if err != nil {
+		slog.Error("error generating upload session id", "error", err)
+		internalError(w)
+		return
+	}
+	session := sqldb.UploadSession{
+		ID:           id,
+		UserID:       userID,
+		OriginalName: strings.TrimSpace(req.Name),
+		SizeBytes:    req.Size,
+		StoragePath:  blob.UploadSessionRelativePath(id),
+		CreatedAt:    now,
+		ExpiresAt:    now.Add(uploadSessionTTL),
+	}
+	if err := h.queries.CreateUploadSession(r.Context(), sqldb.CreateUploadSessionParams{
+		ID:           session.ID,
+		UserID:       session.UserID,
+		OriginalName: session.OriginalName,
+		SizeBytes:    session.SizeBytes,
+		StoragePath:  session.StoragePath,
+		CreatedAt:    session.CreatedAt,
+		ExpiresAt:    session.ExpiresAt,
+	}); err != nil {
+		slog.Error("error creating upload session", "error", err, "user_id", userID)
+		internalError(w)
+		return
+	}
+
+	w.Header().Set(uploadOffsetHeader, "0")
+	writeJSON(w, http.StatusCreated, uploadSessionResponse(session))
+}
+
+// GET /api/v1/uploads/sessions/{sessionID}
+func (h *UploadHandler) GetUploadSession(w http.ResponseWriter, r *http.Request) {
+	session, ok := h.loadUploadSession(w, r)
+	if !ok {
+		return
+	}
+	w.Header().Set(uploadOffsetHeader, strconv.FormatInt(session.ReceivedBytes, 10))
+	writeJSON(w, http.StatusOK, uploadSessionResponse(session))
+}
+
+// PATCH /api/v1/uploads/sessions/{sessionID}
+//
+// The body is the chunk's raw bytes and Upload-Offset where they start,
+// which must be the session's offset.
+func (h *UploadHandler) UploadChunk(w http.ResponseWriter, r *http.Request) {
+	session, ok := h.loadUploadSession(w, r)
+	if !ok {
+		return
+	}
+
+	offset, err := strconv.ParseInt(r.Header.Get(uploadOffsetHeader), 10, 64)
+	if err != nil || offset < 0 {
+		badRequest(w, "Upload-Offset header must be a non-negative integer")
+		return
+	}
+	w.Header().Set(uploadOffsetHeader, strconv.FormatInt(session.ReceivedBytes, 10))
+	if offset != session.ReceivedBytes {
+		conflict(w, "Upload-Offset does not match the upload's offset")
+		return
+	}
+	if !h.claimUploadSession(session.ID) {
+		conflict(w, "Another chunk of this upload is being written")
+		return
+	}
+	defer h.releaseUploadSession(session.ID)
+
+	remaining := session.SizeBytes - session.ReceivedBytes
+	written, writeErr := h.blobs.WriteAt(session.StoragePath, offset, http.MaxBytesReader(w, r.Body, remaining))
+	received := offset + written
+	if written > 0 {
+		rows, err := h.queries.AdvanceUploadSession(r.Context(), sqldb.AdvanceUploadSessionParams{
+			ReceivedBytes: received,
+			ID:            session.ID,
+			FromBytes:     offset,
+		})
+		if err != nil {
+			slog.Error("error advancing upload session", "error", err, "upload_id", session.ID)
+			internalError(w)
+			return
+		}
+		if rows == 0 {
+			notFound(w, "Upload not found")
+			return
+		}
+	}
+	w.Header().Set(uploadOffsetHeader, strconv.FormatInt(received, 10))
+
+	if writeErr != nil {
+		if isBodyTooLargeError(writeErr) {
+			payloadTooLarge(w, "Chunk runs past the upload's size")
+			return
+		}
+		// The client went away mid-chunk; what arrived is kept for it to
+		// resume from
+		slog.Debug("upload chunk cut off", "error", writeErr, "upload_id", session.ID, "offset", received)
+		badRequest(w, "Chunk was cut off")
+		return
+	}
+
+	session.ReceivedBytes = received
+	writeJSON(w, http.StatusOK, uploadSessionResponse(session))
+}
+
+// POST /api/v1/uploads/sessions/{sessionID}/finalize
+func (h *UploadHandler) FinalizeUploadSession(w http.ResponseWriter, r *http.Request) {
+	session, ok := h.loadUploadSession(w, r)
+	if !ok {
+		return
+	}
+	if session.ReceivedBytes != session.SizeBytes {
+		w.Header().Set(uploadOffsetHeader, strconv.FormatInt(session.ReceivedBytes, 10))
+		conflict(w, "Upload is incomplete")
+		return
+	}
+	if !h.claimUploadSession(session.ID) {
+		conflict(w, "Another chunk of this upload is being written")
+		return
+	}
+	defer h.releaseUploadSession(session.ID)
+
+	file, err := h.blobs.Open(session.StoragePath)
+	if err != nil {
+		slog.Error("error opening upload session file", "error", err, "upload_id", session.ID)
+		internalError(w)
+		return
+	}
+	maxBytes := h.uploadLimit(r, blob.KindChatAttachment)
+	stored, err := h.blobs.SaveLimited(r.Context(), blob.KindChatAttachment, session.OriginalName, file, maxBytes)
+	file.Close()
+	if errors.Is(err, blob.ErrFileTooLarge) || errors.Is(err, blob.ErrDisallowedType) || errors.Is(err, blob.ErrExecutableFile) {
+		// The bytes will never pass, so don't keep them for a retry
+		h.deleteUploadSession(r, session)
+	}
+	if !handleSizedBlobSaveError(w, err, blob.KindChatAttachment, session.OriginalName, maxBytes) {
+		return
+	}
+
+	h.deleteUploadSession(r, session)
+	h.createChatAttachment(w, r, stored, session.UserID)
+}
+
+// DELETE /api/v1/uploads/sessions/{sessionID}
+func (h *UploadHandler) DeleteUploadSession(w http.ResponseWriter, r *http.Request) {
+	session, ok := h.loadUploadSession(w, r)
+	if !ok {
+		return
+	}
+	h.deleteUploadSession(r, session)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// loadUploadSession returns the caller's unexpired session named in the
+// URL, writing 404 if there is none.
+func (h *UploadHandler) loadUploadSession(w http.ResponseWriter, r *http.Request) (sqldb.UploadSession, bool) {
+	userID := GetUserID(r)
+	if userID == "" {
+		unauthorized(w, "User not found in context")
+		return sqldb.UploadSession{}, false
+	}
+
+	session, err := h.queries.GetUploadSession(r.Context(), sqldb.GetUploadSessionParams{
+		ID:     chi.URLParam(r, "sessionID"),
+		UserID: userID,
+		Now:    h.clock.Now().UTC(),
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		notFound(w, "Upload not found")
+		return sqldb.UploadSession{}, false
+	}
+	if err != nil {
+		slog.Error("error loading upload session", "error", err, "user_id", userID)
+		internalError(w)
+		return sqldb.UploadSession{}, false
+	}
+	return session, true
+}
+
+func (h *UploadHandler) deleteUploadSession(r *http.Request, session sqldb.UploadSession) {
+	if _, err := h.queries.DeleteUploadSession(r.Context(), session.ID); err != nil {
+		slog.Error("error deleting upload session", "error", err, "upload_id", session.ID)
+		return
+	}
+	if err := h.blobs.Delete(session.StoragePath); err != nil {
+		slog.Warn("error deleting upload session file", "error", err, "upload_id", session.ID)
+	}
+}
+
+// claimUploadSession reports whether the caller may write to the session,
+// keeping two requests from writing the same file at once.
+func (h *UploadHandler) claimUploadSession(id string) bool {
+	h.sessionMu.Lock()
+	defer h.sessionMu.Unlock()
+	if h.writingSessions[id] {
+		return false
+	}
+	h.writingSessions[id] = true
+	return true
+}
+
+func (h *UploadHandler) releaseUploadSession(id string) {
+	h.sessionMu.Lock()
+	defer h.sessionMu.Unlock()
+	delete(h.writingSessions, id)
+}
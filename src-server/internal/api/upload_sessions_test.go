@@ -0,0 +1,164 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"lobby/internal/blob"
+	sqldb "lobby/internal/db/sqlc"
+	"lobby/internal/models"
+)
+
+// cutOffReader returns its data and then fails, like a body whose
+// connection dropped.
+type cutOffReader struct {
+	data io.Reader
+}
+
+func (r *cutOffReader) Read(p []byte) (int, error) {
+	n, err := r.data.Read(p)
+	if errors.Is(err, io.EOF) {
+		return n, io.ErrUnexpectedEOF
+	}
+	return n, err
+}
+
+func TestResumableUpload(t *testing.T) {
+	database := openTestDB(t)
+	queries := database.Queries()
+	if err := queries.CreateUser(context.Background(), sqldb.CreateUserParams{
+		ID:        "usr_1",
+		Username:  "alice",
+		Email:     "alice@example.com",
+		CreatedAt: time.Now().UTC(),
+	}); err != nil {
+		t.Fatalf("CreateUser() error = %v", err)
+	}
+	root := t.TempDir()
+	blobs, err := blob.NewService(root, 1<<20)
+	if err != nil {
+		t.Fatalf("blob.NewService() error = %v", err)
+	}
+	handler := NewUploadHandler(database, queries, blobs, nil, "Lobby", "http://localhost", models.UploadLimits{Default: 1 << 20})
+
+	router := chi.NewRouter()
+	router.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), userIDKey, "usr_1")))
+		})
+	})
+	router.Post("/sessions", handler.CreateUploadSession)
+	router.Get("/sessions/{sessionID}", handler.GetUploadSession)
+	router.Patch("/sessions/{sessionID}", handler.UploadChunk)
+	router.Post("/sessions/{sessionID}/finalize", handler.FinalizeUploadSession)
+
+	do := func(method, path string, offset int64, body io.Reader) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(method, path, body)
+		if offset >= 0 {
+			req.Header.Set(uploadOffsetHeader, strconv.FormatInt(offset, 10))
+		}
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+	create := func(name string, size int) UploadSessionResponse {
+		t.Helper()
+		rr := do(http.MethodPost, "/sessions", -1, strings.NewReader(`{"name":"`+name+`","size":`+strconv.Itoa(size)+`}`))
+		if rr.Code != http.StatusCreated {
+			t.Fatalf("create status = %d, body=%q", rr.Code, rr.Body.String())
+		}
+		var session UploadSessionResponse
+		if err := json.Unmarshal(rr.Body.Bytes(), &session); err != nil {
+			t.Fatalf("json.Unmarshal() error = %v", err)
+		}
+		return session
+	}
+
+	content := bytes.Repeat([]byte("hello, resumable world\n"), 10)
+	session := create("notes.txt", len(content))
+	path := "/sessions/" + session.ID
+
+	if rr := do(http.MethodPatch, path, 0, bytes.NewReader(content[:50])); rr.Code != http.StatusOK {
+		t.Fatalf("first chunk status = %d, body=%q", rr.Code, rr.Body.String())
+	}
+
+	// A chunk sent again at a stale offset is refused with the real one
+	rr := do(http.MethodPatch, path, 0, bytes.NewReader(content[:50]))
+	if rr.Code != http.StatusConflict || rr.Header().Get(uploadOffsetHeader) != "50" {
+		t.Fatalf("stale chunk status = %d, offset %q; want 409 at 50", rr.Code, rr.Header().Get(uploadOffsetHeader))
+	}
+
+	// A chunk cut off part way keeps what arrived
+	rr = do(http.MethodPatch, path, 50, &cutOffReader{bytes.NewReader(content[50:80])})
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("cut off chunk status = %d, want 400", rr.Code)
+	}
+	rr = do(http.MethodGet, path, -1, nil)
+	if rr.Code != http.StatusOK || rr.Header().Get(uploadOffsetHeader) != "80" {
+		t.Fatalf("offset after cut off chunk = %d %q, want 80", rr.Code, rr.Header().Get(uploadOffsetHeader))
+	}
+
+	if rr := do(http.MethodPost, path+"/finalize", -1, nil); rr.Code != http.StatusConflict {
+		t.Fatalf("early finalize status = %d, want 409", rr.Code)
+	}
+	// A chunk running past the size keeps only what fits
+	if rr := do(http.MethodPatch, path, 80, bytes.NewReader(append(content[80:], '!'))); rr.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("oversize chunk status = %d, want 413", rr.Code)
+	}
+	if rr := do(http.MethodPatch, path, int64(len(content)), nil); rr.Code != http.StatusOK {
+		t.Fatalf("empty final chunk status = %d, body=%q", rr.Code, rr.Body.String())
+	}
+
+	rr = do(http.MethodPost, path+"/finalize", -1, nil)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("finalize status = %d, body=%q", rr.Code, rr.Body.String())
+	}
+	var uploaded ChatUploadResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &uploaded); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v", err)
+	}
+	if uploaded.Name != "notes.txt" || uploaded.Size != int64(len(content)) || !strings.HasPrefix(uploaded.MimeType, "text/plain") {
+		t.Fatalf("finalized upload = %+v", uploaded)
+	}
+	row, err := queries.GetBlobByID(context.Background(), uploaded.ID)
+	if err != nil {
+		t.Fatalf("GetBlobByID() error = %v", err)
+	}
+	stored, err := os.ReadFile(filepath.Join(root, row.StoragePath))
+	if err != nil || !bytes.Equal(stored, content) {
+		t.Fatalf("stored file = %q, %v; want the uploaded bytes", stored, err)
+	}
+	if rr := do(http.MethodGet, path, -1, nil); rr.Code != http.StatusNotFound {
+		t.Fatalf("session after finalize status = %d, want 404", rr.Code)
+	}
+	if _, err := os.Stat(filepath.Join(root, blob.UploadSessionRelativePath(session.ID))); !os.IsNotExist(err) {
+		t.Fatalf("session file after finalize: %v, want removed", err)
+	}
+
+	// Finalize applies the same type checks as a single-request upload
+	executable := append([]byte("MZ"), make([]byte, 62)...)
+	session = create("setup.txt", len(executable))
+	if rr := do(http.MethodPatch, "/sessions/"+session.ID, 0, bytes.NewReader(executable)); rr.Code != http.StatusOK {
+		t.Fatalf("executable chunk status = %d, body=%q", rr.Code, rr.Body.String())
+	}
+	if rr := do(http.MethodPost, "/sessions/"+session.ID+"/finalize", -1, nil); rr.Code != http.StatusBadRequest {
+		t.Fatalf("executable finalize status = %d, want 400", rr.Code)
+	}
+	if rr := do(http.MethodGet, "/sessions/"+session.ID, -1, nil); rr.Code != http.StatusNotFound {
+		t.Fatalf("rejected session status = %d, want 404", rr.Code)
+	}
+}
@@ -5,29 +5,53 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"image"
+	"io"
 	"log/slog"
 	"mime/multipart"
 	"net/http"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"lobby/internal/blob"
+	"lobby/internal/clock"
 	"lobby/internal/db"
 	sqldb "lobby/internal/db/sqlc"
 	"lobby/internal/mediaurl"
+	"lobby/internal/models"
 	"lobby/internal/ws"
 )
 
 const chatAttachmentTTL = 24 * time.Hour
 
+// uploadEnvelopeBytes is allowed on top of the file size for the multipart
+// envelope.
+const uploadEnvelopeBytes = 1 << 20
+
+// animatedAvatarMaxBytes caps the GIF or WebP kept as an animated avatar,
+// which is served as uploaded; larger ones keep only their still frame.
+const animatedAvatarMaxBytes = 2 << 20
+
 type UploadHandler struct {
-	database                *db.DB
-	queries                 *sqldb.Queries
-	blobs                   *blob.Service
-	hub                     *ws.Hub
-	serverName              string
-	baseURL                 string
-	uploadRequestLimitBytes int64
+	database     *db.DB
+	queries      *sqldb.Queries
+	blobs        *blob.Service
+	hub          *ws.Hub
+	serverName   string
+	baseURL      string
+	uploadLimits models.UploadLimits
+	mediaURLs    *mediaurl.Signer
+	clock        clock.Clock
+
+	// scanner, when set, checks chat attachments before they are recorded.
+	scanner            blob.Scanner
+	quarantineInfected bool
+	scanFailOpen       bool
+
+	sessionMu       sync.Mutex
+	writingSessions map[string]bool // upload session ID -> a request is writing it
 }
 
 func NewUploadHandler(
@@ -37,32 +61,128 @@ func NewUploadHandler(
 	hub *ws.Hub,
 	serverName string,
 	baseURL string,
-	uploadRequestLimitBytes int64,
+	uploadLimits models.UploadLimits,
 ) *UploadHandler {
 	return &UploadHandler{
-		database:                database,
-		queries:                 queries,
-		blobs:                   blobs,
-		hub:                     hub,
-		serverName:              serverName,
-		baseURL:                 baseURL,
-		uploadRequestLimitBytes: uploadRequestLimitBytes,
+		database:     database,
+		queries:      queries,
+		blobs:        blobs,
+		hub:          hub,
+		serverName:   serverName,
+		baseURL:      baseURL,
+		uploadLimits: uploadLimits,
+		clock:        clock.Real,
+
+		writingSessions: make(map[string]bool),
+	}
+}
+
+// SetClock replaces the clock behind upload session and unclaimed attachment
+// expiry.
+func (h *UploadHandler) SetClock(c clock.Clock) {
+	h.clock = c
+}
+
+// SetMediaSigner makes uploads sign the chat attachment URLs they return.
+func (h *UploadHandler) SetMediaSigner(signer *mediaurl.Signer) {
+	h.mediaURLs = signer
+}
+
+// SetScanner makes chat uploads pass scanner before they are recorded.
+// Infected files are deleted, or with quarantine kept aside under a row
+// that cannot be attached or served. With failOpen, files are accepted as
+// unscanned while the scanner fails; otherwise they are refused.
+func (h *UploadHandler) SetScanner(scanner blob.Scanner, quarantine, failOpen bool) {
+	h.scanner = scanner
+	h.quarantineInfected = quarantine
+	h.scanFailOpen = failOpen
+}
+
+// uploadLimit returns the caller's upload cap, resolved from their role.
+// uploadLimit returns the caller's size cap for a file of kind.
+func (h *UploadHandler) uploadLimit(r *http.Request, kind blob.Kind) int64 {
+	if kind == blob.KindAvatar || kind == blob.KindServerImage {
+		return h.uploadLimits.ForAvatar(GetUserRole(r))
 	}
+	return h.uploadLimits.ForRole(GetUserRole(r))
 }
 
 type ChatUploadResponse struct {
-	ID       string             `json:"id"`
-	Name     string             `json:"name"`
-	MimeType string             `json:"mimeType"`
-	Size     int64              `json:"size"`
-	URL      string             `json:"url"`
-	Preview  *ChatUploadPreview `json:"preview,omitempty"`
+	ID          string                 `json:"id"`
+	Name        string                 `json:"name"`
+	MimeType    string                 `json:"mimeType"`
+	Size        int64                  `json:"size"`
+	URL         string                 `json:"url"`
+	Preview     *ChatUploadPreview     `json:"preview,omitempty"`
+	TextPreview *ChatUploadTextPreview `json:"textPreview,omitempty"`
+	Audio       *ChatUploadAudio       `json:"audio,omitempty"`
+	ScanStatus  string                 `json:"scanStatus,omitempty"`
 }
 
 type ChatUploadPreview struct {
 	URL    string `json:"url"`
 	Width  int64  `json:"width"`
 	Height int64  `json:"height"`
+	// Animated images only; the preview is the first frame.
+	FrameCount int64 `json:"frameCount,omitempty"`
+	DurationMs int64 `json:"durationMs,omitempty"`
+}
+
+type ChatUploadTextPreview struct {
+	Text     string `json:"text"`
+	Language string `json:"language"`
+}
+
+type ChatUploadAudio struct {
+	DurationMs int64 `json:"durationMs"`
+	// Waveform is base64, one byte per peak from 0 to 255.
+	Waveform string `json:"waveform,omitempty"`
+}
+
+type UploadPrecheckRequest struct {
+	Name     string `json:"name" validate:"required,max=255"`
+	Size     int64  `json:"size" validate:"required,min=1"`
+	MimeType string `json:"mimeType" validate:"max=255"`
+	Kind     string `json:"kind" validate:"omitempty,oneof=chat_attachment avatar server_image"`
+}
+
+type UploadPrecheckResponse struct {
+	MaxBytes int64 `json:"maxBytes"`
+}
+
+// POST /api/v1/uploads/precheck
+func (h *UploadHandler) PrecheckUpload(w http.ResponseWriter, r *http.Request) {
+	var req UploadPrecheckRequest
+	if err := decodeAndValidate(r.Body, &req); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+
+	kind := blob.KindChatAttachment
+	if req.Kind != "" {
+		kind = blob.Kind(req.Kind)
+	}
+	if strings.TrimSpace(req.Name) == "" {
+		badRequest(w, "File name is required")
+		return
+	}
+
+	if !handleBlobSaveError(w, h.blobs.Precheck(kind, req.Size, req.MimeType)) {
+		return
+	}
+	maxBytes := h.uploadLimit(r, kind)
+	if req.Size > maxBytes {
+		fileTooLarge(w, ws.AttachmentViolation{
+			Name:   req.Name,
+			Kind:   string(kind),
+			Reason: ws.AttachmentTooLarge,
+			Limit:  maxBytes,
+			Size:   req.Size,
+		})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, UploadPrecheckResponse{MaxBytes: maxBytes})
 }
 
 // POST /api/v1/uploads/chat
@@ -73,19 +193,30 @@ func (h *UploadHandler) UploadChatAttachment(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	file, fileHeader, cleanup, ok := readSingleFileUpload(w, r, h.uploadRequestLimitBytes)
+	maxBytes := h.uploadLimit(r, blob.KindChatAttachment)
+	file, fileHeader, cleanup, ok := readSingleFileUpload(w, r, blob.KindChatAttachment, maxBytes)
 	if !ok {
 		return
 	}
 	defer cleanup()
 	defer file.Close()
 
-	stored, err := h.blobs.Save(r.Context(), blob.KindChatAttachment, fileHeader.Filename, file)
-	if !handleBlobSaveError(w, err) {
+	stored, err := h.blobs.SaveLimited(r.Context(), blob.KindChatAttachment, fileHeader.Filename, file, maxBytes)
+	if !handleSizedBlobSaveError(w, err, blob.KindChatAttachment, fileHeader.Filename, maxBytes) {
 		return
 	}
+	h.createChatAttachment(w, r, stored, userID)
+}
 
-	expiresAt := time.Now().UTC().Add(chatAttachmentTTL)
+// createChatAttachment records a stored chat attachment, generates its
+// previews, and writes the upload response.
+func (h *UploadHandler) createChatAttachment(w http.ResponseWriter, r *http.Request, stored *blob.StoredBlob, userID string) {
+	scanStatus, ok := h.scanChatAttachment(w, r, stored, userID)
+	if !ok {
+		return
+	}
+
+	expiresAt := h.clock.Now().UTC().Add(chatAttachmentTTL)
 	createErr := h.queries.CreateBlob(r.Context(), buildCreateBlobParams(stored, userID, &expiresAt))
 	if createErr != nil {
 		_ = h.blobs.Delete(stored.StoragePath)
@@ -93,27 +224,135 @@ func (h *UploadHandler) UploadChatAttachment(w http.ResponseWriter, r *http.Requ
 		internalError(w)
 		return
 	}
+	if scanStatus != "" {
+		if _, err := h.queries.UpdateBlobScanStatus(r.Context(), sqldb.UpdateBlobScanStatusParams{
+			ScanStatus: &scanStatus,
+			ID:         stored.ID,
+		}); err != nil {
+			slog.Warn("error recording chat upload scan status", "error", err, "blob_id", stored.ID)
+		}
+	}
 
 	var preview *ChatUploadPreview
 	if isImageMimeType(stored.MimeType) {
 		generatedPreview, previewErr := h.createChatAttachmentPreview(r.Context(), stored.ID, stored.StoragePath)
-		if previewErr != nil {
+		if errors.Is(previewErr, blob.ErrUnsupportedImageFormat) {
+			slog.Debug("skipping chat image preview", "error", previewErr, "blob_id", stored.ID)
+		} else if previewErr != nil {
 			slog.Warn("error generating chat image preview", "error", previewErr, "blob_id", stored.ID)
 		} else {
 			preview = generatedPreview
 		}
 	}
 
+	var textPreview *ChatUploadTextPreview
+	if blob.IsTextPreviewable(stored.MimeType, stored.SizeBytes) {
+		generatedPreview, previewErr := h.createChatAttachmentTextPreview(r.Context(), stored)
+		if previewErr != nil {
+			slog.Warn("error generating chat text preview", "error", previewErr, "blob_id", stored.ID)
+		} else {
+			textPreview = generatedPreview
+		}
+	}
+
+	var audio *ChatUploadAudio
+	if blob.IsAudioMimeType(stored.MimeType) {
+		generatedAudio, audioErr := h.createChatAttachmentAudioMetadata(r.Context(), stored)
+		if audioErr != nil {
+			slog.Warn("error reading chat audio metadata", "error", audioErr, "blob_id", stored.ID)
+		} else {
+			audio = generatedAudio
+		}
+	}
+
 	writeJSON(w, http.StatusCreated, ChatUploadResponse{
-		ID:       stored.ID,
-		Name:     stored.OriginalName,
-		MimeType: stored.MimeType,
-		Size:     stored.SizeBytes,
-		URL:      mediaurl.Blob(h.baseURL, stored.ID),
-		Preview:  preview,
+		ID:          stored.ID,
+		Name:        stored.OriginalName,
+		MimeType:    stored.MimeType,
+		Size:        stored.SizeBytes,
+		URL:         h.mediaURLs.Blob(h.baseURL, stored.ID),
+		Preview:     preview,
+		TextPreview: textPreview,
+		Audio:       audio,
+		ScanStatus:  scanStatus,
 	})
 }
 
+// scanChatAttachment runs the scanner over a stored chat attachment and
+// returns the scan status to record, empty when scanning is off. It answers
+// the request itself and returns false when the file is refused.
+func (h *UploadHandler) scanChatAttachment(w http.ResponseWriter, r *http.Request, stored *blob.StoredBlob, userID string) (string, bool) {
+	if h.scanner == nil {
+		return "", true
+	}
+
+	file, err := h.blobs.Open(stored.StoragePath)
+	if err != nil {
+		_ = h.blobs.Delete(stored.StoragePath)
+		slog.Error("error opening chat upload for scanning", "error", err, "blob_id", stored.ID)
+		internalError(w)
+		return "", false
+	}
+	result, err := h.scanner.Scan(r.Context(), file)
+	file.Close()
+	if err != nil {
+		if h.scanFailOpen {
+			slog.Warn("virus scan failed, accepting upload unscanned", "error", err, "blob_id", stored.ID)
+			return blob.ScanStatusUnscanned, true
+		}
+		_ = h.blobs.Delete(stored.StoragePath)
+		slog.Error("virus scan failed", "error", err, "blob_id", stored.ID)
+		writeError(w, http.StatusServiceUnavailable, ErrCodeInternal, "Virus scanning is unavailable, try again later")
+		return "", false
+	}
+	if !result.Infected {
+		return blob.ScanStatusClean, true
+	}
+
+	slog.Warn("infected upload refused",
+		"blob_id", stored.ID,
+		"user_id", userID,
+		"signature", result.Signature,
+		"quarantined", h.quarantineInfected,
+	)
+	if h.quarantineInfected {
+		h.quarantineChatAttachment(r.Context(), stored, userID, result.Signature)
+	} else {
+		_ = h.blobs.Delete(stored.StoragePath)
+	}
+	writeError(w, http.StatusUnprocessableEntity, ErrCodeAttachmentInvalid, "File failed virus scan")
+	return "", false
+}
+
+// quarantineChatAttachment moves an infected file aside and records it as
+// infected, so it can be reviewed until blob cleanup removes it with other
+// unclaimed uploads.
+func (h *UploadHandler) quarantineChatAttachment(ctx context.Context, stored *blob.StoredBlob, userID, signature string) {
+	path, err := h.blobs.Quarantine(stored.StoragePath, stored.ID)
+	if err != nil {
+		_ = h.blobs.Delete(stored.StoragePath)
+		slog.Error("error quarantining chat upload", "error", err, "blob_id", stored.ID)
+		return
+	}
+
+	quarantined := *stored
+	quarantined.StoragePath = path
+	expiresAt := h.clock.Now().UTC().Add(chatAttachmentTTL)
+	if err := h.queries.CreateBlob(ctx, buildCreateBlobParams(&quarantined, userID, &expiresAt)); err != nil {
+		_ = h.blobs.Delete(path)
+		slog.Error("error recording quarantined chat upload", "error", err, "blob_id", stored.ID)
+		return
+	}
+	status := blob.ScanStatusInfected
+	if _, err := h.queries.UpdateBlobScanStatus(ctx, sqldb.UpdateBlobScanStatusParams{
+		ScanStatus:    &status,
+		ScanSignature: &signature,
+		ID:            stored.ID,
+	}); err != nil {
+		slog.Error("error marking quarantined chat upload", "error", err, "blob_id", stored.ID)
+	}
+}
+
 // POST /api/v1/users/me/avatar
 func (h *UploadHandler) UploadAvatar(w http.ResponseWriter, r *http.Request) {
 	userID := GetUserID(r)
@@ -122,14 +361,19 @@ func (h *UploadHandler) UploadAvatar(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	file, fileHeader, cleanup, ok := readSingleFileUpload(w, r, h.uploadRequestLimitBytes)
+	file, fileHeader, cleanup, ok := readSingleFileUpload(w, r, blob.KindAvatar, h.uploadLimit(r, blob.KindAvatar))
 	if !ok {
 		return
 	}
 	defer cleanup()
 	defer file.Close()
 
-	normalized, err := blob.NormalizeStaticImage(file, blob.DefaultProfileImageMaxEdge, blob.DefaultProfileJPEGQuality)
+	crop, ok := parseImageCrop(w, r)
+	if !ok {
+		return
+	}
+
+	normalized, err := blob.NormalizeStaticImage(file, blob.DefaultProfileImageMaxEdge, blob.DefaultProfileJPEGQuality, crop)
 	if !handleImageNormalizeError(w, err) {
 		return
 	}
@@ -146,7 +390,19 @@ func (h *UploadHandler) UploadAvatar(w http.ResponseWriter, r *http.Request) {
 		}
 	}()
 
-	oldAvatarBlobID := ""
+	animated, ok := h.saveAnimatedAvatar(w, r, file, fileHeader.Filename, normalized, crop)
+	if !ok {
+		return
+	}
+	if animated != nil {
+		defer func() {
+			if cleanupStoredFile {
+				_ = h.blobs.Delete(animated.StoragePath)
+			}
+		}()
+	}
+
+	oldAvatarBlobIDs := make([]string, 0, 2)
 	tx, err := h.database.BeginTx(r.Context(), nil)
 	if err != nil {
 		slog.Error("error starting avatar update transaction", "error", err, "user_id", userID)
@@ -167,9 +423,12 @@ func (h *UploadHandler) UploadAvatar(w http.ResponseWriter, r *http.Request) {
 		internalError(w)
 		return
 	}
-	if userRow.AvatarUrl != nil {
-		if blobID, ok := mediaurl.ParseBlobID(*userRow.AvatarUrl); ok {
-			oldAvatarBlobID = blobID
+	for _, oldURL := range []*string{userRow.AvatarUrl, userRow.AvatarAnimatedUrl} {
+		if oldURL == nil {
+			continue
+		}
+		if blobID, ok := mediaurl.ParseBlobID(*oldURL); ok {
+			oldAvatarBlobIDs = append(oldAvatarBlobIDs, blobID)
 		}
 	}
 
@@ -180,12 +439,25 @@ func (h *UploadHandler) UploadAvatar(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	var avatarAnimatedURL *string
+	if animated != nil {
+		err = qtx.CreateBlob(r.Context(), buildCreateBlobParams(animated, userID, nil))
+		if err != nil {
+			slog.Error("error creating animated avatar blob record", "error", err, "user_id", userID)
+			internalError(w)
+			return
+		}
+		animatedURL := mediaurl.Blob(h.baseURL, animated.ID)
+		avatarAnimatedURL = &animatedURL
+	}
+
 	avatarURL := mediaurl.Blob(h.baseURL, stored.ID)
 	now := time.Now().UTC()
 	rowsAffected, err := qtx.UpdateUserAvatarURL(r.Context(), sqldb.UpdateUserAvatarURLParams{
-		AvatarUrl: &avatarURL,
-		UpdatedAt: &now,
-		ID:        userID,
+		AvatarUrl:         &avatarURL,
+		AvatarAnimatedUrl: avatarAnimatedURL,
+		UpdatedAt:         &now,
+		ID:                userID,
 	})
 	if err != nil {
 		slog.Error("error updating user avatar url", "error", err, "user_id", userID)
@@ -213,18 +485,52 @@ func (h *UploadHandler) UploadAvatar(w http.ResponseWriter, r *http.Request) {
 
 	user := modelUserFromDBUser(updatedUserRow)
 	h.hub.BroadcastDispatch(ws.EventUserUpdate, ws.UserUpdatePayload{
-		ID:       user.ID,
-		Username: user.Username,
-		Avatar:   user.GetAvatarURL(),
+		ID:             user.ID,
+		Username:       user.Username,
+		Avatar:         user.GetAvatarURL(),
+		AvatarAnimated: user.GetAvatarAnimatedURL(),
 	})
 
-	if oldAvatarBlobID != "" && oldAvatarBlobID != stored.ID {
-		h.deleteBlobByIDBestEffort(r.Context(), oldAvatarBlobID, string(blob.KindAvatar))
+	for _, oldAvatarBlobID := range oldAvatarBlobIDs {
+		if oldAvatarBlobID != stored.ID && (animated == nil || oldAvatarBlobID != animated.ID) {
+			h.deleteBlobByIDBestEffort(r.Context(), oldAvatarBlobID, string(blob.KindAvatar))
+		}
 	}
 
 	writeJSON(w, http.StatusOK, user)
 }
 
+// saveAnimatedAvatar stores an animated GIF or WebP avatar as uploaded, next
+// to its still frame. Frames are not re-encoded, so a cropped or oversized
+// animation is dropped and only the still frame is kept; nil, true means
+// there is nothing to keep.
+func (h *UploadHandler) saveAnimatedAvatar(
+	w http.ResponseWriter,
+	r *http.Request,
+	file multipart.File,
+	filename string,
+	normalized *blob.Preview,
+	crop *blob.Crop,
+) (*blob.StoredBlob, bool) {
+	if normalized.Animation == nil || crop != nil {
+		return nil, true
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		slog.Error("error rewinding animated avatar upload", "error", err)
+		internalError(w)
+		return nil, false
+	}
+
+	animated, err := h.blobs.SaveLimited(r.Context(), blob.KindAvatar, filename, file, animatedAvatarMaxBytes)
+	if errors.Is(err, blob.ErrFileTooLarge) {
+		return nil, true
+	}
+	if !handleBlobSaveError(w, err) {
+		return nil, false
+	}
+	return animated, true
+}
+
 // POST /api/v1/server/image
 func (h *UploadHandler) UploadServerImage(w http.ResponseWriter, r *http.Request) {
 	userID := GetUserID(r)
@@ -232,15 +538,24 @@ func (h *UploadHandler) UploadServerImage(w http.ResponseWriter, r *http.Request
 		unauthorized(w, "User not found in context")
 		return
 	}
+	if !models.RoleAtLeast(GetUserRole(r), models.RoleAdmin) {
+		writeError(w, http.StatusForbidden, ErrCodeServerManageDenied, "Only admins can change server settings")
+		return
+	}
 
-	file, fileHeader, cleanup, ok := readSingleFileUpload(w, r, h.uploadRequestLimitBytes)
+	file, fileHeader, cleanup, ok := readSingleFileUpload(w, r, blob.KindServerImage, h.uploadLimit(r, blob.KindServerImage))
 	if !ok {
 		return
 	}
 	defer cleanup()
 	defer file.Close()
 
-	normalized, err := blob.NormalizeStaticImage(file, blob.DefaultProfileImageMaxEdge, blob.DefaultProfileJPEGQuality)
+	crop, ok := parseImageCrop(w, r)
+	if !ok {
+		return
+	}
+
+	normalized, err := blob.NormalizeStaticImage(file, blob.DefaultProfileImageMaxEdge, blob.DefaultProfileJPEGQuality, crop)
 	if !handleImageNormalizeError(w, err) {
 		return
 	}
@@ -321,7 +636,10 @@ func (h *UploadHandler) UploadServerImage(w http.ResponseWriter, r *http.Request
 	writeJSON(w, http.StatusOK, ServerInfoResponse{
 		Name:           h.serverName,
 		IconURL:        iconURL,
-		UploadMaxBytes: h.blobs.MaxUploadBytes(),
+		UploadMaxBytes: h.uploadLimit(r, blob.KindChatAttachment),
+		AvatarMaxBytes: h.uploadLimit(r, blob.KindAvatar),
+		MaxAttachments: h.uploadLimits.MaxAttachments,
+		ServerProfile:  serverProfileFromSettings(oldSettings),
 	})
 }
 
@@ -404,12 +722,20 @@ func (h *UploadHandler) createChatAttachmentPreview(
 	previewSizeBytes := previewSize
 	previewWidth := int64(preview.Width)
 	previewHeight := int64(preview.Height)
+	var previewFrameCount, previewDurationMs *int64
+	if preview.Animation != nil {
+		frames := int64(preview.Animation.Frames)
+		durationMs := preview.Animation.Duration.Milliseconds()
+		previewFrameCount, previewDurationMs = &frames, &durationMs
+	}
 	rowsAffected, err := h.queries.UpdateBlobPreview(ctx, sqldb.UpdateBlobPreviewParams{
 		PreviewStoragePath: &previewPath,
 		PreviewMimeType:    &previewMimeType,
 		PreviewSizeBytes:   &previewSizeBytes,
 		PreviewWidth:       &previewWidth,
 		PreviewHeight:      &previewHeight,
+		PreviewFrameCount:  previewFrameCount,
+		PreviewDurationMs:  previewDurationMs,
 		ID:                 blobID,
 	})
 	if err != nil {
@@ -421,10 +747,80 @@ func (h *UploadHandler) createChatAttachmentPreview(
 		return nil, errors.New("blob row not found for preview update")
 	}
 
-	return &ChatUploadPreview{
-		URL:    mediaurl.BlobPreview(h.baseURL, blobID),
+	response := &ChatUploadPreview{
+		URL:    h.mediaURLs.BlobPreview(h.baseURL, blobID),
 		Width:  previewWidth,
 		Height: previewHeight,
+	}
+	if preview.Animation != nil {
+		response.FrameCount = *previewFrameCount
+		response.DurationMs = *previewDurationMs
+	}
+	return response, nil
+}
+
+func (h *UploadHandler) createChatAttachmentTextPreview(ctx context.Context, stored *blob.StoredBlob) (*ChatUploadTextPreview, error) {
+	file, err := h.blobs.Open(stored.StoragePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	preview, err := blob.GenerateTextPreview(file, stored.OriginalName, stored.MimeType, blob.DefaultTextPreviewLines, blob.DefaultTextPreviewBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	rowsAffected, err := h.queries.UpdateBlobTextPreview(ctx, sqldb.UpdateBlobTextPreviewParams{
+		PreviewText:     &preview.Text,
+		PreviewLanguage: &preview.Language,
+		ID:              stored.ID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if rowsAffected == 0 {
+		return nil, errors.New("blob row not found for text preview update")
+	}
+
+	return &ChatUploadTextPreview{
+		Text:     preview.Text,
+		Language: preview.Language,
+	}, nil
+}
+
+func (h *UploadHandler) createChatAttachmentAudioMetadata(ctx context.Context, stored *blob.StoredBlob) (*ChatUploadAudio, error) {
+	file, err := h.blobs.Open(stored.StoragePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	metadata, err := blob.GenerateAudioMetadata(file)
+	if err != nil {
+		return nil, err
+	}
+
+	durationMs := metadata.Duration.Milliseconds()
+	var waveform *string
+	if encoded := metadata.EncodedWaveform(); encoded != "" {
+		waveform = &encoded
+	}
+	rowsAffected, err := h.queries.UpdateBlobAudioMetadata(ctx, sqldb.UpdateBlobAudioMetadataParams{
+		AudioDurationMs: &durationMs,
+		AudioWaveform:   waveform,
+		ID:              stored.ID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if rowsAffected == 0 {
+		return nil, errors.New("blob row not found for audio metadata update")
+	}
+
+	return &ChatUploadAudio{
+		DurationMs: durationMs,
+		Waveform:   metadata.EncodedWaveform(),
 	}, nil
 }
 
@@ -432,19 +828,22 @@ func isImageMimeType(mimeType string) bool {
 	return strings.HasPrefix(strings.ToLower(strings.TrimSpace(mimeType)), "image/")
 }
 
+// readSingleFileUpload parses a multipart upload of one file of kind, at
+// most maxBytes long.
 func readSingleFileUpload(
 	w http.ResponseWriter,
 	r *http.Request,
+	kind blob.Kind,
 	maxBytes int64,
 ) (multipart.File, *multipart.FileHeader, func(), bool) {
 	if maxBytes > 0 {
-		r.Body = http.MaxBytesReader(w, r.Body, maxBytes)
+		r.Body = http.MaxBytesReader(w, r.Body, maxBytes+uploadEnvelopeBytes)
 	}
 
 	err := r.ParseMultipartForm(1 << 20)
 	if err != nil {
 		if isBodyTooLargeError(err) {
-			payloadTooLarge(w, "File exceeds maximum upload size")
+			fileTooLarge(w, ws.AttachmentViolation{Kind: string(kind), Reason: ws.AttachmentTooLarge, Limit: maxBytes})
 		} else {
 			badRequest(w, "Invalid multipart upload")
 		}
@@ -470,10 +869,67 @@ func readSingleFileUpload(
 		badRequest(w, "File name is required")
 		return nil, nil, func() {}, false
 	}
+	if maxBytes > 0 && fileHeader.Size > maxBytes {
+		file.Close()
+		cleanup()
+		fileTooLarge(w, ws.AttachmentViolation{
+			Name:   fileHeader.Filename,
+			Kind:   string(kind),
+			Reason: ws.AttachmentTooLarge,
+			Limit:  maxBytes,
+			Size:   fileHeader.Size,
+		})
+		return nil, nil, func() {}, false
+	}
 
 	return file, fileHeader, cleanup, true
 }
 
+// parseImageCrop reads the optional crop form fields of a profile image upload:
+// a cropX/cropY/cropWidth/cropHeight rectangle in source pixels, or a
+// focusX/focusY focal point between 0 and 1. Must be called after the
+// multipart form is parsed.
+func parseImageCrop(w http.ResponseWriter, r *http.Request) (*blob.Crop, bool) {
+	rectFields := []string{"cropX", "cropY", "cropWidth", "cropHeight"}
+	rectValues := make([]int, 0, len(rectFields))
+	for _, field := range rectFields {
+		raw := r.FormValue(field)
+		if raw == "" {
+			continue
+		}
+		value, err := strconv.Atoi(raw)
+		if err != nil || value < 0 {
+			badRequest(w, field+" must be a non-negative integer")
+			return nil, false
+		}
+		rectValues = append(rectValues, value)
+	}
+	if len(rectValues) == len(rectFields) {
+		x, y, width, height := rectValues[0], rectValues[1], rectValues[2], rectValues[3]
+		if width == 0 || height == 0 {
+			badRequest(w, "cropWidth and cropHeight must be positive")
+			return nil, false
+		}
+		return &blob.Crop{Rect: image.Rect(x, y, x+width, y+height)}, true
+	}
+	if len(rectValues) > 0 {
+		badRequest(w, "cropX, cropY, cropWidth and cropHeight must be provided together")
+		return nil, false
+	}
+
+	rawX, rawY := r.FormValue("focusX"), r.FormValue("focusY")
+	if rawX == "" && rawY == "" {
+		return nil, true
+	}
+	focusX, errX := strconv.ParseFloat(rawX, 64)
+	focusY, errY := strconv.ParseFloat(rawY, 64)
+	if errX != nil || errY != nil || focusX < 0 || focusX > 1 || focusY < 0 || focusY > 1 {
+		badRequest(w, "focusX and focusY must both be between 0 and 1")
+		return nil, false
+	}
+	return &blob.Crop{Focus: &blob.FocalPoint{X: focusX, Y: focusY}}, true
+}
+
 func handleBlobSaveError(w http.ResponseWriter, err error) bool {
 	if err == nil {
 		return true
@@ -497,6 +953,21 @@ func handleBlobSaveError(w http.ResponseWriter, err error) bool {
 	return false
 }
 
+// handleSizedBlobSaveError is handleBlobSaveError for a save capped at
+// limit, naming the file in the 413.
+func handleSizedBlobSaveError(w http.ResponseWriter, err error, kind blob.Kind, name string, limit int64) bool {
+	if errors.Is(err, blob.ErrFileTooLarge) {
+		fileTooLarge(w, ws.AttachmentViolation{
+			Name:   name,
+			Kind:   string(kind),
+			Reason: ws.AttachmentTooLarge,
+			Limit:  limit,
+		})
+		return false
+	}
+	return handleBlobSaveError(w, err)
+}
+
 func handleImageNormalizeError(w http.ResponseWriter, err error) bool {
 	if err == nil {
 		return true
@@ -506,6 +977,14 @@ func handleImageNormalizeError(w http.ResponseWriter, err error) bool {
 		badRequest(w, "Invalid image file")
 		return false
 	}
+	if errors.Is(err, blob.ErrInvalidCrop) {
+		badRequest(w, "Crop region is outside the image")
+		return false
+	}
+	if errors.Is(err, blob.ErrUnsupportedImageFormat) {
+		badRequest(w, "HEIC and AVIF images are not supported by this server")
+		return false
+	}
 
 	slog.Error("error normalizing image", "error", err)
 	internalError(w)
@@ -2,11 +2,26 @@ package api
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
+	"image"
+	"io"
+	"io/fs"
 	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
+
+	"lobby/internal/blob"
+	sqldb "lobby/internal/db/sqlc"
+	"lobby/internal/models"
+	"lobby/internal/ws"
 )
 
 func TestReadSingleFileUploadReturnsJSON413OnOversizeBody(t *testing.T) {
@@ -27,7 +42,7 @@ func TestReadSingleFileUploadReturnsJSON413OnOversizeBody(t *testing.T) {
 	req.Header.Set("Content-Type", writer.FormDataContentType())
 	rr := httptest.NewRecorder()
 
-	file, header, cleanup, ok := readSingleFileUpload(rr, req, 1024)
+	file, header, cleanup, ok := readSingleFileUpload(rr, req, blob.KindChatAttachment, 1024)
 	if cleanup != nil {
 		cleanup()
 	}
@@ -52,4 +67,302 @@ func TestReadSingleFileUploadReturnsJSON413OnOversizeBody(t *testing.T) {
 	if resp.Error.Code != ErrCodePayloadTooLarge {
 		t.Fatalf("error.code = %q, want %q", resp.Error.Code, ErrCodePayloadTooLarge)
 	}
+	want := []ws.AttachmentViolation{{Name: "large.bin", Kind: "chat_attachment", Reason: ws.AttachmentTooLarge, Limit: 1024, Size: 2048}}
+	if !reflect.DeepEqual(resp.Error.Attachments, want) {
+		t.Fatalf("error.attachments = %+v, want %+v", resp.Error.Attachments, want)
+	}
+}
+
+func TestPrecheckUpload(t *testing.T) {
+	blobs, err := blob.NewService(t.TempDir(), 1024)
+	if err != nil {
+		t.Fatalf("blob.NewService() error = %v", err)
+	}
+	handler := NewUploadHandler(nil, nil, blobs, nil, "Lobby", "http://localhost", models.UploadLimits{Default: 1024})
+
+	tests := []struct {
+		name string
+		body string
+		want int
+	}{
+		{name: "accepted", body: `{"name":"notes.txt","size":512,"mimeType":"text/plain"}`, want: http.StatusOK},
+		{name: "too_large", body: `{"name":"video.mp4","size":4096,"mimeType":"video/mp4"}`, want: http.StatusRequestEntityTooLarge},
+		{name: "blocked_type", body: `{"name":"page.html","size":10,"mimeType":"text/html; charset=utf-8"}`, want: http.StatusBadRequest},
+		{name: "avatar_not_image", body: `{"name":"a.pdf","size":10,"mimeType":"application/pdf","kind":"avatar"}`, want: http.StatusBadRequest},
+		{name: "missing_size", body: `{"name":"empty.txt","mimeType":"text/plain"}`, want: http.StatusBadRequest},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/uploads/precheck", strings.NewReader(tc.body))
+			rr := httptest.NewRecorder()
+
+			handler.PrecheckUpload(rr, req)
+
+			if rr.Code != tc.want {
+				t.Fatalf("status = %d, want %d, body=%q", rr.Code, tc.want, rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestPrecheckUploadUsesRoleLimit(t *testing.T) {
+	blobs, err := blob.NewService(t.TempDir(), 4096)
+	if err != nil {
+		t.Fatalf("blob.NewService() error = %v", err)
+	}
+	limits := models.UploadLimits{Default: 1024, ByRole: map[string]int64{models.RoleModerator: 4096}}
+	handler := NewUploadHandler(nil, nil, blobs, nil, "Lobby", "http://localhost", limits)
+
+	tests := []struct {
+		role     string
+		want     int
+		maxBytes int64
+	}{
+		{role: models.RoleMember, want: http.StatusRequestEntityTooLarge},
+		{role: models.RoleModerator, want: http.StatusOK, maxBytes: 4096},
+		{role: models.RoleAdmin, want: http.StatusOK, maxBytes: 4096},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.role, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/uploads/precheck", strings.NewReader(`{"name":"clip.mp4","size":2048,"mimeType":"video/mp4"}`))
+			rr := httptest.NewRecorder()
+			handler.PrecheckUpload(rr, req.WithContext(context.WithValue(req.Context(), userRoleKey, tc.role)))
+
+			if rr.Code != tc.want {
+				t.Fatalf("status = %d, want %d, body=%q", rr.Code, tc.want, rr.Body.String())
+			}
+			if tc.want != http.StatusOK {
+				return
+			}
+			var resp UploadPrecheckResponse
+			if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("json.Unmarshal() error = %v", err)
+			}
+			if resp.MaxBytes != tc.maxBytes {
+				t.Fatalf("maxBytes = %d, want %d", resp.MaxBytes, tc.maxBytes)
+			}
+		})
+	}
+}
+
+func TestPrecheckUploadUsesAvatarLimit(t *testing.T) {
+	blobs, err := blob.NewService(t.TempDir(), 4096)
+	if err != nil {
+		t.Fatalf("blob.NewService() error = %v", err)
+	}
+	limits := models.UploadLimits{Default: 4096, Avatar: 1024}
+	handler := NewUploadHandler(nil, nil, blobs, nil, "Lobby", "http://localhost", limits)
+
+	precheck := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/uploads/precheck", strings.NewReader(body))
+		rr := httptest.NewRecorder()
+		handler.PrecheckUpload(rr, req)
+		return rr
+	}
+
+	if rr := precheck(`{"name":"photo.png","size":2048,"mimeType":"image/png"}`); rr.Code != http.StatusOK {
+		t.Fatalf("chat attachment status = %d, want %d", rr.Code, http.StatusOK)
+	}
+	rr := precheck(`{"name":"photo.png","size":2048,"mimeType":"image/png","kind":"avatar"}`)
+	if rr.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("avatar status = %d, want %d", rr.Code, http.StatusRequestEntityTooLarge)
+	}
+	var resp ErrorResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v", err)
+	}
+	want := []ws.AttachmentViolation{{Name: "photo.png", Kind: "avatar", Reason: ws.AttachmentTooLarge, Limit: 1024, Size: 2048}}
+	if !reflect.DeepEqual(resp.Error.Attachments, want) {
+		t.Fatalf("error.attachments = %+v, want %+v", resp.Error.Attachments, want)
+	}
+}
+
+func TestParseImageCrop(t *testing.T) {
+	tests := []struct {
+		name     string
+		fields   map[string]string
+		wantOK   bool
+		wantCrop *blob.Crop
+	}{
+		{name: "none", fields: map[string]string{}, wantOK: true},
+		{
+			name:     "rectangle",
+			fields:   map[string]string{"cropX": "10", "cropY": "20", "cropWidth": "30", "cropHeight": "40"},
+			wantOK:   true,
+			wantCrop: &blob.Crop{Rect: image.Rect(10, 20, 40, 60)},
+		},
+		{
+			name:     "focal point",
+			fields:   map[string]string{"focusX": "0.25", "focusY": "1"},
+			wantOK:   true,
+			wantCrop: &blob.Crop{Focus: &blob.FocalPoint{X: 0.25, Y: 1}},
+		},
+		{name: "partial rectangle", fields: map[string]string{"cropX": "10", "cropWidth": "30"}},
+		{name: "zero width", fields: map[string]string{"cropX": "0", "cropY": "0", "cropWidth": "0", "cropHeight": "10"}},
+		{name: "negative", fields: map[string]string{"cropX": "-1"}},
+		{name: "focal point out of range", fields: map[string]string{"focusX": "1.5", "focusY": "0"}},
+		{name: "focal point missing axis", fields: map[string]string{"focusX": "0.5"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			form := url.Values{}
+			for key, value := range tt.fields {
+				form.Set(key, value)
+			}
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/users/me/avatar", strings.NewReader(form.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			rr := httptest.NewRecorder()
+
+			crop, ok := parseImageCrop(rr, req)
+			if ok != tt.wantOK {
+				t.Fatalf("parseImageCrop() ok = %v, want %v, body=%q", ok, tt.wantOK, rr.Body.String())
+			}
+			if !ok && rr.Code != http.StatusBadRequest {
+				t.Fatalf("status = %d, want %d", rr.Code, http.StatusBadRequest)
+			}
+			if !reflect.DeepEqual(crop, tt.wantCrop) {
+				t.Fatalf("parseImageCrop() crop = %+v, want %+v", crop, tt.wantCrop)
+			}
+		})
+	}
+}
+
+func TestUploadServerImageRequiresAdmin(t *testing.T) {
+	handler := NewUploadHandler(nil, nil, nil, nil, "Lobby", "http://localhost", models.UploadLimits{Default: 1 << 20})
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/server/image", nil)
+	ctx := context.WithValue(req.Context(), userIDKey, "usr_mod")
+	ctx = context.WithValue(ctx, userRoleKey, models.RoleModerator)
+	rr := httptest.NewRecorder()
+	handler.UploadServerImage(rr, req.WithContext(ctx))
+
+	if rr.Code != http.StatusForbidden {
+		t.Fatalf("status = %d, want %d", rr.Code, http.StatusForbidden)
+	}
+	var resp ErrorResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v, body=%q", err, rr.Body.String())
+	}
+	if resp.Error.Code != ErrCodeServerManageDenied {
+		t.Fatalf("error.code = %q, want %q", resp.Error.Code, ErrCodeServerManageDenied)
+	}
+}
+
+// stubScanner reports files containing "EICAR" as infected, or fails every
+// scan with err.
+type stubScanner struct {
+	err error
+}
+
+func (s stubScanner) Scan(_ context.Context, src io.Reader) (blob.ScanResult, error) {
+	if s.err != nil {
+		return blob.ScanResult{}, s.err
+	}
+	data, err := io.ReadAll(src)
+	if err != nil {
+		return blob.ScanResult{}, err
+	}
+	if bytes.Contains(data, []byte("EICAR")) {
+		return blob.ScanResult{Infected: true, Signature: "Eicar-Test-Signature"}, nil
+	}
+	return blob.ScanResult{}, nil
+}
+
+func TestChatUploadVirusScan(t *testing.T) {
+	database := openTestDB(t)
+	queries := database.Queries()
+	if err := queries.CreateUser(context.Background(), sqldb.CreateUserParams{
+		ID:        "usr_1",
+		Username:  "alice",
+		Email:     "alice@example.com",
+		CreatedAt: time.Now().UTC(),
+	}); err != nil {
+		t.Fatalf("CreateUser() error = %v", err)
+	}
+	root := t.TempDir()
+	blobs, err := blob.NewService(root, 1<<20)
+	if err != nil {
+		t.Fatalf("blob.NewService() error = %v", err)
+	}
+	handler := NewUploadHandler(database, queries, blobs, nil, "Lobby", "http://localhost", models.UploadLimits{Default: 1 << 20})
+
+	upload := func(content string) *httptest.ResponseRecorder {
+		t.Helper()
+		body := bytes.NewBuffer(nil)
+		writer := multipart.NewWriter(body)
+		part, err := writer.CreateFormFile("file", "notes.txt")
+		if err != nil {
+			t.Fatalf("CreateFormFile() error = %v", err)
+		}
+		_, _ = part.Write([]byte(content))
+		_ = writer.Close()
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/uploads/chat", body)
+		req.Header.Set("Content-Type", writer.FormDataContentType())
+		rr := httptest.NewRecorder()
+		handler.UploadChatAttachment(rr, req.WithContext(context.WithValue(req.Context(), userIDKey, "usr_1")))
+		return rr
+	}
+	countFiles := func(dir string) int {
+		t.Helper()
+		n := 0
+		_ = filepath.WalkDir(filepath.Join(root, dir), func(_ string, entry fs.DirEntry, err error) error {
+			if err == nil && !entry.IsDir() {
+				n++
+			}
+			return nil
+		})
+		return n
+	}
+
+	handler.SetScanner(stubScanner{}, false, false)
+	rr := upload("plain notes")
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("clean upload status = %d, body=%q", rr.Code, rr.Body.String())
+	}
+	var uploaded ChatUploadResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &uploaded); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v", err)
+	}
+	if uploaded.ScanStatus != blob.ScanStatusClean {
+		t.Fatalf("clean upload scanStatus = %q, want %q", uploaded.ScanStatus, blob.ScanStatusClean)
+	}
+	if row, err := queries.GetBlobByID(context.Background(), uploaded.ID); err != nil || row.ScanStatus == nil || *row.ScanStatus != blob.ScanStatusClean {
+		t.Fatalf("clean blob scan status = %v, %v", row.ScanStatus, err)
+	}
+
+	if rr := upload("EICAR test"); rr.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("infected upload status = %d, want %d", rr.Code, http.StatusUnprocessableEntity)
+	}
+	if n := countFiles(string(blob.KindChatAttachment)); n != 1 {
+		t.Fatalf("chat attachment files after rejection = %d, want 1", n)
+	}
+
+	// Quarantined files are kept under a row that can't be served
+	handler.SetScanner(stubScanner{}, true, false)
+	if rr := upload("EICAR test"); rr.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("quarantined upload status = %d, want %d", rr.Code, http.StatusUnprocessableEntity)
+	}
+	if n := countFiles("quarantine"); n != 1 {
+		t.Fatalf("quarantined files = %d, want 1", n)
+	}
+	var quarantinedID string
+	if err := database.QueryRow(`SELECT id FROM blobs WHERE scan_status = 'infected'`).Scan(&quarantinedID); err != nil {
+		t.Fatalf("finding quarantined blob: %v", err)
+	}
+	if rr := mediaRequest(NewMediaHandler(queries, blobs, false, nil).GetBlob, quarantinedID, nil); rr.Code != http.StatusNotFound {
+		t.Fatalf("quarantined media status = %d, want %d", rr.Code, http.StatusNotFound)
+	}
+
+	handler.SetScanner(stubScanner{err: errors.New("clamd unreachable")}, false, false)
+	if rr := upload("plain notes"); rr.Code != http.StatusServiceUnavailable {
+		t.Fatalf("fail closed status = %d, want %d", rr.Code, http.StatusServiceUnavailable)
+	}
+	handler.SetScanner(stubScanner{err: errors.New("clamd unreachable")}, false, true)
+	rr = upload("plain notes")
+	if err := json.Unmarshal(rr.Body.Bytes(), &uploaded); err != nil || rr.Code != http.StatusCreated || uploaded.ScanStatus != blob.ScanStatusUnscanned {
+		t.Fatalf("fail open = %d %q, want 201 unscanned", rr.Code, rr.Body.String())
+	}
 }
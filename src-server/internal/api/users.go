@@ -1,8 +1,8 @@
 package api
 
 import (
+	"context"
 	"database/sql"
-	"encoding/json"
 	"errors"
 	"log/slog"
 	"net/http"
@@ -10,18 +10,27 @@ import (
 	"strings"
 	"time"
 
+	"lobby/internal/clock"
 	"lobby/internal/db"
 	sqldb "lobby/internal/db/sqlc"
 	"lobby/internal/ws"
 )
 
 type UserHandler struct {
-	queries *sqldb.Queries
-	hub     *ws.Hub
+	queries       *sqldb.Queries
+	hub           *ws.Hub
+	deletionGrace time.Duration
+	clock         clock.Clock
 }
 
-func NewUserHandler(queries *sqldb.Queries, hub *ws.Hub) *UserHandler {
-	return &UserHandler{queries: queries, hub: hub}
+func NewUserHandler(queries *sqldb.Queries, hub *ws.Hub, deletionGrace time.Duration) *UserHandler {
+	return &UserHandler{queries: queries, hub: hub, deletionGrace: deletionGrace, clock: clock.Real}
+}
+
+// SetClock replaces the clock behind account deletion grace periods and
+// session revocation.
+func (h *UserHandler) SetClock(c clock.Clock) {
+	h.clock = c
 }
 
 // GET /api/v1/users/me
@@ -48,7 +57,7 @@ func (h *UserHandler) GetMe(w http.ResponseWriter, r *http.Request) {
 }
 
 type UpdateUserRequest struct {
-	Username *string `json:"username"`
+	Username *string `json:"username" validate:"omitnil,max=64"`
 }
 
 var usernameRegex = regexp.MustCompile(`^[a-zA-Z0-9_-]{3,32}$`)
@@ -61,8 +70,8 @@ func (h *UserHandler) UpdateMe(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var req UpdateUserRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		badRequest(w, "Invalid JSON body")
+	if err := decodeAndValidate(r.Body, &req); err != nil {
+		writeDecodeError(w, err)
 		return
 	}
 
@@ -137,14 +146,11 @@ func (h *UserHandler) UpdateMe(w http.ResponseWriter, r *http.Request) {
 	user := modelUserFromDBUser(updatedUserRow)
 
 	if updated {
-		avatar := ""
-		if user.AvatarURL != nil {
-			avatar = *user.AvatarURL
-		}
 		h.hub.BroadcastDispatch(ws.EventUserUpdate, ws.UserUpdatePayload{
-			ID:       user.ID,
-			Username: user.Username,
-			Avatar:   avatar,
+			ID:             user.ID,
+			Username:       user.Username,
+			Avatar:         user.GetAvatarURL(),
+			AvatarAnimated: user.GetAvatarAnimatedURL(),
 		})
 	}
 
@@ -159,61 +165,102 @@ func (h *UserHandler) LeaveMe(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if _, err := h.queries.GetActiveUserByID(r.Context(), userID); err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			notFound(w, "User not found")
-			return
-		}
-		slog.Error("error finding user", "error", err)
-		internalError(w)
-		return
-	}
-
-	now := time.Now().UTC()
-	rowsAffected, err := h.queries.DeactivateUser(r.Context(), sqldb.DeactivateUserParams{
-		DeactivatedAt: &now,
-		UpdatedAt:     &now,
-		ID:            userID,
-	})
+	deactivated, err := deactivateMember(r.Context(), h.queries, userID, h.clock.Now().UTC())
 	if err != nil {
 		slog.Error("error deactivating user", "error", err, "user_id", userID)
 		internalError(w)
 		return
 	}
-	if rowsAffected == 0 {
+	if !deactivated {
 		notFound(w, "User not found")
 		return
 	}
 
-	revokedAt := time.Now().UTC()
-	if err := h.queries.RevokeAllRefreshTokensForUser(r.Context(), sqldb.RevokeAllRefreshTokensForUserParams{
-		RevokedAt: &revokedAt,
-		UserID:    userID,
+	disconnectMember(h.hub, userID)
+
+	writeJSON(w, http.StatusOK, map[string]string{"message": "Left server successfully"})
+}
+
+type AccountDeletionResponse struct {
+	DeleteAfter time.Time `json:"deleteAfter"`
+}
+
+// POST /api/v1/users/me/deletion
+// Leaves the server like DELETE /users/me and schedules the account's data to
+// be purged once the grace period ends. Signing in again before then cancels
+// the deletion.
+func (h *UserHandler) DeleteMe(w http.ResponseWriter, r *http.Request) {
+	userID := GetUserID(r)
+	if userID == "" {
+		unauthorized(w, "User not found in context")
+		return
+	}
+
+	// Scheduled first: a failed deactivation leaves an active user, whom the
+	// purge skips.
+	now := h.clock.Now().UTC()
+	deleteAfter := now.Add(h.deletionGrace)
+	if err := h.queries.ScheduleAccountDeletion(r.Context(), sqldb.ScheduleAccountDeletionParams{
+		UserID:      userID,
+		RequestedAt: now,
+		DeleteAfter: deleteAfter,
 	}); err != nil {
-		slog.Error("error revoking refresh tokens", "error", err, "user_id", userID)
+		slog.Error("error scheduling account deletion", "error", err, "user_id", userID)
 		internalError(w)
 		return
 	}
 
-	updatedAt := time.Now().UTC()
-	rowsAffected, err = h.queries.IncrementUserSessionVersion(r.Context(), sqldb.IncrementUserSessionVersionParams{
-		UpdatedAt: &updatedAt,
-		ID:        userID,
-	})
+	deactivated, err := deactivateMember(r.Context(), h.queries, userID, now)
 	if err != nil {
-		slog.Error("error incrementing session version", "error", err, "user_id", userID)
+		slog.Error("error deactivating user", "error", err, "user_id", userID)
 		internalError(w)
 		return
 	}
-	if rowsAffected == 0 {
+	if !deactivated {
 		notFound(w, "User not found")
 		return
 	}
 
-	h.hub.BroadcastDispatch(ws.EventUserLeft, ws.UserLeftPayload{UserID: userID})
-	if client := h.hub.GetClient(userID); client != nil {
-		client.Close()
+	if h.hub != nil {
+		disconnectMember(h.hub, userID)
 	}
 
-	writeJSON(w, http.StatusOK, map[string]string{"message": "Left server successfully"})
+	writeJSON(w, http.StatusOK, AccountDeletionResponse{DeleteAfter: deleteAfter})
+}
+
+// deactivateMember removes userID from the server membership and invalidates
+// all of their sessions as of now. It returns false if the user was already
+// inactive.
+func deactivateMember(ctx context.Context, queries *sqldb.Queries, userID string, now time.Time) (bool, error) {
+	rowsAffected, err := queries.DeactivateUser(ctx, sqldb.DeactivateUserParams{
+		DeactivatedAt: &now,
+		UpdatedAt:     &now,
+		ID:            userID,
+	})
+	if err != nil || rowsAffected == 0 {
+		return false, err
+	}
+
+	if err := queries.RevokeAllRefreshTokensForUser(ctx, sqldb.RevokeAllRefreshTokensForUserParams{
+		RevokedAt: &now,
+		UserID:    userID,
+	}); err != nil {
+		return false, err
+	}
+
+	if _, err := queries.IncrementUserSessionVersion(ctx, sqldb.IncrementUserSessionVersionParams{
+		UpdatedAt: &now,
+		ID:        userID,
+	}); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// disconnectMember announces USER_LEFT and closes the user's websocket.
+func disconnectMember(hub *ws.Hub, userID string) {
+	hub.BroadcastDispatch(ws.EventUserLeft, ws.UserLeftPayload{UserID: userID})
+	if client := hub.GetClient(userID); client != nil {
+		client.Close()
+	}
 }
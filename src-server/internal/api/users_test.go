@@ -28,7 +28,7 @@ func TestUpdateMeAllowsUnchangedUsername(t *testing.T) {
 		t.Fatalf("CreateUser() error = %v", err)
 	}
 
-	handler := NewUserHandler(queries, nil)
+	handler := NewUserHandler(queries, nil, time.Hour)
 	req := httptest.NewRequest(http.MethodPatch, "/api/v1/users/me", strings.NewReader(`{"username":"alice"}`))
 	req = req.WithContext(context.WithValue(req.Context(), userIDKey, "usr_self"))
 	rr := httptest.NewRecorder()
@@ -61,7 +61,7 @@ func TestUpdateMeRejectsTakenUsernameFromAnotherUser(t *testing.T) {
 		}
 	}
 
-	handler := NewUserHandler(queries, nil)
+	handler := NewUserHandler(queries, nil, time.Hour)
 	req := httptest.NewRequest(http.MethodPatch, "/api/v1/users/me", strings.NewReader(`{"username":"bob"}`))
 	req = req.WithContext(context.WithValue(req.Context(), userIDKey, "usr_1"))
 	rr := httptest.NewRecorder()
@@ -81,6 +81,89 @@ func TestUpdateMeRejectsTakenUsernameFromAnotherUser(t *testing.T) {
 	}
 }
 
+type recordingFileRemover struct {
+	deleted []string
+}
+
+func (r *recordingFileRemover) Delete(storagePath string) error {
+	r.deleted = append(r.deleted, storagePath)
+	return nil
+}
+
+func TestDeleteMeSchedulesPurge(t *testing.T) {
+	database := openTestDB(t)
+	queries := database.Queries()
+	ctx := context.Background()
+	now := time.Now().UTC()
+
+	if err := queries.CreateUser(ctx, sqldb.CreateUserParams{
+		ID:        "usr_1",
+		Username:  "alice",
+		Email:     "alice@example.com",
+		CreatedAt: now,
+	}); err != nil {
+		t.Fatalf("CreateUser() error = %v", err)
+	}
+	if err := queries.CreateMessage(ctx, sqldb.CreateMessageParams{ID: "msg_1", AuthorID: "usr_1", Content: "hello", CreatedAt: now}); err != nil {
+		t.Fatalf("CreateMessage() error = %v", err)
+	}
+	if err := queries.CreateBlob(ctx, sqldb.CreateBlobParams{
+		ID:           "blb_1",
+		Kind:         "avatar",
+		UploadedBy:   "usr_1",
+		StoragePath:  "avatars/blb_1",
+		MimeType:     "image/jpeg",
+		SizeBytes:    10,
+		OriginalName: "me.jpg",
+		CreatedAt:    now,
+	}); err != nil {
+		t.Fatalf("CreateBlob() error = %v", err)
+	}
+	if err := queries.UpsertMessageDraft(ctx, sqldb.UpsertMessageDraftParams{UserID: "usr_1", ChannelID: 1, Content: "draft", UpdatedAt: now}); err != nil {
+		t.Fatalf("UpsertMessageDraft() error = %v", err)
+	}
+
+	handler := NewUserHandler(queries, nil, 0)
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/users/me/deletion", nil)
+	rr := httptest.NewRecorder()
+	handler.DeleteMe(rr, req.WithContext(context.WithValue(req.Context(), userIDKey, "usr_1")))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d, body=%q", rr.Code, http.StatusOK, rr.Body.String())
+	}
+	if row, err := queries.GetUserByID(ctx, "usr_1"); err != nil || row.DeactivatedAt == nil || row.Email != "alice@example.com" {
+		t.Fatalf("user during grace = %+v, %v; want deactivated and intact", row, err)
+	}
+
+	due, err := queries.ListDueAccountDeletions(ctx, sqldb.ListDueAccountDeletionsParams{Now: time.Now().UTC(), LimitRows: 10})
+	if err != nil || len(due) != 1 || due[0] != "usr_1" {
+		t.Fatalf("due deletions = %v, %v", due, err)
+	}
+	files := &recordingFileRemover{}
+	if err := db.NewCleanupService(database, files).PurgeAccount(ctx, "usr_1"); err != nil {
+		t.Fatalf("PurgeAccount() error = %v", err)
+	}
+
+	row, err := queries.GetUserByID(ctx, "usr_1")
+	if err != nil {
+		t.Fatalf("GetUserByID() error = %v", err)
+	}
+	if row.Username != "deleted-usr_1" || row.Email != "usr_1@deleted.invalid" || row.AvatarUrl != nil {
+		t.Fatalf("purged user = %+v", row)
+	}
+	if _, err := queries.GetBlobByID(ctx, "blb_1"); err == nil {
+		t.Fatal("avatar blob still exists after purge")
+	}
+	if len(files.deleted) != 1 || files.deleted[0] != "avatars/blb_1" {
+		t.Fatalf("deleted files = %v", files.deleted)
+	}
+	if drafts, err := queries.ListMessageDraftsByUser(ctx, "usr_1"); err != nil || len(drafts) != 0 {
+		t.Fatalf("drafts after purge = %v, %v", drafts, err)
+	}
+	if message, err := queries.GetMessageByID(ctx, "msg_1"); err != nil || message.AuthorID != "usr_1" {
+		t.Fatalf("message after purge = %+v, %v; want kept under the tombstone", message, err)
+	}
+}
+
 func openTestDB(t *testing.T) *db.DB {
 	t.Helper()
 
@@ -1,19 +1,74 @@
 package api
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"net/http"
+	"reflect"
 	"strings"
 
 	"github.com/go-playground/validator/v10"
 )
 
-var requestValidator = validator.New()
+// maxJSONDepth bounds object/array nesting in request bodies so deeply
+// nested payloads are rejected before being decoded into handler structs.
+const maxJSONDepth = 32
+
+var requestValidator = newRequestValidator()
+
+func newRequestValidator() *validator.Validate {
+	v := validator.New()
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			return ""
+		}
+		if name == "" {
+			return strings.ToLower(field.Name)
+		}
+		return name
+	})
+	return v
+}
+
+// FieldError describes a single failed validation rule on a request field.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// RequestValidationError is returned by decodeAndValidate when the body
+// decoded cleanly but one or more fields failed validation.
+type RequestValidationError struct {
+	Fields []FieldError
+}
+
+func (e *RequestValidationError) Error() string {
+	if len(e.Fields) == 0 {
+		return "invalid request payload"
+	}
+	return e.Fields[0].Message
+}
+
+var errJSONTooDeep = errors.New("JSON body exceeds maximum nesting depth")
 
 func decodeAndValidate(body io.Reader, dst any) error {
-	decoder := json.NewDecoder(body)
+	raw, err := io.ReadAll(body)
+	if err != nil {
+		return fmt.Errorf("invalid JSON body")
+	}
+
+	if err := checkJSONDepth(raw, maxJSONDepth); err != nil {
+		if errors.Is(err, errJSONTooDeep) {
+			return err
+		}
+		return fmt.Errorf("invalid JSON body")
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(raw))
 	decoder.DisallowUnknownFields()
 
 	if err := decoder.Decode(dst); err != nil {
@@ -25,21 +80,16 @@ func decodeAndValidate(body io.Reader, dst any) error {
 	}
 
 	if err := requestValidator.Struct(dst); err != nil {
-		if validationErrors, ok := err.(validator.ValidationErrors); ok && len(validationErrors) > 0 {
-			first := validationErrors[0]
-			field := strings.ToLower(first.Field())
-			switch first.Tag() {
-			case "required":
-				return fmt.Errorf("%s is required", field)
-			case "email":
-				return fmt.Errorf("invalid email format")
-			case "len":
-				return fmt.Errorf("invalid %s length", field)
-			case "numeric":
-				return fmt.Errorf("%s must contain only digits", field)
-			default:
-				return fmt.Errorf("invalid %s", field)
+		var validationErrors validator.ValidationErrors
+		if errors.As(err, &validationErrors) && len(validationErrors) > 0 {
+			fields := make([]FieldError, 0, len(validationErrors))
+			for _, fieldErr := range validationErrors {
+				fields = append(fields, FieldError{
+					Field:   fieldErr.Field(),
+					Message: validationMessage(fieldErr),
+				})
 			}
+			return &RequestValidationError{Fields: fields}
 		}
 
 		return fmt.Errorf("invalid request payload")
@@ -47,3 +97,87 @@ func decodeAndValidate(body io.Reader, dst any) error {
 
 	return nil
 }
+
+func validationMessage(fieldErr validator.FieldError) string {
+	field := fieldErr.Field()
+	switch fieldErr.Tag() {
+	case "required":
+		return fmt.Sprintf("%s is required", field)
+	case "email":
+		return "invalid email format"
+	case "len":
+		return fmt.Sprintf("invalid %s length", field)
+	case "min":
+		if isNumericKind(fieldErr.Kind()) {
+			return fmt.Sprintf("%s must be at least %s", field, fieldErr.Param())
+		}
+		return fmt.Sprintf("%s must be at least %s characters", field, fieldErr.Param())
+	case "max":
+		if isNumericKind(fieldErr.Kind()) {
+			return fmt.Sprintf("%s must be at most %s", field, fieldErr.Param())
+		}
+		return fmt.Sprintf("%s must be at most %s characters", field, fieldErr.Param())
+	case "numeric":
+		return fmt.Sprintf("%s must contain only digits", field)
+	case "oneof":
+		return fmt.Sprintf("%s must be one of: %s", field, fieldErr.Param())
+	default:
+		return fmt.Sprintf("invalid %s", field)
+	}
+}
+
+func isNumericKind(kind reflect.Kind) bool {
+	switch kind {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+// checkJSONDepth walks the token stream and fails once nesting exceeds limit.
+func checkJSONDepth(raw []byte, limit int) error {
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	depth := 0
+	for {
+		token, err := decoder.Token()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		delim, ok := token.(json.Delim)
+		if !ok {
+			continue
+		}
+		switch delim {
+		case '{', '[':
+			depth++
+			if depth > limit {
+				return errJSONTooDeep
+			}
+		case '}', ']':
+			depth--
+		}
+	}
+}
+
+// writeDecodeError reports a decodeAndValidate failure, including per-field
+// details when validation (rather than decoding) failed.
+func writeDecodeError(w http.ResponseWriter, err error) {
+	var validationErr *RequestValidationError
+	if errors.As(err, &validationErr) {
+		writeJSON(w, http.StatusBadRequest, ErrorResponse{
+			Error: ErrorDetail{
+				Code:    ErrCodeInvalidRequest,
+				Message: validationErr.Error(),
+				Fields:  validationErr.Fields,
+			},
+		})
+		return
+	}
+	badRequest(w, err.Error())
+}
@@ -0,0 +1,107 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestDecodeAndValidate(t *testing.T) {
+	type payload struct {
+		Email string `json:"email" validate:"required,max=254"`
+		Code  string `json:"code" validate:"required,len=6,numeric"`
+	}
+
+	tests := []struct {
+		name        string
+		body        string
+		wantMessage string
+		wantFields  []string
+	}{
+		{
+			name: "valid",
+			body: `{"email":"a@example.com","code":"123456"}`,
+		},
+		{
+			name:        "unknown_field",
+			body:        `{"email":"a@example.com","code":"123456","extra":true}`,
+			wantMessage: "invalid JSON body",
+		},
+		{
+			name:        "trailing_data",
+			body:        `{"email":"a@example.com","code":"123456"} {}`,
+			wantMessage: "invalid JSON body",
+		},
+		{
+			name:        "too_deep",
+			body:        strings.Repeat("[", maxJSONDepth+1) + strings.Repeat("]", maxJSONDepth+1),
+			wantMessage: errJSONTooDeep.Error(),
+		},
+		{
+			name:        "multiple_field_errors",
+			body:        `{"code":"12a"}`,
+			wantMessage: "email is required",
+			wantFields:  []string{"email", "code"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var dst payload
+			err := decodeAndValidate(strings.NewReader(tc.body), &dst)
+			if tc.wantMessage == "" {
+				if err != nil {
+					t.Fatalf("decodeAndValidate() error = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("decodeAndValidate() error = nil, want %q", tc.wantMessage)
+			}
+			if err.Error() != tc.wantMessage {
+				t.Fatalf("error = %q, want %q", err.Error(), tc.wantMessage)
+			}
+
+			var validationErr *RequestValidationError
+			if !errors.As(err, &validationErr) {
+				if len(tc.wantFields) > 0 {
+					t.Fatalf("error type = %T, want *RequestValidationError", err)
+				}
+				return
+			}
+			if len(validationErr.Fields) != len(tc.wantFields) {
+				t.Fatalf("fields = %+v, want %v", validationErr.Fields, tc.wantFields)
+			}
+			for i, field := range tc.wantFields {
+				if validationErr.Fields[i].Field != field {
+					t.Fatalf("fields[%d].field = %q, want %q", i, validationErr.Fields[i].Field, field)
+				}
+			}
+		})
+	}
+}
+
+func TestWriteDecodeErrorIncludesFields(t *testing.T) {
+	rr := httptest.NewRecorder()
+	writeDecodeError(rr, &RequestValidationError{Fields: []FieldError{
+		{Field: "username", Message: "username is required"},
+	}})
+
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want %d", rr.Code, http.StatusBadRequest)
+	}
+
+	var resp ErrorResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v, body=%q", err, rr.Body.String())
+	}
+	if resp.Error.Code != ErrCodeInvalidRequest {
+		t.Fatalf("error.code = %q, want %q", resp.Error.Code, ErrCodeInvalidRequest)
+	}
+	if len(resp.Error.Fields) != 1 || resp.Error.Fields[0].Field != "username" {
+		t.Fatalf("error.fields = %+v, want username entry", resp.Error.Fields)
+	}
+}
@@ -0,0 +1,136 @@
+package api
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"lobby/internal/sfu"
+	"lobby/internal/ws"
+)
+
+// voiceSelfTestTimeout bounds candidate gathering, including the TURN
+// allocation, for one self-test.
+const voiceSelfTestTimeout = 10 * time.Second
+
+type VoiceHandler struct {
+	hub     *ws.Hub
+	running atomic.Bool
+}
+
+func NewVoiceHandler(hub *ws.Hub) *VoiceHandler {
+	return &VoiceHandler{hub: hub}
+}
+
+type VoiceSelfTestCheckResponse struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	Detail string `json:"detail"`
+}
+
+type VoiceSelfTestCandidateResponse struct {
+	Type     string `json:"type"`
+	Protocol string `json:"protocol"`
+	Address  string `json:"address"`
+	Port     uint16 `json:"port"`
+}
+
+type VoiceSelfTestResponse struct {
+	OK         bool                             `json:"ok"`
+	Checks     []VoiceSelfTestCheckResponse     `json:"checks"`
+	Candidates []VoiceSelfTestCandidateResponse `json:"candidates"`
+	DurationMs int64                            `json:"durationMs"`
+}
+
+func voiceSelfTestResponse(report *sfu.SelfTestReport, duration time.Duration) VoiceSelfTestResponse {
+	resp := VoiceSelfTestResponse{
+		OK:         report.OK,
+		Checks:     make([]VoiceSelfTestCheckResponse, 0, len(report.Checks)),
+		Candidates: make([]VoiceSelfTestCandidateResponse, 0, len(report.Candidates)),
+		DurationMs: duration.Milliseconds(),
+	}
+	for _, c := range report.Checks {
+		resp.Checks = append(resp.Checks, VoiceSelfTestCheckResponse(c))
+	}
+	for _, c := range report.Candidates {
+		resp.Candidates = append(resp.Candidates, VoiceSelfTestCandidateResponse(c))
+	}
+	return resp
+}
+
+type VoiceRouteResponse struct {
+	UserID              string `json:"userId"`
+	LocalCandidateType  string `json:"localCandidateType"`
+	RemoteCandidateType string `json:"remoteCandidateType"`
+	Protocol            string `json:"protocol"`
+}
+
+// GET /api/v1/voice/routes
+func (h *VoiceHandler) ListRoutes(w http.ResponseWriter, r *http.Request) {
+	routes := h.hub.VoiceRoutes()
+	resp := make([]VoiceRouteResponse, 0, len(routes))
+	for _, route := range routes {
+		resp = append(resp, VoiceRouteResponse{
+			UserID:              route.UserID,
+			LocalCandidateType:  route.LocalType,
+			RemoteCandidateType: route.RemoteType,
+			Protocol:            route.Protocol,
+		})
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+type VoiceStatsResponse struct {
+	UserID       string  `json:"userId"`
+	RTTMs        int64   `json:"rttMs"`
+	JitterMs     int64   `json:"jitterMs"`
+	UplinkLoss   float64 `json:"uplinkLoss"`
+	DownlinkLoss float64 `json:"downlinkLoss"`
+	UploadKbps   uint64  `json:"uploadKbps"`
+	DownloadKbps uint64  `json:"downloadKbps"`
+	Quality      string  `json:"quality"`
+}
+
+// GET /api/v1/voice/stats
+func (h *VoiceHandler) ListStats(w http.ResponseWriter, r *http.Request) {
+	stats := h.hub.VoiceStats()
+	resp := make([]VoiceStatsResponse, 0, len(stats))
+	for _, s := range stats {
+		resp = append(resp, VoiceStatsResponse{
+			UserID:       s.UserID,
+			RTTMs:        s.RTT.Milliseconds(),
+			JitterMs:     s.Jitter.Milliseconds(),
+			UplinkLoss:   s.UplinkLoss,
+			DownlinkLoss: s.DownlinkLoss,
+			UploadKbps:   s.InboundBitrate / 1000,
+			DownloadKbps: s.OutboundBitrate / 1000,
+			Quality:      ws.VoiceQuality(s),
+		})
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// POST /api/v1/voice/selftest
+func (h *VoiceHandler) SelfTest(w http.ResponseWriter, r *http.Request) {
+	if !h.running.CompareAndSwap(false, true) {
+		conflict(w, "A voice self-test is already running")
+		return
+	}
+	defer h.running.Store(false)
+
+	ctx, cancel := context.WithTimeout(r.Context(), voiceSelfTestTimeout)
+	defer cancel()
+
+	started := time.Now()
+	report, err := h.hub.VoiceSelfTest(ctx)
+	if err != nil {
+		slog.Error("error running voice self-test", "error", err)
+		internalError(w)
+		return
+	}
+
+	slog.Info("voice self-test finished", "ok", report.OK, "by", GetUserID(r))
+	writeJSON(w, http.StatusOK, voiceSelfTestResponse(report, time.Since(started)))
+}
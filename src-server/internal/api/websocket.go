@@ -113,6 +113,7 @@ func NewWebSocketHandler(hub *ws.Hub, cfg config.WebSocketConfig, ipResolver *Cl
 		upgrader: websocket.Upgrader{
 			ReadBufferSize:  1024,
 			WriteBufferSize: 1024,
+			Subprotocols:    []string{ws.BotSubprotocol},
 		},
 	}
 
@@ -121,6 +122,12 @@ func NewWebSocketHandler(hub *ws.Hub, cfg config.WebSocketConfig, ipResolver *Cl
 }
 
 func (h *WebSocketHandler) ServeWS(w http.ResponseWriter, r *http.Request) {
+	encoding := r.URL.Query().Get("encoding")
+	if !ws.IsValidEncoding(encoding) {
+		http.Error(w, "Unsupported encoding", http.StatusBadRequest)
+		return
+	}
+
 	clientIP := h.ipResolver.Resolve(r)
 	if !h.preAuthBudget.reserve(clientIP) {
 		slog.Warn("rejecting websocket upgrade due to pre-auth budget", "component", "ws", "ip", clientIP)
@@ -136,6 +143,11 @@ func (h *WebSocketHandler) ServeWS(w http.ResponseWriter, r *http.Request) {
 	}
 
 	client := ws.NewClient(h.hub, conn)
+	client.SetRemoteIP(clientIP)
+	client.SetEncoding(encoding)
+	if conn.Subprotocol() == ws.BotSubprotocol {
+		client.EnableBotMode()
+	}
 	h.preAuthBudget.track(client, clientIP)
 
 	client.OnIdentified(func(client *ws.Client) {
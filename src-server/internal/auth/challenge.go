@@ -0,0 +1,158 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/bits"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"lobby/internal/clock"
+)
+
+var ErrChallengeFailed = errors.New("proof-of-work challenge failed")
+
+// Challenge is a hashcash-style puzzle a client solves before requesting a
+// magic code. A nonce solves it when SHA-256 of "<Token>:<email>:<nonce>"
+// starts with Bits zero bits.
+type Challenge struct {
+	Token     string
+	Bits      int
+	ExpiresAt time.Time
+}
+
+// ChallengeService issues and checks proof-of-work challenges. Tokens are
+// signed, so issuing needs no storage; solved tokens are remembered until
+// they expire so each one buys a single request.
+type ChallengeService struct {
+	secret []byte
+	bits   int
+	ttl    time.Duration
+	clock  clock.Clock
+
+	mu    sync.Mutex
+	spent map[string]time.Time
+}
+
+// NewChallengeService creates the service. bits is the difficulty; 0
+// disables the challenge.
+func NewChallengeService(secret string, bits int, ttl time.Duration) *ChallengeService {
+	return &ChallengeService{
+		secret: []byte(secret),
+		bits:   bits,
+		ttl:    ttl,
+		clock:  clock.Real,
+		spent:  make(map[string]time.Time),
+	}
+}
+
+// SetClock replaces the clock used to expire challenges.
+func (s *ChallengeService) SetClock(c clock.Clock) {
+	s.clock = c
+}
+
+// Enabled reports whether magic code requests must carry a solved challenge.
+func (s *ChallengeService) Enabled() bool {
+	return s != nil && s.bits > 0
+}
+
+// Issue returns a new challenge.
+func (s *ChallengeService) Issue() (Challenge, error) {
+	var salt [16]byte
+	if _, err := rand.Read(salt[:]); err != nil {
+		return Challenge{}, fmt.Errorf("generating challenge salt: %w", err)
+	}
+	expiresAt := s.clock.Now().Add(s.ttl)
+	payload := strconv.Itoa(s.bits) + "." + strconv.FormatInt(expiresAt.Unix(), 10) + "." + hex.EncodeToString(salt[:])
+	return Challenge{
+		Token:     payload + "." + s.signature(payload),
+		Bits:      s.bits,
+		ExpiresAt: expiresAt,
+	}, nil
+}
+
+// Verify checks that nonce solves token for email and spends the token.
+func (s *ChallengeService) Verify(token, email, nonce string) error {
+	payload, signature, ok := cutLast(token, ".")
+	if !ok || !hmac.Equal([]byte(signature), []byte(s.signature(payload))) {
+		return ErrChallengeFailed
+	}
+	fields := strings.Split(payload, ".")
+	if len(fields) != 3 {
+		return ErrChallengeFailed
+	}
+	difficulty, err := strconv.Atoi(fields[0])
+	if err != nil || difficulty < s.bits {
+		return ErrChallengeFailed
+	}
+	expiresUnix, err := strconv.ParseInt(fields[1], 10, 64)
+	if err != nil {
+		return ErrChallengeFailed
+	}
+	expiresAt := time.Unix(expiresUnix, 0)
+	now := s.clock.Now()
+	if !now.Before(expiresAt) {
+		return ErrChallengeFailed
+	}
+
+	sum := sha256.Sum256([]byte(token + ":" + email + ":" + nonce))
+	if leadingZeroBits(sum[:]) < difficulty {
+		return ErrChallengeFailed
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for spentToken, spentExpiry := range s.spent {
+		if !now.Before(spentExpiry) {
+			delete(s.spent, spentToken)
+		}
+	}
+	if _, ok := s.spent[token]; ok {
+		return ErrChallengeFailed
+	}
+	s.spent[token] = expiresAt
+	return nil
+}
+
+// SolveChallenge searches for a nonce that solves token for email at the
+// given difficulty. It is what clients run; the server only verifies.
+func SolveChallenge(token, email string, difficulty int) string {
+	for n := 0; ; n++ {
+		nonce := strconv.Itoa(n)
+		sum := sha256.Sum256([]byte(token + ":" + email + ":" + nonce))
+		if leadingZeroBits(sum[:]) >= difficulty {
+			return nonce
+		}
+	}
+}
+
+func (s *ChallengeService) signature(payload string) string {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte("pow-challenge:" + payload))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func leadingZeroBits(sum []byte) int {
+	n := 0
+	for _, b := range sum {
+		if b != 0 {
+			return n + bits.LeadingZeros8(b)
+		}
+		n += 8
+	}
+	return n
+}
+
+func cutLast(s, sep string) (before, after string, found bool) {
+	i := strings.LastIndex(s, sep)
+	if i < 0 {
+		return s, "", false
+	}
+	return s[:i], s[i+len(sep):], true
+}
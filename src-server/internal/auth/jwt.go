@@ -1,27 +1,42 @@
 package auth
 
 import (
+	"crypto"
 	"crypto/rand"
 	"crypto/sha256"
 	"encoding/hex"
 	"fmt"
+	"os"
 	"strings"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 
+	"lobby/internal/clock"
 	"lobby/internal/models"
 )
 
+// Supported access token signing algorithms.
+const (
+	JWTAlgorithmHS256 = "HS256"
+	JWTAlgorithmRS256 = "RS256"
+	JWTAlgorithmEdDSA = "EdDSA"
+)
+
 type JWTService struct {
-	secret          []byte
+	method          jwt.SigningMethod
+	signKey         any
+	verifyKey       any
 	accessTokenTTL  time.Duration
 	refreshTokenTTL time.Duration
+	leeway          time.Duration
+	clock           clock.Clock
 }
 
 type Claims struct {
 	UserID         string `json:"userId"`
 	SessionVersion int    `json:"sessionVersion"`
+	SessionID      string `json:"sessionId,omitempty"` // user_sessions row the token was issued for
 	jwt.RegisteredClaims
 }
 
@@ -33,26 +48,89 @@ type TokenPair struct {
 
 func NewJWTService(secret string, accessTTL, refreshTTL time.Duration) *JWTService {
 	return &JWTService{
-		secret:          []byte(secret),
+		method:          jwt.SigningMethodHS256,
+		signKey:         []byte(secret),
+		verifyKey:       []byte(secret),
 		accessTokenTTL:  accessTTL,
 		refreshTokenTTL: refreshTTL,
+		clock:           clock.Real,
 	}
 }
 
-func (s *JWTService) GenerateTokenPair(user *models.User) (*TokenPair, string, error) {
-	accessExpiry := time.Now().Add(s.accessTokenTTL)
+// SetClock replaces the clock used to issue, validate, and expire tokens.
+func (s *JWTService) SetClock(c clock.Clock) {
+	s.clock = c
+}
+
+// SetAlgorithm switches access tokens to algorithm. HS256 keeps signing with
+// the secret; RS256 and EdDSA sign with the PEM private key in keyFile and
+// verify with its public half. Tokens signed with the previous algorithm
+// stop validating. An empty algorithm means HS256.
+func (s *JWTService) SetAlgorithm(algorithm, keyFile string) error {
+	if algorithm == "" || algorithm == JWTAlgorithmHS256 {
+		return nil
+	}
+
+	keyPEM, err := os.ReadFile(keyFile)
+	if err != nil {
+		return fmt.Errorf("reading jwt key file: %w", err)
+	}
+
+	var key crypto.Signer
+	switch algorithm {
+	case JWTAlgorithmRS256:
+		s.method = jwt.SigningMethodRS256
+		key, err = jwt.ParseRSAPrivateKeyFromPEM(keyPEM)
+	case JWTAlgorithmEdDSA:
+		s.method = jwt.SigningMethodEdDSA
+		var parsed crypto.PrivateKey
+		parsed, err = jwt.ParseEdPrivateKeyFromPEM(keyPEM)
+		if err == nil {
+			signer, ok := parsed.(crypto.Signer)
+			if !ok {
+				return fmt.Errorf("jwt key file does not contain an Ed25519 private key")
+			}
+			key = signer
+		}
+	default:
+		return fmt.Errorf("unsupported jwt algorithm %q", algorithm)
+	}
+	if err != nil {
+		return fmt.Errorf("parsing jwt key file: %w", err)
+	}
+
+	s.signKey = key
+	s.verifyKey = key.Public()
+	return nil
+}
+
+// SetLeeway allows access tokens to validate for this long past their
+// expiry, absorbing clock drift between servers and clients.
+func (s *JWTService) SetLeeway(d time.Duration) {
+	s.leeway = d
+}
+
+// Leeway returns how long an access token stays valid past its expiry.
+func (s *JWTService) Leeway() time.Duration {
+	return s.leeway
+}
+
+func (s *JWTService) GenerateTokenPair(user *models.User, sessionID string) (*TokenPair, string, error) {
+	now := s.clock.Now()
+	accessExpiry := now.Add(s.accessTokenTTL)
 	accessClaims := Claims{
 		UserID:         user.ID,
 		SessionVersion: user.SessionVersion,
+		SessionID:      sessionID,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(accessExpiry),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			IssuedAt:  jwt.NewNumericDate(now),
 			Subject:   user.ID,
 		},
 	}
 
-	accessToken := jwt.NewWithClaims(jwt.SigningMethodHS256, accessClaims)
-	accessTokenString, err := accessToken.SignedString(s.secret)
+	accessToken := jwt.NewWithClaims(s.method, accessClaims)
+	accessTokenString, err := accessToken.SignedString(s.signKey)
 	if err != nil {
 		return nil, "", fmt.Errorf("signing access token: %w", err)
 	}
@@ -73,11 +151,11 @@ func (s *JWTService) GenerateTokenPair(user *models.User) (*TokenPair, string, e
 
 func (s *JWTService) ValidateAccessToken(tokenString string) (*Claims, error) {
 	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (any, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+		if token.Method.Alg() != s.method.Alg() {
 			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
 		}
-		return s.secret, nil
-	})
+		return s.verifyKey, nil
+	}, jwt.WithTimeFunc(s.clock.Now), jwt.WithLeeway(s.leeway))
 	if err != nil {
 		return nil, fmt.Errorf("parsing token: %w", err)
 	}
@@ -91,7 +169,7 @@ func (s *JWTService) ValidateAccessToken(tokenString string) (*Claims, error) {
 }
 
 func (s *JWTService) RefreshTokenExpiry() time.Time {
-	return time.Now().Add(s.refreshTokenTTL)
+	return s.clock.Now().Add(s.refreshTokenTTL)
 }
 
 func HashRefreshToken(token string) string {
@@ -107,6 +185,33 @@ func HashMagicCode(email, code string) string {
 	return hashToken(normalized)
 }
 
+// HashEmail identifies an email address in the ban list without storing it.
+func HashEmail(email string) string {
+	return hashToken("email:" + strings.ToLower(strings.TrimSpace(email)))
+}
+
+// BotTokenPrefix marks bot API tokens so IDENTIFY can tell them apart from
+// access JWTs without a database lookup.
+const BotTokenPrefix = "lobby_bot_"
+
+// GenerateBotToken returns a new bot API token. Only its HashBotToken digest
+// is stored.
+func GenerateBotToken() (string, error) {
+	token, err := generateSecureToken(32)
+	if err != nil {
+		return "", err
+	}
+	return BotTokenPrefix + token, nil
+}
+
+func IsBotToken(token string) bool {
+	return strings.HasPrefix(token, BotTokenPrefix)
+}
+
+func HashBotToken(token string) string {
+	return hashToken(token)
+}
+
 func GenerateOpaqueToken(length int) (string, error) {
 	return generateSecureToken(length)
 }
@@ -1,20 +1,35 @@
 package auth
 
 import (
+	"crypto/hmac"
 	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"math/big"
+	"strings"
 	"time"
+
+	"lobby/internal/clock"
 )
 
 const MaxAttempts = 5
 
 type MagicCodeService struct {
-	ttl time.Duration
+	ttl        time.Duration
+	linkSecret []byte
+	clock      clock.Clock
+}
+
+// NewMagicCodeService creates the service. linkSecret signs magic login links
+// so they cannot be built from a code ID alone.
+func NewMagicCodeService(ttl time.Duration, linkSecret string) *MagicCodeService {
+	return &MagicCodeService{ttl: ttl, linkSecret: []byte(linkSecret), clock: clock.Real}
 }
 
-func NewMagicCodeService(ttl time.Duration) *MagicCodeService {
-	return &MagicCodeService{ttl: ttl}
+// SetClock replaces the clock used to expire codes.
+func (s *MagicCodeService) SetClock(c clock.Clock) {
+	s.clock = c
 }
 
 // GenerateCode creates a 6-digit zero-padded numeric code using crypto/rand
@@ -29,5 +44,29 @@ func (s *MagicCodeService) GenerateCode() (string, error) {
 
 // ExpiresAt returns when a newly created code should expire
 func (s *MagicCodeService) ExpiresAt() time.Time {
-	return time.Now().Add(s.ttl)
+	return s.clock.Now().Add(s.ttl)
+}
+
+// LinkToken returns the token for a magic login link that redeems the code
+// with codeID.
+func (s *MagicCodeService) LinkToken(codeID string) string {
+	return codeID + "." + s.linkSignature(codeID)
+}
+
+// ParseLinkToken checks a LinkToken signature and returns its code ID.
+func (s *MagicCodeService) ParseLinkToken(token string) (string, bool) {
+	codeID, signature, ok := strings.Cut(token, ".")
+	if !ok || codeID == "" {
+		return "", false
+	}
+	if !hmac.Equal([]byte(signature), []byte(s.linkSignature(codeID))) {
+		return "", false
+	}
+	return codeID, true
+}
+
+func (s *MagicCodeService) linkSignature(codeID string) string {
+	mac := hmac.New(sha256.New, s.linkSecret)
+	mac.Write([]byte("magic-link:" + codeID))
+	return hex.EncodeToString(mac.Sum(nil))
 }
@@ -0,0 +1,210 @@
+package blob
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/draw"
+	"time"
+
+	"golang.org/x/image/webp"
+)
+
+// Animation describes an animated image: how many frames it has and how long
+// one loop of them plays.
+type Animation struct {
+	Frames   int
+	Duration time.Duration
+}
+
+// gifDefaultFrameDelay is the delay browsers play GIF frames asking for
+// 10ms or less at, which many GIFs do by accident.
+const gifDefaultFrameDelay = 100 * time.Millisecond
+
+// detectAnimation returns the animation of a GIF or WebP image with more
+// than one frame, or nil. It walks the container without decoding pixels,
+// so counting the frames of a large GIF stays cheap.
+func detectAnimation(data []byte) *Animation {
+	var animation *Animation
+	switch {
+	case bytes.HasPrefix(data, []byte("GIF87a")), bytes.HasPrefix(data, []byte("GIF89a")):
+		animation = gifAnimation(data)
+	case isWebP(data):
+		animation, _ = webpAnimation(data)
+	}
+	if animation == nil || animation.Frames < 2 {
+		return nil
+	}
+	return animation
+}
+
+// gifAnimation counts the image descriptors of a GIF and adds up the delays
+// of the graphic control extensions before them. A truncated file counts the
+// frames that arrived.
+func gifAnimation(data []byte) *Animation {
+	const headerLen = 13
+	if len(data) < headerLen {
+		return nil
+	}
+	pos := headerLen + colorTableLen(data[10])
+
+	animation := &Animation{}
+	var delay time.Duration
+	for pos < len(data) {
+		switch data[pos] {
+		case 0x21: // extension
+			if pos+2 >= len(data) {
+				return animation
+			}
+			if data[pos+1] == 0xF9 && pos+6 < len(data) && data[pos+2] == 4 {
+				delay = time.Duration(binary.LittleEndian.Uint16(data[pos+4:pos+6])) * 10 * time.Millisecond
+			}
+			pos = skipGIFSubBlocks(data, pos+2)
+		case 0x2C: // image descriptor
+			const descriptorLen = 10
+			if pos+descriptorLen >= len(data) {
+				return animation
+			}
+			animation.Frames++
+			if delay <= 10*time.Millisecond {
+				delay = gifDefaultFrameDelay
+			}
+			animation.Duration += delay
+			delay = 0
+			pos += descriptorLen + colorTableLen(data[pos+9])
+			// The LZW minimum code size precedes the image data
+			pos = skipGIFSubBlocks(data, pos+1)
+		default: // trailer, or garbage after the last frame
+			return animation
+		}
+	}
+	return animation
+}
+
+// colorTableLen returns the size of the color table a GIF flags byte
+// announces.
+func colorTableLen(flags byte) int {
+	if flags&0x80 == 0 {
+		return 0
+	}
+	return 3 << ((flags & 0x07) + 1)
+}
+
+// skipGIFSubBlocks returns the position after the sub-block sequence at pos,
+// or len(data) if it runs off the end.
+func skipGIFSubBlocks(data []byte, pos int) int {
+	for pos < len(data) {
+		n := int(data[pos])
+		pos++
+		if n == 0 {
+			return pos
+		}
+		pos += n
+	}
+	return len(data)
+}
+
+func isWebP(data []byte) bool {
+	return len(data) >= 12 && string(data[0:4]) == "RIFF" && string(data[8:12]) == "WEBP"
+}
+
+// webpFrame is the first frame of an animated WebP: where it sits on the
+// canvas and its ALPH, VP8 or VP8L chunks.
+type webpFrame struct {
+	canvas image.Rectangle
+	at     image.Point
+	width  int
+	height int
+	chunks []byte
+}
+
+// webpAnimation reads the VP8X header and ANMF frames of a WebP, returning
+// nil for a still image.
+func webpAnimation(data []byte) (*Animation, *webpFrame) {
+	var (
+		animation *Animation
+		first     *webpFrame
+		canvas    image.Rectangle
+	)
+	for pos := 12; pos+8 <= len(data); {
+		fourCC := string(data[pos : pos+4])
+		size := int(binary.LittleEndian.Uint32(data[pos+4 : pos+8]))
+		pos += 8
+		if size < 0 || size > len(data)-pos {
+			break
+		}
+		payload := data[pos : pos+size]
+		// Chunks are padded to an even length
+		pos += size + size&1
+
+		switch fourCC {
+		case "VP8X":
+			const animationBit = 1 << 1
+			if len(payload) < 10 || payload[0]&animationBit == 0 {
+				return nil, nil
+			}
+			animation = &Animation{}
+			canvas = image.Rect(0, 0, int(uint24(payload[4:7]))+1, int(uint24(payload[7:10]))+1)
+		case "ANMF":
+			if animation == nil || len(payload) < 16 {
+				continue
+			}
+			animation.Frames++
+			animation.Duration += time.Duration(uint24(payload[12:15])) * time.Millisecond
+			if first == nil {
+				first = &webpFrame{
+					canvas: canvas,
+					at:     image.Pt(int(uint24(payload[0:3]))*2, int(uint24(payload[3:6]))*2),
+					width:  int(uint24(payload[6:9])) + 1,
+					height: int(uint24(payload[9:12])) + 1,
+					chunks: payload[16:],
+				}
+			}
+		}
+	}
+	return animation, first
+}
+
+func uint24(b []byte) uint32 {
+	return uint32(b[0]) | uint32(b[1])<<8 | uint32(b[2])<<16
+}
+
+// decode returns the frame drawn on its canvas. x/image/webp only reads
+// still images, so the frame's chunks are rewrapped as one first.
+func (f *webpFrame) decode() (image.Image, error) {
+	const alphaBit = 1 << 4
+	var flags byte
+	if bytes.HasPrefix(f.chunks, []byte("ALPH")) {
+		flags |= alphaBit
+	}
+	header := make([]byte, 10)
+	header[0] = flags
+	putUint24(header[4:7], uint32(f.width-1))
+	putUint24(header[7:10], uint32(f.height-1))
+
+	still := bytes.NewBuffer(nil)
+	still.WriteString("RIFF")
+	_ = binary.Write(still, binary.LittleEndian, uint32(4+8+len(header)+len(f.chunks)))
+	still.WriteString("WEBPVP8X")
+	_ = binary.Write(still, binary.LittleEndian, uint32(len(header)))
+	still.Write(header)
+	still.Write(f.chunks)
+
+	frame, err := webp.Decode(still)
+	if err != nil {
+		return nil, fmt.Errorf("decoding first webp frame: %w", err)
+	}
+	if f.canvas.Empty() {
+		return frame, nil
+	}
+	img := image.NewNRGBA(f.canvas)
+	draw.Draw(img, frame.Bounds().Add(f.at), frame, frame.Bounds().Min, draw.Src)
+	return img, nil
+}
+
+func putUint24(b []byte, v uint32) {
+	b[0] = byte(v)
+	b[1] = byte(v >> 8)
+	b[2] = byte(v >> 16)
+}
@@ -0,0 +1,178 @@
+package blob
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/color/palette"
+	"image/gif"
+	"image/jpeg"
+	"testing"
+	"time"
+)
+
+func TestGenerateStaticImagePreviewMarksAnimatedGIF(t *testing.T) {
+	frame := func(c color.Color) *image.Paletted {
+		img := image.NewPaletted(image.Rect(0, 0, 960, 480), palette.Plan9)
+		for i := range img.Pix {
+			img.Pix[i] = uint8(img.Palette.Index(c))
+		}
+		return img
+	}
+	src := &gif.GIF{
+		Image: []*image.Paletted{frame(color.RGBA{R: 255, A: 255}), frame(color.RGBA{B: 255, A: 255}), frame(color.RGBA{G: 255, A: 255})},
+		// The zero delay plays at the 100ms browsers use
+		Delay: []int{5, 0, 20},
+	}
+	buf := bytes.NewBuffer(nil)
+	if err := gif.EncodeAll(buf, src); err != nil {
+		t.Fatalf("gif.EncodeAll() error = %v", err)
+	}
+
+	preview, err := GenerateStaticImagePreview(bytes.NewReader(buf.Bytes()), 480, 80)
+	if err != nil {
+		t.Fatalf("GenerateStaticImagePreview() error = %v", err)
+	}
+	if preview.Width != 480 || preview.Height != 240 {
+		t.Fatalf("preview dimensions = %dx%d, want 480x240", preview.Width, preview.Height)
+	}
+	want := Animation{Frames: 3, Duration: 350 * time.Millisecond}
+	if preview.Animation == nil || *preview.Animation != want {
+		t.Fatalf("preview.Animation = %+v, want %+v", preview.Animation, want)
+	}
+	assertPreviewColor(t, preview, color.RGBA{R: 255, A: 255})
+
+	buf.Reset()
+	if err := gif.Encode(buf, frame(color.White), nil); err != nil {
+		t.Fatalf("gif.Encode() error = %v", err)
+	}
+	preview, err = GenerateStaticImagePreview(bytes.NewReader(buf.Bytes()), 480, 80)
+	if err != nil {
+		t.Fatalf("GenerateStaticImagePreview() still error = %v", err)
+	}
+	if preview.Animation != nil {
+		t.Fatalf("still gif Animation = %+v, want nil", preview.Animation)
+	}
+}
+
+func TestGenerateStaticImagePreviewDecodesWebP(t *testing.T) {
+	still := riffWebP(webpChunk("VP8L", solidVP8L(16, 8, color.NRGBA{B: 255, A: 255})))
+	preview, err := GenerateStaticImagePreview(bytes.NewReader(still), 480, 80)
+	if err != nil {
+		t.Fatalf("GenerateStaticImagePreview() still error = %v", err)
+	}
+	if preview.Width != 16 || preview.Height != 8 || preview.Animation != nil {
+		t.Fatalf("still preview = %dx%d animation %+v, want 16x8 and none", preview.Width, preview.Height, preview.Animation)
+	}
+	assertPreviewColor(t, preview, color.RGBA{B: 255, A: 255})
+
+	preview, err = GenerateStaticImagePreview(bytes.NewReader(animatedTestWebP()), 480, 80)
+	if err != nil {
+		t.Fatalf("GenerateStaticImagePreview() animated error = %v", err)
+	}
+	want := Animation{Frames: 2, Duration: 100 * time.Millisecond}
+	if preview.Animation == nil || *preview.Animation != want {
+		t.Fatalf("preview.Animation = %+v, want %+v", preview.Animation, want)
+	}
+	if preview.Width != 16 || preview.Height != 8 {
+		t.Fatalf("animated preview dimensions = %dx%d, want 16x8", preview.Width, preview.Height)
+	}
+	assertPreviewColor(t, preview, color.RGBA{R: 255, A: 255})
+}
+
+// animatedTestWebP is a 16x8 WebP playing a red frame for 40ms, then a
+// green one for 60ms.
+func animatedTestWebP() []byte {
+	vp8x := make([]byte, 10)
+	vp8x[0] = 1 << 1 // animation
+	putUint24(vp8x[4:7], 16-1)
+	putUint24(vp8x[7:10], 8-1)
+	anmf := func(c color.NRGBA, duration uint32) []byte {
+		header := make([]byte, 16)
+		putUint24(header[6:9], 16-1)
+		putUint24(header[9:12], 8-1)
+		putUint24(header[12:15], duration)
+		return webpChunk("ANMF", append(header, webpChunk("VP8L", solidVP8L(16, 8, c))...))
+	}
+	return riffWebP(
+		webpChunk("VP8X", vp8x),
+		webpChunk("ANIM", make([]byte, 6)),
+		anmf(color.NRGBA{R: 255, A: 255}, 40),
+		anmf(color.NRGBA{G: 255, A: 255}, 60),
+	)
+}
+
+func assertPreviewColor(t *testing.T, preview *Preview, want color.RGBA) {
+	t.Helper()
+	img, err := jpeg.Decode(bytes.NewReader(preview.Data))
+	if err != nil {
+		t.Fatalf("jpeg.Decode() error = %v", err)
+	}
+	r, g, b, _ := img.At(img.Bounds().Dx()/2, img.Bounds().Dy()/2).RGBA()
+	near := func(got uint32, want uint8) bool {
+		diff := int(got>>8) - int(want)
+		return diff > -24 && diff < 24
+	}
+	if !near(r, want.R) || !near(g, want.G) || !near(b, want.B) {
+		t.Fatalf("preview center = %d,%d,%d, want about %d,%d,%d", r>>8, g>>8, b>>8, want.R, want.G, want.B)
+	}
+}
+
+func riffWebP(chunks ...[]byte) []byte {
+	body := bytes.NewBufferString("WEBP")
+	for _, chunk := range chunks {
+		body.Write(chunk)
+	}
+	out := bytes.NewBufferString("RIFF")
+	_ = binary.Write(out, binary.LittleEndian, uint32(body.Len()))
+	out.Write(body.Bytes())
+	return out.Bytes()
+}
+
+func webpChunk(fourCC string, payload []byte) []byte {
+	out := bytes.NewBufferString(fourCC)
+	_ = binary.Write(out, binary.LittleEndian, uint32(len(payload)))
+	out.Write(payload)
+	if len(payload)%2 == 1 {
+		out.WriteByte(0)
+	}
+	return out.Bytes()
+}
+
+// solidVP8L encodes a lossless WebP bitstream of one color: every prefix
+// code has a single symbol, so the pixels take no bits at all.
+func solidVP8L(width, height int, c color.NRGBA) []byte {
+	var (
+		out   = []byte{0x2f}
+		acc   uint64
+		nbits uint
+	)
+	write := func(v uint64, n uint) {
+		acc |= v << nbits
+		nbits += n
+		for nbits >= 8 {
+			out = append(out, byte(acc))
+			acc >>= 8
+			nbits -= 8
+		}
+	}
+	write(uint64(width-1), 14)
+	write(uint64(height-1), 14)
+	write(1, 1) // alpha is used
+	write(0, 3) // version
+	write(0, 1) // no transforms
+	write(0, 1) // no color cache
+	write(0, 1) // no meta prefix codes
+	// Green, red, blue, alpha and distance
+	for _, symbol := range []uint8{c.G, c.R, c.B, c.A, 0} {
+		write(1, 1) // simple code
+		write(0, 1) // one symbol
+		write(1, 1) // 8-bit symbol
+		write(uint64(symbol), 8)
+	}
+	if nbits > 0 {
+		out = append(out, byte(acc))
+	}
+	return out
+}
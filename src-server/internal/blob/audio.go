@@ -0,0 +1,334 @@
+package blob
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"time"
+)
+
+// AudioWaveformPeaks is how many peaks a waveform has, enough for a
+// voice-memo style player at any width.
+const AudioWaveformPeaks = 64
+
+// AudioMetadata is what a player needs before fetching the file: its length
+// and a waveform of AudioWaveformPeaks peaks from 0 to 255.
+type AudioMetadata struct {
+	Duration time.Duration
+	// Waveform is nil when it can't be read without a decoder.
+	Waveform []byte
+}
+
+// EncodedWaveform returns the waveform base64 encoded, as stored and sent to
+// clients, or "" when there is none.
+func (m *AudioMetadata) EncodedWaveform() string {
+	if len(m.Waveform) == 0 {
+		return ""
+	}
+	return base64.StdEncoding.EncodeToString(m.Waveform)
+}
+
+// IsAudioMimeType reports whether an upload may be audio GenerateAudioMetadata
+// reads. Ogg sniffs as application/ogg whatever it holds.
+func IsAudioMimeType(mimeType string) bool {
+	mimeType = strings.ToLower(strings.TrimSpace(mimeType))
+	return strings.HasPrefix(mimeType, "audio/") || mimeType == "application/ogg"
+}
+
+// GenerateAudioMetadata reads the duration and waveform of a WAV or Ogg
+// (Opus or Vorbis) file. WAV peaks come from the PCM samples. There is no
+// Opus or Vorbis decoder, so Ogg peaks follow the bitrate instead, which for
+// variable-bitrate speech rises and falls with loudness.
+func GenerateAudioMetadata(src io.Reader) (*AudioMetadata, error) {
+	reader := bufio.NewReader(src)
+	magic, err := reader.Peek(12)
+	if err != nil {
+		return nil, fmt.Errorf("reading audio header: %w", err)
+	}
+	switch {
+	case string(magic[0:4]) == "RIFF" && string(magic[8:12]) == "WAVE":
+		return wavMetadata(reader)
+	case string(magic[0:4]) == "OggS":
+		return oggMetadata(reader)
+	default:
+		return nil, fmt.Errorf("unsupported audio format")
+	}
+}
+
+const (
+	wavFormatPCM        = 1
+	wavFormatFloat      = 3
+	wavFormatExtensible = 0xFFFE
+)
+
+type wavFormat struct {
+	format        uint16
+	channels      int
+	sampleRate    int
+	blockAlign    int
+	bitsPerSample int
+}
+
+// wavMetadata walks the RIFF chunks to "fmt " and "data", then streams the
+// samples into peaks.
+func wavMetadata(r io.Reader) (*AudioMetadata, error) {
+	if _, err := io.CopyN(io.Discard, r, 12); err != nil {
+		return nil, fmt.Errorf("reading wav header: %w", err)
+	}
+	var format *wavFormat
+	header := make([]byte, 8)
+	for {
+		if _, err := io.ReadFull(r, header); err != nil {
+			return nil, fmt.Errorf("wav has no data chunk")
+		}
+		id := string(header[0:4])
+		size := int64(binary.LittleEndian.Uint32(header[4:8]))
+
+		switch id {
+		case "fmt ":
+			if size < 16 || size > 64 {
+				return nil, fmt.Errorf("invalid wav fmt chunk")
+			}
+			chunk := make([]byte, size)
+			if _, err := io.ReadFull(r, chunk); err != nil {
+				return nil, fmt.Errorf("reading wav fmt chunk: %w", err)
+			}
+			format = parseWAVFormat(chunk)
+		case "data":
+			if format == nil {
+				return nil, fmt.Errorf("wav data before fmt chunk")
+			}
+			return wavSamples(r, format, size)
+		default:
+			if _, err := io.CopyN(io.Discard, r, size); err != nil {
+				return nil, fmt.Errorf("skipping wav %q chunk: %w", id, err)
+			}
+		}
+		// Chunks are padded to an even length
+		if size%2 == 1 {
+			if _, err := io.CopyN(io.Discard, r, 1); err != nil {
+				return nil, fmt.Errorf("wav has no data chunk")
+			}
+		}
+	}
+}
+
+func parseWAVFormat(chunk []byte) *wavFormat {
+	format := &wavFormat{
+		format:        binary.LittleEndian.Uint16(chunk[0:2]),
+		channels:      int(binary.LittleEndian.Uint16(chunk[2:4])),
+		sampleRate:    int(binary.LittleEndian.Uint32(chunk[4:8])),
+		blockAlign:    int(binary.LittleEndian.Uint16(chunk[12:14])),
+		bitsPerSample: int(binary.LittleEndian.Uint16(chunk[14:16])),
+	}
+	// The real format is the first two bytes of the subformat GUID
+	if format.format == wavFormatExtensible && len(chunk) >= 26 {
+		format.format = binary.LittleEndian.Uint16(chunk[24:26])
+	}
+	return format
+}
+
+// wavSamples turns size bytes of samples into the file's metadata, keeping
+// the loudest sample of any channel in each stretch.
+func wavSamples(r io.Reader, format *wavFormat, size int64) (*AudioMetadata, error) {
+	bytesPerSample := format.bitsPerSample / 8
+	if format.channels <= 0 || format.sampleRate <= 0 || bytesPerSample <= 0 ||
+		format.blockAlign < format.channels*bytesPerSample {
+		return nil, fmt.Errorf("invalid wav format")
+	}
+	sample, err := wavSampleReader(format.format, bytesPerSample)
+	if err != nil {
+		return nil, err
+	}
+	frames := size / int64(format.blockAlign)
+	if frames <= 0 || size == math.MaxUint32 {
+		return nil, fmt.Errorf("wav data has no length")
+	}
+
+	metadata := &AudioMetadata{
+		Duration: time.Duration(frames) * time.Second / time.Duration(format.sampleRate),
+		Waveform: make([]byte, AudioWaveformPeaks),
+	}
+	framesPerPeak := (frames + AudioWaveformPeaks - 1) / AudioWaveformPeaks
+	block := make([]byte, format.blockAlign)
+	var loudest float64
+	for frame := int64(0); frame < frames; frame++ {
+		if _, err := io.ReadFull(r, block); err != nil {
+			// A truncated file keeps the peaks that arrived
+			break
+		}
+		for channel := 0; channel < format.channels; channel++ {
+			offset := channel * bytesPerSample
+			loudest = max(loudest, math.Abs(sample(block[offset:offset+bytesPerSample])))
+		}
+		if (frame+1)%framesPerPeak == 0 || frame == frames-1 {
+			metadata.Waveform[frame/framesPerPeak] = byte(math.Round(min(loudest, 1) * 255))
+			loudest = 0
+		}
+	}
+	metadata.Waveform = metadata.Waveform[:(frames+framesPerPeak-1)/framesPerPeak]
+	return metadata, nil
+}
+
+// wavSampleReader returns a function reading one sample as -1 to 1.
+func wavSampleReader(format uint16, bytesPerSample int) (func([]byte) float64, error) {
+	switch {
+	case format == wavFormatPCM && bytesPerSample == 1:
+		// 8-bit PCM is unsigned
+		return func(b []byte) float64 { return (float64(b[0]) - 128) / 128 }, nil
+	case format == wavFormatPCM && bytesPerSample == 2:
+		return func(b []byte) float64 { return float64(int16(binary.LittleEndian.Uint16(b))) / (1 << 15) }, nil
+	case format == wavFormatPCM && bytesPerSample == 3:
+		return func(b []byte) float64 { return float64(int32(uint24(b)<<8)>>8) / (1 << 23) }, nil
+	case format == wavFormatPCM && bytesPerSample == 4:
+		return func(b []byte) float64 { return float64(int32(binary.LittleEndian.Uint32(b))) / (1 << 31) }, nil
+	case format == wavFormatFloat && bytesPerSample == 4:
+		return func(b []byte) float64 { return float64(math.Float32frombits(binary.LittleEndian.Uint32(b))) }, nil
+	case format == wavFormatFloat && bytesPerSample == 8:
+		return func(b []byte) float64 { return math.Float64frombits(binary.LittleEndian.Uint64(b)) }, nil
+	default:
+		return nil, fmt.Errorf("unsupported wav sample format %d with %d bytes per sample", format, bytesPerSample)
+	}
+}
+
+// oggPage is a page of audio in the first logical stream of an Ogg file:
+// the granule position its packets end at and how many bytes they take.
+type oggPage struct {
+	granule uint64
+	bytes   int
+}
+
+// oggNoGranule marks a page on which no packet ends.
+const oggNoGranule = math.MaxUint64
+
+// oggMetadata reads the sample rate from the first packet and the length
+// from the last granule position.
+func oggMetadata(r io.Reader) (*AudioMetadata, error) {
+	var (
+		serial     uint32
+		sampleRate uint64
+		preSkip    uint64
+		pages      []oggPage
+		pending    int
+	)
+	header := make([]byte, 27)
+	for {
+		// A truncated last page ends the stream like EOF
+		if _, err := io.ReadFull(r, header); err != nil {
+			break
+		}
+		if string(header[0:4]) != "OggS" {
+			return nil, fmt.Errorf("invalid ogg page")
+		}
+		segments := make([]byte, header[26])
+		if _, err := io.ReadFull(r, segments); err != nil {
+			break
+		}
+		bodyLen := 0
+		for _, segment := range segments {
+			bodyLen += int(segment)
+		}
+		pageSerial := binary.LittleEndian.Uint32(header[14:18])
+		granule := binary.LittleEndian.Uint64(header[6:14])
+
+		if sampleRate == 0 {
+			body := make([]byte, bodyLen)
+			if _, err := io.ReadFull(r, body); err != nil {
+				return nil, fmt.Errorf("reading ogg codec header: %w", err)
+			}
+			var err error
+			if sampleRate, preSkip, err = oggCodecHeader(body); err != nil {
+				return nil, err
+			}
+			serial = pageSerial
+			continue
+		}
+		if _, err := io.CopyN(io.Discard, r, int64(bodyLen)); err != nil {
+			break
+		}
+		switch {
+		case pageSerial != serial:
+		case granule == 0: // the remaining codec headers
+		case granule == oggNoGranule:
+			pending += bodyLen
+		default:
+			pages = append(pages, oggPage{granule: granule, bytes: pending + bodyLen})
+			pending = 0
+		}
+	}
+	if sampleRate == 0 {
+		return nil, fmt.Errorf("ogg stream has no codec header")
+	}
+	if len(pages) == 0 || pages[len(pages)-1].granule <= preSkip {
+		return nil, fmt.Errorf("ogg stream has no audio")
+	}
+
+	samples := pages[len(pages)-1].granule - preSkip
+	return &AudioMetadata{
+		Duration: time.Duration(float64(samples) / float64(sampleRate) * float64(time.Second)),
+		Waveform: oggBitrateWaveform(pages, preSkip, samples),
+	}, nil
+}
+
+// oggCodecHeader reads the sample rate and pre-skip from the first packet of
+// an Opus or Vorbis stream.
+func oggCodecHeader(packet []byte) (sampleRate uint64, preSkip uint64, err error) {
+	switch {
+	case bytes.HasPrefix(packet, []byte("OpusHead")) && len(packet) >= 19:
+		// Opus granule positions always count at 48kHz
+		return 48000, uint64(binary.LittleEndian.Uint16(packet[10:12])), nil
+	case bytes.HasPrefix(packet, []byte("\x01vorbis")) && len(packet) >= 16:
+		rate := uint64(binary.LittleEndian.Uint32(packet[12:16]))
+		if rate == 0 {
+			return 0, 0, fmt.Errorf("invalid vorbis sample rate")
+		}
+		return rate, 0, nil
+	default:
+		return 0, 0, fmt.Errorf("unsupported ogg codec")
+	}
+}
+
+// oggBitrateWaveform spreads each page's bytes evenly over the samples it
+// covers and scales the bytes per peak to 0-255.
+func oggBitrateWaveform(pages []oggPage, preSkip, samples uint64) []byte {
+	if samples < AudioWaveformPeaks {
+		return nil
+	}
+	peakLen := float64(samples) / AudioWaveformPeaks
+	levels := make([]float64, AudioWaveformPeaks)
+	var start uint64
+	for _, page := range pages {
+		end := page.granule - min(page.granule, preSkip)
+		if end <= start {
+			continue
+		}
+		perSample := float64(page.bytes) / float64(end-start)
+		for i := int(float64(start) / peakLen); i < AudioWaveformPeaks; i++ {
+			from := max(float64(start), float64(i)*peakLen)
+			to := min(float64(end), float64(i+1)*peakLen)
+			if to <= from {
+				break
+			}
+			levels[i] += perSample * (to - from)
+		}
+		start = end
+	}
+
+	loudest := 0.0
+	for _, level := range levels {
+		loudest = max(loudest, level)
+	}
+	if loudest == 0 {
+		return nil
+	}
+	waveform := make([]byte, AudioWaveformPeaks)
+	for i, level := range levels {
+		waveform[i] = byte(math.Round(level / loudest * 255))
+	}
+	return waveform
+}
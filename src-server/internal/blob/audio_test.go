@@ -0,0 +1,110 @@
+package blob
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+	"time"
+)
+
+func TestGenerateAudioMetadataWAV(t *testing.T) {
+	// One second of 16-bit stereo at 8kHz: silent, then half volume on the
+	// right channel only
+	const sampleRate, frames = 8000, 8000
+	samples := bytes.NewBuffer(nil)
+	for frame := 0; frame < frames; frame++ {
+		right := int16(0)
+		if frame >= frames/2 {
+			right = 1 << 14
+			if frame%2 == 1 {
+				right = -right
+			}
+		}
+		_ = binary.Write(samples, binary.LittleEndian, []int16{0, right})
+	}
+	format := make([]byte, 16)
+	binary.LittleEndian.PutUint16(format[0:2], wavFormatPCM)
+	binary.LittleEndian.PutUint16(format[2:4], 2)
+	binary.LittleEndian.PutUint32(format[4:8], sampleRate)
+	binary.LittleEndian.PutUint32(format[8:12], sampleRate*4)
+	binary.LittleEndian.PutUint16(format[12:14], 4)
+	binary.LittleEndian.PutUint16(format[14:16], 16)
+
+	body := bytes.NewBufferString("WAVE")
+	// WAV is RIFF too, so its chunks are laid out like WebP's
+	body.Write(webpChunk("fmt ", format))
+	body.Write(webpChunk("LIST", []byte("INFOjunk!")))
+	body.Write(webpChunk("data", samples.Bytes()))
+	wav := bytes.NewBufferString("RIFF")
+	_ = binary.Write(wav, binary.LittleEndian, uint32(body.Len()))
+	wav.Write(body.Bytes())
+
+	metadata, err := GenerateAudioMetadata(bytes.NewReader(wav.Bytes()))
+	if err != nil {
+		t.Fatalf("GenerateAudioMetadata() error = %v", err)
+	}
+	if metadata.Duration != time.Second {
+		t.Fatalf("Duration = %v, want 1s", metadata.Duration)
+	}
+	if len(metadata.Waveform) != AudioWaveformPeaks {
+		t.Fatalf("len(Waveform) = %d, want %d", len(metadata.Waveform), AudioWaveformPeaks)
+	}
+	if metadata.Waveform[0] != 0 || metadata.Waveform[AudioWaveformPeaks-1] != 128 {
+		t.Fatalf("Waveform = %v, want silence then 128", metadata.Waveform)
+	}
+}
+
+func TestGenerateAudioMetadataOggOpus(t *testing.T) {
+	const preSkip = 312
+	head := append([]byte("OpusHead\x01\x01"), 0, 0, 0x80, 0xBB, 0, 0, 0, 0, 0)
+	binary.LittleEndian.PutUint16(head[10:12], preSkip)
+
+	ogg := bytes.NewBuffer(nil)
+	ogg.Write(oggTestPage(0, head))
+	ogg.Write(oggTestPage(0, []byte("OpusTags")))
+	// One second in four pages, quiet then loud
+	for i, size := range []int{100, 100, 400, 400} {
+		ogg.Write(oggTestPage(uint64(preSkip+12000*(i+1)), make([]byte, size)))
+	}
+	// Another logical stream is ignored
+	other := oggTestPage(preSkip+96000, make([]byte, 50))
+	binary.LittleEndian.PutUint32(other[14:18], 2)
+	ogg.Write(other)
+
+	metadata, err := GenerateAudioMetadata(bytes.NewReader(ogg.Bytes()))
+	if err != nil {
+		t.Fatalf("GenerateAudioMetadata() error = %v", err)
+	}
+	if metadata.Duration != time.Second {
+		t.Fatalf("Duration = %v, want 1s", metadata.Duration)
+	}
+	if len(metadata.Waveform) != AudioWaveformPeaks {
+		t.Fatalf("len(Waveform) = %d, want %d", len(metadata.Waveform), AudioWaveformPeaks)
+	}
+	if metadata.Waveform[0] != 64 || metadata.Waveform[AudioWaveformPeaks-1] != 255 {
+		t.Fatalf("Waveform = %v, want 64 then 255", metadata.Waveform)
+	}
+
+	if _, err := GenerateAudioMetadata(bytes.NewReader([]byte("ID3\x04\x00\x00\x00\x00\x00\x00\x00\x00"))); err == nil {
+		t.Fatalf("GenerateAudioMetadata(mp3) error = nil, want unsupported format")
+	}
+}
+
+// oggTestPage builds a page of stream 1 holding one packet. The checksum is
+// left out, since GenerateAudioMetadata doesn't check it.
+func oggTestPage(granule uint64, packet []byte) []byte {
+	header := make([]byte, 27)
+	copy(header, "OggS")
+	binary.LittleEndian.PutUint64(header[6:14], granule)
+	binary.LittleEndian.PutUint32(header[14:18], 1)
+	var segments []byte
+	for remaining := len(packet); ; remaining -= 255 {
+		if remaining < 255 {
+			segments = append(segments, byte(remaining))
+			break
+		}
+		segments = append(segments, 255)
+	}
+	header[26] = byte(len(segments))
+	return append(append(header, segments...), packet...)
+}
@@ -5,6 +5,7 @@ import (
 	"log/slog"
 	"time"
 
+	"lobby/internal/clock"
 	sqldb "lobby/internal/db/sqlc"
 )
 
@@ -14,28 +15,44 @@ const (
 )
 
 type CleanupService struct {
-	queries   *sqldb.Queries
-	blobs     *Service
-	interval  time.Duration
-	batchSize int64
+	queries           *sqldb.Queries
+	blobs             *Service
+	reconciler        *Reconciler
+	interval          time.Duration
+	reconcileInterval time.Duration
+	batchSize         int64
+	clock             clock.Clock
 }
 
 func NewCleanupService(queries *sqldb.Queries, blobs *Service) *CleanupService {
 	return &CleanupService{
-		queries:   queries,
-		blobs:     blobs,
-		interval:  DefaultCleanupInterval,
-		batchSize: DefaultCleanupBatch,
+		queries:           queries,
+		blobs:             blobs,
+		reconciler:        NewReconciler(queries, blobs),
+		interval:          DefaultCleanupInterval,
+		reconcileInterval: DefaultReconcileInterval,
+		batchSize:         DefaultCleanupBatch,
+		clock:             clock.Real,
 	}
 }
 
+// SetClock replaces the clock that decides which uploads have expired. Call
+// it before Start.
+func (s *CleanupService) SetClock(c clock.Clock) {
+	s.clock = c
+	s.reconciler.SetClock(c)
+}
+
 func (s *CleanupService) Start(ctx context.Context) {
-	slog.Info("starting blob cleanup service", "component", "blob_cleanup", "interval", s.interval)
+	slog.Info("starting blob cleanup service", "component", "blob_cleanup", "interval", s.interval, "reconcile_interval", s.reconcileInterval)
 
 	s.runCleanup(ctx)
+	s.runReconcile(ctx)
 
 	ticker := time.NewTicker(s.interval)
 	defer ticker.Stop()
+	reconcileTicker := time.NewTicker(s.reconcileInterval)
+	defer reconcileTicker.Stop()
 
 	for {
 		select {
@@ -44,12 +61,17 @@ func (s *CleanupService) Start(ctx context.Context) {
 			return
 		case <-ticker.C:
 			s.runCleanup(ctx)
+		case <-reconcileTicker.C:
+			s.runReconcile(ctx)
 		}
 	}
 }
 
 func (s *CleanupService) runCleanup(ctx context.Context) {
-	now := time.Now().UTC()
+	now := s.clock.Now().UTC()
+	s.deleteExpiredRecordings(ctx, now)
+	s.deleteExpiredUploadSessions(ctx, now)
+
 	rows, err := s.queries.ListExpiredUnclaimedChatBlobs(ctx, sqldb.ListExpiredUnclaimedChatBlobsParams{
 		Now:       &now,
 		LimitRows: s.batchSize,
@@ -84,3 +106,88 @@ func (s *CleanupService) runCleanup(ctx context.Context) {
 		slog.Info("deleted expired chat blobs", "component", "blob_cleanup", "count", len(rows))
 	}
 }
+
+// runReconcile checks stored files against blob rows, catching what expiry
+// alone leaves behind, such as files of rows lost to a crash or restore.
+func (s *CleanupService) runReconcile(ctx context.Context) {
+	report, err := s.reconciler.Reconcile(ctx, false)
+	if err != nil {
+		slog.Error("error reconciling blob files", "component", "blob_cleanup", "error", err)
+		return
+	}
+	if report.OrphanedFiles > 0 || report.MissingFiles > 0 || report.Recovered > 0 {
+		slog.Info("reconciled blob files", "component", "blob_cleanup",
+			"blobs", report.Blobs,
+			"files", report.Files,
+			"orphaned_files", report.OrphanedFiles,
+			"orphaned_bytes", report.OrphanedBytes,
+			"missing_files", report.MissingFiles,
+			"newly_missing", report.NewlyMissing,
+			"recovered", report.Recovered,
+		)
+	}
+}
+
+// deleteExpiredRecordings removes voice recordings past their retention,
+// including tracks left behind by a recording that never finished.
+func (s *CleanupService) deleteExpiredRecordings(ctx context.Context, now time.Time) {
+	ids, err := s.queries.ListExpiredRecordings(ctx, sqldb.ListExpiredRecordingsParams{
+		Now:       now,
+		LimitRows: s.batchSize,
+	})
+	if err != nil {
+		slog.Error("error listing expired recordings", "component", "blob_cleanup", "error", err)
+		return
+	}
+
+	for _, id := range ids {
+		rowsAffected, err := s.queries.DeleteRecording(ctx, id)
+		if err != nil {
+			slog.Error("error deleting expired recording row", "component", "blob_cleanup", "error", err, "recording_id", id)
+			continue
+		}
+		if rowsAffected == 0 {
+			continue
+		}
+
+		if err := s.blobs.DeleteAll(RecordingDir(id)); err != nil {
+			slog.Warn("error deleting expired recording files", "component", "blob_cleanup", "error", err, "recording_id", id)
+		}
+	}
+
+	if len(ids) > 0 {
+		slog.Info("deleted expired recordings", "component", "blob_cleanup", "count", len(ids))
+	}
+}
+
+// deleteExpiredUploadSessions removes resumable uploads that were never
+// finalized, with the bytes received for them.
+func (s *CleanupService) deleteExpiredUploadSessions(ctx context.Context, now time.Time) {
+	rows, err := s.queries.ListExpiredUploadSessions(ctx, sqldb.ListExpiredUploadSessionsParams{
+		Now:       now,
+		LimitRows: s.batchSize,
+	})
+	if err != nil {
+		slog.Error("error listing expired upload sessions", "component", "blob_cleanup", "error", err)
+		return
+	}
+
+	for _, row := range rows {
+		rowsAffected, err := s.queries.DeleteUploadSession(ctx, row.ID)
+		if err != nil {
+			slog.Error("error deleting expired upload session row", "component", "blob_cleanup", "error", err, "upload_id", row.ID)
+			continue
+		}
+		if rowsAffected == 0 {
+			continue
+		}
+
+		if err := s.blobs.Delete(row.StoragePath); err != nil {
+			slog.Warn("error deleting expired upload session file", "component", "blob_cleanup", "error", err, "upload_id", row.ID)
+		}
+	}
+
+	if len(rows) > 0 {
+		slog.Info("deleted expired upload sessions", "component", "blob_cleanup", "count", len(rows))
+	}
+}
@@ -0,0 +1,349 @@
+package blob
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// heifBrands maps the major brand of an ISO BMFF "ftyp" box to the MIME
+// type of the HEIF image inside. iPhones write "heic".
+var heifBrands = map[string]string{
+	"heic": "image/heic",
+	"heix": "image/heic",
+	"heim": "image/heic",
+	"heis": "image/heic",
+	"mif1": "image/heif",
+	"avif": "image/avif",
+}
+
+// heifDecodeTimeout bounds one run of the HEIF decode command.
+const heifDecodeTimeout = 30 * time.Second
+
+var (
+	heifDecodeMu      sync.RWMutex
+	heifDecodeCommand []string
+)
+
+// Registering the formats lets image.Decode, and so avatars and previews,
+// reach decodeHEIF; without a command it fails with
+// ErrUnsupportedImageFormat rather than image.ErrFormat. The registration is
+// process-wide: once a command is set, any image.Decode of HEIF input runs
+// it, while image.DecodeConfig only reads the container.
+func init() {
+	for brand := range heifBrands {
+		image.RegisterFormat(brand, "????ftyp"+brand, decodeHEIF, decodeHEIFConfig)
+	}
+}
+
+// heifMimeType returns the MIME type of a HEIC or AVIF image, or "".
+func heifMimeType(sniff []byte) string {
+	if len(sniff) < 12 || string(sniff[4:8]) != "ftyp" {
+		return ""
+	}
+	return heifBrands[string(sniff[8:12])]
+}
+
+// SetHEIFDecodeCommand sets the command HEIC and AVIF images are decoded
+// with. There is no pure-Go decoder for their HEVC and AV1 payloads, so this
+// leans on an external tool such as libheif's "heif-convert {input}
+// {output}". The command runs without a shell, with {input} and {output}
+// replaced by file paths; it must write {output} as PNG. An empty command
+// turns HEIF decoding off.
+func SetHEIFDecodeCommand(command string) error {
+	fields := strings.Fields(command)
+	if len(fields) > 0 {
+		if !strings.Contains(command, "{input}") || !strings.Contains(command, "{output}") {
+			return fmt.Errorf("heif decode command must use {input} and {output}")
+		}
+		if _, err := exec.LookPath(fields[0]); err != nil {
+			return fmt.Errorf("heif decode command: %w", err)
+		}
+	}
+
+	heifDecodeMu.Lock()
+	defer heifDecodeMu.Unlock()
+	heifDecodeCommand = fields
+	return nil
+}
+
+// decodeHEIF writes the image to a temporary directory, runs the decode
+// command on it and decodes the PNG it leaves.
+func decodeHEIF(r io.Reader) (image.Image, error) {
+	heifDecodeMu.RLock()
+	command := heifDecodeCommand
+	heifDecodeMu.RUnlock()
+	if len(command) == 0 {
+		return nil, fmt.Errorf("%w: HEIC and AVIF decoding is not configured", ErrUnsupportedImageFormat)
+	}
+
+	dir, err := os.MkdirTemp("", "lobby-heif-")
+	if err != nil {
+		return nil, fmt.Errorf("creating heif work directory: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	input := filepath.Join(dir, "input")
+	output := filepath.Join(dir, "output.png")
+	file, err := os.OpenFile(input, os.O_CREATE|os.O_WRONLY|os.O_EXCL, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("creating heif input: %w", err)
+	}
+	_, err = io.Copy(file, r)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, fmt.Errorf("writing heif input: %w", err)
+	}
+
+	args := make([]string, len(command))
+	replacer := strings.NewReplacer("{input}", input, "{output}", output)
+	for i, arg := range command {
+		args[i] = replacer.Replace(arg)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), heifDecodeTimeout)
+	defer cancel()
+	if out, err := exec.CommandContext(ctx, args[0], args[1:]...).CombinedOutput(); err != nil {
+		return nil, fmt.Errorf("running heif decode command: %w: %s", err, strings.TrimSpace(string(out)))
+	}
+
+	decoded, err := os.Open(output)
+	if err != nil {
+		return nil, fmt.Errorf("opening decoded heif image: %w", err)
+	}
+	defer decoded.Close()
+	img, format, err := image.Decode(decoded)
+	if err != nil {
+		return nil, fmt.Errorf("reading decoded heif image: %w", err)
+	}
+	if _, ok := heifBrands[format]; ok {
+		return nil, fmt.Errorf("heif decode command wrote another heif image")
+	}
+	return img, nil
+}
+
+// maxHEIFMetaBytes bounds the "meta" box decodeHEIFConfig reads. It holds
+// only item and property tables, a few KB even for large grid images.
+const maxHEIFMetaBytes = 1 << 20
+
+// decodeHEIFConfig reads the size of the primary image from its "ispe"
+// property, so it needs neither the decode command nor the pixels. An "irot"
+// of 90 or 270 degrees swaps it, as decoders apply the rotation.
+func decodeHEIFConfig(r io.Reader) (image.Config, error) {
+	meta, err := readHEIFMeta(r)
+	if err != nil {
+		return image.Config{}, err
+	}
+	width, height, err := heifPrimarySize(meta)
+	if err != nil {
+		return image.Config{}, err
+	}
+	return image.Config{ColorModel: color.NRGBAModel, Width: width, Height: height}, nil
+}
+
+// readHEIFMeta skips top-level boxes up to "meta" and returns its body.
+func readHEIFMeta(r io.Reader) ([]byte, error) {
+	var header [16]byte
+	for {
+		if _, err := io.ReadFull(r, header[:8]); err != nil {
+			return nil, fmt.Errorf("reading heif box: %w", err)
+		}
+		size := uint64(binary.BigEndian.Uint32(header[:4]))
+		headerLen := uint64(8)
+		if size == 1 {
+			if _, err := io.ReadFull(r, header[8:16]); err != nil {
+				return nil, fmt.Errorf("reading heif box: %w", err)
+			}
+			size = binary.BigEndian.Uint64(header[8:16])
+			headerLen = 16
+		}
+		// A size of 0 runs to the end of the file, after which no meta can follow
+		if size < headerLen {
+			return nil, fmt.Errorf("heif meta box not found")
+		}
+
+		bodyLen := size - headerLen
+		if string(header[4:8]) == "meta" {
+			if bodyLen > maxHEIFMetaBytes {
+				return nil, fmt.Errorf("heif meta box is %d bytes", bodyLen)
+			}
+			meta := make([]byte, bodyLen)
+			if _, err := io.ReadFull(r, meta); err != nil {
+				return nil, fmt.Errorf("reading heif meta box: %w", err)
+			}
+			return meta, nil
+		}
+		if _, err := io.CopyN(io.Discard, r, int64(bodyLen)); err != nil {
+			return nil, fmt.Errorf("skipping heif box: %w", err)
+		}
+	}
+}
+
+type heifBox struct {
+	typ  string
+	body []byte
+}
+
+// heifBoxes splits data into the boxes it holds.
+func heifBoxes(data []byte) ([]heifBox, error) {
+	var boxes []heifBox
+	for len(data) > 0 {
+		if len(data) < 8 {
+			return nil, fmt.Errorf("truncated heif box")
+		}
+		size := uint64(binary.BigEndian.Uint32(data))
+		headerLen := uint64(8)
+		switch size {
+		case 0:
+			size = uint64(len(data))
+		case 1:
+			if len(data) < 16 {
+				return nil, fmt.Errorf("truncated heif box")
+			}
+			size = binary.BigEndian.Uint64(data[8:16])
+			headerLen = 16
+		}
+		if size < headerLen || size > uint64(len(data)) {
+			return nil, fmt.Errorf("invalid heif box size %d", size)
+		}
+		boxes = append(boxes, heifBox{typ: string(data[4:8]), body: data[headerLen:size]})
+		data = data[size:]
+	}
+	return boxes, nil
+}
+
+// heifPrimarySize finds the primary item ("pitm") of a meta box body and
+// returns the size given by the properties ("iprp") associated with it.
+func heifPrimarySize(meta []byte) (width, height int, err error) {
+	// meta is a full box: version and flags come first
+	if len(meta) < 4 {
+		return 0, 0, fmt.Errorf("truncated heif meta box")
+	}
+	boxes, err := heifBoxes(meta[4:])
+	if err != nil {
+		return 0, 0, err
+	}
+
+	var primary uint32
+	var properties []heifBox
+	var associations []byte
+	for _, box := range boxes {
+		switch box.typ {
+		case "pitm":
+			switch {
+			case len(box.body) >= 6 && box.body[0] == 0:
+				primary = uint32(binary.BigEndian.Uint16(box.body[4:6]))
+			case len(box.body) >= 8:
+				primary = binary.BigEndian.Uint32(box.body[4:8])
+			default:
+				return 0, 0, fmt.Errorf("truncated heif pitm box")
+			}
+		case "iprp":
+			children, err := heifBoxes(box.body)
+			if err != nil {
+				return 0, 0, err
+			}
+			for _, child := range children {
+				switch child.typ {
+				case "ipco":
+					if properties, err = heifBoxes(child.body); err != nil {
+						return 0, 0, err
+					}
+				case "ipma":
+					associations = child.body
+				}
+			}
+		}
+	}
+
+	indices, err := heifItemProperties(associations, primary)
+	if err != nil {
+		return 0, 0, err
+	}
+	rotated := false
+	for _, index := range indices {
+		if index == 0 || index > len(properties) {
+			continue
+		}
+		property := properties[index-1]
+		switch property.typ {
+		case "ispe":
+			if len(property.body) < 12 {
+				return 0, 0, fmt.Errorf("truncated heif ispe box")
+			}
+			width = int(binary.BigEndian.Uint32(property.body[4:8]))
+			height = int(binary.BigEndian.Uint32(property.body[8:12]))
+		case "irot":
+			rotated = len(property.body) > 0 && property.body[0]&1 == 1
+		}
+	}
+	if width <= 0 || height <= 0 {
+		return 0, 0, fmt.Errorf("heif primary item has no size")
+	}
+	if rotated {
+		width, height = height, width
+	}
+	return width, height, nil
+}
+
+// heifItemProperties returns the 1-based "ipco" indices an "ipma" box body
+// associates with itemID.
+func heifItemProperties(ipma []byte, itemID uint32) ([]int, error) {
+	if len(ipma) < 8 {
+		return nil, fmt.Errorf("heif ipma box not found")
+	}
+	version := ipma[0]
+	indexLen := 1
+	if ipma[3]&1 == 1 {
+		indexLen = 2
+	}
+	count := binary.BigEndian.Uint32(ipma[4:8])
+	data := ipma[8:]
+	for range count {
+		var id uint32
+		if version < 1 {
+			if len(data) < 3 {
+				return nil, fmt.Errorf("truncated heif ipma box")
+			}
+			id = uint32(binary.BigEndian.Uint16(data))
+			data = data[2:]
+		} else {
+			if len(data) < 5 {
+				return nil, fmt.Errorf("truncated heif ipma box")
+			}
+			id = binary.BigEndian.Uint32(data)
+			data = data[4:]
+		}
+		n := int(data[0]) * indexLen
+		data = data[1:]
+		if len(data) < n {
+			return nil, fmt.Errorf("truncated heif ipma box")
+		}
+		if id != itemID {
+			data = data[n:]
+			continue
+		}
+
+		// The top bit of each index marks the property essential
+		indices := make([]int, 0, n/indexLen)
+		for i := 0; i < n; i += indexLen {
+			if indexLen == 2 {
+				indices = append(indices, int(binary.BigEndian.Uint16(data[i:])&0x7FFF))
+			} else {
+				indices = append(indices, int(data[i]&0x7F))
+			}
+		}
+		return indices, nil
+	}
+	return nil, fmt.Errorf("heif item %d has no properties", itemID)
+}
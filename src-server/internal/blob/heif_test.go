@@ -0,0 +1,116 @@
+package blob
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// heicHeader is the start of an iPhone photo: an ftyp box with the "heic"
+// major brand.
+var heicHeader = []byte("\x00\x00\x00\x18ftypheic\x00\x00\x00\x00mif1heic")
+
+func TestDetectMimeTypeHEIF(t *testing.T) {
+	tests := map[string]string{
+		"\x00\x00\x00\x18ftypheic\x00\x00\x00\x00": "image/heic",
+		"\x00\x00\x00\x1cftypavif\x00\x00\x00\x00": "image/avif",
+		"\x00\x00\x00\x18ftypmif1\x00\x00\x00\x00": "image/heif",
+	}
+	for sniff, want := range tests {
+		if got := detectMimeType([]byte(sniff)); got != want {
+			t.Fatalf("detectMimeType(%q) = %q, want %q", sniff[8:12], got, want)
+		}
+	}
+}
+
+func TestNormalizeStaticImageHEIF(t *testing.T) {
+	t.Cleanup(func() { _ = SetHEIFDecodeCommand("") })
+
+	_, err := NormalizeStaticImage(bytes.NewReader(heicHeader), 0, 0, nil)
+	if !errors.Is(err, ErrUnsupportedImageFormat) {
+		t.Fatalf("NormalizeStaticImage() without a command error = %v, want ErrUnsupportedImageFormat", err)
+	}
+
+	if err := SetHEIFDecodeCommand("heif-convert {input}"); err == nil {
+		t.Fatalf("SetHEIFDecodeCommand(no output) error = nil, want an error")
+	}
+
+	// A stand-in for heif-convert that writes a fixed PNG
+	dir := t.TempDir()
+	img := image.NewNRGBA(image.Rect(0, 0, 64, 32))
+	for i := 0; i < len(img.Pix); i += 4 {
+		copy(img.Pix[i:], []byte{0, 0, 255, 255})
+	}
+	decoded := filepath.Join(dir, "decoded.png")
+	buf := bytes.NewBuffer(nil)
+	if err := png.Encode(buf, img); err != nil {
+		t.Fatalf("png.Encode() error = %v", err)
+	}
+	if err := os.WriteFile(decoded, buf.Bytes(), 0o600); err != nil {
+		t.Fatalf("os.WriteFile() error = %v", err)
+	}
+	script := filepath.Join(dir, "fake-heif-convert")
+	if err := os.WriteFile(script, []byte("#!/bin/sh\ncp \""+decoded+"\" \"$2\"\n"), 0o700); err != nil {
+		t.Fatalf("os.WriteFile() error = %v", err)
+	}
+	if err := SetHEIFDecodeCommand(script + " {input} {output}"); err != nil {
+		t.Fatalf("SetHEIFDecodeCommand() error = %v", err)
+	}
+
+	normalized, err := NormalizeStaticImage(bytes.NewReader(heicHeader), 0, 0, nil)
+	if err != nil {
+		t.Fatalf("NormalizeStaticImage() error = %v", err)
+	}
+	if normalized.Width != 64 || normalized.Height != 32 {
+		t.Fatalf("normalized dimensions = %dx%d, want 64x32", normalized.Width, normalized.Height)
+	}
+
+	preview, err := GenerateStaticImagePreview(bytes.NewReader(heicHeader), 480, 80)
+	if err != nil {
+		t.Fatalf("GenerateStaticImagePreview() error = %v", err)
+	}
+	assertPreviewColor(t, preview, color.RGBA{B: 255, A: 255})
+}
+
+// heifTestBox encodes an ISO BMFF box around body.
+func heifTestBox(typ string, body ...[]byte) []byte {
+	content := bytes.Join(body, nil)
+	box := binary.BigEndian.AppendUint32(nil, uint32(8+len(content)))
+	return append(append(box, typ...), content...)
+}
+
+func TestDecodeConfigHEIFReadsPrimaryItemSize(t *testing.T) {
+	fullBox := []byte{0, 0, 0, 0}
+	ispe := func(width, height uint32) []byte {
+		return heifTestBox("ispe", fullBox, binary.BigEndian.AppendUint32(binary.BigEndian.AppendUint32(nil, width), height))
+	}
+	// Item 2 is a 512x512 grid tile; item 1, the primary, is rotated by 90 degrees.
+	meta := heifTestBox("meta", fullBox,
+		heifTestBox("hdlr", fullBox, make([]byte, 20)),
+		heifTestBox("pitm", fullBox, []byte{0, 1}),
+		heifTestBox("iprp",
+			heifTestBox("ipco", ispe(512, 512), ispe(4032, 3024), heifTestBox("irot", []byte{1})),
+			heifTestBox("ipma", fullBox, []byte{0, 0, 0, 2, 0, 2, 1, 1, 0, 1, 2, 0x82, 3}),
+		),
+	)
+	data := append(append(append([]byte{}, heicHeader...), meta...), heifTestBox("mdat", make([]byte, 64))...)
+
+	// No decode command is set, so this must not need one.
+	config, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("image.DecodeConfig() error = %v", err)
+	}
+	if format != "heic" || config.Width != 3024 || config.Height != 4032 {
+		t.Fatalf("image.DecodeConfig() = %s %dx%d, want heic 3024x4032", format, config.Width, config.Height)
+	}
+
+	if _, _, err := image.DecodeConfig(bytes.NewReader(data[:len(heicHeader)+40])); err == nil {
+		t.Fatal("image.DecodeConfig(truncated) error = nil, want an error")
+	}
+}
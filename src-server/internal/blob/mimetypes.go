@@ -0,0 +1,86 @@
+package blob
+
+import (
+	"fmt"
+	"strings"
+)
+
+// MimeRules narrows the MIME types accepted for one blob kind, on top of the
+// built-in rules: script, HTML and SVG types are never accepted, and avatars
+// and server images must be images. A pattern is a full type such as
+// "application/zip" or a whole top-level type such as "image/*".
+type MimeRules struct {
+	Allow []string // when set, only matching types are accepted
+	Deny  []string // matching types are rejected, even when allowed
+}
+
+// SetMimeRules sets the MIME rules per blob kind. Kinds without rules keep
+// the built-in ones. Must be called before the service is used.
+func (s *Service) SetMimeRules(rules map[Kind]MimeRules) error {
+	normalized := make(map[Kind]MimeRules, len(rules))
+	for kind, rule := range rules {
+		if !isValidKind(kind) {
+			return fmt.Errorf("%w: %q", ErrInvalidKind, kind)
+		}
+		allow, err := normalizeMimePatterns(rule.Allow)
+		if err != nil {
+			return err
+		}
+		deny, err := normalizeMimePatterns(rule.Deny)
+		if err != nil {
+			return err
+		}
+		if len(allow) == 0 && len(deny) == 0 {
+			continue
+		}
+		normalized[kind] = MimeRules{Allow: allow, Deny: deny}
+	}
+	s.mimeRules = normalized
+	return nil
+}
+
+func normalizeMimePatterns(patterns []string) ([]string, error) {
+	var out []string
+	for _, pattern := range patterns {
+		pattern = strings.ToLower(strings.TrimSpace(pattern))
+		if pattern == "" {
+			continue
+		}
+		major, minor, ok := strings.Cut(pattern, "/")
+		if !ok || major == "" || major == "*" || minor == "" || strings.Contains(minor, "/") ||
+			(strings.Contains(minor, "*") && minor != "*") {
+			return nil, fmt.Errorf("invalid MIME type pattern %q: want type/subtype or type/*", pattern)
+		}
+		out = append(out, pattern)
+	}
+	return out, nil
+}
+
+// allowsMimeType applies the built-in rules, then the configured ones.
+func (s *Service) allowsMimeType(kind Kind, mimeType string) bool {
+	if !isAllowedMimeType(kind, mimeType) {
+		return false
+	}
+	rules, ok := s.mimeRules[kind]
+	if !ok {
+		return true
+	}
+	mimeType = strings.ToLower(strings.TrimSpace(mimeType))
+	if matchesMimePattern(rules.Deny, mimeType) {
+		return false
+	}
+	return len(rules.Allow) == 0 || matchesMimePattern(rules.Allow, mimeType)
+}
+
+func matchesMimePattern(patterns []string, mimeType string) bool {
+	for _, pattern := range patterns {
+		if prefix, ok := strings.CutSuffix(pattern, "/*"); ok {
+			if strings.HasPrefix(mimeType, prefix+"/") {
+				return true
+			}
+		} else if pattern == mimeType {
+			return true
+		}
+	}
+	return false
+}
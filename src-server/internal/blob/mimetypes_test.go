@@ -0,0 +1,57 @@
+package blob
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"testing"
+)
+
+func TestSaveAppliesMimeRules(t *testing.T) {
+	svc, err := NewService(t.TempDir(), 1024*1024)
+	if err != nil {
+		t.Fatalf("NewService() error = %v", err)
+	}
+	if err := svc.SetMimeRules(map[Kind]MimeRules{
+		KindChatAttachment: {Allow: []string{"image/*", "text/plain"}, Deny: []string{"image/gif"}},
+	}); err != nil {
+		t.Fatalf("SetMimeRules() error = %v", err)
+	}
+
+	if _, err := svc.Save(context.Background(), KindChatAttachment, "notes.txt", bytes.NewReader([]byte("hello"))); err != nil {
+		t.Fatalf("Save(text) error = %v", err)
+	}
+	if _, err := svc.Save(context.Background(), KindChatAttachment, "image.png", bytes.NewReader(encodePNG(t, image.NewRGBA(image.Rect(0, 0, 2, 2))))); err != nil {
+		t.Fatalf("Save(png) error = %v", err)
+	}
+	if _, err := svc.Save(context.Background(), KindChatAttachment, "anim.gif", bytes.NewReader([]byte("GIF89a\x01\x00\x01\x00"))); !errors.Is(err, ErrDisallowedType) {
+		t.Fatalf("Save(gif) error = %v, want ErrDisallowedType", err)
+	}
+	if _, err := svc.Save(context.Background(), KindChatAttachment, "blob.bin", bytes.NewReader([]byte{0x00, 0x01, 0x02})); !errors.Is(err, ErrDisallowedType) {
+		t.Fatalf("Save(binary) error = %v, want ErrDisallowedType", err)
+	}
+	if err := svc.Precheck(KindChatAttachment, 10, "application/zip"); !errors.Is(err, ErrDisallowedType) {
+		t.Fatalf("Precheck(zip) error = %v, want ErrDisallowedType", err)
+	}
+	// Kinds without rules keep the built-in ones.
+	if err := svc.Precheck(KindAvatar, 10, "image/gif"); err != nil {
+		t.Fatalf("Precheck(avatar gif) error = %v", err)
+	}
+}
+
+func TestSetMimeRulesRejectsBadPatterns(t *testing.T) {
+	svc, err := NewService(t.TempDir(), 1024*1024)
+	if err != nil {
+		t.Fatalf("NewService() error = %v", err)
+	}
+
+	for _, pattern := range []string{"zip", "*/*", "image/png/x", "image/p*"} {
+		if err := svc.SetMimeRules(map[Kind]MimeRules{KindChatAttachment: {Deny: []string{pattern}}}); err == nil {
+			t.Fatalf("SetMimeRules(%q) error = nil, want an error", pattern)
+		}
+	}
+	if err := svc.SetMimeRules(map[Kind]MimeRules{"sticker": {Deny: []string{"image/gif"}}}); !errors.Is(err, ErrInvalidKind) {
+		t.Fatalf("SetMimeRules(unknown kind) error = %v, want ErrInvalidKind", err)
+	}
+}
@@ -14,7 +14,7 @@ func TestNormalizeStaticImageConvertsOpaqueImagesToJPEGAndResizes(t *testing.T)
 	src := image.NewRGBA(image.Rect(0, 0, 640, 320))
 	draw.Draw(src, src.Bounds(), &image.Uniform{C: color.RGBA{R: 40, G: 90, B: 220, A: 255}}, image.Point{}, draw.Src)
 
-	normalized, err := NormalizeStaticImage(bytes.NewReader(encodePNG(t, src)), 256, 82)
+	normalized, err := NormalizeStaticImage(bytes.NewReader(encodePNG(t, src)), 256, 82, nil)
 	if err != nil {
 		t.Fatalf("NormalizeStaticImage() error = %v", err)
 	}
@@ -41,7 +41,7 @@ func TestNormalizeStaticImagePreservesAlphaAsPNG(t *testing.T) {
 	src := image.NewNRGBA(image.Rect(0, 0, 400, 400))
 	draw.Draw(src, src.Bounds(), &image.Uniform{C: color.NRGBA{R: 255, G: 60, B: 60, A: 128}}, image.Point{}, draw.Src)
 
-	normalized, err := NormalizeStaticImage(bytes.NewReader(encodePNG(t, src)), 256, 82)
+	normalized, err := NormalizeStaticImage(bytes.NewReader(encodePNG(t, src)), 256, 82, nil)
 	if err != nil {
 		t.Fatalf("NormalizeStaticImage() error = %v", err)
 	}
@@ -69,7 +69,7 @@ func TestNormalizeStaticImageDoesNotUpscaleSmallImages(t *testing.T) {
 	src := image.NewRGBA(image.Rect(0, 0, 48, 32))
 	draw.Draw(src, src.Bounds(), &image.Uniform{C: color.RGBA{R: 20, G: 140, B: 80, A: 255}}, image.Point{}, draw.Src)
 
-	normalized, err := NormalizeStaticImage(bytes.NewReader(encodePNG(t, src)), 256, 82)
+	normalized, err := NormalizeStaticImage(bytes.NewReader(encodePNG(t, src)), 256, 82, nil)
 	if err != nil {
 		t.Fatalf("NormalizeStaticImage() error = %v", err)
 	}
@@ -79,12 +79,89 @@ func TestNormalizeStaticImageDoesNotUpscaleSmallImages(t *testing.T) {
 }
 
 func TestNormalizeStaticImageRejectsInvalidImageData(t *testing.T) {
-	_, err := NormalizeStaticImage(bytes.NewReader([]byte("not-an-image")), 256, 82)
+	_, err := NormalizeStaticImage(bytes.NewReader([]byte("not-an-image")), 256, 82, nil)
 	if !errors.Is(err, ErrInvalidImage) {
 		t.Fatalf("NormalizeStaticImage() error = %v, want ErrInvalidImage", err)
 	}
 }
 
+func TestNormalizeStaticImageKeepsFirstFrameOfAnimation(t *testing.T) {
+	normalized, err := NormalizeStaticImage(bytes.NewReader(animatedTestWebP()), 256, 82, nil)
+	if err != nil {
+		t.Fatalf("NormalizeStaticImage() error = %v", err)
+	}
+	if normalized.Animation == nil || normalized.Animation.Frames != 2 {
+		t.Fatalf("normalized.Animation = %+v, want 2 frames", normalized.Animation)
+	}
+	if normalized.Width != 16 || normalized.Height != 8 {
+		t.Fatalf("normalized dimensions = %dx%d, want 16x8", normalized.Width, normalized.Height)
+	}
+	assertPreviewColor(t, normalized, color.RGBA{R: 255, A: 255})
+
+	still, err := NormalizeStaticImage(bytes.NewReader(encodePNG(t, image.NewRGBA(image.Rect(0, 0, 4, 4)))), 256, 82, nil)
+	if err != nil {
+		t.Fatalf("NormalizeStaticImage() still error = %v", err)
+	}
+	if still.Animation != nil {
+		t.Fatalf("still.Animation = %+v, want nil", still.Animation)
+	}
+}
+
+func TestNormalizeStaticImageAppliesCropRectangle(t *testing.T) {
+	src := image.NewRGBA(image.Rect(0, 0, 200, 100))
+	draw.Draw(src, src.Bounds(), &image.Uniform{C: color.RGBA{R: 200, A: 255}}, image.Point{}, draw.Src)
+	draw.Draw(src, image.Rect(100, 0, 200, 100), &image.Uniform{C: color.RGBA{B: 200, A: 255}}, image.Point{}, draw.Src)
+
+	normalized, err := NormalizeStaticImage(bytes.NewReader(encodePNG(t, src)), 256, 82, &Crop{Rect: image.Rect(120, 10, 180, 70)})
+	if err != nil {
+		t.Fatalf("NormalizeStaticImage() error = %v", err)
+	}
+	if normalized.Width != 60 || normalized.Height != 60 {
+		t.Fatalf("normalized dimensions = %dx%d, want 60x60", normalized.Width, normalized.Height)
+	}
+
+	decoded, _, err := image.Decode(bytes.NewReader(normalized.Data))
+	if err != nil {
+		t.Fatalf("image.Decode() error = %v", err)
+	}
+	r, _, b, _ := decoded.At(30, 30).RGBA()
+	if b <= r {
+		t.Fatalf("decoded pixel r=%d b=%d, want cropped blue half", r, b)
+	}
+}
+
+func TestNormalizeStaticImageCropsSquareAroundFocalPoint(t *testing.T) {
+	src := image.NewRGBA(image.Rect(0, 0, 300, 100))
+	draw.Draw(src, src.Bounds(), &image.Uniform{C: color.RGBA{R: 200, A: 255}}, image.Point{}, draw.Src)
+	draw.Draw(src, image.Rect(200, 0, 300, 100), &image.Uniform{C: color.RGBA{G: 200, A: 255}}, image.Point{}, draw.Src)
+
+	normalized, err := NormalizeStaticImage(bytes.NewReader(encodePNG(t, src)), 256, 82, &Crop{Focus: &FocalPoint{X: 1, Y: 0.5}})
+	if err != nil {
+		t.Fatalf("NormalizeStaticImage() error = %v", err)
+	}
+	if normalized.Width != 100 || normalized.Height != 100 {
+		t.Fatalf("normalized dimensions = %dx%d, want 100x100", normalized.Width, normalized.Height)
+	}
+
+	decoded, _, err := image.Decode(bytes.NewReader(normalized.Data))
+	if err != nil {
+		t.Fatalf("image.Decode() error = %v", err)
+	}
+	r, g, _, _ := decoded.At(10, 50).RGBA()
+	if g <= r {
+		t.Fatalf("decoded pixel r=%d g=%d, want right edge of image", r, g)
+	}
+}
+
+func TestNormalizeStaticImageRejectsCropOutsideImage(t *testing.T) {
+	src := image.NewRGBA(image.Rect(0, 0, 64, 64))
+
+	_, err := NormalizeStaticImage(bytes.NewReader(encodePNG(t, src)), 256, 82, &Crop{Rect: image.Rect(32, 32, 96, 96)})
+	if !errors.Is(err, ErrInvalidCrop) {
+		t.Fatalf("NormalizeStaticImage() error = %v, want ErrInvalidCrop", err)
+	}
+}
+
 func encodePNG(t *testing.T, img image.Image) []byte {
 	t.Helper()
 
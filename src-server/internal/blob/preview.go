@@ -2,6 +2,7 @@ package blob
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"image"
 	_ "image/gif"
@@ -10,6 +11,7 @@ import (
 	"io"
 
 	xdraw "golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
 )
 
 const (
@@ -24,6 +26,9 @@ type Preview struct {
 	MimeType string
 	Width    int
 	Height   int
+	// Animation is set when the source is an animated GIF or WebP; the
+	// preview is then its first frame.
+	Animation *Animation
 }
 
 func GenerateStaticImagePreview(src io.Reader, maxEdge int, quality int) (*Preview, error) {
@@ -34,9 +39,15 @@ func GenerateStaticImagePreview(src io.Reader, maxEdge int, quality int) (*Previ
 		quality = DefaultPreviewQuality
 	}
 
-	img, _, err := image.Decode(src)
+	data, err := io.ReadAll(src)
 	if err != nil {
-		return nil, fmt.Errorf("decoding image: %w", err)
+		return nil, fmt.Errorf("reading image: %w", err)
+	}
+
+	animation := detectAnimation(data)
+	img, err := decodeFirstFrame(data)
+	if err != nil {
+		return nil, err
 	}
 
 	bounds := img.Bounds()
@@ -54,14 +65,47 @@ func GenerateStaticImagePreview(src io.Reader, maxEdge int, quality int) (*Previ
 	}
 
 	return &Preview{
-		Data:     buf.Bytes(),
-		MimeType: "image/jpeg",
-		Width:    width,
-		Height:   height,
+		Data:      buf.Bytes(),
+		MimeType:  "image/jpeg",
+		Width:     width,
+		Height:    height,
+		Animation: animation,
 	}, nil
 }
 
-func NormalizeStaticImage(src io.Reader, maxEdge int, quality int) (*Preview, error) {
+// decodeFirstFrame decodes a still image, or the first frame of an animated
+// one.
+func decodeFirstFrame(data []byte) (image.Image, error) {
+	if isWebP(data) {
+		if animation, frame := webpAnimation(data); animation != nil {
+			if frame == nil {
+				return nil, fmt.Errorf("decoding image: animated webp has no frames")
+			}
+			return frame.decode()
+		}
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("decoding image: %w", err)
+	}
+	return img, nil
+}
+
+// Crop selects the part of a profile image to keep. Rect is in source pixels
+// relative to the image origin and wins over Focus, which centers the largest
+// square that fits on a point given as fractions of width and height.
+type Crop struct {
+	Rect  image.Rectangle
+	Focus *FocalPoint
+}
+
+type FocalPoint struct {
+	X float64
+	Y float64
+}
+
+func NormalizeStaticImage(src io.Reader, maxEdge int, quality int, crop *Crop) (*Preview, error) {
 	if maxEdge <= 0 {
 		maxEdge = DefaultProfileImageMaxEdge
 	}
@@ -69,9 +113,17 @@ func NormalizeStaticImage(src io.Reader, maxEdge int, quality int) (*Preview, er
 		quality = DefaultProfileJPEGQuality
 	}
 
-	img, _, err := image.Decode(src)
+	data, err := io.ReadAll(src)
 	if err != nil {
-		return nil, fmt.Errorf("%w: decoding image: %v", ErrInvalidImage, err)
+		return nil, fmt.Errorf("reading image: %w", err)
+	}
+
+	img, err := decodeFirstFrame(data)
+	if errors.Is(err, ErrUnsupportedImageFormat) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidImage, err)
 	}
 
 	bounds := img.Bounds()
@@ -79,6 +131,13 @@ func NormalizeStaticImage(src io.Reader, maxEdge int, quality int) (*Preview, er
 		return nil, fmt.Errorf("%w: invalid image dimensions", ErrInvalidImage)
 	}
 
+	if crop != nil {
+		bounds, err = cropBounds(bounds, crop)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	width, height := scaleDimensions(bounds.Dx(), bounds.Dy(), maxEdge)
 	normalizedImg := image.NewNRGBA(image.Rect(0, 0, width, height))
 	xdraw.CatmullRom.Scale(normalizedImg, normalizedImg.Bounds(), img, bounds, xdraw.Over, nil)
@@ -98,13 +157,43 @@ func NormalizeStaticImage(src io.Reader, maxEdge int, quality int) (*Preview, er
 	}
 
 	return &Preview{
-		Data:     buf.Bytes(),
-		MimeType: mimeType,
-		Width:    width,
-		Height:   height,
+		Data:      buf.Bytes(),
+		MimeType:  mimeType,
+		Width:     width,
+		Height:    height,
+		Animation: detectAnimation(data),
 	}, nil
 }
 
+func cropBounds(bounds image.Rectangle, crop *Crop) (image.Rectangle, error) {
+	if !crop.Rect.Empty() {
+		rect := crop.Rect.Add(bounds.Min)
+		if !rect.In(bounds) {
+			return image.Rectangle{}, fmt.Errorf("%w: rectangle outside image", ErrInvalidCrop)
+		}
+		return rect, nil
+	}
+
+	if crop.Focus == nil {
+		return bounds, nil
+	}
+	if crop.Focus.X < 0 || crop.Focus.X > 1 || crop.Focus.Y < 0 || crop.Focus.Y > 1 {
+		return image.Rectangle{}, fmt.Errorf("%w: focal point outside image", ErrInvalidCrop)
+	}
+
+	side := min(bounds.Dx(), bounds.Dy())
+	x := clampCropOrigin(bounds.Min.X, bounds.Dx(), side, crop.Focus.X)
+	y := clampCropOrigin(bounds.Min.Y, bounds.Dy(), side, crop.Focus.Y)
+	return image.Rect(x, y, x+side, y+side), nil
+}
+
+// clampCropOrigin centers a span of length side on the focal fraction of
+// [start, start+length) without leaving it.
+func clampCropOrigin(start, length, side int, focus float64) int {
+	origin := start + int(float64(length)*focus+0.5) - side/2
+	return max(start, min(origin, start+length-side))
+}
+
 func scaleDimensions(width, height, maxEdge int) (int, int) {
 	if width <= maxEdge && height <= maxEdge {
 		return width, height
@@ -0,0 +1,219 @@
+package blob
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"lobby/internal/clock"
+	sqldb "lobby/internal/db/sqlc"
+)
+
+const (
+	DefaultReconcileInterval = 24 * time.Hour
+	// DefaultReconcileGrace spares files younger than this: an upload writes
+	// its file before its row.
+	DefaultReconcileGrace = 1 * time.Hour
+	reconcileBatch        = 500
+)
+
+// ReconcileReport totals one reconciliation pass.
+type ReconcileReport struct {
+	Blobs         int
+	Files         int
+	OrphanedFiles int
+	OrphanedBytes int64
+	// MissingFiles counts blob files and previews that have rows but are not
+	// on disk.
+	MissingFiles int
+	// NewlyMissing and Recovered count blobs whose missing_since was set or
+	// cleared by this pass.
+	NewlyMissing int
+	Recovered    int
+}
+
+// Reconciler compares blob rows against the files under the blob root. It
+// deletes files no row refers to and flags rows whose files are gone, which
+// the expiry-only cleanup never notices.
+type Reconciler struct {
+	queries *sqldb.Queries
+	blobs   *Service
+	grace   time.Duration
+	clock   clock.Clock
+}
+
+func NewReconciler(queries *sqldb.Queries, blobs *Service) *Reconciler {
+	return &Reconciler{
+		queries: queries,
+		blobs:   blobs,
+		grace:   DefaultReconcileGrace,
+		clock:   clock.Real,
+	}
+}
+
+// SetClock replaces the clock that decides which files are old enough to
+// delete and when a file went missing.
+func (r *Reconciler) SetClock(c clock.Clock) {
+	r.clock = c
+}
+
+// Reconcile runs one pass. A dry run only reports what it would do.
+func (r *Reconciler) Reconcile(ctx context.Context, dryRun bool) (*ReconcileReport, error) {
+	now := r.clock.Now().UTC()
+	report := &ReconcileReport{}
+
+	// Rows are read before the walk, so a file written after this point is
+	// younger than the grace period and kept.
+	referenced := make(map[string]struct{})
+	if err := r.checkBlobRows(ctx, now, dryRun, referenced, report); err != nil {
+		return nil, err
+	}
+	sessionPaths, err := r.queries.ListUploadSessionPaths(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("listing upload sessions: %w", err)
+	}
+	for _, path := range sessionPaths {
+		referenced[path] = struct{}{}
+	}
+	recordingIDs, err := r.queries.ListRecordingIDs(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("listing recordings: %w", err)
+	}
+	recordings := make(map[string]struct{}, len(recordingIDs))
+	for _, id := range recordingIDs {
+		recordings[RecordingDir(id)] = struct{}{}
+	}
+
+	cutoff := now.Add(-r.grace)
+	err = filepath.WalkDir(r.blobs.rootDir, func(absPath string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+		relPath, err := filepath.Rel(r.blobs.rootDir, absPath)
+		if err != nil {
+			return err
+		}
+		storagePath := filepath.ToSlash(relPath)
+		report.Files++
+		if isReferencedFile(storagePath, referenced, recordings) {
+			return nil
+		}
+
+		info, err := entry.Info()
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if info.ModTime().After(cutoff) {
+			return nil
+		}
+		report.OrphanedFiles++
+		report.OrphanedBytes += info.Size()
+		if dryRun {
+			return nil
+		}
+		if err := r.blobs.Delete(storagePath); err != nil {
+			slog.Warn("error deleting orphaned blob file", "component", "blob_reconcile", "error", err, "path", storagePath)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("walking blob root: %w", err)
+	}
+	return report, nil
+}
+
+// checkBlobRows adds the files of every blob row to referenced and sets or
+// clears missing_since by whether they are on disk.
+func (r *Reconciler) checkBlobRows(ctx context.Context, now time.Time, dryRun bool, referenced map[string]struct{}, report *ReconcileReport) error {
+	afterID := ""
+	for {
+		rows, err := r.queries.ListBlobFiles(ctx, sqldb.ListBlobFilesParams{
+			AfterID:   afterID,
+			LimitRows: reconcileBatch,
+		})
+		if err != nil {
+			return fmt.Errorf("listing blobs: %w", err)
+		}
+
+		for _, row := range rows {
+			report.Blobs++
+			paths := []string{row.StoragePath}
+			if row.PreviewStoragePath != nil {
+				paths = append(paths, *row.PreviewStoragePath)
+			}
+			missing := false
+			for _, path := range paths {
+				referenced[path] = struct{}{}
+				if !r.fileExists(path) {
+					report.MissingFiles++
+					missing = true
+				}
+			}
+
+			var missingSince *time.Time
+			switch {
+			case missing && row.MissingSince == nil:
+				report.NewlyMissing++
+				missingSince = &now
+				slog.Warn("blob file is missing", "component", "blob_reconcile", "blob_id", row.ID, "path", row.StoragePath)
+			case !missing && row.MissingSince != nil:
+				report.Recovered++
+			default:
+				continue
+			}
+			if dryRun {
+				continue
+			}
+			if err := r.queries.SetBlobMissingSince(ctx, sqldb.SetBlobMissingSinceParams{
+				MissingSince: missingSince,
+				ID:           row.ID,
+			}); err != nil {
+				slog.Error("error flagging missing blob", "component", "blob_reconcile", "error", err, "blob_id", row.ID)
+			}
+		}
+
+		if len(rows) < reconcileBatch {
+			return nil
+		}
+		afterID = rows[len(rows)-1].ID
+	}
+}
+
+func (r *Reconciler) fileExists(storagePath string) bool {
+	absPath, err := r.blobs.resolveStoragePath(storagePath)
+	if err != nil {
+		return false
+	}
+	_, err = os.Stat(absPath)
+	return err == nil
+}
+
+// isReferencedFile reports whether a row refers to the file at storagePath.
+// Recording tracks are written before their rows, so everything under the
+// directory of a known recording counts.
+func isReferencedFile(storagePath string, referenced, recordings map[string]struct{}) bool {
+	if _, ok := referenced[storagePath]; ok {
+		return true
+	}
+	dir, _, ok := strings.Cut(strings.TrimPrefix(storagePath, "recording/"), "/")
+	if !ok || !strings.HasPrefix(storagePath, "recording/") {
+		return false
+	}
+	_, ok = recordings[RecordingDir(dir)]
+	return ok
+}
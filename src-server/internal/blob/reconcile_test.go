@@ -0,0 +1,124 @@
+package blob
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"lobby/internal/db"
+	sqldb "lobby/internal/db/sqlc"
+)
+
+func TestReconcile(t *testing.T) {
+	ctx := context.Background()
+	database, err := db.Open(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("db.Open() error = %v", err)
+	}
+	t.Cleanup(func() { _ = database.Close() })
+	queries := database.Queries()
+	if err := queries.CreateUser(ctx, sqldb.CreateUserParams{
+		ID:        "usr_1",
+		Username:  "alice",
+		Email:     "alice@example.com",
+		CreatedAt: time.Now().UTC(),
+	}); err != nil {
+		t.Fatalf("CreateUser() error = %v", err)
+	}
+	root := t.TempDir()
+	blobs, err := NewService(root, 1<<20)
+	if err != nil {
+		t.Fatalf("NewService() error = %v", err)
+	}
+
+	store := func() *StoredBlob {
+		stored, err := blobs.Save(ctx, KindChatAttachment, "notes.txt", strings.NewReader("hello"))
+		if err != nil {
+			t.Fatalf("Save() error = %v", err)
+		}
+		if err := queries.CreateBlob(ctx, sqldb.CreateBlobParams{
+			ID:           stored.ID,
+			Kind:         string(stored.Kind),
+			UploadedBy:   "usr_1",
+			StoragePath:  stored.StoragePath,
+			MimeType:     stored.MimeType,
+			SizeBytes:    stored.SizeBytes,
+			OriginalName: stored.OriginalName,
+			CreatedAt:    stored.CreatedAt,
+		}); err != nil {
+			t.Fatalf("CreateBlob() error = %v", err)
+		}
+		return stored
+	}
+	kept := store()
+	lost := store()
+	if err := os.Remove(filepath.Join(root, lost.StoragePath)); err != nil {
+		t.Fatalf("os.Remove() error = %v", err)
+	}
+
+	writeFile := func(storagePath string, age time.Duration) string {
+		path := filepath.Join(root, filepath.FromSlash(storagePath))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("os.MkdirAll() error = %v", err)
+		}
+		if err := os.WriteFile(path, []byte("orphan"), 0o600); err != nil {
+			t.Fatalf("os.WriteFile() error = %v", err)
+		}
+		modTime := time.Now().Add(-age)
+		if err := os.Chtimes(path, modTime, modTime); err != nil {
+			t.Fatalf("os.Chtimes() error = %v", err)
+		}
+		return path
+	}
+	orphan := writeFile("chat_attachment/zz/blb_orphan", 2*time.Hour)
+	// Possibly an upload whose row isn't written yet
+	young := writeFile("chat_attachment/zz/blb_young", time.Minute)
+
+	reconciler := NewReconciler(queries, blobs)
+	report, err := reconciler.Reconcile(ctx, true)
+	if err != nil {
+		t.Fatalf("Reconcile(dry run) error = %v", err)
+	}
+	want := ReconcileReport{Blobs: 2, Files: 3, OrphanedFiles: 1, OrphanedBytes: 6, MissingFiles: 1, NewlyMissing: 1}
+	if *report != want {
+		t.Fatalf("Reconcile(dry run) = %+v, want %+v", *report, want)
+	}
+	if _, err := os.Stat(orphan); err != nil {
+		t.Fatalf("dry run removed the orphan: %v", err)
+	}
+
+	if _, err := reconciler.Reconcile(ctx, false); err != nil {
+		t.Fatalf("Reconcile() error = %v", err)
+	}
+	if _, err := os.Stat(orphan); !os.IsNotExist(err) {
+		t.Fatalf("orphan stat error = %v, want not exist", err)
+	}
+	if _, err := os.Stat(young); err != nil {
+		t.Fatalf("young file removed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(root, kept.StoragePath)); err != nil {
+		t.Fatalf("referenced file removed: %v", err)
+	}
+	var missing int
+	if err := database.QueryRow(`SELECT COUNT(*) FROM blobs WHERE missing_since IS NOT NULL AND id = ?`, lost.ID).Scan(&missing); err != nil || missing != 1 {
+		t.Fatalf("lost blob flagged = %d, %v; want 1", missing, err)
+	}
+
+	// The file coming back clears the flag
+	writeFile(lost.StoragePath, 0)
+	report, err = reconciler.Reconcile(ctx, false)
+	if err != nil {
+		t.Fatalf("Reconcile() again error = %v", err)
+	}
+	if report.Recovered != 1 || report.MissingFiles != 0 {
+		t.Fatalf("Reconcile() again = %+v, want 1 recovered and none missing", *report)
+	}
+
+	recordings := map[string]struct{}{RecordingDir("rec_1"): {}}
+	if !isReferencedFile("recording/rec_1/usr_1.ogg", nil, recordings) || isReferencedFile("recording/rec_2/usr_1.ogg", nil, recordings) {
+		t.Fatalf("isReferencedFile() does not follow recording rows")
+	}
+}
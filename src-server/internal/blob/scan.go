@@ -0,0 +1,154 @@
+package blob
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Scan statuses recorded on blobs. Blobs stored while scanning is off have
+// none.
+const (
+	ScanStatusClean    = "clean"
+	ScanStatusInfected = "infected"
+	// ScanStatusUnscanned marks a file accepted while the scanner was
+	// failing, with scanning set to fail open.
+	ScanStatusUnscanned = "unscanned"
+)
+
+// ScanResult is a scanner's verdict on one file.
+type ScanResult struct {
+	Infected bool
+	// Signature names what was found, when Infected.
+	Signature string
+}
+
+// Scanner checks uploaded files for malware. ClamdScanner talks to clamd;
+// an ICAP client or anything else fits behind the same interface.
+type Scanner interface {
+	Scan(ctx context.Context, src io.Reader) (ScanResult, error)
+}
+
+// clamdChunkSize is the most sent in one INSTREAM chunk; clamd's default
+// StreamMaxLength caps the whole stream, not chunks.
+const clamdChunkSize = 64 << 10
+
+// ClamdScanner scans files with clamd's INSTREAM command.
+type ClamdScanner struct {
+	network string
+	address string
+	timeout time.Duration
+}
+
+// NewClamdScanner creates a scanner for the clamd at address, either
+// "unix:///path/to/clamd.sock" or "tcp://host:port" (the scheme may be
+// left out for TCP). timeout bounds each scan.
+func NewClamdScanner(address string, timeout time.Duration) (*ClamdScanner, error) {
+	network, addr := "tcp", strings.TrimSpace(address)
+	switch {
+	case strings.HasPrefix(addr, "unix://"):
+		network, addr = "unix", strings.TrimPrefix(addr, "unix://")
+	case strings.HasPrefix(addr, "tcp://"):
+		addr = strings.TrimPrefix(addr, "tcp://")
+	}
+	if addr == "" {
+		return nil, fmt.Errorf("clamd address is required")
+	}
+	return &ClamdScanner{network: network, address: addr, timeout: timeout}, nil
+}
+
+func (s *ClamdScanner) Scan(ctx context.Context, src io.Reader) (ScanResult, error) {
+	if s.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.timeout)
+		defer cancel()
+	}
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, s.network, s.address)
+	if err != nil {
+		return ScanResult{}, fmt.Errorf("connecting to clamd: %w", err)
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	if _, err := io.WriteString(conn, "zINSTREAM\x00"); err != nil {
+		return ScanResult{}, fmt.Errorf("starting clamd stream: %w", err)
+	}
+	buf := make([]byte, 4+clamdChunkSize)
+	for {
+		n, readErr := io.ReadFull(src, buf[4:])
+		if n > 0 {
+			binary.BigEndian.PutUint32(buf[:4], uint32(n))
+			if _, err := conn.Write(buf[:4+n]); err != nil {
+				return ScanResult{}, fmt.Errorf("streaming to clamd: %w", err)
+			}
+		}
+		if errors.Is(readErr, io.EOF) || errors.Is(readErr, io.ErrUnexpectedEOF) {
+			break
+		}
+		if readErr != nil {
+			return ScanResult{}, fmt.Errorf("reading file to scan: %w", readErr)
+		}
+	}
+	if _, err := conn.Write([]byte{0, 0, 0, 0}); err != nil {
+		return ScanResult{}, fmt.Errorf("ending clamd stream: %w", err)
+	}
+
+	reply, err := bufio.NewReader(conn).ReadString(0)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return ScanResult{}, fmt.Errorf("reading clamd reply: %w", err)
+	}
+	return parseClamdReply(reply)
+}
+
+// parseClamdReply reads "stream: OK" or "stream: <signature> FOUND".
+func parseClamdReply(reply string) (ScanResult, error) {
+	reply = strings.TrimSpace(strings.TrimRight(reply, "\x00"))
+	verdict := strings.TrimPrefix(reply, "stream: ")
+	switch {
+	case verdict == "OK":
+		return ScanResult{}, nil
+	case strings.HasSuffix(verdict, " FOUND"):
+		return ScanResult{Infected: true, Signature: strings.TrimSuffix(verdict, " FOUND")}, nil
+	default:
+		return ScanResult{}, fmt.Errorf("clamd: %s", reply)
+	}
+}
+
+// QuarantineRelativePath is where an infected chat attachment is kept for
+// review until it expires.
+func QuarantineRelativePath(blobID string) string {
+	return filepath.ToSlash(filepath.Join("quarantine", blobID))
+}
+
+// Quarantine moves the file at storagePath to the quarantine path of blobID
+// and returns that path.
+func (s *Service) Quarantine(storagePath, blobID string) (string, error) {
+	from, err := s.resolveStoragePath(storagePath)
+	if err != nil {
+		return "", err
+	}
+	relPath := QuarantineRelativePath(blobID)
+	to, err := s.resolveStoragePath(relPath)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(filepath.Dir(to), 0o700); err != nil {
+		return "", fmt.Errorf("creating quarantine directory: %w", err)
+	}
+	if err := os.Rename(from, to); err != nil {
+		return "", fmt.Errorf("quarantining blob file: %w", err)
+	}
+	return relPath, nil
+}
@@ -0,0 +1,92 @@
+package blob
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// serveFakeClamd answers INSTREAM commands like clamd, reporting files that
+// contain "EICAR" as infected.
+func serveFakeClamd(t *testing.T) string {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("net.Listen() error = %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				reader := bufio.NewReader(conn)
+				command, err := reader.ReadString(0)
+				if err != nil || command != "zINSTREAM\x00" {
+					_, _ = io.WriteString(conn, "UNKNOWN COMMAND\x00")
+					return
+				}
+				var data bytes.Buffer
+				for {
+					var size uint32
+					if err := binary.Read(reader, binary.BigEndian, &size); err != nil {
+						return
+					}
+					if size == 0 {
+						break
+					}
+					if _, err := io.CopyN(&data, reader, int64(size)); err != nil {
+						return
+					}
+				}
+				if bytes.Contains(data.Bytes(), []byte("EICAR")) {
+					_, _ = io.WriteString(conn, "stream: Eicar-Test-Signature FOUND\x00")
+					return
+				}
+				_, _ = io.WriteString(conn, "stream: OK\x00")
+			}()
+		}
+	}()
+	return "tcp://" + listener.Addr().String()
+}
+
+func TestClamdScanner(t *testing.T) {
+	scanner, err := NewClamdScanner(serveFakeClamd(t), 5*time.Second)
+	if err != nil {
+		t.Fatalf("NewClamdScanner() error = %v", err)
+	}
+
+	// Bigger than one chunk, so the stream is split
+	clean := strings.Repeat("harmless bytes ", clamdChunkSize/8)
+	result, err := scanner.Scan(context.Background(), strings.NewReader(clean))
+	if err != nil || result.Infected {
+		t.Fatalf("Scan(clean) = %+v, %v; want clean", result, err)
+	}
+
+	result, err = scanner.Scan(context.Background(), strings.NewReader(clean+"EICAR"))
+	if err != nil {
+		t.Fatalf("Scan(infected) error = %v", err)
+	}
+	if !result.Infected || result.Signature != "Eicar-Test-Signature" {
+		t.Fatalf("Scan(infected) = %+v, want Eicar-Test-Signature", result)
+	}
+}
+
+func TestParseClamdReply(t *testing.T) {
+	if _, err := parseClamdReply("INSTREAM size limit exceeded. ERROR\x00"); err == nil {
+		t.Fatalf("parseClamdReply(error reply) error = nil, want an error")
+	}
+	if _, err := NewClamdScanner("unix://", time.Second); err == nil {
+		t.Fatalf("NewClamdScanner(empty socket) error = nil, want an error")
+	}
+}
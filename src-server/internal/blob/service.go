@@ -30,6 +30,10 @@ var (
 	ErrExecutableFile = errors.New("executable files are not allowed")
 	ErrInvalidPath    = errors.New("invalid blob path")
 	ErrInvalidImage   = errors.New("invalid image data")
+	ErrInvalidCrop    = errors.New("invalid image crop")
+	// ErrUnsupportedImageFormat is returned for HEIC and AVIF images while no
+	// HEIF decode command is set.
+	ErrUnsupportedImageFormat = errors.New("unsupported image format")
 )
 
 type StoredBlob struct {
@@ -45,6 +49,8 @@ type StoredBlob struct {
 type Service struct {
 	rootDir        string
 	maxUploadBytes int64
+	newID          db.IDGenerator
+	mimeRules      map[Kind]MimeRules
 }
 
 func NewService(rootDir string, maxUploadBytes int64) (*Service, error) {
@@ -62,20 +68,50 @@ func NewService(rootDir string, maxUploadBytes int64) (*Service, error) {
 	return &Service{
 		rootDir:        rootDir,
 		maxUploadBytes: maxUploadBytes,
+		newID:          db.GenerateID,
 	}, nil
 }
 
+// SetIDFormat selects how blob IDs are generated (db.IDFormatRandom or
+// db.IDFormatULID).
+func (s *Service) SetIDFormat(format string) {
+	s.newID = db.NewIDGenerator(format)
+}
+
 func (s *Service) MaxUploadBytes() int64 {
 	return s.maxUploadBytes
 }
 
-func (s *Service) Save(_ context.Context, kind Kind, originalName string, src io.Reader) (*StoredBlob, error) {
+// Precheck validates client-declared upload metadata before any bytes are sent.
+// An empty mimeType skips the type check. Save still sniffs the real content,
+// so passing Precheck does not guarantee the upload will be accepted.
+func (s *Service) Precheck(kind Kind, sizeBytes int64, mimeType string) error {
+	if !isValidKind(kind) {
+		return ErrInvalidKind
+	}
+	if sizeBytes > s.maxUploadBytes {
+		return ErrFileTooLarge
+	}
+	if mimeType != "" && !s.allowsMimeType(kind, trimMimeParams(mimeType)) {
+		return ErrDisallowedType
+	}
+	return nil
+}
+
+func (s *Service) Save(ctx context.Context, kind Kind, originalName string, src io.Reader) (*StoredBlob, error) {
+	return s.SaveLimited(ctx, kind, originalName, src, s.maxUploadBytes)
+}
+
+// SaveLimited is Save with a tighter size cap, such as the uploader's role
+// limit. maxBytes never raises the service's own limit.
+func (s *Service) SaveLimited(_ context.Context, kind Kind, originalName string, src io.Reader, maxBytes int64) (*StoredBlob, error) {
+	maxBytes = min(maxBytes, s.maxUploadBytes)
 	if !isValidKind(kind) {
 		return nil, ErrInvalidKind
 	}
 
 	name := sanitizeOriginalName(originalName)
-	blobID, err := db.GenerateID("blb")
+	blobID, err := s.newID("blb")
 	if err != nil {
 		return nil, fmt.Errorf("generating blob id: %w", err)
 	}
@@ -112,16 +148,16 @@ func (s *Service) Save(_ context.Context, kind Kind, originalName string, src io
 	}
 
 	mimeType := detectMimeType(sniff)
-	if !isAllowedMimeType(kind, mimeType) {
+	if !s.allowsMimeType(kind, mimeType) {
 		return nil, ErrDisallowedType
 	}
 
 	fullReader := io.MultiReader(bytes.NewReader(sniff), src)
-	written, err := io.Copy(tmpFile, io.LimitReader(fullReader, s.maxUploadBytes+1))
+	written, err := io.Copy(tmpFile, io.LimitReader(fullReader, maxBytes+1))
 	if err != nil {
 		return nil, fmt.Errorf("writing blob file: %w", err)
 	}
-	if written > s.maxUploadBytes {
+	if written > maxBytes {
 		return nil, ErrFileTooLarge
 	}
 	if err := tmpFile.Close(); err != nil {
@@ -186,6 +222,64 @@ func (s *Service) Write(storagePath string, src io.Reader) (int64, error) {
 	return written, nil
 }
 
+// Create opens a new file at storagePath for writing as it is produced, such
+// as a voice recording. Readers may see it before it is complete.
+func (s *Service) Create(storagePath string) (*os.File, error) {
+	absPath, err := s.resolveStoragePath(storagePath)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(absPath), 0o755); err != nil {
+		return nil, fmt.Errorf("creating blob directory: %w", err)
+	}
+
+	file, err := os.Create(absPath)
+	if err != nil {
+		return nil, fmt.Errorf("creating blob file: %w", err)
+	}
+	return file, nil
+}
+
+// NewUploadSessionID returns the ID of a new resumable upload.
+func (s *Service) NewUploadSessionID() (string, error) {
+	return s.newID("upl")
+}
+
+// WriteAt stores src at offset in the file at storagePath, creating it if
+// needed. Anything past offset, such as the tail of a chunk that was cut
+// off, is discarded first. It returns how much of src was written, which
+// counts even when reading src failed part way, and syncs the file so the
+// new length survives a crash.
+func (s *Service) WriteAt(storagePath string, offset int64, src io.Reader) (int64, error) {
+	absPath, err := s.resolveStoragePath(storagePath)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(absPath), 0o755); err != nil {
+		return 0, fmt.Errorf("creating blob directory: %w", err)
+	}
+
+	file, err := os.OpenFile(absPath, os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return 0, fmt.Errorf("opening blob file: %w", err)
+	}
+	defer file.Close()
+
+	if err := file.Truncate(offset); err != nil {
+		return 0, fmt.Errorf("truncating blob file: %w", err)
+	}
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		return 0, fmt.Errorf("seeking blob file: %w", err)
+	}
+	written, copyErr := io.Copy(file, src)
+	if err := file.Sync(); err != nil {
+		return 0, fmt.Errorf("syncing blob file: %w", err)
+	}
+	return written, copyErr
+}
+
 func (s *Service) Delete(storagePath string) error {
 	absPath, err := s.resolveStoragePath(storagePath)
 	if err != nil {
@@ -203,6 +297,20 @@ func (s *Service) Delete(storagePath string) error {
 	return nil
 }
 
+// DeleteAll removes storagePath and everything under it.
+func (s *Service) DeleteAll(storagePath string) error {
+	absPath, err := s.resolveStoragePath(storagePath)
+	if err != nil {
+		return err
+	}
+
+	if err := os.RemoveAll(absPath); err != nil {
+		return fmt.Errorf("deleting blob directory: %w", err)
+	}
+
+	return nil
+}
+
 func (s *Service) resolveStoragePath(storagePath string) (string, error) {
 	clean := filepath.Clean(filepath.FromSlash(storagePath))
 	if clean == "." || strings.HasPrefix(clean, "..") || filepath.IsAbs(clean) {
@@ -220,11 +328,31 @@ func ChatAttachmentPreviewRelativePath(blobID string) string {
 	return filepath.ToSlash(filepath.Join("chat_attachment_preview", blobPathPrefix(blobID), blobID+".jpg"))
 }
 
+// RecordingRelativePath is where the voice of userID in a recording is stored.
+func RecordingRelativePath(recordingID, userID string) string {
+	return filepath.ToSlash(filepath.Join(RecordingDir(recordingID), userID+".ogg"))
+}
+
+// RecordingDir holds every track of a recording.
+func RecordingDir(recordingID string) string {
+	return filepath.ToSlash(filepath.Join("recording", recordingID))
+}
+
+// UploadSessionRelativePath is where the bytes received so far of a
+// resumable upload are kept until it is finalized.
+func UploadSessionRelativePath(sessionID string) string {
+	return filepath.ToSlash(filepath.Join("upload_session", sessionID+".part"))
+}
+
 func blobPathPrefix(blobID string) string {
 	randomPart := strings.TrimPrefix(blobID, "blb_")
 	if len(randomPart) < 2 {
 		return "xx"
 	}
+	// Sortable IDs start with their timestamp; shard on the random tail.
+	if len(randomPart) == db.SortableIDLength {
+		return randomPart[len(randomPart)-2:]
+	}
 	return randomPart[:2]
 }
 
@@ -244,6 +372,9 @@ func detectMimeType(sniff []byte) string {
 		return "application/octet-stream"
 	}
 
+	if mimeType := heifMimeType(sniff); mimeType != "" {
+		return mimeType
+	}
 	return trimMimeParams(http.DetectContentType(sniff))
 }
 
@@ -0,0 +1,140 @@
+package blob
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	// TextPreviewMaxFileBytes is the largest text attachment that gets a
+	// preview; bigger files are usually dumps nobody reads inline.
+	TextPreviewMaxFileBytes = 1 << 20
+	DefaultTextPreviewLines = 20
+	DefaultTextPreviewBytes = 4096
+)
+
+type TextPreview struct {
+	Text     string
+	Language string
+}
+
+// languagesByExtension maps file extensions to syntax highlighting hints.
+var languagesByExtension = map[string]string{
+	".c":     "c",
+	".cpp":   "cpp",
+	".cs":    "csharp",
+	".css":   "css",
+	".csv":   "csv",
+	".diff":  "diff",
+	".go":    "go",
+	".h":     "c",
+	".hpp":   "cpp",
+	".ini":   "ini",
+	".java":  "java",
+	".js":    "javascript",
+	".json":  "json",
+	".kt":    "kotlin",
+	".log":   "log",
+	".lua":   "lua",
+	".md":    "markdown",
+	".patch": "diff",
+	".py":    "python",
+	".rb":    "ruby",
+	".rs":    "rust",
+	".sql":   "sql",
+	".swift": "swift",
+	".toml":  "toml",
+	".ts":    "typescript",
+	".tsx":   "tsx",
+	".tsv":   "csv",
+	".txt":   "plaintext",
+	".xml":   "xml",
+	".yaml":  "yaml",
+	".yml":   "yaml",
+}
+
+var languagesByMimeType = map[string]string{
+	"application/json": "json",
+	"application/xml":  "xml",
+	"text/csv":         "csv",
+	"text/xml":         "xml",
+}
+
+// IsTextPreviewable reports whether an upload is text-like and small enough
+// for GenerateTextPreview.
+func IsTextPreviewable(mimeType string, sizeBytes int64) bool {
+	if sizeBytes <= 0 || sizeBytes > TextPreviewMaxFileBytes {
+		return false
+	}
+	mimeType = strings.ToLower(mimeType)
+	if strings.HasPrefix(mimeType, "text/") {
+		return true
+	}
+	_, ok := languagesByMimeType[mimeType]
+	return ok
+}
+
+// DetectLanguage returns a syntax highlighting hint for a file, from its
+// extension first and its MIME type second, defaulting to "plaintext".
+func DetectLanguage(name string, mimeType string) string {
+	if language, ok := languagesByExtension[strings.ToLower(filepath.Ext(name))]; ok {
+		return language
+	}
+	if language, ok := languagesByMimeType[strings.ToLower(mimeType)]; ok {
+		return language
+	}
+	return "plaintext"
+}
+
+// GenerateTextPreview returns the first maxLines lines of src, capped at
+// maxBytes and cut on a rune boundary. It fails on content that is not UTF-8
+// text.
+func GenerateTextPreview(src io.Reader, name string, mimeType string, maxLines int, maxBytes int) (*TextPreview, error) {
+	if maxLines <= 0 {
+		maxLines = DefaultTextPreviewLines
+	}
+	if maxBytes <= 0 {
+		maxBytes = DefaultTextPreviewBytes
+	}
+
+	buf := make([]byte, maxBytes)
+	n, err := io.ReadFull(src, buf)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return nil, fmt.Errorf("reading text: %w", err)
+	}
+	data := buf[:n]
+
+	// Drop a rune split by the byte cap.
+	if n == maxBytes {
+		for i := 0; i < utf8.UTFMax && len(data) > 0; i++ {
+			if utf8.Valid(data) {
+				break
+			}
+			data = data[:len(data)-1]
+		}
+	}
+	if !utf8.Valid(data) || bytes.IndexByte(data, 0) != -1 {
+		return nil, fmt.Errorf("content is not UTF-8 text")
+	}
+
+	lines := 0
+	for i, b := range data {
+		if b != '\n' {
+			continue
+		}
+		lines++
+		if lines == maxLines {
+			data = data[:i]
+			break
+		}
+	}
+
+	return &TextPreview{
+		Text:     strings.TrimRight(string(data), "\r\n"),
+		Language: DetectLanguage(name, mimeType),
+	}, nil
+}
@@ -0,0 +1,64 @@
+package blob
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGenerateTextPreviewKeepsFirstLines(t *testing.T) {
+	src := strings.NewReader("line 1\nline 2\r\nline 3\nline 4\n")
+
+	preview, err := GenerateTextPreview(src, "server.LOG", "text/plain", 3, 0)
+	if err != nil {
+		t.Fatalf("GenerateTextPreview() error = %v", err)
+	}
+	if preview.Text != "line 1\nline 2\r\nline 3" {
+		t.Fatalf("Text = %q, want first three lines", preview.Text)
+	}
+	if preview.Language != "log" {
+		t.Fatalf("Language = %q, want log", preview.Language)
+	}
+}
+
+func TestGenerateTextPreviewCutsOnRuneBoundary(t *testing.T) {
+	// "é" is two bytes, so a 5-byte cap splits the third one.
+	preview, err := GenerateTextPreview(strings.NewReader("ééé"), "notes.txt", "text/plain", 0, 5)
+	if err != nil {
+		t.Fatalf("GenerateTextPreview() error = %v", err)
+	}
+	if preview.Text != "éé" {
+		t.Fatalf("Text = %q, want %q", preview.Text, "éé")
+	}
+}
+
+func TestGenerateTextPreviewRejectsBinary(t *testing.T) {
+	if _, err := GenerateTextPreview(strings.NewReader("a\x00b"), "dump.txt", "text/plain", 0, 0); err == nil {
+		t.Fatal("GenerateTextPreview() succeeded on binary content")
+	}
+}
+
+func TestDetectLanguage(t *testing.T) {
+	tests := []struct {
+		name     string
+		mimeType string
+		want     string
+	}{
+		{name: "main.go", mimeType: "text/plain", want: "go"},
+		{name: "export", mimeType: "text/csv", want: "csv"},
+		{name: "README", mimeType: "text/plain", want: "plaintext"},
+	}
+	for _, tt := range tests {
+		if got := DetectLanguage(tt.name, tt.mimeType); got != tt.want {
+			t.Errorf("DetectLanguage(%q, %q) = %q, want %q", tt.name, tt.mimeType, got, tt.want)
+		}
+	}
+}
+
+func TestIsTextPreviewable(t *testing.T) {
+	if !IsTextPreviewable("text/plain", 100) || !IsTextPreviewable("application/json", 100) {
+		t.Fatal("expected small text and JSON to be previewable")
+	}
+	if IsTextPreviewable("text/plain", TextPreviewMaxFileBytes+1) || IsTextPreviewable("image/png", 100) {
+		t.Fatal("expected large files and images not to be previewable")
+	}
+}
@@ -0,0 +1,107 @@
+package cluster
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+)
+
+// TypeChannelAccess is a control envelope telling other instances to reload
+// the text channel ACL. It is never delivered to websocket clients.
+const TypeChannelAccess = "_CHANNEL_ACCESS"
+
+// TypeAutomodRules is a control envelope telling other instances to reload
+// the automod rules. It is never delivered to websocket clients.
+const TypeAutomodRules = "_AUTOMOD_RULES"
+
+// TypeUserTimeouts is a control envelope telling other instances to reload
+// the active user timeouts. It is never delivered to websocket clients.
+const TypeUserTimeouts = "_USER_TIMEOUTS"
+
+// TypeUserMutes is a control envelope telling other instances to reload the
+// text mutes of the viewer in UserID. It is never delivered to websocket
+// clients.
+const TypeUserMutes = "_USER_MUTES"
+
+// MemberTTL is how long a member record stays valid without a heartbeat
+// refresh from the instance that owns it.
+const MemberTTL = 45 * time.Second
+
+// Envelope is a broadcast forwarded between lobby instances.
+type Envelope struct {
+	Instance    string          `json:"instance"`
+	Type        string          `json:"type"`
+	Data        json.RawMessage `json:"data,omitempty"`
+	MaskedData  json.RawMessage `json:"masked_data,omitempty"` // Data for clients below moderator, if different
+	ChannelOnly bool            `json:"channel_only,omitempty"`
+	Moderators  bool            `json:"moderators,omitempty"` // moderators and admins only
+	UserID      string          `json:"user_id,omitempty"`    // sole recipient, if set
+	AuthorID    string          `json:"author_id,omitempty"`  // hidden from viewers who muted this user
+}
+
+// VoiceFrame is one Opus packet of a voice user, as cascaded between
+// instances. It travels apart from envelopes, in the encoding of voice.go.
+type VoiceFrame struct {
+	Instance  string // instance the user is connected to
+	UserID    string
+	Sequence  uint16
+	Timestamp uint32 // RTP timestamp at 48 kHz
+	Payload   []byte
+}
+
+// MemberRecord is the presence and voice state of a user connected to one instance.
+type MemberRecord struct {
+	UserID         string `json:"user_id"`
+	Instance       string `json:"instance"`
+	Status         string `json:"status"`
+	StatusText     string `json:"status_text,omitempty"`
+	StatusEmoji    string `json:"status_emoji,omitempty"`
+	InVoice        bool   `json:"in_voice"`
+	Muted          bool   `json:"muted"`
+	Deafened       bool   `json:"deafened"`
+	ServerMuted    bool   `json:"server_muted"`
+	ServerDeafened bool   `json:"server_deafened"`
+	PushToTalk     bool   `json:"push_to_talk"`
+	Streaming      bool   `json:"streaming"`
+	Camera         bool   `json:"camera,omitempty"`
+	SeenAt         int64  `json:"seen_at"` // unix seconds
+}
+
+// Stale reports whether the owning instance stopped refreshing the record.
+func (r MemberRecord) Stale(now time.Time) bool {
+	return now.Sub(time.Unix(r.SeenAt, 0)) > MemberTTL
+}
+
+// Backplane shares broadcasts and member state between lobby instances.
+type Backplane interface {
+	// Publish forwards an envelope to every instance, including the sender.
+	Publish(ctx context.Context, env Envelope) error
+	// Subscribe delivers envelopes from all instances to handler until Close.
+	// It reconnects on its own after transient failures.
+	Subscribe(handler func(Envelope))
+	// SetMember stores rec keyed by (rec.Instance, rec.UserID), so a user
+	// reconnecting to another instance never clobbers or is removed by the old one.
+	SetMember(ctx context.Context, rec MemberRecord) error
+	RemoveMember(ctx context.Context, instance, userID string) error
+	// Members returns the records of all instances, including stale ones.
+	Members(ctx context.Context) ([]MemberRecord, error)
+	// PublishVoice forwards a voice frame to every instance subscribed to
+	// voice, including the sender. Frames never share a channel with
+	// envelopes, so audio cannot delay control traffic.
+	PublishVoice(ctx context.Context, frame VoiceFrame) error
+	// SubscribeVoice delivers voice frames to handler on their own goroutine
+	// until stop is called or Close.
+	SubscribeVoice(handler func(VoiceFrame)) (stop func())
+	Close() error
+}
+
+// NewInstanceID returns a random identifier for this process.
+func NewInstanceID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return time.Now().UTC().Format("20060102150405.000000000")
+	}
+	return hex.EncodeToString(b)
+}
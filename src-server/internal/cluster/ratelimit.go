@@ -0,0 +1,47 @@
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// IncrementRateWindow adds amount to the request count of key in the rate
+// limit window starting at window. The counter expires after ttl.
+func (b *RedisBackplane) IncrementRateWindow(ctx context.Context, key string, window time.Time, amount int, ttl time.Duration) error {
+	windowKey := b.rateWindowKey(key, window)
+	_, err := b.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.IncrBy(ctx, windowKey, int64(amount))
+		pipe.PExpire(ctx, windowKey, ttl)
+		return nil
+	})
+	return err
+}
+
+// RateWindowCounts returns the request counts of key in the current and
+// previous rate limit windows.
+func (b *RedisBackplane) RateWindowCounts(ctx context.Context, key string, current, previous time.Time) (int, int, error) {
+	values, err := b.client.MGet(ctx, b.rateWindowKey(key, current), b.rateWindowKey(key, previous)).Result()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	counts := [2]int{}
+	for i, value := range values {
+		s, ok := value.(string)
+		if !ok {
+			continue // missing window
+		}
+		if counts[i], err = strconv.Atoi(s); err != nil {
+			return 0, 0, fmt.Errorf("redis: invalid rate window count %q", s)
+		}
+	}
+	return counts[0], counts[1], nil
+}
+
+func (b *RedisBackplane) rateWindowKey(key string, window time.Time) string {
+	return b.opts.Prefix + ":ratelimit:" + key + ":" + strconv.FormatInt(window.Unix(), 10)
+}
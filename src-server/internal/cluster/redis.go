@@ -0,0 +1,184 @@
+package cluster
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const redisDialTimeout = 5 * time.Second
+
+type RedisOptions struct {
+	Addr     string
+	Password string
+	Prefix   string // key and channel prefix, e.g. "lobby"
+}
+
+// RedisBackplane implements Backplane with Redis pub/sub for broadcasts and a
+// hash for member state. Connection pooling, reconnects and resubscribing
+// are left to go-redis.
+type RedisBackplane struct {
+	opts   RedisOptions
+	client *redis.Client
+
+	mu     sync.Mutex
+	pubsub *redis.PubSub
+	voice  map[*redis.PubSub]struct{}
+}
+
+func NewRedisBackplane(opts RedisOptions) *RedisBackplane {
+	if opts.Prefix == "" {
+		opts.Prefix = "lobby"
+	}
+	return &RedisBackplane{
+		opts: opts,
+		client: redis.NewClient(&redis.Options{
+			Addr:        opts.Addr,
+			Password:    opts.Password,
+			DialTimeout: redisDialTimeout,
+		}),
+	}
+}
+
+// Ping verifies the server is reachable and the credentials are accepted.
+func (b *RedisBackplane) Ping(ctx context.Context) error {
+	return b.client.Ping(ctx).Err()
+}
+
+func (b *RedisBackplane) Publish(ctx context.Context, env Envelope) error {
+	payload, err := json.Marshal(env)
+	if err != nil {
+		return fmt.Errorf("encoding envelope: %w", err)
+	}
+	return b.client.Publish(ctx, b.eventsChannel(), payload).Err()
+}
+
+func (b *RedisBackplane) SetMember(ctx context.Context, rec MemberRecord) error {
+	payload, err := json.Marshal(rec)
+	if err != nil {
+		return fmt.Errorf("encoding member record: %w", err)
+	}
+	return b.client.HSet(ctx, b.membersKey(), memberField(rec.Instance, rec.UserID), payload).Err()
+}
+
+func (b *RedisBackplane) RemoveMember(ctx context.Context, instance, userID string) error {
+	return b.client.HDel(ctx, b.membersKey(), memberField(instance, userID)).Err()
+}
+
+func (b *RedisBackplane) Members(ctx context.Context) ([]MemberRecord, error) {
+	fields, err := b.client.HGetAll(ctx, b.membersKey()).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	records := make([]MemberRecord, 0, len(fields))
+	for _, value := range fields {
+		var rec MemberRecord
+		if err := json.Unmarshal([]byte(value), &rec); err != nil {
+			slog.Warn("skipping malformed member record", "component", "cluster", "error", err)
+			continue
+		}
+		records = append(records, rec)
+	}
+	return records, nil
+}
+
+// Subscribe delivers envelopes from all instances to handler until Close.
+// The subscription survives reconnects, but envelopes published while it is
+// down are lost.
+func (b *RedisBackplane) Subscribe(handler func(Envelope)) {
+	pubsub := b.client.Subscribe(context.Background(), b.eventsChannel())
+	b.mu.Lock()
+	b.pubsub = pubsub
+	b.mu.Unlock()
+
+	go func() {
+		for msg := range pubsub.Channel() {
+			var env Envelope
+			if err := json.Unmarshal([]byte(msg.Payload), &env); err != nil {
+				slog.Warn("skipping malformed envelope", "component", "cluster", "error", err)
+				continue
+			}
+			handler(env)
+		}
+	}()
+}
+
+func (b *RedisBackplane) PublishVoice(ctx context.Context, frame VoiceFrame) error {
+	payload, err := EncodeVoiceFrame(frame)
+	if err != nil {
+		return err
+	}
+	return b.client.Publish(ctx, b.voiceChannel(), payload).Err()
+}
+
+// SubscribeVoice delivers voice frames from all instances to handler on a
+// subscription of its own, so a busy voice channel never holds up envelopes.
+func (b *RedisBackplane) SubscribeVoice(handler func(VoiceFrame)) (stop func()) {
+	pubsub := b.client.Subscribe(context.Background(), b.voiceChannel())
+	b.mu.Lock()
+	if b.voice == nil {
+		b.voice = make(map[*redis.PubSub]struct{})
+	}
+	b.voice[pubsub] = struct{}{}
+	b.mu.Unlock()
+
+	go func() {
+		for msg := range pubsub.Channel() {
+			frame, err := DecodeVoiceFrame([]byte(msg.Payload))
+			if err != nil {
+				slog.Debug("skipping malformed voice frame", "component", "cluster", "error", err)
+				continue
+			}
+			handler(frame)
+		}
+	}()
+
+	return func() {
+		b.mu.Lock()
+		_, ok := b.voice[pubsub]
+		delete(b.voice, pubsub)
+		b.mu.Unlock()
+		if ok {
+			_ = pubsub.Close()
+		}
+	}
+}
+
+func (b *RedisBackplane) Close() error {
+	b.mu.Lock()
+	pubsub := b.pubsub
+	b.pubsub = nil
+	voice := b.voice
+	b.voice = nil
+	b.mu.Unlock()
+
+	if pubsub != nil {
+		_ = pubsub.Close()
+	}
+	for sub := range voice {
+		_ = sub.Close()
+	}
+	return b.client.Close()
+}
+
+func (b *RedisBackplane) eventsChannel() string {
+	return b.opts.Prefix + ":events"
+}
+
+func (b *RedisBackplane) voiceChannel() string {
+	return b.opts.Prefix + ":voice"
+}
+
+func (b *RedisBackplane) membersKey() string {
+	return b.opts.Prefix + ":members"
+}
+
+func memberField(instance, userID string) string {
+	return instance + ":" + userID
+}
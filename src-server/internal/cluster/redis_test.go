@@ -0,0 +1,187 @@
+package cluster
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+func newTestBackplane(t *testing.T) (*RedisBackplane, *miniredis.Miniredis) {
+	t.Helper()
+
+	srv := miniredis.RunT(t)
+	b := NewRedisBackplane(RedisOptions{Addr: srv.Addr()})
+	t.Cleanup(func() { b.Close() })
+	return b, srv
+}
+
+func TestRedisBackplaneMembers(t *testing.T) {
+	b, _ := newTestBackplane(t)
+	ctx := context.Background()
+
+	if err := b.Ping(ctx); err != nil {
+		t.Fatalf("Ping() error = %v", err)
+	}
+
+	now := time.Now().Unix()
+	for _, rec := range []MemberRecord{
+		{UserID: "usr_1", Instance: "a", Status: "online", SeenAt: now},
+		{UserID: "usr_1", Instance: "b", Status: "idle", SeenAt: now},
+	} {
+		if err := b.SetMember(ctx, rec); err != nil {
+			t.Fatalf("SetMember() error = %v", err)
+		}
+	}
+
+	if err := b.RemoveMember(ctx, "a", "usr_1"); err != nil {
+		t.Fatalf("RemoveMember() error = %v", err)
+	}
+
+	records, err := b.Members(ctx)
+	if err != nil {
+		t.Fatalf("Members() error = %v", err)
+	}
+	if len(records) != 1 || records[0].Instance != "b" || records[0].Status != "idle" {
+		t.Fatalf("Members() = %+v, want only instance b record", records)
+	}
+}
+
+func TestRedisBackplaneRateWindows(t *testing.T) {
+	b, srv := newTestBackplane(t)
+	ctx := context.Background()
+
+	current := time.Now().Truncate(time.Minute)
+	previous := current.Add(-time.Minute)
+	for _, step := range []struct {
+		window time.Time
+		amount int
+	}{{previous, 2}, {current, 1}, {current, 3}} {
+		if err := b.IncrementRateWindow(ctx, "verify:10.0.0.1", step.window, step.amount, 2*time.Minute); err != nil {
+			t.Fatalf("IncrementRateWindow() error = %v", err)
+		}
+	}
+
+	curr, prev, err := b.RateWindowCounts(ctx, "verify:10.0.0.1", current, previous)
+	if err != nil {
+		t.Fatalf("RateWindowCounts() error = %v", err)
+	}
+	if curr != 4 || prev != 2 {
+		t.Fatalf("RateWindowCounts() = %d, %d, want 4, 2", curr, prev)
+	}
+	if ttl := srv.TTL(b.rateWindowKey("verify:10.0.0.1", current)); ttl != 2*time.Minute {
+		t.Fatalf("rate window TTL = %v, want 2m", ttl)
+	}
+
+	curr, prev, err = b.RateWindowCounts(ctx, "verify:10.0.0.2", current, previous)
+	if err != nil || curr != 0 || prev != 0 {
+		t.Fatalf("RateWindowCounts() for unseen key = %d, %d, %v, want zeros", curr, prev, err)
+	}
+}
+
+func TestRedisBackplaneRejectsWrongPassword(t *testing.T) {
+	srv := miniredis.RunT(t)
+	srv.RequireAuth("secret")
+
+	b := NewRedisBackplane(RedisOptions{Addr: srv.Addr(), Password: "wrong"})
+	t.Cleanup(func() { b.Close() })
+	if err := b.Ping(context.Background()); err == nil {
+		t.Fatal("Ping() with a wrong password error = nil, want an error")
+	}
+}
+
+func TestRedisBackplaneSubscriptionSurvivesRestart(t *testing.T) {
+	b, srv := newTestBackplane(t)
+	ctx := context.Background()
+
+	received := make(chan Envelope, 16)
+	b.Subscribe(func(env Envelope) { received <- env })
+
+	// Publish until the subscriber sees one, since SUBSCRIBE is asynchronous.
+	waitForEnvelope := func(typ string) {
+		t.Helper()
+		deadline := time.After(10 * time.Second)
+		for {
+			_ = b.Publish(ctx, Envelope{Instance: "a", Type: typ})
+			select {
+			case env := <-received:
+				if env.Type == typ {
+					return
+				}
+			case <-time.After(50 * time.Millisecond):
+			case <-deadline:
+				t.Fatalf("no %s envelope received", typ)
+			}
+		}
+	}
+
+	waitForEnvelope("BEFORE_RESTART")
+
+	srv.Close()
+	if err := srv.Restart(); err != nil {
+		t.Fatalf("Restart() error = %v", err)
+	}
+
+	waitForEnvelope("AFTER_RESTART")
+}
+
+func TestRedisBackplaneVoiceChannel(t *testing.T) {
+	b, _ := newTestBackplane(t)
+	ctx := context.Background()
+
+	envelopes := make(chan Envelope, 16)
+	b.Subscribe(func(env Envelope) { envelopes <- env })
+	frames := make(chan VoiceFrame, 16)
+	stop := b.SubscribeVoice(func(frame VoiceFrame) { frames <- frame })
+
+	want := VoiceFrame{Instance: "a", UserID: "usr_1", Sequence: 7, Timestamp: 960, Payload: []byte{0xfc, 0xff, 0xfe}}
+	deadline := time.After(10 * time.Second)
+	for received := false; !received; {
+		if err := b.PublishVoice(ctx, want); err != nil {
+			t.Fatalf("PublishVoice() error = %v", err)
+		}
+		select {
+		case got := <-frames:
+			if got.Instance != want.Instance || got.UserID != want.UserID || got.Sequence != want.Sequence ||
+				got.Timestamp != want.Timestamp || string(got.Payload) != string(want.Payload) {
+				t.Fatalf("received %+v, want %+v", got, want)
+			}
+			received = true
+		case <-time.After(50 * time.Millisecond):
+		case <-deadline:
+			t.Fatal("no voice frame received")
+		}
+	}
+	select {
+	case env := <-envelopes:
+		t.Fatalf("voice frame reached the events channel as %+v", env)
+	default:
+	}
+
+	stop()
+	for len(frames) > 0 {
+		<-frames
+	}
+	_ = b.PublishVoice(ctx, want)
+	select {
+	case got := <-frames:
+		t.Fatalf("received %+v after stop", got)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestDecodeVoiceFrameRejectsTruncated(t *testing.T) {
+	data, err := EncodeVoiceFrame(VoiceFrame{Instance: "a", UserID: "usr_1", Payload: []byte{0xfc}})
+	if err != nil {
+		t.Fatalf("EncodeVoiceFrame() error = %v", err)
+	}
+	for n := 0; n < len(data)-1; n++ {
+		if _, err := DecodeVoiceFrame(data[:n]); err == nil {
+			t.Fatalf("DecodeVoiceFrame(%d bytes) error = nil, want an error", n)
+		}
+	}
+	if _, err := EncodeVoiceFrame(VoiceFrame{UserID: string(make([]byte, 256))}); err == nil {
+		t.Fatal("EncodeVoiceFrame() with a 256-byte user ID error = nil, want an error")
+	}
+}
@@ -0,0 +1,59 @@
+package cluster
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+// Voice frames are sent as
+//
+//	instance length (1) | instance | user ID length (1) | user ID |
+//	sequence (2) | timestamp (4) | Opus payload
+//
+// with big-endian integers, to keep per-packet overhead small.
+
+var errMalformedVoiceFrame = errors.New("malformed voice frame")
+
+// EncodeVoiceFrame returns the wire form of frame. Instance and user IDs
+// longer than 255 bytes are rejected.
+func EncodeVoiceFrame(frame VoiceFrame) ([]byte, error) {
+	if len(frame.Instance) > 255 || len(frame.UserID) > 255 {
+		return nil, errMalformedVoiceFrame
+	}
+	buf := make([]byte, 0, 2+len(frame.Instance)+len(frame.UserID)+6+len(frame.Payload))
+	buf = append(buf, byte(len(frame.Instance)))
+	buf = append(buf, frame.Instance...)
+	buf = append(buf, byte(len(frame.UserID)))
+	buf = append(buf, frame.UserID...)
+	buf = binary.BigEndian.AppendUint16(buf, frame.Sequence)
+	buf = binary.BigEndian.AppendUint32(buf, frame.Timestamp)
+	return append(buf, frame.Payload...), nil
+}
+
+// DecodeVoiceFrame parses a frame written by EncodeVoiceFrame. The payload
+// aliases data.
+func DecodeVoiceFrame(data []byte) (VoiceFrame, error) {
+	var frame VoiceFrame
+	var ok bool
+	if frame.Instance, data, ok = readVoiceString(data); !ok {
+		return VoiceFrame{}, errMalformedVoiceFrame
+	}
+	if frame.UserID, data, ok = readVoiceString(data); !ok {
+		return VoiceFrame{}, errMalformedVoiceFrame
+	}
+	if len(data) < 6 {
+		return VoiceFrame{}, errMalformedVoiceFrame
+	}
+	frame.Sequence = binary.BigEndian.Uint16(data)
+	frame.Timestamp = binary.BigEndian.Uint32(data[2:])
+	frame.Payload = data[6:]
+	return frame, nil
+}
+
+func readVoiceString(data []byte) (string, []byte, bool) {
+	if len(data) < 1 || len(data) < 1+int(data[0]) {
+		return "", nil, false
+	}
+	n := int(data[0])
+	return string(data[1 : 1+n]), data[1+n:], true
+}
@@ -6,4 +6,11 @@ const (
 	WSBroadcastBufferSize  = 256
 	RTPPacketBufferBytes   = 1500
 	IDRandomBytes          = 12
+
+	// TextChannelID is the id of the single text_channel row.
+	TextChannelID int64 = 1
+
+	// MessageMaxContentLength is the maximum message (and draft) length in
+	// characters, including HTML markup.
+	MessageMaxContentLength = 8000
 )
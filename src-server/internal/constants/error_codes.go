@@ -2,15 +2,23 @@ package constants
 
 const (
 	// Shared REST/WS transport-agnostic errors
-	ErrCodeAuthFailed        = "AUTH_FAILED"
-	ErrCodeAuthExpired       = "AUTH_EXPIRED"
-	ErrCodeRateLimited       = "RATE_LIMITED"
-	ErrCodeInvalidRequest    = "INVALID_REQUEST"
-	ErrCodePayloadTooLarge   = "PAYLOAD_TOO_LARGE"
-	ErrCodeNotFound          = "NOT_FOUND"
-	ErrCodeConflict          = "CONFLICT"
-	ErrCodeInternal          = "INTERNAL_ERROR"
-	ErrCodeAttachmentInvalid = "ATTACHMENT_INVALID"
+	ErrCodeAuthFailed         = "AUTH_FAILED"
+	ErrCodeAuthExpired        = "AUTH_EXPIRED"
+	ErrCodeRateLimited        = "RATE_LIMITED"
+	ErrCodeInvalidRequest     = "INVALID_REQUEST"
+	ErrCodePayloadTooLarge    = "PAYLOAD_TOO_LARGE"
+	ErrCodeNotFound           = "NOT_FOUND"
+	ErrCodeConflict           = "CONFLICT"
+	ErrCodeInternal           = "INTERNAL_ERROR"
+	ErrCodeAttachmentInvalid  = "ATTACHMENT_INVALID"
+	ErrCodeForbidden          = "FORBIDDEN"
+	ErrCodeBanned             = "BANNED"
+	ErrCodeRegistrationClosed = "REGISTRATION_CLOSED"
+	ErrCodeInviteInvalid      = "INVITE_INVALID"
+	ErrCodeTimeout            = "TIMEOUT"
+	ErrCodeServerManageDenied = "SERVER_MANAGE_FORBIDDEN"
+	ErrCodeConnectionLimit    = "CONNECTION_LIMIT"
+	ErrCodeChallengeFailed    = "CHALLENGE_FAILED"
 
 	// Voice / signaling domain errors
 	ErrCodeMessageTooLong               = "MESSAGE_TOO_LONG"
@@ -23,4 +31,9 @@ const (
 	ErrCodeVoiceNegotiationFailed       = "VOICE_NEGOTIATION_FAILED"
 	ErrCodeVoiceNegotiationTimeout      = "VOICE_NEGOTIATION_TIMEOUT"
 	ErrCodeSignalingRateLimited         = "SIGNALING_RATE_LIMITED"
+	ErrCodeScreenShareInUse             = "SCREEN_SHARE_IN_USE"
+	ErrCodeVideoCodecUnsupported        = "VIDEO_CODEC_UNSUPPORTED"
+	ErrCodeChannelArchived              = "CHANNEL_ARCHIVED"
+	ErrCodeAutomodBlocked               = "AUTOMOD_BLOCKED"
+	ErrCodeAutomodRemoved               = "AUTOMOD_REMOVED"
 )
@@ -2,28 +2,49 @@ package db
 
 import (
 	"context"
+	"fmt"
 	"log/slog"
 	"time"
 
+	"lobby/internal/clock"
 	sqldb "lobby/internal/db/sqlc"
 )
 
 const (
 	DefaultCleanupInterval = 1 * time.Hour
+	accountPurgeBatch      = 20
 )
 
+// FileRemover deletes stored files by their storage path. It is satisfied by
+// blob.Service, which this package cannot import.
+type FileRemover interface {
+	Delete(storagePath string) error
+}
+
 type CleanupService struct {
+	database *DB
 	queries  *sqldb.Queries
+	files    FileRemover
 	interval time.Duration
+	clock    clock.Clock
 }
 
-func NewCleanupService(queries *sqldb.Queries) *CleanupService {
+func NewCleanupService(database *DB, files FileRemover) *CleanupService {
 	return &CleanupService{
-		queries:  queries,
+		database: database,
+		queries:  database.Queries(),
+		files:    files,
 		interval: DefaultCleanupInterval,
+		clock:    clock.Real,
 	}
 }
 
+// SetClock replaces the clock that decides what has expired. Call it before
+// Start.
+func (s *CleanupService) SetClock(c clock.Clock) {
+	s.clock = c
+}
+
 func (s *CleanupService) Start(ctx context.Context) {
 	slog.Info("starting token cleanup service", "component", "cleanup", "interval", s.interval)
 
@@ -44,7 +65,7 @@ func (s *CleanupService) Start(ctx context.Context) {
 }
 
 func (s *CleanupService) runCleanup(ctx context.Context) {
-	expiresBefore := time.Now().UTC()
+	expiresBefore := s.clock.Now().UTC()
 
 	magicDeleted, err := s.queries.DeleteExpiredMagicCodes(ctx, expiresBefore)
 	if err != nil {
@@ -53,6 +74,27 @@ func (s *CleanupService) runCleanup(ctx context.Context) {
 		slog.Info("deleted expired magic codes", "component", "cleanup", "count", magicDeleted)
 	}
 
+	throttlesDeleted, err := s.queries.DeleteExpiredMagicCodeThrottles(ctx, expiresBefore)
+	if err != nil {
+		slog.Error("error deleting expired magic code throttles", "component", "cleanup", "error", err)
+	} else if throttlesDeleted > 0 {
+		slog.Info("deleted expired magic code throttles", "component", "cleanup", "count", throttlesDeleted)
+	}
+
+	rateLimitsDeleted, err := s.queries.DeleteExpiredRateLimitWindows(ctx, expiresBefore)
+	if err != nil {
+		slog.Error("error deleting expired rate limit windows", "component", "cleanup", "error", err)
+	} else if rateLimitsDeleted > 0 {
+		slog.Info("deleted expired rate limit windows", "component", "cleanup", "count", rateLimitsDeleted)
+	}
+
+	emailChangesDeleted, err := s.queries.DeleteExpiredEmailChanges(ctx, expiresBefore)
+	if err != nil {
+		slog.Error("error deleting expired email changes", "component", "cleanup", "error", err)
+	} else if emailChangesDeleted > 0 {
+		slog.Info("deleted expired email changes", "component", "cleanup", "count", emailChangesDeleted)
+	}
+
 	registrationDeleted, err := s.queries.DeleteExpiredRegistrationTokens(ctx, expiresBefore)
 	if err != nil {
 		slog.Error("error deleting expired registration tokens", "component", "cleanup", "error", err)
@@ -66,4 +108,104 @@ func (s *CleanupService) runCleanup(ctx context.Context) {
 	} else if refreshDeleted > 0 {
 		slog.Info("deleted expired refresh tokens", "component", "cleanup", "count", refreshDeleted)
 	}
+
+	sessionsDeleted, err := s.queries.DeleteOrphanedUserSessions(ctx)
+	if err != nil {
+		slog.Error("error deleting orphaned user sessions", "component", "cleanup", "error", err)
+	} else if sessionsDeleted > 0 {
+		slog.Info("deleted orphaned user sessions", "component", "cleanup", "count", sessionsDeleted)
+	}
+
+	s.purgeDeletedAccounts(ctx, expiresBefore)
+}
+
+// purgeDeletedAccounts purges accounts whose deletion grace period is over.
+func (s *CleanupService) purgeDeletedAccounts(ctx context.Context, now time.Time) {
+	userIDs, err := s.queries.ListDueAccountDeletions(ctx, sqldb.ListDueAccountDeletionsParams{
+		Now:       now,
+		LimitRows: accountPurgeBatch,
+	})
+	if err != nil {
+		slog.Error("error listing due account deletions", "component", "cleanup", "error", err)
+		return
+	}
+
+	for _, userID := range userIDs {
+		if err := s.PurgeAccount(ctx, userID); err != nil {
+			slog.Error("error purging deleted account", "component", "cleanup", "error", err, "user_id", userID)
+			continue
+		}
+		slog.Info("purged deleted account", "component", "cleanup", "user_id", userID)
+	}
+}
+
+// PurgeAccount removes a deactivated user's personal data. The users row is
+// kept as an anonymous tombstone so their messages, now authorless, and any
+// ban stay in place. Avatars and chat attachments are deleted with their
+// files. A user who was reactivated in the meantime is left alone.
+func (s *CleanupService) PurgeAccount(ctx context.Context, userID string) error {
+	tx, err := s.database.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+	qtx := s.queries.WithTx(tx)
+
+	if _, err := qtx.DeleteAccountDeletion(ctx, userID); err != nil {
+		return fmt.Errorf("clearing deletion request: %w", err)
+	}
+	now := s.clock.Now().UTC()
+	anonymized, err := qtx.AnonymizeDeletedUser(ctx, sqldb.AnonymizeDeletedUserParams{
+		Username:  "deleted-" + userID,
+		Email:     userID + "@deleted.invalid",
+		UpdatedAt: &now,
+		ID:        userID,
+	})
+	if err != nil {
+		return fmt.Errorf("anonymizing user: %w", err)
+	}
+	if anonymized == 0 {
+		return tx.Commit()
+	}
+
+	blobs, err := qtx.ListAccountBlobs(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("listing blobs: %w", err)
+	}
+	steps := []struct {
+		name string
+		run  func(context.Context, string) error
+	}{
+		{"blobs", qtx.DeleteAccountBlobs},
+		{"refresh tokens", qtx.DeleteRefreshTokensForUser},
+		{"sessions", qtx.DeleteUserSessionsForUser},
+		{"drafts", qtx.DeleteMessageDraftsForUser},
+		{"notification settings", qtx.DeleteChannelNotificationSettingsForUser},
+		{"notification rules", qtx.DeleteNotificationRulesForUser},
+		{"email changes", qtx.DeleteEmailChangesForUser},
+		{"mutes", qtx.DeleteUserMutesForUser},
+	}
+	for _, step := range steps {
+		if err := step.run(ctx, userID); err != nil {
+			return fmt.Errorf("deleting %s: %w", step.name, err)
+		}
+	}
+	if _, err := qtx.RemoveTextChannelMember(ctx, userID); err != nil {
+		return fmt.Errorf("removing channel membership: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("committing: %w", err)
+	}
+
+	for _, row := range blobs {
+		if row.PreviewStoragePath != nil {
+			if err := s.files.Delete(*row.PreviewStoragePath); err != nil {
+				slog.Warn("error deleting purged account blob preview", "component", "cleanup", "error", err, "blob_id", row.ID)
+			}
+		}
+		if err := s.files.Delete(row.StoragePath); err != nil {
+			slog.Warn("error deleting purged account blob file", "component", "cleanup", "error", err, "blob_id", row.ID)
+		}
+	}
+	return nil
 }
@@ -2,11 +2,33 @@ package db
 
 import (
 	"crypto/rand"
+	"encoding/binary"
 	"encoding/hex"
+	"strings"
+	"sync"
+	"time"
 
 	"lobby/internal/constants"
 )
 
+// ID formats accepted by database.id_format.
+const (
+	IDFormatRandom = "random"
+	IDFormatULID   = "ulid"
+)
+
+// IDGenerator returns a new ID with the given prefix.
+type IDGenerator func(prefix string) (string, error)
+
+// NewIDGenerator returns the generator for a database.id_format value.
+// Anything but IDFormatULID gets GenerateID.
+func NewIDGenerator(format string) IDGenerator {
+	if format == IDFormatULID {
+		return GenerateSortableID
+	}
+	return GenerateID
+}
+
 func GenerateID(prefix string) (string, error) {
 	b := make([]byte, constants.IDRandomBytes)
 	if _, err := rand.Read(b); err != nil {
@@ -14,3 +36,84 @@ func GenerateID(prefix string) (string, error) {
 	}
 	return prefix + "_" + hex.EncodeToString(b), nil
 }
+
+// SortableIDLength is the length of a GenerateSortableID suffix.
+const SortableIDLength = 26
+
+// crockfordAlphabet is the lowercase Crockford base32 alphabet used by ULIDs.
+const crockfordAlphabet = "0123456789abcdefghjkmnpqrstvwxyz"
+
+// ulidState keeps IDs from this process strictly increasing: IDs within the
+// same millisecond (or after the clock steps back) reuse the last timestamp
+// and increment its entropy.
+var ulidState struct {
+	sync.Mutex
+	ms      uint64
+	entropy [10]byte
+}
+
+// GenerateSortableID returns a ULID-suffixed ID (48-bit millisecond
+// timestamp, then 80 random bits, lowercase Crockford base32). IDs sort
+// lexically by creation time.
+func GenerateSortableID(prefix string) (string, error) {
+	ms := uint64(time.Now().UnixMilli())
+
+	ulidState.Lock()
+	defer ulidState.Unlock()
+
+	if ms <= ulidState.ms && incrementEntropy(&ulidState.entropy) {
+		ms = ulidState.ms
+	} else {
+		if ms <= ulidState.ms {
+			// Entropy overflowed within one millisecond; borrow the next.
+			ms = ulidState.ms + 1
+		}
+		if _, err := rand.Read(ulidState.entropy[:]); err != nil {
+			return "", err
+		}
+		ulidState.ms = ms
+	}
+
+	var b [16]byte
+	binary.BigEndian.PutUint64(b[:8], ms<<16)
+	copy(b[6:], ulidState.entropy[:])
+	return prefix + "_" + encodeULID(b), nil
+}
+
+// IsSortableIDSuffix reports whether suffix could have come from
+// GenerateSortableID.
+func IsSortableIDSuffix(suffix string) bool {
+	if len(suffix) != SortableIDLength || suffix[0] > '7' {
+		return false
+	}
+	for i := 0; i < len(suffix); i++ {
+		if strings.IndexByte(crockfordAlphabet, suffix[i]) < 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// incrementEntropy adds one to entropy and reports false when it wraps.
+func incrementEntropy(entropy *[10]byte) bool {
+	for i := len(entropy) - 1; i >= 0; i-- {
+		entropy[i]++
+		if entropy[i] != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func encodeULID(b [16]byte) string {
+	hi := binary.BigEndian.Uint64(b[:8])
+	lo := binary.BigEndian.Uint64(b[8:])
+
+	out := make([]byte, SortableIDLength)
+	for i := SortableIDLength - 1; i >= 0; i-- {
+		out[i] = crockfordAlphabet[lo&31]
+		lo = lo>>5 | hi<<59
+		hi >>= 5
+	}
+	return string(out)
+}
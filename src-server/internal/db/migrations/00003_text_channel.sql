@@ -0,0 +1,12 @@
+-- +goose Up
+CREATE TABLE text_channel (
+    id INTEGER PRIMARY KEY CHECK (id = 1),
+    name TEXT NOT NULL CHECK (length(trim(name)) > 0),
+    topic TEXT NOT NULL DEFAULT '',
+    description TEXT NOT NULL DEFAULT '',
+    updated_by TEXT REFERENCES users(id) ON DELETE SET NULL,
+    updated_at DATETIME NOT NULL
+);
+
+INSERT INTO text_channel (id, name, topic, description, updated_by, updated_at)
+VALUES (1, 'general', '', '', NULL, CURRENT_TIMESTAMP);
@@ -0,0 +1,8 @@
+-- +goose Up
+ALTER TABLE text_channel ADD COLUMN private BOOLEAN NOT NULL DEFAULT 0;
+
+CREATE TABLE text_channel_members (
+    user_id TEXT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    added_by TEXT REFERENCES users(id) ON DELETE SET NULL,
+    added_at DATETIME NOT NULL
+);
@@ -0,0 +1,2 @@
+-- +goose Up
+ALTER TABLE text_channel ADD COLUMN archived BOOLEAN NOT NULL DEFAULT 0;
@@ -0,0 +1,8 @@
+-- +goose Up
+CREATE TABLE channel_notification_settings (
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    channel_id INTEGER NOT NULL REFERENCES text_channel(id) ON DELETE CASCADE,
+    level TEXT NOT NULL CHECK (level IN ('all', 'mentions', 'muted')),
+    updated_at DATETIME NOT NULL,
+    PRIMARY KEY (user_id, channel_id)
+);
@@ -0,0 +1,2 @@
+-- +goose Up
+ALTER TABLE text_channel ADD COLUMN slow_mode_seconds INTEGER NOT NULL DEFAULT 0;
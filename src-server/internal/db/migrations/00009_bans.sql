@@ -0,0 +1,11 @@
+-- +goose Up
+CREATE TABLE bans (
+    id TEXT PRIMARY KEY,
+    user_id TEXT NOT NULL UNIQUE REFERENCES users(id) ON DELETE CASCADE,
+    email_hash TEXT NOT NULL,
+    reason TEXT NOT NULL DEFAULT '',
+    banned_by TEXT REFERENCES users(id) ON DELETE SET NULL,
+    created_at DATETIME NOT NULL
+);
+
+CREATE INDEX idx_bans_email_hash ON bans(email_hash);
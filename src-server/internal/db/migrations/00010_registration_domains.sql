@@ -0,0 +1,6 @@
+-- +goose Up
+CREATE TABLE registration_domains (
+    domain TEXT PRIMARY KEY,
+    created_by TEXT REFERENCES users(id) ON DELETE SET NULL,
+    created_at DATETIME NOT NULL
+);
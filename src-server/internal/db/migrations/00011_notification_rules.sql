@@ -0,0 +1,11 @@
+-- +goose Up
+CREATE TABLE notification_rules (
+    id TEXT PRIMARY KEY,
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    keyword TEXT NOT NULL DEFAULT '',
+    author_id TEXT REFERENCES users(id) ON DELETE CASCADE,
+    channel_id INTEGER REFERENCES text_channel(id) ON DELETE CASCADE,
+    created_at DATETIME NOT NULL
+);
+
+CREATE INDEX idx_notification_rules_user_id ON notification_rules(user_id);
@@ -0,0 +1,9 @@
+-- +goose Up
+CREATE TABLE automod_rules (
+    id TEXT PRIMARY KEY,
+    kind TEXT NOT NULL CHECK (kind IN ('word', 'regex', 'invite')),
+    pattern TEXT NOT NULL DEFAULT '',
+    action TEXT NOT NULL CHECK (action IN ('block', 'flag', 'delete_warn')),
+    created_by TEXT REFERENCES users(id) ON DELETE SET NULL,
+    created_at DATETIME NOT NULL
+);
@@ -0,0 +1,12 @@
+-- +goose Up
+CREATE TABLE invites (
+    code TEXT PRIMARY KEY,
+    created_by TEXT REFERENCES users(id) ON DELETE SET NULL,
+    max_uses INTEGER,
+    uses INTEGER NOT NULL DEFAULT 0,
+    expires_at DATETIME,
+    revoked_at DATETIME,
+    created_at DATETIME NOT NULL
+);
+
+ALTER TABLE registration_tokens ADD COLUMN invite_code TEXT REFERENCES invites(code) ON DELETE SET NULL;
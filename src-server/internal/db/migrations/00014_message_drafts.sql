@@ -0,0 +1,8 @@
+-- +goose Up
+CREATE TABLE message_drafts (
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    channel_id INTEGER NOT NULL REFERENCES text_channel(id) ON DELETE CASCADE,
+    content TEXT NOT NULL,
+    updated_at DATETIME NOT NULL,
+    PRIMARY KEY (user_id, channel_id)
+);
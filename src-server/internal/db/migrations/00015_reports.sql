@@ -0,0 +1,18 @@
+-- +goose Up
+CREATE TABLE reports (
+    id TEXT PRIMARY KEY,
+    reporter_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    target_type TEXT NOT NULL CHECK (target_type IN ('message', 'user')),
+    target_id TEXT NOT NULL,
+    reason TEXT NOT NULL DEFAULT '',
+    resolved_by TEXT REFERENCES users(id) ON DELETE SET NULL,
+    resolved_at DATETIME,
+    created_at DATETIME NOT NULL
+);
+
+-- A reporter has at most one open report per target.
+CREATE UNIQUE INDEX idx_reports_open_reporter_target
+    ON reports(reporter_id, target_type, target_id)
+    WHERE resolved_at IS NULL;
+
+CREATE INDEX idx_reports_target ON reports(target_type, target_id);
@@ -0,0 +1,8 @@
+-- +goose Up
+CREATE TABLE user_timeouts (
+    user_id TEXT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    reason TEXT NOT NULL DEFAULT '',
+    created_by TEXT REFERENCES users(id) ON DELETE SET NULL,
+    expires_at DATETIME NOT NULL,
+    created_at DATETIME NOT NULL
+);
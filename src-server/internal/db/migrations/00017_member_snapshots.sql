@@ -0,0 +1,13 @@
+-- +goose Up
+CREATE TABLE member_snapshots (
+    user_id TEXT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    status TEXT NOT NULL,
+    in_voice BOOLEAN NOT NULL DEFAULT 0,
+    muted BOOLEAN NOT NULL DEFAULT 0,
+    deafened BOOLEAN NOT NULL DEFAULT 0,
+    server_muted BOOLEAN NOT NULL DEFAULT 0,
+    server_deafened BOOLEAN NOT NULL DEFAULT 0,
+    push_to_talk BOOLEAN NOT NULL DEFAULT 0,
+    streaming BOOLEAN NOT NULL DEFAULT 0,
+    saved_at DATETIME NOT NULL
+);
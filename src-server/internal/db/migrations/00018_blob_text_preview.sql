@@ -0,0 +1,3 @@
+-- +goose Up
+ALTER TABLE blobs ADD COLUMN preview_text TEXT;
+ALTER TABLE blobs ADD COLUMN preview_language TEXT;
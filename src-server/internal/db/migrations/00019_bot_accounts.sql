@@ -0,0 +1,16 @@
+-- +goose Up
+ALTER TABLE users ADD COLUMN bot BOOLEAN NOT NULL DEFAULT 0;
+
+CREATE TABLE bot_tokens (
+    id TEXT PRIMARY KEY,
+    bot_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    token_hash TEXT NOT NULL UNIQUE,
+    scopes TEXT NOT NULL,
+    created_by TEXT REFERENCES users(id) ON DELETE SET NULL,
+    created_at DATETIME NOT NULL,
+    last_used_at DATETIME,
+    revoked_at DATETIME
+);
+
+CREATE INDEX idx_bot_tokens_bot_id ON bot_tokens(bot_id);
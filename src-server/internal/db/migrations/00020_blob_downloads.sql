@@ -0,0 +1,5 @@
+-- +goose Up
+ALTER TABLE blobs ADD COLUMN download_count INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE blobs ADD COLUMN last_downloaded_at DATETIME;
+
+CREATE INDEX idx_blobs_download_count ON blobs(download_count DESC) WHERE download_count > 0;
@@ -0,0 +1,3 @@
+-- +goose Up
+ALTER TABLE channel_notification_settings ADD COLUMN suppress_everyone BOOLEAN NOT NULL DEFAULT 0;
+ALTER TABLE channel_notification_settings ADD COLUMN suppress_roles BOOLEAN NOT NULL DEFAULT 0;
@@ -0,0 +1,17 @@
+-- +goose Up
+CREATE TABLE user_sessions (
+    id TEXT PRIMARY KEY,
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    device_name TEXT,
+    user_agent TEXT,
+    ip_address TEXT,
+    created_at DATETIME NOT NULL,
+    last_used_at DATETIME NOT NULL,
+    revoked_at DATETIME
+);
+
+CREATE INDEX idx_user_sessions_user_id ON user_sessions(user_id);
+
+ALTER TABLE refresh_tokens ADD COLUMN session_id TEXT REFERENCES user_sessions(id);
+
+CREATE INDEX idx_refresh_tokens_session_id ON refresh_tokens(session_id);
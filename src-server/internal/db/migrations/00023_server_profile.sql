@@ -0,0 +1,5 @@
+-- +goose Up
+ALTER TABLE server_settings ADD COLUMN description TEXT NOT NULL DEFAULT '';
+ALTER TABLE server_settings ADD COLUMN rules_summary TEXT NOT NULL DEFAULT '';
+ALTER TABLE server_settings ADD COLUMN contact_email TEXT NOT NULL DEFAULT '';
+ALTER TABLE server_settings ADD COLUMN social_links TEXT NOT NULL DEFAULT '[]';
@@ -0,0 +1,13 @@
+-- +goose Up
+CREATE TABLE email_changes (
+    id TEXT PRIMARY KEY,
+    user_id TEXT NOT NULL UNIQUE REFERENCES users(id) ON DELETE CASCADE,
+    new_email TEXT NOT NULL,
+    current_code_hash TEXT NOT NULL,
+    new_code_hash TEXT NOT NULL,
+    current_confirmed_at DATETIME,
+    new_confirmed_at DATETIME,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    expires_at DATETIME NOT NULL,
+    created_at DATETIME NOT NULL
+);
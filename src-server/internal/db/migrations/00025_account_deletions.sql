@@ -0,0 +1,8 @@
+-- +goose Up
+CREATE TABLE account_deletions (
+    user_id TEXT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    requested_at DATETIME NOT NULL,
+    delete_after DATETIME NOT NULL
+);
+
+CREATE INDEX idx_account_deletions_delete_after ON account_deletions(delete_after);
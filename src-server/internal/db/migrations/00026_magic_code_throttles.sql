@@ -0,0 +1,9 @@
+-- +goose Up
+CREATE TABLE magic_code_throttles (
+    email_hash TEXT PRIMARY KEY,
+    window_ends_at DATETIME NOT NULL,
+    sent_count INTEGER NOT NULL,
+    next_allowed_at DATETIME NOT NULL
+);
+
+CREATE INDEX idx_magic_code_throttles_window_ends_at ON magic_code_throttles(window_ends_at);
@@ -0,0 +1,7 @@
+-- +goose Up
+CREATE TABLE user_mutes (
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    muted_user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at DATETIME NOT NULL,
+    PRIMARY KEY (user_id, muted_user_id)
+);
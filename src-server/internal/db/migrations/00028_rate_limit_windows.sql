@@ -0,0 +1,10 @@
+-- +goose Up
+CREATE TABLE rate_limit_windows (
+    limit_key TEXT NOT NULL,
+    window_start INTEGER NOT NULL,
+    count INTEGER NOT NULL,
+    expires_at DATETIME NOT NULL,
+    PRIMARY KEY (limit_key, window_start)
+);
+
+CREATE INDEX idx_rate_limit_windows_expires_at ON rate_limit_windows(expires_at);
@@ -0,0 +1,19 @@
+-- +goose Up
+CREATE TABLE recordings (
+    id TEXT PRIMARY KEY,
+    started_by TEXT REFERENCES users(id) ON DELETE SET NULL,
+    started_at DATETIME NOT NULL,
+    ended_at DATETIME,
+    expires_at DATETIME NOT NULL
+);
+
+CREATE INDEX idx_recordings_expires_at ON recordings(expires_at);
+
+CREATE TABLE recording_tracks (
+    recording_id TEXT NOT NULL REFERENCES recordings(id) ON DELETE CASCADE,
+    user_id TEXT NOT NULL,
+    storage_path TEXT NOT NULL,
+    size_bytes INTEGER NOT NULL,
+    offset_ms INTEGER NOT NULL, -- when the user was first heard, after the recording started
+    PRIMARY KEY (recording_id, user_id)
+);
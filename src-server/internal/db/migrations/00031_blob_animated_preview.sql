@@ -0,0 +1,3 @@
+-- +goose Up
+ALTER TABLE blobs ADD COLUMN preview_frame_count INTEGER;
+ALTER TABLE blobs ADD COLUMN preview_duration_ms INTEGER;
@@ -0,0 +1,3 @@
+-- +goose Up
+ALTER TABLE blobs ADD COLUMN scan_status TEXT;
+ALTER TABLE blobs ADD COLUMN scan_signature TEXT;
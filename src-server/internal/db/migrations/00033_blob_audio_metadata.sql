@@ -0,0 +1,3 @@
+-- +goose Up
+ALTER TABLE blobs ADD COLUMN audio_duration_ms INTEGER;
+ALTER TABLE blobs ADD COLUMN audio_waveform TEXT;
@@ -0,0 +1,2 @@
+-- +goose Up
+ALTER TABLE blobs ADD COLUMN missing_since DATETIME;
@@ -0,0 +1,2 @@
+-- +goose Up
+ALTER TABLE users ADD COLUMN avatar_animated_url TEXT;
@@ -0,0 +1,65 @@
+-- name: ScheduleAccountDeletion :exec
+INSERT INTO account_deletions (
+    user_id,
+    requested_at,
+    delete_after
+) VALUES (
+    sqlc.arg(user_id),
+    sqlc.arg(requested_at),
+    sqlc.arg(delete_after)
+)
+ON CONFLICT(user_id) DO UPDATE
+SET requested_at = excluded.requested_at,
+    delete_after = excluded.delete_after;
+
+-- name: DeleteAccountDeletion :execrows
+DELETE FROM account_deletions
+WHERE user_id = sqlc.arg(user_id);
+
+-- name: ListDueAccountDeletions :many
+SELECT user_id
+FROM account_deletions
+WHERE delete_after <= sqlc.arg(now)
+ORDER BY delete_after ASC
+LIMIT sqlc.arg(limit_rows);
+
+-- name: AnonymizeDeletedUser :execrows
+UPDATE users
+SET username = sqlc.arg(username),
+    email = sqlc.arg(email),
+    avatar_url = NULL,
+    avatar_animated_url = NULL,
+    updated_at = sqlc.arg(updated_at)
+WHERE id = sqlc.arg(id)
+  AND deactivated_at IS NOT NULL;
+
+-- name: ListAccountBlobs :many
+SELECT id, storage_path, preview_storage_path
+FROM blobs
+WHERE uploaded_by = sqlc.arg(user_id)
+  AND kind IN ('avatar', 'chat_attachment');
+
+-- name: DeleteAccountBlobs :exec
+DELETE FROM blobs
+WHERE uploaded_by = sqlc.arg(user_id)
+  AND kind IN ('avatar', 'chat_attachment');
+
+-- name: DeleteChannelNotificationSettingsForUser :exec
+DELETE FROM channel_notification_settings
+WHERE user_id = sqlc.arg(user_id);
+
+-- name: DeleteNotificationRulesForUser :exec
+DELETE FROM notification_rules
+WHERE user_id = sqlc.arg(user_id);
+
+-- name: DeleteMessageDraftsForUser :exec
+DELETE FROM message_drafts
+WHERE user_id = sqlc.arg(user_id);
+
+-- name: DeleteRefreshTokensForUser :exec
+DELETE FROM refresh_tokens
+WHERE user_id = sqlc.arg(user_id);
+
+-- name: DeleteUserSessionsForUser :exec
+DELETE FROM user_sessions
+WHERE user_id = sqlc.arg(user_id);
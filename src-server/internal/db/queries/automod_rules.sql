@@ -0,0 +1,29 @@
+-- name: CreateAutomodRule :exec
+INSERT INTO automod_rules (
+    id,
+    kind,
+    pattern,
+    action,
+    created_by,
+    created_at
+) VALUES (
+    sqlc.arg(id),
+    sqlc.arg(kind),
+    sqlc.arg(pattern),
+    sqlc.arg(action),
+    sqlc.arg(created_by),
+    sqlc.arg(created_at)
+);
+
+-- name: ListAutomodRules :many
+SELECT id, kind, pattern, action, created_by, created_at
+FROM automod_rules
+ORDER BY created_at, id;
+
+-- name: DeleteAutomodRule :execrows
+DELETE FROM automod_rules
+WHERE id = sqlc.arg(id);
+
+-- name: CountAutomodRules :one
+SELECT COUNT(*)
+FROM automod_rules;
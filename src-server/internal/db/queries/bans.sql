@@ -0,0 +1,32 @@
+-- name: CreateBan :exec
+INSERT INTO bans (
+    id,
+    user_id,
+    email_hash,
+    reason,
+    banned_by,
+    created_at
+) VALUES (
+    sqlc.arg(id),
+    sqlc.arg(user_id),
+    sqlc.arg(email_hash),
+    sqlc.arg(reason),
+    sqlc.arg(banned_by),
+    sqlc.arg(created_at)
+);
+
+-- name: CountBansForIdentity :one
+SELECT COUNT(*)
+FROM bans
+WHERE user_id = sqlc.arg(user_id)
+   OR email_hash = sqlc.arg(email_hash);
+
+-- name: ListBans :many
+SELECT b.id, b.user_id, COALESCE(u.username, '') AS username, b.reason, b.banned_by, b.created_at
+FROM bans b
+LEFT JOIN users u ON b.user_id = u.id
+ORDER BY b.created_at DESC;
+
+-- name: DeleteBanByUserID :execrows
+DELETE FROM bans
+WHERE user_id = sqlc.arg(user_id);
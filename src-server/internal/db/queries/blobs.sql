@@ -23,7 +23,8 @@ INSERT INTO blobs (
 
 -- name: GetBlobByID :one
 SELECT id, kind, uploaded_by, storage_path, mime_type, size_bytes, original_name, message_id, claimed_at, expires_at, created_at,
-       preview_storage_path, preview_mime_type, preview_size_bytes, preview_width, preview_height
+       preview_storage_path, preview_mime_type, preview_size_bytes, preview_width, preview_height,
+       scan_status
 FROM blobs
 WHERE id = sqlc.arg(id)
 LIMIT 1;
@@ -34,7 +35,27 @@ SET preview_storage_path = sqlc.arg(preview_storage_path),
     preview_mime_type = sqlc.arg(preview_mime_type),
     preview_size_bytes = sqlc.arg(preview_size_bytes),
     preview_width = sqlc.arg(preview_width),
-    preview_height = sqlc.arg(preview_height)
+    preview_height = sqlc.arg(preview_height),
+    preview_frame_count = sqlc.arg(preview_frame_count),
+    preview_duration_ms = sqlc.arg(preview_duration_ms)
+WHERE id = sqlc.arg(id);
+
+-- name: UpdateBlobTextPreview :execrows
+UPDATE blobs
+SET preview_text = sqlc.arg(preview_text),
+    preview_language = sqlc.arg(preview_language)
+WHERE id = sqlc.arg(id);
+
+-- name: UpdateBlobAudioMetadata :execrows
+UPDATE blobs
+SET audio_duration_ms = sqlc.arg(audio_duration_ms),
+    audio_waveform = sqlc.arg(audio_waveform)
+WHERE id = sqlc.arg(id);
+
+-- name: UpdateBlobScanStatus :execrows
+UPDATE blobs
+SET scan_status = sqlc.arg(scan_status),
+    scan_signature = sqlc.arg(scan_signature)
 WHERE id = sqlc.arg(id);
 
 -- name: ClaimChatBlobsForMessage :execrows
@@ -46,11 +67,14 @@ WHERE kind = 'chat_attachment'
   AND uploaded_by = sqlc.arg(uploaded_by)
   AND message_id IS NULL
   AND (expires_at IS NULL OR expires_at > sqlc.arg(now))
+  AND (scan_status IS NULL OR scan_status != 'infected')
   AND id IN (sqlc.slice(blob_ids));
 
 -- name: ListMessageAttachments :many
 SELECT id, original_name, mime_type, size_bytes, created_at,
-       preview_storage_path, preview_mime_type, preview_size_bytes, preview_width, preview_height
+       preview_storage_path, preview_mime_type, preview_size_bytes, preview_width, preview_height,
+       preview_text, preview_language, preview_frame_count, preview_duration_ms,
+       scan_status, audio_duration_ms, audio_waveform
 FROM blobs
 WHERE message_id = sqlc.arg(message_id)
   AND kind = 'chat_attachment'
@@ -58,12 +82,21 @@ ORDER BY created_at ASC, id ASC;
 
 -- name: ListMessageAttachmentsByMessageIDs :many
 SELECT message_id, id, original_name, mime_type, size_bytes, created_at,
-       preview_storage_path, preview_mime_type, preview_size_bytes, preview_width, preview_height
+       preview_storage_path, preview_mime_type, preview_size_bytes, preview_width, preview_height,
+       preview_text, preview_language, preview_frame_count, preview_duration_ms,
+       scan_status, audio_duration_ms, audio_waveform
 FROM blobs
 WHERE kind = 'chat_attachment'
   AND message_id IN (sqlc.slice(message_ids))
 ORDER BY message_id ASC, created_at ASC, id ASC;
 
+-- name: ListChatBlobsByMessageAuthor :many
+SELECT b.id, b.storage_path, b.preview_storage_path
+FROM blobs b
+JOIN messages m ON b.message_id = m.id
+WHERE b.kind = 'chat_attachment'
+  AND m.author_id = sqlc.arg(author_id);
+
 -- name: ListExpiredUnclaimedChatBlobs :many
 SELECT id, storage_path, preview_storage_path
 FROM blobs
@@ -77,3 +110,32 @@ LIMIT sqlc.arg(limit_rows);
 -- name: DeleteBlobByID :execrows
 DELETE FROM blobs
 WHERE id = sqlc.arg(id);
+
+-- name: IncrementBlobDownloadCount :exec
+UPDATE blobs
+SET download_count = download_count + 1,
+    last_downloaded_at = sqlc.arg(downloaded_at)
+WHERE id = sqlc.arg(id);
+
+-- name: ListTopDownloadedBlobs :many
+SELECT id, kind, original_name, mime_type, size_bytes, message_id, download_count, last_downloaded_at, created_at
+FROM blobs
+WHERE download_count > 0
+ORDER BY download_count DESC, id
+LIMIT sqlc.arg(limit_rows);
+
+-- name: SumBlobDownloads :one
+SELECT CAST(COALESCE(SUM(download_count), 0) AS INTEGER) AS total
+FROM blobs;
+
+-- name: ListBlobFiles :many
+SELECT id, storage_path, preview_storage_path, missing_since
+FROM blobs
+WHERE id > sqlc.arg(after_id)
+ORDER BY id ASC
+LIMIT sqlc.arg(limit_rows);
+
+-- name: SetBlobMissingSince :exec
+UPDATE blobs
+SET missing_since = sqlc.arg(missing_since)
+WHERE id = sqlc.arg(id);
@@ -0,0 +1,43 @@
+-- name: CreateBotToken :exec
+INSERT INTO bot_tokens (
+    id,
+    bot_id,
+    name,
+    token_hash,
+    scopes,
+    created_by,
+    created_at
+) VALUES (
+    sqlc.arg(id),
+    sqlc.arg(bot_id),
+    sqlc.arg(name),
+    sqlc.arg(token_hash),
+    sqlc.arg(scopes),
+    sqlc.arg(created_by),
+    sqlc.arg(created_at)
+);
+
+-- name: GetActiveBotTokenByHash :one
+SELECT id, bot_id, name, token_hash, scopes, created_by, created_at, last_used_at, revoked_at
+FROM bot_tokens
+WHERE token_hash = sqlc.arg(token_hash)
+  AND revoked_at IS NULL
+LIMIT 1;
+
+-- name: ListBotTokens :many
+SELECT id, bot_id, name, token_hash, scopes, created_by, created_at, last_used_at, revoked_at
+FROM bot_tokens
+WHERE bot_id = sqlc.arg(bot_id)
+ORDER BY created_at DESC, id;
+
+-- name: RevokeBotToken :execrows
+UPDATE bot_tokens
+SET revoked_at = sqlc.arg(revoked_at)
+WHERE id = sqlc.arg(id)
+  AND bot_id = sqlc.arg(bot_id)
+  AND revoked_at IS NULL;
+
+-- name: TouchBotToken :exec
+UPDATE bot_tokens
+SET last_used_at = sqlc.arg(last_used_at)
+WHERE id = sqlc.arg(id);
@@ -0,0 +1,53 @@
+-- name: CreateEmailChange :exec
+INSERT INTO email_changes (
+    id,
+    user_id,
+    new_email,
+    current_code_hash,
+    new_code_hash,
+    attempts,
+    expires_at,
+    created_at
+) VALUES (
+    sqlc.arg(id),
+    sqlc.arg(user_id),
+    sqlc.arg(new_email),
+    sqlc.arg(current_code_hash),
+    sqlc.arg(new_code_hash),
+    0,
+    sqlc.arg(expires_at),
+    sqlc.arg(created_at)
+);
+
+-- name: GetEmailChangeForUser :one
+SELECT id, user_id, new_email, current_code_hash, new_code_hash, current_confirmed_at, new_confirmed_at, attempts, expires_at, created_at
+FROM email_changes
+WHERE user_id = sqlc.arg(user_id)
+LIMIT 1;
+
+-- name: IncrementEmailChangeAttempts :one
+UPDATE email_changes
+SET attempts = attempts + 1
+WHERE id = sqlc.arg(id)
+  AND attempts < sqlc.arg(max_attempts)
+RETURNING attempts;
+
+-- name: ConfirmEmailChangeCurrent :execrows
+UPDATE email_changes
+SET current_confirmed_at = sqlc.arg(confirmed_at)
+WHERE id = sqlc.arg(id)
+  AND current_confirmed_at IS NULL;
+
+-- name: ConfirmEmailChangeNew :execrows
+UPDATE email_changes
+SET new_confirmed_at = sqlc.arg(confirmed_at)
+WHERE id = sqlc.arg(id)
+  AND new_confirmed_at IS NULL;
+
+-- name: DeleteEmailChangesForUser :exec
+DELETE FROM email_changes
+WHERE user_id = sqlc.arg(user_id);
+
+-- name: DeleteExpiredEmailChanges :execrows
+DELETE FROM email_changes
+WHERE expires_at < sqlc.arg(expires_before);
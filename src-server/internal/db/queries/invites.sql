@@ -0,0 +1,45 @@
+-- name: CreateInvite :exec
+INSERT INTO invites (
+    code,
+    created_by,
+    max_uses,
+    expires_at,
+    created_at
+) VALUES (
+    sqlc.arg(code),
+    sqlc.arg(created_by),
+    sqlc.arg(max_uses),
+    sqlc.arg(expires_at),
+    sqlc.arg(created_at)
+);
+
+-- name: GetUsableInvite :one
+SELECT code, created_by, max_uses, uses, expires_at, revoked_at, created_at
+FROM invites
+WHERE code = sqlc.arg(code)
+  AND revoked_at IS NULL
+  AND (expires_at IS NULL OR expires_at > sqlc.arg(now))
+  AND (max_uses IS NULL OR uses < max_uses)
+LIMIT 1;
+
+-- name: ListActiveInvites :many
+SELECT code, created_by, max_uses, uses, expires_at, revoked_at, created_at
+FROM invites
+WHERE revoked_at IS NULL
+  AND (expires_at IS NULL OR expires_at > sqlc.arg(now))
+  AND (max_uses IS NULL OR uses < max_uses)
+ORDER BY created_at DESC;
+
+-- name: UseInvite :execrows
+UPDATE invites
+SET uses = uses + 1
+WHERE code = sqlc.arg(code)
+  AND revoked_at IS NULL
+  AND (expires_at IS NULL OR expires_at > sqlc.arg(now))
+  AND (max_uses IS NULL OR uses < max_uses);
+
+-- name: RevokeInvite :execrows
+UPDATE invites
+SET revoked_at = sqlc.arg(revoked_at)
+WHERE code = sqlc.arg(code)
+  AND revoked_at IS NULL;
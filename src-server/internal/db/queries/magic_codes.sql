@@ -23,6 +23,13 @@ WHERE email = sqlc.arg(email)
 ORDER BY created_at DESC
 LIMIT 1;
 
+-- name: GetUnusedMagicCodeByID :one
+SELECT id, email, code_hash, expires_at, used_at, attempts, created_at
+FROM magic_codes
+WHERE id = sqlc.arg(id)
+  AND used_at IS NULL
+LIMIT 1;
+
 -- name: IncrementMagicCodeAttempts :one
 UPDATE magic_codes
 SET attempts = attempts + 1
@@ -39,3 +46,34 @@ WHERE id = sqlc.arg(id)
 -- name: DeleteExpiredMagicCodes :execrows
 DELETE FROM magic_codes
 WHERE expires_at < sqlc.arg(expires_before);
+
+-- name: GetMagicCodeThrottle :one
+SELECT email_hash, window_ends_at, sent_count, next_allowed_at
+FROM magic_code_throttles
+WHERE email_hash = sqlc.arg(email_hash);
+
+-- name: UpsertMagicCodeThrottle :exec
+INSERT INTO magic_code_throttles (
+    email_hash,
+    window_ends_at,
+    sent_count,
+    next_allowed_at
+) VALUES (
+    sqlc.arg(email_hash),
+    sqlc.arg(window_ends_at),
+    sqlc.arg(sent_count),
+    sqlc.arg(next_allowed_at)
+)
+ON CONFLICT(email_hash) DO UPDATE
+SET window_ends_at = excluded.window_ends_at,
+    sent_count = excluded.sent_count,
+    next_allowed_at = excluded.next_allowed_at;
+
+-- name: DeleteMagicCodeThrottle :exec
+DELETE FROM magic_code_throttles
+WHERE email_hash = sqlc.arg(email_hash);
+
+-- name: DeleteExpiredMagicCodeThrottles :execrows
+DELETE FROM magic_code_throttles
+WHERE window_ends_at < sqlc.arg(now)
+  AND next_allowed_at < sqlc.arg(now);
@@ -0,0 +1,32 @@
+-- name: CreateMemberSnapshot :exec
+INSERT INTO member_snapshots (
+    user_id,
+    status,
+    in_voice,
+    muted,
+    deafened,
+    server_muted,
+    server_deafened,
+    push_to_talk,
+    streaming,
+    saved_at
+) VALUES (
+    sqlc.arg(user_id),
+    sqlc.arg(status),
+    sqlc.arg(in_voice),
+    sqlc.arg(muted),
+    sqlc.arg(deafened),
+    sqlc.arg(server_muted),
+    sqlc.arg(server_deafened),
+    sqlc.arg(push_to_talk),
+    sqlc.arg(streaming),
+    sqlc.arg(saved_at)
+);
+
+-- name: DeleteMemberSnapshots :exec
+DELETE FROM member_snapshots;
+
+-- name: ListMemberSnapshots :many
+SELECT user_id, status, in_voice, muted, deafened, server_muted, server_deafened, push_to_talk, streaming, saved_at
+FROM member_snapshots
+ORDER BY user_id;
@@ -0,0 +1,26 @@
+-- name: ListMessageDraftsByUser :many
+SELECT user_id, channel_id, content, updated_at
+FROM message_drafts
+WHERE user_id = sqlc.arg(user_id)
+ORDER BY channel_id;
+
+-- name: UpsertMessageDraft :exec
+INSERT INTO message_drafts (
+    user_id,
+    channel_id,
+    content,
+    updated_at
+) VALUES (
+    sqlc.arg(user_id),
+    sqlc.arg(channel_id),
+    sqlc.arg(content),
+    sqlc.arg(updated_at)
+)
+ON CONFLICT (user_id, channel_id) DO UPDATE
+SET content = excluded.content,
+    updated_at = excluded.updated_at;
+
+-- name: DeleteMessageDraft :exec
+DELETE FROM message_drafts
+WHERE user_id = sqlc.arg(user_id)
+  AND channel_id = sqlc.arg(channel_id);
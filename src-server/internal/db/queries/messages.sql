@@ -17,6 +17,8 @@ SELECT
     m.author_id,
     COALESCE(u.username, '') AS author_name,
     u.avatar_url AS author_avatar_url,
+    u.avatar_animated_url AS author_avatar_animated_url,
+    COALESCE(u.bot, FALSE) AS author_bot,
     m.content,
     m.created_at,
     m.edited_at
@@ -31,6 +33,8 @@ SELECT
     m.author_id,
     COALESCE(u.username, '') AS author_name,
     u.avatar_url AS author_avatar_url,
+    u.avatar_animated_url AS author_avatar_animated_url,
+    COALESCE(u.bot, FALSE) AS author_bot,
     m.content,
     m.created_at,
     m.edited_at
@@ -45,3 +49,7 @@ SELECT id, author_id, content, created_at, edited_at
 FROM messages
 WHERE id = sqlc.arg(id)
 LIMIT 1;
+
+-- name: DeleteMessagesByAuthor :execrows
+DELETE FROM messages
+WHERE author_id = sqlc.arg(author_id);
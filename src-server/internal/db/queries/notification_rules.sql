@@ -0,0 +1,43 @@
+-- name: CreateNotificationRule :exec
+INSERT INTO notification_rules (
+    id,
+    user_id,
+    keyword,
+    author_id,
+    channel_id,
+    created_at
+) VALUES (
+    sqlc.arg(id),
+    sqlc.arg(user_id),
+    sqlc.arg(keyword),
+    sqlc.arg(author_id),
+    sqlc.arg(channel_id),
+    sqlc.arg(created_at)
+);
+
+-- name: CountNotificationRulesByUser :one
+SELECT COUNT(*)
+FROM notification_rules
+WHERE user_id = sqlc.arg(user_id);
+
+-- name: ListNotificationRulesByUser :many
+SELECT id, user_id, keyword, author_id, channel_id, created_at
+FROM notification_rules
+WHERE user_id = sqlc.arg(user_id)
+ORDER BY created_at;
+
+-- name: ListNotificationRulesForChannel :many
+SELECT r.id, r.user_id, r.keyword, r.author_id, u.role, COALESCE(s.level, 'all') AS level
+FROM notification_rules r
+JOIN users u ON u.id = r.user_id
+LEFT JOIN channel_notification_settings s
+  ON s.user_id = r.user_id
+ AND s.channel_id = sqlc.arg(channel_id)
+WHERE u.deactivated_at IS NULL
+  AND (r.channel_id IS NULL OR r.channel_id = sqlc.arg(channel_id))
+ORDER BY r.user_id, r.created_at;
+
+-- name: DeleteNotificationRule :execrows
+DELETE FROM notification_rules
+WHERE id = sqlc.arg(id)
+  AND user_id = sqlc.arg(user_id);
@@ -0,0 +1,62 @@
+-- name: GetChannelNotificationSettings :one
+SELECT level, suppress_everyone, suppress_roles
+FROM channel_notification_settings
+WHERE user_id = sqlc.arg(user_id)
+  AND channel_id = sqlc.arg(channel_id)
+LIMIT 1;
+
+-- name: UpsertChannelNotificationLevel :exec
+INSERT INTO channel_notification_settings (
+    user_id,
+    channel_id,
+    level,
+    updated_at
+) VALUES (
+    sqlc.arg(user_id),
+    sqlc.arg(channel_id),
+    sqlc.arg(level),
+    sqlc.arg(updated_at)
+)
+ON CONFLICT (user_id, channel_id) DO UPDATE
+SET level = excluded.level,
+    updated_at = excluded.updated_at;
+
+-- name: ListChannelNotificationOverrides :many
+SELECT user_id, level
+FROM channel_notification_settings
+WHERE channel_id = sqlc.arg(channel_id)
+  AND level != 'all';
+
+-- name: UpsertChannelNotificationSettings :exec
+INSERT INTO channel_notification_settings (
+    user_id,
+    channel_id,
+    level,
+    suppress_everyone,
+    suppress_roles,
+    updated_at
+) VALUES (
+    sqlc.arg(user_id),
+    sqlc.arg(channel_id),
+    sqlc.arg(level),
+    sqlc.arg(suppress_everyone),
+    sqlc.arg(suppress_roles),
+    sqlc.arg(updated_at)
+)
+ON CONFLICT (user_id, channel_id) DO UPDATE
+SET level = excluded.level,
+    suppress_everyone = excluded.suppress_everyone,
+    suppress_roles = excluded.suppress_roles,
+    updated_at = excluded.updated_at;
+
+-- name: ListMentionCandidates :many
+SELECT u.id, u.username, u.role,
+       COALESCE(s.level, 'all') AS level,
+       COALESCE(s.suppress_everyone, FALSE) AS suppress_everyone,
+       COALESCE(s.suppress_roles, FALSE) AS suppress_roles
+FROM users u
+LEFT JOIN channel_notification_settings s
+  ON s.user_id = u.id
+ AND s.channel_id = sqlc.arg(channel_id)
+WHERE u.deactivated_at IS NULL
+ORDER BY u.id;
@@ -0,0 +1,24 @@
+-- name: IncrementRateLimitWindow :exec
+INSERT INTO rate_limit_windows (
+    limit_key,
+    window_start,
+    count,
+    expires_at
+) VALUES (
+    sqlc.arg(limit_key),
+    sqlc.arg(window_start),
+    sqlc.arg(count),
+    sqlc.arg(expires_at)
+)
+ON CONFLICT(limit_key, window_start) DO UPDATE
+SET count = rate_limit_windows.count + excluded.count;
+
+-- name: ListRateLimitWindows :many
+SELECT limit_key, window_start, count, expires_at
+FROM rate_limit_windows
+WHERE limit_key = sqlc.arg(limit_key)
+  AND window_start >= sqlc.arg(since);
+
+-- name: DeleteExpiredRateLimitWindows :execrows
+DELETE FROM rate_limit_windows
+WHERE expires_at < sqlc.arg(now);
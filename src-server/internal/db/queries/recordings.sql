@@ -0,0 +1,48 @@
+-- name: CreateRecording :exec
+INSERT INTO recordings (
+    id,
+    started_by,
+    started_at,
+    expires_at
+) VALUES (
+    sqlc.arg(id),
+    sqlc.arg(started_by),
+    sqlc.arg(started_at),
+    sqlc.arg(expires_at)
+);
+
+-- name: FinishRecording :exec
+UPDATE recordings
+SET ended_at = sqlc.arg(ended_at),
+    expires_at = sqlc.arg(expires_at)
+WHERE id = sqlc.arg(id);
+
+-- name: AddRecordingTrack :exec
+INSERT INTO recording_tracks (
+    recording_id,
+    user_id,
+    storage_path,
+    size_bytes,
+    offset_ms
+) VALUES (
+    sqlc.arg(recording_id),
+    sqlc.arg(user_id),
+    sqlc.arg(storage_path),
+    sqlc.arg(size_bytes),
+    sqlc.arg(offset_ms)
+);
+
+-- name: ListExpiredRecordings :many
+SELECT id
+FROM recordings
+WHERE expires_at <= sqlc.arg(now)
+ORDER BY expires_at ASC
+LIMIT sqlc.arg(limit_rows);
+
+-- name: DeleteRecording :execrows
+DELETE FROM recordings
+WHERE id = sqlc.arg(id);
+
+-- name: ListRecordingIDs :many
+SELECT id
+FROM recordings;
@@ -4,17 +4,19 @@ INSERT INTO refresh_tokens (
     user_id,
     token_hash,
     expires_at,
-    created_at
+    created_at,
+    session_id
 ) VALUES (
     sqlc.arg(id),
     sqlc.arg(user_id),
     sqlc.arg(token_hash),
     sqlc.arg(expires_at),
-    sqlc.arg(created_at)
+    sqlc.arg(created_at),
+    sqlc.arg(session_id)
 );
 
 -- name: GetRefreshTokenByHash :one
-SELECT id, user_id, token_hash, expires_at, created_at, revoked_at
+SELECT id, user_id, token_hash, expires_at, created_at, revoked_at, session_id
 FROM refresh_tokens
 WHERE token_hash = sqlc.arg(token_hash)
 LIMIT 1;
@@ -38,6 +40,19 @@ SET revoked_at = sqlc.arg(revoked_at)
 WHERE user_id = sqlc.arg(user_id)
   AND revoked_at IS NULL;
 
+-- name: RevokeRefreshTokensForSession :exec
+UPDATE refresh_tokens
+SET revoked_at = sqlc.arg(revoked_at)
+WHERE session_id = sqlc.arg(session_id)
+  AND revoked_at IS NULL;
+
+-- name: RevokeRefreshTokensExceptSession :exec
+UPDATE refresh_tokens
+SET revoked_at = sqlc.arg(revoked_at)
+WHERE user_id = sqlc.arg(user_id)
+  AND (session_id IS NULL OR session_id != sqlc.arg(keep_session_id))
+  AND revoked_at IS NULL;
+
 -- name: DeleteExpiredRefreshTokens :execrows
 DELETE FROM refresh_tokens
 WHERE expires_at < sqlc.arg(expires_before);
@@ -0,0 +1,24 @@
+-- name: CreateRegistrationDomain :exec
+INSERT INTO registration_domains (
+    domain,
+    created_by,
+    created_at
+) VALUES (
+    sqlc.arg(domain),
+    sqlc.arg(created_by),
+    sqlc.arg(created_at)
+);
+
+-- name: CountRegistrationDomains :one
+SELECT COUNT(*)
+FROM registration_domains
+WHERE domain = sqlc.arg(domain);
+
+-- name: ListRegistrationDomains :many
+SELECT domain, created_by, created_at
+FROM registration_domains
+ORDER BY domain;
+
+-- name: DeleteRegistrationDomain :execrows
+DELETE FROM registration_domains
+WHERE domain = sqlc.arg(domain);
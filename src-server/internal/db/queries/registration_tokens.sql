@@ -4,17 +4,19 @@ INSERT INTO registration_tokens (
     email,
     token_hash,
     expires_at,
-    created_at
+    created_at,
+    invite_code
 ) VALUES (
     sqlc.arg(id),
     sqlc.arg(email),
     sqlc.arg(token_hash),
     sqlc.arg(expires_at),
-    sqlc.arg(created_at)
+    sqlc.arg(created_at),
+    sqlc.arg(invite_code)
 );
 
 -- name: GetValidRegistrationToken :one
-SELECT id, email, token_hash, expires_at, used_at, created_at
+SELECT id, email, token_hash, expires_at, used_at, created_at, invite_code
 FROM registration_tokens
 WHERE token_hash = sqlc.arg(token_hash)
   AND used_at IS NULL
@@ -27,7 +29,7 @@ SET used_at = sqlc.arg(used_at)
 WHERE token_hash = sqlc.arg(token_hash)
   AND used_at IS NULL
   AND expires_at > sqlc.arg(now)
-RETURNING id, email, token_hash, expires_at, used_at, created_at;
+RETURNING id, email, token_hash, expires_at, used_at, created_at, invite_code;
 
 -- name: DeleteExpiredRegistrationTokens :execrows
 DELETE FROM registration_tokens
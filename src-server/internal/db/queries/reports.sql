@@ -0,0 +1,53 @@
+-- name: CreateReport :execrows
+INSERT INTO reports (
+    id,
+    reporter_id,
+    target_type,
+    target_id,
+    reason,
+    created_at
+) VALUES (
+    sqlc.arg(id),
+    sqlc.arg(reporter_id),
+    sqlc.arg(target_type),
+    sqlc.arg(target_id),
+    sqlc.arg(reason),
+    sqlc.arg(created_at)
+)
+ON CONFLICT (reporter_id, target_type, target_id) WHERE resolved_at IS NULL DO NOTHING;
+
+-- name: GetReportByID :one
+SELECT id, reporter_id, target_type, target_id, reason, resolved_by, resolved_at, created_at
+FROM reports
+WHERE id = sqlc.arg(id)
+LIMIT 1;
+
+-- name: GetOpenReport :one
+SELECT id, reporter_id, target_type, target_id, reason, resolved_by, resolved_at, created_at
+FROM reports
+WHERE reporter_id = sqlc.arg(reporter_id)
+  AND target_type = sqlc.arg(target_type)
+  AND target_id = sqlc.arg(target_id)
+  AND resolved_at IS NULL
+LIMIT 1;
+
+-- name: CountOpenReportsForTarget :one
+SELECT COUNT(*)
+FROM reports
+WHERE target_type = sqlc.arg(target_type)
+  AND target_id = sqlc.arg(target_id)
+  AND resolved_at IS NULL;
+
+-- name: ListOpenReports :many
+SELECT id, reporter_id, target_type, target_id, reason, resolved_by, resolved_at, created_at
+FROM reports
+WHERE resolved_at IS NULL
+ORDER BY created_at ASC, id ASC;
+
+-- name: ResolveReportsForTarget :execrows
+UPDATE reports
+SET resolved_by = sqlc.arg(resolved_by),
+    resolved_at = sqlc.arg(resolved_at)
+WHERE resolved_at IS NULL
+  AND target_type = sqlc.arg(target_type)
+  AND target_id = sqlc.arg(target_id);
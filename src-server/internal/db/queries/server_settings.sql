@@ -1,5 +1,5 @@
 -- name: GetServerSettings :one
-SELECT id, icon_blob_id, updated_at
+SELECT id, icon_blob_id, updated_at, description, rules_summary, contact_email, social_links
 FROM server_settings
 WHERE id = 1
 LIMIT 1;
@@ -9,3 +9,12 @@ UPDATE server_settings
 SET icon_blob_id = sqlc.arg(icon_blob_id),
     updated_at = sqlc.arg(updated_at)
 WHERE id = 1;
+
+-- name: UpdateServerProfile :execrows
+UPDATE server_settings
+SET description = sqlc.arg(description),
+    rules_summary = sqlc.arg(rules_summary),
+    contact_email = sqlc.arg(contact_email),
+    social_links = sqlc.arg(social_links),
+    updated_at = sqlc.arg(updated_at)
+WHERE id = 1;
@@ -0,0 +1,61 @@
+-- name: GetTextChannel :one
+SELECT id, name, topic, description, updated_by, updated_at, private, archived, slow_mode_seconds
+FROM text_channel
+WHERE id = 1
+LIMIT 1;
+
+-- name: UpdateTextChannel :execrows
+UPDATE text_channel
+SET name = sqlc.arg(name),
+    topic = sqlc.arg(topic),
+    description = sqlc.arg(description),
+    updated_by = sqlc.arg(updated_by),
+    updated_at = sqlc.arg(updated_at)
+WHERE id = 1;
+
+-- name: SetTextChannelPrivate :execrows
+UPDATE text_channel
+SET private = sqlc.arg(private),
+    updated_by = sqlc.arg(updated_by),
+    updated_at = sqlc.arg(updated_at)
+WHERE id = 1;
+
+-- name: SetTextChannelArchived :execrows
+UPDATE text_channel
+SET archived = sqlc.arg(archived),
+    updated_by = sqlc.arg(updated_by),
+    updated_at = sqlc.arg(updated_at)
+WHERE id = 1;
+
+-- name: SetTextChannelSlowMode :execrows
+UPDATE text_channel
+SET slow_mode_seconds = sqlc.arg(slow_mode_seconds),
+    updated_by = sqlc.arg(updated_by),
+    updated_at = sqlc.arg(updated_at)
+WHERE id = 1;
+
+-- name: AddTextChannelMember :exec
+INSERT INTO text_channel_members (
+    user_id,
+    added_by,
+    added_at
+) VALUES (
+    sqlc.arg(user_id),
+    sqlc.arg(added_by),
+    sqlc.arg(added_at)
+)
+ON CONFLICT (user_id) DO NOTHING;
+
+-- name: RemoveTextChannelMember :execrows
+DELETE FROM text_channel_members
+WHERE user_id = sqlc.arg(user_id);
+
+-- name: ListTextChannelMemberIDs :many
+SELECT user_id
+FROM text_channel_members
+ORDER BY added_at;
+
+-- name: CountTextChannelMember :one
+SELECT COUNT(*)
+FROM text_channel_members
+WHERE user_id = sqlc.arg(user_id);
@@ -0,0 +1,52 @@
+-- name: CreateUploadSession :exec
+INSERT INTO upload_sessions (
+    id,
+    user_id,
+    original_name,
+    size_bytes,
+    storage_path,
+    created_at,
+    expires_at
+) VALUES (
+    sqlc.arg(id),
+    sqlc.arg(user_id),
+    sqlc.arg(original_name),
+    sqlc.arg(size_bytes),
+    sqlc.arg(storage_path),
+    sqlc.arg(created_at),
+    sqlc.arg(expires_at)
+);
+
+-- name: GetUploadSession :one
+SELECT id, user_id, original_name, size_bytes, received_bytes, storage_path, created_at, expires_at
+FROM upload_sessions
+WHERE id = sqlc.arg(id)
+  AND user_id = sqlc.arg(user_id)
+  AND expires_at > sqlc.arg(now);
+
+-- name: CountUploadSessionsForUser :one
+SELECT COUNT(*)
+FROM upload_sessions
+WHERE user_id = sqlc.arg(user_id)
+  AND expires_at > sqlc.arg(now);
+
+-- name: AdvanceUploadSession :execrows
+UPDATE upload_sessions
+SET received_bytes = sqlc.arg(received_bytes)
+WHERE id = sqlc.arg(id)
+  AND received_bytes = sqlc.arg(from_bytes);
+
+-- name: ListExpiredUploadSessions :many
+SELECT id, storage_path
+FROM upload_sessions
+WHERE expires_at <= sqlc.arg(now)
+ORDER BY expires_at ASC
+LIMIT sqlc.arg(limit_rows);
+
+-- name: DeleteUploadSession :execrows
+DELETE FROM upload_sessions
+WHERE id = sqlc.arg(id);
+
+-- name: ListUploadSessionPaths :many
+SELECT storage_path
+FROM upload_sessions;
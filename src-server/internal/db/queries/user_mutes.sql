@@ -0,0 +1,31 @@
+-- name: AddUserMute :exec
+INSERT INTO user_mutes (
+    user_id,
+    muted_user_id,
+    created_at
+) VALUES (
+    sqlc.arg(user_id),
+    sqlc.arg(muted_user_id),
+    sqlc.arg(created_at)
+)
+ON CONFLICT (user_id, muted_user_id) DO NOTHING;
+
+-- name: DeleteUserMute :execrows
+DELETE FROM user_mutes
+WHERE user_id = sqlc.arg(user_id)
+  AND muted_user_id = sqlc.arg(muted_user_id);
+
+-- name: ListUserMutes :many
+SELECT user_id, muted_user_id, created_at
+FROM user_mutes
+WHERE user_id = sqlc.arg(user_id)
+ORDER BY created_at;
+
+-- name: ListAllUserMutes :many
+SELECT user_id, muted_user_id, created_at
+FROM user_mutes;
+
+-- name: DeleteUserMutesForUser :exec
+DELETE FROM user_mutes
+WHERE user_id = sqlc.arg(user_id)
+   OR muted_user_id = sqlc.arg(user_id);
@@ -0,0 +1,68 @@
+-- name: CreateUserSession :exec
+INSERT INTO user_sessions (
+    id,
+    user_id,
+    device_name,
+    user_agent,
+    ip_address,
+    created_at,
+    last_used_at
+) VALUES (
+    sqlc.arg(id),
+    sqlc.arg(user_id),
+    sqlc.arg(device_name),
+    sqlc.arg(user_agent),
+    sqlc.arg(ip_address),
+    sqlc.arg(created_at),
+    sqlc.arg(created_at)
+);
+
+-- name: DeleteOrphanedUserSessions :execrows
+DELETE FROM user_sessions
+WHERE NOT EXISTS (
+    SELECT 1
+    FROM refresh_tokens rt
+    WHERE rt.session_id = user_sessions.id
+);
+
+-- name: GetActiveUserSession :one
+SELECT id, user_id, device_name, user_agent, ip_address, created_at, last_used_at, revoked_at
+FROM user_sessions
+WHERE id = sqlc.arg(id)
+  AND revoked_at IS NULL
+LIMIT 1;
+
+-- name: ListActiveUserSessions :many
+SELECT s.id, s.user_id, s.device_name, s.user_agent, s.ip_address, s.created_at, s.last_used_at, s.revoked_at
+FROM user_sessions s
+WHERE s.user_id = sqlc.arg(user_id)
+  AND s.revoked_at IS NULL
+  AND EXISTS (
+      SELECT 1
+      FROM refresh_tokens rt
+      WHERE rt.session_id = s.id
+        AND rt.revoked_at IS NULL
+        AND rt.expires_at > sqlc.arg(now)
+  )
+ORDER BY s.last_used_at DESC, s.id;
+
+-- name: RevokeUserSession :execrows
+UPDATE user_sessions
+SET revoked_at = sqlc.arg(revoked_at)
+WHERE id = sqlc.arg(id)
+  AND user_id = sqlc.arg(user_id)
+  AND revoked_at IS NULL;
+
+-- name: RevokeOtherUserSessions :exec
+UPDATE user_sessions
+SET revoked_at = sqlc.arg(revoked_at)
+WHERE user_id = sqlc.arg(user_id)
+  AND id != sqlc.arg(keep_id)
+  AND revoked_at IS NULL;
+
+-- name: TouchUserSession :exec
+UPDATE user_sessions
+SET last_used_at = sqlc.arg(last_used_at),
+    user_agent = sqlc.arg(user_agent),
+    ip_address = sqlc.arg(ip_address)
+WHERE id = sqlc.arg(id);
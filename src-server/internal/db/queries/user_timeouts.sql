@@ -0,0 +1,30 @@
+-- name: UpsertUserTimeout :exec
+INSERT INTO user_timeouts (
+    user_id,
+    reason,
+    created_by,
+    expires_at,
+    created_at
+) VALUES (
+    sqlc.arg(user_id),
+    sqlc.arg(reason),
+    sqlc.arg(created_by),
+    sqlc.arg(expires_at),
+    sqlc.arg(created_at)
+)
+ON CONFLICT (user_id) DO UPDATE
+SET reason = excluded.reason,
+    created_by = excluded.created_by,
+    expires_at = excluded.expires_at,
+    created_at = excluded.created_at;
+
+-- name: ListActiveUserTimeouts :many
+SELECT user_id, reason, created_by, expires_at, created_at
+FROM user_timeouts
+WHERE expires_at > sqlc.arg(now)
+ORDER BY expires_at;
+
+-- name: DeleteUserTimeout :execrows
+DELETE FROM user_timeouts
+WHERE user_id = sqlc.arg(user_id)
+  AND expires_at > sqlc.arg(now);
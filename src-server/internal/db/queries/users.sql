@@ -13,25 +13,54 @@ INSERT INTO users (
     sqlc.arg(created_at)
 );
 
+-- name: CreateBotUser :exec
+INSERT INTO users (
+    id,
+    username,
+    email,
+    session_version,
+    bot,
+    created_at
+) VALUES (
+    sqlc.arg(id),
+    sqlc.arg(username),
+    sqlc.arg(email),
+    1,
+    1,
+    sqlc.arg(created_at)
+);
+
 -- name: GetActiveUserByID :one
-SELECT id, username, email, avatar_url, session_version, created_at, updated_at, deactivated_at
+SELECT id, username, email, avatar_url, session_version, created_at, updated_at, deactivated_at, role, bot, avatar_animated_url
 FROM users
 WHERE id = sqlc.arg(id)
   AND deactivated_at IS NULL
 LIMIT 1;
 
+-- name: GetUserByID :one
+SELECT id, username, email, avatar_url, session_version, created_at, updated_at, deactivated_at, role, bot, avatar_animated_url
+FROM users
+WHERE id = sqlc.arg(id)
+LIMIT 1;
+
 -- name: GetUserByEmail :one
-SELECT id, username, email, avatar_url, session_version, created_at, updated_at, deactivated_at
+SELECT id, username, email, avatar_url, session_version, created_at, updated_at, deactivated_at, role, bot, avatar_animated_url
 FROM users
 WHERE email = sqlc.arg(email)
 LIMIT 1;
 
 -- name: ListActiveUsers :many
-SELECT id, username, avatar_url, created_at, updated_at
+SELECT id, username, avatar_url, created_at, updated_at, role, bot, avatar_animated_url
 FROM users
 WHERE deactivated_at IS NULL
 ORDER BY username;
 
+-- name: ListBotUsers :many
+SELECT id, username, email, avatar_url, session_version, created_at, updated_at, deactivated_at, role, bot, avatar_animated_url
+FROM users
+WHERE bot = 1
+ORDER BY username;
+
 -- name: UpdateUsername :execrows
 UPDATE users
 SET username = sqlc.arg(username),
@@ -41,6 +70,13 @@ WHERE id = sqlc.arg(id);
 -- name: UpdateUserAvatarURL :execrows
 UPDATE users
 SET avatar_url = sqlc.arg(avatar_url),
+    avatar_animated_url = sqlc.arg(avatar_animated_url),
+    updated_at = sqlc.arg(updated_at)
+WHERE id = sqlc.arg(id);
+
+-- name: UpdateUserEmail :execrows
+UPDATE users
+SET email = sqlc.arg(email),
     updated_at = sqlc.arg(updated_at)
 WHERE id = sqlc.arg(id);
 
@@ -58,6 +94,11 @@ SET deactivated_at = NULL,
 WHERE id = sqlc.arg(id)
   AND deactivated_at IS NOT NULL;
 
+-- name: CountHumanUsers :one
+SELECT COUNT(*)
+FROM users
+WHERE bot = 0;
+
 -- name: CountUsersByUsername :one
 SELECT COUNT(*)
 FROM users
@@ -68,3 +109,35 @@ UPDATE users
 SET session_version = session_version + 1,
     updated_at = sqlc.arg(updated_at)
 WHERE id = sqlc.arg(id);
+
+-- name: SetUserRole :execrows
+UPDATE users
+SET role = sqlc.arg(role),
+    updated_at = sqlc.arg(updated_at)
+WHERE id = sqlc.arg(id);
+
+-- name: ListActiveUserIDs :many
+SELECT id
+FROM users
+WHERE deactivated_at IS NULL
+ORDER BY id;
+
+-- name: ListInactiveMemberIDs :many
+SELECT u.id
+FROM users u
+WHERE u.deactivated_at IS NULL
+  AND u.role = 'member'
+  AND u.created_at < sqlc.arg(cutoff)
+  AND NOT EXISTS (
+      SELECT 1
+      FROM refresh_tokens rt
+      WHERE rt.user_id = u.id
+        AND rt.created_at >= sqlc.arg(cutoff)
+  )
+  AND NOT EXISTS (
+      SELECT 1
+      FROM messages m
+      WHERE m.author_id = u.id
+        AND m.created_at >= sqlc.arg(cutoff)
+  )
+ORDER BY u.id;
@@ -20,6 +20,7 @@ WHERE kind = 'chat_attachment'
   AND uploaded_by = ?3
   AND message_id IS NULL
   AND (expires_at IS NULL OR expires_at > ?4)
+  AND (scan_status IS NULL OR scan_status != 'infected')
   AND id IN (/*SLICE:blob_ids*/?)
 `
 
@@ -119,7 +120,8 @@ func (q *Queries) DeleteBlobByID(ctx context.Context, id string) (int64, error)
 
 const getBlobByID = `-- name: GetBlobByID :one
 SELECT id, kind, uploaded_by, storage_path, mime_type, size_bytes, original_name, message_id, claimed_at, expires_at, created_at,
-       preview_storage_path, preview_mime_type, preview_size_bytes, preview_width, preview_height
+       preview_storage_path, preview_mime_type, preview_size_bytes, preview_width, preview_height,
+       scan_status
 FROM blobs
 WHERE id = ?1
 LIMIT 1
@@ -142,6 +144,7 @@ type GetBlobByIDRow struct {
 	PreviewSizeBytes   *int64
 	PreviewWidth       *int64
 	PreviewHeight      *int64
+	ScanStatus         *string
 }
 
 func (q *Queries) GetBlobByID(ctx context.Context, id string) (GetBlobByIDRow, error) {
@@ -164,10 +167,113 @@ func (q *Queries) GetBlobByID(ctx context.Context, id string) (GetBlobByIDRow, e
 		&i.PreviewSizeBytes,
 		&i.PreviewWidth,
 		&i.PreviewHeight,
+		&i.ScanStatus,
 	)
 	return i, err
 }
 
+const incrementBlobDownloadCount = `-- name: IncrementBlobDownloadCount :exec
+UPDATE blobs
+SET download_count = download_count + 1,
+    last_downloaded_at = ?1
+WHERE id = ?2
+`
+
+type IncrementBlobDownloadCountParams struct {
+	DownloadedAt *time.Time
+	ID           string
+}
+
+func (q *Queries) IncrementBlobDownloadCount(ctx context.Context, arg IncrementBlobDownloadCountParams) error {
+	_, err := q.db.ExecContext(ctx, incrementBlobDownloadCount, arg.DownloadedAt, arg.ID)
+	return err
+}
+
+const listBlobFiles = `-- name: ListBlobFiles :many
+SELECT id, storage_path, preview_storage_path, missing_since
+FROM blobs
+WHERE id > ?1
+ORDER BY id ASC
+LIMIT ?2
+`
+
+type ListBlobFilesParams struct {
+	AfterID   string
+	LimitRows int64
+}
+
+type ListBlobFilesRow struct {
+	ID                 string
+	StoragePath        string
+	PreviewStoragePath *string
+	MissingSince       *time.Time
+}
+
+func (q *Queries) ListBlobFiles(ctx context.Context, arg ListBlobFilesParams) ([]ListBlobFilesRow, error) {
+	rows, err := q.db.QueryContext(ctx, listBlobFiles, arg.AfterID, arg.LimitRows)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListBlobFilesRow{}
+	for rows.Next() {
+		var i ListBlobFilesRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.StoragePath,
+			&i.PreviewStoragePath,
+			&i.MissingSince,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listChatBlobsByMessageAuthor = `-- name: ListChatBlobsByMessageAuthor :many
+SELECT b.id, b.storage_path, b.preview_storage_path
+FROM blobs b
+JOIN messages m ON b.message_id = m.id
+WHERE b.kind = 'chat_attachment'
+  AND m.author_id = ?1
+`
+
+type ListChatBlobsByMessageAuthorRow struct {
+	ID                 string
+	StoragePath        string
+	PreviewStoragePath *string
+}
+
+func (q *Queries) ListChatBlobsByMessageAuthor(ctx context.Context, authorID string) ([]ListChatBlobsByMessageAuthorRow, error) {
+	rows, err := q.db.QueryContext(ctx, listChatBlobsByMessageAuthor, authorID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListChatBlobsByMessageAuthorRow{}
+	for rows.Next() {
+		var i ListChatBlobsByMessageAuthorRow
+		if err := rows.Scan(&i.ID, &i.StoragePath, &i.PreviewStoragePath); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const listExpiredUnclaimedChatBlobs = `-- name: ListExpiredUnclaimedChatBlobs :many
 SELECT id, storage_path, preview_storage_path
 FROM blobs
@@ -215,7 +321,9 @@ func (q *Queries) ListExpiredUnclaimedChatBlobs(ctx context.Context, arg ListExp
 
 const listMessageAttachments = `-- name: ListMessageAttachments :many
 SELECT id, original_name, mime_type, size_bytes, created_at,
-       preview_storage_path, preview_mime_type, preview_size_bytes, preview_width, preview_height
+       preview_storage_path, preview_mime_type, preview_size_bytes, preview_width, preview_height,
+       preview_text, preview_language, preview_frame_count, preview_duration_ms,
+       scan_status, audio_duration_ms, audio_waveform
 FROM blobs
 WHERE message_id = ?1
   AND kind = 'chat_attachment'
@@ -233,6 +341,13 @@ type ListMessageAttachmentsRow struct {
 	PreviewSizeBytes   *int64
 	PreviewWidth       *int64
 	PreviewHeight      *int64
+	PreviewText        *string
+	PreviewLanguage    *string
+	PreviewFrameCount  *int64
+	PreviewDurationMs  *int64
+	ScanStatus         *string
+	AudioDurationMs    *int64
+	AudioWaveform      *string
 }
 
 func (q *Queries) ListMessageAttachments(ctx context.Context, messageID *string) ([]ListMessageAttachmentsRow, error) {
@@ -255,6 +370,13 @@ func (q *Queries) ListMessageAttachments(ctx context.Context, messageID *string)
 			&i.PreviewSizeBytes,
 			&i.PreviewWidth,
 			&i.PreviewHeight,
+			&i.PreviewText,
+			&i.PreviewLanguage,
+			&i.PreviewFrameCount,
+			&i.PreviewDurationMs,
+			&i.ScanStatus,
+			&i.AudioDurationMs,
+			&i.AudioWaveform,
 		); err != nil {
 			return nil, err
 		}
@@ -271,7 +393,9 @@ func (q *Queries) ListMessageAttachments(ctx context.Context, messageID *string)
 
 const listMessageAttachmentsByMessageIDs = `-- name: ListMessageAttachmentsByMessageIDs :many
 SELECT message_id, id, original_name, mime_type, size_bytes, created_at,
-       preview_storage_path, preview_mime_type, preview_size_bytes, preview_width, preview_height
+       preview_storage_path, preview_mime_type, preview_size_bytes, preview_width, preview_height,
+       preview_text, preview_language, preview_frame_count, preview_duration_ms,
+       scan_status, audio_duration_ms, audio_waveform
 FROM blobs
 WHERE kind = 'chat_attachment'
   AND message_id IN (/*SLICE:message_ids*/?)
@@ -290,6 +414,13 @@ type ListMessageAttachmentsByMessageIDsRow struct {
 	PreviewSizeBytes   *int64
 	PreviewWidth       *int64
 	PreviewHeight      *int64
+	PreviewText        *string
+	PreviewLanguage    *string
+	PreviewFrameCount  *int64
+	PreviewDurationMs  *int64
+	ScanStatus         *string
+	AudioDurationMs    *int64
+	AudioWaveform      *string
 }
 
 func (q *Queries) ListMessageAttachmentsByMessageIDs(ctx context.Context, messageIds []*string) ([]ListMessageAttachmentsByMessageIDsRow, error) {
@@ -323,6 +454,13 @@ func (q *Queries) ListMessageAttachmentsByMessageIDs(ctx context.Context, messag
 			&i.PreviewSizeBytes,
 			&i.PreviewWidth,
 			&i.PreviewHeight,
+			&i.PreviewText,
+			&i.PreviewLanguage,
+			&i.PreviewFrameCount,
+			&i.PreviewDurationMs,
+			&i.ScanStatus,
+			&i.AudioDurationMs,
+			&i.AudioWaveform,
 		); err != nil {
 			return nil, err
 		}
@@ -337,14 +475,118 @@ func (q *Queries) ListMessageAttachmentsByMessageIDs(ctx context.Context, messag
 	return items, nil
 }
 
+const listTopDownloadedBlobs = `-- name: ListTopDownloadedBlobs :many
+SELECT id, kind, original_name, mime_type, size_bytes, message_id, download_count, last_downloaded_at, created_at
+FROM blobs
+WHERE download_count > 0
+ORDER BY download_count DESC, id
+LIMIT ?1
+`
+
+type ListTopDownloadedBlobsRow struct {
+	ID               string
+	Kind             string
+	OriginalName     string
+	MimeType         string
+	SizeBytes        int64
+	MessageID        *string
+	DownloadCount    int64
+	LastDownloadedAt *time.Time
+	CreatedAt        time.Time
+}
+
+func (q *Queries) ListTopDownloadedBlobs(ctx context.Context, limitRows int64) ([]ListTopDownloadedBlobsRow, error) {
+	rows, err := q.db.QueryContext(ctx, listTopDownloadedBlobs, limitRows)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListTopDownloadedBlobsRow{}
+	for rows.Next() {
+		var i ListTopDownloadedBlobsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Kind,
+			&i.OriginalName,
+			&i.MimeType,
+			&i.SizeBytes,
+			&i.MessageID,
+			&i.DownloadCount,
+			&i.LastDownloadedAt,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const setBlobMissingSince = `-- name: SetBlobMissingSince :exec
+UPDATE blobs
+SET missing_since = ?1
+WHERE id = ?2
+`
+
+type SetBlobMissingSinceParams struct {
+	MissingSince *time.Time
+	ID           string
+}
+
+func (q *Queries) SetBlobMissingSince(ctx context.Context, arg SetBlobMissingSinceParams) error {
+	_, err := q.db.ExecContext(ctx, setBlobMissingSince, arg.MissingSince, arg.ID)
+	return err
+}
+
+const sumBlobDownloads = `-- name: SumBlobDownloads :one
+SELECT CAST(COALESCE(SUM(download_count), 0) AS INTEGER) AS total
+FROM blobs
+`
+
+func (q *Queries) SumBlobDownloads(ctx context.Context) (int64, error) {
+	row := q.db.QueryRowContext(ctx, sumBlobDownloads)
+	var total int64
+	err := row.Scan(&total)
+	return total, err
+}
+
+const updateBlobAudioMetadata = `-- name: UpdateBlobAudioMetadata :execrows
+UPDATE blobs
+SET audio_duration_ms = ?1,
+    audio_waveform = ?2
+WHERE id = ?3
+`
+
+type UpdateBlobAudioMetadataParams struct {
+	AudioDurationMs *int64
+	AudioWaveform   *string
+	ID              string
+}
+
+func (q *Queries) UpdateBlobAudioMetadata(ctx context.Context, arg UpdateBlobAudioMetadataParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, updateBlobAudioMetadata, arg.AudioDurationMs, arg.AudioWaveform, arg.ID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
 const updateBlobPreview = `-- name: UpdateBlobPreview :execrows
 UPDATE blobs
 SET preview_storage_path = ?1,
     preview_mime_type = ?2,
     preview_size_bytes = ?3,
     preview_width = ?4,
-    preview_height = ?5
-WHERE id = ?6
+    preview_height = ?5,
+    preview_frame_count = ?6,
+    preview_duration_ms = ?7
+WHERE id = ?8
 `
 
 type UpdateBlobPreviewParams struct {
@@ -353,6 +595,8 @@ type UpdateBlobPreviewParams struct {
 	PreviewSizeBytes   *int64
 	PreviewWidth       *int64
 	PreviewHeight      *int64
+	PreviewFrameCount  *int64
+	PreviewDurationMs  *int64
 	ID                 string
 }
 
@@ -363,6 +607,8 @@ func (q *Queries) UpdateBlobPreview(ctx context.Context, arg UpdateBlobPreviewPa
 		arg.PreviewSizeBytes,
 		arg.PreviewWidth,
 		arg.PreviewHeight,
+		arg.PreviewFrameCount,
+		arg.PreviewDurationMs,
 		arg.ID,
 	)
 	if err != nil {
@@ -370,3 +616,45 @@ func (q *Queries) UpdateBlobPreview(ctx context.Context, arg UpdateBlobPreviewPa
 	}
 	return result.RowsAffected()
 }
+
+const updateBlobScanStatus = `-- name: UpdateBlobScanStatus :execrows
+UPDATE blobs
+SET scan_status = ?1,
+    scan_signature = ?2
+WHERE id = ?3
+`
+
+type UpdateBlobScanStatusParams struct {
+	ScanStatus    *string
+	ScanSignature *string
+	ID            string
+}
+
+func (q *Queries) UpdateBlobScanStatus(ctx context.Context, arg UpdateBlobScanStatusParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, updateBlobScanStatus, arg.ScanStatus, arg.ScanSignature, arg.ID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const updateBlobTextPreview = `-- name: UpdateBlobTextPreview :execrows
+UPDATE blobs
+SET preview_text = ?1,
+    preview_language = ?2
+WHERE id = ?3
+`
+
+type UpdateBlobTextPreviewParams struct {
+	PreviewText     *string
+	PreviewLanguage *string
+	ID              string
+}
+
+func (q *Queries) UpdateBlobTextPreview(ctx context.Context, arg UpdateBlobTextPreviewParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, updateBlobTextPreview, arg.PreviewText, arg.PreviewLanguage, arg.ID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
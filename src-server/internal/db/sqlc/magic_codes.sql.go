@@ -47,6 +47,20 @@ func (q *Queries) CreateMagicCode(ctx context.Context, arg CreateMagicCodeParams
 	return err
 }
 
+const deleteExpiredMagicCodeThrottles = `-- name: DeleteExpiredMagicCodeThrottles :execrows
+DELETE FROM magic_code_throttles
+WHERE window_ends_at < ?1
+  AND next_allowed_at < ?1
+`
+
+func (q *Queries) DeleteExpiredMagicCodeThrottles(ctx context.Context, now time.Time) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteExpiredMagicCodeThrottles, now)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
 const deleteExpiredMagicCodes = `-- name: DeleteExpiredMagicCodes :execrows
 DELETE FROM magic_codes
 WHERE expires_at < ?1
@@ -60,6 +74,16 @@ func (q *Queries) DeleteExpiredMagicCodes(ctx context.Context, expiresBefore tim
 	return result.RowsAffected()
 }
 
+const deleteMagicCodeThrottle = `-- name: DeleteMagicCodeThrottle :exec
+DELETE FROM magic_code_throttles
+WHERE email_hash = ?1
+`
+
+func (q *Queries) DeleteMagicCodeThrottle(ctx context.Context, emailHash string) error {
+	_, err := q.db.ExecContext(ctx, deleteMagicCodeThrottle, emailHash)
+	return err
+}
+
 const getLatestUnusedMagicCodeByEmail = `-- name: GetLatestUnusedMagicCodeByEmail :one
 SELECT id, email, code_hash, expires_at, used_at, attempts, created_at
 FROM magic_codes
@@ -84,6 +108,47 @@ func (q *Queries) GetLatestUnusedMagicCodeByEmail(ctx context.Context, email str
 	return i, err
 }
 
+const getMagicCodeThrottle = `-- name: GetMagicCodeThrottle :one
+SELECT email_hash, window_ends_at, sent_count, next_allowed_at
+FROM magic_code_throttles
+WHERE email_hash = ?1
+`
+
+func (q *Queries) GetMagicCodeThrottle(ctx context.Context, emailHash string) (MagicCodeThrottle, error) {
+	row := q.db.QueryRowContext(ctx, getMagicCodeThrottle, emailHash)
+	var i MagicCodeThrottle
+	err := row.Scan(
+		&i.EmailHash,
+		&i.WindowEndsAt,
+		&i.SentCount,
+		&i.NextAllowedAt,
+	)
+	return i, err
+}
+
+const getUnusedMagicCodeByID = `-- name: GetUnusedMagicCodeByID :one
+SELECT id, email, code_hash, expires_at, used_at, attempts, created_at
+FROM magic_codes
+WHERE id = ?1
+  AND used_at IS NULL
+LIMIT 1
+`
+
+func (q *Queries) GetUnusedMagicCodeByID(ctx context.Context, id string) (MagicCode, error) {
+	row := q.db.QueryRowContext(ctx, getUnusedMagicCodeByID, id)
+	var i MagicCode
+	err := row.Scan(
+		&i.ID,
+		&i.Email,
+		&i.CodeHash,
+		&i.ExpiresAt,
+		&i.UsedAt,
+		&i.Attempts,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
 const incrementMagicCodeAttempts = `-- name: IncrementMagicCodeAttempts :one
 UPDATE magic_codes
 SET attempts = attempts + 1
@@ -123,3 +188,38 @@ func (q *Queries) MarkMagicCodeUsedIfUnused(ctx context.Context, arg MarkMagicCo
 	}
 	return result.RowsAffected()
 }
+
+const upsertMagicCodeThrottle = `-- name: UpsertMagicCodeThrottle :exec
+INSERT INTO magic_code_throttles (
+    email_hash,
+    window_ends_at,
+    sent_count,
+    next_allowed_at
+) VALUES (
+    ?1,
+    ?2,
+    ?3,
+    ?4
+)
+ON CONFLICT(email_hash) DO UPDATE
+SET window_ends_at = excluded.window_ends_at,
+    sent_count = excluded.sent_count,
+    next_allowed_at = excluded.next_allowed_at
+`
+
+type UpsertMagicCodeThrottleParams struct {
+	EmailHash     string
+	WindowEndsAt  time.Time
+	SentCount     int64
+	NextAllowedAt time.Time
+}
+
+func (q *Queries) UpsertMagicCodeThrottle(ctx context.Context, arg UpsertMagicCodeThrottleParams) error {
+	_, err := q.db.ExecContext(ctx, upsertMagicCodeThrottle,
+		arg.EmailHash,
+		arg.WindowEndsAt,
+		arg.SentCount,
+		arg.NextAllowedAt,
+	)
+	return err
+}
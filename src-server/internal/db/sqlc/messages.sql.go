@@ -41,6 +41,19 @@ func (q *Queries) CreateMessage(ctx context.Context, arg CreateMessageParams) er
 	return err
 }
 
+const deleteMessagesByAuthor = `-- name: DeleteMessagesByAuthor :execrows
+DELETE FROM messages
+WHERE author_id = ?1
+`
+
+func (q *Queries) DeleteMessagesByAuthor(ctx context.Context, authorID string) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteMessagesByAuthor, authorID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
 const getMessageByID = `-- name: GetMessageByID :one
 SELECT id, author_id, content, created_at, edited_at
 FROM messages
@@ -67,6 +80,8 @@ SELECT
     m.author_id,
     COALESCE(u.username, '') AS author_name,
     u.avatar_url AS author_avatar_url,
+    u.avatar_animated_url AS author_avatar_animated_url,
+    COALESCE(u.bot, FALSE) AS author_bot,
     m.content,
     m.created_at,
     m.edited_at
@@ -77,13 +92,15 @@ LIMIT ?1
 `
 
 type ListMessageHistoryRow struct {
-	ID              string
-	AuthorID        string
-	AuthorName      string
-	AuthorAvatarUrl *string
-	Content         string
-	CreatedAt       time.Time
-	EditedAt        *time.Time
+	ID                      string
+	AuthorID                string
+	AuthorName              string
+	AuthorAvatarUrl         *string
+	AuthorAvatarAnimatedUrl *string
+	AuthorBot               bool
+	Content                 string
+	CreatedAt               time.Time
+	EditedAt                *time.Time
 }
 
 func (q *Queries) ListMessageHistory(ctx context.Context, limitRows int64) ([]ListMessageHistoryRow, error) {
@@ -100,6 +117,8 @@ func (q *Queries) ListMessageHistory(ctx context.Context, limitRows int64) ([]Li
 			&i.AuthorID,
 			&i.AuthorName,
 			&i.AuthorAvatarUrl,
+			&i.AuthorAvatarAnimatedUrl,
+			&i.AuthorBot,
 			&i.Content,
 			&i.CreatedAt,
 			&i.EditedAt,
@@ -123,6 +142,8 @@ SELECT
     m.author_id,
     COALESCE(u.username, '') AS author_name,
     u.avatar_url AS author_avatar_url,
+    u.avatar_animated_url AS author_avatar_animated_url,
+    COALESCE(u.bot, FALSE) AS author_bot,
     m.content,
     m.created_at,
     m.edited_at
@@ -139,13 +160,15 @@ type ListMessageHistoryBeforeParams struct {
 }
 
 type ListMessageHistoryBeforeRow struct {
-	ID              string
-	AuthorID        string
-	AuthorName      string
-	AuthorAvatarUrl *string
-	Content         string
-	CreatedAt       time.Time
-	EditedAt        *time.Time
+	ID                      string
+	AuthorID                string
+	AuthorName              string
+	AuthorAvatarUrl         *string
+	AuthorAvatarAnimatedUrl *string
+	AuthorBot               bool
+	Content                 string
+	CreatedAt               time.Time
+	EditedAt                *time.Time
 }
 
 func (q *Queries) ListMessageHistoryBefore(ctx context.Context, arg ListMessageHistoryBeforeParams) ([]ListMessageHistoryBeforeRow, error) {
@@ -162,6 +185,8 @@ func (q *Queries) ListMessageHistoryBefore(ctx context.Context, arg ListMessageH
 			&i.AuthorID,
 			&i.AuthorName,
 			&i.AuthorAvatarUrl,
+			&i.AuthorAvatarAnimatedUrl,
+			&i.AuthorBot,
 			&i.Content,
 			&i.CreatedAt,
 			&i.EditedAt,
@@ -8,6 +8,30 @@ import (
 	"time"
 )
 
+type AccountDeletion struct {
+	UserID      string
+	RequestedAt time.Time
+	DeleteAfter time.Time
+}
+
+type AutomodRule struct {
+	ID        string
+	Kind      string
+	Pattern   string
+	Action    string
+	CreatedBy *string
+	CreatedAt time.Time
+}
+
+type Ban struct {
+	ID        string
+	UserID    string
+	EmailHash string
+	Reason    string
+	BannedBy  *string
+	CreatedAt time.Time
+}
+
 type Blob struct {
 	ID                 string
 	Kind               string
@@ -25,6 +49,61 @@ type Blob struct {
 	PreviewWidth       *int64
 	PreviewHeight      *int64
 	CreatedAt          time.Time
+	PreviewText        *string
+	PreviewLanguage    *string
+	DownloadCount      int64
+	LastDownloadedAt   *time.Time
+	PreviewFrameCount  *int64
+	PreviewDurationMs  *int64
+	ScanStatus         *string
+	ScanSignature      *string
+	AudioDurationMs    *int64
+	AudioWaveform      *string
+	MissingSince       *time.Time
+}
+
+type BotToken struct {
+	ID         string
+	BotID      string
+	Name       string
+	TokenHash  string
+	Scopes     string
+	CreatedBy  *string
+	CreatedAt  time.Time
+	LastUsedAt *time.Time
+	RevokedAt  *time.Time
+}
+
+type ChannelNotificationSetting struct {
+	UserID           string
+	ChannelID        int64
+	Level            string
+	UpdatedAt        time.Time
+	SuppressEveryone bool
+	SuppressRoles    bool
+}
+
+type EmailChange struct {
+	ID                 string
+	UserID             string
+	NewEmail           string
+	CurrentCodeHash    string
+	NewCodeHash        string
+	CurrentConfirmedAt *time.Time
+	NewConfirmedAt     *time.Time
+	Attempts           int64
+	ExpiresAt          time.Time
+	CreatedAt          time.Time
+}
+
+type Invite struct {
+	Code      string
+	CreatedBy *string
+	MaxUses   *int64
+	Uses      int64
+	ExpiresAt *time.Time
+	RevokedAt *time.Time
+	CreatedAt time.Time
 }
 
 type MagicCode struct {
@@ -37,6 +116,26 @@ type MagicCode struct {
 	CreatedAt time.Time
 }
 
+type MagicCodeThrottle struct {
+	EmailHash     string
+	WindowEndsAt  time.Time
+	SentCount     int64
+	NextAllowedAt time.Time
+}
+
+type MemberSnapshot struct {
+	UserID         string
+	Status         string
+	InVoice        bool
+	Muted          bool
+	Deafened       bool
+	ServerMuted    bool
+	ServerDeafened bool
+	PushToTalk     bool
+	Streaming      bool
+	SavedAt        time.Time
+}
+
 type Message struct {
 	ID        string
 	AuthorID  string
@@ -45,6 +144,45 @@ type Message struct {
 	EditedAt  *time.Time
 }
 
+type MessageDraft struct {
+	UserID    string
+	ChannelID int64
+	Content   string
+	UpdatedAt time.Time
+}
+
+type NotificationRule struct {
+	ID        string
+	UserID    string
+	Keyword   string
+	AuthorID  *string
+	ChannelID *int64
+	CreatedAt time.Time
+}
+
+type RateLimitWindow struct {
+	LimitKey    string
+	WindowStart int64
+	Count       int64
+	ExpiresAt   time.Time
+}
+
+type Recording struct {
+	ID        string
+	StartedBy *string
+	StartedAt time.Time
+	EndedAt   *time.Time
+	ExpiresAt time.Time
+}
+
+type RecordingTrack struct {
+	RecordingID string
+	UserID      string
+	StoragePath string
+	SizeBytes   int64
+	OffsetMs    int64
+}
+
 type RefreshToken struct {
 	ID        string
 	UserID    string
@@ -52,30 +190,110 @@ type RefreshToken struct {
 	ExpiresAt time.Time
 	CreatedAt time.Time
 	RevokedAt *time.Time
+	SessionID *string
 }
 
-type RegistrationToken struct {
-	ID        string
-	Email     string
-	TokenHash string
-	ExpiresAt time.Time
-	UsedAt    *time.Time
+type RegistrationDomain struct {
+	Domain    string
+	CreatedBy *string
 	CreatedAt time.Time
 }
 
+type RegistrationToken struct {
+	ID         string
+	Email      string
+	TokenHash  string
+	ExpiresAt  time.Time
+	UsedAt     *time.Time
+	CreatedAt  time.Time
+	InviteCode *string
+}
+
+type Report struct {
+	ID         string
+	ReporterID string
+	TargetType string
+	TargetID   string
+	Reason     string
+	ResolvedBy *string
+	ResolvedAt *time.Time
+	CreatedAt  time.Time
+}
+
 type ServerSetting struct {
-	ID         int64
-	IconBlobID *string
-	UpdatedAt  time.Time
+	ID           int64
+	IconBlobID   *string
+	UpdatedAt    time.Time
+	Description  string
+	RulesSummary string
+	ContactEmail string
+	SocialLinks  string
+}
+
+type TextChannel struct {
+	ID              int64
+	Name            string
+	Topic           string
+	Description     string
+	UpdatedBy       *string
+	UpdatedAt       time.Time
+	Private         bool
+	Archived        bool
+	SlowModeSeconds int64
+}
+
+type TextChannelMember struct {
+	UserID  string
+	AddedBy *string
+	AddedAt time.Time
+}
+
+type UploadSession struct {
+	ID            string
+	UserID        string
+	OriginalName  string
+	SizeBytes     int64
+	ReceivedBytes int64
+	StoragePath   string
+	CreatedAt     time.Time
+	ExpiresAt     time.Time
 }
 
 type User struct {
-	ID             string
-	Username       string
-	Email          string
-	AvatarUrl      *string
-	SessionVersion int64
-	CreatedAt      time.Time
-	UpdatedAt      *time.Time
-	DeactivatedAt  *time.Time
+	ID                string
+	Username          string
+	Email             string
+	AvatarUrl         *string
+	SessionVersion    int64
+	CreatedAt         time.Time
+	UpdatedAt         *time.Time
+	DeactivatedAt     *time.Time
+	Role              string
+	Bot               bool
+	AvatarAnimatedUrl *string
+}
+
+type UserMute struct {
+	UserID      string
+	MutedUserID string
+	CreatedAt   time.Time
+}
+
+type UserSession struct {
+	ID         string
+	UserID     string
+	DeviceName *string
+	UserAgent  *string
+	IpAddress  *string
+	CreatedAt  time.Time
+	LastUsedAt time.Time
+	RevokedAt  *time.Time
+}
+
+type UserTimeout struct {
+	UserID    string
+	Reason    string
+	CreatedBy *string
+	ExpiresAt time.Time
+	CreatedAt time.Time
 }
@@ -16,13 +16,15 @@ INSERT INTO refresh_tokens (
     user_id,
     token_hash,
     expires_at,
-    created_at
+    created_at,
+    session_id
 ) VALUES (
     ?1,
     ?2,
     ?3,
     ?4,
-    ?5
+    ?5,
+    ?6
 )
 `
 
@@ -32,6 +34,7 @@ type CreateRefreshTokenParams struct {
 	TokenHash string
 	ExpiresAt time.Time
 	CreatedAt time.Time
+	SessionID *string
 }
 
 func (q *Queries) CreateRefreshToken(ctx context.Context, arg CreateRefreshTokenParams) error {
@@ -41,6 +44,7 @@ func (q *Queries) CreateRefreshToken(ctx context.Context, arg CreateRefreshToken
 		arg.TokenHash,
 		arg.ExpiresAt,
 		arg.CreatedAt,
+		arg.SessionID,
 	)
 	return err
 }
@@ -59,7 +63,7 @@ func (q *Queries) DeleteExpiredRefreshTokens(ctx context.Context, expiresBefore
 }
 
 const getRefreshTokenByHash = `-- name: GetRefreshTokenByHash :one
-SELECT id, user_id, token_hash, expires_at, created_at, revoked_at
+SELECT id, user_id, token_hash, expires_at, created_at, revoked_at, session_id
 FROM refresh_tokens
 WHERE token_hash = ?1
 LIMIT 1
@@ -75,6 +79,7 @@ func (q *Queries) GetRefreshTokenByHash(ctx context.Context, tokenHash string) (
 		&i.ExpiresAt,
 		&i.CreatedAt,
 		&i.RevokedAt,
+		&i.SessionID,
 	)
 	return i, err
 }
@@ -137,3 +142,39 @@ func (q *Queries) RevokeRefreshTokenForRotation(ctx context.Context, arg RevokeR
 	}
 	return result.RowsAffected()
 }
+
+const revokeRefreshTokensExceptSession = `-- name: RevokeRefreshTokensExceptSession :exec
+UPDATE refresh_tokens
+SET revoked_at = ?1
+WHERE user_id = ?2
+  AND (session_id IS NULL OR session_id != ?3)
+  AND revoked_at IS NULL
+`
+
+type RevokeRefreshTokensExceptSessionParams struct {
+	RevokedAt     *time.Time
+	UserID        string
+	KeepSessionID *string
+}
+
+func (q *Queries) RevokeRefreshTokensExceptSession(ctx context.Context, arg RevokeRefreshTokensExceptSessionParams) error {
+	_, err := q.db.ExecContext(ctx, revokeRefreshTokensExceptSession, arg.RevokedAt, arg.UserID, arg.KeepSessionID)
+	return err
+}
+
+const revokeRefreshTokensForSession = `-- name: RevokeRefreshTokensForSession :exec
+UPDATE refresh_tokens
+SET revoked_at = ?1
+WHERE session_id = ?2
+  AND revoked_at IS NULL
+`
+
+type RevokeRefreshTokensForSessionParams struct {
+	RevokedAt *time.Time
+	SessionID *string
+}
+
+func (q *Queries) RevokeRefreshTokensForSession(ctx context.Context, arg RevokeRefreshTokensForSessionParams) error {
+	_, err := q.db.ExecContext(ctx, revokeRefreshTokensForSession, arg.RevokedAt, arg.SessionID)
+	return err
+}
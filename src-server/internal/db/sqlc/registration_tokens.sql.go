@@ -16,7 +16,7 @@ SET used_at = ?1
 WHERE token_hash = ?2
   AND used_at IS NULL
   AND expires_at > ?3
-RETURNING id, email, token_hash, expires_at, used_at, created_at
+RETURNING id, email, token_hash, expires_at, used_at, created_at, invite_code
 `
 
 type ConsumeValidRegistrationTokenParams struct {
@@ -35,6 +35,7 @@ func (q *Queries) ConsumeValidRegistrationToken(ctx context.Context, arg Consume
 		&i.ExpiresAt,
 		&i.UsedAt,
 		&i.CreatedAt,
+		&i.InviteCode,
 	)
 	return i, err
 }
@@ -45,22 +46,25 @@ INSERT INTO registration_tokens (
     email,
     token_hash,
     expires_at,
-    created_at
+    created_at,
+    invite_code
 ) VALUES (
     ?1,
     ?2,
     ?3,
     ?4,
-    ?5
+    ?5,
+    ?6
 )
 `
 
 type CreateRegistrationTokenParams struct {
-	ID        string
-	Email     string
-	TokenHash string
-	ExpiresAt time.Time
-	CreatedAt time.Time
+	ID         string
+	Email      string
+	TokenHash  string
+	ExpiresAt  time.Time
+	CreatedAt  time.Time
+	InviteCode *string
 }
 
 func (q *Queries) CreateRegistrationToken(ctx context.Context, arg CreateRegistrationTokenParams) error {
@@ -70,6 +74,7 @@ func (q *Queries) CreateRegistrationToken(ctx context.Context, arg CreateRegistr
 		arg.TokenHash,
 		arg.ExpiresAt,
 		arg.CreatedAt,
+		arg.InviteCode,
 	)
 	return err
 }
@@ -88,7 +93,7 @@ func (q *Queries) DeleteExpiredRegistrationTokens(ctx context.Context, expiresBe
 }
 
 const getValidRegistrationToken = `-- name: GetValidRegistrationToken :one
-SELECT id, email, token_hash, expires_at, used_at, created_at
+SELECT id, email, token_hash, expires_at, used_at, created_at, invite_code
 FROM registration_tokens
 WHERE token_hash = ?1
   AND used_at IS NULL
@@ -111,6 +116,7 @@ func (q *Queries) GetValidRegistrationToken(ctx context.Context, arg GetValidReg
 		&i.ExpiresAt,
 		&i.UsedAt,
 		&i.CreatedAt,
+		&i.InviteCode,
 	)
 	return i, err
 }
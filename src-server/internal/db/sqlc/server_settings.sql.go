@@ -11,7 +11,7 @@ import (
 )
 
 const getServerSettings = `-- name: GetServerSettings :one
-SELECT id, icon_blob_id, updated_at
+SELECT id, icon_blob_id, updated_at, description, rules_summary, contact_email, social_links
 FROM server_settings
 WHERE id = 1
 LIMIT 1
@@ -20,7 +20,15 @@ LIMIT 1
 func (q *Queries) GetServerSettings(ctx context.Context) (ServerSetting, error) {
 	row := q.db.QueryRowContext(ctx, getServerSettings)
 	var i ServerSetting
-	err := row.Scan(&i.ID, &i.IconBlobID, &i.UpdatedAt)
+	err := row.Scan(
+		&i.ID,
+		&i.IconBlobID,
+		&i.UpdatedAt,
+		&i.Description,
+		&i.RulesSummary,
+		&i.ContactEmail,
+		&i.SocialLinks,
+	)
 	return i, err
 }
 
@@ -43,3 +51,35 @@ func (q *Queries) SetServerIconBlobID(ctx context.Context, arg SetServerIconBlob
 	}
 	return result.RowsAffected()
 }
+
+const updateServerProfile = `-- name: UpdateServerProfile :execrows
+UPDATE server_settings
+SET description = ?1,
+    rules_summary = ?2,
+    contact_email = ?3,
+    social_links = ?4,
+    updated_at = ?5
+WHERE id = 1
+`
+
+type UpdateServerProfileParams struct {
+	Description  string
+	RulesSummary string
+	ContactEmail string
+	SocialLinks  string
+	UpdatedAt    time.Time
+}
+
+func (q *Queries) UpdateServerProfile(ctx context.Context, arg UpdateServerProfileParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, updateServerProfile,
+		arg.Description,
+		arg.RulesSummary,
+		arg.ContactEmail,
+		arg.SocialLinks,
+		arg.UpdatedAt,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -10,6 +10,19 @@ import (
 	"time"
 )
 
+const countHumanUsers = `-- name: CountHumanUsers :one
+SELECT COUNT(*)
+FROM users
+WHERE bot = 0
+`
+
+func (q *Queries) CountHumanUsers(ctx context.Context) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countHumanUsers)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const countUsersByUsername = `-- name: CountUsersByUsername :one
 SELECT COUNT(*)
 FROM users
@@ -23,6 +36,41 @@ func (q *Queries) CountUsersByUsername(ctx context.Context, username string) (in
 	return count, err
 }
 
+const createBotUser = `-- name: CreateBotUser :exec
+INSERT INTO users (
+    id,
+    username,
+    email,
+    session_version,
+    bot,
+    created_at
+) VALUES (
+    ?1,
+    ?2,
+    ?3,
+    1,
+    1,
+    ?4
+)
+`
+
+type CreateBotUserParams struct {
+	ID        string
+	Username  string
+	Email     string
+	CreatedAt time.Time
+}
+
+func (q *Queries) CreateBotUser(ctx context.Context, arg CreateBotUserParams) error {
+	_, err := q.db.ExecContext(ctx, createBotUser,
+		arg.ID,
+		arg.Username,
+		arg.Email,
+		arg.CreatedAt,
+	)
+	return err
+}
+
 const createUser = `-- name: CreateUser :exec
 INSERT INTO users (
     id,
@@ -79,7 +127,7 @@ func (q *Queries) DeactivateUser(ctx context.Context, arg DeactivateUserParams)
 }
 
 const getActiveUserByID = `-- name: GetActiveUserByID :one
-SELECT id, username, email, avatar_url, session_version, created_at, updated_at, deactivated_at
+SELECT id, username, email, avatar_url, session_version, created_at, updated_at, deactivated_at, role, bot, avatar_animated_url
 FROM users
 WHERE id = ?1
   AND deactivated_at IS NULL
@@ -98,12 +146,15 @@ func (q *Queries) GetActiveUserByID(ctx context.Context, id string) (User, error
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.DeactivatedAt,
+		&i.Role,
+		&i.Bot,
+		&i.AvatarAnimatedUrl,
 	)
 	return i, err
 }
 
 const getUserByEmail = `-- name: GetUserByEmail :one
-SELECT id, username, email, avatar_url, session_version, created_at, updated_at, deactivated_at
+SELECT id, username, email, avatar_url, session_version, created_at, updated_at, deactivated_at, role, bot, avatar_animated_url
 FROM users
 WHERE email = ?1
 LIMIT 1
@@ -121,6 +172,35 @@ func (q *Queries) GetUserByEmail(ctx context.Context, email string) (User, error
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.DeactivatedAt,
+		&i.Role,
+		&i.Bot,
+		&i.AvatarAnimatedUrl,
+	)
+	return i, err
+}
+
+const getUserByID = `-- name: GetUserByID :one
+SELECT id, username, email, avatar_url, session_version, created_at, updated_at, deactivated_at, role, bot, avatar_animated_url
+FROM users
+WHERE id = ?1
+LIMIT 1
+`
+
+func (q *Queries) GetUserByID(ctx context.Context, id string) (User, error) {
+	row := q.db.QueryRowContext(ctx, getUserByID, id)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.Username,
+		&i.Email,
+		&i.AvatarUrl,
+		&i.SessionVersion,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.DeactivatedAt,
+		&i.Role,
+		&i.Bot,
+		&i.AvatarAnimatedUrl,
 	)
 	return i, err
 }
@@ -145,19 +225,52 @@ func (q *Queries) IncrementUserSessionVersion(ctx context.Context, arg Increment
 	return result.RowsAffected()
 }
 
+const listActiveUserIDs = `-- name: ListActiveUserIDs :many
+SELECT id
+FROM users
+WHERE deactivated_at IS NULL
+ORDER BY id
+`
+
+func (q *Queries) ListActiveUserIDs(ctx context.Context) ([]string, error) {
+	rows, err := q.db.QueryContext(ctx, listActiveUserIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []string{}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const listActiveUsers = `-- name: ListActiveUsers :many
-SELECT id, username, avatar_url, created_at, updated_at
+SELECT id, username, avatar_url, created_at, updated_at, role, bot, avatar_animated_url
 FROM users
 WHERE deactivated_at IS NULL
 ORDER BY username
 `
 
 type ListActiveUsersRow struct {
-	ID        string
-	Username  string
-	AvatarUrl *string
-	CreatedAt time.Time
-	UpdatedAt *time.Time
+	ID                string
+	Username          string
+	AvatarUrl         *string
+	CreatedAt         time.Time
+	UpdatedAt         *time.Time
+	Role              string
+	Bot               bool
+	AvatarAnimatedUrl *string
 }
 
 func (q *Queries) ListActiveUsers(ctx context.Context) ([]ListActiveUsersRow, error) {
@@ -175,6 +288,9 @@ func (q *Queries) ListActiveUsers(ctx context.Context) ([]ListActiveUsersRow, er
 			&i.AvatarUrl,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.Role,
+			&i.Bot,
+			&i.AvatarAnimatedUrl,
 		); err != nil {
 			return nil, err
 		}
@@ -189,6 +305,92 @@ func (q *Queries) ListActiveUsers(ctx context.Context) ([]ListActiveUsersRow, er
 	return items, nil
 }
 
+const listBotUsers = `-- name: ListBotUsers :many
+SELECT id, username, email, avatar_url, session_version, created_at, updated_at, deactivated_at, role, bot, avatar_animated_url
+FROM users
+WHERE bot = 1
+ORDER BY username
+`
+
+func (q *Queries) ListBotUsers(ctx context.Context) ([]User, error) {
+	rows, err := q.db.QueryContext(ctx, listBotUsers)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []User{}
+	for rows.Next() {
+		var i User
+		if err := rows.Scan(
+			&i.ID,
+			&i.Username,
+			&i.Email,
+			&i.AvatarUrl,
+			&i.SessionVersion,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.DeactivatedAt,
+			&i.Role,
+			&i.Bot,
+			&i.AvatarAnimatedUrl,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listInactiveMemberIDs = `-- name: ListInactiveMemberIDs :many
+SELECT u.id
+FROM users u
+WHERE u.deactivated_at IS NULL
+  AND u.role = 'member'
+  AND u.created_at < ?1
+  AND NOT EXISTS (
+      SELECT 1
+      FROM refresh_tokens rt
+      WHERE rt.user_id = u.id
+        AND rt.created_at >= ?1
+  )
+  AND NOT EXISTS (
+      SELECT 1
+      FROM messages m
+      WHERE m.author_id = u.id
+        AND m.created_at >= ?1
+  )
+ORDER BY u.id
+`
+
+func (q *Queries) ListInactiveMemberIDs(ctx context.Context, cutoff time.Time) ([]string, error) {
+	rows, err := q.db.QueryContext(ctx, listInactiveMemberIDs, cutoff)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []string{}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const reactivateUser = `-- name: ReactivateUser :execrows
 UPDATE users
 SET deactivated_at = NULL,
@@ -210,21 +412,70 @@ func (q *Queries) ReactivateUser(ctx context.Context, arg ReactivateUserParams)
 	return result.RowsAffected()
 }
 
+const setUserRole = `-- name: SetUserRole :execrows
+UPDATE users
+SET role = ?1,
+    updated_at = ?2
+WHERE id = ?3
+`
+
+type SetUserRoleParams struct {
+	Role      string
+	UpdatedAt *time.Time
+	ID        string
+}
+
+func (q *Queries) SetUserRole(ctx context.Context, arg SetUserRoleParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, setUserRole, arg.Role, arg.UpdatedAt, arg.ID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
 const updateUserAvatarURL = `-- name: UpdateUserAvatarURL :execrows
 UPDATE users
 SET avatar_url = ?1,
+    avatar_animated_url = ?2,
+    updated_at = ?3
+WHERE id = ?4
+`
+
+type UpdateUserAvatarURLParams struct {
+	AvatarUrl         *string
+	AvatarAnimatedUrl *string
+	UpdatedAt         *time.Time
+	ID                string
+}
+
+func (q *Queries) UpdateUserAvatarURL(ctx context.Context, arg UpdateUserAvatarURLParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, updateUserAvatarURL,
+		arg.AvatarUrl,
+		arg.AvatarAnimatedUrl,
+		arg.UpdatedAt,
+		arg.ID,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const updateUserEmail = `-- name: UpdateUserEmail :execrows
+UPDATE users
+SET email = ?1,
     updated_at = ?2
 WHERE id = ?3
 `
 
-type UpdateUserAvatarURLParams struct {
-	AvatarUrl *string
+type UpdateUserEmailParams struct {
+	Email     string
 	UpdatedAt *time.Time
 	ID        string
 }
 
-func (q *Queries) UpdateUserAvatarURL(ctx context.Context, arg UpdateUserAvatarURLParams) (int64, error) {
-	result, err := q.db.ExecContext(ctx, updateUserAvatarURL, arg.AvatarUrl, arg.UpdatedAt, arg.ID)
+func (q *Queries) UpdateUserEmail(ctx context.Context, arg UpdateUserEmailParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, updateUserEmail, arg.Email, arg.UpdatedAt, arg.ID)
 	if err != nil {
 		return 0, err
 	}
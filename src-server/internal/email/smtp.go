@@ -14,6 +14,13 @@ const (
 	smtpTimeout = 30 * time.Second
 )
 
+// Sender delivers the transactional emails Lobby sends. SMTPService is the
+// production implementation.
+type Sender interface {
+	SendMagicCode(to, code, link string, ttl time.Duration) error
+	SendEmailChangeCode(to, code, newEmail string, ttl time.Duration) error
+}
+
 type SMTPService struct {
 	host     string
 	port     int
@@ -32,7 +39,7 @@ func NewSMTPService(host string, port int, username, password, from string) *SMT
 	}
 }
 
-func (s *SMTPService) SendMagicCode(to, code string, ttl time.Duration) error {
+func (s *SMTPService) SendMagicCode(to, code, link string, ttl time.Duration) error {
 	subject := "Your Lobby Login Code"
 	body := fmt.Sprintf(`Hello!
 
@@ -40,11 +47,36 @@ Your login code for Lobby is:
 
     %s
 
-This code will expire in %d minutes.
+Or open this link to sign in with the Lobby app:
+
+    %s
+
+The code and link expire in %d minutes and work only once.
 
 If you didn't request this email, you can safely ignore it.
 
-- The Lobby Team`, code, int(ttl.Minutes()))
+- The Lobby Team`, code, link, int(ttl.Minutes()))
+
+	return s.send(to, subject, body)
+}
+
+// SendEmailChangeCode sends one of the two codes confirming a change of the
+// account email to newEmail. Both the current and the new address get one.
+func (s *SMTPService) SendEmailChangeCode(to, code, newEmail string, ttl time.Duration) error {
+	subject := "Confirm your Lobby email change"
+	body := fmt.Sprintf(`Hello!
+
+A request was made to change the email of your Lobby account to %s.
+To confirm it from this address, enter this code:
+
+    %s
+
+This code will expire in %d minutes. The email only changes once both the
+current and the new address have been confirmed.
+
+If you didn't request this change, you can safely ignore this email.
+
+- The Lobby Team`, newEmail, code, int(ttl.Minutes()))
 
 	return s.send(to, subject, body)
 }
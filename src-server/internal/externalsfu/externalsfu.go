@@ -0,0 +1,41 @@
+// Package externalsfu lets the hub hand voice media to an SFU it does not
+// run, for deployments larger than the built-in pion SFU serves. The hub
+// keeps voice state and membership; clients connect to the external SFU
+// with the grant they get in RTC_READY.
+package externalsfu
+
+import (
+	"context"
+	"fmt"
+)
+
+const ProviderLiveKit = "livekit"
+
+// Grant is what a client needs to connect to the external SFU.
+type Grant struct {
+	URL   string
+	Token string
+	Room  string
+}
+
+// Backend is an external SFU. LiveKit is the only implementation.
+type Backend interface {
+	// Provider names the SFU, as in sfu.external.provider.
+	Provider() string
+	// Grant lets userID, shown as name, join the voice room.
+	Grant(userID, name string) (Grant, error)
+	// RemoveParticipant disconnects userID's media, such as after they leave
+	// voice or a moderator removes them. Removing one not connected is not
+	// an error.
+	RemoveParticipant(ctx context.Context, userID string) error
+}
+
+// New returns the backend for provider.
+func New(provider, url, apiKey, apiSecret, room string) (Backend, error) {
+	switch provider {
+	case ProviderLiveKit:
+		return NewLiveKit(url, apiKey, apiSecret, room), nil
+	default:
+		return nil, fmt.Errorf("unknown external SFU provider %q", provider)
+	}
+}
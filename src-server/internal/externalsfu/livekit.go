@@ -0,0 +1,142 @@
+package externalsfu
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	// liveKitTokenTTL bounds how long a join token works. LiveKit only checks
+	// it when connecting, so it does not cut calls short.
+	liveKitTokenTTL    = 6 * time.Hour
+	liveKitAdminTTL    = time.Minute
+	liveKitHTTPTimeout = 10 * time.Second
+)
+
+// LiveKit grants access to one room of a LiveKit server and removes
+// participants through its room service API.
+type LiveKit struct {
+	url       string // ws:// or wss:// URL clients connect to
+	apiKey    string
+	apiSecret string
+	room      string
+	client    *http.Client
+	now       func() time.Time
+}
+
+func NewLiveKit(url, apiKey, apiSecret, room string) *LiveKit {
+	return &LiveKit{
+		url:       url,
+		apiKey:    apiKey,
+		apiSecret: apiSecret,
+		room:      room,
+		client:    &http.Client{Timeout: liveKitHTTPTimeout},
+		now:       time.Now,
+	}
+}
+
+// liveKitVideoGrant is the "video" claim of a LiveKit access token.
+type liveKitVideoGrant struct {
+	Room         string `json:"room,omitempty"`
+	RoomJoin     bool   `json:"roomJoin,omitempty"`
+	RoomAdmin    bool   `json:"roomAdmin,omitempty"`
+	CanPublish   *bool  `json:"canPublish,omitempty"`
+	CanSubscribe *bool  `json:"canSubscribe,omitempty"`
+}
+
+type liveKitClaims struct {
+	jwt.RegisteredClaims
+	Name  string            `json:"name,omitempty"`
+	Video liveKitVideoGrant `json:"video"`
+}
+
+func (l *LiveKit) Provider() string {
+	return ProviderLiveKit
+}
+
+func (l *LiveKit) Grant(userID, name string) (Grant, error) {
+	allowed := true
+	token, err := l.sign(userID, name, liveKitVideoGrant{
+		Room:         l.room,
+		RoomJoin:     true,
+		CanPublish:   &allowed,
+		CanSubscribe: &allowed,
+	}, liveKitTokenTTL)
+	if err != nil {
+		return Grant{}, err
+	}
+	return Grant{URL: l.url, Token: token, Room: l.room}, nil
+}
+
+func (l *LiveKit) RemoveParticipant(ctx context.Context, userID string) error {
+	token, err := l.sign("", "", liveKitVideoGrant{Room: l.room, RoomAdmin: true}, liveKitAdminTTL)
+	if err != nil {
+		return err
+	}
+	body, err := json.Marshal(map[string]string{"room": l.room, "identity": userID})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, l.apiURL()+"/twirp/livekit.RoomService/RemoveParticipant", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := l.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("livekit remove participant: %w", err)
+	}
+	defer resp.Body.Close()
+	// A participant that already left is not an error
+	if resp.StatusCode == http.StatusNotFound {
+		return nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("livekit remove participant: status %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
+
+func (l *LiveKit) sign(identity, name string, grant liveKitVideoGrant, ttl time.Duration) (string, error) {
+	now := l.now()
+	claims := liveKitClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    l.apiKey,
+			Subject:   identity,
+			NotBefore: jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+		},
+		Name:  name,
+		Video: grant,
+	}
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(l.apiSecret))
+	if err != nil {
+		return "", fmt.Errorf("signing livekit token: %w", err)
+	}
+	return token, nil
+}
+
+// apiURL is the HTTP URL of the server's API, which shares the host of the
+// URL clients connect to.
+func (l *LiveKit) apiURL() string {
+	url := strings.TrimSuffix(l.url, "/")
+	if rest, ok := strings.CutPrefix(url, "wss://"); ok {
+		return "https://" + rest
+	}
+	if rest, ok := strings.CutPrefix(url, "ws://"); ok {
+		return "http://" + rest
+	}
+	return url
+}
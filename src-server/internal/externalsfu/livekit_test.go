@@ -0,0 +1,71 @@
+package externalsfu
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func parseLiveKitToken(t *testing.T, token, secret string) *liveKitClaims {
+	t.Helper()
+
+	claims := &liveKitClaims{}
+	if _, err := jwt.ParseWithClaims(token, claims, func(*jwt.Token) (any, error) {
+		return []byte(secret), nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()})); err != nil {
+		t.Fatalf("parsing token: %v", err)
+	}
+	return claims
+}
+
+func TestLiveKitGrant(t *testing.T) {
+	lk := NewLiveKit("wss://sfu.example.com", "key", "secret", "lobby")
+
+	grant, err := lk.Grant("usr_1", "alice")
+	if err != nil {
+		t.Fatalf("Grant() error = %v", err)
+	}
+	if grant.URL != "wss://sfu.example.com" || grant.Room != "lobby" {
+		t.Fatalf("grant = %+v", grant)
+	}
+
+	claims := parseLiveKitToken(t, grant.Token, "secret")
+	if claims.Issuer != "key" || claims.Subject != "usr_1" || claims.Name != "alice" {
+		t.Fatalf("claims = %+v, want issuer key for usr_1 named alice", claims)
+	}
+	if v := claims.Video; v.Room != "lobby" || !v.RoomJoin || v.RoomAdmin || v.CanPublish == nil || !*v.CanPublish {
+		t.Fatalf("video grant = %+v, want join and publish in lobby", v)
+	}
+}
+
+func TestLiveKitRemoveParticipant(t *testing.T) {
+	var got map[string]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/twirp/livekit.RoomService/RemoveParticipant" {
+			http.NotFound(w, r)
+			return
+		}
+		claims := parseLiveKitToken(t, strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer "), "secret")
+		if !claims.Video.RoomAdmin || claims.Video.Room != "lobby" {
+			t.Errorf("admin grant = %+v, want roomAdmin in lobby", claims.Video)
+		}
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Errorf("decoding body: %v", err)
+		}
+		w.Write([]byte("{}"))
+	}))
+	t.Cleanup(server.Close)
+
+	lk := NewLiveKit("ws"+strings.TrimPrefix(server.URL, "http"), "key", "secret", "lobby")
+	if err := lk.RemoveParticipant(context.Background(), "usr_1"); err != nil {
+		t.Fatalf("RemoveParticipant() error = %v", err)
+	}
+	if got["room"] != "lobby" || got["identity"] != "usr_1" {
+		t.Fatalf("request body = %v", got)
+	}
+}
@@ -0,0 +1,136 @@
+// Package history loads message history for the REST API and the bot gateway.
+package history
+
+import (
+	"context"
+
+	sqldb "lobby/internal/db/sqlc"
+	"lobby/internal/mediaurl"
+	"lobby/internal/models"
+)
+
+// Load returns up to limit messages before beforeID, or the newest ones when
+// beforeID is empty, with their attachments. Content is returned as written;
+// masking and embeds are left to the caller. It does not check channel access.
+func Load(ctx context.Context, queries *sqldb.Queries, baseURL string, signer *mediaurl.Signer, beforeID string, limit int64) ([]*models.Message, error) {
+	var rows []sqldb.ListMessageHistoryRow
+	if beforeID != "" {
+		beforeRows, err := queries.ListMessageHistoryBefore(ctx, sqldb.ListMessageHistoryBeforeParams{
+			BeforeID:  beforeID,
+			LimitRows: limit,
+		})
+		if err != nil {
+			return nil, err
+		}
+		rows = make([]sqldb.ListMessageHistoryRow, 0, len(beforeRows))
+		for _, row := range beforeRows {
+			rows = append(rows, sqldb.ListMessageHistoryRow(row))
+		}
+	} else {
+		var err error
+		rows, err = queries.ListMessageHistory(ctx, limit)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	messages := make([]*models.Message, 0, len(rows))
+	if len(rows) == 0 {
+		return messages, nil
+	}
+
+	messageIDs := make([]*string, 0, len(rows))
+	for _, row := range rows {
+		messageID := row.ID
+		messageIDs = append(messageIDs, &messageID)
+	}
+	attachments, err := queries.ListMessageAttachmentsByMessageIDs(ctx, messageIDs)
+	if err != nil {
+		return nil, err
+	}
+	attachmentsByMessageID := make(map[string][]models.MessageAttachment, len(rows))
+	for _, attachment := range attachments {
+		if attachment.MessageID == nil || *attachment.MessageID == "" {
+			continue
+		}
+		messageID := *attachment.MessageID
+		attachmentsByMessageID[messageID] = append(attachmentsByMessageID[messageID], Attachment(baseURL, signer, sqldb.ListMessageAttachmentsRow{
+			ID:                 attachment.ID,
+			OriginalName:       attachment.OriginalName,
+			MimeType:           attachment.MimeType,
+			SizeBytes:          attachment.SizeBytes,
+			CreatedAt:          attachment.CreatedAt,
+			PreviewStoragePath: attachment.PreviewStoragePath,
+			PreviewMimeType:    attachment.PreviewMimeType,
+			PreviewSizeBytes:   attachment.PreviewSizeBytes,
+			PreviewWidth:       attachment.PreviewWidth,
+			PreviewHeight:      attachment.PreviewHeight,
+			PreviewText:        attachment.PreviewText,
+			PreviewLanguage:    attachment.PreviewLanguage,
+			PreviewFrameCount:  attachment.PreviewFrameCount,
+			PreviewDurationMs:  attachment.PreviewDurationMs,
+			ScanStatus:         attachment.ScanStatus,
+			AudioDurationMs:    attachment.AudioDurationMs,
+			AudioWaveform:      attachment.AudioWaveform,
+		}))
+	}
+
+	for _, row := range rows {
+		messages = append(messages, &models.Message{
+			ID:                      row.ID,
+			AuthorID:                row.AuthorID,
+			AuthorName:              row.AuthorName,
+			AuthorAvatarURL:         row.AuthorAvatarUrl,
+			AuthorAvatarAnimatedURL: row.AuthorAvatarAnimatedUrl,
+			AuthorBot:               row.AuthorBot,
+			Content:                 row.Content,
+			Attachments:             attachmentsByMessageID[row.ID],
+			CreatedAt:               row.CreatedAt,
+			EditedAt:                row.EditedAt,
+		})
+	}
+	return messages, nil
+}
+
+// Attachment maps an attachment row to the API model, with media URLs on
+// baseURL signed by signer (unsigned when signer is nil).
+func Attachment(baseURL string, signer *mediaurl.Signer, row sqldb.ListMessageAttachmentsRow) models.MessageAttachment {
+	mapped := models.MessageAttachment{
+		ID:       row.ID,
+		Name:     row.OriginalName,
+		MimeType: row.MimeType,
+		Size:     row.SizeBytes,
+		URL:      signer.Blob(baseURL, row.ID),
+	}
+	if row.PreviewStoragePath != nil {
+		mapped.PreviewURL = signer.BlobPreview(baseURL, row.ID)
+	}
+	if row.PreviewWidth != nil {
+		mapped.PreviewWidth = *row.PreviewWidth
+	}
+	if row.PreviewHeight != nil {
+		mapped.PreviewHeight = *row.PreviewHeight
+	}
+	if row.PreviewText != nil {
+		mapped.PreviewText = *row.PreviewText
+	}
+	if row.PreviewLanguage != nil {
+		mapped.PreviewLanguage = *row.PreviewLanguage
+	}
+	if row.PreviewFrameCount != nil {
+		mapped.PreviewFrameCount = *row.PreviewFrameCount
+	}
+	if row.PreviewDurationMs != nil {
+		mapped.PreviewDurationMs = *row.PreviewDurationMs
+	}
+	if row.ScanStatus != nil {
+		mapped.ScanStatus = *row.ScanStatus
+	}
+	if row.AudioDurationMs != nil {
+		mapped.AudioDurationMs = *row.AudioDurationMs
+	}
+	if row.AudioWaveform != nil {
+		mapped.AudioWaveform = *row.AudioWaveform
+	}
+	return mapped
+}
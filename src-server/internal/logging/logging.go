@@ -0,0 +1,168 @@
+// Package logging installs the process-wide slog handler. Its level can be
+// changed at runtime, for everything or for one component, the value of the
+// "component" attribute, such as "sfu".
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// componentKey is the attribute naming the subsystem a record comes from.
+const componentKey = "component"
+
+var levels = newLevelSet(slog.LevelInfo)
+
+// Setup makes the default logger write JSON to w at the current levels.
+func Setup(w io.Writer) {
+	slog.SetDefault(slog.New(NewHandler(slog.NewJSONHandler(w, &slog.HandlerOptions{
+		Level: slog.LevelDebug,
+	}))))
+}
+
+// NewHandler filters records for inner by the current levels. inner should
+// accept every level.
+func NewHandler(inner slog.Handler) slog.Handler {
+	return &handler{inner: inner, levels: levels}
+}
+
+// ParseLevel parses debug, info, warn or error.
+func ParseLevel(s string) (slog.Level, error) {
+	switch strings.ToLower(s) {
+	case "debug":
+		return slog.LevelDebug, nil
+	case "info":
+		return slog.LevelInfo, nil
+	case "warn":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	default:
+		return 0, fmt.Errorf("unknown log level %q", s)
+	}
+}
+
+// LevelName is the ParseLevel name of level.
+func LevelName(level slog.Level) string {
+	return strings.ToLower(level.String())
+}
+
+// SetLevel sets the level of component, or of everything without its own
+// level when component is empty.
+func SetLevel(component string, level slog.Level) {
+	levels.set(component, level)
+}
+
+// ClearLevel makes component follow the base level again.
+func ClearLevel(component string) {
+	levels.clear(component)
+}
+
+// Levels returns the base level and the components with their own.
+func Levels() (slog.Level, map[string]slog.Level) {
+	return levels.snapshot()
+}
+
+// levelSet is a base level plus per-component overrides. min caches the
+// lowest of them, so records below every level are dropped without a lock.
+type levelSet struct {
+	mu         sync.RWMutex
+	base       slog.Level
+	components map[string]slog.Level
+	min        atomic.Int64
+}
+
+func newLevelSet(base slog.Level) *levelSet {
+	l := &levelSet{base: base, components: make(map[string]slog.Level)}
+	l.min.Store(int64(base))
+	return l
+}
+
+func (l *levelSet) set(component string, level slog.Level) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if component == "" {
+		l.base = level
+	} else {
+		l.components[component] = level
+	}
+	l.updateMinLocked()
+}
+
+func (l *levelSet) clear(component string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.components, component)
+	l.updateMinLocked()
+}
+
+func (l *levelSet) updateMinLocked() {
+	lowest := l.base
+	for _, level := range l.components {
+		lowest = min(lowest, level)
+	}
+	l.min.Store(int64(lowest))
+}
+
+func (l *levelSet) snapshot() (slog.Level, map[string]slog.Level) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.base, maps.Clone(l.components)
+}
+
+// enabled reports whether a record from component at level is logged.
+func (l *levelSet) enabled(component string, level slog.Level) bool {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	if componentLevel, ok := l.components[component]; ok && component != "" {
+		return level >= componentLevel
+	}
+	return level >= l.base
+}
+
+type handler struct {
+	inner     slog.Handler
+	levels    *levelSet
+	component string // set by WithAttrs
+}
+
+func (h *handler) Enabled(ctx context.Context, level slog.Level) bool {
+	return int64(level) >= h.levels.min.Load() && h.inner.Enabled(ctx, level)
+}
+
+func (h *handler) Handle(ctx context.Context, r slog.Record) error {
+	component := h.component
+	if component == "" {
+		r.Attrs(func(a slog.Attr) bool {
+			if a.Key == componentKey {
+				component = a.Value.String()
+				return false
+			}
+			return true
+		})
+	}
+	if !h.levels.enabled(component, r.Level) {
+		return nil
+	}
+	return h.inner.Handle(ctx, r)
+}
+
+func (h *handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	component := h.component
+	for _, a := range attrs {
+		if a.Key == componentKey {
+			component = a.Value.String()
+		}
+	}
+	return &handler{inner: h.inner.WithAttrs(attrs), levels: h.levels, component: component}
+}
+
+func (h *handler) WithGroup(name string) slog.Handler {
+	return &handler{inner: h.inner.WithGroup(name), levels: h.levels, component: h.component}
+}
@@ -0,0 +1,63 @@
+package logging
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestComponentLevelOverridesBase(t *testing.T) {
+	var buf bytes.Buffer
+	levels := newLevelSet(slog.LevelInfo)
+	logger := slog.New(&handler{
+		inner:  slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		levels: levels,
+	})
+	logged := func() string {
+		defer buf.Reset()
+		return buf.String()
+	}
+
+	logger.Debug("offer sdp", "component", "sfu")
+	if out := logged(); out != "" {
+		t.Fatalf("debug logged at info: %s", out)
+	}
+
+	levels.set("sfu", slog.LevelDebug)
+	logger.Debug("offer sdp", "component", "sfu")
+	logger.With("component", "sfu").Debug("answer sdp")
+	logger.Debug("command", "component", "ws")
+	out := logged()
+	if !strings.Contains(out, "offer sdp") || !strings.Contains(out, "answer sdp") {
+		t.Fatalf("sfu debug not logged with sfu at debug: %s", out)
+	}
+	if strings.Contains(out, "command") {
+		t.Fatalf("ws debug logged with only sfu at debug: %s", out)
+	}
+
+	levels.set("", slog.LevelError)
+	logger.Warn("renegotiating", "component", "sfu")
+	logger.Warn("slow client", "component", "ws")
+	if out := logged(); !strings.Contains(out, "renegotiating") || strings.Contains(out, "slow client") {
+		t.Fatalf("base level not applied apart from sfu: %s", out)
+	}
+
+	levels.clear("sfu")
+	logger.Warn("renegotiating", "component", "sfu")
+	if out := logged(); out != "" {
+		t.Fatalf("cleared sfu level still applied: %s", out)
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	for _, name := range []string{"debug", "info", "warn", "error"} {
+		level, err := ParseLevel(strings.ToUpper(name))
+		if err != nil || LevelName(level) != name {
+			t.Errorf("ParseLevel(%q) = %v, %v", name, level, err)
+		}
+	}
+	if _, err := ParseLevel("verbose"); err == nil {
+		t.Error("ParseLevel(verbose) succeeded")
+	}
+}
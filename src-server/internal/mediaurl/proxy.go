@@ -0,0 +1,101 @@
+package mediaurl
+
+import (
+	"crypto/hkdf"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"html"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+)
+
+const (
+	ProxyPathPrefix = "/media/proxy/"
+	// ProxyURLParam carries the external URL of a proxied image.
+	ProxyURLParam = "url"
+
+	// maxImageLinks caps the images proxied for one message.
+	maxImageLinks = 5
+)
+
+// imageExtensions are the link paths treated as images. Links are only
+// guessed at from their path; the proxy checks what the host sends.
+var imageExtensions = map[string]struct{}{
+	".png":  {},
+	".jpg":  {},
+	".jpeg": {},
+	".gif":  {},
+	".webp": {},
+	".avif": {},
+}
+
+// hrefPattern finds links in sanitized message HTML, which always quotes
+// attribute values.
+var hrefPattern = regexp.MustCompile(`href="([^"]+)"`)
+
+// Proxy signs the external image URLs the server hands out, camo style, so
+// clients fetch them through the server's origin and never reveal their
+// address to the image host. Only signed URLs are fetched, so it is not an
+// open proxy. A nil Proxy leaves external images unproxied.
+type Proxy struct {
+	secret []byte
+}
+
+// NewProxy derives the signing key from secret with HKDF, so the JWT secret
+// it is handed never signs anything else directly.
+func NewProxy(secret string) *Proxy {
+	key, err := hkdf.Key(sha256.New, []byte(secret), nil, "lobby media-proxy", sha256.Size)
+	if err != nil {
+		// Only an output length HKDF can't produce fails
+		panic(err)
+	}
+	return &Proxy{secret: key}
+}
+
+// URL returns the proxied URL of the external image at target.
+func (p *Proxy) URL(baseURL, target string) string {
+	baseURL = strings.TrimRight(strings.TrimSpace(baseURL), "/")
+	query := url.Values{}
+	query.Set(ProxyURLParam, target)
+	return baseURL + ProxyPathPrefix + p.signature(target) + "?" + query.Encode()
+}
+
+// Verify reports whether signature was made by URL for target.
+func (p *Proxy) Verify(signature, target string) bool {
+	return hmac.Equal([]byte(signature), []byte(p.signature(target)))
+}
+
+func (p *Proxy) signature(target string) string {
+	mac := hmac.New(sha256.New, p.secret)
+	mac.Write([]byte("media-proxy:" + target))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// ImageLinks returns the http and https links in sanitized message HTML
+// whose path names an image, up to five, without duplicates.
+func ImageLinks(content string) []string {
+	var links []string
+	seen := make(map[string]struct{})
+	for _, match := range hrefPattern.FindAllStringSubmatch(content, -1) {
+		link := html.UnescapeString(match[1])
+		u, err := url.Parse(link)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			continue
+		}
+		if _, ok := imageExtensions[strings.ToLower(path.Ext(u.Path))]; !ok {
+			continue
+		}
+		if _, ok := seen[link]; ok {
+			continue
+		}
+		seen[link] = struct{}{}
+		links = append(links, link)
+		if len(links) == maxImageLinks {
+			break
+		}
+	}
+	return links
+}
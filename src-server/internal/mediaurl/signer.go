@@ -0,0 +1,96 @@
+package mediaurl
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"net/url"
+	"strconv"
+	"time"
+
+	"lobby/internal/clock"
+)
+
+// Query parameters of a signed media URL.
+const (
+	ExpiresParam   = "expires"
+	SignatureParam = "sig"
+)
+
+// Signer signs chat attachment URLs with an expiry, so /media can serve them
+// without the caller's token and stop once they leak. A nil Signer hands out
+// plain, permanent URLs.
+type Signer struct {
+	secret []byte
+	ttl    time.Duration
+	clock  clock.Clock
+}
+
+// NewSigner creates a signer whose URLs stay valid for at least ttl.
+func NewSigner(secret string, ttl time.Duration) *Signer {
+	return &Signer{secret: []byte(secret), ttl: ttl, clock: clock.Real}
+}
+
+// SetClock replaces the clock used to set and check expiry.
+func (s *Signer) SetClock(c clock.Clock) {
+	s.clock = c
+}
+
+// Blob returns the URL of blobID, signed unless s is nil.
+func (s *Signer) Blob(baseURL, blobID string) string {
+	return s.sign(Blob(baseURL, blobID), blobID)
+}
+
+// BlobPreview returns the preview URL of blobID, signed unless s is nil.
+func (s *Signer) BlobPreview(baseURL, blobID string) string {
+	return s.sign(BlobPreview(baseURL, blobID), blobID)
+}
+
+func (s *Signer) sign(rawURL, blobID string) string {
+	if s == nil {
+		return rawURL
+	}
+	expires := s.expiry().Unix()
+	query := url.Values{}
+	query.Set(ExpiresParam, strconv.FormatInt(expires, 10))
+	query.Set(SignatureParam, s.signature(blobID, expires))
+	return rawURL + "?" + query.Encode()
+}
+
+// expiry rounds now+ttl up to a multiple of half the ttl, so an attachment
+// keeps the same URL, and the client's cache entry, for a while.
+func (s *Signer) expiry() time.Time {
+	expires := s.clock.Now().Add(s.ttl)
+	step := s.ttl / 2
+	if step < time.Second {
+		return expires
+	}
+	if rounded := expires.Truncate(step); rounded.Before(expires) {
+		return rounded.Add(step)
+	}
+	return expires
+}
+
+// Verify reports whether query holds an unexpired signature for blobID,
+// and how long it stays valid. The signature covers the blob and its preview
+// alike.
+func (s *Signer) Verify(blobID string, query url.Values) (time.Duration, bool) {
+	expires, err := strconv.ParseInt(query.Get(ExpiresParam), 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	remaining := time.Unix(expires, 0).Sub(s.clock.Now())
+	if remaining <= 0 {
+		return 0, false
+	}
+	if !hmac.Equal([]byte(query.Get(SignatureParam)), []byte(s.signature(blobID, expires))) {
+		return 0, false
+	}
+	return remaining, true
+}
+
+func (s *Signer) signature(blobID string, expires int64) string {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte("media-url:" + blobID + ":" + strconv.FormatInt(expires, 10)))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
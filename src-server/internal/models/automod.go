@@ -0,0 +1,106 @@
+package models
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+)
+
+// Automod rule kinds.
+const (
+	AutomodKindWord   = "word"   // whole word or phrase, ignoring case
+	AutomodKindRegex  = "regex"  // RE2 pattern
+	AutomodKindInvite = "invite" // third-party chat invite links; pattern unused
+)
+
+// Automod actions, applied to the sender's message before it is stored.
+const (
+	AutomodActionBlock      = "block"       // reject; the sender keeps the draft
+	AutomodActionFlag       = "flag"        // deliver, but alert moderators
+	AutomodActionDeleteWarn = "delete_warn" // discard and warn the sender
+)
+
+var automodInvitePattern = regexp.MustCompile(
+	`(?i)\b(?:discord(?:app)?\.com/invite|discord\.gg|t\.me/joinchat|chat\.whatsapp\.com|guilded\.gg/i)/[a-z0-9_-]+`,
+)
+
+var ErrInvalidAutomodRule = errors.New("invalid automod rule")
+
+// AutomodRule is a compiled automod rule.
+type AutomodRule struct {
+	ID      string
+	Kind    string
+	Pattern string
+	Action  string
+	re      *regexp.Regexp
+}
+
+// IsValidAutomodAction reports whether action is one of the known actions.
+func IsValidAutomodAction(action string) bool {
+	switch action {
+	case AutomodActionBlock, AutomodActionFlag, AutomodActionDeleteWarn:
+		return true
+	}
+	return false
+}
+
+// CompileAutomodRule validates and compiles a stored rule.
+func CompileAutomodRule(id, kind, pattern, action string) (AutomodRule, error) {
+	if !IsValidAutomodAction(action) {
+		return AutomodRule{}, ErrInvalidAutomodRule
+	}
+	rule := AutomodRule{ID: id, Kind: kind, Pattern: pattern, Action: action}
+	switch kind {
+	case AutomodKindWord:
+		if strings.TrimSpace(pattern) == "" {
+			return AutomodRule{}, ErrInvalidAutomodRule
+		}
+		rule.Pattern = strings.ToLower(strings.TrimSpace(pattern))
+	case AutomodKindRegex:
+		re, err := regexp.Compile(pattern)
+		if err != nil || pattern == "" {
+			return AutomodRule{}, ErrInvalidAutomodRule
+		}
+		rule.re = re
+	case AutomodKindInvite:
+		rule.Pattern = ""
+		rule.re = automodInvitePattern
+	default:
+		return AutomodRule{}, ErrInvalidAutomodRule
+	}
+	return rule, nil
+}
+
+// Match returns the text in content that triggered the rule, or "" if the
+// rule does not match.
+func (r AutomodRule) Match(content string) string {
+	if r.re != nil {
+		return r.re.FindString(content)
+	}
+	if containsWord(strings.ToLower(content), r.Pattern) {
+		return r.Pattern
+	}
+	return ""
+}
+
+// automodActionRank orders actions by severity so the strictest match wins.
+var automodActionRank = map[string]int{
+	AutomodActionFlag:       0,
+	AutomodActionDeleteWarn: 1,
+	AutomodActionBlock:      2,
+}
+
+// EvaluateAutomod returns the strictest rule matching content and the text
+// that triggered it. ok is false when no rule matches.
+func EvaluateAutomod(rules []AutomodRule, content string) (rule AutomodRule, matched string, ok bool) {
+	for _, candidate := range rules {
+		text := candidate.Match(content)
+		if text == "" {
+			continue
+		}
+		if !ok || automodActionRank[candidate.Action] > automodActionRank[rule.Action] {
+			rule, matched, ok = candidate, text, true
+		}
+	}
+	return rule, matched, ok
+}
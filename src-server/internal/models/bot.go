@@ -0,0 +1,32 @@
+package models
+
+import "strings"
+
+// Bot token scopes. A bot connection may only use the gateway operations its
+// token's scopes allow; human sessions are unrestricted.
+const (
+	ScopeMessagesRead  = "messages:read"
+	ScopeMessagesWrite = "messages:write"
+	ScopeMembersRead   = "members:read"
+)
+
+var botScopes = map[string]bool{
+	ScopeMessagesRead:  true,
+	ScopeMessagesWrite: true,
+	ScopeMembersRead:   true,
+}
+
+// IsValidBotScope reports whether scope is one of the known bot scopes.
+func IsValidBotScope(scope string) bool {
+	return botScopes[scope]
+}
+
+// ParseBotScopes splits the space-separated scopes stored with a bot token.
+func ParseBotScopes(scopes string) []string {
+	return strings.Fields(scopes)
+}
+
+// FormatBotScopes joins scopes for storage.
+func FormatBotScopes(scopes []string) string {
+	return strings.Join(scopes, " ")
+}
@@ -0,0 +1,134 @@
+package models
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// How a message mentions a notification recipient. A user reached several
+// ways gets the most specific one.
+const (
+	MentionUser     = "user"
+	MentionRole     = "role"
+	MentionEveryone = "everyone"
+	MentionHere     = "here"
+)
+
+// roleMentions maps group mention names to the role they reach. A role
+// mention reaches that role and every role above it.
+var roleMentions = map[string]string{
+	"moderator":  RoleModerator,
+	"moderators": RoleModerator,
+	"admin":      RoleAdmin,
+	"admins":     RoleAdmin,
+}
+
+// Mentions are the @mentions found in a message. Group names (here,
+// everyone, and role names) take precedence over usernames.
+type Mentions struct {
+	Usernames []string // lowercased
+	Roles     []string
+	Everyone  bool
+	Here      bool
+}
+
+// MentionRecipient is a potential recipient as ResolveMention sees it.
+type MentionRecipient struct {
+	Username         string
+	Role             string
+	Online           bool
+	SuppressEveryone bool // ignore @everyone and @here
+	SuppressRoles    bool // ignore role mentions
+}
+
+// ParseMentions finds @name tokens in content. A token must start the text or
+// follow a non-word character, so email addresses are not mentions.
+func ParseMentions(content string) Mentions {
+	var m Mentions
+	seen := make(map[string]bool)
+	for offset := 0; offset < len(content); {
+		i := strings.IndexByte(content[offset:], '@')
+		if i < 0 {
+			break
+		}
+		at := offset + i
+		offset = at + 1
+
+		before, _ := utf8.DecodeLastRuneInString(content[:at])
+		if isWordRune(before) {
+			continue
+		}
+		end := offset
+		for end < len(content) && isMentionByte(content[end]) {
+			end++
+		}
+		name := strings.ToLower(content[offset:end])
+		offset = end
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+
+		switch name {
+		case "everyone":
+			m.Everyone = true
+		case "here":
+			m.Here = true
+		default:
+			if role, ok := roleMentions[name]; ok {
+				m.Roles = append(m.Roles, role)
+			} else {
+				m.Usernames = append(m.Usernames, name)
+			}
+		}
+	}
+	return m
+}
+
+// IsEmpty reports whether the message mentions nobody.
+func (m Mentions) IsEmpty() bool {
+	return len(m.Usernames) == 0 && !m.HasGroup()
+}
+
+// HasGroup reports whether the message uses @here, @everyone, or a role
+// mention, which need the mention-everyone permission.
+func (m Mentions) HasGroup() bool {
+	return m.Everyone || m.Here || len(m.Roles) > 0
+}
+
+// ResolveMention returns how m reaches r, or "" if it does not. Group
+// mentions count only when groupsAllowed and are subject to r's suppression
+// settings; direct mentions always count.
+func (m Mentions) ResolveMention(r MentionRecipient, groupsAllowed bool) string {
+	username := strings.ToLower(r.Username)
+	for _, name := range m.Usernames {
+		if name == username {
+			return MentionUser
+		}
+	}
+	if !groupsAllowed {
+		return ""
+	}
+	if !r.SuppressRoles {
+		for _, role := range m.Roles {
+			if RoleAtLeast(r.Role, role) {
+				return MentionRole
+			}
+		}
+	}
+	if r.SuppressEveryone {
+		return ""
+	}
+	if m.Everyone {
+		return MentionEveryone
+	}
+	if m.Here && r.Online {
+		return MentionHere
+	}
+	return ""
+}
+
+func isMentionByte(b byte) bool {
+	return b == '_' || b == '-' ||
+		('a' <= b && b <= 'z') || ('A' <= b && b <= 'Z') || ('0' <= b && b <= '9')
+}
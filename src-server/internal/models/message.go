@@ -3,14 +3,24 @@ package models
 import "time"
 
 type Message struct {
-	ID              string              `json:"id"`
-	AuthorID        string              `json:"authorId"`
-	AuthorName      string              `json:"authorName"`
-	AuthorAvatarURL *string             `json:"authorAvatarUrl,omitempty"`
-	Content         string              `json:"content"`
-	Attachments     []MessageAttachment `json:"attachments,omitempty"`
-	CreatedAt       time.Time           `json:"createdAt"`
-	EditedAt        *time.Time          `json:"editedAt,omitempty"`
+	ID                      string              `json:"id"`
+	AuthorID                string              `json:"authorId"`
+	AuthorName              string              `json:"authorName"`
+	AuthorAvatarURL         *string             `json:"authorAvatarUrl,omitempty"`
+	AuthorAvatarAnimatedURL *string             `json:"authorAvatarAnimatedUrl,omitempty"`
+	AuthorBot               bool                `json:"authorBot,omitempty"`
+	Content                 string              `json:"content"`
+	Attachments             []MessageAttachment `json:"attachments,omitempty"`
+	Embeds                  []MessageEmbed      `json:"embeds,omitempty"`
+	CreatedAt               time.Time           `json:"createdAt"`
+	EditedAt                *time.Time          `json:"editedAt,omitempty"`
+}
+
+// MessageEmbed is an image a message links to, with the URL the media
+// proxy serves it from.
+type MessageEmbed struct {
+	URL      string `json:"url"`
+	ProxyURL string `json:"proxyUrl"`
 }
 
 type MessageAttachment struct {
@@ -22,4 +32,16 @@ type MessageAttachment struct {
 	PreviewURL    string `json:"previewUrl,omitempty"`
 	PreviewWidth  int64  `json:"previewWidth,omitempty"`
 	PreviewHeight int64  `json:"previewHeight,omitempty"`
+	// Text attachments: the first lines and a syntax highlighting hint.
+	PreviewText     string `json:"previewText,omitempty"`
+	PreviewLanguage string `json:"previewLanguage,omitempty"`
+	// Animated GIF and WebP attachments: the preview is the first frame.
+	PreviewFrameCount int64 `json:"previewFrameCount,omitempty"`
+	PreviewDurationMs int64 `json:"previewDurationMs,omitempty"`
+	// ScanStatus is "clean" or "unscanned" when the server scans uploads.
+	ScanStatus string `json:"scanStatus,omitempty"`
+	// Audio attachments: the length and a base64 waveform of up to 64 peaks
+	// from 0 to 255.
+	AudioDurationMs int64  `json:"audioDurationMs,omitempty"`
+	AudioWaveform   string `json:"audioWaveform,omitempty"`
 }
@@ -0,0 +1,77 @@
+package models
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Per-channel notification levels. A user without a stored setting gets
+// NotificationAll.
+const (
+	NotificationAll      = "all"
+	NotificationMentions = "mentions"
+	NotificationMuted    = "muted"
+)
+
+// IsValidNotificationLevel reports whether level is one of the known levels.
+func IsValidNotificationLevel(level string) bool {
+	switch level {
+	case NotificationAll, NotificationMentions, NotificationMuted:
+		return true
+	}
+	return false
+}
+
+// ShouldNotify reports whether a user with the given channel notification
+// level should be notified (mention alert, push, email digest) about a
+// message. mentioned is true when the message mentions the user.
+func ShouldNotify(level string, mentioned bool) bool {
+	switch level {
+	case NotificationMuted:
+		return false
+	case NotificationMentions:
+		return mentioned
+	default:
+		return true
+	}
+}
+
+// NotificationRuleMatches reports whether a message matches a user's
+// notification rule. Every condition the rule sets must hold: keyword must
+// appear in content as a whole word or phrase, ignoring case, and
+// authorFilter must be the message author. A rule without conditions never
+// matches.
+func NotificationRuleMatches(keyword, authorFilter, content, authorID string) bool {
+	if keyword == "" && authorFilter == "" {
+		return false
+	}
+	if authorFilter != "" && authorFilter != authorID {
+		return false
+	}
+	return keyword == "" || containsWord(strings.ToLower(content), strings.ToLower(keyword))
+}
+
+func containsWord(s, word string) bool {
+	for offset := 0; offset < len(s); {
+		i := strings.Index(s[offset:], word)
+		if i < 0 {
+			return false
+		}
+		start := offset + i
+		end := start + len(word)
+
+		before, _ := utf8.DecodeLastRuneInString(s[:start])
+		after, _ := utf8.DecodeRuneInString(s[end:])
+		if !isWordRune(before) && !isWordRune(after) {
+			return true
+		}
+		_, size := utf8.DecodeRuneInString(s[start:])
+		offset = start + size
+	}
+	return false
+}
+
+func isWordRune(r rune) bool {
+	return r != utf8.RuneError && (unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_')
+}
@@ -0,0 +1,16 @@
+package models
+
+const (
+	RegistrationOpen       = "open"
+	RegistrationInviteOnly = "invite_only"
+	RegistrationAllowlist  = "allowlist"
+)
+
+// IsValidRegistrationMode reports whether mode is one of the known registration modes.
+func IsValidRegistrationMode(mode string) bool {
+	switch mode {
+	case RegistrationOpen, RegistrationInviteOnly, RegistrationAllowlist:
+		return true
+	}
+	return false
+}
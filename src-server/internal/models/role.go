@@ -0,0 +1,45 @@
+package models
+
+const (
+	RoleMember    = "member"
+	RoleModerator = "moderator"
+	RoleAdmin     = "admin"
+)
+
+var roleRanks = map[string]int{
+	RoleMember:    0,
+	RoleModerator: 1,
+	RoleAdmin:     2,
+}
+
+// IsValidRole reports whether role is one of the known roles.
+func IsValidRole(role string) bool {
+	_, ok := roleRanks[role]
+	return ok
+}
+
+// RoleAtLeast reports whether role ranks at or above required.
+// An empty required role imposes no restriction; unknown roles never qualify.
+func RoleAtLeast(role, required string) bool {
+	if required == "" {
+		return true
+	}
+	have, ok := roleRanks[role]
+	if !ok {
+		return false
+	}
+	need, ok := roleRanks[required]
+	if !ok {
+		return false
+	}
+	return have >= need
+}
+
+// CanAccessTextChannel reports whether a user may read and post in the text
+// channel. Private channels admit invited users plus moderators and admins.
+func CanAccessTextChannel(role string, private, invited bool) bool {
+	if !private || invited {
+		return true
+	}
+	return RoleAtLeast(role, RoleModerator)
+}
@@ -0,0 +1,32 @@
+package models
+
+import "encoding/json"
+
+// ServerLink is a labelled link shown on the server's about screen, such as
+// a website or social profile.
+type ServerLink struct {
+	Label string `json:"label"`
+	URL   string `json:"url"`
+}
+
+// ParseServerLinks decodes the JSON array stored in server_settings. Invalid
+// data yields no links rather than an error so the about screen still loads.
+func ParseServerLinks(raw string) []ServerLink {
+	links := []ServerLink{}
+	if err := json.Unmarshal([]byte(raw), &links); err != nil {
+		return []ServerLink{}
+	}
+	return links
+}
+
+// FormatServerLinks encodes links for storage.
+func FormatServerLinks(links []ServerLink) string {
+	if len(links) == 0 {
+		return "[]"
+	}
+	data, err := json.Marshal(links)
+	if err != nil {
+		return "[]"
+	}
+	return string(data)
+}
@@ -0,0 +1,49 @@
+package models
+
+// UploadLimits resolves the upload size cap for a role. A ByRole entry applies
+// to that role and every role above it, unless a higher role has its own;
+// roles with no applicable entry get Default.
+type UploadLimits struct {
+	Default int64
+	ByRole  map[string]int64
+	// Avatar caps avatar and server image uploads for every role. Zero
+	// leaves them under the role cap.
+	Avatar int64
+	// MaxAttachments caps the attachments on one message. Zero means no cap.
+	MaxAttachments int
+}
+
+// ForRole returns the upload cap in bytes for role. Unknown roles get Default.
+func (l UploadLimits) ForRole(role string) int64 {
+	rank, ok := roleRanks[role]
+	if !ok {
+		return l.Default
+	}
+	limit, limitRank := l.Default, -1
+	for entryRole, entryLimit := range l.ByRole {
+		entryRank, ok := roleRanks[entryRole]
+		if !ok || entryRank > rank || entryRank <= limitRank {
+			continue
+		}
+		limit, limitRank = entryLimit, entryRank
+	}
+	return limit
+}
+
+// ForAvatar returns the cap in bytes for an avatar or server image uploaded
+// by role.
+func (l UploadLimits) ForAvatar(role string) int64 {
+	if l.Avatar > 0 {
+		return l.Avatar
+	}
+	return l.ForRole(role)
+}
+
+// Max returns the largest cap any role can get, which bounds blob storage.
+func (l UploadLimits) Max() int64 {
+	limit := max(l.Default, l.Avatar)
+	for _, entryLimit := range l.ByRole {
+		limit = max(limit, entryLimit)
+	}
+	return limit
+}
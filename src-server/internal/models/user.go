@@ -3,14 +3,19 @@ package models
 import "time"
 
 type User struct {
-	ID             string     `json:"id"`
-	Username       string     `json:"username"`
-	Email          string     `json:"email,omitempty"`
-	AvatarURL      *string    `json:"avatarUrl,omitempty"`
-	CreatedAt      time.Time  `json:"createdAt"`
-	UpdatedAt      *time.Time `json:"updatedAt,omitempty"`
-	DeactivatedAt  *time.Time `json:"-"`
-	SessionVersion int        `json:"-"`
+	ID        string  `json:"id"`
+	Username  string  `json:"username"`
+	Email     string  `json:"email,omitempty"`
+	AvatarURL *string `json:"avatarUrl,omitempty"`
+	// AvatarAnimatedURL is set for GIF and WebP avatars; AvatarURL is then
+	// their still first frame.
+	AvatarAnimatedURL *string    `json:"avatarAnimatedUrl,omitempty"`
+	Role              string     `json:"role"`
+	CreatedAt         time.Time  `json:"createdAt"`
+	UpdatedAt         *time.Time `json:"updatedAt,omitempty"`
+	DeactivatedAt     *time.Time `json:"-"`
+	SessionVersion    int        `json:"-"`
+	Bot               bool       `json:"bot,omitempty"`
 }
 
 func (u *User) GetAvatarURL() string {
@@ -20,6 +25,13 @@ func (u *User) GetAvatarURL() string {
 	return ""
 }
 
+func (u *User) GetAvatarAnimatedURL() string {
+	if u.AvatarAnimatedURL != nil {
+		return *u.AvatarAnimatedURL
+	}
+	return ""
+}
+
 type MagicCode struct {
 	ID        string
 	Email     string
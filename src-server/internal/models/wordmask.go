@@ -0,0 +1,63 @@
+package models
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// WordMask replaces configured words in message content with asterisks. Words
+// match whole words only, ignoring case. A nil WordMask masks nothing.
+type WordMask struct {
+	re *regexp.Regexp
+}
+
+// NewWordMask builds a mask for words, or returns nil if there are none.
+func NewWordMask(words []string) *WordMask {
+	patterns := make([]string, 0, len(words))
+	for _, word := range words {
+		word = strings.TrimSpace(word)
+		if word != "" {
+			patterns = append(patterns, regexp.QuoteMeta(word))
+		}
+	}
+	if len(patterns) == 0 {
+		return nil
+	}
+	// Longest first, so a phrase wins over a word it starts with.
+	sort.Slice(patterns, func(i, j int) bool { return len(patterns[i]) > len(patterns[j]) })
+	return &WordMask{re: regexp.MustCompile(`(?i)(?:` + strings.Join(patterns, "|") + `)`)}
+}
+
+// Mask returns content with every masked word replaced by one asterisk per
+// character.
+func (m *WordMask) Mask(content string) string {
+	if m == nil || content == "" {
+		return content
+	}
+
+	matches := m.re.FindAllStringIndex(content, -1)
+	if len(matches) == 0 {
+		return content
+	}
+
+	var b strings.Builder
+	last := 0
+	for _, match := range matches {
+		start, end := match[0], match[1]
+		before, _ := utf8.DecodeLastRuneInString(content[:start])
+		after, _ := utf8.DecodeRuneInString(content[end:])
+		if isWordRune(before) || isWordRune(after) {
+			continue
+		}
+		b.WriteString(content[last:start])
+		b.WriteString(strings.Repeat("*", utf8.RuneCountInString(content[start:end])))
+		last = end
+	}
+	if last == 0 {
+		return content
+	}
+	b.WriteString(content[last:])
+	return b.String()
+}
@@ -0,0 +1,307 @@
+package mq
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+
+	"lobby/internal/config"
+	"lobby/internal/ws"
+)
+
+const (
+	publishRetryMin = 500 * time.Millisecond
+	publishRetryMax = 30 * time.Second
+)
+
+// Message is the JSON body published for every gateway event.
+type Message struct {
+	ID       string `json:"id"`
+	Type     string `json:"type"`
+	Topic    string `json:"topic"`
+	Audience string `json:"audience"` // all, channel, user or moderators
+	// UserID is the recipient of a per-user event, such as DRAFT_UPDATE.
+	UserID    string          `json:"user_id,omitempty"`
+	Data      json.RawMessage `json:"data"`
+	Timestamp time.Time       `json:"timestamp"`
+}
+
+var audienceNames = map[ws.Audience]string{
+	ws.AudienceAll:        "all",
+	ws.AudienceChannel:    "channel",
+	ws.AudienceUser:       "user",
+	ws.AudienceModerators: "moderators",
+}
+
+type outbound struct {
+	subject string
+	msg     Message
+}
+
+// Publisher forwards hub events to NATS JetStream with at-least-once delivery:
+// events are published asynchronously with up to MaxPending awaiting their ack,
+// and each one is retried until the stream acknowledges it. Events are buffered
+// in memory, so anything still queued when the process dies is lost.
+type Publisher struct {
+	cfg    config.EventStreamConfig
+	topics map[ws.Topic]struct{} // nil means every topic
+
+	queue chan outbound
+	stop  chan struct{} // closed by Close: drain the queue, then exit
+	abort chan struct{} // closed when Close gives up: stop retrying
+	done  chan struct{}
+
+	closeOnce sync.Once
+	abortOnce sync.Once
+}
+
+func NewPublisher(cfg config.EventStreamConfig) *Publisher {
+	var topics map[ws.Topic]struct{}
+	if len(cfg.Topics) > 0 {
+		topics = make(map[ws.Topic]struct{}, len(cfg.Topics))
+		for _, topic := range cfg.Topics {
+			topics[ws.Topic(topic)] = struct{}{}
+		}
+	}
+
+	p := &Publisher{
+		cfg:    cfg,
+		topics: topics,
+		queue:  make(chan outbound, cfg.QueueSize),
+		stop:   make(chan struct{}),
+		abort:  make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	go p.run()
+	return p
+}
+
+// HandleEvent implements ws.Subscriber. It never blocks; events are dropped
+// with a warning when the queue is full.
+//
+// Events meant for one user or for moderators are only published when their
+// topic is listed, and events with masked words are published masked.
+func (p *Publisher) HandleEvent(e ws.Event) {
+	_, listed := p.topics[e.Topic]
+	if p.topics != nil && !listed {
+		return
+	}
+	if e.Audience != ws.AudienceAll && e.Audience != ws.AudienceChannel && !listed {
+		return
+	}
+
+	payload := e.Data
+	if e.MaskedData != nil {
+		payload = e.MaskedData
+	}
+	data, err := json.Marshal(payload)
+	if err != nil {
+		slog.Error("error encoding event for stream", "component", "mq", "type", e.Type, "error", err)
+		return
+	}
+	var userID string
+	if e.Audience == ws.AudienceUser {
+		userID = e.UserID
+	}
+
+	out := outbound{
+		subject: p.subjectFor(e),
+		msg: Message{
+			ID:        newMessageID(),
+			Type:      e.Type,
+			Topic:     string(e.Topic),
+			Audience:  audienceNames[e.Audience],
+			UserID:    userID,
+			Data:      data,
+			Timestamp: time.Now().UTC(),
+		},
+	}
+
+	select {
+	case p.queue <- out:
+	default:
+		slog.Warn("event stream queue full, dropping event", "component", "mq", "type", e.Type)
+	}
+}
+
+// subjectFor returns the per-topic override, or <prefix>.<event type in lower case>.
+func (p *Publisher) subjectFor(e ws.Event) string {
+	if subject, ok := p.cfg.Subjects[string(e.Topic)]; ok {
+		return subject
+	}
+	return p.cfg.SubjectPrefix + "." + strings.ToLower(e.Type)
+}
+
+// Close stops accepting events and waits for the queue to drain until ctx ends.
+func (p *Publisher) Close(ctx context.Context) {
+	p.closeOnce.Do(func() {
+		close(p.stop)
+	})
+	select {
+	case <-p.done:
+	case <-ctx.Done():
+		p.abortOnce.Do(func() {
+			close(p.abort)
+		})
+		slog.Warn("event stream closed with undelivered events", "component", "mq", "queued", len(p.queue))
+	}
+}
+
+func (p *Publisher) run() {
+	defer close(p.done)
+
+	nc, err := nats.Connect(p.cfg.NATSURL,
+		nats.Name("lobby"),
+		nats.RetryOnFailedConnect(true),
+		nats.MaxReconnects(-1),
+		nats.ReconnectWait(publishRetryMin),
+		nats.DisconnectErrHandler(func(_ *nats.Conn, err error) {
+			if err != nil {
+				slog.Warn("event stream disconnected", "component", "mq", "error", err)
+			}
+		}),
+		nats.ReconnectHandler(func(nc *nats.Conn) {
+			slog.Info("event stream reconnected", "component", "mq", "url", nc.ConnectedUrlRedacted())
+		}),
+	)
+	if err != nil {
+		slog.Error("error connecting to event stream", "component", "mq", "error", err)
+		return
+	}
+	defer nc.Close()
+
+	// Retries are handled here, with backoff and the original Nats-Msg-Id,
+	// so the client's own no-responders retry is turned off.
+	js, err := jetstream.New(nc,
+		jetstream.WithPublishAsyncMaxPending(p.cfg.MaxPending),
+		jetstream.WithPublishAsyncTimeout(p.cfg.AckTimeout),
+	)
+	if err != nil {
+		slog.Error("error creating JetStream context", "component", "mq", "error", err)
+		return
+	}
+
+	// Up to MaxPending events wait for their ack at once; acks are collected
+	// in publish order by awaitAcks.
+	pending := make(chan inflight, p.cfg.MaxPending)
+	acked := make(chan struct{})
+	go func() {
+		defer close(acked)
+		for f := range pending {
+			if !p.awaitAck(js, f) {
+				return
+			}
+		}
+	}()
+	defer func() {
+		close(pending)
+		<-acked
+	}()
+
+	for {
+		var out outbound
+		select {
+		case out = <-p.queue:
+		case <-p.stop:
+			// Drain what is already queued, then exit.
+			select {
+			case out = <-p.queue:
+			default:
+				return
+			}
+		}
+
+		future, ok := p.publish(js, out)
+		if !ok {
+			return
+		}
+		select {
+		case pending <- inflight{out: out, future: future}:
+		case <-p.abort:
+			return
+		}
+	}
+}
+
+type inflight struct {
+	out    outbound
+	future jetstream.PubAckFuture
+}
+
+// publish hands out to the async publisher, retrying while the pending window
+// is full or the connection is down. It reports false once Close gives up.
+func (p *Publisher) publish(js jetstream.JetStream, out outbound) (jetstream.PubAckFuture, bool) {
+	backoff := publishRetryMin
+	for {
+		future, err := p.publishAsync(js, out)
+		if err == nil {
+			return future, true
+		}
+		slog.Warn("error publishing event, retrying", "component", "mq", "subject", out.subject, "error", err, "retry_in", backoff)
+		if !p.wait(backoff) {
+			return nil, false
+		}
+		backoff = min(backoff*2, publishRetryMax)
+	}
+}
+
+// awaitAck waits for the stream to acknowledge f, republishing it with the
+// same Nats-Msg-Id until it does. It reports false once Close gives up.
+func (p *Publisher) awaitAck(js jetstream.JetStream, f inflight) bool {
+	backoff := publishRetryMin
+	for {
+		select {
+		case <-f.future.Ok():
+			return true
+		case err := <-f.future.Err():
+			slog.Warn("event not acknowledged, retrying", "component", "mq", "subject", f.out.subject, "error", err, "retry_in", backoff)
+		case <-p.abort:
+			return false
+		}
+		if !p.wait(backoff) {
+			return false
+		}
+		backoff = min(backoff*2, publishRetryMax)
+
+		future, ok := p.publish(js, f.out)
+		if !ok {
+			return false
+		}
+		f.future = future
+	}
+}
+
+func (p *Publisher) publishAsync(js jetstream.JetStream, out outbound) (jetstream.PubAckFuture, error) {
+	body, err := json.Marshal(out.msg)
+	if err != nil {
+		return nil, err
+	}
+	msg := &nats.Msg{Subject: out.subject, Data: body}
+	return js.PublishMsgAsync(msg, jetstream.WithMsgID(out.msg.ID), jetstream.WithRetryAttempts(0))
+}
+
+// wait sleeps for d and reports false if Close gives up first.
+func (p *Publisher) wait(d time.Duration) bool {
+	select {
+	case <-time.After(d):
+		return true
+	case <-p.abort:
+		return false
+	}
+}
+
+func newMessageID() string {
+	b := make([]byte, 12)
+	if _, err := rand.Read(b); err != nil {
+		return time.Now().UTC().Format("20060102150405.000000000")
+	}
+	return hex.EncodeToString(b)
+}
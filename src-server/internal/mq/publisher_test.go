@@ -0,0 +1,240 @@
+package mq
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"lobby/internal/config"
+	"lobby/internal/ws"
+)
+
+type publishedMsg struct {
+	subject string
+	msgID   string
+	body    []byte
+}
+
+// startFakeJetStream speaks enough of the NATS protocol for nats.go: it
+// answers the CONNECT/PING handshake, remembers the reply inbox SUB and acks
+// HPUB frames on it. With rejectFirst the first frame gets a 503 no-responders
+// status. Acks are held until batch frames are outstanding. Each frame is
+// reported before it is answered.
+func startFakeJetStream(t *testing.T, batch int, rejectFirst bool) (string, <-chan publishedMsg) {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("net.Listen() error = %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	published := make(chan publishedMsg, 8)
+	attempts := 0
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func(conn net.Conn) {
+				defer conn.Close()
+				r := bufio.NewReader(conn)
+				fmt.Fprint(conn, "INFO {\"server_id\":\"fake\",\"proto\":1,\"headers\":true,\"max_payload\":1048576}\r\n")
+
+				var sid string
+				var held []string
+				for {
+					line, err := readNATSLine(r)
+					if err != nil {
+						return
+					}
+					switch {
+					case line == "PING":
+						fmt.Fprint(conn, "PONG\r\n")
+					case strings.HasPrefix(line, "SUB "):
+						// SUB <subject> <sid>
+						fields := strings.Fields(line)
+						sid = fields[len(fields)-1]
+					case strings.HasPrefix(line, "HPUB "):
+						// HPUB <subject> <reply> <#header> <#total>
+						fields := strings.Fields(line)
+						hdrLen, _ := strconv.Atoi(fields[3])
+						total, _ := strconv.Atoi(fields[4])
+						buf := make([]byte, total+2)
+						if _, err := io.ReadFull(r, buf); err != nil {
+							return
+						}
+						header := string(buf[:hdrLen])
+						msgID := strings.TrimSpace(strings.SplitN(strings.Split(header, "Nats-Msg-Id:")[1], "\r\n", 2)[0])
+						published <- publishedMsg{subject: fields[1], msgID: msgID, body: buf[hdrLen:total]}
+
+						attempts++
+						if rejectFirst && attempts == 1 {
+							status := "NATS/1.0 503\r\n\r\n"
+							fmt.Fprintf(conn, "HMSG %s %s %d %d\r\n%s\r\n", fields[2], sid, len(status), len(status), status)
+							continue
+						}
+						held = append(held, fields[2])
+						if len(held) < batch {
+							continue
+						}
+						for _, reply := range held {
+							ack := `{"stream":"EVENTS","seq":1}`
+							fmt.Fprintf(conn, "MSG %s %s %d\r\n%s\r\n", reply, sid, len(ack), ack)
+						}
+						held = nil
+					}
+				}
+			}(conn)
+		}
+	}()
+	return "nats://" + ln.Addr().String(), published
+}
+
+func readNATSLine(r *bufio.Reader) (string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+func TestPublisherRetriesUntilAcked(t *testing.T) {
+	url, published := startFakeJetStream(t, 1, true)
+	p := NewPublisher(config.EventStreamConfig{
+		NATSURL:       url,
+		SubjectPrefix: "lobby.events",
+		AckTimeout:    time.Second,
+		MaxPending:    4,
+		QueueSize:     4,
+	})
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		p.Close(ctx)
+	})
+
+	p.HandleEvent(ws.Event{
+		Topic: ws.TopicMessage,
+		Type:  ws.EventMessageCreate,
+		Data:  map[string]string{"content": "hi"},
+	})
+
+	var first, second publishedMsg
+	for i, dst := range []*publishedMsg{&first, &second} {
+		select {
+		case *dst = <-published:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for publish attempt %d", i+1)
+		}
+	}
+
+	if first.subject != "lobby.events.message_create" {
+		t.Fatalf("subject = %q, want %q", first.subject, "lobby.events.message_create")
+	}
+	if first.msgID == "" || first.msgID != second.msgID {
+		t.Fatalf("retry Nats-Msg-Id = %q, want %q", second.msgID, first.msgID)
+	}
+
+	var msg Message
+	if err := json.Unmarshal(second.body, &msg); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v", err)
+	}
+	if msg.Type != ws.EventMessageCreate || msg.Topic != string(ws.TopicMessage) {
+		t.Fatalf("unexpected message: %+v", msg)
+	}
+}
+
+func TestPublisherKeepsSeveralEventsInFlight(t *testing.T) {
+	// No ack is sent until three events are outstanding, so this only
+	// completes if the publisher does not wait for each ack in turn.
+	url, published := startFakeJetStream(t, 3, false)
+	p := NewPublisher(config.EventStreamConfig{
+		NATSURL:       url,
+		SubjectPrefix: "lobby.events",
+		AckTimeout:    5 * time.Second,
+		MaxPending:    4,
+		QueueSize:     4,
+	})
+
+	for range 3 {
+		p.HandleEvent(ws.Event{Topic: ws.TopicTyping, Type: ws.EventTypingStart})
+	}
+	for i := range 3 {
+		select {
+		case <-published:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for publish %d", i+1)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	p.Close(ctx)
+	select {
+	case <-p.done:
+	default:
+		t.Fatal("Close() returned before the pending acks arrived")
+	}
+}
+
+func TestPublisherFiltersTopicsAndOverridesSubjects(t *testing.T) {
+	p := &Publisher{
+		cfg: config.EventStreamConfig{
+			SubjectPrefix: "lobby.events",
+			Subjects:      map[string]string{"presence": "analytics.presence"},
+		},
+		topics: map[ws.Topic]struct{}{ws.TopicPresence: {}},
+		queue:  make(chan outbound, 4),
+	}
+
+	p.HandleEvent(ws.Event{Topic: ws.TopicTyping, Type: ws.EventTypingStart})
+	p.HandleEvent(ws.Event{Topic: ws.TopicPresence, Type: ws.EventPresenceUpdate})
+
+	if len(p.queue) != 1 {
+		t.Fatalf("queued = %d, want 1", len(p.queue))
+	}
+	if out := <-p.queue; out.subject != "analytics.presence" {
+		t.Fatalf("subject = %q, want %q", out.subject, "analytics.presence")
+	}
+}
+
+func TestPublisherSkipsPrivateEventsAndPublishesMaskedData(t *testing.T) {
+	p := &Publisher{
+		cfg:   config.EventStreamConfig{SubjectPrefix: "lobby.events"},
+		queue: make(chan outbound, 4),
+	}
+
+	p.HandleEvent(ws.Event{Topic: ws.TopicDraft, Type: ws.EventDraftUpdate, Audience: ws.AudienceUser, UserID: "usr_1", Data: "draft"})
+	p.HandleEvent(ws.Event{Topic: ws.TopicModeration, Type: ws.EventModAlert, Audience: ws.AudienceModerators, Data: "alert"})
+	p.HandleEvent(ws.Event{Topic: ws.TopicMessage, Type: ws.EventMessageCreate, Audience: ws.AudienceChannel, Data: "raw words", MaskedData: "*** words"})
+
+	if len(p.queue) != 1 {
+		t.Fatalf("queued = %d, want 1", len(p.queue))
+	}
+	out := <-p.queue
+	if string(out.msg.Data) != `"*** words"` {
+		t.Fatalf("data = %s, want the masked message", out.msg.Data)
+	}
+	if out.msg.Audience != "channel" {
+		t.Fatalf("audience = %q, want %q", out.msg.Audience, "channel")
+	}
+
+	// Listing a per-user topic publishes it, with its recipient.
+	p.topics = map[ws.Topic]struct{}{ws.TopicDraft: {}}
+	p.HandleEvent(ws.Event{Topic: ws.TopicDraft, Type: ws.EventDraftUpdate, Audience: ws.AudienceUser, UserID: "usr_1", Data: "draft"})
+	if len(p.queue) != 1 {
+		t.Fatalf("queued listed draft = %d, want 1", len(p.queue))
+	}
+	if out := <-p.queue; out.msg.UserID != "usr_1" || out.msg.Audience != "user" {
+		t.Fatalf("listed draft = %+v, want user_id usr_1 and audience user", out.msg)
+	}
+}
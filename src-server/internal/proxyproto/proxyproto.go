@@ -0,0 +1,194 @@
+// Package proxyproto accepts HAProxy PROXY protocol (v1 and v2) headers on a
+// listener, so the client address survives a TCP load balancer such as HAProxy
+// or Traefik.
+package proxyproto
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// headerTimeout bounds how long a trusted peer has to send the header.
+	headerTimeout = 5 * time.Second
+	// v1MaxLength is the longest v1 header line, including CRLF.
+	v1MaxLength = 107
+)
+
+var v2Signature = []byte("\r\n\r\n\x00\r\nQUIT\n")
+
+var errMissingHeader = errors.New("proxyproto: missing PROXY header")
+
+// Listener reads a PROXY header from every connection whose peer is trusted
+// and reports the address it carries as the connection's RemoteAddr.
+// Connections from other peers pass through unchanged, so clients cannot
+// spoof their address by sending a header themselves.
+type Listener struct {
+	net.Listener
+	trusted func(net.IP) bool
+}
+
+func NewListener(inner net.Listener, trusted func(net.IP) bool) *Listener {
+	return &Listener{Listener: inner, trusted: trusted}
+}
+
+func (l *Listener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+
+	addr, ok := conn.RemoteAddr().(*net.TCPAddr)
+	if !ok || !l.trusted(addr.IP) {
+		return conn, nil
+	}
+	return &Conn{Conn: conn, reader: bufio.NewReader(conn)}, nil
+}
+
+// Conn is a connection from a trusted proxy. The header is read lazily on the
+// first Read or RemoteAddr call so a slow proxy never blocks Accept. A
+// connection without a valid header fails every Read.
+type Conn struct {
+	net.Conn
+	reader *bufio.Reader
+
+	once   sync.Once
+	remote net.Addr
+	err    error
+}
+
+func (c *Conn) Read(b []byte) (int, error) {
+	c.once.Do(c.readHeader)
+	if c.err != nil {
+		return 0, c.err
+	}
+	return c.reader.Read(b)
+}
+
+// RemoteAddr returns the client address from the header. Headers that carry no
+// address (v1 UNKNOWN, v2 LOCAL health checks) keep the proxy's own address.
+func (c *Conn) RemoteAddr() net.Addr {
+	c.once.Do(c.readHeader)
+	if c.remote != nil {
+		return c.remote
+	}
+	return c.Conn.RemoteAddr()
+}
+
+func (c *Conn) readHeader() {
+	if err := c.Conn.SetReadDeadline(time.Now().Add(headerTimeout)); err != nil {
+		c.err = err
+		return
+	}
+	c.remote, c.err = readHeader(c.reader)
+	if err := c.Conn.SetReadDeadline(time.Time{}); err != nil && c.err == nil {
+		c.err = err
+	}
+}
+
+func readHeader(r *bufio.Reader) (net.Addr, error) {
+	prefix, err := r.Peek(len(v2Signature))
+	if err != nil {
+		return nil, fmt.Errorf("proxyproto: reading header: %w", err)
+	}
+	switch {
+	case bytes.Equal(prefix, v2Signature):
+		return readV2(r)
+	case bytes.HasPrefix(prefix, []byte("PROXY ")):
+		return readV1(r)
+	default:
+		return nil, errMissingHeader
+	}
+}
+
+// readV1 parses "PROXY TCP4|TCP6 <src> <dst> <srcport> <dstport>\r\n" or
+// "PROXY UNKNOWN ...\r\n".
+func readV1(r *bufio.Reader) (net.Addr, error) {
+	line := make([]byte, 0, v1MaxLength)
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			return nil, fmt.Errorf("proxyproto: reading v1 header: %w", err)
+		}
+		line = append(line, b)
+		if b == '\n' {
+			break
+		}
+		if len(line) >= v1MaxLength {
+			return nil, errors.New("proxyproto: v1 header too long")
+		}
+	}
+	if !bytes.HasSuffix(line, []byte("\r\n")) {
+		return nil, errors.New("proxyproto: v1 header not terminated by CRLF")
+	}
+
+	fields := strings.Split(string(line[:len(line)-2]), " ")
+	if len(fields) >= 2 && fields[1] == "UNKNOWN" {
+		return nil, nil
+	}
+	if len(fields) != 6 || (fields[1] != "TCP4" && fields[1] != "TCP6") {
+		return nil, fmt.Errorf("proxyproto: invalid v1 header %q", line)
+	}
+
+	ip := net.ParseIP(fields[2])
+	port, err := strconv.ParseUint(fields[4], 10, 16)
+	if ip == nil || err != nil || (fields[1] == "TCP4") != (ip.To4() != nil) {
+		return nil, fmt.Errorf("proxyproto: invalid v1 source %q", fields[2]+":"+fields[4])
+	}
+	return &net.TCPAddr{IP: ip, Port: int(port)}, nil
+}
+
+// readV2 parses the binary v2 header. Only TCP over IPv4/IPv6 carries an
+// address we use; other families keep the proxy's address.
+func readV2(r *bufio.Reader) (net.Addr, error) {
+	var header [16]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return nil, fmt.Errorf("proxyproto: reading v2 header: %w", err)
+	}
+	if header[12]>>4 != 2 {
+		return nil, fmt.Errorf("proxyproto: unsupported v2 version %d", header[12]>>4)
+	}
+
+	payload := make([]byte, binary.BigEndian.Uint16(header[14:16]))
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return nil, fmt.Errorf("proxyproto: reading v2 addresses: %w", err)
+	}
+
+	switch header[12] & 0x0F {
+	case 0x0: // LOCAL: the proxy's own connection, e.g. a health check.
+		return nil, nil
+	case 0x1: // PROXY
+	default:
+		return nil, fmt.Errorf("proxyproto: unsupported v2 command %d", header[12]&0x0F)
+	}
+
+	switch header[13] {
+	case 0x11: // TCP over IPv4
+		if len(payload) < 12 {
+			return nil, errors.New("proxyproto: short v2 IPv4 address block")
+		}
+		return &net.TCPAddr{
+			IP:   net.IP(bytes.Clone(payload[0:4])),
+			Port: int(binary.BigEndian.Uint16(payload[8:10])),
+		}, nil
+	case 0x21: // TCP over IPv6
+		if len(payload) < 36 {
+			return nil, errors.New("proxyproto: short v2 IPv6 address block")
+		}
+		return &net.TCPAddr{
+			IP:   net.IP(bytes.Clone(payload[0:16])),
+			Port: int(binary.BigEndian.Uint16(payload[32:34])),
+		}, nil
+	default:
+		return nil, nil
+	}
+}
@@ -0,0 +1,120 @@
+package proxyproto
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net"
+	"strings"
+	"testing"
+)
+
+func TestReadHeaderV1(t *testing.T) {
+	r := bufio.NewReader(strings.NewReader("PROXY TCP4 198.51.100.7 10.0.0.1 43210 8080\r\nGET / HTTP/1.1\r\n"))
+	addr, err := readHeader(r)
+	if err != nil {
+		t.Fatalf("readHeader() error = %v", err)
+	}
+	if got := addr.String(); got != "198.51.100.7:43210" {
+		t.Fatalf("addr = %q, want %q", got, "198.51.100.7:43210")
+	}
+
+	rest, _ := io.ReadAll(r)
+	if string(rest) != "GET / HTTP/1.1\r\n" {
+		t.Fatalf("remaining = %q, want the request line", rest)
+	}
+}
+
+func TestReadHeaderV1Unknown(t *testing.T) {
+	addr, err := readHeader(bufio.NewReader(strings.NewReader("PROXY UNKNOWN\r\n")))
+	if err != nil || addr != nil {
+		t.Fatalf("readHeader() = %v, %v, want nil address", addr, err)
+	}
+}
+
+func TestReadHeaderRejectsMissingOrInvalid(t *testing.T) {
+	for _, input := range []string{
+		"GET / HTTP/1.1\r\nHost: x\r\n",
+		"PROXY TCP4 2001:db8::1 10.0.0.1 1 2\r\n",
+		"PROXY TCP4 198.51.100.7 10.0.0.1 99999 8080\r\n",
+		"PROXY TCP4 198.51.100.7 10.0.0.1 1\n",
+	} {
+		if _, err := readHeader(bufio.NewReader(strings.NewReader(input))); err == nil {
+			t.Errorf("readHeader(%q) succeeded, want error", input)
+		}
+	}
+}
+
+func v2Header(command, family byte, addresses []byte) []byte {
+	var b bytes.Buffer
+	b.Write(v2Signature)
+	b.WriteByte(0x20 | command)
+	b.WriteByte(family)
+	_ = binary.Write(&b, binary.BigEndian, uint16(len(addresses)))
+	b.Write(addresses)
+	return b.Bytes()
+}
+
+func TestReadHeaderV2(t *testing.T) {
+	addresses := make([]byte, 36)
+	copy(addresses[0:16], net.ParseIP("2001:db8::17"))
+	copy(addresses[16:32], net.ParseIP("2001:db8::1"))
+	binary.BigEndian.PutUint16(addresses[32:34], 4711)
+	binary.BigEndian.PutUint16(addresses[34:36], 8080)
+
+	addr, err := readHeader(bufio.NewReader(bytes.NewReader(v2Header(0x1, 0x21, addresses))))
+	if err != nil {
+		t.Fatalf("readHeader() error = %v", err)
+	}
+	if got := addr.String(); got != "[2001:db8::17]:4711" {
+		t.Fatalf("addr = %q, want %q", got, "[2001:db8::17]:4711")
+	}
+
+	// LOCAL is what HAProxy sends for its own health checks.
+	addr, err = readHeader(bufio.NewReader(bytes.NewReader(v2Header(0x0, 0x00, nil))))
+	if err != nil || addr != nil {
+		t.Fatalf("LOCAL readHeader() = %v, %v, want nil address", addr, err)
+	}
+}
+
+func TestListenerOnlyParsesTrustedPeers(t *testing.T) {
+	inner, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("net.Listen() error = %v", err)
+	}
+	defer inner.Close()
+
+	for _, tc := range []struct {
+		name    string
+		trusted bool
+		want    string
+	}{
+		{name: "trusted", trusted: true, want: "198.51.100.7"},
+		{name: "untrusted", trusted: false, want: "127.0.0.1"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			listener := NewListener(inner, func(net.IP) bool { return tc.trusted })
+
+			client, err := net.Dial("tcp", inner.Addr().String())
+			if err != nil {
+				t.Fatalf("net.Dial() error = %v", err)
+			}
+			defer client.Close()
+			if _, err := client.Write([]byte("PROXY TCP4 198.51.100.7 127.0.0.1 43210 8080\r\n")); err != nil {
+				t.Fatalf("Write() error = %v", err)
+			}
+
+			conn, err := listener.Accept()
+			if err != nil {
+				t.Fatalf("Accept() error = %v", err)
+			}
+			defer conn.Close()
+
+			host, _, _ := net.SplitHostPort(conn.RemoteAddr().String())
+			if host != tc.want {
+				t.Fatalf("RemoteAddr host = %q, want %q", host, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,231 @@
+// Package recording writes voice recordings: one Ogg Opus file per user
+// heard while the recording runs.
+package recording
+
+import (
+	"log/slog"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pion/rtp"
+	"github.com/pion/webrtc/v4/pkg/media/oggwriter"
+
+	"lobby/internal/blob"
+)
+
+const (
+	// Opus always runs its RTP clock at 48 kHz
+	sampleRate = 48000
+	channels   = 2
+
+	// queueSize is how many packets may wait for the writer before new ones
+	// are dropped, about ten seconds of one speaker.
+	queueSize = 512
+)
+
+// FileCreator opens a new file in storage. It is satisfied by blob.Service.
+type FileCreator interface {
+	Create(storagePath string) (*os.File, error)
+}
+
+// Track is one user's file in a finished recording.
+type Track struct {
+	UserID      string
+	StoragePath string
+	SizeBytes   int64
+	Offset      time.Duration // when the user was first heard, after the recording started
+}
+
+type queuedPacket struct {
+	userID string
+	packet rtp.Packet
+	at     time.Time
+}
+
+// Session is a running recording. Audio handed to WriteAudio is written by a
+// single goroutine, so a slow disk drops packets instead of stalling media.
+type Session struct {
+	ID        string
+	StartedBy string
+	StartedAt time.Time
+
+	files   FileCreator
+	began   time.Time // monotonic start, for track offsets
+	dropped atomic.Uint64
+
+	mu      sync.RWMutex
+	stopped bool
+	queue   chan queuedPacket
+
+	done   chan struct{}
+	tracks map[string]*track // owned by the writer goroutine
+}
+
+// Start begins a recording that writes each user's audio to files at
+// blob.RecordingRelativePath.
+func Start(files FileCreator, id, startedBy string, startedAt time.Time) *Session {
+	s := &Session{
+		ID:        id,
+		StartedBy: startedBy,
+		StartedAt: startedAt,
+		files:     files,
+		began:     time.Now(),
+		queue:     make(chan queuedPacket, queueSize),
+		done:      make(chan struct{}),
+		tracks:    make(map[string]*track),
+	}
+	go s.run()
+	return s
+}
+
+// WriteAudio queues an Opus RTP packet sent by userID. It copies what it
+// keeps, never blocks, and does nothing once the recording has stopped.
+func (s *Session) WriteAudio(userID string, packet *rtp.Packet) {
+	queued := queuedPacket{userID: userID, packet: *packet, at: time.Now()}
+	queued.packet.Payload = slices.Clone(packet.Payload)
+	queued.packet.Extensions = nil
+	queued.packet.CSRC = nil
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.stopped {
+		return
+	}
+	select {
+	case s.queue <- queued:
+	default:
+		s.dropped.Add(1)
+	}
+}
+
+// Stop ends the recording, waits for queued audio to be written, and returns
+// the finished tracks ordered by user ID. Only the first call returns them.
+func (s *Session) Stop() []Track {
+	s.mu.Lock()
+	if s.stopped {
+		s.mu.Unlock()
+		return nil
+	}
+	s.stopped = true
+	close(s.queue)
+	s.mu.Unlock()
+
+	<-s.done
+	if dropped := s.dropped.Load(); dropped > 0 {
+		slog.Warn("recording dropped audio packets", "component", "recording", "recording_id", s.ID, "count", dropped)
+	}
+
+	tracks := make([]Track, 0, len(s.tracks))
+	for userID, t := range s.tracks {
+		if t.ogg == nil {
+			continue
+		}
+		tracks = append(tracks, Track{
+			UserID:      userID,
+			StoragePath: t.path,
+			SizeBytes:   t.file.n,
+			Offset:      t.offset,
+		})
+	}
+	slices.SortFunc(tracks, func(a, b Track) int {
+		return strings.Compare(a.UserID, b.UserID)
+	})
+	return tracks
+}
+
+func (s *Session) run() {
+	defer close(s.done)
+	for queued := range s.queue {
+		s.write(queued)
+	}
+	for userID, t := range s.tracks {
+		if t.ogg == nil {
+			continue
+		}
+		if err := t.ogg.Close(); err != nil {
+			slog.Error("error closing recording track", "component", "recording", "recording_id", s.ID, "user_id", userID, "error", err)
+		}
+	}
+}
+
+func (s *Session) write(queued queuedPacket) {
+	t, ok := s.tracks[queued.userID]
+	if !ok {
+		t = s.openTrack(queued.userID, queued.at)
+		s.tracks[queued.userID] = t
+	}
+	if t.ogg == nil {
+		return
+	}
+
+	t.rebase(&queued.packet, queued.at)
+	if err := t.ogg.WriteRTP(&queued.packet); err != nil {
+		slog.Error("error writing recording track", "component", "recording", "recording_id", s.ID, "user_id", queued.userID, "error", err)
+		_ = t.ogg.Close()
+		t.ogg = nil
+	}
+}
+
+// openTrack creates userID's file. A track that fails to open is kept
+// without a writer, so the user's later packets are skipped.
+func (s *Session) openTrack(userID string, at time.Time) *track {
+	t := &track{path: blob.RecordingRelativePath(s.ID, userID), offset: at.Sub(s.began)}
+	file, err := s.files.Create(t.path)
+	if err != nil {
+		slog.Error("error creating recording track", "component", "recording", "recording_id", s.ID, "user_id", userID, "error", err)
+		return t
+	}
+	t.file = &countingFile{File: file}
+	t.ogg, err = oggwriter.NewWith(t.file, sampleRate, channels)
+	if err != nil {
+		slog.Error("error starting recording track", "component", "recording", "recording_id", s.ID, "user_id", userID, "error", err)
+		_ = file.Close()
+		t.ogg = nil
+	}
+	return t
+}
+
+// track is one user's file while the recording runs
+type track struct {
+	path   string
+	offset time.Duration
+	file   *countingFile
+	ogg    *oggwriter.OggWriter
+
+	started bool
+	ssrc    uint32
+	shift   uint32 // added to incoming RTP timestamps
+	lastTS  uint32 // last timestamp written, after shift
+	lastAt  time.Time
+}
+
+// rebase keeps the track's timestamps continuous when the user's stream is
+// replaced, such as after rejoining voice or switching to relayed audio. The
+// new stream continues from the old one plus the time that passed between.
+func (t *track) rebase(packet *rtp.Packet, at time.Time) {
+	if t.started && packet.SSRC != t.ssrc {
+		elapsed := uint32(at.Sub(t.lastAt).Seconds() * sampleRate)
+		t.shift = t.lastTS + elapsed - packet.Timestamp
+	}
+	t.started = true
+	t.ssrc = packet.SSRC
+	packet.Timestamp += t.shift
+	t.lastTS = packet.Timestamp
+	t.lastAt = at
+}
+
+// countingFile counts the bytes written to a track
+type countingFile struct {
+	*os.File
+	n int64
+}
+
+func (f *countingFile) Write(p []byte) (int, error) {
+	n, err := f.File.Write(p)
+	f.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,83 @@
+package recording
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pion/rtp"
+)
+
+type dirFiles string
+
+func (d dirFiles) Create(storagePath string) (*os.File, error) {
+	path := filepath.Join(string(d), filepath.FromSlash(storagePath))
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+	return os.Create(path)
+}
+
+func opusPacket(ssrc uint32, seq uint16, timestamp uint32) *rtp.Packet {
+	return &rtp.Packet{
+		Header:  rtp.Header{Version: 2, SSRC: ssrc, SequenceNumber: seq, Timestamp: timestamp},
+		Payload: []byte{0xfc, 0xff, 0xfe},
+	}
+}
+
+func TestSessionWritesOneTrackPerUser(t *testing.T) {
+	dir := t.TempDir()
+	s := Start(dirFiles(dir), "rec_1", "usr_1", time.Now())
+
+	for i := range 5 {
+		s.WriteAudio("usr_2", opusPacket(2, uint16(i), uint32(i*960)))
+		s.WriteAudio("usr_1", opusPacket(1, uint16(i), uint32(i*960)))
+	}
+	tracks := s.Stop()
+	s.WriteAudio("usr_3", opusPacket(3, 0, 0))
+
+	if len(tracks) != 2 || tracks[0].UserID != "usr_1" || tracks[1].UserID != "usr_2" {
+		t.Fatalf("tracks = %+v, want usr_1 and usr_2", tracks)
+	}
+	for _, track := range tracks {
+		data, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(track.StoragePath)))
+		if err != nil {
+			t.Fatalf("reading %s: %v", track.StoragePath, err)
+		}
+		if !bytes.HasPrefix(data, []byte("OggS")) {
+			t.Fatalf("%s is not an Ogg file", track.StoragePath)
+		}
+		if int64(len(data)) != track.SizeBytes {
+			t.Fatalf("%s SizeBytes = %d, file has %d", track.StoragePath, track.SizeBytes, len(data))
+		}
+	}
+	if s.Stop() != nil {
+		t.Fatal("second Stop returned tracks")
+	}
+}
+
+func TestTrackRebaseOnNewStream(t *testing.T) {
+	var tr track
+	start := time.Now()
+
+	first := opusPacket(1, 0, 1000)
+	tr.rebase(first, start)
+	if first.Timestamp != 1000 {
+		t.Fatalf("first stream timestamp = %d, want it unchanged", first.Timestamp)
+	}
+
+	// The user rejoins two seconds later with a new SSRC and clock
+	rejoined := opusPacket(2, 0, 500_000)
+	tr.rebase(rejoined, start.Add(2*time.Second))
+	if want := uint32(1000 + 2*sampleRate); rejoined.Timestamp != want {
+		t.Fatalf("rejoined timestamp = %d, want %d", rejoined.Timestamp, want)
+	}
+
+	next := opusPacket(2, 1, 500_960)
+	tr.rebase(next, start.Add(2*time.Second+20*time.Millisecond))
+	if want := uint32(1000 + 2*sampleRate + 960); next.Timestamp != want {
+		t.Fatalf("next timestamp = %d, want %d", next.Timestamp, want)
+	}
+}
@@ -0,0 +1,364 @@
+package sfu
+
+import (
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/pion/webrtc/v4"
+)
+
+// CameraState tracks one user's camera
+type CameraState struct {
+	UserID   string
+	Track    *webrtc.TrackLocalStaticRTP
+	HasTrack bool // true once the camera track has actually arrived
+	Width    int
+	Height   int
+}
+
+// CameraUpdateFunc is called when a camera goes live, changes size, or stops.
+type CameraUpdateFunc func(userID string, enabled bool, width, height int)
+
+// CameraManager manages camera video and subscriptions. Unlike screen
+// shares, any number of users can publish a camera and a viewer can
+// subscribe to any number of them.
+type CameraManager struct {
+	sfu              *SFU
+	mu               sync.RWMutex
+	cameras          map[string]*CameraState        // publisherID -> state
+	viewers          map[string]map[string]bool     // publisherID -> set of viewerIDs
+	pendingKeyframes map[string]map[string]struct{} // viewerID -> publisherIDs awaiting a keyframe
+	paused           map[string]map[string]struct{} // viewerID -> publisherIDs paused for its bandwidth
+	onUpdateCallback CameraUpdateFunc
+}
+
+func NewCameraManager(sfu *SFU) *CameraManager {
+	return &CameraManager{
+		sfu:              sfu,
+		cameras:          make(map[string]*CameraState),
+		viewers:          make(map[string]map[string]bool),
+		pendingKeyframes: make(map[string]map[string]struct{}),
+		paused:           make(map[string]map[string]struct{}),
+	}
+}
+
+func (cm *CameraManager) SetUpdateCallback(cb CameraUpdateFunc) {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	cm.onUpdateCallback = cb
+}
+
+// StartCamera registers userID's camera and adds the transceiver it is sent
+// on; the caller triggers the renegotiation that offers it. Like StartShare,
+// the broadcast waits for the track; calling it again while live only
+// updates the reported size.
+func (cm *CameraManager) StartCamera(userID string, width, height int) error {
+	cm.mu.Lock()
+	if state, exists := cm.cameras[userID]; exists {
+		changed := state.Width != width || state.Height != height
+		state.Width, state.Height = width, height
+		live := state.HasTrack
+		cb := cm.onUpdateCallback
+		cm.mu.Unlock()
+		if cb != nil && live && changed {
+			cb(userID, true, width, height)
+		}
+		return nil
+	}
+
+	cm.cameras[userID] = &CameraState{UserID: userID, Width: width, Height: height}
+	cm.viewers[userID] = make(map[string]bool)
+	cm.mu.Unlock()
+
+	peer := cm.sfu.GetPeer(userID)
+	if peer == nil {
+		return nil
+	}
+
+	// A camera restarted in the same session comes back on the existing
+	// transceiver via replaceTrack(), so OnTrack won't fire again
+	if existingTrack := peer.GetLocalTrack(TrackCamera); existingTrack != nil {
+		slog.Debug("reusing existing camera track", "component", "camera", "user_id", userID)
+		cm.onCameraTrackReady(userID, existingTrack)
+		return nil
+	}
+
+	if err := peer.EnsureCameraTransceiver(); err != nil {
+		return err
+	}
+
+	slog.Debug("registered camera, waiting for track", "component", "camera", "user_id", userID)
+	return nil
+}
+
+func (cm *CameraManager) StopCamera(userID string) {
+	cm.mu.Lock()
+	state, exists := cm.cameras[userID]
+	if !exists {
+		cm.mu.Unlock()
+		return
+	}
+	hadTrack := state.HasTrack
+
+	viewerIDs := make([]string, 0, len(cm.viewers[userID]))
+	for viewerID := range cm.viewers[userID] {
+		viewerIDs = append(viewerIDs, viewerID)
+		delete(cm.pendingKeyframes[viewerID], userID)
+		delete(cm.paused[viewerID], userID)
+	}
+	delete(cm.cameras, userID)
+	delete(cm.viewers, userID)
+	cb := cm.onUpdateCallback
+	cm.mu.Unlock()
+
+	for _, viewerID := range viewerIDs {
+		cm.removeCameraTrackFromViewer(userID, viewerID)
+	}
+
+	slog.Info("user stopped camera", "component", "camera", "user_id", userID)
+
+	if cb != nil && hadTrack {
+		cb(userID, false, 0, 0)
+	}
+}
+
+// Subscribe adds publisherID's camera to viewerID's peer. Subscribing to a
+// camera that isn't live is a no-op.
+func (cm *CameraManager) Subscribe(viewerID, publisherID string) error {
+	if viewerID == publisherID {
+		return nil
+	}
+
+	cm.mu.Lock()
+	state, exists := cm.cameras[publisherID]
+	if !exists || !state.HasTrack {
+		cm.mu.Unlock()
+		slog.Debug("subscribe failed: camera not live", "component", "camera", "publisher_id", publisherID)
+		return nil
+	}
+	if cm.viewers[publisherID][viewerID] {
+		cm.mu.Unlock()
+		return nil
+	}
+	if peer := cm.sfu.GetPeer(viewerID); peer != nil && !peer.AcceptsVideoCodec(state.Track.Codec().MimeType) {
+		cm.mu.Unlock()
+		return ErrVideoCodecUnsupported
+	}
+	cm.viewers[publisherID][viewerID] = true
+	track := state.Track
+	cm.mu.Unlock()
+
+	cm.addCameraTrackToViewer(publisherID, viewerID, track)
+	slog.Debug("user subscribed to camera", "component", "camera", "viewer_id", viewerID, "publisher_id", publisherID)
+	return nil
+}
+
+func (cm *CameraManager) Unsubscribe(viewerID, publisherID string) {
+	cm.mu.Lock()
+	if !cm.viewers[publisherID][viewerID] {
+		cm.mu.Unlock()
+		return
+	}
+	delete(cm.viewers[publisherID], viewerID)
+	delete(cm.pendingKeyframes[viewerID], publisherID)
+	delete(cm.paused[viewerID], publisherID)
+	cm.mu.Unlock()
+
+	cm.removeCameraTrackFromViewer(publisherID, viewerID)
+	slog.Debug("user unsubscribed from camera", "component", "camera", "viewer_id", viewerID, "publisher_id", publisherID)
+}
+
+func (cm *CameraManager) OnUserDisconnect(userID string) {
+	cm.StopCamera(userID)
+
+	cm.mu.Lock()
+	for _, viewers := range cm.viewers {
+		delete(viewers, userID)
+	}
+	delete(cm.pendingKeyframes, userID)
+	delete(cm.paused, userID)
+	cm.mu.Unlock()
+}
+
+// IsPublishing reports whether userID's camera is live.
+func (cm *CameraManager) IsPublishing(userID string) bool {
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
+	state, exists := cm.cameras[userID]
+	return exists && state.HasTrack
+}
+
+// Subscriptions returns the publishers viewerID is subscribed to.
+func (cm *CameraManager) Subscriptions(viewerID string) []string {
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
+	var publishers []string
+	for publisherID, viewers := range cm.viewers {
+		if viewers[viewerID] {
+			publishers = append(publishers, publisherID)
+		}
+	}
+	return publishers
+}
+
+// onCameraTrackReady is called when a camera track arrives from a peer. A
+// track for a camera that was stopped in the meantime is kept on the peer
+// for a later StartCamera but not announced.
+func (cm *CameraManager) onCameraTrackReady(userID string, track *webrtc.TrackLocalStaticRTP) {
+	cm.mu.Lock()
+	state, exists := cm.cameras[userID]
+	if !exists {
+		cm.mu.Unlock()
+		slog.Debug("camera track arrived after CAMERA_STOP", "component", "camera", "user_id", userID)
+		return
+	}
+	state.Track = track
+	state.HasTrack = true
+	width, height := state.Width, state.Height
+
+	viewers := make([]string, 0, len(cm.viewers[userID]))
+	for viewerID := range cm.viewers[userID] {
+		viewers = append(viewers, viewerID)
+	}
+	cb := cm.onUpdateCallback
+	cm.mu.Unlock()
+
+	slog.Info("camera track ready", "component", "camera", "user_id", userID)
+
+	if cb != nil {
+		cb(userID, true, width, height)
+	}
+
+	for _, viewerID := range viewers {
+		cm.addCameraTrackToViewer(userID, viewerID, track)
+	}
+}
+
+func (cm *CameraManager) addCameraTrackToViewer(publisherID, viewerID string, track *webrtc.TrackLocalStaticRTP) {
+	peer := cm.sfu.GetPeer(viewerID)
+	if peer == nil || peer.IsClosed() {
+		return
+	}
+
+	if err := peer.AddTrack(publisherID, TrackCamera, track); err != nil {
+		slog.Error("error adding camera track to viewer", "component", "camera", "viewer_id", viewerID, "error", err)
+		return
+	}
+
+	// Keyframe is requested once the viewer has answered
+	cm.mu.Lock()
+	if cm.pendingKeyframes[viewerID] == nil {
+		cm.pendingKeyframes[viewerID] = make(map[string]struct{})
+	}
+	cm.pendingKeyframes[viewerID][publisherID] = struct{}{}
+	cm.mu.Unlock()
+
+	cm.sfu.TriggerRenegotiation(viewerID)
+}
+
+// OnRenegotiationComplete is called when a viewer's SDP answer is received
+// and requests keyframes for the cameras it was just given.
+func (cm *CameraManager) OnRenegotiationComplete(viewerID string) {
+	cm.mu.Lock()
+	pending := cm.pendingKeyframes[viewerID]
+	delete(cm.pendingKeyframes, viewerID)
+	cm.mu.Unlock()
+
+	for publisherID := range pending {
+		publisherPeer := cm.sfu.GetPeer(publisherID)
+		if publisherPeer == nil || publisherPeer.IsClosed() {
+			continue
+		}
+		if err := publisherPeer.RequestKeyframe(TrackCamera); err != nil {
+			slog.Error("error requesting keyframe", "component", "camera", "publisher_id", publisherID, "error", err)
+		}
+	}
+}
+
+func (cm *CameraManager) removeCameraTrackFromViewer(publisherID, viewerID string) {
+	peer := cm.sfu.GetPeer(viewerID)
+	if peer == nil || peer.IsClosed() {
+		return
+	}
+
+	if err := peer.RemoveTrack(publisherID, TrackCamera); err != nil {
+		slog.Error("error removing camera track from viewer", "component", "camera", "viewer_id", viewerID, "error", err)
+		return
+	}
+
+	cm.sfu.TriggerRenegotiation(viewerID)
+}
+
+// adaptToEstimate fits viewerID's cameras into budget, the part of its
+// downlink estimate not used by screen share. Cameras are taken in publisher
+// order; one that doesn't fit is paused for the viewer, and a paused one is
+// resumed once it fits with upgradeHeadroomPercent to spare.
+func (cm *CameraManager) adaptToEstimate(viewerID string, budget uint64) {
+	now := time.Now()
+	var pause, resume []*CameraState
+
+	cm.mu.Lock()
+	publisherIDs := make([]string, 0, len(cm.viewers))
+	for publisherID, viewers := range cm.viewers {
+		if viewers[viewerID] && cm.cameras[publisherID].HasTrack {
+			publisherIDs = append(publisherIDs, publisherID)
+		}
+	}
+	slices.Sort(publisherIDs)
+
+	var used uint64
+	for _, publisherID := range publisherIDs {
+		var bitrate uint64
+		if publisherPeer := cm.sfu.GetPeer(publisherID); publisherPeer != nil {
+			bitrate = publisherPeer.cameraMeter.Bitrate(now)
+		}
+		_, paused := cm.paused[viewerID][publisherID]
+		need := bitrate
+		if paused {
+			need = need * upgradeHeadroomPercent / 100
+		}
+
+		switch {
+		case used+need <= budget:
+			used += bitrate
+			if paused {
+				delete(cm.paused[viewerID], publisherID)
+				resume = append(resume, cm.cameras[publisherID])
+			}
+		case !paused:
+			if cm.paused[viewerID] == nil {
+				cm.paused[viewerID] = make(map[string]struct{})
+			}
+			cm.paused[viewerID][publisherID] = struct{}{}
+			pause = append(pause, cm.cameras[publisherID])
+		}
+	}
+	cm.mu.Unlock()
+
+	peer := cm.sfu.GetPeer(viewerID)
+	if peer == nil || peer.IsClosed() {
+		return
+	}
+	for _, state := range pause {
+		if err := peer.ReplaceTrack(state.UserID, TrackCamera, nil); err != nil {
+			slog.Error("error pausing camera", "component", "camera", "viewer_id", viewerID, "error", err)
+			continue
+		}
+		slog.Debug("paused camera for viewer bandwidth", "component", "camera", "viewer_id", viewerID, "publisher_id", state.UserID, "budget_bps", budget)
+	}
+	for _, state := range resume {
+		if err := peer.ReplaceTrack(state.UserID, TrackCamera, state.Track); err != nil {
+			slog.Error("error resuming camera", "component", "camera", "viewer_id", viewerID, "error", err)
+			continue
+		}
+		// The viewer needs a keyframe to pick the camera up again
+		if publisherPeer := cm.sfu.GetPeer(state.UserID); publisherPeer != nil && !publisherPeer.IsClosed() {
+			if err := publisherPeer.RequestKeyframe(TrackCamera); err != nil {
+				slog.Error("error requesting keyframe", "component", "camera", "publisher_id", state.UserID, "error", err)
+			}
+		}
+		slog.Debug("resumed camera for viewer", "component", "camera", "viewer_id", viewerID, "publisher_id", state.UserID, "budget_bps", budget)
+	}
+}
@@ -0,0 +1,179 @@
+package sfu
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/pion/webrtc/v4"
+)
+
+type cameraUpdate struct {
+	userID        string
+	enabled       bool
+	width, height int
+}
+
+func newTestCameraManager(t *testing.T) (*CameraManager, *[]cameraUpdate) {
+	t.Helper()
+
+	s, err := New(&Config{})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	t.Cleanup(s.Close)
+
+	var updates []cameraUpdate
+	cm := NewCameraManager(s)
+	cm.SetUpdateCallback(func(userID string, enabled bool, width, height int) {
+		updates = append(updates, cameraUpdate{userID, enabled, width, height})
+	})
+	return cm, &updates
+}
+
+func newTestCameraTrack(t *testing.T, userID string) *webrtc.TrackLocalStaticRTP {
+	t.Helper()
+
+	track, err := webrtc.NewTrackLocalStaticRTP(webrtc.RTPCodecCapability{MimeType: webrtc.MimeTypeVP9}, TrackCamera, userID)
+	if err != nil {
+		t.Fatalf("NewTrackLocalStaticRTP() error = %v", err)
+	}
+	return track
+}
+
+func TestCameraAnnouncedOnceTrackArrives(t *testing.T) {
+	cm, updates := newTestCameraManager(t)
+
+	if err := cm.StartCamera("usr_1", 640, 480); err != nil {
+		t.Fatalf("StartCamera() error = %v", err)
+	}
+	if cm.IsPublishing("usr_1") || len(*updates) != 0 {
+		t.Fatal("camera announced before its track arrived")
+	}
+
+	cm.onCameraTrackReady("usr_1", newTestCameraTrack(t, "usr_1"))
+	if !cm.IsPublishing("usr_1") {
+		t.Fatal("IsPublishing = false after the track arrived")
+	}
+	want := []cameraUpdate{{"usr_1", true, 640, 480}}
+	if !slices.Equal(*updates, want) {
+		t.Fatalf("updates = %+v, want %+v", *updates, want)
+	}
+
+	if err := cm.StartCamera("usr_1", 1280, 720); err != nil {
+		t.Fatalf("StartCamera() resize error = %v", err)
+	}
+	if err := cm.StartCamera("usr_1", 1280, 720); err != nil {
+		t.Fatalf("StartCamera() repeat error = %v", err)
+	}
+	want = append(want, cameraUpdate{"usr_1", true, 1280, 720})
+	if !slices.Equal(*updates, want) {
+		t.Fatalf("updates = %+v, want %+v", *updates, want)
+	}
+
+	cm.StopCamera("usr_1")
+	want = append(want, cameraUpdate{userID: "usr_1"})
+	if !slices.Equal(*updates, want) {
+		t.Fatalf("updates = %+v, want %+v", *updates, want)
+	}
+	if cm.IsPublishing("usr_1") {
+		t.Fatal("IsPublishing = true after StopCamera")
+	}
+}
+
+func TestCameraTrackAfterStopIsNotAnnounced(t *testing.T) {
+	cm, updates := newTestCameraManager(t)
+
+	if err := cm.StartCamera("usr_1", 0, 0); err != nil {
+		t.Fatalf("StartCamera() error = %v", err)
+	}
+	cm.StopCamera("usr_1")
+	cm.onCameraTrackReady("usr_1", newTestCameraTrack(t, "usr_1"))
+
+	if cm.IsPublishing("usr_1") || len(*updates) != 0 {
+		t.Fatalf("stopped camera announced: updates = %+v", *updates)
+	}
+}
+
+func TestCameraSubscriptions(t *testing.T) {
+	cm, _ := newTestCameraManager(t)
+
+	for _, userID := range []string{"usr_1", "usr_2"} {
+		if err := cm.StartCamera(userID, 0, 0); err != nil {
+			t.Fatalf("StartCamera(%s) error = %v", userID, err)
+		}
+	}
+	cm.onCameraTrackReady("usr_1", newTestCameraTrack(t, "usr_1"))
+
+	for _, publisherID := range []string{"usr_1", "usr_2", "usr_3"} {
+		if err := cm.Subscribe("usr_3", publisherID); err != nil {
+			t.Fatalf("Subscribe(%s) error = %v", publisherID, err)
+		}
+	}
+	if got := cm.Subscriptions("usr_3"); !slices.Equal(got, []string{"usr_1"}) {
+		t.Fatalf("Subscriptions = %v, want only the live camera", got)
+	}
+
+	cm.onCameraTrackReady("usr_2", newTestCameraTrack(t, "usr_2"))
+	if err := cm.Subscribe("usr_3", "usr_2"); err != nil {
+		t.Fatalf("Subscribe(usr_2) error = %v", err)
+	}
+	got := cm.Subscriptions("usr_3")
+	slices.Sort(got)
+	if !slices.Equal(got, []string{"usr_1", "usr_2"}) {
+		t.Fatalf("Subscriptions = %v, want both cameras", got)
+	}
+
+	cm.Unsubscribe("usr_3", "usr_1")
+	if got := cm.Subscriptions("usr_3"); !slices.Equal(got, []string{"usr_2"}) {
+		t.Fatalf("Subscriptions after Unsubscribe = %v", got)
+	}
+
+	cm.OnUserDisconnect("usr_2")
+	if got := cm.Subscriptions("usr_3"); len(got) != 0 {
+		t.Fatalf("Subscriptions after publisher left = %v", got)
+	}
+}
+
+func TestCameraPausedForViewerBandwidth(t *testing.T) {
+	cm, _ := newTestCameraManager(t)
+
+	publisher, err := cm.sfu.AddPeer("usr_1")
+	if err != nil {
+		t.Fatalf("AddPeer(usr_1) error = %v", err)
+	}
+	viewer, err := cm.sfu.AddPeer("usr_2")
+	if err != nil {
+		t.Fatalf("AddPeer(usr_2) error = %v", err)
+	}
+	if err := cm.StartCamera("usr_1", 0, 0); err != nil {
+		t.Fatalf("StartCamera() error = %v", err)
+	}
+	track := newTestCameraTrack(t, "usr_1")
+	cm.onCameraTrackReady("usr_1", track)
+	if err := cm.Subscribe("usr_2", "usr_1"); err != nil {
+		t.Fatalf("Subscribe() error = %v", err)
+	}
+
+	publisher.cameraMeter.bitrate = 500_000
+	publisher.cameraMeter.sampledAt = time.Now()
+	sending := func() bool {
+		viewer.mu.RLock()
+		defer viewer.mu.RUnlock()
+		return viewer.outputTracks["usr_1:"+TrackCamera].Track() != nil
+	}
+
+	cm.adaptToEstimate("usr_2", 300_000)
+	if sending() {
+		t.Fatal("camera still sent over a 300 kbps estimate")
+	}
+	// Resuming needs headroom above the camera's 500 kbps
+	cm.adaptToEstimate("usr_2", 600_000)
+	if sending() {
+		t.Fatal("camera resumed without headroom")
+	}
+	cm.adaptToEstimate("usr_2", 700_000)
+	if !sending() {
+		t.Fatal("camera not resumed once it fits")
+	}
+}
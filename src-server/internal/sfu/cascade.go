@@ -0,0 +1,33 @@
+package sfu
+
+import "fmt"
+
+// SetCascadeSink makes sink receive the audio of every voice user connected
+// to this SFU, WebRTC peers and websocket relays alike, so it can be sent to
+// SFUs on other instances. Audio of remote participants is never passed back.
+// A nil sink stops cascading.
+func (s *SFU) SetCascadeSink(sink RelaySink) {
+	if sink == nil {
+		s.cascade.Store(nil)
+		return
+	}
+	s.cascade.Store(&sink)
+}
+
+// AddRemoteParticipant adds userID, a voice user connected to another
+// instance, whose audio arrives through WriteRelayAudio. Peers are offered
+// its audio track like any relay's. Remove it with RemoveRelay.
+func (s *SFU) AddRemoteParticipant(userID string) error {
+	if s.IsRelay(userID) {
+		return fmt.Errorf("user %s already has an audio relay", userID)
+	}
+	return s.addRelay(userID, nil, true)
+}
+
+// IsRemoteParticipant reports whether userID is connected to another instance.
+func (s *SFU) IsRemoteParticipant(userID string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	r, ok := s.relays[userID]
+	return ok && r.remote
+}
@@ -0,0 +1,158 @@
+package sfu
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/pion/webrtc/v4"
+)
+
+// ErrVideoCodecUnsupported is returned when a viewer can't decode the codec
+// a screen share or camera is sent in. Video is never transcoded.
+var ErrVideoCodecUnsupported = errors.New("video codec not supported by viewer")
+
+// videoFeedback makes viewers report their bandwidth estimate, which picks
+// their screen share layer, and NACK lost packets so they are retransmitted.
+var videoFeedback = []webrtc.RTCPFeedback{
+	{Type: webrtc.TypeRTCPFBGoogREMB},
+	{Type: webrtc.TypeRTCPFBNACK},
+}
+
+// nackHistoryPackets is how many packets are kept per outgoing video stream
+// to answer NACKs from, about a second of screen share. Must be a power of two.
+const nackHistoryPackets = 1024
+
+// videoCodecs are the video codecs the SFU forwards. Each peer is offered
+// only those it listed when joining, so a streamer sends one of them and
+// viewers get only the video they can decode.
+var videoCodecs = []webrtc.RTPCodecParameters{
+	{
+		RTPCodecCapability: webrtc.RTPCodecCapability{
+			MimeType:     webrtc.MimeTypeVP9,
+			ClockRate:    90000,
+			SDPFmtpLine:  "profile-id=0",
+			RTCPFeedback: videoFeedback,
+		},
+		PayloadType: 98,
+	},
+	{
+		RTPCodecCapability: webrtc.RTPCodecCapability{
+			MimeType:     webrtc.MimeTypeVP8,
+			ClockRate:    90000,
+			RTCPFeedback: videoFeedback,
+		},
+		PayloadType: 96,
+	},
+	{
+		RTPCodecCapability: webrtc.RTPCodecCapability{
+			MimeType:     webrtc.MimeTypeH264,
+			ClockRate:    90000,
+			SDPFmtpLine:  "level-asymmetry-allowed=1;packetization-mode=1;profile-level-id=42e01f",
+			RTCPFeedback: videoFeedback,
+		},
+		PayloadType: 102,
+	},
+}
+
+// rtxPayloadTypes maps each video codec's payload type to the one its
+// retransmissions (RTX) are sent with.
+var rtxPayloadTypes = map[webrtc.PayloadType]webrtc.PayloadType{
+	98:  99,
+	96:  97,
+	102: 103,
+}
+
+// defaultVideoCodecs is assumed for peers that don't list their codecs,
+// which predate the others.
+var defaultVideoCodecs = []string{webrtc.MimeTypeVP9}
+
+func registerVideoCodecs(mediaEngine *webrtc.MediaEngine) error {
+	for _, codec := range videoCodecs {
+		if err := mediaEngine.RegisterCodec(codec, webrtc.RTPCodecTypeVideo); err != nil {
+			return fmt.Errorf("failed to register %s codec: %w", codec.MimeType, err)
+		}
+		if err := mediaEngine.RegisterCodec(rtxCodec(codec), webrtc.RTPCodecTypeVideo); err != nil {
+			return fmt.Errorf("failed to register %s rtx codec: %w", codec.MimeType, err)
+		}
+	}
+	return nil
+}
+
+// rtxCodec returns the RTX codec retransmissions of codec are sent with.
+func rtxCodec(codec webrtc.RTPCodecParameters) webrtc.RTPCodecParameters {
+	return webrtc.RTPCodecParameters{
+		RTPCodecCapability: webrtc.RTPCodecCapability{
+			MimeType:    webrtc.MimeTypeRTX,
+			ClockRate:   codec.ClockRate,
+			SDPFmtpLine: fmt.Sprintf("apt=%d", codec.PayloadType),
+		},
+		PayloadType: rtxPayloadTypes[codec.PayloadType],
+	}
+}
+
+// matchVideoCodecs returns the forwarded codecs among mimeTypes, in the
+// order given. Unknown and repeated MIME types are skipped.
+func matchVideoCodecs(mimeTypes []string) []webrtc.RTPCodecParameters {
+	matched := make([]webrtc.RTPCodecParameters, 0, len(videoCodecs))
+	for _, mimeType := range mimeTypes {
+		for _, codec := range videoCodecs {
+			if strings.EqualFold(codec.MimeType, mimeType) && !hasVideoCodec(matched, codec.MimeType) {
+				matched = append(matched, codec)
+			}
+		}
+	}
+	return matched
+}
+
+func hasVideoCodec(codecs []webrtc.RTPCodecParameters, mimeType string) bool {
+	for _, codec := range codecs {
+		if strings.EqualFold(codec.MimeType, mimeType) {
+			return true
+		}
+	}
+	return false
+}
+
+// SetVideoCodecs records the video codecs the peer can send and decode, as
+// MIME types in order of preference. Without any known ones the peer gets
+// defaultVideoCodecs. Call it before the peer negotiates video.
+func (p *Peer) SetVideoCodecs(mimeTypes []string) {
+	codecs := matchVideoCodecs(mimeTypes)
+	if len(codecs) == 0 {
+		codecs = matchVideoCodecs(defaultVideoCodecs)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.videoCodecs = codecs
+}
+
+// AcceptsVideoCodec reports whether the peer can decode video in mimeType.
+func (p *Peer) AcceptsVideoCodec(mimeType string) bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return hasVideoCodec(p.videoCodecsLocked(), mimeType)
+}
+
+// videoCodecsLocked returns the peer's video codecs. p.mu must be held.
+func (p *Peer) videoCodecsLocked() []webrtc.RTPCodecParameters {
+	if p.videoCodecs == nil {
+		return matchVideoCodecs(defaultVideoCodecs)
+	}
+	return p.videoCodecs
+}
+
+// preferVideoCodecsLocked limits what a video transceiver of the peer
+// offers to the peer's codecs and their RTX. p.mu must be held.
+func (p *Peer) preferVideoCodecsLocked(t *webrtc.RTPTransceiver) error {
+	codecs := p.videoCodecsLocked()
+	preferred := make([]webrtc.RTPCodecParameters, 0, 2*len(codecs))
+	for _, codec := range codecs {
+		preferred = append(preferred, codec, rtxCodec(codec))
+	}
+	if err := t.SetCodecPreferences(preferred); err != nil {
+		return fmt.Errorf("failed to set video codecs: %w", err)
+	}
+	return nil
+}
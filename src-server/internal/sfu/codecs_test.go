@@ -0,0 +1,82 @@
+package sfu
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/pion/webrtc/v4"
+)
+
+func TestMatchVideoCodecs(t *testing.T) {
+	got := matchVideoCodecs([]string{"video/h264", "video/AV1", webrtc.MimeTypeVP8, webrtc.MimeTypeH264})
+	if len(got) != 2 || got[0].MimeType != webrtc.MimeTypeH264 || got[1].MimeType != webrtc.MimeTypeVP8 {
+		t.Fatalf("matchVideoCodecs = %+v, want H264 then VP8", got)
+	}
+}
+
+func TestPeerVideoCodecs(t *testing.T) {
+	s, err := New(&Config{})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	t.Cleanup(s.Close)
+
+	peer, err := s.AddPeer("usr_1")
+	if err != nil {
+		t.Fatalf("AddPeer() error = %v", err)
+	}
+	if !peer.AcceptsVideoCodec(webrtc.MimeTypeVP9) || peer.AcceptsVideoCodec(webrtc.MimeTypeVP8) {
+		t.Fatal("a peer that listed no codecs should accept only VP9")
+	}
+
+	vp8, err := webrtc.NewTrackLocalStaticRTP(webrtc.RTPCodecCapability{MimeType: webrtc.MimeTypeVP8}, TrackVideo, "usr_2")
+	if err != nil {
+		t.Fatalf("NewTrackLocalStaticRTP() error = %v", err)
+	}
+	if err := peer.AddTrack("usr_2", TrackVideo, vp8); !errors.Is(err, ErrVideoCodecUnsupported) {
+		t.Fatalf("AddTrack(VP8) error = %v, want ErrVideoCodecUnsupported", err)
+	}
+
+	peer.SetVideoCodecs([]string{webrtc.MimeTypeVP8})
+	if !peer.AcceptsVideoCodec(webrtc.MimeTypeVP8) || peer.AcceptsVideoCodec(webrtc.MimeTypeVP9) {
+		t.Fatal("SetVideoCodecs did not replace the default")
+	}
+	if err := peer.AddTrack("usr_2", TrackVideo, vp8); err != nil {
+		t.Fatalf("AddTrack(VP8) after listing it error = %v", err)
+	}
+}
+
+func TestVideoOfferNegotiatesRetransmission(t *testing.T) {
+	s, err := New(&Config{})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	t.Cleanup(s.Close)
+
+	peer, err := s.AddPeer("usr_1")
+	if err != nil {
+		t.Fatalf("AddPeer() error = %v", err)
+	}
+	peer.SetVideoCodecs([]string{webrtc.MimeTypeVP8})
+	vp8, err := webrtc.NewTrackLocalStaticRTP(webrtc.RTPCodecCapability{MimeType: webrtc.MimeTypeVP8}, TrackVideo, "usr_2")
+	if err != nil {
+		t.Fatalf("NewTrackLocalStaticRTP() error = %v", err)
+	}
+	if err := peer.AddTrack("usr_2", TrackVideo, vp8); err != nil {
+		t.Fatalf("AddTrack() error = %v", err)
+	}
+
+	offer, err := peer.conn.CreateOffer(nil)
+	if err != nil {
+		t.Fatalf("CreateOffer() error = %v", err)
+	}
+	for _, want := range []string{"a=rtcp-fb:96 nack", "a=rtpmap:97 rtx/90000", "a=fmtp:97 apt=96"} {
+		if !strings.Contains(offer.SDP, want) {
+			t.Errorf("offer is missing %q", want)
+		}
+	}
+	if strings.Contains(offer.SDP, "apt=98") {
+		t.Error("offer carries RTX for VP9, which the peer did not list")
+	}
+}
@@ -1,6 +1,10 @@
 package sfu
 
-import "github.com/pion/webrtc/v4"
+import (
+	"time"
+
+	"github.com/pion/webrtc/v4"
+)
 
 type Config struct {
 	// PublicIP is the public IP address for ICE candidates (empty for auto-detect)
@@ -9,6 +13,18 @@ type Config struct {
 	MinPort uint16
 	// MaxPort for WebRTC UDP ports
 	MaxPort uint16
+	// UDPPort, when set, carries all media on this one UDP port instead of
+	// the MinPort-MaxPort range
+	UDPPort uint16
+	// TCPPort, when set, also accepts ICE over TCP on this port, for clients
+	// behind firewalls that block UDP
+	TCPPort uint16
+	// ICELite makes the SFU an ICE-lite agent, which only answers
+	// connectivity checks. Requires a publicly reachable address.
+	ICELite bool
+	// KeyframeInterval, when set, requests a keyframe from every video
+	// publisher this often, so late joiners get a picture within it
+	KeyframeInterval time.Duration
 	// STUNUrl for server-side candidate gathering (e.g. "stun:turn.myserver.com:3478")
 	STUNUrl string
 }
@@ -26,3 +42,9 @@ func (c *Config) ToWebRTCConfig() webrtc.Configuration {
 		},
 	}
 }
+
+// LogComponents are the "component" values of this package's log records,
+// which sfu.logLevel applies to.
+func LogComponents() []string {
+	return []string{"sfu", "camera", "screenshare"}
+}
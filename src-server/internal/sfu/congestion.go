@@ -0,0 +1,58 @@
+package sfu
+
+import (
+	"fmt"
+
+	"github.com/pion/interceptor"
+	"github.com/pion/interceptor/pkg/twcc"
+	"github.com/pion/sdp/v3"
+	"github.com/pion/webrtc/v4"
+)
+
+// Congestion control. Uplinks negotiate transport-wide congestion control:
+// the SFU answers the transport sequence numbers streamers stamp on their
+// packets with TWCC feedback, which drives the browser's send-side bandwidth
+// estimate. The SFU never stamps what it forwards, so viewers keep reporting
+// their downlink as REMB. A viewer's REMB estimate is shared by all video it
+// receives: screen share picks its layer first, and cameras that don't fit
+// in what is left are paused for that viewer until they do.
+
+// configureTWCC negotiates transport-cc for audio and video and generates
+// TWCC feedback for incoming streams. Must be called after the codecs are
+// registered.
+func configureTWCC(mediaEngine *webrtc.MediaEngine, interceptors *interceptor.Registry) error {
+	for _, kind := range []webrtc.RTPCodecType{webrtc.RTPCodecTypeAudio, webrtc.RTPCodecTypeVideo} {
+		if err := mediaEngine.RegisterHeaderExtension(webrtc.RTPHeaderExtensionCapability{URI: sdp.TransportCCURI}, kind); err != nil {
+			return fmt.Errorf("failed to register transport-cc extension: %w", err)
+		}
+	}
+
+	generator, err := twcc.NewSenderInterceptor()
+	if err != nil {
+		return fmt.Errorf("failed to create twcc feedback generator: %w", err)
+	}
+	interceptors.Add(generator)
+	return nil
+}
+
+// onViewerEstimate handles a viewer's REMB bandwidth estimate, read from the
+// sender of sourceUserID's screen share or camera. The screen share manager
+// picks the viewer's layer with it, and the viewer's cameras get what the
+// chosen layer leaves.
+func (s *SFU) onViewerEstimate(viewerID, sourceUserID, trackKind string, bitrate uint64) {
+	s.mu.RLock()
+	sm := s.screenShareManager
+	cm := s.cameraManager
+	s.mu.RUnlock()
+
+	if sm != nil && trackKind == TrackVideo {
+		sm.onViewerEstimate(viewerID, sourceUserID, bitrate)
+	}
+	if cm != nil {
+		budget := bitrate
+		if sm != nil {
+			budget -= min(budget, sm.forwardedBitrate(viewerID))
+		}
+		cm.adaptToEstimate(viewerID, budget)
+	}
+}
@@ -0,0 +1,109 @@
+package sfu
+
+import (
+	"log/slog"
+	"time"
+)
+
+// minKeyframeInterval is the least time between PLIs sent for one video
+// track. Requests in between are merged into one sent when it has passed,
+// so a burst of new viewers costs the streamer's encoder one keyframe.
+const minKeyframeInterval = 500 * time.Millisecond
+
+// keyframeThrottle rate limits the PLIs sent for one video track.
+type keyframeThrottle struct {
+	last     time.Time
+	pending  bool // a merged PLI is scheduled
+	periodic bool // startPeriodicKeyframes is running
+}
+
+// request reports whether a PLI may be sent at now. Otherwise, if none is
+// scheduled yet, delay is how long until the merged one should be sent.
+func (t *keyframeThrottle) request(now time.Time) (send bool, delay time.Duration) {
+	if wait := t.last.Add(minKeyframeInterval).Sub(now); wait > 0 {
+		if t.pending {
+			return false, 0
+		}
+		t.pending = true
+		return false, wait
+	}
+	t.last = now
+	return true, 0
+}
+
+// fire records that the merged PLI was sent at now.
+func (t *keyframeThrottle) fire(now time.Time) {
+	t.pending = false
+	t.last = now
+}
+
+func (p *Peer) keyframeThrottleLocked(label string) *keyframeThrottle {
+	t, ok := p.keyframes[label]
+	if !ok {
+		t = &keyframeThrottle{}
+		p.keyframes[label] = t
+	}
+	return t
+}
+
+// RequestKeyframe asks the peer for a keyframe on the video track with
+// label, at most once per minKeyframeInterval.
+func (p *Peer) RequestKeyframe(label string) error {
+	p.keyframeMu.Lock()
+	send, delay := p.keyframeThrottleLocked(label).request(time.Now())
+	p.keyframeMu.Unlock()
+
+	if delay > 0 {
+		time.AfterFunc(delay, func() { p.sendMergedKeyframe(label) })
+	}
+	if !send {
+		return nil
+	}
+	return p.sendPLI(label)
+}
+
+func (p *Peer) sendMergedKeyframe(label string) {
+	p.keyframeMu.Lock()
+	p.keyframeThrottleLocked(label).fire(time.Now())
+	p.keyframeMu.Unlock()
+
+	if p.IsClosed() {
+		return
+	}
+	if err := p.sendPLI(label); err != nil {
+		slog.Debug("failed to send merged keyframe request", "component", "sfu", "peer_id", p.ID, "label", label, "error", err)
+	}
+}
+
+// startPeriodicKeyframes requests a keyframe on the video track with label
+// every Config.KeyframeInterval until the peer closes, so viewers who missed
+// one get a picture in time. Does nothing if the interval is unset or the
+// track already has them.
+func (p *Peer) startPeriodicKeyframes(label string) {
+	interval := p.sfu.config.KeyframeInterval
+	if interval <= 0 {
+		return
+	}
+
+	p.keyframeMu.Lock()
+	t := p.keyframeThrottleLocked(label)
+	started := t.periodic
+	t.periodic = true
+	p.keyframeMu.Unlock()
+	if started {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			if p.IsClosed() {
+				return
+			}
+			if err := p.RequestKeyframe(label); err != nil {
+				slog.Debug("failed to send periodic keyframe request", "component", "sfu", "peer_id", p.ID, "label", label, "error", err)
+			}
+		}
+	}()
+}
@@ -0,0 +1,30 @@
+package sfu
+
+import (
+	"testing"
+	"time"
+)
+
+func TestKeyframeThrottleMergesBursts(t *testing.T) {
+	var throttle keyframeThrottle
+	now := time.Now()
+
+	if send, _ := throttle.request(now); !send {
+		t.Fatal("first request was not sent")
+	}
+	send, delay := throttle.request(now.Add(100 * time.Millisecond))
+	if send || delay != minKeyframeInterval-100*time.Millisecond {
+		t.Fatalf("request within the interval: send = %v, delay = %v, want a merged PLI in %v", send, delay, minKeyframeInterval-100*time.Millisecond)
+	}
+	if send, delay := throttle.request(now.Add(200 * time.Millisecond)); send || delay != 0 {
+		t.Fatalf("second request within the interval: send = %v, delay = %v, want it merged", send, delay)
+	}
+
+	throttle.fire(now.Add(minKeyframeInterval))
+	if send, _ := throttle.request(now.Add(minKeyframeInterval + 100*time.Millisecond)); send {
+		t.Fatal("request right after the merged PLI was sent")
+	}
+	if send, _ := throttle.request(now.Add(3 * minKeyframeInterval)); !send {
+		t.Fatal("request after the interval was not sent")
+	}
+}
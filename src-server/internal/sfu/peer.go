@@ -2,13 +2,13 @@ package sfu
 
 import (
 	"fmt"
-	"lobby/internal/constants"
 	"log/slog"
 	"sync"
 	"sync/atomic"
 	"time"
 
 	"github.com/pion/rtcp"
+	"github.com/pion/rtp"
 	"github.com/pion/webrtc/v4"
 )
 
@@ -25,17 +25,32 @@ const (
 	peerCloseTimeout = 3 * time.Second
 )
 
+// Video track labels. A peer's screen share arrives on any video
+// transceiver, its camera only on the one EnsureCameraTransceiver added.
+const (
+	TrackVideo  = "video"
+	TrackCamera = "camera"
+)
+
 type Peer struct {
-	ID            string
-	conn          *webrtc.PeerConnection
-	sfu           *SFU
-	mu            sync.RWMutex
-	state         atomic.Int32
-	wg            sync.WaitGroup
-	localTracks   map[string]*webrtc.TrackLocalStaticRTP // trackKind -> track (e.g., "audio", "video")
-	outputTracks  map[string]*webrtc.RTPSender           // sourceUserID:trackKind -> sender
-	videoReceiver *webrtc.RTPReceiver                    // For PLI requests
-	videoSSRC     uint32                                 // Video track SSRC
+	ID           string
+	conn         *webrtc.PeerConnection
+	sfu          *SFU
+	mu           sync.RWMutex
+	state        atomic.Int32
+	wg           sync.WaitGroup
+	localTracks  map[string]*webrtc.TrackLocalStaticRTP // track label -> track ("audio", TrackVideo, TrackCamera)
+	outputTracks map[string]*webrtc.RTPSender           // sourceUserID:label -> sender
+	outputSSRCs  map[uint32]*webrtc.RTPSender           // outgoing SSRC -> sender, for sender reports
+	videoSSRCs   map[string][]uint32                    // video track label -> source SSRCs, for PLI requests
+	layers       map[string]*layeredTrack               // video track label -> quality layers (screen share)
+	cameraRecv   *webrtc.RTPTransceiver                 // set by EnsureCameraTransceiver
+	videoCodecs  []webrtc.RTPCodecParameters            // set by SetVideoCodecs, nil means defaultVideoCodecs
+	cameraMeter  bitrateMeter                           // bitrate of the camera the peer publishes
+	route        atomic.Pointer[PeerRoute]              // set once ICE selects a candidate pair
+	stats        statsSampler                           // totals of the previous Stats call
+	keyframeMu   sync.Mutex                             // guards keyframes
+	keyframes    map[string]*keyframeThrottle           // video track label -> PLI rate limit
 }
 
 func NewPeer(id string, sfu *SFU) (*Peer, error) {
@@ -51,8 +66,14 @@ func NewPeer(id string, sfu *SFU) (*Peer, error) {
 		sfu:          sfu,
 		localTracks:  make(map[string]*webrtc.TrackLocalStaticRTP),
 		outputTracks: make(map[string]*webrtc.RTPSender),
+		outputSSRCs:  make(map[uint32]*webrtc.RTPSender),
+		videoSSRCs:   make(map[string][]uint32),
+		layers:       make(map[string]*layeredTrack),
+		keyframes:    make(map[string]*keyframeThrottle),
 	}
 	peer.state.Store(int32(PeerStateConnecting))
+	peer.watchRoute()
+	sfu.senderReports.attach(conn.ID(), peer.outputClock)
 
 	conn.OnICECandidate(func(candidate *webrtc.ICECandidate) {
 		if candidate == nil {
@@ -74,7 +95,18 @@ func NewPeer(id string, sfu *SFU) (*Peer, error) {
 	})
 
 	conn.OnTrack(func(remoteTrack *webrtc.TrackRemote, receiver *webrtc.RTPReceiver) {
+		peer.mu.Lock()
 		trackKind := remoteTrack.Kind().String()
+		if peer.cameraRecv != nil && peer.cameraRecv.Receiver() == receiver {
+			trackKind = TrackCamera
+		}
+		peer.mu.Unlock()
+
+		clock := peer.readSenderReports(remoteTrack, receiver)
+		if trackKind == TrackVideo {
+			peer.addLayeredVideo(remoteTrack, clock)
+			return
+		}
 
 		localTrack, err := webrtc.NewTrackLocalStaticRTP(
 			remoteTrack.Codec().RTPCodecCapability,
@@ -89,31 +121,53 @@ func NewPeer(id string, sfu *SFU) (*Peer, error) {
 		peer.mu.Lock()
 		peer.localTracks[trackKind] = localTrack
 		if remoteTrack.Kind() == webrtc.RTPCodecTypeVideo {
-			peer.videoReceiver = receiver
-			peer.videoSSRC = uint32(remoteTrack.SSRC())
+			peer.videoSSRCs[trackKind] = []uint32{uint32(remoteTrack.SSRC())}
 		}
 		peer.mu.Unlock()
+		if remoteTrack.Kind() == webrtc.RTPCodecTypeVideo {
+			peer.startPeriodicKeyframes(trackKind)
+		}
 
 		sfu.OnPeerTrackReady(id, trackKind, localTrack)
 		peer.wg.Add(1)
-		go peer.forwardTrack(remoteTrack, localTrack, trackKind)
+		go peer.forwardTrack(remoteTrack, localTrack, trackKind, clock)
 	})
 
 	return peer, nil
 }
 
-func (p *Peer) forwardTrack(remote *webrtc.TrackRemote, local *webrtc.TrackLocalStaticRTP, kind string) {
+func (p *Peer) forwardTrack(remote *webrtc.TrackRemote, local *webrtc.TrackLocalStaticRTP, kind string, clock *sourceClock) {
 	defer p.wg.Done()
+	p.sfu.bindClock(local, clock)
+	defer p.sfu.unbindClock(local)
 
-	buf := make([]byte, constants.RTPPacketBufferBytes)
+	bufp := getPacketBuffer()
+	defer putPacketBuffer(bufp)
+	buf := *bufp
 
+	// Each packet is parsed once into pkt, whose payload points into buf, and
+	// shared by the relays, the tap and the write to subscribers
+	var pkt rtp.Packet
 	for {
 		n, _, err := remote.Read(buf)
 		if err != nil {
 			slog.Debug("track read ended", "component", "sfu", "peer_id", p.ID, "kind", kind, "error", err)
 			return
 		}
-		if _, err := local.Write(buf[:n]); err != nil {
+		if kind == "audio" && p.sfu.IsAudioSuppressed(p.ID) {
+			continue
+		}
+		if err := pkt.Unmarshal(buf[:n]); err != nil {
+			continue
+		}
+		if kind == "audio" {
+			p.sfu.relayPeerAudio(p.ID, &pkt)
+			p.sfu.tapAudio(p.ID, &pkt)
+		}
+		if kind == TrackCamera {
+			p.cameraMeter.count(n, time.Now())
+		}
+		if err := local.WriteRTP(&pkt); err != nil {
 			slog.Debug("track write error", "component", "sfu", "peer_id", p.ID, "kind", kind, "error", err)
 			return
 		}
@@ -121,13 +175,29 @@ func (p *Peer) forwardTrack(remote *webrtc.TrackRemote, local *webrtc.TrackLocal
 }
 
 // drainRTCP reads and discards RTCP packets from an RTP sender.
-// This prevents the RTCP receive buffer from filling up.
-func (p *Peer) drainRTCP(sender *webrtc.RTPSender) {
+// This prevents the RTCP receive buffer from filling up. Video senders are
+// parsed instead, so the viewer's REMB estimates reach congestion control.
+func (p *Peer) drainRTCP(sender *webrtc.RTPSender, sourceUserID, trackKind string) {
 	defer p.wg.Done()
 
-	buf := make([]byte, constants.RTPPacketBufferBytes)
+	if trackKind == TrackVideo || trackKind == TrackCamera {
+		for {
+			packets, _, err := sender.ReadRTCP()
+			if err != nil {
+				return
+			}
+			for _, pkt := range packets {
+				if remb, ok := pkt.(*rtcp.ReceiverEstimatedMaximumBitrate); ok {
+					p.sfu.onViewerEstimate(p.ID, sourceUserID, trackKind, uint64(remb.Bitrate))
+				}
+			}
+		}
+	}
+
+	bufp := getPacketBuffer()
+	defer putPacketBuffer(bufp)
 	for {
-		if _, _, err := sender.Read(buf); err != nil {
+		if _, _, err := sender.Read(*bufp); err != nil {
 			return
 		}
 	}
@@ -192,20 +262,49 @@ func (p *Peer) EnsureVideoTransceiver() error {
 		return ErrPeerNotActive
 	}
 	for _, t := range p.conn.GetTransceivers() {
-		if t.Kind() == webrtc.RTPCodecTypeVideo {
+		if t.Kind() == webrtc.RTPCodecTypeVideo && t != p.cameraRecv {
 			return nil
 		}
 	}
-	_, err := p.conn.AddTransceiverFromKind(webrtc.RTPCodecTypeVideo, webrtc.RTPTransceiverInit{
+	t, err := p.conn.AddTransceiverFromKind(webrtc.RTPCodecTypeVideo, webrtc.RTPTransceiverInit{
 		Direction: webrtc.RTPTransceiverDirectionSendrecv,
 	})
 	if err != nil {
 		return fmt.Errorf("failed to add video transceiver: %w", err)
 	}
+	if err := p.preferVideoCodecsLocked(t); err != nil {
+		return err
+	}
 	slog.Debug("added video transceiver for screen share", "component", "sfu", "peer_id", p.ID)
 	return nil
 }
 
+// EnsureCameraTransceiver adds the recvonly video transceiver the peer's
+// camera is sent on, if it doesn't exist yet. Tracks arriving on it are
+// labeled TrackCamera.
+func (p *Peer) EnsureCameraTransceiver() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.IsClosed() {
+		return ErrPeerNotActive
+	}
+	if p.cameraRecv != nil {
+		return nil
+	}
+	t, err := p.conn.AddTransceiverFromKind(webrtc.RTPCodecTypeVideo, webrtc.RTPTransceiverInit{
+		Direction: webrtc.RTPTransceiverDirectionRecvonly,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to add camera transceiver: %w", err)
+	}
+	if err := p.preferVideoCodecsLocked(t); err != nil {
+		return err
+	}
+	p.cameraRecv = t
+	slog.Debug("added camera transceiver", "component", "sfu", "peer_id", p.ID)
+	return nil
+}
+
 func (p *Peer) AddICECandidate(candidate webrtc.ICECandidateInit) error {
 	if p.IsClosed() {
 		return ErrPeerNotActive
@@ -226,21 +325,68 @@ func (p *Peer) AddTrack(sourceUserID string, trackKind string, track *webrtc.Tra
 		return nil
 	}
 
+	video := track.Kind() == webrtc.RTPCodecTypeVideo
+	if video && !hasVideoCodec(p.videoCodecsLocked(), track.Codec().MimeType) {
+		return ErrVideoCodecUnsupported
+	}
+
 	sender, err := p.conn.AddTrack(track)
 	if err != nil {
 		return err
 	}
+	if video {
+		for _, t := range p.conn.GetTransceivers() {
+			if t.Sender() == sender {
+				if err := p.preferVideoCodecsLocked(t); err != nil {
+					slog.Warn("error limiting video codecs", "component", "sfu", "peer_id", p.ID, "error", err)
+				}
+				break
+			}
+		}
+	}
 
 	p.outputTracks[key] = sender
+	if encodings := sender.GetParameters().Encodings; len(encodings) > 0 {
+		p.outputSSRCs[uint32(encodings[0].SSRC)] = sender
+	}
 
 	// Drain RTCP packets to prevent buffer overflow
 	p.wg.Add(1)
-	go p.drainRTCP(sender)
+	go p.drainRTCP(sender, sourceUserID, trackKind)
 
 	slog.Debug("added track to peer", "component", "sfu", "kind", trackKind, "source_id", sourceUserID, "peer_id", p.ID)
 	return nil
 }
 
+// HasOutputTrack reports whether the peer receives sourceUserID's trackKind.
+func (p *Peer) HasOutputTrack(sourceUserID string, trackKind string) bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	_, ok := p.outputTracks[sourceUserID+":"+trackKind]
+	return ok
+}
+
+// ReplaceTrack switches what an existing output track sends, without
+// renegotiating. The new track must use the same codec. A nil track pauses
+// the output until the next ReplaceTrack.
+func (p *Peer) ReplaceTrack(sourceUserID string, trackKind string, track *webrtc.TrackLocalStaticRTP) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.IsClosed() {
+		return nil
+	}
+
+	sender, exists := p.outputTracks[sourceUserID+":"+trackKind]
+	if !exists {
+		return nil
+	}
+	if track == nil {
+		return sender.ReplaceTrack(nil)
+	}
+	return sender.ReplaceTrack(track)
+}
+
 func (p *Peer) RemoveTrack(sourceUserID string, trackKind string) error {
 	p.mu.Lock()
 	defer p.mu.Unlock()
@@ -260,6 +406,7 @@ func (p *Peer) RemoveTrack(sourceUserID string, trackKind string) error {
 	}
 
 	delete(p.outputTracks, key)
+	p.forgetOutputSSRCLocked(sender)
 	slog.Debug("removed track from peer", "component", "sfu", "kind", trackKind, "source_id", sourceUserID, "peer_id", p.ID)
 	return nil
 }
@@ -288,6 +435,7 @@ func (p *Peer) RemoveAllTracksFrom(sourceUserID string) error {
 			continue
 		}
 		delete(p.outputTracks, key)
+		p.forgetOutputSSRCLocked(sender)
 		slog.Debug("removed track from peer", "component", "sfu", "track_key", key, "peer_id", p.ID)
 	}
 
@@ -300,21 +448,44 @@ func (p *Peer) GetLocalTrack(trackKind string) *webrtc.TrackLocalStaticRTP {
 	return p.localTracks[trackKind]
 }
 
-// RequestKeyframe sends a PLI (Picture Loss Indication) to request a keyframe
-func (p *Peer) RequestKeyframe() error {
+// sendPLI sends a PLI (Picture Loss Indication) to request a keyframe on
+// the video track with label, on every simulcast layer it has
+func (p *Peer) sendPLI(label string) error {
 	p.mu.RLock()
-	receiver := p.videoReceiver
-	ssrc := p.videoSSRC
+	ssrcs := p.videoSSRCs[label]
 	p.mu.RUnlock()
 
-	if receiver == nil {
+	if len(ssrcs) == 0 {
 		return nil
 	}
 
-	// Send PLI (Picture Loss Indication) to request a keyframe
-	return p.conn.WriteRTCP([]rtcp.Packet{
-		&rtcp.PictureLossIndication{MediaSSRC: ssrc},
-	})
+	packets := make([]rtcp.Packet, 0, len(ssrcs))
+	for _, ssrc := range ssrcs {
+		packets = append(packets, &rtcp.PictureLossIndication{MediaSSRC: ssrc})
+	}
+	return p.conn.WriteRTCP(packets)
+}
+
+// unlimitedBitrate is sent as the REMB estimate to lift a bitrate limit
+const unlimitedBitrate = 1 << 30
+
+// LimitBitrate asks the peer to send the video track with label at no more
+// than bps bits per second, by sending it a REMB estimate. 0 lifts the limit.
+func (p *Peer) LimitBitrate(label string, bps uint64) error {
+	p.mu.RLock()
+	ssrcs := p.videoSSRCs[label]
+	p.mu.RUnlock()
+
+	if len(ssrcs) == 0 {
+		return nil
+	}
+	if bps == 0 {
+		bps = unlimitedBitrate
+	}
+	return p.conn.WriteRTCP([]rtcp.Packet{&rtcp.ReceiverEstimatedMaximumBitrate{
+		Bitrate: float32(bps),
+		SSRCs:   ssrcs,
+	}})
 }
 
 func (p *Peer) IsReadyForRenegotiation() bool {
@@ -0,0 +1,45 @@
+package sfu
+
+import (
+	"sync"
+
+	"github.com/pion/rtp"
+
+	"lobby/internal/constants"
+)
+
+// packetBufferPool holds the read buffers of forwarding goroutines, so tracks
+// coming and going under load reuse buffers instead of allocating them.
+var packetBufferPool = sync.Pool{
+	New: func() any {
+		buf := make([]byte, constants.RTPPacketBufferBytes)
+		return &buf
+	},
+}
+
+func getPacketBuffer() *[]byte {
+	return packetBufferPool.Get().(*[]byte)
+}
+
+func putPacketBuffer(buf *[]byte) {
+	packetBufferPool.Put(buf)
+}
+
+// rtpPacketPool holds packets built per frame outside the forwarding
+// goroutines, which each reuse one packet of their own.
+var rtpPacketPool = sync.Pool{
+	New: func() any {
+		return &rtp.Packet{}
+	},
+}
+
+func getRTPPacket() *rtp.Packet {
+	return rtpPacketPool.Get().(*rtp.Packet)
+}
+
+// putRTPPacket returns packet to the pool, dropping its payload so the pool
+// does not keep the caller's buffer alive.
+func putRTPPacket(packet *rtp.Packet) {
+	*packet = rtp.Packet{}
+	rtpPacketPool.Put(packet)
+}
@@ -0,0 +1,28 @@
+package sfu
+
+import "testing"
+
+func TestRelayAudioForwardingDoesNotAllocate(t *testing.T) {
+	s, err := New(&Config{})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	t.Cleanup(s.Close)
+	if err := s.AddRelay("usr_1", func(RelayFrame) {}); err != nil {
+		t.Fatalf("AddRelay() error = %v", err)
+	}
+	if err := s.AddRelay("usr_2", func(RelayFrame) {}); err != nil {
+		t.Fatalf("AddRelay() error = %v", err)
+	}
+
+	frame := RelayFrame{Sequence: 1, Timestamp: 960, Payload: make([]byte, 80)}
+	allocs := testing.AllocsPerRun(100, func() {
+		frame.Sequence++
+		if err := s.WriteRelayAudio("usr_1", frame); err != nil {
+			t.Fatalf("WriteRelayAudio() error = %v", err)
+		}
+	})
+	if allocs > 0 {
+		t.Fatalf("WriteRelayAudio allocated %v times per frame, want 0", allocs)
+	}
+}
@@ -0,0 +1,183 @@
+package sfu
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"log/slog"
+
+	"github.com/pion/rtp"
+	"github.com/pion/webrtc/v4"
+)
+
+// RelayFrame is one Opus packet carried over the gateway instead of WebRTC.
+type RelayFrame struct {
+	SourceUserID string
+	Sequence     uint16
+	Timestamp    uint32 // RTP timestamp at 48 kHz
+	Payload      []byte // only valid for the duration of a RelaySink call
+}
+
+// maxStackRelaySinks is how many relay sinks a forwarded frame collects
+// without allocating. Relays are a rare fallback, so more is unusual.
+const maxStackRelaySinks = 8
+
+// RelaySink receives the audio a relay participant should hear. It is called
+// from media goroutines and must not block.
+type RelaySink func(frame RelayFrame)
+
+// relay is a voice participant whose audio travels over the websocket
+// because neither UDP nor TURN could connect. Its frames are written into
+// track, which WebRTC peers subscribe to like any other audio track.
+// Remote relays are users connected to another instance; they have no sink.
+type relay struct {
+	track  *webrtc.TrackLocalStaticRTP
+	ssrc   uint32
+	sink   RelaySink
+	remote bool
+}
+
+// AddRelay switches userID to relayed audio. Any WebRTC peer for userID
+// should be removed first. Existing peers are offered the relay's audio
+// track, and sink starts receiving everyone else's audio.
+func (s *SFU) AddRelay(userID string, sink RelaySink) error {
+	return s.addRelay(userID, sink, false)
+}
+
+func (s *SFU) addRelay(userID string, sink RelaySink, remote bool) error {
+	track, err := webrtc.NewTrackLocalStaticRTP(opusCapability, "audio", userID)
+	if err != nil {
+		return fmt.Errorf("creating relay track: %w", err)
+	}
+	var ssrc [4]byte
+	if _, err := rand.Read(ssrc[:]); err != nil {
+		return fmt.Errorf("generating relay SSRC: %w", err)
+	}
+
+	s.mu.Lock()
+	if s.relays == nil {
+		s.relays = make(map[string]*relay)
+	}
+	s.relays[userID] = &relay{track: track, ssrc: binary.BigEndian.Uint32(ssrc[:]), sink: sink, remote: remote}
+	s.mu.Unlock()
+
+	s.OnPeerTrackReady(userID, "audio", track)
+	slog.Info("added audio relay", "component", "sfu", "user_id", userID, "remote", remote)
+	return nil
+}
+
+// RemoveRelay stops relaying userID's audio and drops its track from peers.
+func (s *SFU) RemoveRelay(userID string) {
+	s.mu.Lock()
+	if _, ok := s.relays[userID]; !ok {
+		s.mu.Unlock()
+		return
+	}
+	delete(s.relays, userID)
+	s.forgetWhisperLocked(userID)
+	otherPeers := make(map[string]*Peer)
+	for otherUserID, otherPeer := range s.peers {
+		if !otherPeer.IsClosed() {
+			otherPeers[otherUserID] = otherPeer
+		}
+	}
+	s.mu.Unlock()
+
+	for otherUserID, otherPeer := range otherPeers {
+		if otherPeer.IsClosed() {
+			continue
+		}
+		if err := otherPeer.RemoveAllTracksFrom(userID); err != nil {
+			slog.Error("error removing relay track from peer", "component", "sfu", "peer_id", otherUserID, "error", err)
+		}
+		s.triggerRenegotiation(otherUserID, otherPeer)
+	}
+
+	slog.Info("removed audio relay", "component", "sfu", "user_id", userID)
+}
+
+// IsRelay reports whether userID's audio is relayed over the websocket.
+func (s *SFU) IsRelay(userID string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	_, ok := s.relays[userID]
+	return ok
+}
+
+// WriteRelayAudio publishes an Opus frame sent by relay participant userID to
+// WebRTC peers and to the other relays, and cascades it to other instances
+// unless userID is remote. Server-muted users are dropped.
+func (s *SFU) WriteRelayAudio(userID string, frame RelayFrame) error {
+	if s.IsAudioSuppressed(userID) {
+		return nil
+	}
+
+	s.mu.RLock()
+	source, ok := s.relays[userID]
+	var buf [maxStackRelaySinks]RelaySink
+	sinks := s.relaySinksLocked(buf[:0], userID)
+	_, whispering := s.whispers[userID]
+	s.mu.RUnlock()
+	if !ok {
+		return fmt.Errorf("user %s has no audio relay", userID)
+	}
+
+	frame.SourceUserID = userID
+	for _, sink := range sinks {
+		sink(frame)
+	}
+	if cascade := s.cascade.Load(); cascade != nil && !source.remote && !whispering {
+		(*cascade)(frame)
+	}
+
+	packet := getRTPPacket()
+	defer putRTPPacket(packet)
+	packet.Header = rtp.Header{
+		Version:        2,
+		PayloadType:    opusPayloadType,
+		SequenceNumber: frame.Sequence,
+		Timestamp:      frame.Timestamp,
+		SSRC:           source.ssrc,
+	}
+	packet.Payload = frame.Payload
+	s.tapAudio(userID, packet)
+	return source.track.WriteRTP(packet)
+}
+
+// relayPeerAudio hands an RTP audio packet from WebRTC peer userID to every
+// relay participant and to the cascade sink.
+func (s *SFU) relayPeerAudio(userID string, packet *rtp.Packet) {
+	s.mu.RLock()
+	var buf [maxStackRelaySinks]RelaySink
+	sinks := s.relaySinksLocked(buf[:0], userID)
+	_, whispering := s.whispers[userID]
+	s.mu.RUnlock()
+	if cascade := s.cascade.Load(); cascade != nil && !whispering {
+		sinks = append(sinks, *cascade)
+	}
+	if len(sinks) == 0 {
+		return
+	}
+
+	frame := RelayFrame{
+		SourceUserID: userID,
+		Sequence:     packet.SequenceNumber,
+		Timestamp:    packet.Timestamp,
+		Payload:      packet.Payload,
+	}
+	for _, sink := range sinks {
+		sink(frame)
+	}
+}
+
+// relaySinksLocked appends to sinks the sink of every local relay except
+// userID's that may hear userID. Callers pass a stack array so forwarding a
+// frame does not allocate. s.mu must be held.
+func (s *SFU) relaySinksLocked(sinks []RelaySink, userID string) []RelaySink {
+	for relayUserID, r := range s.relays {
+		if relayUserID != userID && r.sink != nil && s.mayHearLocked(userID, relayUserID) {
+			sinks = append(sinks, r.sink)
+		}
+	}
+	return sinks
+}
@@ -0,0 +1,66 @@
+package sfu
+
+import (
+	"log/slog"
+	"slices"
+	"strings"
+
+	"github.com/pion/webrtc/v4"
+)
+
+// PeerRoute is how a peer's media reaches the SFU: the candidate types and
+// transport of the ICE pair it selected. A relay remote candidate means the
+// client goes through TURN, possibly over TCP/TLS on its side.
+type PeerRoute struct {
+	UserID     string
+	LocalType  string // host, srflx, prflx, or relay on the SFU side
+	RemoteType string // same, on the client side
+	Protocol   string // udp or tcp between the two
+}
+
+func routeFromPair(pair *webrtc.ICECandidatePair) *PeerRoute {
+	if pair == nil || pair.Local == nil || pair.Remote == nil {
+		return nil
+	}
+	return &PeerRoute{
+		LocalType:  pair.Local.Typ.String(),
+		RemoteType: pair.Remote.Typ.String(),
+		Protocol:   pair.Local.Protocol.String(),
+	}
+}
+
+// watchRoute records the peer's route whenever ICE selects a candidate pair.
+func (p *Peer) watchRoute() {
+	p.conn.SCTP().Transport().ICETransport().OnSelectedCandidatePairChange(func(pair *webrtc.ICECandidatePair) {
+		route := routeFromPair(pair)
+		if route == nil {
+			return
+		}
+		route.UserID = p.ID
+		p.route.Store(route)
+		slog.Info("peer route selected", "component", "sfu", "peer_id", p.ID, "local", route.LocalType, "remote", route.RemoteType, "protocol", route.Protocol)
+	})
+}
+
+// Route returns the peer's current route, or nil before ICE has selected one.
+func (p *Peer) Route() *PeerRoute {
+	return p.route.Load()
+}
+
+// PeerRoutes returns the routes of the peers ICE has connected, sorted by
+// user ID.
+func (s *SFU) PeerRoutes() []PeerRoute {
+	s.mu.RLock()
+	routes := make([]PeerRoute, 0, len(s.peers))
+	for _, peer := range s.peers {
+		if route := peer.Route(); route != nil {
+			routes = append(routes, *route)
+		}
+	}
+	s.mu.RUnlock()
+
+	slices.SortFunc(routes, func(a, b PeerRoute) int {
+		return strings.Compare(a.UserID, b.UserID)
+	})
+	return routes
+}
@@ -1,8 +1,10 @@
 package sfu
 
 import (
+	"fmt"
 	"log/slog"
 	"sync"
+	"time"
 
 	"github.com/pion/webrtc/v4"
 )
@@ -22,7 +24,25 @@ type ScreenShareManager struct {
 	subscriptions    map[string]string            // viewerID -> streamerID
 	streamerViewers  map[string]map[string]bool   // streamerID -> set of viewerIDs
 	pendingKeyframes map[string]string            // viewerID -> streamerID (pending keyframe requests)
+	viewerLayers     map[string]string            // viewerID -> quality layer forwarded to it
+	viewerEstimates  map[string]uint64            // viewerID -> latest REMB bandwidth estimate (bps)
+	viewerCaps       map[string]uint64            // viewerID -> bitrate cap of its subscription (bps)
+	streamerCaps     map[string]uint64            // streamerID -> bitrate limit last sent to it (bps)
+	viewerRamps      map[string]time.Time         // viewerID -> when its layer last changed, while ramping up
 	onUpdateCallback func(userID string, streaming bool)
+	singleShare      bool          // reject StartShare while another user is sharing
+	maxViewerBitrate uint64        // cap on what each viewer is sent (bps), 0 for none
+	rampInterval     time.Duration // least time between a new viewer's layer steps up, 0 for no ramp-up
+}
+
+// ShareInUseError is returned by StartShare when the single-share policy is
+// enabled and another user already holds the active share.
+type ShareInUseError struct {
+	ActiveUserID string
+}
+
+func (e *ShareInUseError) Error() string {
+	return fmt.Sprintf("screen share already active for user %s", e.ActiveUserID)
 }
 
 func NewScreenShareManager(sfu *SFU) *ScreenShareManager {
@@ -32,6 +52,11 @@ func NewScreenShareManager(sfu *SFU) *ScreenShareManager {
 		subscriptions:    make(map[string]string),
 		streamerViewers:  make(map[string]map[string]bool),
 		pendingKeyframes: make(map[string]string),
+		viewerLayers:     make(map[string]string),
+		viewerEstimates:  make(map[string]uint64),
+		viewerCaps:       make(map[string]uint64),
+		streamerCaps:     make(map[string]uint64),
+		viewerRamps:      make(map[string]time.Time),
 	}
 
 	return sm
@@ -43,14 +68,45 @@ func (sm *ScreenShareManager) SetUpdateCallback(cb func(userID string, streaming
 	sm.onUpdateCallback = cb
 }
 
+// SetSingleShare toggles the "one active share per room" policy.
+func (sm *ScreenShareManager) SetSingleShare(enabled bool) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	sm.singleShare = enabled
+}
+
+// SetMaxViewerBitrate caps the screen share bitrate sent to each viewer, in
+// bits per second. 0 removes the cap.
+func (sm *ScreenShareManager) SetMaxViewerBitrate(bps uint64) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	sm.maxViewerBitrate = bps
+}
+
+// SetViewerRampInterval makes new viewers of a layered share start on its
+// lowest layer and step up at most one layer per interval. 0 starts them on
+// the layer their bandwidth estimate allows.
+func (sm *ScreenShareManager) SetViewerRampInterval(interval time.Duration) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	sm.rampInterval = interval
+}
+
 // The broadcast to clients happens later when the video track actually arrives
-func (sm *ScreenShareManager) StartShare(userID string) {
+func (sm *ScreenShareManager) StartShare(userID string) error {
 	sm.mu.Lock()
 
 	// Only register if not already registered
 	if _, exists := sm.activeStreams[userID]; exists {
 		sm.mu.Unlock()
-		return
+		return nil
+	}
+
+	if sm.singleShare {
+		for activeUserID := range sm.activeStreams {
+			sm.mu.Unlock()
+			return &ShareInUseError{ActiveUserID: activeUserID}
+		}
 	}
 
 	// Check if peer already has a video track from a previous share in this session
@@ -58,7 +114,7 @@ func (sm *ScreenShareManager) StartShare(userID string) {
 	peer := sm.sfu.GetPeer(userID)
 	var existingTrack *webrtc.TrackLocalStaticRTP
 	if peer != nil {
-		existingTrack = peer.GetLocalTrack("video")
+		existingTrack = peer.GetLocalTrack(TrackVideo)
 	}
 
 	// Create state with HasTrack=false until track arrives (or is reused)
@@ -75,7 +131,7 @@ func (sm *ScreenShareManager) StartShare(userID string) {
 	if existingTrack != nil {
 		slog.Debug("reusing existing video track", "component", "screenshare", "user_id", userID)
 		sm.onVideoTrackReady(userID, existingTrack)
-		return
+		return nil
 	}
 
 	if peer != nil {
@@ -86,6 +142,7 @@ func (sm *ScreenShareManager) StartShare(userID string) {
 
 	slog.Debug("registered for streaming, waiting for video track", "component", "screenshare", "user_id", userID)
 	// NOTE: We do NOT broadcast here - wait for onVideoTrackReady
+	return nil
 }
 
 func (sm *ScreenShareManager) StopShare(userID string) {
@@ -108,10 +165,12 @@ func (sm *ScreenShareManager) StopShare(userID string) {
 
 	delete(sm.activeStreams, userID)
 	delete(sm.streamerViewers, userID)
+	delete(sm.streamerCaps, userID)
 
 	// Clean up subscriptions
 	for _, viewerID := range viewerIDs {
 		delete(sm.subscriptions, viewerID)
+		delete(sm.viewerCaps, viewerID)
 	}
 
 	cb := sm.onUpdateCallback
@@ -130,7 +189,11 @@ func (sm *ScreenShareManager) StopShare(userID string) {
 	}
 }
 
-func (sm *ScreenShareManager) Subscribe(viewerID, streamerID string) error {
+// Subscribe starts forwarding streamerID's share to viewerID. maxBitrate
+// caps what the viewer is sent, in bits per second (0 for no cap of its own);
+// the server-wide cap still applies. Subscribing again to the same share
+// updates the cap.
+func (sm *ScreenShareManager) Subscribe(viewerID, streamerID string, maxBitrate uint64) error {
 	sm.mu.Lock()
 	state, exists := sm.activeStreams[streamerID]
 	if !exists {
@@ -146,6 +209,12 @@ func (sm *ScreenShareManager) Subscribe(viewerID, streamerID string) error {
 		return nil
 	}
 
+	// Forward only a codec the viewer can decode
+	if peer := sm.sfu.GetPeer(viewerID); peer != nil && state.Track != nil && !peer.AcceptsVideoCodec(state.Track.Codec().MimeType) {
+		sm.mu.Unlock()
+		return ErrVideoCodecUnsupported
+	}
+
 	// Unsubscribe from current stream if any
 	if currentStreamer, isSubscribed := sm.subscriptions[viewerID]; isSubscribed && currentStreamer != streamerID {
 		delete(sm.streamerViewers[currentStreamer], viewerID)
@@ -167,11 +236,23 @@ func (sm *ScreenShareManager) Subscribe(viewerID, streamerID string) error {
 		sm.streamerViewers[streamerID] = make(map[string]bool)
 	}
 	sm.streamerViewers[streamerID][viewerID] = true
+	if sm.maxViewerBitrate > 0 && (maxBitrate == 0 || maxBitrate > sm.maxViewerBitrate) {
+		maxBitrate = sm.maxViewerBitrate
+	}
+	if maxBitrate > 0 {
+		sm.viewerCaps[viewerID] = maxBitrate
+	} else {
+		delete(sm.viewerCaps, viewerID)
+	}
+	_, forwarding := sm.viewerLayers[viewerID]
 	track := state.Track
 	sm.mu.Unlock()
 
-	// Add video track to viewer
-	if track != nil {
+	sm.limitStreamer(streamerID)
+	if forwarding {
+		// Already watching: the new cap may call for another layer
+		sm.switchLayer(streamerID, viewerID)
+	} else if track != nil {
 		sm.addVideoTrackToViewer(streamerID, viewerID, track)
 	}
 
@@ -189,12 +270,14 @@ func (sm *ScreenShareManager) Unsubscribe(viewerID string) {
 
 	delete(sm.subscriptions, viewerID)
 	delete(sm.pendingKeyframes, viewerID)
+	delete(sm.viewerCaps, viewerID)
 	if sm.streamerViewers[streamerID] != nil {
 		delete(sm.streamerViewers[streamerID], viewerID)
 	}
 	sm.mu.Unlock()
 
 	sm.removeVideoTrackFromViewer(streamerID, viewerID)
+	sm.limitStreamer(streamerID)
 	slog.Debug("user unsubscribed from stream", "component", "screenshare", "viewer_id", viewerID, "streamer_id", streamerID)
 }
 
@@ -204,6 +287,11 @@ func (sm *ScreenShareManager) OnUserDisconnect(userID string) {
 
 	// Unsubscribe if user was viewing
 	sm.Unsubscribe(userID)
+
+	sm.mu.Lock()
+	delete(sm.viewerEstimates, userID)
+	delete(sm.viewerCaps, userID)
+	sm.mu.Unlock()
 }
 
 func (sm *ScreenShareManager) IsStreaming(userID string) bool {
@@ -282,7 +370,14 @@ func (sm *ScreenShareManager) addVideoTrackToViewer(streamerID, viewerID string,
 		return
 	}
 
-	if err := peer.AddTrack(streamerID, "video", track); err != nil {
+	// Start a layered stream on the layer the viewer's bandwidth allows, or
+	// the lowest when it ramps up
+	layer, ramp := sm.startLayer(streamerID, viewerID)
+	if layer != nil {
+		track = layer.track
+	}
+
+	if err := peer.AddTrack(streamerID, TrackVideo, track); err != nil {
 		slog.Error("error adding video track to viewer", "component", "screenshare", "viewer_id", viewerID, "error", err)
 		return
 	}
@@ -290,6 +385,12 @@ func (sm *ScreenShareManager) addVideoTrackToViewer(streamerID, viewerID string,
 	// Store pending keyframe request - will be triggered after renegotiation completes
 	sm.mu.Lock()
 	sm.pendingKeyframes[viewerID] = streamerID
+	if layer != nil {
+		sm.viewerLayers[viewerID] = layer.id
+	}
+	if ramp {
+		sm.viewerRamps[viewerID] = time.Now()
+	}
 	sm.mu.Unlock()
 
 	sm.sfu.TriggerRenegotiation(viewerID)
@@ -312,7 +413,7 @@ func (sm *ScreenShareManager) OnRenegotiationComplete(viewerID string) {
 	// Now request keyframe from streamer - viewer is ready to receive
 	streamerPeer := sm.sfu.GetPeer(streamerID)
 	if streamerPeer != nil && !streamerPeer.IsClosed() {
-		if err := streamerPeer.RequestKeyframe(); err != nil {
+		if err := streamerPeer.RequestKeyframe(TrackVideo); err != nil {
 			slog.Error("error requesting keyframe", "component", "screenshare", "streamer_id", streamerID, "error", err)
 		} else {
 			slog.Debug("requested keyframe", "component", "screenshare", "streamer_id", streamerID, "viewer_id", viewerID)
@@ -326,10 +427,223 @@ func (sm *ScreenShareManager) removeVideoTrackFromViewer(streamerID, viewerID st
 		return
 	}
 
-	if err := peer.RemoveTrack(streamerID, "video"); err != nil {
+	if err := peer.RemoveTrack(streamerID, TrackVideo); err != nil {
 		slog.Error("error removing video track from viewer", "component", "screenshare", "viewer_id", viewerID, "error", err)
 		return
 	}
 
+	sm.mu.Lock()
+	delete(sm.viewerLayers, viewerID)
+	delete(sm.viewerRamps, viewerID)
+	sm.mu.Unlock()
+
 	sm.sfu.TriggerRenegotiation(viewerID)
 }
+
+// shareLayers returns the quality layers of streamerID's share, or nil if
+// it has none.
+func (sm *ScreenShareManager) shareLayers(streamerID string) *layeredTrack {
+	streamerPeer := sm.sfu.GetPeer(streamerID)
+	if streamerPeer == nil {
+		return nil
+	}
+	return streamerPeer.videoLayers(TrackVideo)
+}
+
+// viewerBudget returns the bandwidth viewerID's layer is picked with: its
+// estimate, lowered to its cap. 0 means unknown.
+func (sm *ScreenShareManager) viewerBudget(viewerID string) uint64 {
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+	estimate := sm.viewerEstimates[viewerID]
+	if limit := sm.viewerCaps[viewerID]; limit > 0 && (estimate == 0 || limit < estimate) {
+		estimate = limit
+	}
+	return estimate
+}
+
+// selectLayer picks the quality layer of streamerID's share to forward to
+// viewerID, or nil if the share has no layers.
+func (sm *ScreenShareManager) selectLayer(streamerID, viewerID string) *videoLayer {
+	layers := sm.shareLayers(streamerID)
+	if layers == nil {
+		return nil
+	}
+
+	sm.mu.RLock()
+	current := sm.viewerLayers[viewerID]
+	sm.mu.RUnlock()
+	return layers.selectLayer(current, sm.viewerBudget(viewerID), time.Now())
+}
+
+// startLayer picks the layer a new viewer of streamerID's share starts on,
+// and whether it ramps up from there. With a ramp interval set that is the
+// lowest running layer, so a constrained link is not flooded with the full
+// bitrate before the viewer's first estimate arrives.
+func (sm *ScreenShareManager) startLayer(streamerID, viewerID string) (*videoLayer, bool) {
+	sm.mu.RLock()
+	interval := sm.rampInterval
+	sm.mu.RUnlock()
+
+	if interval > 0 {
+		if layers := sm.shareLayers(streamerID); layers != nil {
+			if lowest := layers.lowest(time.Now()); lowest != nil {
+				return lowest, true
+			}
+		}
+	}
+	return sm.selectLayer(streamerID, viewerID), false
+}
+
+// rampLayer paces a ramping viewer's way up from currentID towards target,
+// the layer selectLayer picked. The viewer moves at most one layer up per
+// ramp interval, and takes the step once the next layer fits its estimate,
+// without the headroom selectLayer asks for: the step probes whether the
+// link carries it, and the estimates that follow move the viewer back down
+// if not. Moving down is never held back. The ramp ends at the top layer.
+func (sm *ScreenShareManager) rampLayer(streamerID, viewerID, currentID string, target *videoLayer, rampedAt time.Time) *videoLayer {
+	layers := sm.shareLayers(streamerID)
+	if layers == nil {
+		return target
+	}
+	current := layers.layer(currentID)
+	if current == nil {
+		return target
+	}
+	now := time.Now()
+	next, nextBitrate := layers.above(currentID, now)
+	if next == nil {
+		sm.mu.Lock()
+		delete(sm.viewerRamps, viewerID)
+		sm.mu.Unlock()
+		return target
+	}
+	if target != current && target.Bitrate(now) < current.Bitrate(now) {
+		return target
+	}
+
+	sm.mu.RLock()
+	interval := sm.rampInterval
+	sm.mu.RUnlock()
+	if now.Sub(rampedAt) < interval {
+		return current
+	}
+	if budget := sm.viewerBudget(viewerID); budget == 0 || nextBitrate <= budget {
+		return next
+	}
+	return current
+}
+
+// onViewerEstimate records a viewer's bandwidth estimate and switches it to
+// another layer of the share it is watching when that fits better.
+func (sm *ScreenShareManager) onViewerEstimate(viewerID, streamerID string, bitrate uint64) {
+	sm.mu.Lock()
+	sm.viewerEstimates[viewerID] = bitrate
+	_, forwarding := sm.viewerLayers[viewerID]
+	watching := sm.subscriptions[viewerID] == streamerID
+	sm.mu.Unlock()
+
+	if !watching || !forwarding {
+		return
+	}
+	sm.switchLayer(streamerID, viewerID)
+}
+
+// switchLayer moves viewerID to the layer of streamerID's share that now
+// fits it best, if that is another one.
+func (sm *ScreenShareManager) switchLayer(streamerID, viewerID string) {
+	sm.mu.RLock()
+	current := sm.viewerLayers[viewerID]
+	rampedAt, ramping := sm.viewerRamps[viewerID]
+	sm.mu.RUnlock()
+
+	layer := sm.selectLayer(streamerID, viewerID)
+	if layer != nil && ramping {
+		layer = sm.rampLayer(streamerID, viewerID, current, layer, rampedAt)
+	}
+	if layer == nil || layer.id == current {
+		return
+	}
+
+	peer := sm.sfu.GetPeer(viewerID)
+	if peer == nil || peer.IsClosed() {
+		return
+	}
+	if err := peer.ReplaceTrack(streamerID, TrackVideo, layer.track); err != nil {
+		slog.Error("error switching screen share layer", "component", "screenshare", "viewer_id", viewerID, "error", err)
+		return
+	}
+
+	sm.mu.Lock()
+	sm.viewerLayers[viewerID] = layer.id
+	if _, ok := sm.viewerRamps[viewerID]; ok {
+		sm.viewerRamps[viewerID] = time.Now()
+	}
+	sm.mu.Unlock()
+
+	// The new layer needs a keyframe before the viewer can decode it
+	if streamerPeer := sm.sfu.GetPeer(streamerID); streamerPeer != nil && !streamerPeer.IsClosed() {
+		if err := streamerPeer.RequestKeyframe(TrackVideo); err != nil {
+			slog.Error("error requesting keyframe", "component", "screenshare", "streamer_id", streamerID, "error", err)
+		}
+	}
+	slog.Debug("switched screen share layer", "component", "screenshare", "viewer_id", viewerID, "streamer_id", streamerID, "layer", layer.id)
+}
+
+// limitStreamer asks streamerID to send no more than its most generous
+// viewer may receive, so capped viewers don't cost the streamer's uplink or
+// the SFU's inbound bandwidth for video nobody is sent. The limit is sent as
+// REMB whenever it changes; an uncapped viewer lifts it.
+func (sm *ScreenShareManager) limitStreamer(streamerID string) {
+	sm.mu.Lock()
+	var limit uint64
+	for viewerID := range sm.streamerViewers[streamerID] {
+		viewerCap := sm.viewerCaps[viewerID]
+		if viewerCap == 0 {
+			limit = 0
+			break
+		}
+		limit = max(limit, viewerCap)
+	}
+	if sm.streamerCaps[streamerID] == limit {
+		sm.mu.Unlock()
+		return
+	}
+	if limit > 0 {
+		sm.streamerCaps[streamerID] = limit
+	} else {
+		delete(sm.streamerCaps, streamerID)
+	}
+	sm.mu.Unlock()
+
+	streamerPeer := sm.sfu.GetPeer(streamerID)
+	if streamerPeer == nil || streamerPeer.IsClosed() {
+		return
+	}
+	if err := streamerPeer.LimitBitrate(TrackVideo, limit); err != nil {
+		slog.Error("error limiting screen share bitrate", "component", "screenshare", "streamer_id", streamerID, "error", err)
+		return
+	}
+	slog.Debug("limited screen share bitrate", "component", "screenshare", "streamer_id", streamerID, "limit_bps", limit)
+}
+
+// forwardedBitrate returns the bitrate of the screen share layer forwarded
+// to viewerID, or 0 if it is not watching one.
+func (sm *ScreenShareManager) forwardedBitrate(viewerID string) uint64 {
+	sm.mu.RLock()
+	streamerID, watching := sm.subscriptions[viewerID]
+	current, forwarding := sm.viewerLayers[viewerID]
+	sm.mu.RUnlock()
+	if !watching || !forwarding {
+		return 0
+	}
+
+	layers := sm.shareLayers(streamerID)
+	if layers == nil {
+		return 0
+	}
+	if layer := layers.layer(current); layer != nil {
+		return layer.Bitrate(time.Now())
+	}
+	return 0
+}
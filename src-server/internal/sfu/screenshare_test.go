@@ -0,0 +1,221 @@
+package sfu
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/pion/webrtc/v4"
+)
+
+func newTestScreenShareManager(t *testing.T) *ScreenShareManager {
+	t.Helper()
+
+	s, err := New(&Config{})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	t.Cleanup(s.Close)
+
+	return NewScreenShareManager(s)
+}
+
+func TestStartShareSingleSharePolicy(t *testing.T) {
+	sm := newTestScreenShareManager(t)
+	sm.SetSingleShare(true)
+
+	if err := sm.StartShare("usr_1"); err != nil {
+		t.Fatalf("StartShare(usr_1) error = %v", err)
+	}
+	if err := sm.StartShare("usr_1"); err != nil {
+		t.Fatalf("StartShare(usr_1) repeat error = %v, want nil", err)
+	}
+
+	err := sm.StartShare("usr_2")
+	var inUse *ShareInUseError
+	if !errors.As(err, &inUse) {
+		t.Fatalf("StartShare(usr_2) error = %v, want *ShareInUseError", err)
+	}
+	if inUse.ActiveUserID != "usr_1" {
+		t.Fatalf("ActiveUserID = %q, want %q", inUse.ActiveUserID, "usr_1")
+	}
+
+	sm.StopShare("usr_1")
+	if err := sm.StartShare("usr_2"); err != nil {
+		t.Fatalf("StartShare(usr_2) after stop error = %v", err)
+	}
+}
+
+func TestStartShareAllowsConcurrentSharesByDefault(t *testing.T) {
+	sm := newTestScreenShareManager(t)
+
+	for _, userID := range []string{"usr_1", "usr_2"} {
+		if err := sm.StartShare(userID); err != nil {
+			t.Fatalf("StartShare(%s) error = %v", userID, err)
+		}
+	}
+}
+
+func TestSubscribeViewerBitrateCap(t *testing.T) {
+	sm := newTestScreenShareManager(t)
+	sm.SetMaxViewerBitrate(1_000_000)
+
+	streamer, err := sm.sfu.AddPeer("usr_1")
+	if err != nil {
+		t.Fatalf("AddPeer(usr_1) error = %v", err)
+	}
+	now := time.Now()
+	layers := &layeredTrack{}
+	for _, l := range []struct {
+		id      string
+		bitrate uint64
+	}{{"q", 200_000}, {"h", 800_000}, {"f", 2_000_000}} {
+		track, err := webrtc.NewTrackLocalStaticRTP(webrtc.RTPCodecCapability{MimeType: webrtc.MimeTypeVP9}, TrackVideo, "usr_1")
+		if err != nil {
+			t.Fatalf("NewTrackLocalStaticRTP() error = %v", err)
+		}
+		layer := newRatedLayer(l.id, l.bitrate, now)
+		layer.track = track
+		layers.add(layer)
+	}
+	streamer.layers[TrackVideo] = layers
+	sm.onVideoTrackReady("usr_1", layers.layer("f").track)
+
+	for _, viewerID := range []string{"usr_2", "usr_3"} {
+		if _, err := sm.sfu.AddPeer(viewerID); err != nil {
+			t.Fatalf("AddPeer(%s) error = %v", viewerID, err)
+		}
+	}
+	layerOf := func(viewerID string) string {
+		sm.mu.RLock()
+		defer sm.mu.RUnlock()
+		return sm.viewerLayers[viewerID]
+	}
+	streamerCap := func() uint64 {
+		sm.mu.RLock()
+		defer sm.mu.RUnlock()
+		return sm.streamerCaps["usr_1"]
+	}
+
+	// The server cap keeps usr_2 off the 2 Mbps layer
+	if err := sm.Subscribe("usr_2", "usr_1", 0); err != nil {
+		t.Fatalf("Subscribe() error = %v", err)
+	}
+	if got := layerOf("usr_2"); got != "h" {
+		t.Fatalf("layer under the server cap = %q, want h", got)
+	}
+
+	// A lower cap of its own moves it down; a higher one can't lift it
+	if err := sm.Subscribe("usr_2", "usr_1", 300_000); err != nil {
+		t.Fatalf("Subscribe() with cap error = %v", err)
+	}
+	if got := layerOf("usr_2"); got != "q" {
+		t.Fatalf("layer under a 300 kbps cap = %q, want q", got)
+	}
+	if got := streamerCap(); got != 300_000 {
+		t.Fatalf("streamer limit = %d, want 300000", got)
+	}
+	if err := sm.Subscribe("usr_3", "usr_1", 5_000_000); err != nil {
+		t.Fatalf("Subscribe(usr_3) error = %v", err)
+	}
+	if got := layerOf("usr_3"); got != "h" {
+		t.Fatalf("layer over the server cap = %q, want h", got)
+	}
+
+	// The streamer is limited to the most generous viewer's cap
+	if got := streamerCap(); got != 1_000_000 {
+		t.Fatalf("streamer limit = %d, want 1000000", got)
+	}
+	sm.Unsubscribe("usr_3")
+	if got := streamerCap(); got != 300_000 {
+		t.Fatalf("streamer limit after usr_3 left = %d, want 300000", got)
+	}
+}
+
+func TestSubscribeViewerRampUp(t *testing.T) {
+	sm := newTestScreenShareManager(t)
+	sm.SetViewerRampInterval(time.Hour)
+
+	streamer, err := sm.sfu.AddPeer("usr_1")
+	if err != nil {
+		t.Fatalf("AddPeer(usr_1) error = %v", err)
+	}
+	now := time.Now()
+	layers := &layeredTrack{}
+	for _, l := range []struct {
+		id      string
+		bitrate uint64
+	}{{"q", 200_000}, {"h", 800_000}, {"f", 2_000_000}} {
+		track, err := webrtc.NewTrackLocalStaticRTP(webrtc.RTPCodecCapability{MimeType: webrtc.MimeTypeVP9}, TrackVideo, "usr_1")
+		if err != nil {
+			t.Fatalf("NewTrackLocalStaticRTP() error = %v", err)
+		}
+		layer := newRatedLayer(l.id, l.bitrate, now)
+		layer.track = track
+		layers.add(layer)
+	}
+	streamer.layers[TrackVideo] = layers
+	sm.onVideoTrackReady("usr_1", layers.layer("f").track)
+
+	if _, err := sm.sfu.AddPeer("usr_2"); err != nil {
+		t.Fatalf("AddPeer(usr_2) error = %v", err)
+	}
+	layerOf := func() string {
+		sm.mu.RLock()
+		defer sm.mu.RUnlock()
+		return sm.viewerLayers["usr_2"]
+	}
+	// rewind makes the ramp interval pass
+	rewind := func() {
+		sm.mu.Lock()
+		defer sm.mu.Unlock()
+		if _, ok := sm.viewerRamps["usr_2"]; ok {
+			sm.viewerRamps["usr_2"] = time.Now().Add(-2 * time.Hour)
+		}
+	}
+
+	if err := sm.Subscribe("usr_2", "usr_1", 0); err != nil {
+		t.Fatalf("Subscribe() error = %v", err)
+	}
+	if got := layerOf(); got != "q" {
+		t.Fatalf("layer on subscribe = %q, want q", got)
+	}
+
+	// A generous estimate moves the viewer up one layer per interval
+	sm.onViewerEstimate("usr_2", "usr_1", 10_000_000)
+	if got := layerOf(); got != "q" {
+		t.Fatalf("layer within the first interval = %q, want q", got)
+	}
+	rewind()
+	sm.onViewerEstimate("usr_2", "usr_1", 10_000_000)
+	if got := layerOf(); got != "h" {
+		t.Fatalf("layer after one interval = %q, want h", got)
+	}
+
+	// Dropping down is immediate; stepping up needs the next layer to fit
+	sm.onViewerEstimate("usr_2", "usr_1", 100_000)
+	if got := layerOf(); got != "q" {
+		t.Fatalf("layer after the estimate fell = %q, want q", got)
+	}
+	rewind()
+	sm.onViewerEstimate("usr_2", "usr_1", 700_000)
+	if got := layerOf(); got != "q" {
+		t.Fatalf("layer with the next one not fitting = %q, want q", got)
+	}
+	rewind()
+	sm.onViewerEstimate("usr_2", "usr_1", 10_000_000)
+	rewind()
+	sm.onViewerEstimate("usr_2", "usr_1", 10_000_000)
+	if got := layerOf(); got != "f" {
+		t.Fatalf("layer after ramping up = %q, want f", got)
+	}
+
+	// Reaching the top layer ends the ramp
+	sm.onViewerEstimate("usr_2", "usr_1", 10_000_000)
+	sm.mu.RLock()
+	_, ramping := sm.viewerRamps["usr_2"]
+	sm.mu.RUnlock()
+	if ramping {
+		t.Fatal("viewer still ramping on the top layer")
+	}
+}
@@ -0,0 +1,210 @@
+package sfu
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/pion/webrtc/v4"
+
+	"lobby/internal/config"
+)
+
+// Self-test check statuses.
+const (
+	SelfTestPass = "pass"
+	SelfTestFail = "fail"
+	SelfTestSkip = "skip"
+)
+
+// selfTestUserID identifies the self-test in generated TURN usernames.
+const selfTestUserID = "selftest"
+
+// SelfTestCheck is the outcome of one self-test check.
+type SelfTestCheck struct {
+	Name   string
+	Status string
+	Detail string
+}
+
+// SelfTestCandidate is an ICE candidate gathered during the self-test.
+type SelfTestCandidate struct {
+	Type     string
+	Protocol string
+	Address  string
+	Port     uint16
+}
+
+// SelfTestReport is the result of SelfTest. OK is false when any check failed.
+type SelfTestReport struct {
+	OK         bool
+	Checks     []SelfTestCheck
+	Candidates []SelfTestCandidate
+}
+
+func (r *SelfTestReport) add(name, status, detail string) {
+	r.Checks = append(r.Checks, SelfTestCheck{Name: name, Status: status, Detail: detail})
+	if status == SelfTestFail {
+		r.OK = false
+	}
+}
+
+// SelfTest allocates a throwaway peer with the SFU's settings, gathers its
+// ICE candidates, and checks them against the configured port range, public
+// IP, and STUN server. When turn is configured, a second relay-only peer
+// checks that the TURN server hands out allocations for the shared secret.
+// Neither peer is registered with the SFU, so calls are unaffected.
+func (s *SFU) SelfTest(ctx context.Context, turn config.TURNConfig) *SelfTestReport {
+	report := &SelfTestReport{OK: true}
+
+	candidates, err := s.gatherCandidates(ctx, s.config.ToWebRTCConfig())
+	if err != nil && len(candidates) == 0 {
+		report.add("peer", SelfTestFail, err.Error())
+		return report
+	}
+	report.add("peer", SelfTestPass, fmt.Sprintf("gathered %d candidates", len(candidates)))
+
+	var host, srflx []webrtc.ICECandidate
+	for _, c := range candidates {
+		report.Candidates = append(report.Candidates, SelfTestCandidate{
+			Type:     c.Typ.String(),
+			Protocol: c.Protocol.String(),
+			Address:  c.Address,
+			Port:     c.Port,
+		})
+		switch c.Typ {
+		case webrtc.ICECandidateTypeHost:
+			if c.Protocol == webrtc.ICEProtocolUDP {
+				host = append(host, c)
+			}
+		case webrtc.ICECandidateTypeSrflx:
+			srflx = append(srflx, c)
+		}
+	}
+
+	switch {
+	case s.config.UDPPort > 0:
+		status, detail := SelfTestPass, fmt.Sprintf("host candidates use UDP port %d", s.config.UDPPort)
+		if len(host) == 0 {
+			status, detail = SelfTestFail, fmt.Sprintf("no host candidate on UDP port %d", s.config.UDPPort)
+		}
+		for _, c := range host {
+			if c.Port != s.config.UDPPort {
+				status, detail = SelfTestFail, fmt.Sprintf("host candidate on UDP port %d, want %d", c.Port, s.config.UDPPort)
+				break
+			}
+		}
+		report.add("port_range", status, detail)
+	case s.config.MinPort == 0 || s.config.MaxPort == 0:
+		report.add("port_range", SelfTestSkip, "no port range configured")
+	case len(host) == 0:
+		report.add("port_range", SelfTestFail, fmt.Sprintf("could not bind a UDP port in %d-%d", s.config.MinPort, s.config.MaxPort))
+	default:
+		status, detail := SelfTestPass, fmt.Sprintf("bound UDP port %d in %d-%d", host[0].Port, s.config.MinPort, s.config.MaxPort)
+		for _, c := range host {
+			if c.Port < s.config.MinPort || c.Port > s.config.MaxPort {
+				status, detail = SelfTestFail, fmt.Sprintf("bound UDP port %d outside %d-%d", c.Port, s.config.MinPort, s.config.MaxPort)
+				break
+			}
+		}
+		report.add("port_range", status, detail)
+	}
+
+	if s.config.PublicIP == "" {
+		report.add("public_ip", SelfTestSkip, "no public IP configured")
+	} else {
+		status, detail := SelfTestFail, fmt.Sprintf("no host candidate advertises %s", s.config.PublicIP)
+		for _, c := range host {
+			if c.Address == s.config.PublicIP {
+				status, detail = SelfTestPass, fmt.Sprintf("host candidates advertise %s", s.config.PublicIP)
+				break
+			}
+		}
+		report.add("public_ip", status, detail)
+	}
+
+	switch {
+	case s.config.STUNUrl == "":
+		report.add("stun", SelfTestSkip, "no STUN server configured")
+	case len(srflx) == 0:
+		report.add("stun", SelfTestFail, fmt.Sprintf("no server-reflexive candidate from %s", s.config.STUNUrl))
+	default:
+		report.add("stun", SelfTestPass, fmt.Sprintf("%s reports %s:%d", s.config.STUNUrl, srflx[0].Address, srflx[0].Port))
+	}
+
+	if turn.Host == "" {
+		report.add("turn", SelfTestSkip, "no TURN server configured")
+		return report
+	}
+	turnURL := fmt.Sprintf("turn:%s:%d", turn.Host, turn.Port)
+	username, credential := GenerateTURNCredentials(turn.Secret, selfTestUserID, turn.TTL)
+	relayCandidates, err := s.gatherCandidates(ctx, webrtc.Configuration{
+		ICEServers:         []webrtc.ICEServer{{URLs: []string{turnURL}, Username: username, Credential: credential}},
+		ICETransportPolicy: webrtc.ICETransportPolicyRelay,
+	})
+	var relay *webrtc.ICECandidate
+	for i := range relayCandidates {
+		if relayCandidates[i].Typ == webrtc.ICECandidateTypeRelay {
+			relay = &relayCandidates[i]
+			break
+		}
+	}
+	switch {
+	case relay != nil:
+		report.add("turn", SelfTestPass, fmt.Sprintf("%s allocated %s:%d", turnURL, relay.Address, relay.Port))
+	case err != nil:
+		report.add("turn", SelfTestFail, fmt.Sprintf("no relay candidate from %s: %v", turnURL, err))
+	default:
+		report.add("turn", SelfTestFail, fmt.Sprintf("no relay candidate from %s", turnURL))
+	}
+	return report
+}
+
+// gatherCandidates creates a receive-only audio peer with cfg and returns the
+// candidates it gathers before gathering completes or ctx is done. Candidates
+// gathered before a timeout are returned along with the error.
+func (s *SFU) gatherCandidates(ctx context.Context, cfg webrtc.Configuration) ([]webrtc.ICECandidate, error) {
+	conn, err := s.api.NewPeerConnection(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("creating peer connection: %w", err)
+	}
+	defer conn.Close()
+
+	var (
+		mu         sync.Mutex
+		candidates []webrtc.ICECandidate
+	)
+	conn.OnICECandidate(func(candidate *webrtc.ICECandidate) {
+		if candidate == nil {
+			return
+		}
+		mu.Lock()
+		candidates = append(candidates, *candidate)
+		mu.Unlock()
+	})
+
+	if _, err := conn.AddTransceiverFromKind(webrtc.RTPCodecTypeAudio, webrtc.RTPTransceiverInit{
+		Direction: webrtc.RTPTransceiverDirectionRecvonly,
+	}); err != nil {
+		return nil, fmt.Errorf("adding transceiver: %w", err)
+	}
+	offer, err := conn.CreateOffer(nil)
+	if err != nil {
+		return nil, fmt.Errorf("creating offer: %w", err)
+	}
+	gathered := webrtc.GatheringCompletePromise(conn)
+	if err := conn.SetLocalDescription(offer); err != nil {
+		return nil, fmt.Errorf("setting local description: %w", err)
+	}
+
+	select {
+	case <-gathered:
+		err = nil
+	case <-ctx.Done():
+		err = fmt.Errorf("candidate gathering: %w", ctx.Err())
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	return append([]webrtc.ICECandidate(nil), candidates...), err
+}
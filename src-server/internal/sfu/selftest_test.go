@@ -0,0 +1,103 @@
+package sfu
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"lobby/internal/config"
+)
+
+func TestSelfTestChecksPortRange(t *testing.T) {
+	s, err := New(&Config{MinPort: 50000, MaxPort: 50100})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	t.Cleanup(s.Close)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	report := s.SelfTest(ctx, config.TURNConfig{})
+
+	statuses := make(map[string]string)
+	for _, check := range report.Checks {
+		statuses[check.Name] = check.Status
+	}
+	want := map[string]string{
+		"peer":       SelfTestPass,
+		"port_range": SelfTestPass,
+		"public_ip":  SelfTestSkip,
+		"stun":       SelfTestSkip,
+		"turn":       SelfTestSkip,
+	}
+	for name, status := range want {
+		if statuses[name] != status {
+			t.Errorf("check %s = %q, want %q (report %+v)", name, statuses[name], status, report.Checks)
+		}
+	}
+	if !report.OK {
+		t.Errorf("OK = false, want true")
+	}
+	for _, c := range report.Candidates {
+		if c.Type == "host" && c.Protocol == "udp" && (c.Port < 50000 || c.Port > 50100) {
+			t.Errorf("candidate port %d outside configured range", c.Port)
+		}
+	}
+}
+
+func TestSelfTestChecksUDPPort(t *testing.T) {
+	l, err := net.ListenUDP("udp", &net.UDPAddr{})
+	if err != nil {
+		t.Fatalf("ListenUDP() error = %v", err)
+	}
+	port := uint16(l.LocalAddr().(*net.UDPAddr).Port)
+	l.Close()
+
+	s, err := New(&Config{MinPort: 50000, MaxPort: 50100, UDPPort: port})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	t.Cleanup(s.Close)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	report := s.SelfTest(ctx, config.TURNConfig{})
+
+	for _, check := range report.Checks {
+		if check.Name == "port_range" && check.Status != SelfTestPass {
+			t.Errorf("port_range = %q (%s), want pass", check.Status, check.Detail)
+		}
+	}
+	for _, c := range report.Candidates {
+		if c.Type == "host" && c.Protocol == "udp" && c.Port != port {
+			t.Errorf("candidate port %d, want the muxed port %d", c.Port, port)
+		}
+	}
+}
+
+func TestSelfTestGathersTCPCandidates(t *testing.T) {
+	l, err := net.Listen("tcp", ":0")
+	if err != nil {
+		t.Fatalf("Listen() error = %v", err)
+	}
+	port := uint16(l.Addr().(*net.TCPAddr).Port)
+	l.Close()
+
+	s, err := New(&Config{MinPort: 50000, MaxPort: 50100, TCPPort: port})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	t.Cleanup(s.Close)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	report := s.SelfTest(ctx, config.TURNConfig{})
+
+	for _, c := range report.Candidates {
+		if c.Type == "host" && c.Protocol == "tcp" && c.Port == port {
+			return
+		}
+	}
+	t.Fatalf("no TCP host candidate on port %d in %+v", port, report.Candidates)
+}
@@ -0,0 +1,321 @@
+package sfu
+
+import (
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pion/interceptor"
+	"github.com/pion/rtcp"
+	"github.com/pion/rtp"
+	"github.com/pion/webrtc/v4"
+)
+
+// senderReportInterval is how often each forwarded stream gets an RTCP
+// sender report.
+const senderReportInterval = time.Second
+
+// ntpEpochOffset is the number of seconds from 1900, the NTP epoch, to 1970.
+const ntpEpochOffset = 2208988800
+
+// sourceClock maps a publisher's RTP timestamps onto the SFU's wall clock.
+// It is learned from the sender reports the publisher sends. The arrival time
+// stands in for the publisher's wall clock, which the SFU cannot read; the
+// offset is the one-way delay, shared by all of a publisher's streams, so
+// their audio and video stay in sync.
+type sourceClock struct {
+	clockRate uint32
+	mu        sync.Mutex
+	rtp       uint32    // RTP timestamp of the latest report
+	at        time.Time // when that report arrived, zero before the first
+}
+
+func newSourceClock(clockRate uint32) *sourceClock {
+	return &sourceClock{clockRate: clockRate}
+}
+
+func (c *sourceClock) observe(rtpTime uint32, at time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.rtp = rtpTime
+	c.at = at
+}
+
+// rtpAt returns the publisher's RTP timestamp at now, or false before the
+// publisher has sent a report.
+func (c *sourceClock) rtpAt(now time.Time) (uint32, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.at.IsZero() || c.clockRate == 0 {
+		return 0, false
+	}
+	return c.rtp + rtpTicks(now.Sub(c.at), c.clockRate), true
+}
+
+// rtpTicks converts d to RTP timestamp units. Negative durations wrap, as
+// RTP timestamps do.
+func rtpTicks(d time.Duration, clockRate uint32) uint32 {
+	return uint32(int64(d.Seconds() * float64(clockRate)))
+}
+
+// toNTP converts t to a 64-bit NTP timestamp.
+func toNTP(t time.Time) uint64 {
+	nanos := uint64(t.UnixNano()) + ntpEpochOffset*uint64(time.Second)
+	secs := nanos / uint64(time.Second)
+	frac := (nanos % uint64(time.Second)) << 32 / uint64(time.Second)
+	return secs<<32 | frac
+}
+
+// readSenderReports reads the RTCP the publisher sends alongside remote,
+// recording its sender reports in the returned clock. Reading also lets the
+// receiver report interceptor see them.
+func (p *Peer) readSenderReports(remote *webrtc.TrackRemote, receiver *webrtc.RTPReceiver) *sourceClock {
+	clock := newSourceClock(remote.Codec().ClockRate)
+	ssrc := uint32(remote.SSRC())
+	rid := remote.RID()
+
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+
+		bufp := getPacketBuffer()
+		defer putPacketBuffer(bufp)
+		for {
+			n, _, err := receiver.ReadSimulcast(*bufp, rid)
+			if err != nil {
+				return
+			}
+			packets, err := rtcp.Unmarshal((*bufp)[:n])
+			if err != nil {
+				continue
+			}
+			for _, pkt := range packets {
+				if sr, ok := pkt.(*rtcp.SenderReport); ok && sr.SSRC == ssrc {
+					clock.observe(sr.RTPTime, time.Now())
+				}
+			}
+		}
+	}()
+	return clock
+}
+
+// bindClock records that track carries the stream clock was learned from.
+func (s *SFU) bindClock(track *webrtc.TrackLocalStaticRTP, clock *sourceClock) {
+	s.clocks.Store(track, clock)
+}
+
+func (s *SFU) unbindClock(track *webrtc.TrackLocalStaticRTP) {
+	s.clocks.Delete(track)
+}
+
+// trackClock returns the publisher clock of track, or nil for tracks that
+// are not forwarded from a publisher (relayed and remote audio).
+func (s *SFU) trackClock(track *webrtc.TrackLocalStaticRTP) *sourceClock {
+	if clock, ok := s.clocks.Load(track); ok {
+		return clock.(*sourceClock)
+	}
+	return nil
+}
+
+// outputClock returns the publisher clock of the track the output stream
+// ssrc currently carries, or nil.
+func (p *Peer) outputClock(ssrc uint32) *sourceClock {
+	p.mu.RLock()
+	sender := p.outputSSRCs[ssrc]
+	p.mu.RUnlock()
+	if sender == nil {
+		return nil
+	}
+	track, ok := sender.Track().(*webrtc.TrackLocalStaticRTP)
+	if !ok {
+		return nil
+	}
+	return p.sfu.trackClock(track)
+}
+
+func (p *Peer) forgetOutputSSRCLocked(sender *webrtc.RTPSender) {
+	for ssrc, s := range p.outputSSRCs {
+		if s == sender {
+			delete(p.outputSSRCs, ssrc)
+		}
+	}
+}
+
+// senderReportFactory builds a senderReporter for each PeerConnection and
+// holds it until the Peer owning the connection attaches its clocks.
+type senderReportFactory struct {
+	mu      sync.Mutex
+	pending map[string]*senderReporter // PeerConnection ID -> reporter
+}
+
+func newSenderReportFactory() *senderReportFactory {
+	return &senderReportFactory{pending: make(map[string]*senderReporter)}
+}
+
+func (f *senderReportFactory) NewInterceptor(id string) (interceptor.Interceptor, error) {
+	r := &senderReporter{
+		id:      id,
+		factory: f,
+		streams: make(map[uint32]*reportedStream),
+		close:   make(chan struct{}),
+	}
+	f.mu.Lock()
+	f.pending[id] = r
+	f.mu.Unlock()
+	return r, nil
+}
+
+// attach hands the reporter of PeerConnection id the lookup of the
+// publisher clock behind each of its outgoing streams.
+func (f *senderReportFactory) attach(id string, clock func(ssrc uint32) *sourceClock) {
+	f.mu.Lock()
+	r := f.pending[id]
+	delete(f.pending, id)
+	f.mu.Unlock()
+	if r != nil {
+		r.clock.Store(&clock)
+	}
+}
+
+func (f *senderReportFactory) forget(id string) {
+	f.mu.Lock()
+	delete(f.pending, id)
+	f.mu.Unlock()
+}
+
+// senderReporter sends the sender reports of one PeerConnection's outgoing
+// streams. It replaces pion's sender interceptor, which derives the RTP time
+// from when the SFU forwarded the latest packet: that mixes the network
+// jitter of each stream into its report, so receivers drift audio and video
+// apart. Streams forwarded from a publisher take the RTP time from the
+// publisher's own reports instead.
+type senderReporter struct {
+	interceptor.NoOp
+	id        string
+	factory   *senderReportFactory
+	clock     atomic.Pointer[func(ssrc uint32) *sourceClock]
+	mu        sync.Mutex
+	streams   map[uint32]*reportedStream
+	started   bool
+	close     chan struct{}
+	closeOnce sync.Once
+	wg        sync.WaitGroup
+}
+
+// reportedStream is the state of one outgoing stream a report needs.
+type reportedStream struct {
+	ssrc      uint32
+	clockRate uint32
+	mu        sync.Mutex
+	lastRTP   uint32
+	lastAt    time.Time // when lastRTP was sent, zero before the first packet
+	packets   uint32
+	octets    uint32
+}
+
+func (s *reportedStream) sent(header *rtp.Header, payloadLen int, at time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastRTP = header.Timestamp
+	s.lastAt = at
+	s.packets++
+	s.octets += uint32(payloadLen)
+}
+
+// report returns the stream's sender report at now, or nil before it has
+// sent anything. clock, when not nil, is the publisher clock of the stream.
+func (s *reportedStream) report(now time.Time, clock *sourceClock) *rtcp.SenderReport {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.lastAt.IsZero() {
+		return nil
+	}
+
+	rtpTime, ok := uint32(0), false
+	if clock != nil {
+		rtpTime, ok = clock.rtpAt(now)
+	}
+	if !ok {
+		rtpTime = s.lastRTP + rtpTicks(now.Sub(s.lastAt), s.clockRate)
+	}
+	return &rtcp.SenderReport{
+		SSRC:        s.ssrc,
+		NTPTime:     toNTP(now),
+		RTPTime:     rtpTime,
+		PacketCount: s.packets,
+		OctetCount:  s.octets,
+	}
+}
+
+func (r *senderReporter) BindRTCPWriter(writer interceptor.RTCPWriter) interceptor.RTCPWriter {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.started {
+		r.started = true
+		r.wg.Add(1)
+		go r.run(writer)
+	}
+	return writer
+}
+
+func (r *senderReporter) BindLocalStream(info *interceptor.StreamInfo, writer interceptor.RTPWriter) interceptor.RTPWriter {
+	stream := &reportedStream{ssrc: info.SSRC, clockRate: info.ClockRate}
+	r.mu.Lock()
+	r.streams[info.SSRC] = stream
+	r.mu.Unlock()
+
+	return interceptor.RTPWriterFunc(func(header *rtp.Header, payload []byte, a interceptor.Attributes) (int, error) {
+		stream.sent(header, len(payload), time.Now())
+		return writer.Write(header, payload, a)
+	})
+}
+
+func (r *senderReporter) UnbindLocalStream(info *interceptor.StreamInfo) {
+	r.mu.Lock()
+	delete(r.streams, info.SSRC)
+	r.mu.Unlock()
+}
+
+func (r *senderReporter) Close() error {
+	r.closeOnce.Do(func() { close(r.close) })
+	r.wg.Wait()
+	r.factory.forget(r.id)
+	return nil
+}
+
+func (r *senderReporter) run(writer interceptor.RTCPWriter) {
+	defer r.wg.Done()
+
+	ticker := time.NewTicker(senderReportInterval)
+	defer ticker.Stop()
+	var streams []*reportedStream
+	for {
+		select {
+		case <-r.close:
+			return
+		case now := <-ticker.C:
+			r.mu.Lock()
+			streams = streams[:0]
+			for _, stream := range r.streams {
+				streams = append(streams, stream)
+			}
+			r.mu.Unlock()
+
+			lookup := r.clock.Load()
+			for _, stream := range streams {
+				var clock *sourceClock
+				if lookup != nil {
+					clock = (*lookup)(stream.ssrc)
+				}
+				sr := stream.report(now, clock)
+				if sr == nil {
+					continue
+				}
+				if _, err := writer.Write([]rtcp.Packet{sr}, interceptor.Attributes{}); err != nil {
+					slog.Debug("error sending sender report", "component", "sfu", "ssrc", sr.SSRC, "error", err)
+				}
+			}
+		}
+	}
+}
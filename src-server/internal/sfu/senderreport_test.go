@@ -0,0 +1,51 @@
+package sfu
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pion/rtp"
+)
+
+func TestSenderReportUsesPublisherClock(t *testing.T) {
+	start := time.Unix(1700000000, 0)
+	stream := &reportedStream{ssrc: 1234, clockRate: 90000}
+
+	if sr := stream.report(start, nil); sr != nil {
+		t.Fatalf("report before any packet = %+v, want nil", sr)
+	}
+
+	// The last packet left 100ms late, after queuing behind a keyframe
+	stream.sent(&rtp.Header{Timestamp: 9000}, 1000, start.Add(100*time.Millisecond))
+
+	// Without a publisher report the RTP time follows the send time
+	now := start.Add(time.Second)
+	sr := stream.report(now, nil)
+	if want := uint32(9000 + 81000); sr.RTPTime != want {
+		t.Fatalf("fallback RTPTime = %d, want %d", sr.RTPTime, want)
+	}
+	if sr.PacketCount != 1 || sr.OctetCount != 1000 {
+		t.Fatalf("counts = %d packets %d octets, want 1 and 1000", sr.PacketCount, sr.OctetCount)
+	}
+
+	// The publisher said RTP 9000 was captured at start
+	clock := newSourceClock(90000)
+	if sr := stream.report(now, clock); sr.RTPTime != 9000+81000 {
+		t.Fatalf("RTPTime before the publisher's report = %d, want the fallback", sr.RTPTime)
+	}
+	clock.observe(9000, start)
+	sr = stream.report(now, clock)
+	if want := uint32(9000 + 90000); sr.RTPTime != want {
+		t.Fatalf("RTPTime = %d, want %d", sr.RTPTime, want)
+	}
+	if sr.NTPTime != toNTP(now) {
+		t.Fatalf("NTPTime = %d, want %d", sr.NTPTime, toNTP(now))
+	}
+}
+
+func TestToNTP(t *testing.T) {
+	got := toNTP(time.Unix(0, int64(500*time.Millisecond)))
+	if want := uint64(ntpEpochOffset)<<32 | 1<<31; got != want {
+		t.Fatalf("toNTP() = %#x, want %#x", got, want)
+	}
+}
@@ -3,11 +3,30 @@ package sfu
 import (
 	"fmt"
 	"log/slog"
+	"net"
 	"sync"
+	"sync/atomic"
 
+	"github.com/pion/ice/v4"
+	"github.com/pion/interceptor"
+	"github.com/pion/interceptor/pkg/nack"
+	"github.com/pion/rtp"
 	"github.com/pion/webrtc/v4"
 )
 
+// opusCapability is the audio codec negotiated with every peer.
+var opusCapability = webrtc.RTPCodecCapability{
+	MimeType:    webrtc.MimeTypeOpus,
+	ClockRate:   48000,
+	Channels:    2,
+	SDPFmtpLine: "minptime=10;useinbandfec=1",
+}
+
+const opusPayloadType = 111
+
+// iceTCPReadBuffer is how many packets the ICE-TCP mux buffers per connection
+const iceTCPReadBuffer = 8
+
 type SignalingCallback func(userID string, eventType string, payload interface{})
 
 type RtcOfferPayload struct {
@@ -28,18 +47,55 @@ type SFU struct {
 	peers                 map[string]*Peer
 	signalingCallback     SignalingCallback
 	screenShareManager    *ScreenShareManager
+	cameraManager         *CameraManager
 	pendingRenegotiations map[string]bool // userID -> needs renegotiation
 	negotiating           map[string]bool // userID -> offer in flight (guards triggerRenegotiation TOCTOU)
+	suppressedAudio       sync.Map        // userID -> struct{}; audio not forwarded (muted or deafened)
+	relays                map[string]*relay
+	audioTap              atomic.Pointer[AudioTap]
+	cascade               atomic.Pointer[RelaySink] // local audio for other instances
+	whispers              map[string]*whisper       // userID -> recipients of their audio
+	whisperMu             sync.Mutex                // serializes SetWhisper and ClearWhisper
+	senderReports         *senderReportFactory
+	clocks                sync.Map   // *webrtc.TrackLocalStaticRTP -> *sourceClock of its publisher
+	udpMux                ice.UDPMux // set when Config.UDPPort is
+	tcpMux                ice.TCPMux // set when Config.TCPPort is
 }
 
 func New(config *Config) (*SFU, error) {
 	settingEngine := webrtc.SettingEngine{}
 
-	if config.MinPort > 0 && config.MaxPort > 0 {
+	var udpMux ice.UDPMux
+	if config.UDPPort > 0 {
+		// Every peer shares one socket; ICE demultiplexes by ufrag
+		conn, err := net.ListenUDP("udp", &net.UDPAddr{Port: int(config.UDPPort)})
+		if err != nil {
+			return nil, fmt.Errorf("failed to listen on UDP port %d: %w", config.UDPPort, err)
+		}
+		udpMux = webrtc.NewICEUDPMux(nil, conn)
+		settingEngine.SetICEUDPMux(udpMux)
+	} else if config.MinPort > 0 && config.MaxPort > 0 {
 		if err := settingEngine.SetEphemeralUDPPortRange(config.MinPort, config.MaxPort); err != nil {
 			return nil, fmt.Errorf("failed to set port range: %w", err)
 		}
 	}
+	var tcpMux ice.TCPMux
+	if config.TCPPort > 0 {
+		listener, err := net.Listen("tcp", fmt.Sprintf(":%d", config.TCPPort))
+		if err != nil {
+			if udpMux != nil {
+				udpMux.Close()
+			}
+			return nil, fmt.Errorf("failed to listen on TCP port %d: %w", config.TCPPort, err)
+		}
+		tcpMux = webrtc.NewICETCPMux(nil, listener, iceTCPReadBuffer)
+		settingEngine.SetICETCPMux(tcpMux)
+		settingEngine.SetNetworkTypes([]webrtc.NetworkType{
+			webrtc.NetworkTypeUDP4, webrtc.NetworkTypeUDP6,
+			webrtc.NetworkTypeTCP4, webrtc.NetworkTypeTCP6,
+		})
+	}
+	settingEngine.SetLite(config.ICELite)
 
 	if config.PublicIP != "" {
 		settingEngine.SetNAT1To1IPs([]string{config.PublicIP}, webrtc.ICECandidateTypeHost)
@@ -48,32 +104,44 @@ func New(config *Config) (*SFU, error) {
 	mediaEngine := &webrtc.MediaEngine{}
 	// Register Opus with low-latency parameters for audio
 	if err := mediaEngine.RegisterCodec(webrtc.RTPCodecParameters{
-		RTPCodecCapability: webrtc.RTPCodecCapability{
-			MimeType:    webrtc.MimeTypeOpus,
-			ClockRate:   48000,
-			Channels:    2,
-			SDPFmtpLine: "minptime=10;useinbandfec=1",
-		},
-		PayloadType: 111,
+		RTPCodecCapability: opusCapability,
+		PayloadType:        opusPayloadType,
 	}, webrtc.RTPCodecTypeAudio); err != nil {
 		return nil, fmt.Errorf("failed to register opus codec: %w", err)
 	}
 
-	// Register VP9 for screen sharing video
-	if err := mediaEngine.RegisterCodec(webrtc.RTPCodecParameters{
-		RTPCodecCapability: webrtc.RTPCodecCapability{
-			MimeType:    webrtc.MimeTypeVP9,
-			ClockRate:   90000,
-			SDPFmtpLine: "profile-id=0",
-		},
-		PayloadType: 98,
-	}, webrtc.RTPCodecTypeVideo); err != nil {
-		return nil, fmt.Errorf("failed to register VP9 codec: %w", err)
+	// Register the video codecs screen shares and cameras are forwarded in
+	if err := registerVideoCodecs(mediaEngine); err != nil {
+		return nil, err
+	}
+
+	// Accept rid-based simulcast from screen share streamers
+	if err := webrtc.ConfigureSimulcastExtensionHeaders(mediaEngine); err != nil {
+		return nil, fmt.Errorf("failed to register simulcast extensions: %w", err)
+	}
+
+	interceptors := &interceptor.Registry{}
+	if err := configureTWCC(mediaEngine, interceptors); err != nil {
+		return nil, err
+	}
+
+	// Keep recent video packets to retransmit those viewers NACK. Only
+	// streams negotiated with nack feedback (video) are buffered.
+	responder, err := nack.NewResponderInterceptor(nack.ResponderSize(nackHistoryPackets))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create nack responder: %w", err)
+	}
+	interceptors.Add(responder)
+
+	senderReports := newSenderReportFactory()
+	if err := configureStats(interceptors, senderReports); err != nil {
+		return nil, err
 	}
 
 	api := webrtc.NewAPI(
 		webrtc.WithSettingEngine(settingEngine),
 		webrtc.WithMediaEngine(mediaEngine),
+		webrtc.WithInterceptorRegistry(interceptors),
 	)
 
 	return &SFU{
@@ -82,6 +150,10 @@ func New(config *Config) (*SFU, error) {
 		peers:                 make(map[string]*Peer),
 		pendingRenegotiations: make(map[string]bool),
 		negotiating:           make(map[string]bool),
+		relays:                make(map[string]*relay),
+		senderReports:         senderReports,
+		udpMux:                udpMux,
+		tcpMux:                tcpMux,
 	}, nil
 }
 
@@ -98,6 +170,13 @@ func (s *SFU) SetScreenShareManager(sm *ScreenShareManager) {
 	s.screenShareManager = sm
 }
 
+// SetCameraManager sets the camera manager camera tracks are routed to
+func (s *SFU) SetCameraManager(cm *CameraManager) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cameraManager = cm
+}
+
 // HasPendingScreenShare checks if a user has a pending screen share (registered but no track yet)
 func (s *SFU) HasPendingScreenShare(userID string) bool {
 	s.mu.RLock()
@@ -138,6 +217,7 @@ func (s *SFU) RemovePeer(userID string) {
 	delete(s.peers, userID)
 	delete(s.pendingRenegotiations, userID)
 	delete(s.negotiating, userID)
+	s.forgetWhisperLocked(userID)
 
 	// Collect other peers to update (while still holding lock)
 	otherPeers := make(map[string]*Peer)
@@ -165,6 +245,45 @@ func (s *SFU) RemovePeer(userID string) {
 	slog.Info("removed peer", "component", "sfu", "user_id", userID)
 }
 
+// SetAudioSuppressed stops or resumes forwarding userID's audio to other peers.
+// It is keyed by user rather than peer, so it also applies after a rejoin.
+func (s *SFU) SetAudioSuppressed(userID string, suppressed bool) {
+	if suppressed {
+		s.suppressedAudio.Store(userID, struct{}{})
+	} else {
+		s.suppressedAudio.Delete(userID)
+	}
+}
+
+// IsAudioSuppressed reports whether userID's audio is being dropped.
+func (s *SFU) IsAudioSuppressed(userID string) bool {
+	_, ok := s.suppressedAudio.Load(userID)
+	return ok
+}
+
+// AudioTap receives a copy of the audio forwarded from every user, such as
+// for a recording. It is called from media goroutines and must not block;
+// packet is only valid for the duration of the call.
+type AudioTap func(userID string, packet *rtp.Packet)
+
+// SetAudioTap starts handing forwarded audio to tap, or stops with nil.
+// Server-muted audio is not forwarded, so it never reaches the tap.
+func (s *SFU) SetAudioTap(tap AudioTap) {
+	if tap == nil {
+		s.audioTap.Store(nil)
+		return
+	}
+	s.audioTap.Store(&tap)
+}
+
+// tapAudio hands an RTP audio packet from userID to the audio tap, if any.
+// Whispers are private, so they are not tapped.
+func (s *SFU) tapAudio(userID string, packet *rtp.Packet) {
+	if tap := s.audioTap.Load(); tap != nil && !s.IsWhispering(userID) {
+		(*tap)(userID, packet)
+	}
+}
+
 func (s *SFU) GetPeer(userID string) *Peer {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
@@ -388,8 +507,20 @@ func (s *SFU) OnIceCandidate(userID string, candidate *webrtc.ICECandidate) {
 
 func (s *SFU) OnPeerTrackReady(userID string, trackKind string, track *webrtc.TrackLocalStaticRTP) {
 	// For audio tracks, distribute to all peers
-	// For video tracks, only distribute to subscribed peers (handled by screenshare manager)
-	if trackKind == "video" {
+	// For video tracks, only distribute to subscribed peers (handled by the
+	// screenshare and camera managers)
+	if trackKind == TrackCamera {
+		s.mu.RLock()
+		cm := s.cameraManager
+		s.mu.RUnlock()
+		if cm != nil {
+			cm.onCameraTrackReady(userID, track)
+		} else {
+			slog.Warn("camera track ready but no camera manager", "component", "sfu", "user_id", userID)
+		}
+		return
+	}
+	if trackKind == TrackVideo {
 		s.mu.RLock()
 		sm := s.screenShareManager
 		s.mu.RUnlock()
@@ -410,10 +541,24 @@ func (s *SFU) OnPeerTrackReady(userID string, trackKind string, track *webrtc.Tr
 		}
 	}
 	peer := s.peers[userID]
+	relayTracks := make(map[string]*webrtc.TrackLocalStaticRTP, len(s.relays))
+	for relayUserID, r := range s.relays {
+		if relayUserID != userID && s.mayHearLocked(relayUserID, userID) {
+			relayTracks[relayUserID] = r.track
+		}
+	}
+	// Whisperers' audio reaches only their recipients; the rest get the
+	// track when the whisper ends
+	excluded := make(map[string]bool) // peers not hearing userID
+	unheard := make(map[string]bool)  // peers whose whisper excludes userID
+	for otherUserID := range otherPeers {
+		excluded[otherUserID] = !s.mayHearLocked(userID, otherUserID)
+		unheard[otherUserID] = !s.mayHearLocked(otherUserID, userID)
+	}
 	s.mu.RUnlock()
 
 	for otherUserID, otherPeer := range otherPeers {
-		if otherPeer.IsClosed() {
+		if otherPeer.IsClosed() || excluded[otherUserID] {
 			continue
 		}
 		if err := otherPeer.AddTrack(userID, trackKind, track); err != nil {
@@ -426,7 +571,7 @@ func (s *SFU) OnPeerTrackReady(userID string, trackKind string, track *webrtc.Tr
 		addedTracks := 0
 		for sourceUserID, sourcePeer := range otherPeers {
 			sourceTrack := sourcePeer.GetLocalTrack("audio")
-			if sourceTrack == nil {
+			if sourceTrack == nil || unheard[sourceUserID] {
 				continue
 			}
 			if err := peer.AddTrack(sourceUserID, "audio", sourceTrack); err != nil {
@@ -434,6 +579,12 @@ func (s *SFU) OnPeerTrackReady(userID string, trackKind string, track *webrtc.Tr
 			}
 			addedTracks++
 		}
+		for relayUserID, relayTrack := range relayTracks {
+			if err := peer.AddTrack(relayUserID, "audio", relayTrack); err != nil {
+				slog.Error("error adding relay track to new peer", "component", "sfu", "source_id", relayUserID, "peer_id", userID, "error", err)
+			}
+			addedTracks++
+		}
 		if addedTracks > 0 {
 			s.triggerRenegotiation(userID, peer)
 		}
@@ -492,4 +643,17 @@ func (s *SFU) Close() {
 		delete(s.peers, userID)
 	}
 	slog.Info("closed all peer connections", "component", "sfu")
+
+	if s.udpMux != nil {
+		if err := s.udpMux.Close(); err != nil {
+			slog.Warn("error closing UDP mux", "component", "sfu", "error", err)
+		}
+		s.udpMux = nil
+	}
+	if s.tcpMux != nil {
+		if err := s.tcpMux.Close(); err != nil {
+			slog.Warn("error closing TCP mux", "component", "sfu", "error", err)
+		}
+		s.tcpMux = nil
+	}
 }
@@ -0,0 +1,359 @@
+package sfu
+
+import (
+	"cmp"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pion/rtp"
+	"github.com/pion/rtp/codecs"
+	"github.com/pion/webrtc/v4"
+)
+
+// Screen share quality layers. A streamer either sends simulcast (one RTP
+// stream per rid) or a single VP9 SVC stream, which is split here into one
+// track per spatial layer. Every layer is a separate local track; viewers are
+// switched between them with ReplaceTrack based on the REMB estimates their
+// browser sends, so each gets the best layer its bandwidth allows. Streams
+// without either are a single layer and forwarded as before.
+const (
+	// layerSampleInterval is how often a layer's bitrate is measured. A
+	// layer with no packets for two intervals counts as stopped.
+	layerSampleInterval = time.Second
+
+	// upgradeHeadroomPercent is how much of a higher layer's bitrate a
+	// viewer's estimate must cover before it is switched up.
+	upgradeHeadroomPercent = 125
+
+	// maxSpatialLayers caps the VP9 spatial layers split out of one stream.
+	maxSpatialLayers = 4
+)
+
+// bitrateMeter measures the bitrate of a forwarded video stream
+type bitrateMeter struct {
+	mu        sync.Mutex
+	bytes     uint64
+	sampledAt time.Time
+	bitrate   uint64 // bits per second over the last sample
+}
+
+// count records n forwarded bytes.
+func (m *bitrateMeter) count(n int, now time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.sampledAt.IsZero() {
+		m.sampledAt = now
+	}
+	m.bytes += uint64(n)
+	if elapsed := now.Sub(m.sampledAt); elapsed >= layerSampleInterval {
+		m.bitrate = m.bytes * 8 * uint64(time.Second) / uint64(elapsed)
+		m.bytes = 0
+		m.sampledAt = now
+	}
+}
+
+// Bitrate returns the stream's bitrate in bits per second, or 0 before its
+// first sample and once it has stopped.
+func (m *bitrateMeter) Bitrate(now time.Time) uint64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.sampledAt.IsZero() || now.Sub(m.sampledAt) > 2*layerSampleInterval {
+		return 0
+	}
+	return m.bitrate
+}
+
+// videoLayer is one quality layer of a screen share
+type videoLayer struct {
+	bitrateMeter
+	id    string // simulcast rid, or "s<N>" for VP9 spatial layer N
+	track *webrtc.TrackLocalStaticRTP
+}
+
+func newVideoLayer(id string, track *webrtc.TrackLocalStaticRTP) *videoLayer {
+	return &videoLayer{id: id, track: track}
+}
+
+// layeredTrack is the set of quality layers of one screen share
+type layeredTrack struct {
+	mu     sync.RWMutex
+	layers []*videoLayer
+}
+
+func (t *layeredTrack) add(layer *videoLayer) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.layers = append(t.layers, layer)
+}
+
+func (t *layeredTrack) layer(id string) *videoLayer {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	for _, layer := range t.layers {
+		if layer.id == id {
+			return layer
+		}
+	}
+	return nil
+}
+
+// ratedLayer is a running layer with its bitrate at the time it was rated.
+type ratedLayer struct {
+	layer   *videoLayer
+	bitrate uint64
+}
+
+// runningLocked returns the running layers, lowest bitrate first.
+func (t *layeredTrack) runningLocked(now time.Time) []ratedLayer {
+	running := make([]ratedLayer, 0, len(t.layers))
+	for _, layer := range t.layers {
+		if bitrate := layer.Bitrate(now); bitrate > 0 {
+			running = append(running, ratedLayer{layer, bitrate})
+		}
+	}
+	slices.SortStableFunc(running, func(a, b ratedLayer) int {
+		return cmp.Compare(a.bitrate, b.bitrate)
+	})
+	return running
+}
+
+// selectLayer returns the layer to forward to a viewer currently on
+// currentID with bandwidth estimate (0 if unknown): the highest running
+// layer that fits, with headroom when it is an upgrade, else the lowest.
+// Without an estimate it is the highest running layer.
+func (t *layeredTrack) selectLayer(currentID string, estimate uint64, now time.Time) *videoLayer {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	if len(t.layers) == 0 {
+		return nil
+	}
+
+	running := t.runningLocked(now)
+	var current uint64
+	for _, r := range running {
+		if r.layer.id == currentID {
+			current = r.bitrate
+		}
+	}
+	if len(running) == 0 {
+		// Nothing measured yet: keep the viewer where it is
+		for _, layer := range t.layers {
+			if layer.id == currentID {
+				return layer
+			}
+		}
+		return t.layers[len(t.layers)-1]
+	}
+	if estimate == 0 {
+		return running[len(running)-1].layer
+	}
+
+	chosen := running[0].layer
+	for _, r := range running {
+		need := r.bitrate
+		if r.bitrate > current {
+			need = need * upgradeHeadroomPercent / 100
+		}
+		if need <= estimate {
+			chosen = r.layer
+		}
+	}
+	return chosen
+}
+
+// lowest returns the running layer with the lowest bitrate, or nil before
+// any layer has been measured.
+func (t *layeredTrack) lowest(now time.Time) *videoLayer {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	if running := t.runningLocked(now); len(running) > 0 {
+		return running[0].layer
+	}
+	return nil
+}
+
+// above returns the running layer one step up from currentID and its
+// bitrate, or nil if currentID is the highest or not running.
+func (t *layeredTrack) above(currentID string, now time.Time) (*videoLayer, uint64) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	running := t.runningLocked(now)
+	for i, r := range running {
+		if r.layer.id == currentID && i+1 < len(running) {
+			return running[i+1].layer, running[i+1].bitrate
+		}
+	}
+	return nil, 0
+}
+
+func spatialLayerID(sid int) string {
+	return fmt.Sprintf("s%d", sid)
+}
+
+// videoLayers returns the quality layers of the peer's video track with
+// label, or nil if it has none.
+func (p *Peer) videoLayers(label string) *layeredTrack {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.layers[label]
+}
+
+// addLayeredVideo handles a screen share track arriving from the peer. The
+// first layer is announced like any other track; later simulcast layers only
+// become available to layer selection. A layer ID arriving again means a new
+// stream, which replaces the old layers.
+func (p *Peer) addLayeredVideo(remote *webrtc.TrackRemote, clock *sourceClock) {
+	track, err := webrtc.NewTrackLocalStaticRTP(remote.Codec().RTPCodecCapability, TrackVideo, p.ID)
+	if err != nil {
+		slog.Error("failed to create local track", "component", "sfu", "peer_id", p.ID, "error", err)
+		return
+	}
+
+	svc := remote.RID() == ""
+	layerID := remote.RID()
+	if svc {
+		layerID = spatialLayerID(0)
+	}
+	layer := newVideoLayer(layerID, track)
+
+	p.mu.Lock()
+	layers := p.layers[TrackVideo]
+	first := layers == nil || layers.layer(layerID) != nil
+	if first {
+		layers = &layeredTrack{}
+		p.layers[TrackVideo] = layers
+		p.localTracks[TrackVideo] = track
+		p.videoSSRCs[TrackVideo] = nil
+	}
+	layers.add(layer)
+	p.videoSSRCs[TrackVideo] = append(p.videoSSRCs[TrackVideo], uint32(remote.SSRC()))
+	p.mu.Unlock()
+
+	slog.Debug("screen share layer added", "component", "sfu", "peer_id", p.ID, "layer", layerID)
+	if first {
+		p.startPeriodicKeyframes(TrackVideo)
+		p.sfu.OnPeerTrackReady(p.ID, TrackVideo, track)
+	}
+
+	p.wg.Add(1)
+	if svc && strings.EqualFold(remote.Codec().MimeType, webrtc.MimeTypeVP9) {
+		go p.forwardSpatialLayers(remote, track.Codec(), layers, layer, clock)
+	} else {
+		go p.forwardLayer(remote, layer, clock)
+	}
+}
+
+// forwardLayer forwards one simulcast layer unchanged.
+func (p *Peer) forwardLayer(remote *webrtc.TrackRemote, layer *videoLayer, clock *sourceClock) {
+	defer p.wg.Done()
+	p.sfu.bindClock(layer.track, clock)
+	defer p.sfu.unbindClock(layer.track)
+
+	bufp := getPacketBuffer()
+	defer putPacketBuffer(bufp)
+	buf := *bufp
+
+	var pkt rtp.Packet
+	for {
+		n, _, err := remote.Read(buf)
+		if err != nil {
+			slog.Debug("track read ended", "component", "sfu", "peer_id", p.ID, "layer", layer.id, "error", err)
+			return
+		}
+		if err := pkt.Unmarshal(buf[:n]); err != nil {
+			continue
+		}
+		layer.count(n, time.Now())
+		if err := layer.track.WriteRTP(&pkt); err != nil {
+			slog.Debug("track write error", "component", "sfu", "peer_id", p.ID, "layer", layer.id, "error", err)
+			return
+		}
+	}
+}
+
+// spatialOutput is one layer split out of a VP9 SVC stream. It carries the
+// packets of its spatial layer and all below it, renumbered to close the
+// gaps left by the layers above.
+type spatialOutput struct {
+	sid     int
+	layer   *videoLayer
+	dropped uint16
+}
+
+// take returns pkt, from spatial layer sid, as this output forwards it, or
+// false if the output leaves it out.
+func (o *spatialOutput) take(pkt rtp.Packet, sid int, layerEnd bool) (rtp.Packet, bool) {
+	if sid > o.sid {
+		o.dropped++
+		return pkt, false
+	}
+	pkt.SequenceNumber -= o.dropped
+	// The top layer's last packet ends the picture for this output
+	pkt.Marker = pkt.Marker || (layerEnd && sid == o.sid)
+	return pkt, true
+}
+
+// forwardSpatialLayers splits a VP9 stream by spatial layer. Layer N's track
+// is created when a packet of layer N first arrives, so a stream without SVC
+// stays a single layer carrying every packet.
+func (p *Peer) forwardSpatialLayers(remote *webrtc.TrackRemote, codec webrtc.RTPCodecCapability, layers *layeredTrack, base *videoLayer, clock *sourceClock) {
+	defer p.wg.Done()
+
+	outputs := []*spatialOutput{{layer: base}}
+	p.sfu.bindClock(base.track, clock)
+	defer func() {
+		for _, out := range outputs {
+			p.sfu.unbindClock(out.layer.track)
+		}
+	}()
+	bufp := getPacketBuffer()
+	defer putPacketBuffer(bufp)
+	buf := *bufp
+
+	var pkt rtp.Packet
+	var vp9 codecs.VP9Packet
+	for {
+		n, _, err := remote.Read(buf)
+		if err != nil {
+			slog.Debug("track read ended", "component", "sfu", "peer_id", p.ID, "kind", TrackVideo, "error", err)
+			return
+		}
+		if err := pkt.Unmarshal(buf[:n]); err != nil {
+			continue
+		}
+		sid, layerEnd := 0, false
+		if _, err := vp9.Unmarshal(pkt.Payload); err == nil && vp9.L {
+			sid, layerEnd = int(vp9.SID), vp9.E
+		}
+
+		for len(outputs) <= sid && len(outputs) < maxSpatialLayers {
+			track, err := webrtc.NewTrackLocalStaticRTP(codec, TrackVideo, p.ID)
+			if err != nil {
+				slog.Error("failed to create local track", "component", "sfu", "peer_id", p.ID, "error", err)
+				return
+			}
+			layer := newVideoLayer(spatialLayerID(len(outputs)), track)
+			p.sfu.bindClock(track, clock)
+			layers.add(layer)
+			outputs = append(outputs, &spatialOutput{sid: len(outputs), layer: layer})
+			slog.Debug("screen share layer added", "component", "sfu", "peer_id", p.ID, "layer", layer.id)
+		}
+
+		now := time.Now()
+		for _, out := range outputs {
+			forwarded, ok := out.take(pkt, sid, layerEnd)
+			if !ok {
+				continue
+			}
+			out.layer.count(n, now)
+			if err := out.layer.track.WriteRTP(&forwarded); err != nil {
+				slog.Debug("track write error", "component", "sfu", "peer_id", p.ID, "layer", out.layer.id, "error", err)
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package sfu
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pion/rtp"
+)
+
+// newRatedLayer returns a layer measured at bitrate bps as of now.
+func newRatedLayer(id string, bitrate uint64, now time.Time) *videoLayer {
+	layer := newVideoLayer(id, nil)
+	start := now.Add(-layerSampleInterval)
+	layer.count(0, start)
+	layer.count(int(bitrate/8), now)
+	return layer
+}
+
+func TestVideoLayerBitrate(t *testing.T) {
+	now := time.Now()
+	layer := newVideoLayer("h", nil)
+	if got := layer.Bitrate(now); got != 0 {
+		t.Fatalf("Bitrate before any packet = %d, want 0", got)
+	}
+
+	layer.count(50_000, now)
+	layer.count(75_000, now.Add(500*time.Millisecond))
+	if got := layer.Bitrate(now.Add(500 * time.Millisecond)); got != 0 {
+		t.Fatalf("Bitrate before the first sample = %d, want 0", got)
+	}
+
+	layer.count(0, now.Add(time.Second))
+	if got := layer.Bitrate(now.Add(time.Second)); got != 1_000_000 {
+		t.Fatalf("Bitrate = %d, want 1000000", got)
+	}
+	if got := layer.Bitrate(now.Add(time.Second + 3*layerSampleInterval)); got != 0 {
+		t.Fatalf("Bitrate of a stopped layer = %d, want 0", got)
+	}
+}
+
+func TestSelectLayer(t *testing.T) {
+	now := time.Now()
+	layers := &layeredTrack{}
+	// Added out of bitrate order, as simulcast rids may arrive
+	layers.add(newRatedLayer("h", 800_000, now))
+	layers.add(newRatedLayer("q", 200_000, now))
+	layers.add(newRatedLayer("f", 2_000_000, now))
+
+	tests := []struct {
+		name     string
+		current  string
+		estimate uint64
+		want     string
+	}{
+		{"unknown estimate gets the highest", "", 0, "f"},
+		{"fits the middle layer", "", 1_500_000, "h"},
+		{"below every layer gets the lowest", "h", 100_000, "q"},
+		{"upgrade needs headroom", "h", 2_200_000, "h"},
+		{"upgrade with headroom", "h", 2_500_000, "f"},
+		{"stays on a layer that still fits", "f", 2_000_000, "f"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := layers.selectLayer(tt.current, tt.estimate, now); got.id != tt.want {
+				t.Fatalf("selectLayer(%q, %d) = %q, want %q", tt.current, tt.estimate, got.id, tt.want)
+			}
+		})
+	}
+}
+
+func TestSelectLayerSkipsStoppedLayers(t *testing.T) {
+	now := time.Now()
+	layers := &layeredTrack{}
+	layers.add(newRatedLayer("q", 200_000, now))
+	layers.add(newRatedLayer("f", 2_000_000, now.Add(-5*layerSampleInterval)))
+
+	if got := layers.selectLayer("f", 0, now); got.id != "q" {
+		t.Fatalf("selectLayer = %q, want the running layer", got.id)
+	}
+
+	unmeasured := &layeredTrack{}
+	unmeasured.add(newVideoLayer("s0", nil))
+	unmeasured.add(newVideoLayer("s1", nil))
+	if got := unmeasured.selectLayer("s0", 0, now); got.id != "s0" {
+		t.Fatalf("selectLayer before measurement = %q, want the current layer", got.id)
+	}
+	if got := unmeasured.selectLayer("", 0, now); got.id != "s1" {
+		t.Fatalf("selectLayer for a new viewer = %q, want the last layer", got.id)
+	}
+}
+
+func TestSpatialOutputTake(t *testing.T) {
+	base := &spatialOutput{sid: 0}
+	top := &spatialOutput{sid: 1}
+
+	// One picture: layer 0 in packets 10-11, layer 1 in 12-13
+	packets := []struct {
+		seq      uint16
+		sid      int
+		layerEnd bool
+		marker   bool
+	}{
+		{10, 0, false, false},
+		{11, 0, true, false},
+		{12, 1, false, false},
+		{13, 1, true, true},
+		{14, 0, true, false},
+	}
+
+	var baseSeqs []uint16
+	var baseMarkers []bool
+	for _, p := range packets {
+		pkt := rtp.Packet{Header: rtp.Header{SequenceNumber: p.seq, Marker: p.marker}}
+		if out, ok := base.take(pkt, p.sid, p.layerEnd); ok {
+			baseSeqs = append(baseSeqs, out.SequenceNumber)
+			baseMarkers = append(baseMarkers, out.Marker)
+		}
+		out, ok := top.take(pkt, p.sid, p.layerEnd)
+		if !ok || out.SequenceNumber != p.seq || out.Marker != p.marker {
+			t.Fatalf("top output changed packet %d: ok=%v seq=%d marker=%v", p.seq, ok, out.SequenceNumber, out.Marker)
+		}
+	}
+
+	wantSeqs := []uint16{10, 11, 12}
+	wantMarkers := []bool{false, true, true}
+	for i := range wantSeqs {
+		if i >= len(baseSeqs) || baseSeqs[i] != wantSeqs[i] || baseMarkers[i] != wantMarkers[i] {
+			t.Fatalf("base output seqs=%v markers=%v, want %v %v", baseSeqs, baseMarkers, wantSeqs, wantMarkers)
+		}
+	}
+	if len(baseSeqs) != len(wantSeqs) {
+		t.Fatalf("base output forwarded %d packets, want %d", len(baseSeqs), len(wantSeqs))
+	}
+}
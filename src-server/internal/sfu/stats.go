@@ -0,0 +1,163 @@
+package sfu
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pion/interceptor"
+	"github.com/pion/interceptor/pkg/report"
+	"github.com/pion/webrtc/v4"
+)
+
+// PeerStats is a peer's connection quality since its previous sample.
+// Loss is a fraction from 0 to 1; bitrates are in bits per second.
+type PeerStats struct {
+	UserID          string
+	RTT             time.Duration // ICE round trip between the SFU and the peer
+	Jitter          time.Duration // worst jitter of the media the peer sends
+	UplinkLoss      float64       // packets the peer sent that never arrived
+	DownlinkLoss    float64       // packets sent to the peer it reports lost
+	InboundBitrate  uint64        // media received from the peer
+	OutboundBitrate uint64        // media forwarded to the peer
+}
+
+// statsTotals are the cumulative counters of one stats report, kept to
+// turn the next report into rates.
+type statsTotals struct {
+	at            time.Time
+	packetsIn     uint64
+	packetsLost   uint64
+	bytesIn       uint64
+	bytesOut      uint64
+	collected     bool
+	downlinkLoss  float64
+	downlinkCount int
+}
+
+// statsSampler remembers a peer's previous totals.
+type statsSampler struct {
+	mu   sync.Mutex
+	last statsTotals
+}
+
+// configureStats makes GetStats report RTP counters and the loss and round
+// trip peers send back in RTCP receiver reports, and sends reports to them.
+// Sender reports come from senders rather than pion's interceptor.
+func configureStats(interceptors *interceptor.Registry, senders *senderReportFactory) error {
+	if err := webrtc.ConfigureStatsInterceptor(interceptors); err != nil {
+		return fmt.Errorf("failed to configure stats interceptor: %w", err)
+	}
+	receiver, err := report.NewReceiverInterceptor()
+	if err != nil {
+		return fmt.Errorf("failed to configure rtcp reports: %w", err)
+	}
+	interceptors.Add(receiver)
+	interceptors.Add(senders)
+	return nil
+}
+
+// summarizeStats reduces report to the peer's cumulative totals and its
+// current RTT and jitter.
+func summarizeStats(report webrtc.StatsReport, at time.Time) (statsTotals, time.Duration, time.Duration) {
+	totals := statsTotals{at: at, collected: true}
+	var rtt, jitter time.Duration
+	for _, stat := range report {
+		switch s := stat.(type) {
+		case webrtc.ICECandidatePairStats:
+			if s.Nominated && s.State == webrtc.StatsICECandidatePairStateSucceeded && s.CurrentRoundTripTime > 0 {
+				rtt = seconds(s.CurrentRoundTripTime)
+			}
+		case webrtc.InboundRTPStreamStats:
+			totals.packetsIn += uint64(s.PacketsReceived)
+			if s.PacketsLost > 0 {
+				totals.packetsLost += uint64(s.PacketsLost)
+			}
+			totals.bytesIn += s.BytesReceived
+			jitter = max(jitter, seconds(s.Jitter))
+		case webrtc.OutboundRTPStreamStats:
+			totals.bytesOut += s.BytesSent
+		case webrtc.RemoteInboundRTPStreamStats:
+			totals.downlinkLoss += s.FractionLost
+			totals.downlinkCount++
+			if rtt == 0 && s.RoundTripTime > 0 {
+				rtt = seconds(s.RoundTripTime)
+			}
+		}
+	}
+	return totals, rtt, jitter
+}
+
+// diffStats turns two consecutive totals into a PeerStats. Rates are zero
+// for the first sample.
+func diffStats(prev, cur statsTotals) PeerStats {
+	var stats PeerStats
+	if cur.downlinkCount > 0 {
+		stats.DownlinkLoss = cur.downlinkLoss / float64(cur.downlinkCount)
+	}
+	if !prev.collected || !cur.at.After(prev.at) {
+		return stats
+	}
+
+	received := counterDelta(prev.packetsIn, cur.packetsIn)
+	lost := counterDelta(prev.packetsLost, cur.packetsLost)
+	if received+lost > 0 {
+		stats.UplinkLoss = float64(lost) / float64(received+lost)
+	}
+	elapsed := cur.at.Sub(prev.at).Seconds()
+	stats.InboundBitrate = uint64(float64(counterDelta(prev.bytesIn, cur.bytesIn)*8) / elapsed)
+	stats.OutboundBitrate = uint64(float64(counterDelta(prev.bytesOut, cur.bytesOut)*8) / elapsed)
+	return stats
+}
+
+// counterDelta is how much a counter grew, or zero when it went back
+// because a stream ended.
+func counterDelta(prev, cur uint64) uint64 {
+	if cur < prev {
+		return 0
+	}
+	return cur - prev
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
+
+// Stats samples the peer's connection quality since its previous call.
+func (p *Peer) Stats() PeerStats {
+	cur, rtt, jitter := summarizeStats(p.conn.GetStats(), time.Now())
+
+	p.stats.mu.Lock()
+	stats := diffStats(p.stats.last, cur)
+	p.stats.last = cur
+	p.stats.mu.Unlock()
+
+	stats.UserID = p.ID
+	stats.RTT = rtt
+	stats.Jitter = jitter
+	return stats
+}
+
+// PeerStats samples every active peer, sorted by user ID. Each call
+// measures rates since the previous one, so only one caller should poll.
+func (s *SFU) PeerStats() []PeerStats {
+	s.mu.RLock()
+	peers := make([]*Peer, 0, len(s.peers))
+	for _, peer := range s.peers {
+		if peer.IsActive() {
+			peers = append(peers, peer)
+		}
+	}
+	s.mu.RUnlock()
+
+	stats := make([]PeerStats, 0, len(peers))
+	for _, peer := range peers {
+		stats = append(stats, peer.Stats())
+	}
+	slices.SortFunc(stats, func(a, b PeerStats) int {
+		return strings.Compare(a.UserID, b.UserID)
+	})
+	return stats
+}
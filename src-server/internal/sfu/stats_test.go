@@ -0,0 +1,43 @@
+package sfu
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pion/webrtc/v4"
+)
+
+func TestPeerStatsFromReports(t *testing.T) {
+	start := time.Now()
+	report := func(received uint32, lost int32, bytesIn, bytesOut uint64) webrtc.StatsReport {
+		return webrtc.StatsReport{
+			"pair": webrtc.ICECandidatePairStats{
+				Nominated:            true,
+				State:                webrtc.StatsICECandidatePairStateSucceeded,
+				CurrentRoundTripTime: 0.08,
+			},
+			"in":  webrtc.InboundRTPStreamStats{PacketsReceived: received, PacketsLost: lost, BytesReceived: bytesIn, Jitter: 0.012},
+			"out": webrtc.OutboundRTPStreamStats{BytesSent: bytesOut},
+			"remote": webrtc.RemoteInboundRTPStreamStats{
+				FractionLost: 0.05,
+			},
+		}
+	}
+
+	first, rtt, jitter := summarizeStats(report(100, 0, 10_000, 20_000), start)
+	if rtt != 80*time.Millisecond || jitter != 12*time.Millisecond {
+		t.Fatalf("rtt = %v, jitter = %v, want 80ms and 12ms", rtt, jitter)
+	}
+	if stats := diffStats(statsTotals{}, first); stats.InboundBitrate != 0 || stats.DownlinkLoss != 0.05 {
+		t.Fatalf("first sample = %+v, want no rates and 5%% downlink loss", stats)
+	}
+
+	second, _, _ := summarizeStats(report(190, 10, 60_000, 120_000), start.Add(2*time.Second))
+	stats := diffStats(first, second)
+	if stats.UplinkLoss != 0.1 {
+		t.Errorf("UplinkLoss = %v, want 0.1", stats.UplinkLoss)
+	}
+	if stats.InboundBitrate != 200_000 || stats.OutboundBitrate != 400_000 {
+		t.Errorf("bitrates = %d in, %d out, want 200000 and 400000", stats.InboundBitrate, stats.OutboundBitrate)
+	}
+}
@@ -31,6 +31,8 @@ func GenerateTURNCredentials(secret, userID string, ttl time.Duration) (username
 
 // BuildICEServers produces the ICE server list sent to clients joining voice.
 // If TURN is configured (Host non-empty), it returns both a STUN and TURN entry.
+// The TURN entry offers UDP and TCP, plus TLS when cfg.TLSPort is set, so
+// clients behind firewalls that block UDP can still relay.
 // Otherwise it returns nil (the client will attempt direct connections only).
 func BuildICEServers(cfg config.TURNConfig, userID string) []ICEServerInfo {
 	if cfg.Host == "" {
@@ -38,12 +40,18 @@ func BuildICEServers(cfg config.TURNConfig, userID string) []ICEServerInfo {
 	}
 
 	stunURL := fmt.Sprintf("stun:%s:%d", cfg.Host, cfg.Port)
-	turnURL := fmt.Sprintf("turn:%s:%d", cfg.Host, cfg.Port)
+	turnURLs := []string{
+		fmt.Sprintf("turn:%s:%d", cfg.Host, cfg.Port),
+		fmt.Sprintf("turn:%s:%d?transport=tcp", cfg.Host, cfg.Port),
+	}
+	if cfg.TLSPort > 0 {
+		turnURLs = append(turnURLs, fmt.Sprintf("turns:%s:%d?transport=tcp", cfg.Host, cfg.TLSPort))
+	}
 
 	username, credential := GenerateTURNCredentials(cfg.Secret, userID, cfg.TTL)
 
 	return []ICEServerInfo{
 		{URLs: []string{stunURL}},
-		{URLs: []string{turnURL}, Username: username, Credential: credential},
+		{URLs: turnURLs, Username: username, Credential: credential},
 	}
 }
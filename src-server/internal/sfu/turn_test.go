@@ -0,0 +1,29 @@
+package sfu
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"lobby/internal/config"
+)
+
+func TestBuildICEServersOffersTCPAndTLS(t *testing.T) {
+	cfg := config.TURNConfig{Host: "turn.example.com", Port: 3478, Secret: "secret", TTL: time.Hour}
+
+	servers := BuildICEServers(cfg, "usr_1")
+	want := []string{"turn:turn.example.com:3478", "turn:turn.example.com:3478?transport=tcp"}
+	if len(servers) != 2 || !slices.Equal(servers[1].URLs, want) {
+		t.Fatalf("servers = %+v, want TURN over %v", servers, want)
+	}
+
+	cfg.TLSPort = 5349
+	servers = BuildICEServers(cfg, "usr_1")
+	if got := servers[1].URLs; got[len(got)-1] != "turns:turn.example.com:5349?transport=tcp" {
+		t.Fatalf("TURN URLs = %v, want a turns: URL last", got)
+	}
+
+	if BuildICEServers(config.TURNConfig{}, "usr_1") != nil {
+		t.Fatal("ICE servers without a TURN host, want nil")
+	}
+}
@@ -0,0 +1,147 @@
+package sfu
+
+import (
+	"log/slog"
+
+	"github.com/pion/webrtc/v4"
+)
+
+// whisper limits who hears a user's audio to its recipients. Other peers'
+// outputs of the whisperer are paused with ReplaceTrack rather than removed,
+// so ending the whisper needs no renegotiation. Peers that join during a
+// whisper get the track only once it ends.
+type whisper struct {
+	recipients map[string]struct{}
+	paused     map[string]struct{} // listeners whose output is paused
+}
+
+// SetWhisper sends userID's audio only to recipients until ClearWhisper.
+// Relays outside recipients stop receiving it, and it is neither recorded nor
+// cascaded to other instances. Calling it again replaces the recipients.
+func (s *SFU) SetWhisper(userID string, recipients []string) {
+	s.whisperMu.Lock()
+	defer s.whisperMu.Unlock()
+
+	s.mu.Lock()
+	if s.whispers == nil {
+		s.whispers = make(map[string]*whisper)
+	}
+	w := s.whispers[userID]
+	if w == nil {
+		w = &whisper{paused: make(map[string]struct{})}
+		s.whispers[userID] = w
+	}
+	w.recipients = make(map[string]struct{}, len(recipients))
+	for _, recipient := range recipients {
+		w.recipients[recipient] = struct{}{}
+	}
+	s.mu.Unlock()
+
+	s.applyWhisper(userID, w)
+	slog.Debug("whisper set", "component", "sfu", "user_id", userID, "recipients", len(recipients))
+}
+
+// ClearWhisper sends userID's audio to everyone again.
+func (s *SFU) ClearWhisper(userID string) {
+	s.whisperMu.Lock()
+	defer s.whisperMu.Unlock()
+
+	s.mu.Lock()
+	w, ok := s.whispers[userID]
+	delete(s.whispers, userID)
+	s.mu.Unlock()
+	if !ok {
+		return
+	}
+
+	w.recipients = nil
+	s.applyWhisper(userID, w)
+	slog.Debug("whisper cleared", "component", "sfu", "user_id", userID)
+}
+
+// IsWhispering reports whether userID's audio only reaches whisper recipients.
+func (s *SFU) IsWhispering(userID string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	_, ok := s.whispers[userID]
+	return ok
+}
+
+// applyWhisper pauses, resumes or adds userID's audio output on every other
+// peer to match w. Nil recipients means everyone hears it. s.whisperMu must
+// be held.
+func (s *SFU) applyWhisper(userID string, w *whisper) {
+	s.mu.RLock()
+	listeners := make(map[string]*Peer, len(s.peers))
+	for listenerID, peer := range s.peers {
+		if listenerID != userID && !peer.IsClosed() {
+			listeners[listenerID] = peer
+		}
+	}
+	source := s.peers[userID]
+	var track *webrtc.TrackLocalStaticRTP
+	if r, ok := s.relays[userID]; ok {
+		track = r.track
+	}
+	s.mu.RUnlock()
+	if track == nil && source != nil {
+		track = source.GetLocalTrack("audio")
+	}
+	if track == nil {
+		return
+	}
+
+	for listenerID, peer := range listeners {
+		_, paused := w.paused[listenerID]
+		if !w.hears(listenerID) {
+			if !paused && peer.HasOutputTrack(userID, "audio") {
+				if err := peer.ReplaceTrack(userID, "audio", nil); err != nil {
+					slog.Error("error pausing whispered audio", "component", "sfu", "peer_id", listenerID, "error", err)
+					continue
+				}
+				w.paused[listenerID] = struct{}{}
+			}
+			continue
+		}
+
+		if !peer.HasOutputTrack(userID, "audio") {
+			// Joined during the whisper, so never got the track
+			if err := peer.AddTrack(userID, "audio", track); err != nil {
+				slog.Error("error adding whispered audio", "component", "sfu", "peer_id", listenerID, "error", err)
+				continue
+			}
+			s.triggerRenegotiation(listenerID, peer)
+		} else if paused {
+			if err := peer.ReplaceTrack(userID, "audio", track); err != nil {
+				slog.Error("error resuming whispered audio", "component", "sfu", "peer_id", listenerID, "error", err)
+				continue
+			}
+		}
+		delete(w.paused, listenerID)
+	}
+}
+
+// hears reports whether listenerID receives the whisperer's audio.
+func (w *whisper) hears(listenerID string) bool {
+	if w.recipients == nil {
+		return true
+	}
+	_, ok := w.recipients[listenerID]
+	return ok
+}
+
+// mayHearLocked reports whether listenerID receives userID's audio. s.mu
+// must be held.
+func (s *SFU) mayHearLocked(userID, listenerID string) bool {
+	w, ok := s.whispers[userID]
+	return !ok || w.hears(listenerID)
+}
+
+// forgetWhisperLocked drops userID's whisper and its paused outputs on other
+// whisperers, once userID's peer or relay is gone. s.mu must be held.
+func (s *SFU) forgetWhisperLocked(userID string) {
+	delete(s.whispers, userID)
+	for _, w := range s.whispers {
+		delete(w.paused, userID)
+	}
+}
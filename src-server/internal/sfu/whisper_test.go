@@ -0,0 +1,55 @@
+package sfu
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/pion/rtp"
+)
+
+func TestWhisperReachesOnlyRecipients(t *testing.T) {
+	s, err := New(&Config{})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	t.Cleanup(s.Close)
+
+	heard := make(map[string][]string)
+	for _, userID := range []string{"usr_1", "usr_2", "usr_3"} {
+		if err := s.AddRelay(userID, func(frame RelayFrame) {
+			heard[userID] = append(heard[userID], frame.SourceUserID)
+		}); err != nil {
+			t.Fatalf("AddRelay(%s) error = %v", userID, err)
+		}
+	}
+	var tapped int
+	s.SetAudioTap(func(string, *rtp.Packet) { tapped++ })
+
+	s.SetWhisper("usr_1", []string{"usr_2"})
+	if err := s.WriteRelayAudio("usr_1", RelayFrame{Sequence: 1, Payload: []byte{0xfc}}); err != nil {
+		t.Fatalf("WriteRelayAudio() error = %v", err)
+	}
+	if !slices.Equal(heard["usr_2"], []string{"usr_1"}) || len(heard["usr_3"]) != 0 {
+		t.Fatalf("heard = %v, want only usr_2 to hear usr_1", heard)
+	}
+	if tapped != 0 {
+		t.Fatal("whispered audio reached the audio tap")
+	}
+
+	s.ClearWhisper("usr_1")
+	if s.IsWhispering("usr_1") {
+		t.Fatal("still whispering after ClearWhisper")
+	}
+	if err := s.WriteRelayAudio("usr_1", RelayFrame{Sequence: 2, Payload: []byte{0xfc}}); err != nil {
+		t.Fatalf("WriteRelayAudio() error = %v", err)
+	}
+	if len(heard["usr_3"]) != 1 || tapped != 1 {
+		t.Fatalf("heard = %v, tapped = %d after the whisper ended", heard, tapped)
+	}
+
+	s.SetWhisper("usr_1", []string{"usr_3"})
+	s.RemoveRelay("usr_1")
+	if s.IsWhispering("usr_1") {
+		t.Fatal("whisper kept after its relay was removed")
+	}
+}
@@ -0,0 +1,713 @@
+package testutil
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/frisksitron/lobby/src-server/pkg/lobbyclient"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/gorilla/websocket"
+	"github.com/vmihailenco/msgpack/v5"
+
+	"lobby/internal/api"
+	"lobby/internal/auth"
+	"lobby/internal/config"
+	"lobby/internal/constants"
+	"lobby/internal/db"
+	"lobby/internal/models"
+	"lobby/internal/ws"
+)
+
+func TestIdentifyMessageHistory(t *testing.T) {
+	server := NewServer(t)
+	alice := server.SignUp(t, "alice@example.com", "alice")
+	bob := server.SignUp(t, "bob@example.com", "bob")
+
+	aliceWS := server.Connect(t, alice.AccessToken)
+	if aliceWS.Ready.User == nil || aliceWS.Ready.User.ID != alice.User.ID {
+		t.Fatalf("READY user = %+v, want %q", aliceWS.Ready.User, alice.User.ID)
+	}
+	bobWS := server.Connect(t, bob.AccessToken)
+
+	aliceWS.Send(t, ws.CmdMessageSend, ws.MessageSendPayload{Content: "hello bob", Nonce: "n1"})
+
+	var echoed ws.MessageCreatePayload
+	aliceWS.Expect(t, ws.EventMessageCreate).Decode(t, &echoed)
+	if echoed.Nonce != "n1" || echoed.Content != "hello bob" {
+		t.Fatalf("sender MESSAGE_CREATE = %+v, want nonce echo", echoed)
+	}
+	var received ws.MessageCreatePayload
+	bobWS.Expect(t, ws.EventMessageCreate).Decode(t, &received)
+	if received.ID != echoed.ID || received.Author == nil || received.Author.ID != alice.User.ID {
+		t.Fatalf("recipient MESSAGE_CREATE = %+v, want %s by %s", received, echoed.ID, alice.User.ID)
+	}
+
+	var history []models.Message
+	if status := server.Do(t, http.MethodGet, "/api/v1/messages", bob.AccessToken, nil, &history); status != http.StatusOK {
+		t.Fatalf("GET /api/v1/messages status = %d", status)
+	}
+	if len(history) != 1 || history[0].ID != echoed.ID || history[0].AuthorID != alice.User.ID || history[0].Content != "hello bob" {
+		t.Fatalf("history = %+v, want the sent message", history)
+	}
+
+	if status := server.Do(t, http.MethodGet, "/api/v1/messages", "", nil, nil); status != http.StatusUnauthorized {
+		t.Fatalf("anonymous history status = %d, want %d", status, http.StatusUnauthorized)
+	}
+}
+
+func TestAccessTokenExpiresWithClock(t *testing.T) {
+	server := NewServer(t)
+	alice := server.SignUp(t, "alice@example.com", "alice")
+
+	if status := server.Do(t, http.MethodGet, "/api/v1/users/me", alice.AccessToken, nil, nil); status != http.StatusOK {
+		t.Fatalf("GET /api/v1/users/me status = %d, want %d", status, http.StatusOK)
+	}
+	server.Clock.Advance(server.Config.Auth.AccessTokenTTL + time.Second)
+	if status := server.Do(t, http.MethodGet, "/api/v1/users/me", alice.AccessToken, nil, nil); status != http.StatusUnauthorized {
+		t.Fatalf("expired token status = %d, want %d", status, http.StatusUnauthorized)
+	}
+
+	var refreshed api.RefreshResponse
+	if status := server.Do(t, http.MethodPost, "/api/v1/auth/refresh", "", api.RefreshRequest{RefreshToken: alice.RefreshToken}, &refreshed); status != http.StatusOK {
+		t.Fatalf("refresh status = %d, want %d", status, http.StatusOK)
+	}
+	if status := server.Do(t, http.MethodGet, "/api/v1/users/me", refreshed.AccessToken, nil, nil); status != http.StatusOK {
+		t.Fatalf("refreshed token status = %d, want %d", status, http.StatusOK)
+	}
+}
+
+func TestAccessTokenKeyFileSigningAndLeeway(t *testing.T) {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("ed25519.GenerateKey() error = %v", err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatalf("x509.MarshalPKCS8PrivateKey() error = %v", err)
+	}
+	keyFile := filepath.Join(t.TempDir(), "jwt.pem")
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0o600); err != nil {
+		t.Fatalf("writing key file: %v", err)
+	}
+
+	server := NewServer(t, func(cfg *config.Config) {
+		cfg.Auth.JWTAlgorithm = auth.JWTAlgorithmEdDSA
+		cfg.Auth.JWTKeyFile = keyFile
+		cfg.Auth.JWTLeeway = time.Minute
+	})
+	alice := server.SignUp(t, "alice@example.com", "alice")
+
+	token, _, err := jwt.NewParser().ParseUnverified(alice.AccessToken, &auth.Claims{})
+	if err != nil {
+		t.Fatalf("ParseUnverified() error = %v", err)
+	}
+	if token.Method.Alg() != auth.JWTAlgorithmEdDSA {
+		t.Fatalf("access token alg = %s, want %s", token.Method.Alg(), auth.JWTAlgorithmEdDSA)
+	}
+
+	server.Clock.Advance(server.Config.Auth.AccessTokenTTL + 30*time.Second)
+	if status := server.Do(t, http.MethodGet, "/api/v1/users/me", alice.AccessToken, nil, nil); status != http.StatusOK {
+		t.Fatalf("token within leeway status = %d, want %d", status, http.StatusOK)
+	}
+	server.Clock.Advance(time.Minute)
+	if status := server.Do(t, http.MethodGet, "/api/v1/users/me", alice.AccessToken, nil, nil); status != http.StatusUnauthorized {
+		t.Fatalf("token past leeway status = %d, want %d", status, http.StatusUnauthorized)
+	}
+}
+
+func TestFirstUserBecomesAdmin(t *testing.T) {
+	server := NewServer(t, func(cfg *config.Config) {
+		cfg.Auth.FirstUserAdmin = true
+	})
+	alice := server.SignUp(t, "alice@example.com", "alice")
+	bob := server.SignUp(t, "bob@example.com", "bob")
+
+	if alice.User.Role != models.RoleAdmin {
+		t.Fatalf("first user role = %q, want %q", alice.User.Role, models.RoleAdmin)
+	}
+	var me models.User
+	if status := server.Do(t, http.MethodGet, "/api/v1/users/me", bob.AccessToken, nil, &me); status != http.StatusOK {
+		t.Fatalf("GET /api/v1/users/me status = %d", status)
+	}
+	if bob.User.Role != models.RoleMember || me.Role != models.RoleMember {
+		t.Fatalf("second user role = %q (me %q), want %q", bob.User.Role, me.Role, models.RoleMember)
+	}
+}
+
+func TestMagicCodeRequiresSolvedChallenge(t *testing.T) {
+	server := NewServer(t, func(cfg *config.Config) {
+		cfg.Auth.MagicCodePoWBits = 8
+	})
+	const email = "alice@example.com"
+
+	if status := server.Do(t, http.MethodPost, "/api/v1/auth/login/magic-code", "", api.MagicCodeRequest{Email: email}, nil); status != http.StatusForbidden {
+		t.Fatalf("unsolved request status = %d, want %d", status, http.StatusForbidden)
+	}
+
+	var challenge api.MagicCodeChallengeResponse
+	server.Do(t, http.MethodGet, "/api/v1/auth/login/challenge", "", nil, &challenge)
+	if !challenge.Required || challenge.Bits != 8 {
+		t.Fatalf("challenge = %+v, want 8 required bits", challenge)
+	}
+	solved := api.MagicCodeRequest{
+		Email:     email,
+		Challenge: challenge.Challenge,
+		Nonce:     auth.SolveChallenge(challenge.Challenge, email, challenge.Bits),
+	}
+	other := solved
+	other.Email = "bob@example.com"
+	if status := server.Do(t, http.MethodPost, "/api/v1/auth/login/magic-code", "", other, nil); status != http.StatusForbidden {
+		t.Fatalf("solution reused for another email status = %d, want %d", status, http.StatusForbidden)
+	}
+	if status := server.Do(t, http.MethodPost, "/api/v1/auth/login/magic-code", "", solved, nil); status != http.StatusOK {
+		t.Fatalf("solved request status = %d, want %d", status, http.StatusOK)
+	}
+	if status := server.Do(t, http.MethodPost, "/api/v1/auth/login/magic-code", "", solved, nil); status != http.StatusForbidden {
+		t.Fatalf("replayed solution status = %d, want %d", status, http.StatusForbidden)
+	}
+	if got := len(server.Mailbox.Messages(email)); got != 1 {
+		t.Fatalf("mails sent = %d, want 1", got)
+	}
+
+	server.SignUp(t, "carol@example.com", "carol")
+}
+
+// Each test stays under the per-IP limit of five magic code requests a minute.
+func TestMagicCodeBackoffAndDailyCap(t *testing.T) {
+	server := NewServer(t, func(cfg *config.Config) {
+		cfg.Auth.MagicCodeDailyLimit = 2
+	})
+
+	steps := []struct {
+		advance time.Duration
+		want    int
+	}{
+		{0, http.StatusOK},
+		{29 * time.Second, http.StatusTooManyRequests},
+		{time.Second, http.StatusOK},
+		{time.Hour, http.StatusTooManyRequests}, // daily cap
+		{24 * time.Hour, http.StatusOK},
+	}
+	for i, step := range steps {
+		server.Clock.Advance(step.advance)
+		status := server.Do(t, http.MethodPost, "/api/v1/auth/login/magic-code", "", api.MagicCodeRequest{Email: "alice@example.com"}, nil)
+		if status != step.want {
+			t.Fatalf("request %d status = %d, want %d", i, status, step.want)
+		}
+	}
+}
+
+func TestMagicCodeThrottleIsPerAddressAndLiftedBySignIn(t *testing.T) {
+	server := NewServer(t)
+	const email = "alice@example.com"
+	request := func(email string) int {
+		return server.Do(t, http.MethodPost, "/api/v1/auth/login/magic-code", "", api.MagicCodeRequest{Email: email}, nil)
+	}
+
+	if status := request(email); status != http.StatusOK {
+		t.Fatalf("first request status = %d, want %d", status, http.StatusOK)
+	}
+	if status := request(email); status != http.StatusTooManyRequests {
+		t.Fatalf("second request status = %d, want %d", status, http.StatusTooManyRequests)
+	}
+	if status := request("bob@example.com"); status != http.StatusOK {
+		t.Fatalf("other address status = %d, want %d", status, http.StatusOK)
+	}
+
+	if status := server.Do(t, http.MethodPost, "/api/v1/auth/login/magic-code/verify", "", api.VerifyMagicCodeRequest{
+		Email: email,
+		Code:  server.Mailbox.LastCode(t, email),
+	}, nil); status != http.StatusOK {
+		t.Fatalf("verify status = %d, want %d", status, http.StatusOK)
+	}
+	if status := request(email); status != http.StatusOK {
+		t.Fatalf("request after sign-in status = %d, want throttle lifted", status)
+	}
+}
+
+func TestMutedUserHiddenFromGatewayButNotHistory(t *testing.T) {
+	server := NewServer(t)
+	alice := server.SignUp(t, "alice@example.com", "alice")
+	bob := server.SignUp(t, "bob@example.com", "bob")
+	carol := server.SignUp(t, "carol@example.com", "carol")
+
+	if status := server.Do(t, http.MethodPut, "/api/v1/users/me/mutes/"+bob.User.ID, alice.AccessToken, nil, nil); status != http.StatusNoContent {
+		t.Fatalf("mute status = %d, want %d", status, http.StatusNoContent)
+	}
+	var mutes []api.MuteResponse
+	server.Do(t, http.MethodGet, "/api/v1/users/me/mutes", alice.AccessToken, nil, &mutes)
+	if len(mutes) != 1 || mutes[0].UserID != bob.User.ID {
+		t.Fatalf("mutes = %+v, want bob", mutes)
+	}
+
+	aliceWS := server.Connect(t, alice.AccessToken)
+	bobWS := server.Connect(t, bob.AccessToken)
+	carolWS := server.Connect(t, carol.AccessToken)
+
+	bobWS.Send(t, ws.CmdTyping, nil)
+	bobWS.Send(t, ws.CmdMessageSend, ws.MessageSendPayload{Content: "from bob", Nonce: "b1"})
+	bobWS.Expect(t, ws.EventMessageCreate)
+	carolWS.Send(t, ws.CmdMessageSend, ws.MessageSendPayload{Content: "from carol", Nonce: "c1"})
+
+	for {
+		frame := aliceWS.read(t)
+		if frame.Op != ws.OpDispatch {
+			continue
+		}
+		if frame.Type == ws.EventTypingStart {
+			t.Fatalf("alice received TYPING_START from a muted user: %s", frame.Data)
+		}
+		if frame.Type == ws.EventMessageCreate {
+			var message ws.MessageCreatePayload
+			frame.Decode(t, &message)
+			if message.Content != "from carol" {
+				t.Fatalf("alice received %q, want muted user's message skipped", message.Content)
+			}
+			break
+		}
+	}
+
+	var history []models.Message
+	server.Do(t, http.MethodGet, "/api/v1/messages", alice.AccessToken, nil, &history)
+	if len(history) != 2 {
+		t.Fatalf("history has %d messages, want both", len(history))
+	}
+
+	if status := server.Do(t, http.MethodDelete, "/api/v1/users/me/mutes/"+bob.User.ID, alice.AccessToken, nil, nil); status != http.StatusNoContent {
+		t.Fatalf("unmute status = %d, want %d", status, http.StatusNoContent)
+	}
+	server.Clock.Advance(time.Second) // past bob's message rate limit
+	bobWS.Send(t, ws.CmdMessageSend, ws.MessageSendPayload{Content: "bob again", Nonce: "b2"})
+	var message ws.MessageCreatePayload
+	aliceWS.Expect(t, ws.EventMessageCreate).Decode(t, &message)
+	if message.Content != "bob again" {
+		t.Fatalf("after unmute alice received %q, want bob's message", message.Content)
+	}
+}
+
+func TestBootstrapCombinesInitialLoad(t *testing.T) {
+	server := NewServer(t)
+	alice := server.SignUp(t, "alice@example.com", "alice")
+	bob := server.SignUp(t, "bob@example.com", "bob")
+
+	aliceWS := server.Connect(t, alice.AccessToken)
+	aliceWS.Send(t, ws.CmdMessageSend, ws.MessageSendPayload{Content: "first", Nonce: "n1"})
+	aliceWS.Expect(t, ws.EventMessageCreate)
+	if status := server.Do(t, http.MethodPut, "/api/v1/users/me/mutes/"+alice.User.ID, bob.AccessToken, nil, nil); status != http.StatusNoContent {
+		t.Fatalf("mute status = %d", status)
+	}
+
+	var boot api.BootstrapResponse
+	if status := server.Do(t, http.MethodGet, "/api/v1/bootstrap", bob.AccessToken, nil, &boot); status != http.StatusOK {
+		t.Fatalf("GET /api/v1/bootstrap status = %d", status)
+	}
+	if boot.User == nil || boot.User.ID != bob.User.ID {
+		t.Fatalf("user = %+v, want bob", boot.User)
+	}
+	if boot.Server.Name != server.Config.Server.Name || boot.Server.UploadMaxBytes == 0 {
+		t.Fatalf("server = %+v, want configured server info", boot.Server)
+	}
+	if len(boot.Messages) != 1 || boot.Messages[0].Content != "first" {
+		t.Fatalf("messages = %+v, want the sent message", boot.Messages)
+	}
+	if len(boot.Mutes) != 1 || boot.Mutes[0].UserID != alice.User.ID {
+		t.Fatalf("mutes = %+v, want alice", boot.Mutes)
+	}
+	online := false
+	for _, member := range boot.Members {
+		online = online || (member.ID == alice.User.ID && member.Status == "online")
+	}
+	if !online {
+		t.Fatalf("members = %+v, want alice online", boot.Members)
+	}
+
+	if status := server.Do(t, http.MethodGet, "/api/v1/bootstrap", "", nil, nil); status != http.StatusUnauthorized {
+		t.Fatalf("anonymous bootstrap status = %d, want %d", status, http.StatusUnauthorized)
+	}
+}
+
+func TestVoiceSelfTestIsAdminOnly(t *testing.T) {
+	server := NewServer(t, func(cfg *config.Config) {
+		cfg.Auth.FirstUserAdmin = true
+	})
+	alice := server.SignUp(t, "alice@example.com", "alice")
+	bob := server.SignUp(t, "bob@example.com", "bob")
+
+	if status := server.Do(t, http.MethodPost, "/api/v1/voice/selftest", bob.AccessToken, nil, nil); status != http.StatusForbidden {
+		t.Fatalf("member self-test status = %d, want %d", status, http.StatusForbidden)
+	}
+
+	var report api.VoiceSelfTestResponse
+	if status := server.Do(t, http.MethodPost, "/api/v1/voice/selftest", alice.AccessToken, nil, &report); status != http.StatusOK {
+		t.Fatalf("admin self-test status = %d", status)
+	}
+	if len(report.Checks) == 0 || report.Checks[0].Name != "peer" || report.Checks[0].Status != "pass" {
+		t.Fatalf("checks = %+v, want a passing peer check first", report.Checks)
+	}
+}
+
+func TestVoiceRoutesIsAdminOnly(t *testing.T) {
+	server := NewServer(t, func(cfg *config.Config) {
+		cfg.Auth.FirstUserAdmin = true
+	})
+	alice := server.SignUp(t, "alice@example.com", "alice")
+	bob := server.SignUp(t, "bob@example.com", "bob")
+
+	if status := server.Do(t, http.MethodGet, "/api/v1/voice/routes", bob.AccessToken, nil, nil); status != http.StatusForbidden {
+		t.Fatalf("member routes status = %d, want %d", status, http.StatusForbidden)
+	}
+	var routes []api.VoiceRouteResponse
+	if status := server.Do(t, http.MethodGet, "/api/v1/voice/routes", alice.AccessToken, nil, &routes); status != http.StatusOK {
+		t.Fatalf("admin routes status = %d", status)
+	}
+	if len(routes) != 0 {
+		t.Fatalf("routes = %+v, want none without voice peers", routes)
+	}
+}
+
+func TestVoiceStatsIsAdminOnly(t *testing.T) {
+	server := NewServer(t, func(cfg *config.Config) {
+		cfg.Auth.FirstUserAdmin = true
+	})
+	alice := server.SignUp(t, "alice@example.com", "alice")
+	bob := server.SignUp(t, "bob@example.com", "bob")
+
+	if status := server.Do(t, http.MethodGet, "/api/v1/voice/stats", bob.AccessToken, nil, nil); status != http.StatusForbidden {
+		t.Fatalf("member stats status = %d, want %d", status, http.StatusForbidden)
+	}
+	var stats []api.VoiceStatsResponse
+	if status := server.Do(t, http.MethodGet, "/api/v1/voice/stats", alice.AccessToken, nil, &stats); status != http.StatusOK {
+		t.Fatalf("admin stats status = %d", status)
+	}
+	if len(stats) != 0 {
+		t.Fatalf("stats = %+v, want none without voice peers", stats)
+	}
+}
+
+func TestSortableMessageIDs(t *testing.T) {
+	server := NewServer(t, func(cfg *config.Config) {
+		cfg.Database.IDFormat = db.IDFormatULID
+	})
+	alice := server.SignUp(t, "alice@example.com", "alice")
+	aliceWS := server.Connect(t, alice.AccessToken)
+
+	var ids []string
+	for _, nonce := range []string{"n1", "n2", "n3"} {
+		aliceWS.Send(t, ws.CmdMessageSend, ws.MessageSendPayload{Content: nonce, Nonce: nonce})
+		var created ws.MessageCreatePayload
+		aliceWS.Expect(t, ws.EventMessageCreate).Decode(t, &created)
+		if len(ids) > 0 && created.ID <= ids[len(ids)-1] {
+			t.Fatalf("message ID %s does not sort after %s", created.ID, ids[len(ids)-1])
+		}
+		ids = append(ids, created.ID)
+		server.Clock.Advance(time.Second) // clear the message rate limit
+	}
+
+	var history []models.Message
+	if status := server.Do(t, http.MethodGet, "/api/v1/messages?before="+ids[2], alice.AccessToken, nil, &history); status != http.StatusOK {
+		t.Fatalf("GET /api/v1/messages?before status = %d", status)
+	}
+	if len(history) != 2 || history[0].ID != ids[1] || history[1].ID != ids[0] {
+		t.Fatalf("history = %+v, want the first two messages newest first", history)
+	}
+}
+
+func TestLobbyClientAgainstServer(t *testing.T) {
+	server := NewServer(t, func(cfg *config.Config) {
+		cfg.Auth.FirstUserAdmin = true
+	})
+	alice := server.SignUp(t, "alice@example.com", "alice")
+	ctx := context.Background()
+
+	client := lobbyclient.New(server.URL)
+	var apiErr *lobbyclient.APIError
+	if _, err := client.Me(ctx); !errors.As(err, &apiErr) || apiErr.Status != http.StatusUnauthorized || apiErr.Code != lobbyclient.ErrCodeAuthFailed {
+		t.Fatalf("anonymous Me() error = %v, want 401 %s", err, lobbyclient.ErrCodeAuthFailed)
+	}
+	client.SetToken(alice.AccessToken)
+	if me, err := client.Me(ctx); err != nil || me.ID != alice.User.ID {
+		t.Fatalf("Me() = %+v, %v, want alice", me, err)
+	}
+
+	gw, err := client.Connect(ctx, alice.AccessToken, lobbyclient.GatewayOptions{})
+	if err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+	defer gw.Close()
+	if ready := gw.Ready(); ready.User == nil || ready.User.ID != alice.User.ID {
+		t.Fatalf("READY user = %+v, want alice", ready.User)
+	}
+	if err := gw.Send(lobbyclient.CmdMessageSend, lobbyclient.MessageSendPayload{Content: "hello", Nonce: "n1"}); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	var created lobbyclient.MessageCreatePayload
+	for frame := range gw.Frames() {
+		if frame.Type == lobbyclient.EventMessageCreate {
+			if err := frame.Decode(&created); err != nil {
+				t.Fatalf("decoding MESSAGE_CREATE: %v", err)
+			}
+			break
+		}
+	}
+	if created.Nonce != "n1" {
+		t.Fatalf("MESSAGE_CREATE = %+v, want nonce echo (gateway error %v)", created, gw.Err())
+	}
+
+	messages, err := client.ListMessages(ctx, "", 10)
+	if err != nil || len(messages) != 1 || messages[0].ID != created.ID {
+		t.Fatalf("ListMessages() = %+v, %v, want the sent message", messages, err)
+	}
+
+	var bot api.BotResponse
+	if status := server.Do(t, http.MethodPost, "/api/v1/admin/bots", alice.AccessToken, api.CreateBotRequest{Username: "helper"}, &bot); status != http.StatusCreated {
+		t.Fatalf("create bot status = %d", status)
+	}
+	var botToken api.CreateBotTokenResponse
+	if status := server.Do(t, http.MethodPost, "/api/v1/admin/bots/"+bot.ID+"/tokens", alice.AccessToken, api.CreateBotTokenRequest{
+		Name:   "sdk",
+		Scopes: []string{models.ScopeMessagesRead},
+	}, &botToken); status != http.StatusCreated {
+		t.Fatalf("create bot token status = %d", status)
+	}
+	botGW, err := lobbyclient.New(server.URL).Connect(ctx, botToken.Token, lobbyclient.GatewayOptions{Bot: true})
+	if err != nil {
+		t.Fatalf("bot Connect() error = %v", err)
+	}
+	defer botGW.Close()
+	history, err := botGW.History(ctx, "", 0)
+	if err != nil || len(history) != 1 || history[0].ID != created.ID {
+		t.Fatalf("History() = %+v, %v, want the sent message", history, err)
+	}
+	var gwErr *lobbyclient.GatewayError
+	if _, err := botGW.Members(ctx, 0); !errors.As(err, &gwErr) || gwErr.Code != lobbyclient.ErrCodeForbidden {
+		t.Fatalf("Members() without members:read error = %v, want %s", err, lobbyclient.ErrCodeForbidden)
+	}
+}
+
+func TestCommandCorrelation(t *testing.T) {
+	server := NewServer(t)
+	alice := server.SignUp(t, "alice@example.com", "alice")
+	ctx := context.Background()
+
+	gw, err := lobbyclient.New(server.URL).Connect(ctx, alice.AccessToken, lobbyclient.GatewayOptions{})
+	if err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+	defer gw.Close()
+
+	if err := gw.Command(ctx, lobbyclient.CmdPresenceSet, lobbyclient.PresenceSetPayload{Status: "dnd"}); err != nil {
+		t.Fatalf("PRESENCE_SET error = %v, want ACK", err)
+	}
+	var gwErr *lobbyclient.GatewayError
+	err = gw.Command(ctx, lobbyclient.CmdMessageSend, lobbyclient.MessageSendPayload{Content: strings.Repeat("a", constants.MessageMaxContentLength+1)})
+	if !errors.As(err, &gwErr) || gwErr.Code != lobbyclient.ErrCodeMessageTooLong {
+		t.Fatalf("oversized MESSAGE_SEND error = %v, want NACK %s", err, lobbyclient.ErrCodeMessageTooLong)
+	}
+	err = gw.Command(ctx, "NOT_A_COMMAND", nil)
+	if !errors.As(err, &gwErr) || gwErr.Code != lobbyclient.ErrCodeInvalidRequest {
+		t.Fatalf("unknown command error = %v, want NACK %s", err, lobbyclient.ErrCodeInvalidRequest)
+	}
+}
+
+func TestBatchCapability(t *testing.T) {
+	server := NewServer(t)
+	alice := server.SignUp(t, "alice@example.com", "alice")
+	ctx := context.Background()
+
+	gw, err := lobbyclient.New(server.URL).Connect(ctx, alice.AccessToken, lobbyclient.GatewayOptions{Batch: true})
+	if err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+	defer gw.Close()
+	if caps := gw.Ready().Capabilities; len(caps) != 1 || caps[0] != lobbyclient.CapabilityBatch {
+		t.Fatalf("READY capabilities = %v, want [%s]", caps, lobbyclient.CapabilityBatch)
+	}
+
+	// Batched or not, frames arrive one by one.
+	if err := gw.Command(ctx, lobbyclient.CmdMessageSend, lobbyclient.MessageSendPayload{Content: "hello", Nonce: "n1"}); err != nil {
+		t.Fatalf("MESSAGE_SEND error = %v", err)
+	}
+	for frame := range gw.Frames() {
+		if frame.Op == lobbyclient.OpBatch {
+			t.Fatal("Frames delivered an unpacked BATCH")
+		}
+		if frame.Type == lobbyclient.EventMessageCreate {
+			return
+		}
+	}
+	t.Fatalf("no MESSAGE_CREATE (gateway error %v)", gw.Err())
+}
+
+func TestResumeReplaysMissedEvents(t *testing.T) {
+	server := NewServer(t)
+	alice := server.SignUp(t, "alice@example.com", "alice")
+	bob := server.SignUp(t, "bob@example.com", "bob")
+
+	aliceWS := server.Connect(t, alice.AccessToken)
+	bobWS := server.Connect(t, bob.AccessToken)
+	aliceWS.Expect(t, ws.EventPresenceUpdate) // bob online
+	if aliceWS.Seq == 0 {
+		t.Fatal("PRESENCE_UPDATE carried no seq")
+	}
+	aliceWS.Close()
+	for {
+		var presence ws.PresenceUpdatePayload
+		bobWS.Expect(t, ws.EventPresenceUpdate).Decode(t, &presence)
+		if presence.UserID == alice.User.ID && presence.Status == "offline" {
+			break
+		}
+	}
+
+	bobWS.Send(t, ws.CmdMessageSend, ws.MessageSendPayload{Content: "while you were out", Nonce: "n1"})
+	bobWS.Expect(t, ws.EventMessageCreate)
+
+	stale := server.Dial(t)
+	stale.Send(t, ws.CmdResume, ws.ResumePayload{Token: alice.AccessToken, SessionID: "not-a-session", Seq: aliceWS.Seq})
+	if frame := stale.read(t); frame.Op != ws.OpInvalidSession {
+		t.Fatalf("RESUME with an unknown session got op %d %s, want INVALID_SESSION", frame.Op, frame.Type)
+	}
+
+	resumed := server.Dial(t)
+	resumed.Send(t, ws.CmdResume, ws.ResumePayload{Token: alice.AccessToken, SessionID: aliceWS.Ready.SessionID, Seq: aliceWS.Seq})
+	var created ws.MessageCreatePayload
+	frame := resumed.Expect(t, ws.EventMessageCreate)
+	frame.Decode(t, &created)
+	if created.Content != "while you were out" || frame.Seq <= aliceWS.Seq {
+		t.Fatalf("replayed %q at seq %d, want bob's message after seq %d", created.Content, frame.Seq, aliceWS.Seq)
+	}
+	var done ws.ResumedPayload
+	resumed.Expect(t, ws.EventResumed).Decode(t, &done)
+	if done.SessionID != aliceWS.Ready.SessionID || done.Replayed == 0 {
+		t.Fatalf("RESUMED = %+v, want alice's session with replayed events", done)
+	}
+
+	// The resumed session keeps its seq and receives live events.
+	server.Clock.Advance(time.Second) // clear the message rate limit
+	bobWS.Send(t, ws.CmdMessageSend, ws.MessageSendPayload{Content: "welcome back", Nonce: "n2"})
+	live := resumed.Expect(t, ws.EventMessageCreate)
+	if live.Seq <= frame.Seq {
+		t.Fatalf("live event seq %d, want after %d", live.Seq, frame.Seq)
+	}
+
+	// A rejected RESUME leaves the connection open for IDENTIFY.
+	stale.Send(t, ws.CmdIdentify, ws.IdentifyPayload{Token: bob.AccessToken})
+	for frame := stale.read(t); frame.Op != ws.OpReady; frame = stale.read(t) {
+	}
+}
+
+func TestMsgpackGatewayEncoding(t *testing.T) {
+	server := NewServer(t)
+	alice := server.SignUp(t, "alice@example.com", "alice")
+	wsURL := "ws" + strings.TrimPrefix(server.URL, "http") + "/ws"
+
+	if _, resp, err := websocket.DefaultDialer.Dial(wsURL+"?encoding=xml", nil); err == nil || resp == nil || resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("dial with an unknown encoding: err=%v, want 400", err)
+	}
+
+	conn, _, err := websocket.DefaultDialer.Dial(wsURL+"?encoding="+ws.EncodingMsgpack, nil)
+	if err != nil {
+		t.Fatalf("dialing websocket: %v", err)
+	}
+	defer conn.Close()
+
+	type frame struct {
+		Op   ws.OpCode          `msgpack:"op"`
+		Type string             `msgpack:"t"`
+		Data msgpack.RawMessage `msgpack:"d"`
+	}
+	read := func() frame {
+		t.Helper()
+		_ = conn.SetReadDeadline(time.Now().Add(wsTimeout))
+		kind, data, err := conn.ReadMessage()
+		if err != nil {
+			t.Fatalf("reading websocket frame: %v", err)
+		}
+		if kind != websocket.BinaryMessage {
+			t.Fatalf("frame kind = %d, want binary", kind)
+		}
+		var f frame
+		if err := msgpack.Unmarshal(data, &f); err != nil {
+			t.Fatalf("decoding msgpack frame: %v", err)
+		}
+		return f
+	}
+	send := func(command string, data any) {
+		t.Helper()
+		encoded, err := msgpack.Marshal(map[string]any{"op": ws.OpDispatch, "t": command, "d": data})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := conn.WriteMessage(websocket.BinaryMessage, encoded); err != nil {
+			t.Fatalf("sending %s: %v", command, err)
+		}
+	}
+
+	if hello := read(); hello.Op != ws.OpHello {
+		t.Fatalf("first frame op = %d, want HELLO", hello.Op)
+	}
+	send(ws.CmdIdentify, map[string]any{"token": alice.AccessToken})
+	ready := read()
+	if ready.Op != ws.OpReady {
+		t.Fatalf("op = %d %s, want READY", ready.Op, ready.Type)
+	}
+	var payload struct {
+		User struct {
+			ID        string `msgpack:"id"`
+			CreatedAt string `msgpack:"created_at"`
+		} `msgpack:"user"`
+	}
+	if err := msgpack.Unmarshal(ready.Data, &payload); err != nil {
+		t.Fatalf("decoding READY: %v", err)
+	}
+	if payload.User.ID != alice.User.ID || payload.User.CreatedAt == "" {
+		t.Fatalf("READY user = %+v, want alice with a string created_at", payload.User)
+	}
+
+	send(ws.CmdMessageSend, map[string]any{"content": "packed", "nonce": "n1"})
+	for {
+		f := read()
+		if f.Type != ws.EventMessageCreate {
+			continue
+		}
+		var created struct {
+			Content string `msgpack:"content"`
+		}
+		if err := msgpack.Unmarshal(f.Data, &created); err != nil || created.Content != "packed" {
+			t.Fatalf("MESSAGE_CREATE = %+v, %v", created, err)
+		}
+		break
+	}
+}
+
+func TestHeartbeatAck(t *testing.T) {
+	server := NewServer(t)
+	alice := server.SignUp(t, "alice@example.com", "alice")
+	aliceWS := server.Connect(t, alice.AccessToken)
+
+	sent := time.Now().UnixMilli()
+	if err := aliceWS.conn.WriteJSON(ws.WSMessage{Op: ws.OpHeartbeat, Data: ws.HeartbeatPayload{Ts: sent}}); err != nil {
+		t.Fatalf("sending HEARTBEAT: %v", err)
+	}
+	for {
+		frame := aliceWS.read(t)
+		if frame.Op != ws.OpHeartbeatAck {
+			continue
+		}
+		var ack ws.HeartbeatPayload
+		frame.Decode(t, &ack)
+		if ack.Ts != sent || ack.ServerTs < sent {
+			t.Fatalf("HEARTBEAT_ACK = %+v, want ts %d echoed with a server timestamp", ack, sent)
+		}
+		return
+	}
+}
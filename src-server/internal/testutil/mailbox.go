@@ -0,0 +1,63 @@
+package testutil
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// Mail is one email captured by Mailbox.
+type Mail struct {
+	To       string
+	Code     string
+	Link     string // magic login link; empty for email change codes
+	NewEmail string // target address of an email change; empty for login codes
+}
+
+// Mailbox is an email.Sender that records mail instead of delivering it.
+type Mailbox struct {
+	mu   sync.Mutex
+	mail []Mail
+}
+
+func (m *Mailbox) SendMagicCode(to, code, link string, ttl time.Duration) error {
+	m.record(Mail{To: to, Code: code, Link: link})
+	return nil
+}
+
+func (m *Mailbox) SendEmailChangeCode(to, code, newEmail string, ttl time.Duration) error {
+	m.record(Mail{To: to, Code: code, NewEmail: newEmail})
+	return nil
+}
+
+func (m *Mailbox) record(mail Mail) {
+	m.mu.Lock()
+	m.mail = append(m.mail, mail)
+	m.mu.Unlock()
+}
+
+// Messages returns the mail sent to to, oldest first.
+func (m *Mailbox) Messages(to string) []Mail {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var mail []Mail
+	for _, sent := range m.mail {
+		if sent.To == to {
+			mail = append(mail, sent)
+		}
+	}
+	return mail
+}
+
+// LastCode returns the code in the latest mail sent to to, failing the test
+// if there is none.
+func (m *Mailbox) LastCode(t testing.TB, to string) string {
+	t.Helper()
+
+	mail := m.Messages(to)
+	if len(mail) == 0 {
+		t.Fatalf("no mail sent to %s", to)
+	}
+	return mail[len(mail)-1].Code
+}
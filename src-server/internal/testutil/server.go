@@ -0,0 +1,214 @@
+// Package testutil runs a complete Lobby server in-process so end-to-end
+// tests can drive it over real HTTP and websocket connections.
+package testutil
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"lobby/internal/api"
+	"lobby/internal/auth"
+	"lobby/internal/blob"
+	"lobby/internal/clock"
+	"lobby/internal/config"
+	"lobby/internal/db"
+	"lobby/internal/models"
+)
+
+// Server is an in-process Lobby server backed by a temporary SQLite database
+// and blob root. Mail is captured by Mailbox instead of being sent. The SFU
+// has no public IP or port range, so voice signaling works but no media
+// flows. Clock drives token expiry, WS rate limits, and cooldowns; it starts
+// at the real time and only moves when advanced.
+type Server struct {
+	URL     string
+	Config  *config.Config
+	DB      *db.DB
+	Mailbox *Mailbox
+	Clock   *clock.Fake
+
+	api  *api.Server
+	http *httptest.Server
+}
+
+// NewServer starts a server for the duration of t. Options may adjust the
+// config before the server is built; the defaults mirror config.yaml.
+func NewServer(t testing.TB, options ...func(*config.Config)) *Server {
+	t.Helper()
+
+	dir := t.TempDir()
+	ts := httptest.NewUnstartedServer(nil)
+	baseURL := "http://" + ts.Listener.Addr().String()
+
+	cfg := &config.Config{
+		Server: config.ServerConfig{
+			Name:    "Test Lobby",
+			BaseURL: baseURL,
+			WebSocket: config.WebSocketConfig{
+				AllowedOrigins:           []string{baseURL, "null"},
+				MaxUnauthenticatedPerIP:  20,
+				MaxUnauthenticatedGlobal: 200,
+				UnauthenticatedTimeout:   10 * time.Second,
+				MaxAuthenticatedPerIP:    50,
+				MaxVoiceSessionsPerIP:    10,
+			},
+		},
+		Database: config.DatabaseConfig{Path: filepath.Join(dir, "lobby.db")},
+		Storage: config.StorageConfig{
+			BlobRoot:       filepath.Join(dir, "blobs"),
+			UploadMaxBytes: 10 * 1024 * 1024,
+		},
+		Auth: config.AuthConfig{
+			JWTSecret:            "test-secret-that-is-at-least-32-chars",
+			AccessTokenTTL:       15 * time.Minute,
+			RefreshTokenTTL:      24 * time.Hour,
+			MagicCodeTTL:         10 * time.Minute,
+			RegistrationMode:     models.RegistrationOpen,
+			AccountDeletionGrace: 30 * 24 * time.Hour,
+		},
+		Permissions: config.PermissionsConfig{
+			ScreenShareRole:     models.RoleMember,
+			CameraRole:          models.RoleMember,
+			RecordingRole:       models.RoleModerator,
+			MentionEveryoneRole: models.RoleModerator,
+			PrioritySpeakerRole: models.RoleAdmin,
+		},
+		Recording: config.RecordingConfig{
+			MaxDuration: 4 * time.Hour,
+			Retention:   30 * 24 * time.Hour,
+		},
+	}
+	for _, option := range options {
+		option(cfg)
+	}
+
+	database, err := db.Open(cfg.Database.Path)
+	if err != nil {
+		t.Fatalf("db.Open() error = %v", err)
+	}
+	blobService, err := blob.NewService(cfg.Storage.BlobRoot, cfg.Storage.UploadLimits().Max())
+	if err != nil {
+		_ = database.Close()
+		t.Fatalf("blob.NewService() error = %v", err)
+	}
+	mailbox := &Mailbox{}
+	fakeClock := clock.NewFake(time.Now())
+	server, err := api.NewServer(cfg, database, mailbox, blobService, fakeClock)
+	if err != nil {
+		_ = database.Close()
+		t.Fatalf("api.NewServer() error = %v", err)
+	}
+
+	ts.Config.Handler = server
+	ts.Start()
+	t.Cleanup(func() {
+		ts.Close()
+		server.Shutdown()
+		_ = database.Close()
+	})
+
+	return &Server{
+		URL:     baseURL,
+		Config:  cfg,
+		DB:      database,
+		Mailbox: mailbox,
+		Clock:   fakeClock,
+		api:     server,
+		http:    ts,
+	}
+}
+
+// Do sends a JSON request to path, authenticated with accessToken when it
+// is not empty, and decodes a successful response into out when it is not
+// nil. It returns the status code.
+func (s *Server) Do(t testing.TB, method, path, accessToken string, body, out any) int {
+	t.Helper()
+
+	var reader io.Reader
+	if body != nil {
+		raw, err := json.Marshal(body)
+		if err != nil {
+			t.Fatalf("encoding %s %s body: %v", method, path, err)
+		}
+		reader = bytes.NewReader(raw)
+	}
+	req, err := http.NewRequest(method, s.URL+path, reader)
+	if err != nil {
+		t.Fatalf("building %s %s: %v", method, path, err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if accessToken != "" {
+		req.Header.Set("Authorization", "Bearer "+accessToken)
+	}
+
+	resp, err := s.http.Client().Do(req)
+	if err != nil {
+		t.Fatalf("%s %s: %v", method, path, err)
+	}
+	defer resp.Body.Close()
+	raw, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("reading %s %s response: %v", method, path, err)
+	}
+	if out != nil && resp.StatusCode < http.StatusBadRequest {
+		if err := json.Unmarshal(raw, out); err != nil {
+			t.Fatalf("decoding %s %s response: %v, body=%q", method, path, err, raw)
+		}
+	}
+	return resp.StatusCode
+}
+
+// SignUp registers a new account through the magic code flow, reading the
+// code from the mailbox, and returns its session. The auth rate limits
+// allow about two sign-ups per minute from one test.
+// RequestMagicCode mails a magic code to email, solving the proof-of-work
+// challenge first when the server requires one.
+func (s *Server) RequestMagicCode(t testing.TB, email string) {
+	t.Helper()
+
+	var challenge api.MagicCodeChallengeResponse
+	if status := s.Do(t, http.MethodGet, "/api/v1/auth/login/challenge", "", nil, &challenge); status != http.StatusOK {
+		t.Fatalf("fetching magic code challenge: status %d", status)
+	}
+	req := api.MagicCodeRequest{Email: email}
+	if challenge.Required {
+		req.Challenge = challenge.Challenge
+		req.Nonce = auth.SolveChallenge(challenge.Challenge, email, challenge.Bits)
+	}
+	if status := s.Do(t, http.MethodPost, "/api/v1/auth/login/magic-code", "", req, nil); status != http.StatusOK {
+		t.Fatalf("requesting magic code for %s: status %d", email, status)
+	}
+}
+
+func (s *Server) SignUp(t testing.TB, email, username string) *api.AuthResponse {
+	t.Helper()
+
+	s.RequestMagicCode(t, email)
+	var verified api.VerifyMagicCodeResponse
+	if status := s.Do(t, http.MethodPost, "/api/v1/auth/login/magic-code/verify", "", api.VerifyMagicCodeRequest{
+		Email: email,
+		Code:  s.Mailbox.LastCode(t, email),
+	}, &verified); status != http.StatusOK {
+		t.Fatalf("verifying magic code for %s: status %d", email, status)
+	}
+	if verified.RegistrationToken == "" {
+		t.Fatalf("verifying magic code for %s: next = %q, want register", email, verified.Next)
+	}
+
+	var session api.AuthResponse
+	if status := s.Do(t, http.MethodPost, "/api/v1/auth/register", "", api.RegisterRequest{
+		RegistrationToken: verified.RegistrationToken,
+		Username:          username,
+	}, &session); status != http.StatusOK {
+		t.Fatalf("registering %s: status %d", username, status)
+	}
+	return &session
+}
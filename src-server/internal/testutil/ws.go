@@ -0,0 +1,117 @@
+package testutil
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"lobby/internal/ws"
+)
+
+// wsTimeout bounds how long a WSClient waits for an expected frame.
+const wsTimeout = 5 * time.Second
+
+// Frame is a websocket message as received, with its payload left raw.
+type Frame struct {
+	Op   ws.OpCode       `json:"op"`
+	Type string          `json:"t,omitempty"`
+	Data json.RawMessage `json:"d,omitempty"`
+	Seq  uint64          `json:"s,omitempty"`
+}
+
+// Decode unmarshals the frame's payload into target, failing the test on
+// error.
+func (f *Frame) Decode(t testing.TB, target any) {
+	t.Helper()
+	if err := json.Unmarshal(f.Data, target); err != nil {
+		t.Fatalf("decoding %s payload: %v, data=%s", f.Type, err, f.Data)
+	}
+}
+
+// WSClient is a websocket connection to a test Server.
+type WSClient struct {
+	conn  *websocket.Conn
+	Ready ws.ReadyPayload
+	// Seq is the highest event seq received, for RESUME.
+	Seq uint64
+}
+
+// Dial opens /ws and waits for HELLO without identifying. The connection is
+// closed when t finishes.
+func (s *Server) Dial(t testing.TB) *WSClient {
+	t.Helper()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(s.URL, "http")+"/ws", nil)
+	if err != nil {
+		t.Fatalf("dialing websocket: %v", err)
+	}
+	c := &WSClient{conn: conn}
+	t.Cleanup(func() {
+		_ = conn.Close()
+	})
+
+	if hello := c.read(t); hello.Op != ws.OpHello {
+		t.Fatalf("first frame op = %d, want HELLO", hello.Op)
+	}
+	return c
+}
+
+// Connect dials /ws, identifies with accessToken, and waits for READY.
+func (s *Server) Connect(t testing.TB, accessToken string) *WSClient {
+	t.Helper()
+
+	c := s.Dial(t)
+	c.Send(t, ws.CmdIdentify, ws.IdentifyPayload{Token: accessToken})
+	for {
+		frame := c.read(t)
+		if frame.Op == ws.OpDispatch && frame.Type == ws.EventError {
+			t.Fatalf("IDENTIFY rejected: %s", frame.Data)
+		}
+		if frame.Op == ws.OpReady {
+			frame.Decode(t, &c.Ready)
+			return c
+		}
+	}
+}
+
+// Send writes a DISPATCH command.
+func (c *WSClient) Send(t testing.TB, command string, data any) {
+	t.Helper()
+	if err := c.conn.WriteJSON(ws.WSMessage{Op: ws.OpDispatch, Type: command, Data: data}); err != nil {
+		t.Fatalf("sending %s: %v", command, err)
+	}
+}
+
+// Expect skips frames until a DISPATCH of eventType arrives and returns it.
+func (c *WSClient) Expect(t testing.TB, eventType string) *Frame {
+	t.Helper()
+	for {
+		frame := c.read(t)
+		if frame.Op == ws.OpDispatch && frame.Type == eventType {
+			return frame
+		}
+	}
+}
+
+// Close closes the connection.
+func (c *WSClient) Close() {
+	_ = c.conn.Close()
+}
+
+func (c *WSClient) read(t testing.TB) *Frame {
+	t.Helper()
+	if err := c.conn.SetReadDeadline(time.Now().Add(wsTimeout)); err != nil {
+		t.Fatalf("setting websocket read deadline: %v", err)
+	}
+	var frame Frame
+	if err := c.conn.ReadJSON(&frame); err != nil {
+		t.Fatalf("reading websocket frame: %v", err)
+	}
+	if frame.Seq > c.Seq {
+		c.Seq = frame.Seq
+	}
+	return &frame
+}
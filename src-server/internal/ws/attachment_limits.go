@@ -0,0 +1,67 @@
+package ws
+
+import (
+	"github.com/frisksitron/lobby/src-server/pkg/lobbyclient"
+
+	sqldb "lobby/internal/db/sqlc"
+	"lobby/internal/models"
+)
+
+const (
+	AttachmentTooMany     = lobbyclient.AttachmentTooMany
+	AttachmentTooLarge    = lobbyclient.AttachmentTooLarge
+	AttachmentUnavailable = lobbyclient.AttachmentUnavailable
+)
+
+// SetUploadLimits sets the attachment count and size caps MESSAGE_SEND
+// enforces. Must be called before Run.
+func (h *Hub) SetUploadLimits(limits models.UploadLimits) {
+	h.uploadLimits = limits
+}
+
+// tooManyAttachments returns a violation for each attachment past the
+// per-message cap.
+func (h *Hub) tooManyAttachments(attachmentIDs []string) []AttachmentViolation {
+	limit := h.uploadLimits.MaxAttachments
+	if limit <= 0 || len(attachmentIDs) <= limit {
+		return nil
+	}
+	violations := make([]AttachmentViolation, 0, len(attachmentIDs)-limit)
+	for _, id := range attachmentIDs[limit:] {
+		violations = append(violations, AttachmentViolation{
+			ID:     id,
+			Reason: AttachmentTooMany,
+			Limit:  int64(limit),
+		})
+	}
+	return violations
+}
+
+// claimedAttachmentViolations compares the attachments a message asked for
+// with the ones it claimed. Those it could not claim are unavailable; those
+// over the sender's cap, which may have been lowered since the upload, are
+// too large.
+func (h *Hub) claimedAttachmentViolations(user *models.User, attachmentIDs []string, claimed []sqldb.ListMessageAttachmentsRow) []AttachmentViolation {
+	sizes := make(map[string]int64, len(claimed))
+	for _, attachment := range claimed {
+		sizes[attachment.ID] = attachment.SizeBytes
+	}
+	limit := h.uploadLimits.ForRole(user.Role)
+
+	var violations []AttachmentViolation
+	for _, id := range attachmentIDs {
+		size, ok := sizes[id]
+		switch {
+		case !ok:
+			violations = append(violations, AttachmentViolation{ID: id, Reason: AttachmentUnavailable})
+		case limit > 0 && size > limit:
+			violations = append(violations, AttachmentViolation{
+				ID:     id,
+				Reason: AttachmentTooLarge,
+				Limit:  limit,
+				Size:   size,
+			})
+		}
+	}
+	return violations
+}
@@ -0,0 +1,53 @@
+package ws
+
+import (
+	"reflect"
+	"testing"
+
+	sqldb "lobby/internal/db/sqlc"
+	"lobby/internal/models"
+)
+
+func TestHandleMessageSendRejectsTooManyAttachments(t *testing.T) {
+	h := &Hub{
+		clients:      make(map[*Client]bool),
+		uploadLimits: models.UploadLimits{Default: 1024, MaxAttachments: 2},
+	}
+	client := newIdentifiedTestClient(h, "usr_1")
+	h.clients[client] = true
+
+	client.handleMessageSend(&WSMessage{
+		Op:   OpDispatch,
+		Type: CmdMessageSend,
+		Data: map[string]interface{}{"attachment_ids": []string{"blb_1", "blb_2", "blb_2", "blb_3"}, "nonce": "n1"},
+	})
+
+	payload, ok := nextSent(client).Data.(ErrorPayload)
+	want := []AttachmentViolation{{ID: "blb_3", Reason: AttachmentTooMany, Limit: 2}}
+	if !ok || payload.Code != ErrCodeAttachmentInvalid || payload.Nonce != "n1" || !reflect.DeepEqual(payload.Attachments, want) {
+		t.Fatalf("error payload = %+v, want attachments %+v", payload, want)
+	}
+}
+
+func TestClaimedAttachmentViolations(t *testing.T) {
+	h := &Hub{uploadLimits: models.UploadLimits{Default: 100, ByRole: map[string]int64{models.RoleModerator: 1000}}}
+	claimed := []sqldb.ListMessageAttachmentsRow{
+		{ID: "blb_small", SizeBytes: 50},
+		{ID: "blb_large", SizeBytes: 500},
+	}
+	ids := []string{"blb_small", "blb_large", "blb_gone"}
+
+	got := h.claimedAttachmentViolations(&models.User{ID: "usr_1", Role: models.RoleMember}, ids, claimed)
+	want := []AttachmentViolation{
+		{ID: "blb_large", Reason: AttachmentTooLarge, Limit: 100, Size: 500},
+		{ID: "blb_gone", Reason: AttachmentUnavailable},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("member violations = %+v, want %+v", got, want)
+	}
+
+	got = h.claimedAttachmentViolations(&models.User{ID: "usr_mod", Role: models.RoleModerator}, ids[:2], claimed)
+	if len(got) != 0 {
+		t.Fatalf("moderator violations = %+v, want none", got)
+	}
+}
@@ -0,0 +1,78 @@
+package ws
+
+import (
+	"context"
+	"log/slog"
+
+	"lobby/internal/cluster"
+	"lobby/internal/models"
+)
+
+// ReloadAutomodRules refreshes the cached automod rules and tells other
+// instances to do the same.
+func (h *Hub) ReloadAutomodRules(ctx context.Context) error {
+	if err := h.reloadAutomodRules(ctx); err != nil {
+		return err
+	}
+	if h.backplane != nil {
+		h.enqueueClusterTask(clusterTask{env: &cluster.Envelope{
+			Instance: h.instanceID,
+			Type:     cluster.TypeAutomodRules,
+		}})
+	}
+	return nil
+}
+
+func (h *Hub) reloadAutomodRules(ctx context.Context) error {
+	rows, err := h.queries.ListAutomodRules(ctx)
+	if err != nil {
+		return err
+	}
+
+	rules := make([]models.AutomodRule, 0, len(rows))
+	for _, row := range rows {
+		rule, err := models.CompileAutomodRule(row.ID, row.Kind, row.Pattern, row.Action)
+		if err != nil {
+			slog.Warn("skipping invalid automod rule", "component", "hub", "rule_id", row.ID, "error", err)
+			continue
+		}
+		rules = append(rules, rule)
+	}
+
+	h.mu.Lock()
+	h.automodRules = rules
+	h.mu.Unlock()
+	return nil
+}
+
+// CheckAutomod returns the strictest automod rule content trips and the text
+// that matched. Moderators and admins are exempt.
+func (h *Hub) CheckAutomod(user *models.User, content string) (models.AutomodRule, string, bool) {
+	if content == "" || user == nil || models.RoleAtLeast(user.Role, models.RoleModerator) {
+		return models.AutomodRule{}, "", false
+	}
+
+	h.mu.RLock()
+	rules := h.automodRules
+	h.mu.RUnlock()
+	return models.EvaluateAutomod(rules, content)
+}
+
+// publishAutomodAlert tells moderators that a message tripped rule. messageID
+// is empty when the message was not delivered.
+func (h *Hub) publishAutomodAlert(rule models.AutomodRule, matched, messageID string, author *MessageAuthor, content string) {
+	slog.Info("automod rule triggered", "component", "ws", "rule_id", rule.ID, "action", rule.Action, "user_id", author.ID)
+	h.Publish(Event{
+		Topic: TopicModeration,
+		Type:  EventAutomodAlert,
+		Data: AutomodAlertPayload{
+			RuleID:    rule.ID,
+			Action:    rule.Action,
+			Matched:   matched,
+			MessageID: messageID,
+			Author:    author,
+			Content:   content,
+		},
+		Audience: AudienceModerators,
+	})
+}
@@ -0,0 +1,106 @@
+package ws
+
+import (
+	"testing"
+
+	"lobby/internal/models"
+)
+
+func mustCompileAutomodRule(t *testing.T, id, kind, pattern, action string) models.AutomodRule {
+	t.Helper()
+
+	rule, err := models.CompileAutomodRule(id, kind, pattern, action)
+	if err != nil {
+		t.Fatalf("CompileAutomodRule(%s) error = %v", id, err)
+	}
+	return rule
+}
+
+func TestEvaluateAutomodPicksStrictestRule(t *testing.T) {
+	rules := []models.AutomodRule{
+		mustCompileAutomodRule(t, "amr_flag", models.AutomodKindWord, "free nitro", models.AutomodActionFlag),
+		mustCompileAutomodRule(t, "amr_invite", models.AutomodKindInvite, "", models.AutomodActionDeleteWarn),
+		mustCompileAutomodRule(t, "amr_regex", models.AutomodKindRegex, `(?i)\bcrypto\s*giveaway\b`, models.AutomodActionBlock),
+	}
+
+	tests := []struct {
+		content string
+		wantID  string
+	}{
+		{content: "hello there", wantID: ""},
+		{content: "Free Nitro for everyone", wantID: "amr_flag"},
+		{content: "free nitrous oxide", wantID: ""},
+		{content: "free nitro at discord.gg/abc123", wantID: "amr_invite"},
+		{content: "CRYPTO giveaway at discord.gg/abc123", wantID: "amr_regex"},
+	}
+	for _, tt := range tests {
+		rule, _, ok := models.EvaluateAutomod(rules, tt.content)
+		if rule.ID != tt.wantID || ok != (tt.wantID != "") {
+			t.Fatalf("EvaluateAutomod(%q) = %q (ok=%v), want %q", tt.content, rule.ID, ok, tt.wantID)
+		}
+	}
+
+	for _, tc := range [][3]string{
+		{models.AutomodKindWord, "  ", models.AutomodActionBlock},
+		{models.AutomodKindRegex, "(", models.AutomodActionBlock},
+		{models.AutomodKindWord, "spam", "ban"},
+		{"emoji", "x", models.AutomodActionFlag},
+	} {
+		if _, err := models.CompileAutomodRule("amr_bad", tc[0], tc[1], tc[2]); err == nil {
+			t.Fatalf("CompileAutomodRule(%v) expected error", tc)
+		}
+	}
+}
+
+func TestHandleMessageSendBlockedByAutomodAlertsModerators(t *testing.T) {
+	h := &Hub{
+		clients:      make(map[*Client]bool),
+		automodRules: []models.AutomodRule{mustCompileAutomodRule(t, "amr_1", models.AutomodKindWord, "spam", models.AutomodActionBlock)},
+	}
+	sender := newIdentifiedTestClient(h, "usr_1")
+	sender.user.Role = models.RoleMember
+	moderator := newIdentifiedTestClient(h, "usr_mod")
+	moderator.user.Role = models.RoleModerator
+	h.clients[sender] = true
+	h.clients[moderator] = true
+
+	sender.handleMessageSend(&WSMessage{
+		Op:   OpDispatch,
+		Type: CmdMessageSend,
+		Data: map[string]interface{}{"content": "buy SPAM now", "nonce": "n1"},
+	})
+
+	var gotError bool
+	for msg := nextSent(sender); msg != nil; msg = nextSent(sender) {
+		if payload, ok := msg.Data.(ErrorPayload); ok {
+			gotError = payload.Code == ErrCodeAutomodBlocked && payload.Nonce == "n1"
+		}
+		if msg.Type == EventAutomodAlert {
+			t.Fatal("sender must not receive the automod alert")
+		}
+	}
+	if !gotError {
+		t.Fatal("expected AUTOMOD_BLOCKED error for the sender")
+	}
+
+	var alert *AutomodAlertPayload
+	for msg := nextSent(moderator); msg != nil; msg = nextSent(moderator) {
+		if payload, ok := msg.Data.(AutomodAlertPayload); ok && msg.Type == EventAutomodAlert {
+			alert = &payload
+		}
+	}
+	if alert == nil || alert.RuleID != "amr_1" || alert.Action != models.AutomodActionBlock || alert.MessageID != "" || alert.Author.ID != "usr_1" {
+		t.Fatalf("unexpected alert: %+v", alert)
+	}
+}
+
+func TestCheckAutomodExemptsModerators(t *testing.T) {
+	h := &Hub{automodRules: []models.AutomodRule{mustCompileAutomodRule(t, "amr_1", models.AutomodKindWord, "spam", models.AutomodActionBlock)}}
+
+	if _, _, ok := h.CheckAutomod(&models.User{ID: "usr_1", Role: models.RoleMember}, "spam"); !ok {
+		t.Fatal("expected member message to match")
+	}
+	if _, _, ok := h.CheckAutomod(&models.User{ID: "usr_mod", Role: models.RoleModerator}, "spam"); ok {
+		t.Fatal("expected moderators to be exempt")
+	}
+}
@@ -0,0 +1,85 @@
+package ws
+
+import "time"
+
+// Server-side auto-idle. Commands, REQUESTs, and client HEARTBEATs count as
+// activity; HEARTBEAT_ACK does not, since clients send it unprompted by the
+// user. On the janitor tick an online client quiet for idleAfter is shown
+// idle, and an online or idle one quiet for offlineAfter is shown offline.
+// dnd and a user-chosen offline are left alone. The next activity restores
+// the status the user had, unless that activity is a PRESENCE_SET.
+
+// SetAutoPresence sets how long identified clients may be inactive before
+// they are shown idle and offline. Zero disables either step. Must be called
+// before Run.
+func (h *Hub) SetAutoPresence(idleAfter, offlineAfter time.Duration) {
+	h.idleAfter = idleAfter
+	h.offlineAfter = offlineAfter
+}
+
+// markActive records activity and, when the hub had marked the client away,
+// restores its status. explicit is set for PRESENCE_SET, which picks the
+// status itself.
+func (c *Client) markActive(explicit bool) {
+	c.lastActivity.Store(c.hub.now().UnixNano())
+
+	c.mu.Lock()
+	restore := c.autoAwayFrom
+	c.autoAwayFrom = ""
+	if restore != "" && !explicit {
+		c.status = restore
+	}
+	c.mu.Unlock()
+
+	if restore != "" && !explicit && c.IsIdentified() {
+		c.hub.BroadcastDispatch(EventPresenceUpdate, c.presenceUpdate())
+	}
+}
+
+// autoAwayStatusLocked returns the status inactivity moves the client to, or "" to
+// leave it. Caller must hold c.mu.
+func (h *Hub) autoAwayStatusLocked(c *Client, inactive time.Duration) string {
+	switch current := c.status; {
+	case current != "online" && current != "idle":
+		return ""
+	case h.offlineAfter > 0 && inactive >= h.offlineAfter:
+		return "offline"
+	case h.idleAfter > 0 && inactive >= h.idleAfter && current == "online":
+		return "idle"
+	}
+	return ""
+}
+
+// applyAutoPresence moves inactive clients to idle or offline and broadcasts
+// the change. It runs on the janitor tick.
+func (h *Hub) applyAutoPresence() {
+	if h.idleAfter <= 0 && h.offlineAfter <= 0 {
+		return
+	}
+	now := h.now()
+
+	h.mu.RLock()
+	var changed []*Client
+	for client := range h.clients {
+		last := client.lastActivity.Load()
+		if last == 0 || !client.IsIdentified() {
+			continue
+		}
+		inactive := now.Sub(time.Unix(0, last))
+
+		client.mu.Lock()
+		if next := h.autoAwayStatusLocked(client, inactive); next != "" && next != client.status {
+			if client.autoAwayFrom == "" {
+				client.autoAwayFrom = client.status
+			}
+			client.status = next
+			changed = append(changed, client)
+		}
+		client.mu.Unlock()
+	}
+	h.mu.RUnlock()
+
+	for _, client := range changed {
+		h.broadcastPresenceUpdate(client.presenceUpdate(), nil)
+	}
+}
@@ -0,0 +1,104 @@
+package ws
+
+import (
+	"testing"
+	"time"
+
+	"lobby/internal/clock"
+)
+
+func TestAutoPresenceIdlesThenRestores(t *testing.T) {
+	fake := clock.NewFake(time.Now())
+	h := &Hub{clients: make(map[*Client]bool), broadcast: make(chan *WSMessage, 4), clock: fake}
+	h.SetAutoPresence(5*time.Minute, 30*time.Minute)
+	c := newIdentifiedTestClient(h, "usr_1")
+	c.SetStatus("online")
+	h.clients[c] = true
+	c.markActive(false)
+
+	fake.Advance(4 * time.Minute)
+	h.applyAutoPresence()
+	if got := c.GetStatus(); got != "online" {
+		t.Fatalf("status after 4m = %q, want online", got)
+	}
+
+	fake.Advance(time.Minute)
+	h.applyAutoPresence()
+	if got := c.GetStatus(); got != "idle" {
+		t.Fatalf("status after 5m = %q, want idle", got)
+	}
+	msg := nextSent(c)
+	payload, ok := msg.Data.(PresenceUpdatePayload)
+	if msg.Type != EventPresenceUpdate || !ok || payload.Status != "idle" {
+		t.Fatalf("expected idle PRESENCE_UPDATE, got type=%s data=%+v", msg.Type, msg.Data)
+	}
+
+	fake.Advance(25 * time.Minute)
+	h.applyAutoPresence()
+	if got := c.GetStatus(); got != "offline" {
+		t.Fatalf("status after 30m = %q, want offline", got)
+	}
+	nextSent(c)
+
+	c.markActive(false)
+	if got := c.GetStatus(); got != "online" {
+		t.Fatalf("status after activity = %q, want online", got)
+	}
+	msg = <-h.broadcast
+	if payload, ok := msg.Data.(PresenceUpdatePayload); !ok || payload.Status != "online" {
+		t.Fatalf("expected online PRESENCE_UPDATE, got %+v", msg.Data)
+	}
+}
+
+func TestAutoPresenceLeavesChosenStatus(t *testing.T) {
+	fake := clock.NewFake(time.Now())
+	h := &Hub{clients: make(map[*Client]bool), clock: fake}
+	h.SetAutoPresence(time.Minute, time.Hour)
+
+	dnd := newIdentifiedTestClient(h, "usr_1")
+	dnd.SetStatus("dnd")
+	invisible := newIdentifiedTestClient(h, "usr_2")
+	invisible.SetStatus("offline")
+	for _, c := range []*Client{dnd, invisible} {
+		h.clients[c] = true
+		c.markActive(false)
+	}
+
+	fake.Advance(2 * time.Hour)
+	h.applyAutoPresence()
+	if dnd.GetStatus() != "dnd" || invisible.GetStatus() != "offline" {
+		t.Fatalf("statuses = %q, %q; want dnd, offline", dnd.GetStatus(), invisible.GetStatus())
+	}
+	if dnd.queuedLen() != 0 || invisible.queuedLen() != 0 {
+		t.Fatal("broadcast a presence change for a chosen status")
+	}
+}
+
+func TestAutoPresenceExplicitSetWins(t *testing.T) {
+	fake := clock.NewFake(time.Now())
+	h := &Hub{clients: make(map[*Client]bool), broadcast: make(chan *WSMessage, 4), clock: fake}
+	h.SetAutoPresence(time.Minute, 0)
+	c := newIdentifiedTestClient(h, "usr_1")
+	c.SetStatus("online")
+	h.clients[c] = true
+	c.markActive(false)
+
+	fake.Advance(time.Minute)
+	h.applyAutoPresence()
+	nextSent(c)
+
+	c.handleMessage(&WSMessage{
+		Op:   OpDispatch,
+		Type: CmdPresenceSet,
+		Data: map[string]interface{}{"status": "dnd"},
+	})
+	if got := c.GetStatus(); got != "dnd" {
+		t.Fatalf("status after PRESENCE_SET = %q, want dnd", got)
+	}
+
+	fake.Advance(time.Hour)
+	c.markActive(false)
+	if got := c.GetStatus(); got != "dnd" {
+		t.Fatalf("activity after PRESENCE_SET restored %q", got)
+	}
+}
@@ -0,0 +1,62 @@
+package ws
+
+import (
+	"slices"
+
+	"github.com/frisksitron/lobby/src-server/pkg/lobbyclient"
+)
+
+const CapabilityBatch = lobbyclient.CapabilityBatch
+
+// maxBatchSize bounds how many messages one BATCH frame carries.
+const maxBatchSize = 64
+
+// enabledCapabilities returns the known capabilities among requested, for
+// READY.
+func enabledCapabilities(requested []string) []string {
+	if slices.Contains(requested, CapabilityBatch) {
+		return []string{CapabilityBatch}
+	}
+	return nil
+}
+
+// writeOutbound writes message from WritePump. With batching on, messages
+// already queued behind it are written in the same BATCH frame; nothing waits
+// for more to arrive, so batching only kicks in during bursts. It returns
+// false when the connection is broken or the hub closed c.send.
+func (c *Client) writeOutbound(message *WSMessage) bool {
+	if !c.batching.Load() {
+		return c.writeMessage(message)
+	}
+
+	batch := []*WSMessage{message}
+	closed := false
+	for len(batch) < maxBatchSize {
+		var next *WSMessage
+		select {
+		case msg, ok := <-c.send:
+			if !ok {
+				closed = true
+			}
+			next = msg
+		default:
+			next, _ = c.nextEvent()
+		}
+		if next == nil {
+			break
+		}
+		batch = append(batch, next)
+	}
+
+	var written bool
+	if len(batch) == 1 {
+		written = c.writeMessage(message)
+	} else {
+		written = c.writeMessage(&WSMessage{Op: OpBatch, Data: batch})
+	}
+	if closed {
+		c.writeClose()
+		return false
+	}
+	return written
+}
@@ -0,0 +1,108 @@
+package ws
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/websocket"
+)
+
+// newBatchTestClient returns a client whose conn writes to the returned
+// websocket.
+func newBatchTestClient(t *testing.T) (*Client, *websocket.Conn) {
+	t.Helper()
+	conns := make(chan *websocket.Conn, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := (&websocket.Upgrader{}).Upgrade(w, r, nil)
+		if err != nil {
+			t.Errorf("upgrade: %v", err)
+			return
+		}
+		conns <- conn
+	}))
+	t.Cleanup(srv.Close)
+
+	peer, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	t.Cleanup(func() { peer.Close() })
+	conn := <-conns
+	t.Cleanup(func() { conn.Close() })
+
+	c := newIdentifiedTestClient(&Hub{}, "usr_1")
+	c.conn = conn
+	return c, peer
+}
+
+type batchTestFrame struct {
+	Op   OpCode          `json:"op"`
+	Type string          `json:"t"`
+	Data json.RawMessage `json:"d"`
+	Seq  uint64          `json:"s"`
+}
+
+func readBatchTestFrame(t *testing.T, peer *websocket.Conn) batchTestFrame {
+	t.Helper()
+	var frame batchTestFrame
+	if err := peer.ReadJSON(&frame); err != nil {
+		t.Fatalf("reading frame: %v", err)
+	}
+	return frame
+}
+
+func TestWriteOutboundBatchesQueuedMessages(t *testing.T) {
+	c, peer := newBatchTestClient(t)
+	c.batching.Store(true)
+	c.resume = newResumeSession("sess_1", "usr_1")
+
+	c.send <- &WSMessage{Op: OpDispatch, Type: EventCommandAck}
+	c.enqueueEvent(&WSMessage{Op: OpDispatch, Type: EventTypingStart}, c.resume)
+	c.enqueueEvent(&WSMessage{Op: OpDispatch, Type: EventPresenceUpdate}, c.resume)
+	if !c.writeOutbound(&WSMessage{Op: OpDispatch, Type: EventError}) {
+		t.Fatal("writeOutbound reported a broken connection")
+	}
+
+	frame := readBatchTestFrame(t, peer)
+	var batch []batchTestFrame
+	if frame.Op != OpBatch || json.Unmarshal(frame.Data, &batch) != nil {
+		t.Fatalf("got op %d, want a BATCH frame", frame.Op)
+	}
+	want := []struct {
+		typ string
+		seq uint64
+	}{{EventError, 0}, {EventCommandAck, 0}, {EventPresenceUpdate, 1}, {EventTypingStart, 2}}
+	if len(batch) != len(want) {
+		t.Fatalf("batch has %d messages, want %d", len(batch), len(want))
+	}
+	for i, w := range want {
+		if batch[i].Type != w.typ || batch[i].Seq != w.seq {
+			t.Fatalf("batch[%d] = %s seq %d, want %s seq %d", i, batch[i].Type, batch[i].Seq, w.typ, w.seq)
+		}
+	}
+	if n := c.queuedLen(); n != 0 {
+		t.Fatalf("%d messages still queued after the batch", n)
+	}
+
+	// A message with nothing queued behind it goes out on its own.
+	c.writeOutbound(&WSMessage{Op: OpDispatch, Type: EventCommandAck})
+	if frame := readBatchTestFrame(t, peer); frame.Op != OpDispatch || frame.Type != EventCommandAck {
+		t.Fatalf("lone message sent as op %d %s", frame.Op, frame.Type)
+	}
+}
+
+func TestWriteOutboundWithoutBatching(t *testing.T) {
+	c, peer := newBatchTestClient(t)
+	c.send <- &WSMessage{Op: OpDispatch, Type: EventCommandAck}
+
+	c.writeOutbound(&WSMessage{Op: OpDispatch, Type: EventError})
+	if frame := readBatchTestFrame(t, peer); frame.Op != OpDispatch || frame.Type != EventError {
+		t.Fatalf("got op %d %s, want the ERROR on its own", frame.Op, frame.Type)
+	}
+	if len(c.send) != 1 {
+		t.Fatal("writeOutbound took a queued message without batching")
+	}
+}
@@ -0,0 +1,273 @@
+package ws
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"time"
+
+	"github.com/frisksitron/lobby/src-server/pkg/lobbyclient"
+
+	"lobby/internal/constants"
+	"lobby/internal/history"
+)
+
+// BotSubprotocol is the Sec-WebSocket-Protocol value that enables REQUEST
+// frames, letting bots fetch history and member deltas without the REST API.
+const BotSubprotocol = lobbyclient.BotSubprotocol
+
+const (
+	botHistoryDefaultLimit = 50
+
+	// Request budget per bot connection
+	botRequestLimit  = 30
+	botRequestWindow = 10 * time.Second
+
+	// memberLogSize bounds the member change log; MEMBERS_GET calls with an
+	// older cursor fall back to a full snapshot.
+	memberLogSize = 1024
+)
+
+type memberChange struct {
+	seq    uint64
+	userID string
+}
+
+// EnableBotMode marks the connection as having negotiated BotSubprotocol.
+// Must be called before ReadPump.
+func (c *Client) EnableBotMode() {
+	c.bot = true
+}
+
+func (c *Client) handleRequest(msg *WSMessage) {
+	if !c.IsIdentified() {
+		return
+	}
+
+	var envelope struct {
+		RequestID string `json:"request_id"`
+	}
+	if !c.decodeDispatchData(msg, &envelope) || envelope.RequestID == "" {
+		c.sendResponseError(msg.Type, "", ErrCodeInvalidRequest, "Missing request_id", 0)
+		return
+	}
+	requestID := envelope.RequestID
+
+	if !c.bot {
+		c.sendResponseError(msg.Type, requestID, ErrCodeForbidden, "Requests require the "+BotSubprotocol+" subprotocol", 0)
+		return
+	}
+	if !c.allowBotCommand(msg.Type, requestID) {
+		return
+	}
+	if ok, retryAfter := c.allowCommandRateLimit(&c.botRequests, botRequestLimit, botRequestWindow); !ok {
+		c.sendResponseError(msg.Type, requestID, ErrCodeRateLimited, "Too many requests", retryAfter)
+		return
+	}
+
+	switch msg.Type {
+	case ReqHistoryGet:
+		c.handleHistoryGet(msg, requestID)
+	case ReqMembersGet:
+		c.handleMembersGet(msg, requestID)
+	default:
+		c.sendResponseError(msg.Type, requestID, ErrCodeInvalidRequest, "Unknown request type", 0)
+	}
+}
+
+func (c *Client) handleHistoryGet(msg *WSMessage, requestID string) {
+	var data HistoryGetPayload
+	if !c.decodeDispatchData(msg, &data) {
+		c.sendResponseError(msg.Type, requestID, ErrCodeInvalidRequest, "Invalid request payload", 0)
+		return
+	}
+
+	limit := data.Limit
+	if limit == 0 {
+		limit = botHistoryDefaultLimit
+	}
+	if limit < 0 || limit > constants.MessageHistoryMaxLimit {
+		c.sendResponseError(msg.Type, requestID, ErrCodeInvalidRequest, "Invalid limit", 0)
+		return
+	}
+
+	if !c.hub.CanAccessChannel(c.user) {
+		c.sendResponseError(msg.Type, requestID, ErrCodeForbidden, "You do not have access to this channel", 0)
+		return
+	}
+
+	messages, err := c.hub.loadHistory(context.Background(), data.Before, int64(limit))
+	if err != nil {
+		slog.Error("error loading history for request", "component", "ws", "user_id", c.getUserID(), "error", err)
+		c.sendResponseError(msg.Type, requestID, ErrCodeInternal, "Failed to load history", 0)
+		return
+	}
+
+	for i := range messages {
+		messages[i].Content = c.hub.maskContentFor(c.user, messages[i].Content)
+		messages[i].Embeds = c.hub.messageEmbeds(messages[i].Content)
+	}
+	c.sendResponse(msg.Type, requestID, HistoryResult{Messages: messages})
+}
+
+func (c *Client) handleMembersGet(msg *WSMessage, requestID string) {
+	var data MembersGetPayload
+	if !c.decodeDispatchData(msg, &data) {
+		c.sendResponseError(msg.Type, requestID, ErrCodeInvalidRequest, "Invalid request payload", 0)
+		return
+	}
+
+	c.sendResponse(msg.Type, requestID, c.hub.memberDelta(data.Since))
+}
+
+func (c *Client) sendResponse(requestType, requestID string, result interface{}) {
+	c.trySend(&WSMessage{
+		Op:   OpResponse,
+		Type: requestType,
+		Data: ResponsePayload{RequestID: requestID, Result: result},
+	})
+}
+
+func (c *Client) sendResponseError(requestType, requestID, code, message string, retryAfter int64) {
+	c.trySend(&WSMessage{
+		Op:   OpResponse,
+		Type: requestType,
+		Data: ResponsePayload{
+			RequestID: requestID,
+			Error:     &ErrorPayload{Code: code, Message: message, RetryAfter: retryAfter},
+		},
+	})
+}
+
+// loadHistory returns up to limit messages, newest first, optionally before
+// the message with ID beforeID.
+func (h *Hub) loadHistory(ctx context.Context, beforeID string, limit int64) ([]MessageCreatePayload, error) {
+	rows, err := history.Load(ctx, h.queries, h.baseURL, h.mediaURLs, beforeID, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	messages := make([]MessageCreatePayload, 0, len(rows))
+	for _, row := range rows {
+		author := &MessageAuthor{ID: row.AuthorID, Username: row.AuthorName, Bot: row.AuthorBot}
+		if row.AuthorAvatarURL != nil {
+			author.Avatar = *row.AuthorAvatarURL
+		}
+		if row.AuthorAvatarAnimatedURL != nil {
+			author.AvatarAnimated = *row.AuthorAvatarAnimatedURL
+		}
+		var attachments []MessageAttachment
+		for _, attachment := range row.Attachments {
+			attachments = append(attachments, MessageAttachment(attachment))
+		}
+		message := MessageCreatePayload{
+			ID:          row.ID,
+			Author:      author,
+			Content:     row.Content,
+			Attachments: attachments,
+			CreatedAt:   row.CreatedAt.Format(time.RFC3339Nano),
+		}
+		if row.EditedAt != nil {
+			message.EditedAt = row.EditedAt.Format(time.RFC3339Nano)
+		}
+		messages = append(messages, message)
+	}
+	return messages, nil
+}
+
+// recordMemberEvent is the event bus subscriber that feeds the member change log.
+func (h *Hub) recordMemberEvent(e Event) {
+	if userID := memberEventUserID(e.Data); userID != "" {
+		h.recordMemberChange(userID)
+	}
+}
+
+// memberEventUserID returns the user a member-state event is about, or "" for
+// events that do not change MemberState (e.g. VOICE_SPEAKING). Remote events
+// carry their payload as json.RawMessage.
+func memberEventUserID(data interface{}) string {
+	switch payload := data.(type) {
+	case PresenceUpdatePayload:
+		return payload.UserID
+	case VoiceStateUpdatePayload:
+		return payload.UserID
+	case ScreenShareUpdatePayload:
+		return payload.UserID
+	case VideoStatePayload:
+		return payload.UserID
+	case UserJoinedPayload:
+		return payload.Member.ID
+	case UserLeftPayload:
+		return payload.UserID
+	case UserUpdatePayload:
+		return payload.ID
+	case json.RawMessage:
+		var fields struct {
+			UserID string `json:"user_id"`
+			ID     string `json:"id"`
+			Member struct {
+				ID string `json:"id"`
+			} `json:"member"`
+		}
+		if err := json.Unmarshal(payload, &fields); err != nil {
+			return ""
+		}
+		switch {
+		case fields.Member.ID != "":
+			return fields.Member.ID
+		case fields.UserID != "":
+			return fields.UserID
+		}
+		return fields.ID
+	}
+	return ""
+}
+
+func (h *Hub) recordMemberChange(userID string) {
+	h.memberLogMu.Lock()
+	defer h.memberLogMu.Unlock()
+
+	h.memberSeq++
+	h.memberLog = append(h.memberLog, memberChange{seq: h.memberSeq, userID: userID})
+	if len(h.memberLog) > memberLogSize {
+		h.memberLog = append(h.memberLog[:0], h.memberLog[len(h.memberLog)-memberLogSize:]...)
+	}
+}
+
+// memberDelta returns members changed after cursor since, or a full snapshot
+// when since is 0 or older than the change log.
+func (h *Hub) memberDelta(since uint64) MembersResult {
+	h.memberLogMu.Lock()
+	seq := h.memberSeq
+	full := since == 0 || since > seq || (len(h.memberLog) > 0 && since < h.memberLog[0].seq-1)
+	changed := make(map[string]struct{})
+	if !full {
+		for _, change := range h.memberLog {
+			if change.seq > since {
+				changed[change.userID] = struct{}{}
+			}
+		}
+	}
+	h.memberLogMu.Unlock()
+
+	if !full && len(changed) == 0 {
+		return MembersResult{Seq: seq, Members: []MemberState{}}
+	}
+
+	snapshot := h.GetMemberSnapshot()
+	if full {
+		return MembersResult{Seq: seq, Full: true, Members: snapshot}
+	}
+
+	result := MembersResult{Seq: seq, Members: make([]MemberState, 0, len(changed))}
+	for _, member := range snapshot {
+		if _, ok := changed[member.ID]; ok {
+			result.Members = append(result.Members, member)
+			delete(changed, member.ID)
+		}
+	}
+	for userID := range changed {
+		result.Removed = append(result.Removed, userID)
+	}
+	return result
+}
@@ -0,0 +1,159 @@
+package ws
+
+import (
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"lobby/internal/db"
+	sqldb "lobby/internal/db/sqlc"
+)
+
+func openBotTestHub(t *testing.T) *Hub {
+	t.Helper()
+
+	database, err := db.Open(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("db.Open() error = %v", err)
+	}
+	t.Cleanup(func() {
+		_ = database.Close()
+	})
+
+	now := time.Now().UTC()
+	for _, user := range []sqldb.CreateUserParams{
+		{ID: "usr_1", Username: "alice", Email: "alice@example.com", CreatedAt: now},
+		{ID: "usr_2", Username: "bob", Email: "bob@example.com", CreatedAt: now},
+	} {
+		if err := database.Queries().CreateUser(context.Background(), user); err != nil {
+			t.Fatalf("CreateUser() error = %v", err)
+		}
+	}
+
+	return &Hub{
+		clients:       make(map[*Client]bool),
+		userClients:   make(map[string]*Client),
+		voiceSessions: make(map[string]*VoiceSession),
+		database:      database,
+		queries:       database.Queries(),
+		baseURL:       "http://localhost",
+	}
+}
+
+func receiveResponse(t *testing.T, c *Client) ResponsePayload {
+	t.Helper()
+
+	select {
+	case msg := <-c.send:
+		payload, ok := msg.Data.(ResponsePayload)
+		if msg.Op != OpResponse || !ok {
+			t.Fatalf("expected response, got op=%d data=%T", msg.Op, msg.Data)
+		}
+		return payload
+	default:
+		t.Fatal("expected response to be sent")
+	}
+	return ResponsePayload{}
+}
+
+func TestHandleRequestRequiresBotSubprotocol(t *testing.T) {
+	h := &Hub{}
+	c := newIdentifiedTestClient(h, "usr_1")
+
+	c.handleMessage(&WSMessage{
+		Op:   OpRequest,
+		Type: ReqMembersGet,
+		Data: map[string]interface{}{"request_id": "r1"},
+	})
+
+	resp := receiveResponse(t, c)
+	if resp.RequestID != "r1" || resp.Error == nil || resp.Error.Code != ErrCodeForbidden {
+		t.Fatalf("unexpected response: %+v", resp)
+	}
+}
+
+func TestHandleHistoryGetReturnsMessages(t *testing.T) {
+	h := openBotTestHub(t)
+	createdAt := time.Now().UTC()
+	for _, id := range []string{"msg_a", "msg_b"} {
+		if err := h.queries.CreateMessage(context.Background(), sqldb.CreateMessageParams{
+			ID:        id,
+			AuthorID:  "usr_1",
+			Content:   "hello " + id,
+			CreatedAt: createdAt,
+		}); err != nil {
+			t.Fatalf("CreateMessage() error = %v", err)
+		}
+	}
+
+	c := newIdentifiedTestClient(h, "usr_2")
+	c.EnableBotMode()
+	c.handleMessage(&WSMessage{
+		Op:   OpRequest,
+		Type: ReqHistoryGet,
+		Data: map[string]interface{}{"request_id": "r1", "limit": 1},
+	})
+
+	resp := receiveResponse(t, c)
+	result, ok := resp.Result.(HistoryResult)
+	if resp.Error != nil || !ok {
+		t.Fatalf("unexpected response: %+v", resp)
+	}
+	if len(result.Messages) != 1 || result.Messages[0].ID != "msg_b" || result.Messages[0].Author.Username != "alice" {
+		t.Fatalf("unexpected messages: %+v", result.Messages)
+	}
+
+	c.handleMessage(&WSMessage{
+		Op:   OpRequest,
+		Type: ReqHistoryGet,
+		Data: map[string]interface{}{"request_id": "r2", "limit": 500},
+	})
+	if resp := receiveResponse(t, c); resp.Error == nil || resp.Error.Code != ErrCodeInvalidRequest {
+		t.Fatalf("expected invalid limit error, got %+v", resp)
+	}
+}
+
+func TestMemberDeltaReturnsChangedMembers(t *testing.T) {
+	h := openBotTestHub(t)
+	h.recordMemberChange("usr_1")
+
+	full := h.memberDelta(0)
+	if !full.Full || len(full.Members) != 2 {
+		t.Fatalf("expected full snapshot of 2 members, got %+v", full)
+	}
+
+	h.recordMemberEvent(Event{Data: PresenceUpdatePayload{UserID: "usr_2", Status: "online"}})
+	h.recordMemberEvent(Event{Data: json.RawMessage(`{"user_id":"usr_gone"}`)})
+	h.recordMemberEvent(Event{Data: VoiceSpeakingPayload{UserID: "usr_1", Speaking: true}})
+
+	delta := h.memberDelta(full.Seq)
+	if delta.Full || delta.Seq != full.Seq+2 {
+		t.Fatalf("unexpected delta cursor: %+v", delta)
+	}
+	if len(delta.Members) != 1 || delta.Members[0].ID != "usr_2" {
+		t.Fatalf("unexpected delta members: %+v", delta.Members)
+	}
+	if len(delta.Removed) != 1 || delta.Removed[0] != "usr_gone" {
+		t.Fatalf("unexpected removed members: %+v", delta.Removed)
+	}
+
+	if empty := h.memberDelta(delta.Seq); empty.Full || len(empty.Members) != 0 {
+		t.Fatalf("expected empty delta, got %+v", empty)
+	}
+}
+
+func TestMemberDeltaFallsBackToSnapshotForExpiredCursor(t *testing.T) {
+	h := openBotTestHub(t)
+	for i := 0; i < memberLogSize+2; i++ {
+		h.recordMemberChange("usr_1")
+	}
+
+	if delta := h.memberDelta(1); !delta.Full {
+		t.Fatalf("expected full snapshot for expired cursor, got %+v", delta)
+	}
+	if delta := h.memberDelta(h.memberSeq - 1); delta.Full || len(delta.Members) != 1 {
+		t.Fatalf("expected single-member delta, got %+v", delta)
+	}
+}
@@ -0,0 +1,135 @@
+package ws
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+	"time"
+
+	"lobby/internal/auth"
+	sqldb "lobby/internal/db/sqlc"
+	"lobby/internal/models"
+)
+
+// identity is what an IDENTIFY token resolves to before the user row is
+// loaded.
+type identity struct {
+	userID         string
+	sessionVersion int       // access tokens only
+	loginSessionID string    // access tokens issued for a tracked login session
+	expiresAt      time.Time // zero for bot tokens, which live until revoked
+	scopes         []string  // nil for humans, who are unrestricted
+	bot            bool
+}
+
+// botCommandScopes lists the DISPATCH commands and REQUEST types a bot token
+// may use and the scope each needs. Anything missing, such as voice and
+// screen sharing, is off limits to bots; an empty scope needs no grant.
+var botCommandScopes = map[string]string{
+	CmdIdentify:        "",
+	CmdResume:          "",
+	CmdPresenceSet:     "",
+	CmdRateLimitStatus: "",
+	CmdMessageSend:     models.ScopeMessagesWrite,
+	CmdTyping:          models.ScopeMessagesWrite,
+	CmdTypingStop:      models.ScopeMessagesWrite,
+	ReqHistoryGet:      models.ScopeMessagesRead,
+	ReqMembersGet:      models.ScopeMembersRead,
+}
+
+// resolveAccessToken validates a human session's access JWT.
+func (c *Client) resolveAccessToken(token string) (identity, bool) {
+	claims, err := c.hub.jwtService.ValidateAccessToken(token)
+	if err != nil {
+		slog.Warn("IDENTIFY invalid token", "component", "ws", "error", err)
+		c.sendError(ErrorPayload{Code: ErrCodeAuthFailed, Message: "Invalid token"})
+		c.Close()
+		return identity{}, false
+	}
+
+	if claims.ExpiresAt == nil {
+		slog.Warn("IDENTIFY token missing expiry", "component", "ws", "user_id", claims.UserID)
+		c.sendError(ErrorPayload{Code: ErrCodeAuthFailed, Message: "Token missing expiry"})
+		c.Close()
+		return identity{}, false
+	}
+
+	// Honor the same clock skew leeway as validation, both here and for the
+	// expiry check, so drift cannot end the session early.
+	expiresAt := claims.ExpiresAt.Time.Add(c.hub.jwtService.Leeway())
+	if !expiresAt.After(c.hub.now()) {
+		slog.Warn("IDENTIFY token already expired", "component", "ws", "user_id", claims.UserID)
+		c.sendError(ErrorPayload{Code: ErrCodeAuthExpired, Message: "Access token expired"})
+		c.Close()
+		return identity{}, false
+	}
+
+	return identity{
+		userID:         claims.UserID,
+		sessionVersion: claims.SessionVersion,
+		loginSessionID: claims.SessionID,
+		expiresAt:      expiresAt,
+	}, true
+}
+
+// resolveBotToken looks up a bot API token by its hash and records its use.
+func (c *Client) resolveBotToken(token string) (identity, bool) {
+	row, err := c.hub.queries.GetActiveBotTokenByHash(context.Background(), auth.HashBotToken(token))
+	if err != nil {
+		slog.Warn("IDENTIFY invalid bot token", "component", "ws", "error", err)
+		c.sendError(ErrorPayload{Code: ErrCodeAuthFailed, Message: "Invalid token"})
+		c.Close()
+		return identity{}, false
+	}
+
+	now := c.hub.now().UTC()
+	if err := c.hub.queries.TouchBotToken(context.Background(), sqldb.TouchBotTokenParams{
+		LastUsedAt: &now,
+		ID:         row.ID,
+	}); err != nil {
+		slog.Warn("failed to record bot token use", "component", "ws", "token_id", row.ID, "error", err)
+	}
+
+	scopes := models.ParseBotScopes(row.Scopes)
+	if scopes == nil {
+		scopes = []string{}
+	}
+	return identity{userID: row.BotID, scopes: scopes, bot: true}, true
+}
+
+// HasScope reports whether the connection may use scope. Human sessions hold
+// every scope; bot sessions hold only those granted to their token.
+func (c *Client) HasScope(scope string) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.scopes == nil || slices.Contains(c.scopes, scope)
+}
+
+func (c *Client) setScopes(scopes []string) {
+	c.mu.Lock()
+	c.scopes = scopes
+	c.mu.Unlock()
+}
+
+// allowBotCommand reports whether a bot session may use msgType, answering
+// FORBIDDEN when it may not. Human sessions are always allowed.
+func (c *Client) allowBotCommand(msgType, requestID string) bool {
+	if c.user == nil || !c.user.Bot {
+		return true
+	}
+	scope, listed := botCommandScopes[msgType]
+	if listed && (scope == "" || c.HasScope(scope)) {
+		return true
+	}
+
+	message := "Bots cannot use " + msgType
+	if listed {
+		message = "Bot token lacks the " + scope + " scope"
+	}
+	if requestID != "" {
+		c.sendResponseError(msgType, requestID, ErrCodeForbidden, message, 0)
+	} else {
+		c.sendError(ErrorPayload{Code: ErrCodeForbidden, Message: message})
+	}
+	return false
+}
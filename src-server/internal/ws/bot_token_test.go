@@ -0,0 +1,110 @@
+package ws
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"lobby/internal/auth"
+	sqldb "lobby/internal/db/sqlc"
+	"lobby/internal/models"
+)
+
+func TestResolveBotTokenReturnsScopes(t *testing.T) {
+	h := openBotTestHub(t)
+	now := time.Now().UTC()
+	if err := h.queries.CreateBotUser(context.Background(), sqldb.CreateBotUserParams{
+		ID:        "usr_bot",
+		Username:  "helper",
+		Email:     "usr_bot@bots.invalid",
+		CreatedAt: now,
+	}); err != nil {
+		t.Fatalf("CreateBotUser() error = %v", err)
+	}
+	token, err := auth.GenerateBotToken()
+	if err != nil {
+		t.Fatalf("GenerateBotToken() error = %v", err)
+	}
+	if err := h.queries.CreateBotToken(context.Background(), sqldb.CreateBotTokenParams{
+		ID:        "btk_1",
+		BotID:     "usr_bot",
+		Name:      "ci",
+		TokenHash: auth.HashBotToken(token),
+		Scopes:    models.FormatBotScopes([]string{models.ScopeMessagesRead}),
+		CreatedAt: now,
+	}); err != nil {
+		t.Fatalf("CreateBotToken() error = %v", err)
+	}
+
+	c := NewClient(h, nil)
+	id, ok := c.resolveBotToken(token)
+	if !ok {
+		t.Fatal("resolveBotToken() rejected a valid token")
+	}
+	if id.userID != "usr_bot" || !id.bot || !id.expiresAt.IsZero() || !slices.Equal(id.scopes, []string{models.ScopeMessagesRead}) {
+		t.Fatalf("identity = %+v, want usr_bot with messages:read and no expiry", id)
+	}
+
+	rows, err := h.queries.ListBotTokens(context.Background(), "usr_bot")
+	if err != nil || len(rows) != 1 || rows[0].LastUsedAt == nil {
+		t.Fatalf("expected last_used_at recorded, got %+v, err=%v", rows, err)
+	}
+}
+
+func TestAllowBotCommandEnforcesScopes(t *testing.T) {
+	bot := newIdentifiedTestClient(&Hub{}, "usr_bot")
+	bot.user.Bot = true
+	bot.setScopes([]string{models.ScopeMessagesRead})
+
+	if !bot.allowBotCommand(CmdPresenceSet, "") {
+		t.Fatal("expected presence updates to need no scope")
+	}
+	if bot.allowBotCommand(CmdMessageSend, "") {
+		t.Fatal("expected MESSAGE_SEND to require messages:write")
+	}
+	msg := <-bot.send
+	if payload, ok := msg.Data.(ErrorPayload); !ok || payload.Code != ErrCodeForbidden {
+		t.Fatalf("expected FORBIDDEN error, got %+v", msg.Data)
+	}
+	if bot.allowBotCommand(CmdVoiceJoin, "") {
+		t.Fatal("expected voice to be off limits to bots")
+	}
+	<-bot.send
+
+	bot.EnableBotMode()
+	bot.handleMessage(&WSMessage{
+		Op:   OpRequest,
+		Type: ReqMembersGet,
+		Data: map[string]interface{}{"request_id": "r1"},
+	})
+	if resp := receiveResponse(t, bot); resp.Error == nil || resp.Error.Code != ErrCodeForbidden {
+		t.Fatalf("expected MEMBERS_GET to require members:read, got %+v", resp)
+	}
+
+	human := newIdentifiedTestClient(&Hub{}, "usr_1")
+	if !human.allowBotCommand(CmdVoiceJoin, "") || !human.HasScope(models.ScopeMessagesWrite) {
+		t.Fatal("expected human sessions to be unrestricted")
+	}
+}
+
+func TestChannelEventsSkipBotsWithoutReadScope(t *testing.T) {
+	h := &Hub{clients: make(map[*Client]bool)}
+	reader := newIdentifiedTestClient(h, "usr_reader")
+	reader.user.Bot = true
+	reader.setScopes([]string{models.ScopeMessagesRead})
+	writer := newIdentifiedTestClient(h, "usr_writer")
+	writer.user.Bot = true
+	writer.setScopes([]string{models.ScopeMessagesWrite})
+	h.clients[reader] = true
+	h.clients[writer] = true
+
+	h.deliverToClients(Event{Audience: AudienceChannel, Type: EventMessageCreate, Data: MessageCreatePayload{ID: "msg_1"}})
+
+	if reader.queuedLen() != 1 {
+		t.Fatalf("reader got %d messages, want 1", reader.queuedLen())
+	}
+	if writer.queuedLen() != 0 {
+		t.Fatalf("writer without messages:read got %d messages, want 0", writer.queuedLen())
+	}
+}
@@ -0,0 +1,164 @@
+package ws
+
+import (
+	"errors"
+	"log/slog"
+
+	"github.com/frisksitron/lobby/src-server/pkg/lobbyclient"
+
+	"lobby/internal/models"
+	"lobby/internal/sfu"
+)
+
+type (
+	VideoStatePayload      = lobbyclient.VideoStatePayload
+	CameraStartPayload     = lobbyclient.CameraStartPayload
+	CameraSubscribePayload = lobbyclient.CameraSubscribePayload
+)
+
+const (
+	EventVideoState      = lobbyclient.EventVideoState
+	CmdCameraStart       = lobbyclient.CmdCameraStart
+	CmdCameraStop        = lobbyclient.CmdCameraStop
+	CmdCameraSubscribe   = lobbyclient.CmdCameraSubscribe
+	CmdCameraUnsubscribe = lobbyclient.CmdCameraUnsubscribe
+
+	VideoSourceCamera = lobbyclient.VideoSourceCamera
+	LayoutGrid        = lobbyclient.LayoutGrid
+	LayoutSpotlight   = lobbyclient.LayoutSpotlight
+)
+
+// maxCameraDimension bounds the width and height a CAMERA_START reports
+// (8K UHD).
+const maxCameraDimension = 7680
+
+// GetCameraManager returns the camera manager
+func (h *Hub) GetCameraManager() *sfu.CameraManager {
+	return h.cameras
+}
+
+// handleCameraUpdate is called when a user's camera goes live, changes size,
+// or stops.
+func (h *Hub) handleCameraUpdate(userID string, enabled bool, width, height int) {
+	payload := VideoStatePayload{UserID: userID, Source: VideoSourceCamera, Enabled: enabled}
+	if enabled {
+		payload.Width, payload.Height = width, height
+		payload.Layout = LayoutGrid
+	}
+	h.BroadcastDispatch(EventVideoState, payload)
+}
+
+func (c *Client) handleCameraStart(msg *WSMessage) {
+	if !c.IsIdentified() {
+		return
+	}
+
+	if c.hub.GetVoiceLifecycleState(c.user.ID) != VoiceLifecycleActive {
+		c.sendError(ErrorPayload{
+			Code:    ErrCodeVoiceNotInChannel,
+			Message: "Must be in voice to turn on a camera",
+		})
+		return
+	}
+
+	if !models.RoleAtLeast(c.user.Role, c.hub.permissions.CameraRole) {
+		c.sendError(ErrorPayload{
+			Code:    ErrCodeForbidden,
+			Message: "You do not have permission to use a camera",
+		})
+		return
+	}
+
+	var data CameraStartPayload
+	if !c.decodeDispatchData(msg, &data) {
+		return
+	}
+	if data.Width < 0 || data.Height < 0 || data.Width > maxCameraDimension || data.Height > maxCameraDimension {
+		c.sendError(ErrorPayload{
+			Code:    ErrCodeInvalidRequest,
+			Message: "Invalid camera size",
+		})
+		return
+	}
+
+	cm := c.hub.GetCameraManager()
+	if cm == nil {
+		return
+	}
+
+	// Register the camera, then renegotiate so the client sees the camera
+	// transceiver and can attach its track
+	if err := cm.StartCamera(c.user.ID, data.Width, data.Height); err != nil {
+		slog.Error("error starting camera", "component", "ws", "user_id", c.user.ID, "error", err)
+		return
+	}
+
+	if sfuInst := c.hub.GetSFU(); sfuInst != nil {
+		sfuInst.TriggerRenegotiation(c.user.ID)
+	}
+	slog.Info("user requested camera", "component", "ws", "user_id", c.user.ID)
+}
+
+func (c *Client) handleCameraStop() {
+	if !c.IsIdentified() {
+		return
+	}
+
+	cm := c.hub.GetCameraManager()
+	if cm == nil {
+		return
+	}
+
+	cm.StopCamera(c.user.ID)
+}
+
+func (c *Client) handleCameraSubscribe(msg *WSMessage) {
+	if !c.IsIdentified() {
+		return
+	}
+
+	if c.hub.GetVoiceLifecycleState(c.user.ID) != VoiceLifecycleActive {
+		c.sendError(ErrorPayload{
+			Code:    ErrCodeVoiceNotInChannel,
+			Message: "Must be in voice to subscribe to a camera",
+		})
+		return
+	}
+
+	var data CameraSubscribePayload
+	if !c.decodeDispatchData(msg, &data) || data.PublisherID == "" {
+		return
+	}
+
+	cm := c.hub.GetCameraManager()
+	if cm == nil {
+		return
+	}
+
+	if err := cm.Subscribe(c.user.ID, data.PublisherID); errors.Is(err, sfu.ErrVideoCodecUnsupported) {
+		c.sendError(ErrorPayload{
+			Code:    ErrCodeVideoCodecUnsupported,
+			Message: "This camera uses a video codec you did not offer",
+		})
+	} else if err != nil {
+		slog.Error("error subscribing to camera", "component", "ws", "error", err)
+	}
+}
+
+func (c *Client) handleCameraUnsubscribe(msg *WSMessage) {
+	if !c.IsIdentified() {
+		return
+	}
+
+	var data CameraSubscribePayload
+	if !c.decodeDispatchData(msg, &data) || data.PublisherID == "" {
+		return
+	}
+
+	cm := c.hub.GetCameraManager()
+	if cm == nil {
+		return
+	}
+
+	cm.Unsubscribe(c.user.ID, data.PublisherID)
+}
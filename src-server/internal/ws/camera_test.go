@@ -0,0 +1,41 @@
+package ws
+
+import "testing"
+
+func TestCameraUpdatePublishesVideoState(t *testing.T) {
+	h := &Hub{clients: make(map[*Client]bool), events: NewEventBus(), broadcast: make(chan *WSMessage, 2)}
+
+	var got []VideoStatePayload
+	h.Events().Subscribe(SubscriberFunc(func(e Event) {
+		got = append(got, e.Data.(VideoStatePayload))
+	}), TopicVideo)
+
+	h.handleCameraUpdate("usr_1", true, 640, 480)
+	h.handleCameraUpdate("usr_1", false, 0, 0)
+
+	want := []VideoStatePayload{
+		{UserID: "usr_1", Source: VideoSourceCamera, Enabled: true, Width: 640, Height: 480, Layout: LayoutGrid},
+		{UserID: "usr_1", Source: VideoSourceCamera},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d VIDEO_STATE events, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("VIDEO_STATE %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestCameraStartRequiresVoice(t *testing.T) {
+	h := &Hub{clients: make(map[*Client]bool)}
+	c := newIdentifiedTestClient(h, "usr_1")
+
+	c.handleCameraStart(&WSMessage{Op: OpDispatch, Type: CmdCameraStart, Data: CameraStartPayload{Width: 640, Height: 480}})
+
+	msg := nextSent(c)
+	payload, ok := msg.Data.(ErrorPayload)
+	if msg.Type != EventError || !ok || payload.Code != ErrCodeVoiceNotInChannel {
+		t.Fatalf("expected NOT_IN_VOICE error, got type=%s data=%+v", msg.Type, msg.Data)
+	}
+}
@@ -0,0 +1,220 @@
+package ws
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+
+	"lobby/internal/cluster"
+	"lobby/internal/sfu"
+)
+
+// voiceCascadeQueueSize bounds the frames waiting for the backplane, about a
+// second of audio for a dozen speakers. Frames beyond it are dropped.
+const voiceCascadeQueueSize = 512
+
+// ConfigureVoiceCluster sets the region shown to clients in RTC_READY and,
+// when cascade is set, relays voice audio between instances so users in voice
+// hear each other wherever they connected. Each instance sends its own users'
+// audio on the backplane's voice channel and plays other instances' users
+// into its SFU as remote participants, listening only while it has users in
+// voice. Video stays on the instance that carries it. Must be called after
+// AttachBackplane and before Run.
+func (h *Hub) ConfigureVoiceCluster(region string, cascade bool) {
+	if h.backplane == nil {
+		return
+	}
+	h.region = region
+	if !cascade || h.sfu == nil {
+		return
+	}
+	h.voiceFrames = make(chan cluster.VoiceFrame, voiceCascadeQueueSize)
+	h.voiceListen = make(chan struct{}, 1)
+	h.remoteVoice = make(map[string]struct{})
+	h.sfu.SetCascadeSink(h.publishVoiceFrame)
+	slog.Info("voice cascade enabled", "component", "hub", "instance_id", h.instanceID, "region", region)
+}
+
+// voiceNode is the instance carrying voice media for this hub's clients, or
+// nil when running standalone. It is always this instance: the load balancer
+// picks the node when the websocket connects, and users on other instances
+// are reached through the cascade rather than by sending clients elsewhere.
+func (h *Hub) voiceNode() *VoiceNodeInfo {
+	if h.backplane == nil {
+		return nil
+	}
+	return &VoiceNodeInfo{ID: h.instanceID, Region: h.region}
+}
+
+// publishVoiceFrame is the SFU cascade sink. It runs on media goroutines, so
+// it copies the payload before it is reused and never blocks.
+func (h *Hub) publishVoiceFrame(frame sfu.RelayFrame) {
+	select {
+	case h.voiceFrames <- cluster.VoiceFrame{
+		Instance:  h.instanceID,
+		UserID:    frame.SourceUserID,
+		Sequence:  frame.Sequence,
+		Timestamp: frame.Timestamp,
+		Payload:   bytes.Clone(frame.Payload),
+	}:
+	default:
+		// Late audio is useless; dropping is cheaper than backing up media
+	}
+}
+
+// wakeVoiceCascadeLocked asks runVoiceCascade to recheck whether this
+// instance should listen to other instances' voice. Callers hold h.mu after
+// adding or removing a voice session.
+func (h *Hub) wakeVoiceCascadeLocked() {
+	if h.voiceListen == nil {
+		return
+	}
+	select {
+	case h.voiceListen <- struct{}{}:
+	default:
+	}
+}
+
+// runVoiceCascade publishes queued voice frames and keeps the voice
+// subscription in step with local voice sessions until shutdown.
+func (h *Hub) runVoiceCascade() {
+	h.syncVoiceSubscription()
+	for {
+		select {
+		case <-h.shutdown:
+			if h.stopVoice != nil {
+				h.stopVoice()
+				h.stopVoice = nil
+			}
+			return
+		case <-h.voiceListen:
+			h.syncVoiceSubscription()
+		case frame := <-h.voiceFrames:
+			ctx, cancel := context.WithTimeout(context.Background(), clusterOpTimeout)
+			if err := h.backplane.PublishVoice(ctx, frame); err != nil {
+				slog.Debug("error publishing voice frame", "component", "hub", "error", err)
+			}
+			cancel()
+		}
+	}
+}
+
+// syncVoiceSubscription subscribes to other instances' voice while anyone
+// is in voice here, and unsubscribes once the last one leaves, so idle
+// instances never receive audio. Only runVoiceCascade calls it.
+func (h *Hub) syncVoiceSubscription() {
+	h.mu.RLock()
+	listen := len(h.voiceSessions) > 0
+	h.mu.RUnlock()
+
+	switch {
+	case listen && h.stopVoice == nil:
+		h.stopVoice = h.backplane.SubscribeVoice(h.receiveVoiceFrame)
+	case !listen && h.stopVoice != nil:
+		h.stopVoice()
+		h.stopVoice = nil
+		h.dropAllRemoteVoice()
+	}
+}
+
+// receiveVoiceFrame plays a frame from another instance, adding its user as
+// a remote participant on first sight. It runs on the voice subscription's
+// goroutine. Users in voice here are skipped: they moved instances and their
+// frames from the old one are stale.
+func (h *Hub) receiveVoiceFrame(frame cluster.VoiceFrame) {
+	if h.voiceFrames == nil || frame.Instance == h.instanceID || frame.UserID == "" {
+		return
+	}
+
+	h.mu.RLock()
+	_, local := h.voiceSessions[frame.UserID]
+	h.mu.RUnlock()
+	if local {
+		return
+	}
+
+	h.remoteVoiceMu.Lock()
+	if _, ok := h.remoteVoice[frame.UserID]; !ok {
+		if err := h.sfu.AddRemoteParticipant(frame.UserID); err != nil {
+			h.remoteVoiceMu.Unlock()
+			slog.Warn("error adding remote voice participant", "component", "hub", "user_id", frame.UserID, "error", err)
+			return
+		}
+		h.remoteVoice[frame.UserID] = struct{}{}
+	}
+	h.remoteVoiceMu.Unlock()
+
+	if err := h.sfu.WriteRelayAudio(frame.UserID, sfu.RelayFrame{
+		Sequence:  frame.Sequence,
+		Timestamp: frame.Timestamp,
+		Payload:   frame.Payload,
+	}); err != nil {
+		slog.Debug("error writing remote voice frame", "component", "hub", "user_id", frame.UserID, "error", err)
+	}
+}
+
+// dropRemoteVoice stops playing userID's audio from another instance.
+func (h *Hub) dropRemoteVoice(userID string) {
+	if h.voiceFrames == nil {
+		return
+	}
+	h.remoteVoiceMu.Lock()
+	_, ok := h.remoteVoice[userID]
+	delete(h.remoteVoice, userID)
+	h.remoteVoiceMu.Unlock()
+	if ok {
+		h.sfu.RemoveRelay(userID)
+	}
+}
+
+// dropAllRemoteVoice stops playing every user from other instances.
+func (h *Hub) dropAllRemoteVoice() {
+	h.remoteVoiceMu.Lock()
+	users := make([]string, 0, len(h.remoteVoice))
+	for userID := range h.remoteVoice {
+		users = append(users, userID)
+	}
+	h.remoteVoiceMu.Unlock()
+
+	for _, userID := range users {
+		h.dropRemoteVoice(userID)
+	}
+}
+
+// handleRemoteVoiceState drops a remote participant once another instance
+// reports they left voice.
+func (h *Hub) handleRemoteVoiceState(data json.RawMessage) {
+	var payload VoiceStateUpdatePayload
+	if err := json.Unmarshal(data, &payload); err != nil {
+		return
+	}
+	if !payload.InVoice {
+		h.dropRemoteVoice(payload.UserID)
+	}
+}
+
+// pruneRemoteVoice drops remote participants no instance reports in voice,
+// such as when their instance died without announcing it.
+func (h *Hub) pruneRemoteVoice() {
+	if h.voiceFrames == nil {
+		return
+	}
+	members := h.remoteMembers()
+	if members == nil {
+		return
+	}
+
+	h.remoteVoiceMu.Lock()
+	var gone []string
+	for userID := range h.remoteVoice {
+		if !members[userID].InVoice {
+			gone = append(gone, userID)
+		}
+	}
+	h.remoteVoiceMu.Unlock()
+
+	for _, userID := range gone {
+		h.dropRemoteVoice(userID)
+	}
+}
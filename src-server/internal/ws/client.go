@@ -3,7 +3,10 @@ package ws
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log/slog"
+	"slices"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -14,10 +17,10 @@ import (
 	"github.com/gorilla/websocket"
 	"github.com/microcosm-cc/bluemonday"
 
+	"lobby/internal/auth"
 	"lobby/internal/constants"
-	"lobby/internal/db"
 	sqldb "lobby/internal/db/sqlc"
-	"lobby/internal/mediaurl"
+	"lobby/internal/history"
 	"lobby/internal/models"
 	"lobby/internal/sfu"
 )
@@ -57,46 +60,31 @@ const (
 	// Send pings to peer with this period. Must be less than pongWait
 	pingPeriod = 10 * time.Second
 
+	// Warn with AUTH_EXPIRING this long before the token expires
+	authExpiryWarning = time.Minute
+
 	// Maximum message size allowed from peer (increased for video SDP)
 	maxMessageSize = 65536
 
 	// Timeout for hub registration
 	registerTimeout = 5 * time.Second
 
-	// Rate limiting intervals
-	messageRateLimit = 200 * time.Millisecond // 5 messages per second
-
-	// Voice join cooldown: 3 joins in 15s triggers a 15s cooldown
-	voiceJoinLimit    = 3
-	voiceJoinWindow   = 15 * time.Second
-	voiceJoinCooldown = 15 * time.Second
-
 	// Maximum message content length in characters (includes HTML markup)
-	maxMessageContentLength = 8000
-
-	// Mute/deafen cooldown: 5 toggles in 5s triggers a 10s cooldown
-	voiceToggleLimit      = 5
-	voiceToggleWindow     = 5 * time.Second
-	voiceCooldownDuration = 10 * time.Second
-
-	// Signaling command budgets
-	rtcSignalingLimit  = 300
-	rtcSignalingWindow = 10 * time.Second
-
-	screenShareSignalingLimit  = 40
-	screenShareSignalingWindow = 10 * time.Second
+	maxMessageContentLength = constants.MessageMaxContentLength
 )
 
 // Client represents a single WebSocket connection
 type Client struct {
 	hub           *Hub
 	conn          *websocket.Conn
-	send          chan *WSMessage
+	send          chan *WSMessage                  // direct replies and signaling; written first
+	events        [numSendClasses]chan queuedEvent // hub fan-out by sendClass
+	relaySend     chan []byte                      // binary audio frames; dropped when full
 	connCloseOnce sync.Once
 	sendCloseOnce sync.Once
 	authExpiryMu  sync.Mutex
-	authExpiry    *time.Timer
-	authExpiryVer uint64
+	authExpiresAt time.Time // zero when no token expiry is pending
+	authWarned    bool      // AUTH_EXPIRING already sent for authExpiresAt
 
 	callbackMu              sync.Mutex
 	identifiedCallbacks     []func(*Client)
@@ -108,35 +96,77 @@ type Client struct {
 	state atomic.Int32
 
 	// User info (populated after IDENTIFY)
-	user      *models.User
-	mu        sync.RWMutex // Protects status
-	status    string       // online, idle, dnd, offline
-	sessionID string       // Unique session identifier
+	user           *models.User
+	mu             sync.RWMutex // Protects status, custom status, scopes, and loginSessionID
+	status         string       // online, idle, dnd, offline
+	statusText     string       // custom status, shown unless status is offline
+	statusEmoji    string       // emoji or :shortcode: shown with statusText
+	scopes         []string     // bot token scopes; nil for human sessions
+	loginSessionID string       // user_sessions row of the IDENTIFY token, if any
+	sessionID      string       // Unique session identifier
+
+	// intentMask of the event classes left out of IDENTIFY.intents
+	filteredIntents atomic.Uint32
+
+	// Correlated command being handled, if it carried an id
+	correlation atomic.Pointer[commandCorrelation]
+
+	// Sequenced event buffer for RESUME; set by the hub on registration
+	// (protected by the hub's mu)
+	resume *resumeSession
 
 	// DroppedMessages tracks how many messages have been dropped due to full buffer
 	DroppedMessages int64
 
+	// Unix nanos of the last frame read and written, for the hub's janitor
+	lastRead  atomic.Int64
+	lastWrite atomic.Int64
+
+	// CapabilityBatch was requested in IDENTIFY
+	batching atomic.Bool
+
+	// Heartbeat opt-in and the last measured round trip in milliseconds
+	heartbeatsEnabled atomic.Bool
+	latencyMs         atomic.Int64
+	latencyMeasured   atomic.Bool
+
+	// Unix nanos of the last command or heartbeat, by the hub clock, and the
+	// status to restore when the hub marked the client idle or offline for
+	// inactivity ("" when it did not; protected by mu)
+	lastActivity atomic.Int64
+	autoAwayFrom string
+
 	// Rate limiting state — only accessed from the ReadPump goroutine (via handleMessage),
 	// so no mutex is needed.
-	lastMessage         time.Time
-	voiceJoins          []time.Time // timestamps of recent voice joins
-	voiceJoinCooldownAt time.Time   // when join cooldown expires
-	voiceToggles        []time.Time // timestamps of recent mute/deafen toggles
-	voiceCooldownAt     time.Time   // when mute/deafen cooldown expires
-
-	rtcSignals         []time.Time // timestamps of recent RTC signaling commands
-	screenShareSignals []time.Time // timestamps of recent screen-share signaling commands
+	commandRates  map[string][]time.Time // timestamps of recent commands by rate limit bucket
+	rateCooldowns map[string]time.Time   // when a bucket's cooldown expires
+	botRequests   []time.Time            // timestamps of recent REQUEST frames
+	relayFrames   []time.Time            // timestamps of recent binary audio frames
+	heartbeats    []time.Time            // timestamps of recent client HEARTBEATs
+
+	// bot is set when the connection negotiated BotSubprotocol
+	bot bool
+
+	// encoding is the negotiated gateway encoding; nil means JSON
+	encoding encoding
+
+	// remoteIP is the resolved client address, set before the pumps start
+	remoteIP string
 }
 
 // NewClient creates a new client
 func NewClient(hub *Hub, conn *websocket.Conn) *Client {
 	c := &Client{
-		hub:    hub,
-		conn:   conn,
-		send:   make(chan *WSMessage, constants.WSClientSendBufferSize),
-		status: "online",
+		hub:       hub,
+		conn:      conn,
+		send:      make(chan *WSMessage, constants.WSClientSendBufferSize),
+		events:    newEventQueues(),
+		relaySend: make(chan []byte, relaySendBuffer),
+		status:    "online",
 	}
 	c.state.Store(int32(ClientStateConnected))
+	c.markRead()
+	c.markWritten()
 	return c
 }
 
@@ -192,7 +222,7 @@ func (c *Client) runCloseCallbacks() {
 
 // Close performs cleanup for the client, ensuring it only happens once
 func (c *Client) Close() {
-	c.stopAuthExpiryTimer()
+	c.clearAuthExpiry()
 
 	if !c.transitionTo(ClientStateClosing) {
 		// Already closing/closed, but still ensure conn is closed
@@ -204,49 +234,80 @@ func (c *Client) Close() {
 	c.transitionTo(ClientStateClosed)
 }
 
-func (c *Client) stopAuthExpiryTimer() {
+func (c *Client) clearAuthExpiry() {
 	c.authExpiryMu.Lock()
-	defer c.authExpiryMu.Unlock()
-	c.authExpiryVer++
-
-	if c.authExpiry != nil {
-		c.authExpiry.Stop()
-		c.authExpiry = nil
-	}
+	c.authExpiresAt = time.Time{}
+	c.authWarned = false
+	c.authExpiryMu.Unlock()
 }
 
+// scheduleAuthExpiry closes the connection at expiresAt unless a re-IDENTIFY
+// with a fresh token reschedules it first. AUTH_EXPIRING goes out
+// authExpiryWarning ahead, or right away for tokens closer to expiry. Both are
+// checked against the hub clock here and on every janitor tick.
 func (c *Client) scheduleAuthExpiry(expiresAt time.Time) {
 	c.authExpiryMu.Lock()
-	defer c.authExpiryMu.Unlock()
+	c.authExpiresAt = expiresAt
+	c.authWarned = false
+	c.authExpiryMu.Unlock()
 
-	c.authExpiryVer++
-	version := c.authExpiryVer
+	go c.checkAuthExpiry(c.hub.now())
+}
 
-	if c.authExpiry != nil {
-		c.authExpiry.Stop()
-		c.authExpiry = nil
+// checkAuthExpiry sends AUTH_EXPIRING once now is inside the warning window
+// and closes the connection once the token has expired.
+func (c *Client) checkAuthExpiry(now time.Time) {
+	c.authExpiryMu.Lock()
+	expiresAt := c.authExpiresAt
+	if expiresAt.IsZero() {
+		c.authExpiryMu.Unlock()
+		return
 	}
-
-	delay := time.Until(expiresAt)
-	if delay <= 0 {
-		go c.handleAuthExpired(version)
+	if !now.Before(expiresAt) {
+		c.authExpiresAt = time.Time{}
+		c.authExpiryMu.Unlock()
+		c.handleAuthExpired()
 		return
 	}
+	warn := !c.authWarned && !now.Before(expiresAt.Add(-authExpiryWarning))
+	if warn {
+		c.authWarned = true
+	}
+	c.authExpiryMu.Unlock()
 
-	c.authExpiry = time.AfterFunc(delay, func() {
-		c.handleAuthExpired(version)
-	})
+	if warn {
+		c.handleAuthExpiring(expiresAt)
+	}
 }
 
-func (c *Client) handleAuthExpired(version uint64) {
-	c.authExpiryMu.Lock()
-	if version != c.authExpiryVer {
-		c.authExpiryMu.Unlock()
+// expireAuth warns and closes clients whose tokens are expiring. It runs on
+// the janitor tick.
+func (h *Hub) expireAuth() {
+	now := h.now()
+	h.mu.RLock()
+	clients := make([]*Client, 0, len(h.clients))
+	for client := range h.clients {
+		clients = append(clients, client)
+	}
+	h.mu.RUnlock()
+
+	for _, client := range clients {
+		client.checkAuthExpiry(now)
+	}
+}
+
+func (c *Client) handleAuthExpiring(expiresAt time.Time) {
+	if !c.IsIdentified() || c.IsClosed() {
 		return
 	}
-	c.authExpiry = nil
-	c.authExpiryMu.Unlock()
+	c.trySend(&WSMessage{
+		Op:   OpDispatch,
+		Type: EventAuthExpiring,
+		Data: AuthExpiringPayload{ExpiresAt: expiresAt.UnixMilli()},
+	})
+}
 
+func (c *Client) handleAuthExpired() {
 	if !c.IsIdentified() || c.IsClosed() || c.user == nil {
 		return
 	}
@@ -295,21 +356,32 @@ func (c *Client) ReadPump() {
 	c.conn.SetReadLimit(maxMessageSize)
 	c.conn.SetReadDeadline(time.Now().Add(pongWait))
 	c.conn.SetPongHandler(func(string) error {
+		c.markRead()
 		c.conn.SetReadDeadline(time.Now().Add(pongWait))
 		return nil
 	})
 
 	for {
-		_, message, err := c.conn.ReadMessage()
+		messageType, message, err := c.conn.ReadMessage()
 		if err != nil {
 			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
 				slog.Error("websocket error", "component", "ws", "error", err)
 			}
 			break
 		}
+		c.markRead()
+		codec := c.codec()
+		if messageType == websocket.BinaryMessage && (codec.frameType() != websocket.BinaryMessage || isRelayFrame(message)) {
+			c.handleRelayFrame(message)
+			continue
+		}
+		if messageType != codec.frameType() {
+			slog.Warn("message frame does not match encoding", "component", "ws", "user_id", c.getUserID())
+			continue
+		}
 
 		var msg WSMessage
-		if err := json.Unmarshal(message, &msg); err != nil {
+		if err := codec.decode(message, &msg); err != nil {
 			slog.Warn("error parsing message", "component", "ws", "error", err)
 			continue
 		}
@@ -326,36 +398,144 @@ func (c *Client) WritePump() {
 	}()
 
 	for {
+		// Signaling first, then fan-out by class. Only block once everything
+		// is written, so the ping ticker and relay audio are checked between
+		// writes and never starve.
 		select {
 		case message, ok := <-c.send:
-			if c.IsClosed() {
+			if !c.writeDirect(message, ok) || !c.writePending(ticker) {
 				return
 			}
-			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
-			if !ok {
-				// Hub closed the channel
-				c.conn.WriteMessage(websocket.CloseMessage, []byte{})
+			continue
+		default:
+		}
+		if message, ok := c.nextEvent(); ok {
+			if !c.writeQueued(message) || !c.writePending(ticker) {
 				return
 			}
+			continue
+		}
 
-			if err := c.conn.WriteJSON(message); err != nil {
-				slog.Error("error writing message", "component", "ws", "error", err)
+		select {
+		case message, ok := <-c.send:
+			if !c.writeDirect(message, ok) {
 				return
 			}
-
-		case <-ticker.C:
-			if c.IsClosed() {
+		case e := <-c.events[sendState]:
+			if !c.writeQueued(e.stamp()) {
 				return
 			}
-
-			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
-			if err := c.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+		case e := <-c.events[sendChat]:
+			if !c.writeQueued(e.stamp()) {
+				return
+			}
+		case e := <-c.events[sendTyping]:
+			if !c.writeQueued(e.stamp()) {
+				return
+			}
+		case frame := <-c.relaySend:
+			if !c.writeRelay(frame) {
+				return
+			}
+		case <-ticker.C:
+			if !c.writePing() {
 				return
 			}
 		}
 	}
 }
 
+// writeDirect writes a message from c.send; ok false means the hub closed it.
+func (c *Client) writeDirect(message *WSMessage, ok bool) bool {
+	if c.IsClosed() {
+		return false
+	}
+	c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+	if !ok {
+		// Hub closed the channel
+		c.writeClose()
+		return false
+	}
+	return c.writeOutbound(message)
+}
+
+func (c *Client) writeQueued(message *WSMessage) bool {
+	if c.IsClosed() {
+		return false
+	}
+	c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+	return c.writeOutbound(message)
+}
+
+func (c *Client) writeClose() {
+	c.conn.WriteMessage(websocket.CloseMessage, []byte{})
+}
+
+// writePending writes relay audio and a due ping between queued fan-out.
+func (c *Client) writePending(ticker *time.Ticker) bool {
+	select {
+	case frame := <-c.relaySend:
+		if !c.writeRelay(frame) {
+			return false
+		}
+	default:
+	}
+	select {
+	case <-ticker.C:
+		return c.writePing()
+	default:
+		return true
+	}
+}
+
+func (c *Client) writeRelay(frame []byte) bool {
+	if c.IsClosed() {
+		return false
+	}
+	c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+	if err := c.conn.WriteMessage(websocket.BinaryMessage, frame); err != nil {
+		slog.Error("error writing relay frame", "component", "ws", "error", err)
+		return false
+	}
+	c.markWritten()
+	return true
+}
+
+func (c *Client) writePing() bool {
+	if c.IsClosed() {
+		return false
+	}
+
+	c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+	if err := c.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+		return false
+	}
+	c.markWritten()
+
+	return !c.heartbeatDue() || c.writeMessage(&WSMessage{
+		Op:   OpHeartbeat,
+		Data: HeartbeatPayload{Ts: time.Now().UnixMilli()},
+	})
+}
+
+// writeMessage encodes and writes one message from WritePump. It returns
+// false when the connection is broken; messages that fail to encode are
+// logged and skipped.
+func (c *Client) writeMessage(message *WSMessage) bool {
+	codec := c.codec()
+	data, err := codec.encode(message)
+	if err != nil {
+		slog.Error("error encoding message", "component", "ws", "type", message.Type, "error", err)
+		return true
+	}
+	if err := c.conn.WriteMessage(codec.frameType(), data); err != nil {
+		slog.Error("error writing message", "component", "ws", "error", err)
+		return false
+	}
+	c.markWritten()
+	return true
+}
+
 // getUserID returns the user ID or "unknown" if not set
 func (c *Client) getUserID() string {
 	if c.user != nil {
@@ -373,69 +553,46 @@ func (c *Client) SendHello() {
 }
 
 func (c *Client) handleMessage(msg *WSMessage) {
+	if msg.Op != OpHeartbeatAck {
+		c.markActive(msg.Op == OpDispatch && msg.Type == CmdPresenceSet)
+	}
 	switch msg.Op {
 	case OpDispatch:
 		c.handleDispatch(msg)
+	case OpRequest:
+		c.handleRequest(msg)
+	case OpHeartbeat:
+		c.handleHeartbeat(msg)
+	case OpHeartbeatAck:
+		c.handleHeartbeatAck(msg)
 	default:
 		slog.Warn("unknown op code", "component", "ws", "op", msg.Op)
 	}
 }
 
-// handleDispatch routes DISPATCH messages by their type
+// handleDispatch routes DISPATCH messages to the handler registered for
+// their type
 func (c *Client) handleDispatch(msg *WSMessage) {
-	switch msg.Type {
-	case CmdIdentify:
-		c.handleIdentify(msg)
-	case CmdMessageSend:
-		c.handleMessageSend(msg)
-	case CmdPresenceSet:
-		c.handlePresenceSet(msg)
-	case CmdTyping:
-		c.handleTyping()
-	case CmdVoiceJoin:
-		c.handleVoiceJoin(msg)
-	case CmdVoiceLeave:
-		c.handleVoiceLeave()
-	case CmdRtcOffer:
-		if !c.allowRTCSignaling(msg.Type) {
-			return
-		}
-		c.handleRtcOffer(msg)
-	case CmdRtcAnswer:
-		if !c.allowRTCSignaling(msg.Type) {
-			return
-		}
-		c.handleRtcAnswer(msg)
-	case CmdRtcIceCandidate:
-		if !c.allowRTCSignaling(msg.Type) {
-			return
-		}
-		c.handleRtcIceCandidate(msg)
-	case CmdVoiceStateSet:
-		c.handleVoiceStateSet(msg)
-	case CmdScreenShareStart:
-		if !c.allowScreenShareSignaling(msg.Type) {
-			return
-		}
-		c.handleScreenShareStart()
-	case CmdScreenShareStop:
-		if !c.allowScreenShareSignaling(msg.Type) {
-			return
-		}
-		c.handleScreenShareStop()
-	case CmdScreenShareSubscribe:
-		if !c.allowScreenShareSignaling(msg.Type) {
-			return
-		}
-		c.handleScreenShareSubscribe(msg)
-	case CmdScreenShareUnsubscribe:
-		if !c.allowScreenShareSignaling(msg.Type) {
-			return
-		}
-		c.handleScreenShareUnsubscribe()
-	default:
+	defer c.beginCommand(msg)()
+
+	if msg.ID != "" && !c.IsIdentified() && msg.Type != CmdIdentify && msg.Type != CmdResume {
+		c.nackCommand(ErrorPayload{Code: ErrCodeAuthFailed, Message: "Not identified"})
+		return
+	}
+	if !c.allowBotCommand(msg.Type, "") {
+		return
+	}
+	if c.dropReplayedCommand(msg) {
+		return
+	}
+
+	handler, ok := c.hub.lookupCommand(msg.Type)
+	if !ok {
 		slog.Warn("unknown dispatch type", "component", "ws", "type", msg.Type)
+		c.nackCommand(ErrorPayload{Code: ErrCodeInvalidRequest, Message: "Unknown command"})
+		return
 	}
+	handler(c, msg)
 }
 
 func (c *Client) decodeDispatchData(msg *WSMessage, target interface{}) bool {
@@ -447,40 +604,16 @@ func (c *Client) decodeDispatchData(msg *WSMessage, target interface{}) bool {
 
 	if err := json.Unmarshal(raw, target); err != nil {
 		slog.Warn("failed to decode dispatch payload", "component", "ws", "type", msg.Type, "user_id", c.getUserID(), "error", err)
+		c.nackCommand(ErrorPayload{Code: ErrCodeInvalidRequest, Message: "Invalid payload"})
 		return false
 	}
 
 	return true
 }
 
-func (c *Client) allowRTCSignaling(command string) bool {
-	ok, retryAfter := c.allowCommandRateLimit(&c.rtcSignals, rtcSignalingLimit, rtcSignalingWindow)
-	if ok {
-		return true
-	}
-	c.rejectSignalingRateLimit(command, retryAfter)
-	return false
-}
-
-func (c *Client) allowScreenShareSignaling(command string) bool {
-	ok, retryAfter := c.allowCommandRateLimit(&c.screenShareSignals, screenShareSignalingLimit, screenShareSignalingWindow)
-	if ok {
-		return true
-	}
-	c.rejectSignalingRateLimit(command, retryAfter)
-	return false
-}
-
 func (c *Client) allowCommandRateLimit(times *[]time.Time, limit int, window time.Duration) (bool, int64) {
-	now := time.Now()
-	cutoff := now.Add(-window)
-
-	filtered := (*times)[:0]
-	for _, t := range *times {
-		if t.After(cutoff) {
-			filtered = append(filtered, t)
-		}
-	}
+	now := c.hub.now()
+	filtered := recentCommands(*times, now, window)
 
 	if len(filtered) >= limit {
 		retryAfter := filtered[0].Add(window).UnixMilli()
@@ -493,20 +626,6 @@ func (c *Client) allowCommandRateLimit(times *[]time.Time, limit int, window tim
 	return true, 0
 }
 
-func (c *Client) rejectSignalingRateLimit(command string, retryAfter int64) {
-	c.send <- &WSMessage{
-		Op:   OpDispatch,
-		Type: EventError,
-		Data: ErrorPayload{
-			Code:       ErrCodeSignalingRateLimited,
-			Message:    "",
-			RetryAfter: retryAfter,
-		},
-	}
-
-	slog.Warn("signaling command rate limited", "component", "ws", "user_id", c.getUserID(), "command", command, "retry_after", retryAfter)
-}
-
 func (c *Client) handleIdentify(msg *WSMessage) {
 	state := c.State()
 	if state != ClientStateConnected && state != ClientStateIdentified {
@@ -516,74 +635,47 @@ func (c *Client) handleIdentify(msg *WSMessage) {
 	var data IdentifyPayload
 	if !c.decodeDispatchData(msg, &data) {
 		slog.Warn("IDENTIFY invalid payload", "component", "ws", "user_id", c.getUserID())
-		c.send <- &WSMessage{Op: OpDispatch, Type: EventError, Data: ErrorPayload{Code: ErrCodeAuthFailed, Message: "Invalid identify payload"}}
+		c.sendError(ErrorPayload{Code: ErrCodeAuthFailed, Message: "Invalid identify payload"})
 		c.Close()
 		return
 	}
-
-	// Extract and validate token from IDENTIFY payload
-	token := data.Token
-	if token == "" {
-		slog.Warn("IDENTIFY missing token", "component", "ws")
-		c.send <- &WSMessage{Op: OpDispatch, Type: EventError, Data: ErrorPayload{Code: ErrCodeAuthFailed, Message: "Missing token"}}
-		c.Close()
-		return
-	}
-
-	claims, err := c.hub.jwtService.ValidateAccessToken(token)
-	if err != nil {
-		slog.Warn("IDENTIFY invalid token", "component", "ws", "error", err)
-		c.send <- &WSMessage{Op: OpDispatch, Type: EventError, Data: ErrorPayload{Code: ErrCodeAuthFailed, Message: "Invalid token"}}
+	filtered, ok := parseIntents(data.Intents)
+	if !ok {
+		slog.Warn("IDENTIFY unknown intent", "component", "ws", "intents", data.Intents)
+		c.sendError(ErrorPayload{Code: ErrCodeInvalidRequest, Message: "Unknown intent"})
 		c.Close()
 		return
 	}
+	c.filteredIntents.Store(uint32(filtered))
+	capabilities := enabledCapabilities(data.Capabilities)
+	c.batching.Store(slices.Contains(capabilities, CapabilityBatch))
 
-	if claims.ExpiresAt == nil {
-		slog.Warn("IDENTIFY token missing expiry", "component", "ws", "user_id", claims.UserID)
-		c.send <- &WSMessage{Op: OpDispatch, Type: EventError, Data: ErrorPayload{Code: ErrCodeAuthFailed, Message: "Token missing expiry"}}
-		c.Close()
-		return
-	}
-
-	expiresAt := claims.ExpiresAt.Time
-	if !expiresAt.After(time.Now()) {
-		slog.Warn("IDENTIFY token already expired", "component", "ws", "user_id", claims.UserID)
-		c.send <- &WSMessage{Op: OpDispatch, Type: EventError, Data: ErrorPayload{Code: ErrCodeAuthExpired, Message: "Access token expired"}}
-		c.Close()
-		return
-	}
-
-	userRow, err := c.hub.queries.GetActiveUserByID(context.Background(), claims.UserID)
-	if err != nil {
-		slog.Warn("IDENTIFY user not found", "component", "ws", "error", err)
-		c.send <- &WSMessage{Op: OpDispatch, Type: EventError, Data: ErrorPayload{Code: ErrCodeAuthFailed, Message: "User not found"}}
-		c.Close()
-		return
-	}
-	user := modelUserFromDBUser(userRow)
-
-	if claims.SessionVersion != user.SessionVersion {
-		slog.Warn("IDENTIFY token session version mismatch", "component", "ws", "user_id", user.ID)
-		c.send <- &WSMessage{Op: OpDispatch, Type: EventError, Data: ErrorPayload{Code: ErrCodeAuthFailed, Message: "Session invalidated"}}
-		c.Close()
+	user, id, ok := c.authenticate(data.Token)
+	if !ok {
 		return
 	}
 
 	if state == ClientStateIdentified {
 		if c.user == nil || c.user.ID != user.ID {
 			slog.Warn("IDENTIFY attempted user switch", "component", "ws", "current_user_id", c.getUserID(), "token_user_id", user.ID)
-			c.send <- &WSMessage{Op: OpDispatch, Type: EventError, Data: ErrorPayload{Code: ErrCodeAuthFailed, Message: "Session invalidated"}}
+			c.sendError(ErrorPayload{Code: ErrCodeAuthFailed, Message: "Session invalidated"})
 			c.Close()
 			return
 		}
 
 		c.SetUser(user)
-		c.scheduleAuthExpiry(expiresAt)
+		c.setScopes(id.scopes)
+		c.setLoginSessionID(id.loginSessionID)
+		if !id.expiresAt.IsZero() {
+			c.scheduleAuthExpiry(id.expiresAt)
+		}
 		slog.Info("client re-identified", "component", "ws", "user_id", c.user.ID, "session_id", c.sessionID)
 		return
 	}
 
 	c.SetUser(user)
+	c.setScopes(id.scopes)
+	c.setLoginSessionID(id.loginSessionID)
 
 	// Transition to identified state
 	if !c.transitionTo(ClientStateIdentified) {
@@ -597,27 +689,21 @@ func (c *Client) handleIdentify(msg *WSMessage) {
 		case "online", "idle", "dnd":
 			c.SetStatus(data.Presence.Status)
 		}
+		text, textOK := normalizeStatusText(data.Presence.StatusText)
+		emoji, emojiOK := normalizeStatusEmoji(data.Presence.StatusEmoji)
+		if textOK && emojiOK {
+			c.SetCustomStatus(text, emoji)
+		}
 	}
 
 	// Register synchronously to ensure client is in members list before READY
-	done := make(chan struct{})
-	select {
-	case c.hub.registerSync <- registerRequest{client: c, done: done}:
-		select {
-		case <-done:
-			// Registration successful
-		case <-time.After(registerTimeout):
-			slog.Error("registration timeout", "component", "ws", "user_id", c.user.ID)
-			c.Close()
-			return
-		}
-	case <-time.After(registerTimeout):
-		slog.Error("registration send timeout", "component", "ws", "user_id", c.user.ID)
-		c.Close()
+	if c.register(nil) != registerAccepted {
 		return
 	}
 
-	c.scheduleAuthExpiry(expiresAt)
+	if !id.expiresAt.IsZero() {
+		c.scheduleAuthExpiry(id.expiresAt)
+	}
 
 	c.send <- &WSMessage{
 		Op: OpReady,
@@ -626,12 +712,161 @@ func (c *Client) handleIdentify(msg *WSMessage) {
 			SessionID:       c.sessionID,
 			User:            NewReadyUser(c.user),
 			Members:         c.hub.GetMemberSnapshot(),
+			Channel:         c.hub.GetChannelInfo(data.IncludeArchived),
+			Capabilities:    capabilities,
+			Recording:       c.hub.RecordingState(),
 		},
 	}
 
 	slog.Info("client identified", "component", "ws", "user_id", c.user.ID, "session_id", c.sessionID)
 }
 
+// authenticate resolves an IDENTIFY or RESUME token and checks the account
+// may connect. On failure the client has been told why and closed.
+func (c *Client) authenticate(token string) (*models.User, identity, bool) {
+	if token == "" {
+		slog.Warn("IDENTIFY missing token", "component", "ws")
+		c.sendError(ErrorPayload{Code: ErrCodeAuthFailed, Message: "Missing token"})
+		c.Close()
+		return nil, identity{}, false
+	}
+
+	var (
+		id identity
+		ok bool
+	)
+	if auth.IsBotToken(token) {
+		id, ok = c.resolveBotToken(token)
+	} else {
+		id, ok = c.resolveAccessToken(token)
+	}
+	if !ok {
+		return nil, identity{}, false
+	}
+
+	bans, err := c.hub.queries.CountBansForIdentity(context.Background(), sqldb.CountBansForIdentityParams{
+		UserID: id.userID,
+	})
+	if err != nil {
+		slog.Error("IDENTIFY ban check failed", "component", "ws", "user_id", id.userID, "error", err)
+		c.Close()
+		return nil, identity{}, false
+	}
+	if bans > 0 {
+		slog.Warn("IDENTIFY rejected banned user", "component", "ws", "user_id", id.userID)
+		c.sendError(ErrorPayload{Code: ErrCodeAuthFailed, Message: "Banned"})
+		c.Close()
+		return nil, identity{}, false
+	}
+
+	userRow, err := c.hub.queries.GetActiveUserByID(context.Background(), id.userID)
+	if err != nil {
+		slog.Warn("IDENTIFY user not found", "component", "ws", "error", err)
+		c.sendError(ErrorPayload{Code: ErrCodeAuthFailed, Message: "User not found"})
+		c.Close()
+		return nil, identity{}, false
+	}
+	user := modelUserFromDBUser(userRow)
+
+	if id.bot != user.Bot {
+		slog.Warn("IDENTIFY token does not match account type", "component", "ws", "user_id", user.ID, "bot", user.Bot)
+		c.sendError(ErrorPayload{Code: ErrCodeAuthFailed, Message: "Invalid token"})
+		c.Close()
+		return nil, identity{}, false
+	}
+
+	if !id.bot && id.sessionVersion != user.SessionVersion {
+		slog.Warn("IDENTIFY token session version mismatch", "component", "ws", "user_id", user.ID)
+		c.sendError(ErrorPayload{Code: ErrCodeAuthFailed, Message: "Session invalidated"})
+		c.Close()
+		return nil, identity{}, false
+	}
+
+	if id.loginSessionID != "" {
+		session, err := c.hub.queries.GetActiveUserSession(context.Background(), id.loginSessionID)
+		if err != nil || session.UserID != user.ID {
+			slog.Warn("IDENTIFY token session revoked", "component", "ws", "user_id", user.ID, "error", err)
+			c.sendError(ErrorPayload{Code: ErrCodeAuthFailed, Message: "Session invalidated"})
+			c.Close()
+			return nil, identity{}, false
+		}
+	}
+
+	return user, id, true
+}
+
+// register adds the client to the hub, claiming the parked session first for
+// a RESUME. It closes the client on anything but registerAccepted and
+// registerNotResumable.
+func (c *Client) register(resume *resumeRequest) registerResult {
+	result := make(chan registerResult, 1)
+	select {
+	case c.hub.registerSync <- registerRequest{client: c, resume: resume, result: result}:
+		select {
+		case r := <-result:
+			if r == registerLimited {
+				slog.Warn("IDENTIFY rejected by per-IP connection limit", "component", "ws", "user_id", c.user.ID, "ip", c.remoteIP)
+				c.sendError(ErrorPayload{Code: ErrCodeConnectionLimit, Message: "Too many connections from this address"})
+				c.Close()
+			}
+			return r
+		case <-time.After(registerTimeout):
+			slog.Error("registration timeout", "component", "ws", "user_id", c.user.ID)
+			c.Close()
+			return registerTimedOut
+		}
+	case <-time.After(registerTimeout):
+		slog.Error("registration send timeout", "component", "ws", "user_id", c.user.ID)
+		c.Close()
+		return registerTimedOut
+	}
+}
+
+// handleResume picks up a session parked after its connection dropped. The
+// hub replays the events after data.Seq and sends RESUMED instead of READY.
+// When the session cannot be resumed the client gets INVALID_SESSION and
+// stays connected so it can IDENTIFY.
+func (c *Client) handleResume(msg *WSMessage) {
+	if c.State() != ClientStateConnected {
+		return
+	}
+
+	var data ResumePayload
+	if !c.decodeDispatchData(msg, &data) || data.SessionID == "" {
+		slog.Warn("RESUME invalid payload", "component", "ws")
+		c.sendError(ErrorPayload{Code: ErrCodeAuthFailed, Message: "Invalid resume payload"})
+		c.Close()
+		return
+	}
+
+	user, id, ok := c.authenticate(data.Token)
+	if !ok {
+		return
+	}
+
+	c.SetUser(user)
+	c.setScopes(id.scopes)
+	c.setLoginSessionID(id.loginSessionID)
+
+	// The hub moves the client to identified once it claims the session.
+	switch c.register(&resumeRequest{sessionID: data.SessionID, seq: data.Seq}) {
+	case registerAccepted:
+	case registerNotResumable:
+		slog.Info("RESUME rejected", "component", "ws", "user_id", user.ID, "session_id", data.SessionID, "seq", data.Seq)
+		c.send <- &WSMessage{Op: OpInvalidSession, Data: InvalidSessionPayload{Resumable: false}}
+		return
+	default:
+		return
+	}
+
+	c.runIdentifiedCallbacks()
+	if !id.expiresAt.IsZero() {
+		c.scheduleAuthExpiry(id.expiresAt)
+	}
+
+	slog.Info("client resumed", "component", "ws", "user_id", user.ID, "session_id", c.sessionID, "seq", data.Seq)
+}
+
 func (c *Client) handleMessageSend(msg *WSMessage) {
 	if !c.IsIdentified() {
 		return
@@ -650,35 +885,71 @@ func (c *Client) handleMessageSend(msg *WSMessage) {
 	}
 
 	if utf8.RuneCountInString(content) > maxMessageContentLength {
-		c.send <- &WSMessage{
-			Op:   OpDispatch,
-			Type: EventError,
-			Data: ErrorPayload{
-				Code:    ErrCodeMessageTooLong,
-				Message: "Message exceeds maximum length",
-				Nonce:   nonce,
-			},
-		}
+		c.sendError(ErrorPayload{
+			Code:    ErrCodeMessageTooLong,
+			Message: "Message exceeds maximum length",
+			Nonce:   nonce,
+		})
+		return
+	}
+
+	if violations := c.hub.tooManyAttachments(attachmentIDs); len(violations) > 0 {
+		c.sendError(ErrorPayload{
+			Code:        ErrCodeAttachmentInvalid,
+			Message:     fmt.Sprintf("Messages can have at most %d attachments", c.hub.uploadLimits.MaxAttachments),
+			Nonce:       nonce,
+			Attachments: violations,
+		})
+		return
+	}
+
+	if !c.hub.CanAccessChannel(c.user) {
+		c.sendError(ErrorPayload{
+			Code:    ErrCodeForbidden,
+			Message: "You do not have access to this channel",
+			Nonce:   nonce,
+		})
+		return
+	}
+
+	if c.hub.IsChannelArchived() {
+		c.sendError(ErrorPayload{
+			Code:    ErrCodeChannelArchived,
+			Message: "Channel is archived",
+			Nonce:   nonce,
+		})
+		return
+	}
+
+	if c.rejectIfTimedOut(nonce) {
 		return
 	}
 
 	// Rate limit check
-	now := time.Now()
-	if now.Sub(c.lastMessage) < messageRateLimit {
-		c.send <- &WSMessage{
-			Op:   OpDispatch,
-			Type: EventError,
-			Data: ErrorPayload{
-				Code:    ErrCodeRateLimited,
-				Message: "",
-				Nonce:   nonce,
-			},
-		}
+	now := c.hub.now()
+	if ok, status := c.takeRateLimit(RateLimitMessageSend, c.hub.rateLimitRule(RateLimitMessageSend)); !ok {
+		c.sendError(ErrorPayload{
+			Code:       ErrCodeRateLimited,
+			Message:    "",
+			Nonce:      nonce,
+			RetryAfter: status.ResetAt,
+			RateLimit:  status,
+		})
 		return
 	}
-	c.lastMessage = now
 
-	c.hub.BroadcastDispatchExcept(EventTypingStop, TypingStopPayload{
+	if allowed, retryAt := c.hub.CheckSlowMode(c.user, now); !allowed {
+		c.sendError(ErrorPayload{
+			Code:       ErrCodeRateLimited,
+			Message:    "Slow mode is enabled",
+			Nonce:      nonce,
+			RetryAfter: retryAt.UnixMilli(),
+		})
+		return
+	}
+
+	c.hub.endTyping(c)
+	c.hub.broadcastTyping(EventTypingStop, TypingStopPayload{
 		UserID: c.user.ID,
 	}, c)
 
@@ -689,12 +960,34 @@ func (c *Client) handleMessageSend(msg *WSMessage) {
 		return
 	}
 
-	messageID, err := db.GenerateID("msg")
+	author := &MessageAuthor{
+		ID:             c.user.ID,
+		Username:       c.user.Username,
+		Avatar:         c.user.GetAvatarURL(),
+		AvatarAnimated: c.user.GetAvatarAnimatedURL(),
+		Bot:            c.user.Bot,
+	}
+	automodRule, automodMatch, flagged := c.hub.CheckAutomod(c.user, content)
+	if flagged && automodRule.Action != models.AutomodActionFlag {
+		code, message := ErrCodeAutomodBlocked, "Message blocked by automod"
+		if automodRule.Action == models.AutomodActionDeleteWarn {
+			code, message = ErrCodeAutomodRemoved, "Message removed by automod"
+		}
+		c.sendError(ErrorPayload{
+			Code:    code,
+			Message: message,
+			Nonce:   nonce,
+		})
+		c.hub.publishAutomodAlert(automodRule, automodMatch, "", author, content)
+		return
+	}
+
+	messageID, err := c.hub.newMessageID()
 	if err != nil {
 		slog.Error("error generating message id", "component", "ws", "error", err)
 		return
 	}
-	createdAt := time.Now().UTC()
+	createdAt := c.hub.now().UTC()
 
 	tx, err := c.hub.database.BeginTx(context.Background(), nil)
 	if err != nil {
@@ -730,44 +1023,30 @@ func (c *Client) handleMessageSend(msg *WSMessage) {
 			slog.Error("error claiming message attachments", "component", "ws", "error", claimErr)
 			return
 		}
-		if rowsAffected != int64(len(attachmentIDs)) {
-			c.send <- &WSMessage{
-				Op:   OpDispatch,
-				Type: EventError,
-				Data: ErrorPayload{
-					Code:    ErrCodeAttachmentInvalid,
-					Message: "One or more attachments are no longer available",
-					Nonce:   nonce,
-				},
-			}
-			return
-		}
-
 		dbAttachments, listErr := qtx.ListMessageAttachments(context.Background(), messageIDRef)
 		if listErr != nil {
 			slog.Error("error loading message attachments", "component", "ws", "error", listErr)
 			return
 		}
+		if violations := c.hub.claimedAttachmentViolations(c.user, attachmentIDs, dbAttachments); len(violations) > 0 || rowsAffected != int64(len(attachmentIDs)) {
+			message := "One or more attachments are no longer available"
+			for _, violation := range violations {
+				if violation.Reason == AttachmentTooLarge {
+					message = "One or more attachments exceed the upload size limit"
+				}
+			}
+			c.sendError(ErrorPayload{
+				Code:        ErrCodeAttachmentInvalid,
+				Message:     message,
+				Nonce:       nonce,
+				Attachments: violations,
+			})
+			return
+		}
 
 		attachmentsPayload = make([]MessageAttachment, 0, len(dbAttachments))
 		for _, attachment := range dbAttachments {
-			mapped := MessageAttachment{
-				ID:       attachment.ID,
-				Name:     attachment.OriginalName,
-				MimeType: attachment.MimeType,
-				Size:     attachment.SizeBytes,
-				URL:      mediaurl.Blob(c.hub.baseURL, attachment.ID),
-			}
-			if attachment.PreviewStoragePath != nil {
-				mapped.PreviewURL = mediaurl.BlobPreview(c.hub.baseURL, attachment.ID)
-			}
-			if attachment.PreviewWidth != nil {
-				mapped.PreviewWidth = *attachment.PreviewWidth
-			}
-			if attachment.PreviewHeight != nil {
-				mapped.PreviewHeight = *attachment.PreviewHeight
-			}
-			attachmentsPayload = append(attachmentsPayload, mapped)
+			attachmentsPayload = append(attachmentsPayload, MessageAttachment(history.Attachment(c.hub.baseURL, c.hub.mediaURLs, attachment)))
 		}
 	}
 
@@ -775,19 +1054,22 @@ func (c *Client) handleMessageSend(msg *WSMessage) {
 		slog.Error("error committing message transaction", "component", "ws", "error", err)
 		return
 	}
+	c.hub.RecordSlowMode(c.user, now)
 
-	c.hub.BroadcastDispatch(EventMessageCreate, MessageCreatePayload{
-		ID: messageID,
-		Author: &MessageAuthor{
-			ID:       c.user.ID,
-			Username: c.user.Username,
-			Avatar:   c.user.GetAvatarURL(),
-		},
+	c.hub.publishMessageCreate(MessageCreatePayload{
+		ID:          messageID,
+		Author:      author,
 		Content:     content,
 		Attachments: attachmentsPayload,
+		Embeds:      c.hub.messageEmbeds(content),
 		CreatedAt:   createdAt.Format(time.RFC3339Nano),
 		Nonce:       nonce,
 	})
+	c.hub.rememberCommand(c.user.ID, CmdMessageSend, nonce)
+
+	if flagged {
+		c.hub.publishAutomodAlert(automodRule, automodMatch, messageID, author, content)
+	}
 }
 
 func normalizeAttachmentIDs(raw []string) []string {
@@ -826,26 +1108,75 @@ func (c *Client) handlePresenceSet(msg *WSMessage) {
 
 	switch status {
 	case "online", "idle", "dnd", "offline":
-		c.SetStatus(status)
 	default:
+		c.rejectPresenceSet(data.Nonce, "Invalid presence status")
 		return
 	}
 
-	c.hub.BroadcastDispatch(EventPresenceUpdate, PresenceUpdatePayload{
-		UserID: c.user.ID,
-		Status: c.GetStatus(),
+	text, emoji := c.CustomStatus()
+	if data.StatusText != nil {
+		var ok bool
+		if text, ok = normalizeStatusText(*data.StatusText); !ok {
+			c.rejectPresenceSet(data.Nonce, "Invalid status text")
+			return
+		}
+	}
+	if data.StatusEmoji != nil {
+		var ok bool
+		if emoji, ok = normalizeStatusEmoji(*data.StatusEmoji); !ok {
+			c.rejectPresenceSet(data.Nonce, "Invalid status emoji")
+			return
+		}
+	}
+
+	c.SetStatus(status)
+	c.SetCustomStatus(text, emoji)
+	c.hub.BroadcastDispatch(EventPresenceUpdate, c.presenceUpdate())
+	c.sendCommandAck(CmdPresenceSet, data.Nonce)
+}
+
+func (c *Client) rejectPresenceSet(nonce, message string) {
+	if nonce == "" {
+		return
+	}
+	c.sendError(ErrorPayload{
+		Code:    ErrCodeInvalidRequest,
+		Message: message,
+		Nonce:   nonce,
 	})
 }
 
+// sendCommandAck records an applied command for replay protection and, for
+// a command that carried a nonce but no id, confirms it with the legacy
+// COMMAND_ACK. A command with an id is answered only by its ACK.
+func (c *Client) sendCommandAck(command, nonce string) {
+	if nonce == "" {
+		return
+	}
+	c.hub.rememberCommand(c.getUserID(), command, nonce)
+	if c.correlation.Load() != nil {
+		return
+	}
+	c.send <- &WSMessage{
+		Op:   OpDispatch,
+		Type: EventCommandAck,
+		Data: CommandAckPayload{
+			Command: command,
+			Nonce:   nonce,
+		},
+	}
+}
+
 func (c *Client) handleTyping() {
-	if !c.IsIdentified() {
+	if !c.IsIdentified() || !c.hub.CanAccessChannel(c.user) || c.hub.IsChannelArchived() {
 		return
 	}
 
-	c.hub.BroadcastDispatchExcept(EventTypingStart, TypingStartPayload{
+	c.hub.startTyping(c)
+	c.hub.broadcastTyping(EventTypingStart, TypingStartPayload{
 		UserID:    c.user.ID,
 		Username:  c.user.Username,
-		Timestamp: time.Now().UTC().Format(time.RFC3339Nano),
+		Timestamp: c.hub.now().UTC().Format(time.RFC3339Nano),
 	}, c)
 }
 
@@ -854,6 +1185,20 @@ func (c *Client) SetUser(user *models.User) {
 	c.user = user
 }
 
+// LoginSessionID returns the login session the client identified with, or ""
+// for bots and tokens issued before sessions were tracked.
+func (c *Client) LoginSessionID() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.loginSessionID
+}
+
+func (c *Client) setLoginSessionID(sessionID string) {
+	c.mu.Lock()
+	c.loginSessionID = sessionID
+	c.mu.Unlock()
+}
+
 // GetStatus returns the client's current presence status
 func (c *Client) GetStatus() string {
 	c.mu.RLock()
@@ -914,7 +1259,7 @@ func (c *Client) transitionTo(newState ClientState) bool {
 
 // CloseSend closes the send channel (called by hub during cleanup)
 func (c *Client) CloseSend() {
-	c.stopAuthExpiryTimer()
+	c.clearAuthExpiry()
 
 	c.sendCloseOnce.Do(func() { close(c.send) })
 	if c.transitionTo(ClientStateClosing) {
@@ -934,92 +1279,67 @@ func (c *Client) handleVoiceJoin(msg *WSMessage) {
 		return
 	}
 
-	if c.hub.GetVoiceLifecycleState(c.user.ID) != VoiceLifecycleNotInVoice {
-		c.send <- &WSMessage{
-			Op:   OpDispatch,
-			Type: EventError,
-			Data: ErrorPayload{
-				Code:    ErrCodeVoiceStateInvalidTransition,
-				Message: "Cannot join voice from current state",
-			},
-		}
+	if c.rejectIfTimedOut("") {
 		return
 	}
 
-	now := time.Now()
-
-	// Check if in join cooldown
-	if now.Before(c.voiceJoinCooldownAt) {
-		c.send <- &WSMessage{
-			Op:   OpDispatch,
-			Type: EventError,
-			Data: ErrorPayload{
-				Code:       ErrCodeVoiceJoinCooldown,
-				Message:    "",
-				RetryAfter: c.voiceJoinCooldownAt.UnixMilli(),
-			},
-		}
+	if c.hub.GetVoiceLifecycleState(c.user.ID) != VoiceLifecycleNotInVoice {
+		c.sendError(ErrorPayload{
+			Code:    ErrCodeVoiceStateInvalidTransition,
+			Message: "Cannot join voice from current state",
+		})
 		return
 	}
 
-	// Record join and prune old entries
-	cutoff := now.Add(-voiceJoinWindow)
-	filtered := c.voiceJoins[:0]
-	for _, t := range c.voiceJoins {
-		if t.After(cutoff) {
-			filtered = append(filtered, t)
-		}
-	}
-	filtered = append(filtered, now)
-	c.voiceJoins = filtered
-
-	// Check if threshold exceeded
-	if len(c.voiceJoins) >= voiceJoinLimit {
-		c.voiceJoinCooldownAt = now.Add(voiceJoinCooldown)
-		c.voiceJoins = c.voiceJoins[:0]
-		c.send <- &WSMessage{
-			Op:   OpDispatch,
-			Type: EventError,
-			Data: ErrorPayload{
-				Code:       ErrCodeVoiceJoinCooldown,
-				Message:    "",
-				RetryAfter: c.voiceJoinCooldownAt.UnixMilli(),
-			},
-		}
+	// Too many joins start a cooldown
+	if ok, status := c.takeRateLimit(RateLimitVoiceJoin, c.hub.rateLimitRule(RateLimitVoiceJoin)); !ok {
+		c.sendError(ErrorPayload{
+			Code:       ErrCodeVoiceJoinCooldown,
+			Message:    "",
+			RetryAfter: status.ResetAt,
+			RateLimit:  status,
+		})
 		return
 	}
 
 	muted := data.Muted
 	deafened := data.Deafened
 
-	if err := c.hub.BeginVoiceJoin(c.user.ID, muted, deafened); err != nil {
-		c.send <- &WSMessage{
-			Op:   OpDispatch,
-			Type: EventError,
-			Data: ErrorPayload{
-				Code:    ErrCodeVoiceStateInvalidTransition,
-				Message: "Cannot join voice from current state",
-			},
-		}
+	if err := c.hub.BeginVoiceJoin(c.user.ID, muted, deafened); errors.Is(err, errVoiceSessionLimit) {
+		c.sendError(ErrorPayload{
+			Code:    ErrCodeConnectionLimit,
+			Message: "Too many voice sessions from this address",
+		})
+		return
+	} else if err != nil {
+		c.sendError(ErrorPayload{
+			Code:    ErrCodeVoiceStateInvalidTransition,
+			Message: "Cannot join voice from current state",
+		})
 		return
 	}
 
+	if backend := c.hub.GetExternalSFU(); backend != nil {
+		c.joinExternalVoice(backend, data.Nonce)
+		return
+	}
+
+	// Joining here replaces any audio of this user cascaded from another instance
+	c.hub.dropRemoteVoice(c.user.ID)
+
 	sfuInst := c.hub.GetSFU()
 	if sfuInst != nil {
-		_, err := sfuInst.AddPeer(c.user.ID)
+		peer, err := sfuInst.AddPeer(c.user.ID)
 		if err != nil {
 			c.hub.DiscardVoiceSession(c.user.ID)
 			slog.Error("error creating SFU peer", "component", "ws", "user_id", c.user.ID, "error", err)
-			c.send <- &WSMessage{
-				Op:   OpDispatch,
-				Type: EventError,
-				Data: ErrorPayload{
-					Code:    ErrCodeVoiceJoinFailed,
-					Message: "Failed to join voice",
-				},
-			}
+			c.sendError(ErrorPayload{
+				Code:    ErrCodeVoiceJoinFailed,
+				Message: "Failed to join voice",
+			})
 			return
 		}
+		peer.SetVideoCodecs(data.VideoCodecs)
 	}
 
 	iceServers := []ICEServerInfo{}
@@ -1036,6 +1356,7 @@ func (c *Client) handleVoiceJoin(msg *WSMessage) {
 	// Send RTC_READY first so client can set up signaling listeners
 	c.hub.SendDispatchToUser(c.user.ID, EventRtcReady, RtcReadyPayload{
 		ICEServers: iceServers,
+		Node:       c.hub.voiceNode(),
 	})
 
 	// Then send initial offer - client's listeners are now ready
@@ -1045,18 +1366,15 @@ func (c *Client) handleVoiceJoin(msg *WSMessage) {
 			c.hub.DiscardVoiceSession(c.user.ID)
 			sfuInst.RemovePeer(c.user.ID)
 			slog.Error("error sending initial offer", "component", "ws", "user_id", c.user.ID, "error", err)
-			c.send <- &WSMessage{
-				Op:   OpDispatch,
-				Type: EventError,
-				Data: ErrorPayload{
-					Code:    ErrCodeVoiceNegotiationFailed,
-					Message: "Failed to start voice negotiation",
-				},
-			}
+			c.sendError(ErrorPayload{
+				Code:    ErrCodeVoiceNegotiationFailed,
+				Message: "Failed to start voice negotiation",
+			})
 			return
 		}
 	}
 
+	c.hub.rememberCommand(c.user.ID, CmdVoiceJoin, data.Nonce)
 	slog.Info("user joined voice", "component", "ws", "user_id", c.user.ID, "muted", muted, "deafened", deafened)
 }
 
@@ -1065,13 +1383,10 @@ func (c *Client) handleVoiceLeave() {
 		return
 	}
 
-	_, removed := c.hub.RemoveUserFromVoice(c.user.ID)
-	if !removed {
+	if !c.hub.DisconnectUserFromVoice(c.user.ID) {
 		return
 	}
 
-	c.hub.cleanupVoiceForUser(c.user.ID)
-
 	slog.Info("user left voice", "component", "ws", "user_id", c.user.ID)
 }
 
@@ -1082,14 +1397,10 @@ func (c *Client) handleRtcOffer(msg *WSMessage) {
 
 	state := c.hub.GetVoiceLifecycleState(c.user.ID)
 	if state != VoiceLifecycleJoining && state != VoiceLifecycleActive {
-		c.send <- &WSMessage{
-			Op:   OpDispatch,
-			Type: EventError,
-			Data: ErrorPayload{
-				Code:    ErrCodeVoiceNegotiationInvalidState,
-				Message: "RTC offer rejected in current voice state",
-			},
-		}
+		c.sendError(ErrorPayload{
+			Code:    ErrCodeVoiceNegotiationInvalidState,
+			Message: "RTC offer rejected in current voice state",
+		})
 		return
 	}
 
@@ -1108,14 +1419,10 @@ func (c *Client) handleRtcOffer(msg *WSMessage) {
 	answerSDP, err := c.hub.HandleRtcOffer(c.user.ID, sdp)
 	if err != nil {
 		slog.Error("error handling RTC offer", "component", "ws", "user_id", c.user.ID, "error", err)
-		c.send <- &WSMessage{
-			Op:   OpDispatch,
-			Type: EventError,
-			Data: ErrorPayload{
-				Code:    ErrCodeVoiceNegotiationFailed,
-				Message: "Failed to process RTC offer",
-			},
-		}
+		c.sendError(ErrorPayload{
+			Code:    ErrCodeVoiceNegotiationFailed,
+			Message: "Failed to process RTC offer",
+		})
 		return
 	}
 
@@ -1138,14 +1445,10 @@ func (c *Client) handleRtcAnswer(msg *WSMessage) {
 
 	state := c.hub.GetVoiceLifecycleState(c.user.ID)
 	if state != VoiceLifecycleJoining && state != VoiceLifecycleActive {
-		c.send <- &WSMessage{
-			Op:   OpDispatch,
-			Type: EventError,
-			Data: ErrorPayload{
-				Code:    ErrCodeVoiceNegotiationInvalidState,
-				Message: "RTC answer rejected in current voice state",
-			},
-		}
+		c.sendError(ErrorPayload{
+			Code:    ErrCodeVoiceNegotiationInvalidState,
+			Message: "RTC answer rejected in current voice state",
+		})
 		return
 	}
 
@@ -1163,36 +1466,34 @@ func (c *Client) handleRtcAnswer(msg *WSMessage) {
 
 	if err := c.hub.HandleRtcAnswer(c.user.ID, sdp); err != nil {
 		slog.Error("error handling RTC answer", "component", "ws", "user_id", c.user.ID, "error", err)
-		c.send <- &WSMessage{
-			Op:   OpDispatch,
-			Type: EventError,
-			Data: ErrorPayload{
-				Code:    ErrCodeVoiceNegotiationFailed,
-				Message: "Failed to process RTC answer",
-			},
-		}
+		c.sendError(ErrorPayload{
+			Code:    ErrCodeVoiceNegotiationFailed,
+			Message: "Failed to process RTC answer",
+		})
 		return
 	}
 
 	if state == VoiceLifecycleJoining {
 		voiceState, err := c.hub.ActivateVoiceSession(c.user.ID)
 		if err != nil {
-			c.send <- &WSMessage{
-				Op:   OpDispatch,
-				Type: EventError,
-				Data: ErrorPayload{
-					Code:    ErrCodeVoiceStateInvalidTransition,
-					Message: "Cannot activate voice session",
-				},
-			}
+			c.sendError(ErrorPayload{
+				Code:    ErrCodeVoiceStateInvalidTransition,
+				Message: "Cannot activate voice session",
+			})
 			return
 		}
 
 		c.hub.BroadcastDispatch(EventVoiceStateUpdate, VoiceStateUpdatePayload{
-			UserID:   c.user.ID,
-			InVoice:  true,
-			Muted:    voiceState.Muted,
-			Deafened: voiceState.Deafened,
+			UserID:          c.user.ID,
+			InVoice:         true,
+			Muted:           voiceState.Muted,
+			Deafened:        voiceState.Deafened,
+			ServerMuted:     voiceState.ServerMuted,
+			ServerDeafened:  voiceState.ServerDeafened,
+			PushToTalk:      voiceState.PushToTalk,
+			Relay:           voiceState.Relay,
+			PrioritySpeaker: voiceState.PrioritySpeaker,
+			WhisperTo:       voiceState.WhisperTo,
 		})
 	}
 
@@ -1206,14 +1507,10 @@ func (c *Client) handleRtcIceCandidate(msg *WSMessage) {
 
 	state := c.hub.GetVoiceLifecycleState(c.user.ID)
 	if state != VoiceLifecycleJoining && state != VoiceLifecycleActive {
-		c.send <- &WSMessage{
-			Op:   OpDispatch,
-			Type: EventError,
-			Data: ErrorPayload{
-				Code:    ErrCodeVoiceNegotiationInvalidState,
-				Message: "ICE candidate rejected in current voice state",
-			},
-		}
+		c.sendError(ErrorPayload{
+			Code:    ErrCodeVoiceNegotiationInvalidState,
+			Message: "ICE candidate rejected in current voice state",
+		})
 		return
 	}
 
@@ -1231,14 +1528,10 @@ func (c *Client) handleRtcIceCandidate(msg *WSMessage) {
 
 	if err := c.hub.HandleRtcIceCandidate(c.user.ID, candidate, data.SDPMid, data.SDPMLineIndex); err != nil {
 		slog.Error("error handling ICE candidate", "component", "ws", "user_id", c.user.ID, "error", err)
-		c.send <- &WSMessage{
-			Op:   OpDispatch,
-			Type: EventError,
-			Data: ErrorPayload{
-				Code:    ErrCodeVoiceNegotiationFailed,
-				Message: "Failed to process ICE candidate",
-			},
-		}
+		c.sendError(ErrorPayload{
+			Code:    ErrCodeVoiceNegotiationFailed,
+			Message: "Failed to process ICE candidate",
+		})
 		return
 	}
 }
@@ -1254,14 +1547,11 @@ func (c *Client) handleVoiceStateSet(msg *WSMessage) {
 	}
 
 	if c.hub.GetVoiceLifecycleState(c.user.ID) != VoiceLifecycleActive {
-		c.send <- &WSMessage{
-			Op:   OpDispatch,
-			Type: EventError,
-			Data: ErrorPayload{
-				Code:    ErrCodeVoiceStateInvalidTransition,
-				Message: "Voice state updates require an active voice session",
-			},
-		}
+		c.sendError(ErrorPayload{
+			Code:    ErrCodeVoiceStateInvalidTransition,
+			Message: "Voice state updates require an active voice session",
+			Nonce:   data.Nonce,
+		})
 		return
 	}
 
@@ -1278,8 +1568,10 @@ func (c *Client) handleVoiceStateSet(msg *WSMessage) {
 	// Mute/deafen changes
 	muted := data.Muted
 	deafened := data.Deafened
+	pushToTalk := data.PushToTalk
 
-	if muted == nil && deafened == nil {
+	if muted == nil && deafened == nil && pushToTalk == nil {
+		c.sendCommandAck(CmdVoiceStateSet, data.Nonce)
 		return
 	}
 
@@ -1288,62 +1580,52 @@ func (c *Client) handleVoiceStateSet(msg *WSMessage) {
 	isUnmuting := muted != nil && !*muted && currentState != nil && currentState.Muted
 	isUndeafening := deafened != nil && !*deafened && currentState != nil && currentState.Deafened
 
-	// Only rate-limit unmute/undeafen; muting/deafening always goes through
-	if isUnmuting || isUndeafening {
-		now := time.Now()
-
-		// Check if currently in cooldown
-		if now.Before(c.voiceCooldownAt) {
-			c.send <- &WSMessage{
-				Op:   OpDispatch,
-				Type: EventError,
-				Data: ErrorPayload{
-					Code:       ErrCodeVoiceStateCooldown,
-					Message:    "",
-					RetryAfter: c.voiceCooldownAt.UnixMilli(),
-				},
-			}
-			return
-		}
+	// PTT users legitimately toggle transmission on every key press
+	isPushToTalk := currentState != nil && currentState.PushToTalk
+	if pushToTalk != nil {
+		isPushToTalk = *pushToTalk
+	}
 
-		// Record this toggle and prune old entries outside the window
-		cutoff := now.Add(-voiceToggleWindow)
-		filtered := c.voiceToggles[:0]
-		for _, t := range c.voiceToggles {
-			if t.After(cutoff) {
-				filtered = append(filtered, t)
-			}
-		}
-		filtered = append(filtered, now)
-		c.voiceToggles = filtered
-
-		// Check if threshold exceeded
-		if len(c.voiceToggles) >= voiceToggleLimit {
-			c.voiceCooldownAt = now.Add(voiceCooldownDuration)
-			c.voiceToggles = c.voiceToggles[:0]
-			c.send <- &WSMessage{
-				Op:   OpDispatch,
-				Type: EventError,
-				Data: ErrorPayload{
-					Code:       ErrCodeVoiceStateCooldown,
-					Message:    "",
-					RetryAfter: c.voiceCooldownAt.UnixMilli(),
-				},
-			}
+	// Only rate-limit unmute/undeafen; muting/deafening always goes through
+	if (isUnmuting || isUndeafening) && !isPushToTalk {
+		if ok, status := c.takeRateLimit(RateLimitVoiceToggle, c.hub.rateLimitRule(RateLimitVoiceToggle)); !ok {
+			c.sendError(ErrorPayload{
+				Code:       ErrCodeVoiceStateCooldown,
+				Message:    "",
+				RetryAfter: status.ResetAt,
+				Nonce:      data.Nonce,
+				RateLimit:  status,
+			})
 			return
 		}
 	}
 
 	// Process the state change
-	newState := c.hub.UpdateUserVoiceState(c.user.ID, muted, deafened)
-	if newState != nil {
-		c.hub.BroadcastDispatch(EventVoiceStateUpdate, VoiceStateUpdatePayload{
-			UserID:   c.user.ID,
-			InVoice:  true,
-			Muted:    newState.Muted,
-			Deafened: newState.Deafened,
-		})
+	newState := c.hub.UpdateUserVoiceState(c.user.ID, muted, deafened, pushToTalk)
+	if newState == nil {
+		if data.Nonce != "" {
+			c.sendError(ErrorPayload{
+				Code:    ErrCodeVoiceStateInvalidTransition,
+				Message: "Voice state updates require an active voice session",
+				Nonce:   data.Nonce,
+			})
+		}
+		return
 	}
+
+	c.hub.BroadcastDispatch(EventVoiceStateUpdate, VoiceStateUpdatePayload{
+		UserID:          c.user.ID,
+		InVoice:         true,
+		Muted:           newState.Muted,
+		Deafened:        newState.Deafened,
+		ServerMuted:     newState.ServerMuted,
+		ServerDeafened:  newState.ServerDeafened,
+		PushToTalk:      newState.PushToTalk,
+		Relay:           newState.Relay,
+		PrioritySpeaker: newState.PrioritySpeaker,
+		WhisperTo:       newState.WhisperTo,
+	})
+	c.sendCommandAck(CmdVoiceStateSet, data.Nonce)
 }
 
 func (c *Client) handleScreenShareStart() {
@@ -1353,14 +1635,18 @@ func (c *Client) handleScreenShareStart() {
 
 	// User must be in voice to screen share
 	if c.hub.GetVoiceLifecycleState(c.user.ID) != VoiceLifecycleActive {
-		c.send <- &WSMessage{
-			Op:   OpDispatch,
-			Type: EventError,
-			Data: ErrorPayload{
-				Code:    ErrCodeVoiceNotInChannel,
-				Message: "Must be in voice to screen share",
-			},
-		}
+		c.sendError(ErrorPayload{
+			Code:    ErrCodeVoiceNotInChannel,
+			Message: "Must be in voice to screen share",
+		})
+		return
+	}
+
+	if !models.RoleAtLeast(c.user.Role, c.hub.permissions.ScreenShareRole) {
+		c.sendError(ErrorPayload{
+			Code:    ErrCodeForbidden,
+			Message: "You do not have permission to screen share",
+		})
 		return
 	}
 
@@ -1371,7 +1657,18 @@ func (c *Client) handleScreenShareStart() {
 
 	// Register as sharing, then trigger server renegotiation so the client
 	// can change video direction to sendrecv and attach the track
-	sm.StartShare(c.user.ID)
+	if err := sm.StartShare(c.user.ID); err != nil {
+		var inUse *sfu.ShareInUseError
+		if errors.As(err, &inUse) {
+			c.sendError(ErrorPayload{
+				Code:    ErrCodeScreenShareInUse,
+				Message: "Another user is already sharing their screen",
+			})
+			return
+		}
+		slog.Error("error starting screen share", "component", "ws", "user_id", c.user.ID, "error", err)
+		return
+	}
 
 	sfuInst := c.hub.GetSFU()
 	if sfuInst != nil {
@@ -1401,14 +1698,10 @@ func (c *Client) handleScreenShareSubscribe(msg *WSMessage) {
 
 	// Must be in voice to subscribe to screen shares
 	if c.hub.GetVoiceLifecycleState(c.user.ID) != VoiceLifecycleActive {
-		c.send <- &WSMessage{
-			Op:   OpDispatch,
-			Type: EventError,
-			Data: ErrorPayload{
-				Code:    ErrCodeVoiceNotInChannel,
-				Message: "Must be in voice to subscribe to screen share",
-			},
-		}
+		c.sendError(ErrorPayload{
+			Code:    ErrCodeVoiceNotInChannel,
+			Message: "Must be in voice to subscribe to screen share",
+		})
 		return
 	}
 
@@ -1427,7 +1720,12 @@ func (c *Client) handleScreenShareSubscribe(msg *WSMessage) {
 		return
 	}
 
-	if err := sm.Subscribe(c.user.ID, streamerID); err != nil {
+	if err := sm.Subscribe(c.user.ID, streamerID, uint64(data.MaxBitrateKbps)*1000); errors.Is(err, sfu.ErrVideoCodecUnsupported) {
+		c.sendError(ErrorPayload{
+			Code:    ErrCodeVideoCodecUnsupported,
+			Message: "This screen share uses a video codec you did not offer",
+		})
+	} else if err != nil {
 		slog.Error("error subscribing to screen share", "component", "ws", "error", err)
 	}
 }
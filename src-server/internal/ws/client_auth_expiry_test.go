@@ -0,0 +1,80 @@
+package ws
+
+import (
+	"testing"
+	"time"
+
+	"lobby/internal/clock"
+)
+
+func TestScheduleAuthExpiryWarnsBeforeExpiry(t *testing.T) {
+	h := &Hub{clients: make(map[*Client]bool)}
+	c := newIdentifiedTestClient(h, "usr_1")
+	defer c.clearAuthExpiry()
+
+	c.scheduleAuthExpiry(time.Now().Add(time.Hour))
+	select {
+	case msg := <-c.send:
+		t.Fatalf("got %s an hour before expiry", msg.Type)
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	// A token inside the warning window is warned about right away.
+	expiresAt := time.Now().Add(authExpiryWarning / 2)
+	c.scheduleAuthExpiry(expiresAt)
+	select {
+	case msg := <-c.send:
+		payload, ok := msg.Data.(AuthExpiringPayload)
+		if msg.Type != EventAuthExpiring || !ok || payload.ExpiresAt != expiresAt.UnixMilli() {
+			t.Fatalf("expected AUTH_EXPIRING at %d, got type=%s data=%+v", expiresAt.UnixMilli(), msg.Type, msg.Data)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("no AUTH_EXPIRING inside the warning window")
+	}
+}
+
+func TestExpireAuthFollowsHubClock(t *testing.T) {
+	clk := clock.NewFake(time.Now())
+	h := &Hub{clients: make(map[*Client]bool), clock: clk}
+	c := newIdentifiedTestClient(h, "usr_1")
+	h.clients[c] = true
+
+	expiresAt := clk.Now().Add(time.Hour)
+	c.scheduleAuthExpiry(expiresAt)
+	clk.Advance(time.Hour - authExpiryWarning/2)
+	h.expireAuth()
+	select {
+	case msg := <-c.send:
+		if msg.Type != EventAuthExpiring {
+			t.Fatalf("expected AUTH_EXPIRING, got %s", msg.Type)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("no AUTH_EXPIRING after the clock entered the warning window")
+	}
+
+	// The warning goes out once per token.
+	h.expireAuth()
+	select {
+	case msg := <-c.send:
+		t.Fatalf("got a second %s", msg.Type)
+	case <-time.After(20 * time.Millisecond):
+	}
+}
+
+func TestClearAuthExpiryCancelsWarning(t *testing.T) {
+	clk := clock.NewFake(time.Now())
+	h := &Hub{clients: make(map[*Client]bool), clock: clk}
+	c := newIdentifiedTestClient(h, "usr_1")
+	h.clients[c] = true
+
+	c.scheduleAuthExpiry(clk.Now().Add(time.Hour))
+	c.clearAuthExpiry()
+	clk.Advance(time.Hour - authExpiryWarning/2)
+	h.expireAuth()
+
+	select {
+	case msg := <-c.send:
+		t.Fatalf("got %s after the expiry was cleared", msg.Type)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
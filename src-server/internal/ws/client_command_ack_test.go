@@ -0,0 +1,145 @@
+package ws
+
+import (
+	"testing"
+
+	"lobby/internal/models"
+)
+
+func newIdentifiedTestClient(h *Hub, userID string) *Client {
+	c := NewClient(h, nil)
+	c.user = &models.User{ID: userID}
+	c.state.Store(int32(ClientStateIdentified))
+	return c
+}
+
+func TestHandlePresenceSetAcksWithNonce(t *testing.T) {
+	h := &Hub{broadcast: make(chan *WSMessage, 4)}
+	c := newIdentifiedTestClient(h, "usr_1")
+
+	c.handlePresenceSet(&WSMessage{
+		Op:   OpDispatch,
+		Type: CmdPresenceSet,
+		Data: map[string]interface{}{"status": "dnd", "nonce": "n1"},
+	})
+
+	select {
+	case msg := <-c.send:
+		ack, ok := msg.Data.(CommandAckPayload)
+		if msg.Type != EventCommandAck || !ok {
+			t.Fatalf("expected %s, got type=%s data=%T", EventCommandAck, msg.Type, msg.Data)
+		}
+		if ack.Command != CmdPresenceSet || ack.Nonce != "n1" {
+			t.Fatalf("unexpected ack payload: %+v", ack)
+		}
+	default:
+		t.Fatal("expected ack to be sent")
+	}
+}
+
+func TestHandlePresenceSetWithoutNonceSendsNoAck(t *testing.T) {
+	h := &Hub{broadcast: make(chan *WSMessage, 4)}
+	c := newIdentifiedTestClient(h, "usr_1")
+
+	c.handlePresenceSet(&WSMessage{
+		Op:   OpDispatch,
+		Type: CmdPresenceSet,
+		Data: map[string]interface{}{"status": "idle"},
+	})
+
+	select {
+	case msg := <-c.send:
+		t.Fatalf("unexpected message without nonce: type=%s", msg.Type)
+	default:
+	}
+}
+
+func TestHandleVoiceStateSetRejectsWithNonceWhenNotInVoice(t *testing.T) {
+	h := &Hub{
+		voiceSessions: make(map[string]*VoiceSession),
+		broadcast:     make(chan *WSMessage, 4),
+	}
+	c := newIdentifiedTestClient(h, "usr_1")
+
+	c.handleVoiceStateSet(&WSMessage{
+		Op:   OpDispatch,
+		Type: CmdVoiceStateSet,
+		Data: map[string]interface{}{"muted": true, "nonce": "n2"},
+	})
+
+	select {
+	case msg := <-c.send:
+		payload, ok := msg.Data.(ErrorPayload)
+		if msg.Type != EventError || !ok {
+			t.Fatalf("expected %s, got type=%s", EventError, msg.Type)
+		}
+		if payload.Nonce != "n2" {
+			t.Fatalf("expected nonce %q, got %q", "n2", payload.Nonce)
+		}
+	default:
+		t.Fatal("expected error to be sent")
+	}
+}
+
+func TestHandleVoiceStateSetAcksAppliedMute(t *testing.T) {
+	h := &Hub{
+		voiceSessions: map[string]*VoiceSession{
+			"usr_1": {State: VoiceLifecycleActive},
+		},
+		broadcast: make(chan *WSMessage, 4),
+	}
+	c := newIdentifiedTestClient(h, "usr_1")
+
+	c.handleVoiceStateSet(&WSMessage{
+		Op:   OpDispatch,
+		Type: CmdVoiceStateSet,
+		Data: map[string]interface{}{"muted": true, "nonce": "n3"},
+	})
+
+	if state := h.GetUserVoiceState("usr_1"); state == nil || !state.Muted {
+		t.Fatalf("expected user to be muted, got %+v", state)
+	}
+
+	select {
+	case msg := <-c.send:
+		if msg.Type != EventCommandAck {
+			t.Fatalf("expected %s, got %s", EventCommandAck, msg.Type)
+		}
+	default:
+		t.Fatal("expected ack to be sent")
+	}
+}
+
+func TestHandleVoiceStateSetSkipsCooldownForPushToTalk(t *testing.T) {
+	h := &Hub{
+		voiceSessions: map[string]*VoiceSession{
+			"usr_1": {State: VoiceLifecycleActive, Muted: true},
+		},
+		broadcast: make(chan *WSMessage, 64),
+	}
+	c := newIdentifiedTestClient(h, "usr_1")
+
+	c.handleVoiceStateSet(&WSMessage{
+		Op:   OpDispatch,
+		Type: CmdVoiceStateSet,
+		Data: map[string]interface{}{"push_to_talk": true},
+	})
+	for i := 0; i < voiceToggleLimit*2; i++ {
+		for _, muted := range []bool{false, true} {
+			c.handleVoiceStateSet(&WSMessage{
+				Op:   OpDispatch,
+				Type: CmdVoiceStateSet,
+				Data: map[string]interface{}{"muted": muted},
+			})
+		}
+	}
+
+	select {
+	case msg := <-c.send:
+		t.Fatalf("unexpected message for push-to-talk toggles: type=%s data=%+v", msg.Type, msg.Data)
+	default:
+	}
+	if state := h.GetUserVoiceState("usr_1"); state == nil || !state.PushToTalk || !state.Muted {
+		t.Fatalf("unexpected voice state: %+v", state)
+	}
+}
@@ -1,8 +1,10 @@
 package ws
 
 import (
+	"context"
 	"testing"
 
+	"lobby/internal/externalsfu"
 	"lobby/internal/models"
 )
 
@@ -36,3 +38,55 @@ func TestHandleVoiceJoinDoesNotBroadcastInVoiceBeforeActivation(t *testing.T) {
 	default:
 	}
 }
+
+type fakeExternalSFU struct {
+	removed chan string
+}
+
+func (f *fakeExternalSFU) Provider() string { return externalsfu.ProviderLiveKit }
+
+func (f *fakeExternalSFU) Grant(userID, name string) (externalsfu.Grant, error) {
+	return externalsfu.Grant{URL: "wss://sfu.example.com", Token: "token-" + userID, Room: "lobby"}, nil
+}
+
+func (f *fakeExternalSFU) RemoveParticipant(ctx context.Context, userID string) error {
+	f.removed <- userID
+	return nil
+}
+
+func TestHandleVoiceJoinOnExternalSFU(t *testing.T) {
+	backend := &fakeExternalSFU{removed: make(chan string, 1)}
+	h := &Hub{
+		voiceSessions: make(map[string]*VoiceSession),
+		userClients:   make(map[string]*Client),
+		broadcast:     make(chan *WSMessage, 4),
+		externalSFU:   backend,
+	}
+	c := newIdentifiedTestClient(h, "usr_1")
+	h.userClients["usr_1"] = c
+
+	c.handleVoiceJoin(&WSMessage{Op: OpDispatch, Type: CmdVoiceJoin, Data: map[string]interface{}{"muted": true}})
+
+	msg := nextSent(c)
+	ready, ok := msg.Data.(RtcReadyPayload)
+	if msg.Type != EventRtcReady || !ok || ready.External == nil {
+		t.Fatalf("expected RTC_READY with an external grant, got type=%s data=%+v", msg.Type, msg.Data)
+	}
+	if ready.External.Token != "token-usr_1" || ready.External.URL != "wss://sfu.example.com" {
+		t.Fatalf("external grant = %+v", ready.External)
+	}
+	if got := h.GetVoiceLifecycleState("usr_1"); got != VoiceLifecycleActive {
+		t.Fatalf("voice lifecycle state = %q, want %q without RTC negotiation", got, VoiceLifecycleActive)
+	}
+	update := (<-h.broadcast).Data.(VoiceStateUpdatePayload)
+	if !update.InVoice || !update.Muted {
+		t.Fatalf("VOICE_STATE_UPDATE = %+v, want in voice and muted", update)
+	}
+
+	if !h.DisconnectUserFromVoice("usr_1") {
+		t.Fatal("expected user to be disconnected from voice")
+	}
+	if removed := <-backend.removed; removed != "usr_1" {
+		t.Fatalf("removed participant %q, want usr_1", removed)
+	}
+}
@@ -0,0 +1,211 @@
+package ws
+
+import (
+	"fmt"
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+)
+
+// CommandHandler applies one DISPATCH command sent by a client.
+type CommandHandler func(c *Client, msg *WSMessage)
+
+// CommandMiddleware wraps the handler registered for command. Middleware
+// short-circuits a command by returning without calling next.
+type CommandMiddleware func(command string, next CommandHandler) CommandHandler
+
+// commandRegistry maps DISPATCH types to their wrapped handlers. It is built
+// lazily so hubs constructed without NewHub still route the built-in commands.
+type commandRegistry struct {
+	once     sync.Once
+	mu       sync.RWMutex
+	handlers map[string]CommandHandler
+	stats    map[string]*commandStat
+}
+
+type commandStat struct {
+	count    int64
+	duration time.Duration
+}
+
+// CommandStat summarizes how often a DISPATCH command was handled and how
+// long its handler took in total, including middleware.
+type CommandStat struct {
+	Command       string `json:"command"`
+	Count         int64  `json:"count"`
+	TotalDuration int64  `json:"totalDurationMs"`
+}
+
+// RegisterCommand routes DISPATCH messages of type command to handler,
+// wrapped by middleware in the order given, so the first one runs first.
+// Every command is also wrapped by the hub's metrics middleware. It fails if
+// command is already registered.
+func (h *Hub) RegisterCommand(command string, handler CommandHandler, middleware ...CommandMiddleware) error {
+	h.commandRegistry()
+	return h.registerCommand(command, handler, middleware...)
+}
+
+func (h *Hub) registerCommand(command string, handler CommandHandler, middleware ...CommandMiddleware) error {
+	if command == "" || handler == nil {
+		return fmt.Errorf("registering command %q: missing type or handler", command)
+	}
+	for i := len(middleware) - 1; i >= 0; i-- {
+		handler = middleware[i](command, handler)
+	}
+	handler = h.recordCommandMetrics(command, handler)
+
+	h.commands.mu.Lock()
+	defer h.commands.mu.Unlock()
+	if _, exists := h.commands.handlers[command]; exists {
+		return fmt.Errorf("command %q is already registered", command)
+	}
+	h.commands.handlers[command] = handler
+	return nil
+}
+
+// commandRegistry returns the hub's registry, registering the built-in
+// commands on first use.
+func (h *Hub) commandRegistry() *commandRegistry {
+	h.commands.once.Do(func() {
+		h.commands.handlers = make(map[string]CommandHandler)
+		h.commands.stats = make(map[string]*commandStat)
+		h.registerBuiltinCommands()
+	})
+	return &h.commands
+}
+
+func (h *Hub) registerBuiltinCommands() {
+	rtc := hubRateLimit(RateLimitRTCSignaling)
+	screenShare := hubRateLimit(RateLimitScreenShareSignaling)
+
+	builtins := []struct {
+		command    string
+		handler    CommandHandler
+		middleware []CommandMiddleware
+	}{
+		{CmdIdentify, (*Client).handleIdentify, nil},
+		{CmdResume, (*Client).handleResume, nil},
+		{CmdMessageSend, (*Client).handleMessageSend, nil},
+		{CmdPresenceSet, (*Client).handlePresenceSet, nil},
+		{CmdTyping, ignorePayload((*Client).handleTyping), nil},
+		{CmdTypingStop, ignorePayload((*Client).handleTypingStop), nil},
+		{CmdVoiceJoin, (*Client).handleVoiceJoin, nil},
+		{CmdVoiceLeave, ignorePayload((*Client).handleVoiceLeave), nil},
+		{CmdRtcOffer, (*Client).handleRtcOffer, []CommandMiddleware{rtc}},
+		{CmdRtcAnswer, (*Client).handleRtcAnswer, []CommandMiddleware{rtc}},
+		{CmdRtcIceCandidate, (*Client).handleRtcIceCandidate, []CommandMiddleware{rtc}},
+		{CmdVoiceStateSet, (*Client).handleVoiceStateSet, nil},
+		{CmdScreenShareStart, ignorePayload((*Client).handleScreenShareStart), []CommandMiddleware{screenShare}},
+		{CmdScreenShareStop, ignorePayload((*Client).handleScreenShareStop), []CommandMiddleware{screenShare}},
+		{CmdScreenShareSubscribe, (*Client).handleScreenShareSubscribe, []CommandMiddleware{screenShare}},
+		{CmdScreenShareUnsubscribe, ignorePayload((*Client).handleScreenShareUnsubscribe), []CommandMiddleware{screenShare}},
+		{CmdCameraStart, (*Client).handleCameraStart, []CommandMiddleware{screenShare}},
+		{CmdCameraStop, ignorePayload((*Client).handleCameraStop), []CommandMiddleware{screenShare}},
+		{CmdCameraSubscribe, (*Client).handleCameraSubscribe, []CommandMiddleware{screenShare}},
+		{CmdCameraUnsubscribe, (*Client).handleCameraUnsubscribe, []CommandMiddleware{screenShare}},
+		{CmdRecordingStart, ignorePayload((*Client).handleRecordingStart), nil},
+		{CmdRecordingStop, ignorePayload((*Client).handleRecordingStop), nil},
+		{CmdVoiceRelayStart, ignorePayload((*Client).handleVoiceRelayStart), []CommandMiddleware{RequireIdentified()}},
+		{CmdVoiceWhisperStart, (*Client).handleVoiceWhisperStart, []CommandMiddleware{rtc}},
+		{CmdVoiceWhisperStop, ignorePayload((*Client).handleVoiceWhisperStop), []CommandMiddleware{rtc}},
+		{CmdRateLimitStatus, ignorePayload((*Client).handleRateLimitStatus), nil},
+	}
+	for _, builtin := range builtins {
+		if err := h.registerCommand(builtin.command, builtin.handler, builtin.middleware...); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// lookupCommand returns the handler registered for command, if any.
+func (h *Hub) lookupCommand(command string) (CommandHandler, bool) {
+	registry := h.commandRegistry()
+	registry.mu.RLock()
+	defer registry.mu.RUnlock()
+	handler, ok := registry.handlers[command]
+	return handler, ok
+}
+
+// CommandStats returns per-command counts and durations, sorted by command.
+func (h *Hub) CommandStats() []CommandStat {
+	registry := h.commandRegistry()
+	registry.mu.RLock()
+	defer registry.mu.RUnlock()
+
+	stats := make([]CommandStat, 0, len(registry.stats))
+	for command, stat := range registry.stats {
+		stats = append(stats, CommandStat{
+			Command:       command,
+			Count:         stat.count,
+			TotalDuration: stat.duration.Milliseconds(),
+		})
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Command < stats[j].Command })
+	return stats
+}
+
+func (h *Hub) recordCommandMetrics(command string, next CommandHandler) CommandHandler {
+	return func(c *Client, msg *WSMessage) {
+		start := time.Now()
+		next(c, msg)
+		elapsed := time.Since(start)
+
+		h.commands.mu.Lock()
+		stat := h.commands.stats[command]
+		if stat == nil {
+			stat = &commandStat{}
+			h.commands.stats[command] = stat
+		}
+		stat.count++
+		stat.duration += elapsed
+		h.commands.mu.Unlock()
+	}
+}
+
+// RequireIdentified drops the command unless the client has completed
+// IDENTIFY.
+func RequireIdentified() CommandMiddleware {
+	return func(command string, next CommandHandler) CommandHandler {
+		return func(c *Client, msg *WSMessage) {
+			if !c.IsIdentified() {
+				slog.Debug("dropping command from unidentified client", "component", "ws", "type", command)
+				c.nackCommand(ErrorPayload{Code: ErrCodeAuthFailed, Message: "Not identified"})
+				return
+			}
+			next(c, msg)
+		}
+	}
+}
+
+// RateLimit allows at most limit commands per window on each connection.
+// Commands wrapped with the same bucket share one allowance. Rejected
+// commands are answered with SIGNALING_RATE_LIMITED and a retry time.
+func RateLimit(bucket string, limit int, window time.Duration) CommandMiddleware {
+	return func(command string, next CommandHandler) CommandHandler {
+		return func(c *Client, msg *WSMessage) {
+			if !c.allowSignaling(command, bucket, rateLimitRule{limit: limit, window: window}) {
+				return
+			}
+			next(c, msg)
+		}
+	}
+}
+
+// DecodePayload adapts a handler taking a typed payload. Messages whose data
+// does not decode into T are logged and dropped.
+func DecodePayload[T any](handler func(c *Client, msg *WSMessage, data T)) CommandHandler {
+	return func(c *Client, msg *WSMessage) {
+		var data T
+		if !c.decodeDispatchData(msg, &data) {
+			return
+		}
+		handler(c, msg, data)
+	}
+}
+
+func ignorePayload(handler func(c *Client)) CommandHandler {
+	return func(c *Client, _ *WSMessage) {
+		handler(c)
+	}
+}
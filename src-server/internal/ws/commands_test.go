@@ -0,0 +1,104 @@
+package ws
+
+import (
+	"testing"
+	"time"
+
+	"lobby/internal/clock"
+)
+
+func TestRegisteredCommandRunsMiddleware(t *testing.T) {
+	h := &Hub{}
+	type pingPayload struct {
+		Value string `json:"value"`
+	}
+
+	var received []string
+	err := h.RegisterCommand("PLUGIN_PING", DecodePayload(func(c *Client, msg *WSMessage, data pingPayload) {
+		received = append(received, data.Value)
+	}), RequireIdentified(), RateLimit("plugin", 2, time.Minute))
+	if err != nil {
+		t.Fatalf("RegisterCommand() error = %v", err)
+	}
+	if err := h.RegisterCommand("PLUGIN_PING", ignorePayload(func(*Client) {})); err == nil {
+		t.Fatal("expected duplicate registration to fail")
+	}
+	if err := h.RegisterCommand(CmdTyping, ignorePayload(func(*Client) {})); err == nil {
+		t.Fatal("expected built-in command to be registered already")
+	}
+
+	ping := func(c *Client, value string) {
+		c.handleDispatch(&WSMessage{Op: OpDispatch, Type: "PLUGIN_PING", Data: map[string]interface{}{"value": value}})
+	}
+
+	anonymous := NewClient(h, nil)
+	ping(anonymous, "anon")
+	if len(received) != 0 {
+		t.Fatalf("received = %v, want unidentified client dropped", received)
+	}
+
+	c := newIdentifiedTestClient(h, "usr_1")
+	ping(c, "a")
+	ping(c, "b")
+	ping(c, "c")
+	if len(received) != 2 || received[0] != "a" || received[1] != "b" {
+		t.Fatalf("received = %v, want [a b]", received)
+	}
+	select {
+	case msg := <-c.send:
+		payload, ok := msg.Data.(ErrorPayload)
+		if msg.Type != EventError || !ok || payload.Code != ErrCodeSignalingRateLimited || payload.RetryAfter == 0 {
+			t.Fatalf("expected rate limit error, got type=%s data=%+v", msg.Type, msg.Data)
+		}
+	default:
+		t.Fatal("expected rate limit error")
+	}
+
+	stats := h.CommandStats()
+	if len(stats) != 1 || stats[0].Command != "PLUGIN_PING" || stats[0].Count != 4 {
+		t.Fatalf("CommandStats() = %+v, want 4 PLUGIN_PING calls", stats)
+	}
+}
+
+func TestRateLimitSharesBucket(t *testing.T) {
+	h := &Hub{}
+	c := newIdentifiedTestClient(h, "usr_1")
+
+	var calls int
+	count := func(*Client, *WSMessage) { calls++ }
+	limit := RateLimit("shared", 1, time.Minute)
+	first := limit("FIRST", count)
+	second := limit("SECOND", count)
+
+	first(c, &WSMessage{})
+	second(c, &WSMessage{})
+	if calls != 1 {
+		t.Fatalf("calls = %d, want commands on one bucket to share the limit", calls)
+	}
+}
+
+func TestRateLimitWindowFollowsHubClock(t *testing.T) {
+	fake := clock.NewFake(time.Now())
+	h := &Hub{}
+	h.SetClock(fake)
+	c := newIdentifiedTestClient(h, "usr_1")
+
+	var calls int
+	limited := RateLimit("clocked", 1, time.Minute)("CLOCKED", func(*Client, *WSMessage) { calls++ })
+
+	limited(c, &WSMessage{})
+	limited(c, &WSMessage{})
+	if calls != 1 {
+		t.Fatalf("calls = %d, want second command inside the window rejected", calls)
+	}
+	msg := <-c.send
+	if payload, ok := msg.Data.(ErrorPayload); !ok || payload.RetryAfter != fake.Now().Add(time.Minute).UnixMilli() {
+		t.Fatalf("rejection = %+v, want retry after one window", msg.Data)
+	}
+
+	fake.Advance(time.Minute)
+	limited(c, &WSMessage{})
+	if calls != 2 {
+		t.Fatalf("calls = %d, want command allowed once the window passed", calls)
+	}
+}
@@ -0,0 +1,55 @@
+package ws
+
+import "errors"
+
+var errVoiceSessionLimit = errors.New("voice session limit reached for address")
+
+// SetConnectionLimits caps identified connections and voice sessions per
+// client IP. Zero disables a limit. Must be called before Run.
+func (h *Hub) SetConnectionLimits(clientsPerIP, voiceSessionsPerIP int) {
+	h.maxClientsPerIP = clientsPerIP
+	h.maxVoiceSessionsPerIP = voiceSessionsPerIP
+}
+
+// SetRemoteIP records the resolved client address used for per-IP limits.
+// Must be called before the pumps start.
+func (c *Client) SetRemoteIP(ip string) {
+	c.remoteIP = ip
+}
+
+// withinConnectionLimitLocked reports whether client may register. A client
+// replacing the same user's existing connection does not count against it.
+func (h *Hub) withinConnectionLimitLocked(client *Client) bool {
+	if h.maxClientsPerIP <= 0 || client.remoteIP == "" || client.user == nil {
+		return true
+	}
+
+	count := 0
+	for other := range h.clients {
+		if other.remoteIP != client.remoteIP || other.user == nil || other.user.ID == client.user.ID {
+			continue
+		}
+		count++
+	}
+	return count < h.maxClientsPerIP
+}
+
+// withinVoiceLimitLocked reports whether userID may open a voice session
+// without exceeding the per-IP limit of their connection.
+func (h *Hub) withinVoiceLimitLocked(userID string) bool {
+	client := h.userClients[userID]
+	if h.maxVoiceSessionsPerIP <= 0 || client == nil || client.remoteIP == "" {
+		return true
+	}
+
+	count := 0
+	for sessionUserID := range h.voiceSessions {
+		if sessionUserID == userID {
+			continue
+		}
+		if other := h.userClients[sessionUserID]; other != nil && other.remoteIP == client.remoteIP {
+			count++
+		}
+	}
+	return count < h.maxVoiceSessionsPerIP
+}
@@ -0,0 +1,52 @@
+package ws
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestConnectionLimitPerIP(t *testing.T) {
+	h := &Hub{
+		clients:       make(map[*Client]bool),
+		userClients:   make(map[string]*Client),
+		voiceSessions: make(map[string]*VoiceSession),
+	}
+	h.SetConnectionLimits(2, 1)
+
+	register := func(userID, ip string) bool {
+		c := newIdentifiedTestClient(h, userID)
+		c.SetRemoteIP(ip)
+		if !h.withinConnectionLimitLocked(c) {
+			return false
+		}
+		if old := h.userClients[userID]; old != nil {
+			delete(h.clients, old)
+		}
+		h.clients[c] = true
+		h.userClients[userID] = c
+		return true
+	}
+
+	if !register("usr_1", "10.0.0.1") || !register("usr_2", "10.0.0.1") {
+		t.Fatal("expected the first two users from one IP to register")
+	}
+	if register("usr_3", "10.0.0.1") {
+		t.Fatal("expected a third user from the same IP to be rejected")
+	}
+	if !register("usr_1", "10.0.0.1") {
+		t.Fatal("expected a reconnect of an existing user to replace, not count")
+	}
+	if !register("usr_4", "10.0.0.2") {
+		t.Fatal("expected another IP to be unaffected")
+	}
+
+	if err := h.BeginVoiceJoin("usr_1", false, false); err != nil {
+		t.Fatalf("BeginVoiceJoin(usr_1) error = %v", err)
+	}
+	if err := h.BeginVoiceJoin("usr_2", false, false); !errors.Is(err, errVoiceSessionLimit) {
+		t.Fatalf("BeginVoiceJoin(usr_2) error = %v, want errVoiceSessionLimit", err)
+	}
+	if err := h.BeginVoiceJoin("usr_4", false, false); err != nil {
+		t.Fatalf("BeginVoiceJoin(usr_4) error = %v", err)
+	}
+}
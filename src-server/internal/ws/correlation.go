@@ -0,0 +1,68 @@
+package ws
+
+import "sync/atomic"
+
+// Command correlation. A DISPATCH command may carry an id; once its handler
+// returns the client gets ACK, or NACK if the command failed. The ERROR a
+// failing command would send is turned into the NACK, so each correlated
+// command gets exactly one answer. Commands without an id behave as before.
+// Correlated commands other than IDENTIFY and RESUME are NACKed until the
+// client is identified. An ACK only means nothing went wrong: commands the
+// server ignores, such as an empty MESSAGE_SEND, are acked too.
+//
+// ACK and NACK are what clients should rely on. COMMAND_ACK, keyed by the
+// command's nonce, is the legacy answer: it is sent only for commands that
+// carry a nonce and no id, so no command gets both.
+
+// commandCorrelation is the correlated command a client is handling.
+type commandCorrelation struct {
+	id       string
+	command  string
+	answered atomic.Bool
+}
+
+// beginCommand starts correlating msg when it carries an id and returns a
+// func that sends its ACK unless it was already NACKed.
+func (c *Client) beginCommand(msg *WSMessage) func() {
+	if msg.ID == "" {
+		return func() {}
+	}
+	corr := &commandCorrelation{id: msg.ID, command: msg.Type}
+	c.correlation.Store(corr)
+	return func() {
+		c.correlation.CompareAndSwap(corr, nil)
+		if corr.answered.CompareAndSwap(false, true) {
+			c.send <- &WSMessage{Op: OpAck, Data: AckPayload{ID: corr.id, Command: corr.command}}
+		}
+	}
+}
+
+// sendError sends an ERROR, or the NACK of the command being handled when it
+// carried an id. Only the first error of a correlated command is reported.
+func (c *Client) sendError(payload ErrorPayload) {
+	if c.correlation.Load() != nil {
+		c.nackCommand(payload)
+		return
+	}
+	c.send <- &WSMessage{Op: OpDispatch, Type: EventError, Data: payload}
+}
+
+// nackCommand answers the command being handled with NACK and reports
+// whether it did. It does nothing for commands without an id or that were
+// already answered, so silent drops can call it without changing what
+// uncorrelated clients see.
+func (c *Client) nackCommand(payload ErrorPayload) bool {
+	corr := c.correlation.Load()
+	if corr == nil || !corr.answered.CompareAndSwap(false, true) {
+		return false
+	}
+	c.send <- &WSMessage{Op: OpNack, Data: NackPayload{
+		ID:         corr.id,
+		Command:    corr.command,
+		Code:       payload.Code,
+		Message:    payload.Message,
+		RetryAfter: payload.RetryAfter,
+		RateLimit:  payload.RateLimit,
+	}}
+	return true
+}
@@ -0,0 +1,83 @@
+package ws
+
+import "testing"
+
+func TestCorrelatedCommandNackReplacesError(t *testing.T) {
+	h := &Hub{broadcast: make(chan *WSMessage, 4)}
+	c := newIdentifiedTestClient(h, "usr_1")
+
+	c.handleDispatch(&WSMessage{
+		Op:   OpDispatch,
+		Type: CmdPresenceSet,
+		Data: map[string]interface{}{"status": "away", "nonce": "n1"},
+		ID:   "c1",
+	})
+
+	if len(c.send) != 1 {
+		t.Fatalf("got %d replies, want exactly the NACK", len(c.send))
+	}
+	msg := <-c.send
+	nack, ok := msg.Data.(NackPayload)
+	if msg.Op != OpNack || !ok || nack.ID != "c1" || nack.Command != CmdPresenceSet || nack.Code != ErrCodeInvalidRequest {
+		t.Fatalf("expected NACK for c1, got op=%d data=%+v", msg.Op, msg.Data)
+	}
+}
+
+func TestCorrelatedCommandAck(t *testing.T) {
+	h := &Hub{broadcast: make(chan *WSMessage, 4)}
+	c := newIdentifiedTestClient(h, "usr_1")
+
+	c.handleDispatch(&WSMessage{
+		Op:   OpDispatch,
+		Type: CmdPresenceSet,
+		Data: map[string]interface{}{"status": "dnd"},
+		ID:   "c2",
+	})
+
+	msg := <-c.send
+	ack, ok := msg.Data.(AckPayload)
+	if msg.Op != OpAck || !ok || ack.ID != "c2" || ack.Command != CmdPresenceSet {
+		t.Fatalf("expected ACK for c2, got op=%d data=%+v", msg.Op, msg.Data)
+	}
+	if c.correlation.Load() != nil {
+		t.Fatal("correlation left set after the command")
+	}
+}
+
+func TestUncorrelatedCommandStillSendsError(t *testing.T) {
+	h := &Hub{broadcast: make(chan *WSMessage, 4)}
+	c := newIdentifiedTestClient(h, "usr_1")
+
+	c.handleDispatch(&WSMessage{
+		Op:   OpDispatch,
+		Type: CmdPresenceSet,
+		Data: map[string]interface{}{"status": "away", "nonce": "n1"},
+	})
+
+	msg := <-c.send
+	if msg.Type != EventError || len(c.send) != 0 {
+		t.Fatalf("expected a single ERROR, got type=%s and %d more", msg.Type, len(c.send))
+	}
+}
+
+func TestCorrelatedCommandWithNonceGetsOnlyAck(t *testing.T) {
+	h := &Hub{broadcast: make(chan *WSMessage, 4)}
+	c := newIdentifiedTestClient(h, "usr_1")
+
+	c.handleDispatch(&WSMessage{
+		Op:   OpDispatch,
+		Type: CmdPresenceSet,
+		Data: map[string]interface{}{"status": "dnd", "nonce": "n3"},
+		ID:   "c3",
+	})
+
+	if len(c.send) != 1 {
+		t.Fatalf("got %d replies, want exactly the ACK", len(c.send))
+	}
+	if msg := <-c.send; msg.Op != OpAck {
+		t.Fatalf("expected ACK, got op=%d type=%s", msg.Op, msg.Type)
+	}
+	if !h.isReplayedCommand("usr_1", CmdPresenceSet, "n3") {
+		t.Fatal("expected the nonce to be remembered for replay protection")
+	}
+}
@@ -0,0 +1,91 @@
+package ws
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"time"
+
+	"github.com/frisksitron/lobby/src-server/pkg/lobbyclient"
+	"github.com/gorilla/websocket"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// Gateway encodings accepted in the encoding query parameter on /ws.
+const (
+	EncodingJSON    = lobbyclient.EncodingJSON
+	EncodingMsgpack = lobbyclient.EncodingMsgpack
+)
+
+// encoding turns WSMessages into websocket frames and back.
+type encoding interface {
+	frameType() int
+	encode(msg *WSMessage) ([]byte, error)
+	decode(data []byte, msg *WSMessage) error
+}
+
+// IsValidEncoding reports whether name is a gateway encoding; "" means JSON.
+func IsValidEncoding(name string) bool {
+	return name == "" || name == EncodingJSON || name == EncodingMsgpack
+}
+
+// SetEncoding switches the connection to a gateway encoding. Must be called
+// before ReadPump and WritePump.
+func (c *Client) SetEncoding(name string) {
+	if name == EncodingMsgpack {
+		c.encoding = msgpackEncoding{}
+	} else {
+		c.encoding = nil
+	}
+}
+
+func (c *Client) codec() encoding {
+	if c.encoding == nil {
+		return jsonEncoding{}
+	}
+	return c.encoding
+}
+
+type jsonEncoding struct{}
+
+func (jsonEncoding) frameType() int { return websocket.TextMessage }
+
+func (jsonEncoding) encode(msg *WSMessage) ([]byte, error) {
+	return json.Marshal(msg)
+}
+
+func (jsonEncoding) decode(data []byte, msg *WSMessage) error {
+	return json.Unmarshal(data, msg)
+}
+
+// msgpackEncoding reads the json struct tags so both encodings share keys
+// and omitempty rules. Binary frames are also used for relay audio; those
+// start with relayFrameAudio, which no MessagePack map does.
+type msgpackEncoding struct{}
+
+func init() {
+	// Keep timestamps as RFC 3339 strings, as in JSON, rather than the
+	// MessagePack timestamp extension.
+	msgpack.Register(time.Time{}, func(e *msgpack.Encoder, v reflect.Value) error {
+		return e.EncodeString(v.Interface().(time.Time).Format(time.RFC3339Nano))
+	}, nil)
+}
+
+func (msgpackEncoding) frameType() int { return websocket.BinaryMessage }
+
+func (msgpackEncoding) encode(msg *WSMessage) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := msgpack.NewEncoder(&buf)
+	enc.SetCustomStructTag("json")
+	enc.UseCompactInts(true)
+	if err := enc.Encode(msg); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (msgpackEncoding) decode(data []byte, msg *WSMessage) error {
+	dec := msgpack.NewDecoder(bytes.NewReader(data))
+	dec.SetCustomStructTag("json")
+	return dec.Decode(msg)
+}
@@ -0,0 +1,72 @@
+package ws
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+func TestMsgpackEncodingUsesJSONKeys(t *testing.T) {
+	created := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	data, err := msgpackEncoding{}.encode(&WSMessage{
+		Op:   OpDispatch,
+		Type: EventUserUpdate,
+		Data: ReadyUser{ID: "usr_1", Username: "alice", CreatedAt: created},
+		Seq:  7,
+	})
+	if err != nil {
+		t.Fatalf("encode() error = %v", err)
+	}
+	if isRelayFrame(data) {
+		t.Fatal("encoded message looks like a relay frame")
+	}
+
+	var decoded map[string]any
+	if err := msgpack.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("unmarshal error = %v", err)
+	}
+	if decoded["t"] != EventUserUpdate {
+		t.Fatalf("t = %v, want %s", decoded["t"], EventUserUpdate)
+	}
+	payload, ok := decoded["d"].(map[string]any)
+	if !ok {
+		t.Fatalf("d = %T, want a map", decoded["d"])
+	}
+	if payload["username"] != "alice" || payload["created_at"] != created.Format(time.RFC3339Nano) {
+		t.Fatalf("payload = %+v, want username and an RFC 3339 created_at", payload)
+	}
+	if _, ok := payload["updated_at"]; ok {
+		t.Fatal("omitempty field updated_at was encoded")
+	}
+}
+
+func TestMsgpackEncodingDecodesCommands(t *testing.T) {
+	data, err := msgpack.Marshal(map[string]any{
+		"op": 0,
+		"t":  CmdMessageSend,
+		"d":  map[string]any{"content": "hello", "nonce": "n1"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := newIdentifiedTestClient(&Hub{}, "usr_1")
+	c.SetEncoding(EncodingMsgpack)
+	if c.codec().frameType() != websocket.BinaryMessage {
+		t.Fatal("msgpack connection does not use binary frames")
+	}
+
+	var msg WSMessage
+	if err := c.codec().decode(data, &msg); err != nil {
+		t.Fatalf("decode() error = %v", err)
+	}
+	var send MessageSendPayload
+	if msg.Op != OpDispatch || msg.Type != CmdMessageSend || !c.decodeDispatchData(&msg, &send) {
+		t.Fatalf("decoded %+v", msg)
+	}
+	if send.Content != "hello" || send.Nonce != "n1" {
+		t.Fatalf("payload = %+v", send)
+	}
+}
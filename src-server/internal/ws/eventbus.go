@@ -0,0 +1,178 @@
+package ws
+
+import (
+	"log/slog"
+	"sync"
+)
+
+// Topic groups related hub events so subscribers can filter what they observe.
+type Topic string
+
+const (
+	TopicMessage      Topic = "message"      // MESSAGE_CREATE, MESSAGES_PURGED
+	TopicTyping       Topic = "typing"       // TYPING_START, TYPING_STOP
+	TopicPresence     Topic = "presence"     // PRESENCE_UPDATE
+	TopicMember       Topic = "member"       // USER_JOINED, USER_LEFT, USER_UPDATE
+	TopicVoice        Topic = "voice"        // VOICE_STATE_UPDATE, VOICE_SPEAKING
+	TopicScreenShare  Topic = "screen_share" // SCREEN_SHARE_UPDATE
+	TopicVideo        Topic = "video"        // VIDEO_STATE
+	TopicRecording    Topic = "recording"    // RECORDING_STATE
+	TopicChannel      Topic = "channel"      // CHANNEL_UPDATE
+	TopicServer       Topic = "server"       // SERVER_UPDATE
+	TopicNotification Topic = "notification" // NOTIFICATION
+	TopicModeration   Topic = "moderation"   // AUTOMOD_ALERT, MOD_ALERT
+	TopicDraft        Topic = "draft"        // DRAFT_UPDATE
+)
+
+var eventTopics = map[string]Topic{
+	EventMessageCreate:     TopicMessage,
+	EventMessagesPurged:    TopicMessage,
+	EventTypingStart:       TopicTyping,
+	EventTypingStop:        TopicTyping,
+	EventPresenceUpdate:    TopicPresence,
+	EventUserJoined:        TopicMember,
+	EventUserLeft:          TopicMember,
+	EventUserUpdate:        TopicMember,
+	EventVoiceStateUpdate:  TopicVoice,
+	EventVoiceSpeaking:     TopicVoice,
+	EventScreenShareUpdate: TopicScreenShare,
+	EventVideoState:        TopicVideo,
+	EventRecordingState:    TopicRecording,
+	EventChannelUpdate:     TopicChannel,
+	EventServerUpdate:      TopicServer,
+	EventNotification:      TopicNotification,
+	EventAutomodAlert:      TopicModeration,
+	EventModAlert:          TopicModeration,
+	EventDraftUpdate:       TopicDraft,
+}
+
+// TopicForEvent returns the topic a DISPATCH event type is published under.
+// Unknown event types map to a topic named after the event type itself.
+func TopicForEvent(eventType string) Topic {
+	if topic, ok := eventTopics[eventType]; ok {
+		return topic
+	}
+	return Topic(eventType)
+}
+
+// Audience selects which websocket clients receive a published event.
+type Audience int
+
+const (
+	// AudienceAll delivers to every identified client.
+	AudienceAll Audience = iota
+	// AudienceChannel delivers only to clients allowed to access the text channel.
+	AudienceChannel
+	// AudienceUser delivers only to the client of Event.UserID.
+	AudienceUser
+	// AudienceModerators delivers only to moderators and admins.
+	AudienceModerators
+)
+
+// Event is a broadcast published through the hub.
+type Event struct {
+	Topic    Topic
+	Type     string
+	Data     interface{}
+	Audience Audience
+	// UserID is the recipient when Audience is AudienceUser.
+	UserID string
+	// Except is the originating client excluded from websocket delivery, if any.
+	Except *Client
+	// AuthorID, if set, is the user who wrote the message or is typing.
+	// Viewers who muted them do not receive the event over the websocket.
+	AuthorID string
+	// MaskedData, if set, replaces Data for clients below moderator, e.g. a
+	// message with masked words. Subscribers always see Data.
+	MaskedData interface{}
+}
+
+// Subscriber observes hub events. HandleEvent runs synchronously on the
+// publishing goroutine, so implementations must not block; hand slow work
+// (HTTP calls, disk I/O) off to a goroutine or queue.
+type Subscriber interface {
+	HandleEvent(Event)
+}
+
+// SubscriberFunc adapts a plain function to the Subscriber interface.
+type SubscriberFunc func(Event)
+
+func (f SubscriberFunc) HandleEvent(e Event) {
+	f(e)
+}
+
+type subscription struct {
+	id     uint64
+	sub    Subscriber
+	topics map[Topic]struct{} // nil means all topics
+}
+
+// EventBus fans hub events out to subscribers registered by other subsystems
+// (webhooks, push notifications, audit log, plugins).
+type EventBus struct {
+	mu     sync.RWMutex
+	subs   []subscription
+	nextID uint64
+}
+
+func NewEventBus() *EventBus {
+	return &EventBus{}
+}
+
+// Subscribe registers sub for the given topics, or for every topic if none are
+// given. The returned function removes the subscription.
+func (b *EventBus) Subscribe(sub Subscriber, topics ...Topic) func() {
+	var topicSet map[Topic]struct{}
+	if len(topics) > 0 {
+		topicSet = make(map[Topic]struct{}, len(topics))
+		for _, topic := range topics {
+			topicSet[topic] = struct{}{}
+		}
+	}
+
+	b.mu.Lock()
+	b.nextID++
+	id := b.nextID
+	b.subs = append(b.subs, subscription{id: id, sub: sub, topics: topicSet})
+	b.mu.Unlock()
+
+	return func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		for i, s := range b.subs {
+			if s.id == id {
+				b.subs = append(b.subs[:i], b.subs[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// Publish delivers e to every matching subscriber. A panicking subscriber is
+// logged and does not affect the others.
+func (b *EventBus) Publish(e Event) {
+	b.mu.RLock()
+	matched := make([]Subscriber, 0, len(b.subs))
+	for _, s := range b.subs {
+		if s.topics != nil {
+			if _, ok := s.topics[e.Topic]; !ok {
+				continue
+			}
+		}
+		matched = append(matched, s.sub)
+	}
+	b.mu.RUnlock()
+
+	for _, sub := range matched {
+		deliverEvent(sub, e)
+	}
+}
+
+func deliverEvent(sub Subscriber, e Event) {
+	defer func() {
+		if r := recover(); r != nil {
+			slog.Error("event subscriber panicked", "component", "eventbus", "topic", e.Topic, "type", e.Type, "panic", r)
+		}
+	}()
+	sub.HandleEvent(e)
+}
@@ -0,0 +1,75 @@
+package ws
+
+import "testing"
+
+func TestEventBusFiltersByTopic(t *testing.T) {
+	bus := NewEventBus()
+
+	var messages, all []Event
+	bus.Subscribe(SubscriberFunc(func(e Event) { messages = append(messages, e) }), TopicMessage)
+	bus.Subscribe(SubscriberFunc(func(e Event) { all = append(all, e) }))
+
+	bus.Publish(Event{Topic: TopicMessage, Type: EventMessageCreate})
+	bus.Publish(Event{Topic: TopicPresence, Type: EventPresenceUpdate})
+
+	if len(messages) != 1 || messages[0].Type != EventMessageCreate {
+		t.Fatalf("expected one %s event, got %+v", EventMessageCreate, messages)
+	}
+	if len(all) != 2 {
+		t.Fatalf("expected wildcard subscriber to receive 2 events, got %d", len(all))
+	}
+}
+
+func TestEventBusUnsubscribe(t *testing.T) {
+	bus := NewEventBus()
+
+	calls := 0
+	unsubscribe := bus.Subscribe(SubscriberFunc(func(Event) { calls++ }))
+	bus.Publish(Event{Topic: TopicServer})
+	unsubscribe()
+	bus.Publish(Event{Topic: TopicServer})
+
+	if calls != 1 {
+		t.Fatalf("expected 1 call, got %d", calls)
+	}
+}
+
+func TestEventBusRecoversSubscriberPanic(t *testing.T) {
+	bus := NewEventBus()
+
+	delivered := false
+	bus.Subscribe(SubscriberFunc(func(Event) { panic("boom") }))
+	bus.Subscribe(SubscriberFunc(func(Event) { delivered = true }))
+
+	bus.Publish(Event{Topic: TopicChannel})
+
+	if !delivered {
+		t.Fatal("expected subscriber after a panicking one to be called")
+	}
+}
+
+func TestHubBroadcastPublishesToSubscribers(t *testing.T) {
+	h := &Hub{
+		clients: make(map[*Client]bool),
+		events:  NewEventBus(),
+	}
+	sender := newIdentifiedTestClient(h, "usr_1")
+	h.clients[sender] = true
+
+	var got []Event
+	h.Events().Subscribe(SubscriberFunc(func(e Event) { got = append(got, e) }), TopicTyping)
+
+	h.BroadcastChannelDispatch(EventTypingStop, TypingStopPayload{UserID: "usr_1"}, sender)
+
+	if len(got) != 1 {
+		t.Fatalf("expected 1 event, got %d", len(got))
+	}
+	if got[0].Audience != AudienceChannel || got[0].Except != sender {
+		t.Fatalf("unexpected event: %+v", got[0])
+	}
+	select {
+	case msg := <-sender.send:
+		t.Fatalf("unexpected dispatch to excluded sender: type=%s", msg.Type)
+	default:
+	}
+}
@@ -0,0 +1,97 @@
+package ws
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"lobby/internal/config"
+	"lobby/internal/externalsfu"
+)
+
+// externalSFUTimeout bounds calls to the external SFU's API.
+const externalSFUTimeout = 10 * time.Second
+
+// initExternalSFU hands voice media to the SFU cfg names. Screen share,
+// cameras, relay, and recording need the built-in SFU and are unavailable.
+func (h *Hub) initExternalSFU(cfg config.ExternalSFUConfig) error {
+	backend, err := externalsfu.New(cfg.Provider, cfg.URL, cfg.APIKey, cfg.APISecret, cfg.Room)
+	if err != nil {
+		return fmt.Errorf("creating external SFU: %w", err)
+	}
+	h.externalSFU = backend
+	slog.Info("voice uses an external SFU", "component", "hub", "provider", cfg.Provider, "url", cfg.URL)
+	return nil
+}
+
+// GetExternalSFU returns the external SFU, or nil when voice runs on the
+// built-in one.
+func (h *Hub) GetExternalSFU() externalsfu.Backend {
+	return h.externalSFU
+}
+
+// removeExternalParticipant disconnects userID from the external SFU in the
+// background, so a user removed from voice state loses media too.
+func (h *Hub) removeExternalParticipant(userID string) {
+	if h.externalSFU == nil {
+		return
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), externalSFUTimeout)
+		defer cancel()
+		if err := h.externalSFU.RemoveParticipant(ctx, userID); err != nil {
+			slog.Warn("error removing external SFU participant", "component", "hub", "user_id", userID, "error", err)
+		}
+	}()
+}
+
+// joinExternalVoice finishes a VOICE_JOIN on the external SFU. The session
+// is active as soon as the client has its grant, since media never passes
+// through this server.
+func (c *Client) joinExternalVoice(backend externalsfu.Backend, nonce string) {
+	grant, err := backend.Grant(c.user.ID, c.user.Username)
+	if err != nil {
+		c.hub.DiscardVoiceSession(c.user.ID)
+		slog.Error("error granting external SFU access", "component", "ws", "user_id", c.user.ID, "error", err)
+		c.sendError(ErrorPayload{
+			Code:    ErrCodeVoiceJoinFailed,
+			Message: "Failed to join voice",
+		})
+		return
+	}
+
+	c.hub.SendDispatchToUser(c.user.ID, EventRtcReady, RtcReadyPayload{
+		ICEServers: []ICEServerInfo{},
+		External: &ExternalSFUInfo{
+			Provider: backend.Provider(),
+			URL:      grant.URL,
+			Token:    grant.Token,
+			Room:     grant.Room,
+		},
+	})
+
+	voiceState, err := c.hub.ActivateVoiceSession(c.user.ID)
+	if err != nil {
+		c.sendError(ErrorPayload{
+			Code:    ErrCodeVoiceStateInvalidTransition,
+			Message: "Cannot activate voice session",
+		})
+		return
+	}
+	c.hub.BroadcastDispatch(EventVoiceStateUpdate, VoiceStateUpdatePayload{
+		UserID:          c.user.ID,
+		InVoice:         true,
+		Muted:           voiceState.Muted,
+		Deafened:        voiceState.Deafened,
+		ServerMuted:     voiceState.ServerMuted,
+		ServerDeafened:  voiceState.ServerDeafened,
+		PushToTalk:      voiceState.PushToTalk,
+		Relay:           voiceState.Relay,
+		PrioritySpeaker: voiceState.PrioritySpeaker,
+		WhisperTo:       voiceState.WhisperTo,
+	})
+
+	c.hub.rememberCommand(c.user.ID, CmdVoiceJoin, nonce)
+	slog.Info("user joined voice on external SFU", "component", "ws", "user_id", c.user.ID, "provider", backend.Provider())
+}
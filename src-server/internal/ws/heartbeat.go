@@ -0,0 +1,91 @@
+package ws
+
+import (
+	"log/slog"
+	"sort"
+	"time"
+)
+
+// Application-level heartbeats measure round-trip latency, which websocket
+// pings do not expose to browsers. A client opts in by sending HEARTBEAT;
+// the server acks it and from then on also sends its own HEARTBEAT every
+// pingPeriod, timing the client's ack.
+const (
+	heartbeatLimit  = 5
+	heartbeatWindow = 10 * time.Second
+)
+
+// ClientLatency is the last heartbeat round trip measured for a connection.
+type ClientLatency struct {
+	UserID    string `json:"userId"`
+	LatencyMs int64  `json:"latencyMs"`
+	InVoice   bool   `json:"inVoice"`
+}
+
+func (c *Client) handleHeartbeat(msg *WSMessage) {
+	if ok, _ := c.allowCommandRateLimit(&c.heartbeats, heartbeatLimit, heartbeatWindow); !ok {
+		return
+	}
+	var data HeartbeatPayload
+	if !c.decodeDispatchData(msg, &data) {
+		return
+	}
+	c.heartbeatsEnabled.Store(true)
+	c.send <- &WSMessage{
+		Op:   OpHeartbeatAck,
+		Data: HeartbeatPayload{Ts: data.Ts, ServerTs: time.Now().UnixMilli()},
+	}
+}
+
+func (c *Client) handleHeartbeatAck(msg *WSMessage) {
+	var data HeartbeatPayload
+	if !c.decodeDispatchData(msg, &data) || data.Ts <= 0 {
+		return
+	}
+	rtt := time.Now().UnixMilli() - data.Ts
+	if rtt < 0 || rtt > int64(pongWait/time.Millisecond) {
+		slog.Debug("ignoring heartbeat ack with implausible timestamp", "component", "ws", "user_id", c.getUserID(), "rtt_ms", rtt)
+		return
+	}
+	c.latencyMs.Store(rtt)
+	c.latencyMeasured.Store(true)
+}
+
+// heartbeatDue reports whether WritePump should send the server's HEARTBEAT.
+func (c *Client) heartbeatDue() bool {
+	return c.heartbeatsEnabled.Load() && c.IsIdentified()
+}
+
+// Latency returns the last heartbeat round trip, if one has been measured.
+func (c *Client) Latency() (time.Duration, bool) {
+	if !c.latencyMeasured.Load() {
+		return 0, false
+	}
+	return time.Duration(c.latencyMs.Load()) * time.Millisecond, true
+}
+
+// ClientLatencies returns the measured latency of each identified client,
+// sorted by user ID. Clients that never sent a HEARTBEAT are left out.
+func (h *Hub) ClientLatencies() []ClientLatency {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	latencies := []ClientLatency{}
+	for client := range h.clients {
+		if client.user == nil {
+			continue
+		}
+		latency, ok := client.Latency()
+		if !ok {
+			continue
+		}
+		session := h.voiceSessions[client.user.ID]
+		latencies = append(latencies, ClientLatency{
+			UserID:    client.user.ID,
+			LatencyMs: latency.Milliseconds(),
+			InVoice:   session != nil && session.State == VoiceLifecycleActive,
+		})
+	}
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i].UserID < latencies[j].UserID })
+	return latencies
+}
@@ -0,0 +1,51 @@
+package ws
+
+import (
+	"testing"
+	"time"
+)
+
+func TestHandleHeartbeatEchoesTimestamp(t *testing.T) {
+	c := newIdentifiedTestClient(&Hub{}, "usr_1")
+	if c.heartbeatDue() {
+		t.Fatal("heartbeats due before the client opted in")
+	}
+
+	c.handleHeartbeat(&WSMessage{Op: OpHeartbeat, Data: map[string]interface{}{"ts": 1234}})
+
+	msg := <-c.send
+	payload, ok := msg.Data.(HeartbeatPayload)
+	if msg.Op != OpHeartbeatAck || !ok || payload.Ts != 1234 || payload.ServerTs == 0 {
+		t.Fatalf("expected HEARTBEAT_ACK echoing ts, got op=%d data=%+v", msg.Op, msg.Data)
+	}
+	if !c.heartbeatDue() {
+		t.Fatal("heartbeats not due after the client opted in")
+	}
+}
+
+func TestHandleHeartbeatAckRecordsLatency(t *testing.T) {
+	h := &Hub{
+		clients:       make(map[*Client]bool),
+		voiceSessions: map[string]*VoiceSession{"usr_1": {State: VoiceLifecycleActive}},
+	}
+	measured := newIdentifiedTestClient(h, "usr_1")
+	silent := newIdentifiedTestClient(h, "usr_2")
+	h.clients[measured] = true
+	h.clients[silent] = true
+
+	measured.handleHeartbeatAck(&WSMessage{Op: OpHeartbeatAck, Data: map[string]interface{}{"ts": time.Now().Add(-40 * time.Millisecond).UnixMilli()}})
+	latency, ok := measured.Latency()
+	if !ok || latency < 40*time.Millisecond || latency > time.Second {
+		t.Fatalf("Latency() = %v, %v, want about 40ms", latency, ok)
+	}
+
+	silent.handleHeartbeatAck(&WSMessage{Op: OpHeartbeatAck, Data: map[string]interface{}{"ts": time.Now().Add(time.Hour).UnixMilli()}})
+	if _, ok := silent.Latency(); ok {
+		t.Fatal("recorded latency from an ack timestamped in the future")
+	}
+
+	latencies := h.ClientLatencies()
+	if len(latencies) != 1 || latencies[0].UserID != "usr_1" || !latencies[0].InVoice {
+		t.Fatalf("ClientLatencies() = %+v, want usr_1 in voice", latencies)
+	}
+}
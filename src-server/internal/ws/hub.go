@@ -6,15 +6,21 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"slices"
 	"sync"
-	"sync/atomic"
 	"time"
 
 	"lobby/internal/auth"
+	"lobby/internal/clock"
+	"lobby/internal/cluster"
 	"lobby/internal/config"
 	"lobby/internal/constants"
 	"lobby/internal/db"
 	sqldb "lobby/internal/db/sqlc"
+	"lobby/internal/externalsfu"
+	"lobby/internal/mediaurl"
+	"lobby/internal/models"
+	"lobby/internal/recording"
 	"lobby/internal/sfu"
 )
 
@@ -25,16 +31,34 @@ const (
 	voiceJoinWatchdogTimeout           = 12 * time.Second
 )
 
-// registerRequest is used for synchronous registration with a callback
+// registerRequest is used for synchronous registration with a callback.
+// resume is set for RESUME, which claims a parked session instead of
+// starting a new one.
 type registerRequest struct {
 	client *Client
-	done   chan struct{}
+	resume *resumeRequest
+	result chan registerResult
 }
 
+type registerResult int
+
+const (
+	registerAccepted     registerResult = iota
+	registerLimited                     // would exceed the per-IP limit
+	registerNotResumable                // no parked session matched the RESUME
+	registerTimedOut                    // the hub did not answer in time
+)
+
 // VoiceState tracks a user's voice channel state
 type VoiceState struct {
-	Muted    bool
-	Deafened bool
+	Muted           bool
+	Deafened        bool
+	ServerMuted     bool
+	ServerDeafened  bool
+	PushToTalk      bool
+	Relay           bool
+	PrioritySpeaker bool
+	WhisperTo       []string
 }
 
 type VoiceLifecycleState string
@@ -47,10 +71,36 @@ const (
 )
 
 type VoiceSession struct {
-	State    VoiceLifecycleState
-	Muted    bool
-	Deafened bool
-	JoinedAt time.Time
+	State           VoiceLifecycleState
+	Muted           bool
+	Deafened        bool
+	ServerMuted     bool
+	ServerDeafened  bool
+	PushToTalk      bool
+	Relay           bool     // audio goes over the websocket instead of WebRTC
+	PrioritySpeaker bool     // at or above permissions.priority_speaker_role when joining
+	WhisperTo       []string // set while whispering to only these users
+	JoinedAt        time.Time
+}
+
+func (s *VoiceSession) voiceState() *VoiceState {
+	return &VoiceState{
+		Muted:           s.Muted,
+		Deafened:        s.Deafened,
+		ServerMuted:     s.ServerMuted,
+		ServerDeafened:  s.ServerDeafened,
+		PushToTalk:      s.PushToTalk,
+		Relay:           s.Relay,
+		PrioritySpeaker: s.PrioritySpeaker,
+		WhisperTo:       slices.Clone(s.WhisperTo),
+	}
+}
+
+// serverVoiceState is a moderator-imposed mute/deafen. It is kept outside the
+// voice session so leaving and rejoining does not clear it.
+type serverVoiceState struct {
+	muted    bool
+	deafened bool
 }
 
 func isValidVoiceTransition(from, to VoiceLifecycleState) bool {
@@ -79,10 +129,109 @@ type Hub struct {
 	database      *db.DB
 	queries       *sqldb.Queries
 	baseURL       string
+	mediaURLs     *mediaurl.Signer // signs chat attachment URLs; nil leaves them plain
+	mediaProxy    *mediaurl.Proxy  // proxies images linked in messages; nil sends no embeds
 	sfu           *sfu.SFU
+	externalSFU   externalsfu.Backend // set instead of sfu when sfu.external.provider is
 	sfuCfg        *config.SFUConfig
+	permissions   config.PermissionsConfig
 	screenShare   *sfu.ScreenShareManager
+	cameras       *sfu.CameraManager
+	events        *EventBus
 	mu            sync.RWMutex
+
+	// Multi-instance sync; backplane is nil when running standalone.
+	backplane    cluster.Backplane
+	instanceID   string
+	clusterTasks chan clusterTask
+	region       string // cluster.region, shown to clients in RTC_READY
+
+	// Voice cascade between instances; voiceFrames is nil unless enabled.
+	// stopVoice ends the voice subscription and is owned by runVoiceCascade.
+	voiceFrames   chan cluster.VoiceFrame
+	voiceListen   chan struct{}
+	stopVoice     func()
+	remoteVoiceMu sync.Mutex
+	remoteVoice   map[string]struct{} // users on other instances heard here
+
+	// Text channel access (protected by mu)
+	channelPrivate  bool
+	channelArchived bool
+	channelMembers  map[string]struct{}
+	channelSlowMode time.Duration
+	slowModeLastMsg map[string]time.Time
+
+	// Moderator voice restrictions (protected by mu)
+	serverVoice map[string]serverVoiceState
+
+	// Compiled automod rules (protected by mu)
+	automodRules []models.AutomodRule
+
+	// Active moderator timeouts by user ID (protected by mu)
+	userTimeouts map[string]time.Time
+	userMutes    map[string]map[string]struct{} // viewer -> users whose text they muted
+
+	// Members restored from the last shutdown's snapshot, shown until they
+	// reconnect or restoredUntil passes (protected by mu)
+	restoredMembers map[string]cluster.MemberRecord
+	restoredUntil   time.Time
+
+	// Words masked for members; set before Run and read-only afterwards
+	wordMask *models.WordMask
+
+	// Attachment caps for MESSAGE_SEND; set before Run, zero means none
+	uploadLimits models.UploadLimits
+
+	// Time source for rate limits, cooldowns, and replay windows; set before
+	// Run, nil means the system clock
+	clock clock.Clock
+
+	// Message ID generator; set before Run, nil means db.GenerateID
+	messageIDs db.IDGenerator
+
+	// Per-IP limits for identified clients; set before Run, 0 means unlimited
+	maxClientsPerIP       int
+	maxVoiceSessionsPerIP int
+
+	// Inactivity before identified clients are shown idle or offline; set
+	// before Run, 0 disables
+	idleAfter    time.Duration
+	offlineAfter time.Duration
+
+	// Command budget overrides by rate limit bucket; set before Run
+	rateLimits map[string]config.CommandRateLimit
+
+	// Recently applied command nonces, for dropping client retries
+	replayMu        sync.Mutex
+	appliedCommands map[commandKey]time.Time
+	replayPrunedAt  time.Time
+
+	// DISPATCH command handlers and their metrics
+	commands commandRegistry
+
+	// Sessions of disconnected clients kept for RESUME, by user ID
+	// (protected by mu)
+	detachedSessions map[string]*resumeSession
+
+	// Users shown as typing, by user ID
+	typingMu sync.Mutex
+	typing   map[string]typingState
+
+	// Member change log for MEMBERS_GET deltas
+	memberLogMu sync.Mutex
+	memberSeq   uint64
+	memberLog   []memberChange
+
+	// Voice recording; files and cfg are set before Run, nil files
+	// disables it. recording is the running one, if any.
+	recordingFiles recording.FileCreator
+	recordingCfg   config.RecordingConfig
+	recordingMu    sync.Mutex
+	recording      *recording.Session
+
+	// Latest voice connection stats, sampled on every janitor tick
+	voiceStatsMu sync.Mutex
+	voiceStats   []sfu.PeerStats
 }
 
 func NewHub(
@@ -90,6 +239,7 @@ func NewHub(
 	database *db.DB,
 	queries *sqldb.Queries,
 	sfuCfg *config.SFUConfig,
+	permissions config.PermissionsConfig,
 	baseURL string,
 ) (*Hub, error) {
 	h := &Hub{
@@ -105,13 +255,50 @@ func NewHub(
 		queries:       queries,
 		baseURL:       baseURL,
 		sfuCfg:        sfuCfg,
+		permissions:   permissions,
+		events:        NewEventBus(),
+	}
+	h.events.Subscribe(SubscriberFunc(h.recordMemberEvent), TopicPresence, TopicMember, TopicVoice, TopicScreenShare, TopicVideo)
+	h.events.Subscribe(SubscriberFunc(h.routeNotifications), TopicMessage)
+
+	if sfuCfg.External.Provider != "" {
+		if err := h.initExternalSFU(sfuCfg.External); err != nil {
+			return nil, err
+		}
+	} else if err := h.initBuiltinSFU(sfuCfg); err != nil {
+		return nil, err
+	}
+
+	if err := h.ReloadChannelAccess(context.Background()); err != nil {
+		return nil, fmt.Errorf("loading text channel access: %w", err)
+	}
+	if err := h.reloadAutomodRules(context.Background()); err != nil {
+		return nil, fmt.Errorf("loading automod rules: %w", err)
+	}
+	if err := h.reloadUserTimeouts(context.Background()); err != nil {
+		return nil, fmt.Errorf("loading user timeouts: %w", err)
+	}
+	if err := h.loadAllUserMutes(context.Background()); err != nil {
+		return nil, fmt.Errorf("loading user mutes: %w", err)
+	}
+	if err := h.restoreMemberSnapshot(context.Background(), h.now().UTC()); err != nil {
+		slog.Warn("error restoring member snapshot", "component", "hub", "error", err)
 	}
 
-	// Initialize SFU
+	return h, nil
+}
+
+// initBuiltinSFU starts the pion SFU with its screen share and camera
+// managers.
+func (h *Hub) initBuiltinSFU(sfuCfg *config.SFUConfig) error {
 	sfuConfig := &sfu.Config{
-		PublicIP: sfuCfg.PublicIP,
-		MinPort:  sfuCfg.MinPort,
-		MaxPort:  sfuCfg.MaxPort,
+		PublicIP:         sfuCfg.PublicIP,
+		MinPort:          sfuCfg.MinPort,
+		MaxPort:          sfuCfg.MaxPort,
+		UDPPort:          sfuCfg.UDPPort,
+		TCPPort:          sfuCfg.TCPPort,
+		ICELite:          sfuCfg.ICELite,
+		KeyframeInterval: sfuCfg.KeyframeInterval,
 	}
 	if sfuCfg.TURN.Host != "" {
 		sfuConfig.STUNUrl = fmt.Sprintf("stun:%s:%d", sfuCfg.TURN.Host, sfuCfg.TURN.Port)
@@ -119,7 +306,7 @@ func NewHub(
 
 	sfuInstance, err := sfu.New(sfuConfig)
 	if err != nil {
-		return nil, fmt.Errorf("creating SFU: %w", err)
+		return fmt.Errorf("creating SFU: %w", err)
 	}
 	h.sfu = sfuInstance
 	h.sfu.SetSignalingCallback(h.handleSfuSignaling)
@@ -128,15 +315,85 @@ func NewHub(
 	// Initialize screen share manager
 	h.screenShare = sfu.NewScreenShareManager(sfuInstance)
 	h.screenShare.SetUpdateCallback(h.handleScreenShareUpdate)
+	h.screenShare.SetSingleShare(sfuCfg.SingleScreenShare)
+	h.screenShare.SetMaxViewerBitrate(uint64(sfuCfg.MaxViewerBitrateKbps) * 1000)
+	h.screenShare.SetViewerRampInterval(sfuCfg.ViewerRampInterval)
 	sfuInstance.SetScreenShareManager(h.screenShare)
 	slog.Info("screenshare manager initialized", "component", "hub")
 
-	return h, nil
+	h.cameras = sfu.NewCameraManager(sfuInstance)
+	h.cameras.SetUpdateCallback(h.handleCameraUpdate)
+	sfuInstance.SetCameraManager(h.cameras)
+	return nil
+}
+
+// SetClock replaces the clock behind command rate limits, voice cooldowns,
+// moderator timeouts, and replay windows. Must be called before Run.
+func (h *Hub) SetClock(c clock.Clock) {
+	h.clock = c
+}
+
+func (h *Hub) now() time.Time {
+	if h.clock == nil {
+		return time.Now()
+	}
+	return h.clock.Now()
+}
+
+// SetIDFormat selects how message IDs are generated (db.IDFormatRandom or
+// db.IDFormatULID). Must be called before Run.
+func (h *Hub) SetIDFormat(format string) {
+	h.messageIDs = db.NewIDGenerator(format)
+}
+
+// SetMediaSigner makes the hub sign the chat attachment URLs it sends.
+// Must be called before Run.
+func (h *Hub) SetMediaSigner(signer *mediaurl.Signer) {
+	h.mediaURLs = signer
+}
+
+// SetMediaProxy makes messages carry proxied embeds for the images they
+// link to. Must be called before Run.
+func (h *Hub) SetMediaProxy(proxy *mediaurl.Proxy) {
+	h.mediaProxy = proxy
+}
+
+// messageEmbeds returns the proxied images linked in content. Callers
+// pass the content as its reader sees it, so masked links are left out.
+func (h *Hub) messageEmbeds(content string) []MessageEmbed {
+	if h.mediaProxy == nil {
+		return nil
+	}
+	links := mediaurl.ImageLinks(content)
+	if len(links) == 0 {
+		return nil
+	}
+	embeds := make([]MessageEmbed, 0, len(links))
+	for _, link := range links {
+		embeds = append(embeds, MessageEmbed{URL: link, ProxyURL: h.mediaProxy.URL(h.baseURL, link)})
+	}
+	return embeds
+}
+
+func (h *Hub) newMessageID() (string, error) {
+	if h.messageIDs == nil {
+		return db.GenerateID("msg")
+	}
+	return h.messageIDs("msg")
 }
 
 func (h *Hub) Run() {
 	watchdogTicker := time.NewTicker(voiceJoinWatchdogInterval)
 	defer watchdogTicker.Stop()
+	janitorTicker := time.NewTicker(janitorInterval)
+	defer janitorTicker.Stop()
+
+	if h.backplane != nil {
+		go h.runCluster()
+	}
+	if h.voiceFrames != nil {
+		go h.runVoiceCascade()
+	}
 
 	for {
 		select {
@@ -147,6 +404,7 @@ func (h *Hub) Run() {
 				delete(h.clients, client)
 			}
 			h.mu.Unlock()
+			h.stopRecording("shutdown")
 			if h.sfu != nil {
 				h.sfu.Close()
 			}
@@ -155,12 +413,30 @@ func (h *Hub) Run() {
 
 		case req := <-h.registerSync:
 			h.mu.Lock()
+			if !h.withinConnectionLimitLocked(req.client) {
+				h.mu.Unlock()
+				req.result <- registerLimited
+				continue
+			}
+			if req.resume != nil {
+				if !h.claimSessionLocked(req.client, req.resume) {
+					h.mu.Unlock()
+					req.result <- registerNotResumable
+					continue
+				}
+			} else if req.client.user != nil {
+				delete(h.detachedSessions, req.client.user.ID)
+				req.client.resume = newResumeSession(req.client.sessionID, req.client.user.ID)
+			}
 			h.clients[req.client] = true
 			wasInVoice := false
 			shouldBroadcastOnline := false
 			var replacedUserID string
+			var restoredRec cluster.MemberRecord
+			wasRestored := false
 			if req.client.user != nil {
 				replacedUserID = req.client.user.ID
+				restoredRec, wasRestored = h.takeRestoredMemberLocked(replacedUserID)
 				if old, ok := h.userClients[replacedUserID]; ok && old != req.client {
 					// Notify old client before closing so it knows not to retry
 					select {
@@ -183,55 +459,37 @@ func (h *Hub) Run() {
 				h.cleanupVoiceForUser(replacedUserID)
 			}
 
-			close(req.done)
+			req.result <- registerAccepted
 
-			if req.client.user != nil && shouldBroadcastOnline {
-				h.broadcastPresenceUpdate(req.client.user.ID, req.client.GetStatus(), req.client)
-			}
-
-		case client := <-h.unregister:
-			h.mu.Lock()
-			wasInVoice := false
-			wasActiveClient := false
-			var userID string
-			if client.user != nil {
-				userID = client.user.ID
-				wasActiveClient = h.userClients[userID] == client
-				// Only clean up voice if this is still the active client
-				// (not already replaced by registerSync)
-				if wasActiveClient {
-					if _, inVoice := h.removeVoiceSessionLocked(userID); inVoice {
-						wasInVoice = true
-					}
-				}
-			}
-			if _, ok := h.clients[client]; ok {
-				delete(h.clients, client)
-				if client.user != nil {
-					if h.userClients[client.user.ID] == client {
-						delete(h.userClients, client.user.ID)
-					}
-				}
-				client.CloseSend()
+			if wasRestored {
+				h.retireRestoredMember(restoredRec, cluster.MemberRecord{}, true)
 			}
-			h.mu.Unlock()
 
-			if wasInVoice {
-				h.cleanupVoiceForUser(userID)
+			if req.client.user != nil && shouldBroadcastOnline {
+				h.broadcastPresenceUpdate(req.client.presenceUpdate(), req.client)
 			}
 
-			if client.user != nil && wasActiveClient {
-				if _, err := h.queries.GetActiveUserByID(context.Background(), client.user.ID); err == nil {
-					h.broadcastPresenceUpdate(client.user.ID, "offline", nil)
-				} else if !errors.Is(err, sql.ErrNoRows) {
-					slog.Error("error loading user on disconnect", "component", "hub", "error", err, "user_id", client.user.ID)
-				}
-			}
+		case client := <-h.unregister:
+			h.handleUnregister(client)
+
+		case <-janitorTicker.C:
+			h.closeStalledClients()
+			h.expireAuth()
+			h.endRestoreGrace()
+			h.pruneDetachedSessions()
+			h.applyAutoPresence()
+			h.expireTyping()
+			h.pruneSlowMode()
+			h.expireRecording()
+			h.sendVoiceStats()
 
 		case message := <-h.broadcast:
 			h.mu.RLock()
 			for client := range h.clients {
-				h.sendToClientLocked(client, message)
+				h.deliverLocked(client, message)
+			}
+			for _, sess := range h.detachedSessions {
+				sess.record(message)
 			}
 			h.mu.RUnlock()
 
@@ -249,7 +507,53 @@ func (h *Hub) Run() {
 	}
 }
 
-// Caller must hold at least a read lock on h.mu.
+// handleUnregister removes client from the hub and cleans up its voice
+// session and presence. It is safe to call more than once for a client.
+// Must run on the Run goroutine.
+func (h *Hub) handleUnregister(client *Client) {
+	h.mu.Lock()
+	wasInVoice := false
+	wasActiveClient := false
+	var userID string
+	if client.user != nil {
+		userID = client.user.ID
+		wasActiveClient = h.userClients[userID] == client
+		// Only clean up voice if this is still the active client
+		// (not already replaced by registerSync)
+		if wasActiveClient {
+			if _, inVoice := h.removeVoiceSessionLocked(userID); inVoice {
+				wasInVoice = true
+			}
+			h.detachSessionLocked(client)
+		}
+	}
+	if _, ok := h.clients[client]; ok {
+		delete(h.clients, client)
+		if client.user != nil {
+			if h.userClients[client.user.ID] == client {
+				delete(h.userClients, client.user.ID)
+			}
+		}
+		client.CloseSend()
+	}
+	h.mu.Unlock()
+
+	h.stopTyping(client)
+	if wasInVoice {
+		h.cleanupVoiceForUser(userID)
+	}
+
+	if client.user != nil && wasActiveClient {
+		if _, err := h.queries.GetActiveUserByID(context.Background(), client.user.ID); err == nil {
+			h.broadcastPresenceUpdate(PresenceUpdatePayload{UserID: client.user.ID, Status: "offline"}, nil)
+		} else if !errors.Is(err, sql.ErrNoRows) {
+			slog.Error("error loading user on disconnect", "component", "hub", "error", err, "user_id", client.user.ID)
+		}
+	}
+}
+
+// sendToClientLocked queues a direct message, such as RTC signaling, on the
+// client's priority queue. Caller must hold at least a read lock on h.mu.
 func (h *Hub) sendToClientLocked(client *Client, msg *WSMessage) {
 	if !client.IsIdentified() {
 		return
@@ -258,71 +562,277 @@ func (h *Hub) sendToClientLocked(client *Client, msg *WSMessage) {
 	case client.send <- msg:
 		// Message sent successfully
 	default:
-		// Client buffer full - track the drop
-		dropped := atomic.AddInt64(&client.DroppedMessages, 1)
-		userID := "unknown"
-		if client.user != nil {
-			userID = client.user.ID
-		}
+		// Signaling is never dropped; a client this far behind reconnects
+		// instead. Close will be handled by the client's pumps.
+		slog.Warn("disconnecting client with full signaling queue", "component", "hub", "user_id", client.getUserID(), "type", msg.Type)
+		client.Close()
+	}
+}
 
-		// Log warning periodically (every 10 drops)
-		if dropped%10 == 1 {
-			slog.Warn("dropped messages for slow client", "component", "hub", "dropped", dropped, "user_id", userID)
-		}
+// Events returns the hub's event bus for subsystems that observe broadcasts.
+func (h *Hub) Events() *EventBus {
+	return h.events
+}
 
-		// Disconnect clients that fall too far behind
-		if dropped >= maxDroppedMessagesBeforeDisconnect {
-			slog.Warn("disconnecting slow client", "component", "hub", "user_id", userID, "dropped", dropped)
-			// Close will be handled by the client's pumps
-			client.Close()
-		}
+// Publish delivers e to the websocket clients selected by its audience and then
+// to event bus subscribers. All hub broadcasts go through here.
+func (h *Hub) Publish(e Event) {
+	if e.Audience == AudienceAll && e.Except == nil && e.MaskedData == nil && e.AuthorID == "" {
+		h.broadcast <- dispatchMessage(e)
+	} else {
+		h.deliverToClients(e)
 	}
+	h.notifySubscribers(e)
 }
 
-func (h *Hub) Broadcast(msg *WSMessage) {
-	h.broadcast <- msg
+// publishFromRun is Publish for the Run goroutine, which must not enqueue onto
+// its own broadcast channel.
+func (h *Hub) publishFromRun(e Event) {
+	h.deliverToClients(e)
+	h.notifySubscribers(e)
 }
 
-// BroadcastDispatch sends a DISPATCH message to all clients.
-func (h *Hub) BroadcastDispatch(eventType string, data interface{}) {
-	msg := &WSMessage{
-		Op:   OpDispatch,
-		Type: eventType,
-		Data: data,
+func (h *Hub) deliverToClients(e Event) {
+	msg := dispatchMessage(e)
+	maskedMsg := msg
+	if e.MaskedData != nil {
+		maskedMsg = &WSMessage{Op: OpDispatch, Type: e.Type, Data: e.MaskedData}
 	}
-	h.broadcast <- msg
-}
 
-// BroadcastExcept sends a message to all clients except the specified one
-func (h *Hub) BroadcastExcept(msg *WSMessage, except *Client) {
 	h.mu.RLock()
 	defer h.mu.RUnlock()
 
 	for client := range h.clients {
-		if client == except {
+		if client == e.Except {
 			continue
 		}
-		h.sendToClientLocked(client, msg)
+		include, isModerator := h.audienceIncludesLocked(e, client.user, client.HasScope(models.ScopeMessagesRead))
+		if !include {
+			continue
+		}
+		if isModerator {
+			h.deliverLocked(client, msg)
+		} else {
+			h.deliverLocked(client, maskedMsg)
+		}
+	}
+	for _, sess := range h.detachedSessions {
+		include, isModerator := h.audienceIncludesLocked(e, sess.user, sess.hasScope(models.ScopeMessagesRead))
+		if !include {
+			continue
+		}
+		if isModerator {
+			sess.record(msg)
+		} else {
+			sess.record(maskedMsg)
+		}
+	}
+}
+
+// audienceIncludesLocked reports whether e goes to user and whether they get
+// the unmasked moderator copy. Caller must hold at least a read lock on h.mu.
+func (h *Hub) audienceIncludesLocked(e Event, user *models.User, canReadMessages bool) (include, isModerator bool) {
+	if e.Audience == AudienceChannel && (!h.canAccessChannelLocked(user) || !canReadMessages) {
+		return false, false
+	}
+	if e.Audience == AudienceUser && (user == nil || user.ID != e.UserID) {
+		return false, false
+	}
+	if e.AuthorID != "" && user != nil && h.hasMutedLocked(user.ID, e.AuthorID) {
+		return false, false
+	}
+	isModerator = user != nil && models.RoleAtLeast(user.Role, models.RoleModerator)
+	if e.Audience == AudienceModerators && !isModerator {
+		return false, false
+	}
+	return true, isModerator
+}
+
+func (h *Hub) notifySubscribers(e Event) {
+	if h.events != nil {
+		h.events.Publish(e)
+	}
+}
+
+func dispatchMessage(e Event) *WSMessage {
+	return &WSMessage{
+		Op:   OpDispatch,
+		Type: e.Type,
+		Data: e.Data,
 	}
 }
 
+// BroadcastDispatch sends a DISPATCH message to all clients.
+func (h *Hub) BroadcastDispatch(eventType string, data interface{}) {
+	h.Publish(Event{Topic: TopicForEvent(eventType), Type: eventType, Data: data})
+}
+
 // BroadcastDispatchExcept sends a DISPATCH to all clients except one
 func (h *Hub) BroadcastDispatchExcept(eventType string, data interface{}, except *Client) {
-	msg := &WSMessage{
-		Op:   OpDispatch,
-		Type: eventType,
-		Data: data,
+	h.Publish(Event{Topic: TopicForEvent(eventType), Type: eventType, Data: data, Except: except})
+}
+
+// BroadcastChannelDispatch sends a text-channel DISPATCH (messages, typing) to
+// every client allowed to access the channel. If except is not nil, that
+// client won't receive the message.
+func (h *Hub) BroadcastChannelDispatch(eventType string, data interface{}, except *Client) {
+	h.Publish(Event{
+		Topic:    TopicForEvent(eventType),
+		Type:     eventType,
+		Data:     data,
+		Audience: AudienceChannel,
+		Except:   except,
+	})
+}
+
+// broadcastTyping sends TYPING_START/TYPING_STOP from c to the text channel,
+// skipping viewers who muted c's user.
+func (h *Hub) broadcastTyping(eventType string, data interface{}, c *Client) {
+	h.Publish(Event{
+		Topic:    TopicForEvent(eventType),
+		Type:     eventType,
+		Data:     data,
+		Audience: AudienceChannel,
+		Except:   c,
+		AuthorID: c.getUserID(),
+	})
+}
+
+// PublishToUser sends a DISPATCH to every connection of userID, on any
+// instance.
+func (h *Hub) PublishToUser(userID string, eventType string, data interface{}) {
+	h.Publish(Event{
+		Topic:    TopicForEvent(eventType),
+		Type:     eventType,
+		Data:     data,
+		Audience: AudienceUser,
+		UserID:   userID,
+	})
+}
+
+// PublishToModerators sends a DISPATCH to every connected moderator and admin,
+// on any instance.
+func (h *Hub) PublishToModerators(eventType string, data interface{}) {
+	h.Publish(Event{
+		Topic:    TopicForEvent(eventType),
+		Type:     eventType,
+		Data:     data,
+		Audience: AudienceModerators,
+	})
+}
+
+// ReloadChannelAccess refreshes the cached private/archived flags and invite
+// list of the text channel, and tells other instances to do the same.
+func (h *Hub) ReloadChannelAccess(ctx context.Context) error {
+	if err := h.reloadChannelAccess(ctx); err != nil {
+		return err
+	}
+	if h.backplane != nil {
+		h.enqueueClusterTask(clusterTask{env: &cluster.Envelope{
+			Instance: h.instanceID,
+			Type:     cluster.TypeChannelAccess,
+		}})
+	}
+	return nil
+}
+
+func (h *Hub) reloadChannelAccess(ctx context.Context) error {
+	channel, err := h.queries.GetTextChannel(ctx)
+	if err != nil {
+		return err
+	}
+	memberIDs, err := h.queries.ListTextChannelMemberIDs(ctx)
+	if err != nil {
+		return err
 	}
 
+	members := make(map[string]struct{}, len(memberIDs))
+	for _, userID := range memberIDs {
+		members[userID] = struct{}{}
+	}
+
+	h.mu.Lock()
+	h.channelPrivate = channel.Private
+	h.channelArchived = channel.Archived
+	h.channelMembers = members
+	h.channelSlowMode = time.Duration(channel.SlowModeSeconds) * time.Second
+	h.mu.Unlock()
+	return nil
+}
+
+// IsChannelArchived reports whether the text channel is archived (read-only).
+func (h *Hub) IsChannelArchived() bool {
 	h.mu.RLock()
 	defer h.mu.RUnlock()
+	return h.channelArchived
+}
 
-	for client := range h.clients {
-		if client == except {
-			continue
+// CheckSlowMode enforces the text channel slow mode for user. It returns true
+// when the user may post, or false and the time the cooldown ends. Moderators
+// and admins are exempt. The cooldown starts with RecordSlowMode, once a
+// message is stored.
+func (h *Hub) CheckSlowMode(user *models.User, now time.Time) (bool, time.Time) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	if !h.slowModeAppliesLocked(user) {
+		return true, time.Time{}
+	}
+	if last, ok := h.slowModeLastMsg[user.ID]; ok {
+		if retryAt := last.Add(h.channelSlowMode); now.Before(retryAt) {
+			return false, retryAt
 		}
-		h.sendToClientLocked(client, msg)
 	}
+	return true, time.Time{}
+}
+
+// RecordSlowMode starts user's slow mode cooldown at now, the time their
+// message was stored.
+func (h *Hub) RecordSlowMode(user *models.User, now time.Time) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if !h.slowModeAppliesLocked(user) {
+		return
+	}
+	if h.slowModeLastMsg == nil {
+		h.slowModeLastMsg = make(map[string]time.Time)
+	}
+	h.slowModeLastMsg[user.ID] = now
+}
+
+// Caller must hold at least a read lock on h.mu.
+func (h *Hub) slowModeAppliesLocked(user *models.User) bool {
+	return h.channelSlowMode > 0 && user != nil && !models.RoleAtLeast(user.Role, models.RoleModerator)
+}
+
+// pruneSlowMode forgets cooldowns that have ended. It runs on the janitor
+// tick.
+func (h *Hub) pruneSlowMode() {
+	now := h.now()
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for userID, last := range h.slowModeLastMsg {
+		if !now.Before(last.Add(h.channelSlowMode)) {
+			delete(h.slowModeLastMsg, userID)
+		}
+	}
+}
+
+// CanAccessChannel reports whether user may read and post in the text channel.
+func (h *Hub) CanAccessChannel(user *models.User) bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.canAccessChannelLocked(user)
+}
+
+// Caller must hold at least a read lock on h.mu.
+func (h *Hub) canAccessChannelLocked(user *models.User) bool {
+	if user == nil {
+		return false
+	}
+	_, invited := h.channelMembers[user.ID]
+	return models.CanAccessTextChannel(user.Role, h.channelPrivate, invited)
 }
 
 func (h *Hub) SendToUser(userID string, msg *WSMessage) {
@@ -340,54 +850,104 @@ func (h *Hub) GetMemberSnapshot() []MemberState {
 		slog.Error("error building member snapshot", "component", "hub", "error", err)
 		return []MemberState{}
 	}
+	remote := h.remoteMembers()
+	now := h.now()
 
 	h.mu.RLock()
 	defer h.mu.RUnlock()
 
 	members := make([]MemberState, 0, len(users))
 	for _, user := range users {
-		status := "offline"
+		status, statusText, statusEmoji := "offline", "", ""
+		remoteRec, onRemote := remote[user.ID]
+		if !onRemote {
+			// Right after a restart, members from the snapshot stand in for
+			// clients that have not reconnected yet.
+			remoteRec, onRemote = h.restoredMemberLocked(user.ID, now)
+		}
 		if client, ok := h.userClients[user.ID]; ok && client.IsIdentified() {
-			status = client.GetStatus()
+			presence := client.presenceUpdate()
+			status, statusText, statusEmoji = presence.Status, presence.StatusText, presence.StatusEmoji
+		} else if onRemote {
+			status, statusText, statusEmoji = remoteRec.Status, remoteRec.StatusText, remoteRec.StatusEmoji
 		}
 
 		inVoice := false
-		muted := false
-		deafened := false
+		var voice VoiceState
 		if session, ok := h.voiceSessions[user.ID]; ok {
 			if session.State == VoiceLifecycleJoining || session.State == VoiceLifecycleActive {
 				inVoice = true
-				muted = session.Muted
-				deafened = session.Deafened
+				voice = *session.voiceState()
+			}
+		} else if onRemote && remoteRec.InVoice {
+			inVoice = true
+			voice = VoiceState{
+				Muted:          remoteRec.Muted,
+				Deafened:       remoteRec.Deafened,
+				ServerMuted:    remoteRec.ServerMuted,
+				ServerDeafened: remoteRec.ServerDeafened,
+				PushToTalk:     remoteRec.PushToTalk,
 			}
 		}
 
-		streaming := false
-		if h.screenShare != nil {
-			streaming = h.screenShare.IsStreaming(user.ID)
+		streaming := onRemote && remoteRec.Streaming
+		if h.screenShare != nil && h.screenShare.IsStreaming(user.ID) {
+			streaming = true
+		}
+		camera := onRemote && remoteRec.Camera
+		if h.cameras != nil && h.cameras.IsPublishing(user.ID) {
+			camera = true
 		}
 
-		avatar := ""
+		avatar, avatarAnimated := "", ""
 		if user.AvatarUrl != nil {
 			avatar = *user.AvatarUrl
 		}
+		if user.AvatarAnimatedUrl != nil {
+			avatarAnimated = *user.AvatarAnimatedUrl
+		}
 
 		members = append(members, MemberState{
-			ID:        user.ID,
-			Username:  user.Username,
-			Avatar:    avatar,
-			Status:    status,
-			InVoice:   inVoice,
-			Muted:     muted,
-			Deafened:  deafened,
-			Streaming: streaming,
-			CreatedAt: user.CreatedAt,
+			ID:              user.ID,
+			Username:        user.Username,
+			Avatar:          avatar,
+			AvatarAnimated:  avatarAnimated,
+			Status:          status,
+			StatusText:      statusText,
+			StatusEmoji:     statusEmoji,
+			InVoice:         inVoice,
+			Muted:           voice.Muted,
+			Deafened:        voice.Deafened,
+			ServerMuted:     voice.ServerMuted,
+			ServerDeafened:  voice.ServerDeafened,
+			PushToTalk:      voice.PushToTalk,
+			Relay:           voice.Relay,
+			Streaming:       streaming,
+			PrioritySpeaker: inVoice && h.isPrioritySpeakerRole(user.Role),
+			Camera:          camera,
+			Role:            user.Role,
+			CreatedAt:       user.CreatedAt,
+			Bot:             user.Bot,
 		})
 	}
 
 	return members
 }
 
+// GetChannelInfo loads the current text channel metadata for READY, or nil on
+// failure. An archived channel is omitted unless includeArchived is set.
+func (h *Hub) GetChannelInfo(includeArchived bool) *ChannelInfo {
+	row, err := h.queries.GetTextChannel(context.Background())
+	if err != nil {
+		slog.Error("error loading text channel", "component", "hub", "error", err)
+		return nil
+	}
+	if row.Archived && !includeArchived {
+		return nil
+	}
+	return NewChannelInfo(row)
+}
+
 func (h *Hub) GetClient(userID string) *Client {
 	h.mu.RLock()
 	defer h.mu.RUnlock()
@@ -402,27 +962,15 @@ func (h *Hub) IsUserOnline(userID string) bool {
 }
 
 // If except is not nil, that client won't receive the message
-func (h *Hub) broadcastPresenceUpdate(userID string, status string, except *Client) {
-	msg := &WSMessage{
-		Op:   OpDispatch,
-		Type: EventPresenceUpdate,
-		Data: PresenceUpdatePayload{
-			UserID: userID,
-			Status: status,
-		},
-	}
-
-	h.mu.RLock()
-	for client := range h.clients {
-		// Don't send to the excluded client
-		if except != nil && client == except {
-			continue
-		}
-		h.sendToClientLocked(client, msg)
-	}
-	h.mu.RUnlock()
+func (h *Hub) broadcastPresenceUpdate(presence PresenceUpdatePayload, except *Client) {
+	h.publishFromRun(Event{
+		Topic:  TopicPresence,
+		Type:   EventPresenceUpdate,
+		Data:   presence,
+		Except: except,
+	})
 
-	slog.Debug("presence changed", "component", "hub", "user_id", userID, "status", status)
+	slog.Debug("presence changed", "component", "hub", "user_id", presence.UserID, "status", presence.Status)
 }
 
 func (h *Hub) BeginVoiceJoin(userID string, muted, deafened bool) error {
@@ -437,15 +985,38 @@ func (h *Hub) BeginVoiceJoin(userID string, muted, deafened bool) error {
 		return fmt.Errorf("voice state transition %s -> %s is invalid", from, VoiceLifecycleJoining)
 	}
 
+	if !h.withinVoiceLimitLocked(userID) {
+		return errVoiceSessionLimit
+	}
+
+	restriction := h.serverVoice[userID]
 	h.voiceSessions[userID] = &VoiceSession{
-		State:    VoiceLifecycleJoining,
-		Muted:    muted,
-		Deafened: deafened,
-		JoinedAt: time.Now(),
+		State:           VoiceLifecycleJoining,
+		Muted:           muted,
+		Deafened:        deafened,
+		ServerMuted:     restriction.muted,
+		ServerDeafened:  restriction.deafened,
+		JoinedAt:        h.now(),
+		PrioritySpeaker: h.isPrioritySpeakerLocked(userID),
 	}
+	h.syncAudioSuppressionLocked(userID)
+	h.wakeVoiceCascadeLocked()
 	return nil
 }
 
+// isPrioritySpeakerRole reports whether users with role are priority
+// speakers in voice.
+func (h *Hub) isPrioritySpeakerRole(role string) bool {
+	return h.permissions.PrioritySpeakerRole != "" && models.RoleAtLeast(role, h.permissions.PrioritySpeakerRole)
+}
+
+// isPrioritySpeakerLocked reports whether userID's connection has a
+// priority speaker role. h.mu must be held.
+func (h *Hub) isPrioritySpeakerLocked(userID string) bool {
+	client := h.userClients[userID]
+	return client != nil && client.user != nil && h.isPrioritySpeakerRole(client.user.Role)
+}
+
 func (h *Hub) ActivateVoiceSession(userID string) (*VoiceState, error) {
 	h.mu.Lock()
 	defer h.mu.Unlock()
@@ -455,14 +1026,14 @@ func (h *Hub) ActivateVoiceSession(userID string) (*VoiceState, error) {
 		return nil, fmt.Errorf("voice state transition %s -> %s is invalid", VoiceLifecycleNotInVoice, VoiceLifecycleActive)
 	}
 	if session.State == VoiceLifecycleActive {
-		return &VoiceState{Muted: session.Muted, Deafened: session.Deafened}, nil
+		return session.voiceState(), nil
 	}
 	if !isValidVoiceTransition(session.State, VoiceLifecycleActive) {
 		return nil, fmt.Errorf("voice state transition %s -> %s is invalid", session.State, VoiceLifecycleActive)
 	}
 
 	session.State = VoiceLifecycleActive
-	return &VoiceState{Muted: session.Muted, Deafened: session.Deafened}, nil
+	return session.voiceState(), nil
 }
 
 func (h *Hub) RemoveUserFromVoice(userID string) (*VoiceSession, bool) {
@@ -483,6 +1054,8 @@ func (h *Hub) RemoveUserFromVoice(userID string) (*VoiceSession, bool) {
 
 	snapshot := *session
 	delete(h.voiceSessions, userID)
+	h.syncAudioSuppressionLocked(userID)
+	h.wakeVoiceCascadeLocked()
 	return &snapshot, true
 }
 
@@ -512,12 +1085,12 @@ func (h *Hub) GetUserVoiceState(userID string) *VoiceState {
 		return nil
 	}
 
-	return &VoiceState{Muted: session.Muted, Deafened: session.Deafened}
+	return session.voiceState()
 }
 
 // UpdateUserVoiceState atomically updates a user's voice state fields.
 // Only updates fields that are non-nil. Returns the updated state, or nil if user not in voice.
-func (h *Hub) UpdateUserVoiceState(userID string, muted, deafened *bool) *VoiceState {
+func (h *Hub) UpdateUserVoiceState(userID string, muted, deafened, pushToTalk *bool) *VoiceState {
 	h.mu.Lock()
 	defer h.mu.Unlock()
 
@@ -532,8 +1105,86 @@ func (h *Hub) UpdateUserVoiceState(userID string, muted, deafened *bool) *VoiceS
 	if deafened != nil {
 		session.Deafened = *deafened
 	}
+	if pushToTalk != nil {
+		session.PushToTalk = *pushToTalk
+	}
+	h.syncAudioSuppressionLocked(userID)
+
+	return session.voiceState()
+}
+
+// syncAudioSuppressionLocked makes the SFU drop userID's audio while they
+// are muted or deafened, by themselves or a moderator, so a modified client
+// can't talk through a mute. h.mu must be held.
+func (h *Hub) syncAudioSuppressionLocked(userID string) {
+	if h.sfu == nil {
+		return
+	}
+	restriction := h.serverVoice[userID]
+	suppressed := restriction.muted || restriction.deafened
+	if session, ok := h.voiceSessions[userID]; ok {
+		suppressed = suppressed || session.Muted || session.Deafened
+	}
+	h.sfu.SetAudioSuppressed(userID, suppressed)
+}
+
+// SetServerVoiceState applies a moderator-imposed mute or deafen; nil fields
+// are left unchanged. While either is set the SFU drops the user's audio,
+// as it does for a self mute.
+// Broadcasts VOICE_STATE_UPDATE when the user has an active voice session.
+func (h *Hub) SetServerVoiceState(userID string, muted, deafened *bool) {
+	h.mu.Lock()
+	restriction := h.serverVoice[userID]
+	if muted != nil {
+		restriction.muted = *muted
+	}
+	if deafened != nil {
+		restriction.deafened = *deafened
+	}
+	if restriction == (serverVoiceState{}) {
+		delete(h.serverVoice, userID)
+	} else {
+		if h.serverVoice == nil {
+			h.serverVoice = make(map[string]serverVoiceState)
+		}
+		h.serverVoice[userID] = restriction
+	}
+
+	var state *VoiceState
+	if session, ok := h.voiceSessions[userID]; ok {
+		session.ServerMuted = restriction.muted
+		session.ServerDeafened = restriction.deafened
+		if session.State == VoiceLifecycleActive {
+			state = session.voiceState()
+		}
+	}
+	h.syncAudioSuppressionLocked(userID)
+	h.mu.Unlock()
 
-	return &VoiceState{Muted: session.Muted, Deafened: session.Deafened}
+	if state != nil {
+		h.BroadcastDispatch(EventVoiceStateUpdate, VoiceStateUpdatePayload{
+			UserID:          userID,
+			InVoice:         true,
+			Muted:           state.Muted,
+			Deafened:        state.Deafened,
+			ServerMuted:     state.ServerMuted,
+			ServerDeafened:  state.ServerDeafened,
+			PushToTalk:      state.PushToTalk,
+			Relay:           state.Relay,
+			PrioritySpeaker: state.PrioritySpeaker,
+			WhisperTo:       state.WhisperTo,
+		})
+	}
+}
+
+// DisconnectUserFromVoice removes a user from voice on the server's behalf.
+// Returns false if the user was not in voice.
+func (h *Hub) DisconnectUserFromVoice(userID string) bool {
+	if _, removed := h.RemoveUserFromVoice(userID); !removed {
+		return false
+	}
+	h.cleanupVoiceForUser(userID)
+	return true
 }
 
 func (h *Hub) GetSFU() *sfu.SFU {
@@ -544,6 +1195,27 @@ func (h *Hub) GetSFUConfig() *config.SFUConfig {
 	return h.sfuCfg
 }
 
+// VoiceSelfTest runs the SFU's networking self-test against the configured
+// port range, public IP, and STUN/TURN servers.
+func (h *Hub) VoiceSelfTest(ctx context.Context) (*sfu.SelfTestReport, error) {
+	if h.sfu == nil {
+		return nil, fmt.Errorf("SFU not initialized")
+	}
+	var turn config.TURNConfig
+	if h.sfuCfg != nil {
+		turn = h.sfuCfg.TURN
+	}
+	return h.sfu.SelfTest(ctx, turn), nil
+}
+
+// VoiceRoutes returns how each connected voice peer reaches the SFU.
+func (h *Hub) VoiceRoutes() []sfu.PeerRoute {
+	if h.sfu == nil {
+		return nil
+	}
+	return h.sfu.PeerRoutes()
+}
+
 func (h *Hub) HandleRtcOffer(userID string, sdp string) (string, error) {
 	if h.sfu == nil {
 		return "", fmt.Errorf("SFU not initialized")
@@ -569,6 +1241,9 @@ func (h *Hub) HandleRtcAnswer(userID string, sdp string) error {
 	if h.screenShare != nil {
 		h.screenShare.OnRenegotiationComplete(userID)
 	}
+	if h.cameras != nil {
+		h.cameras.OnRenegotiationComplete(userID)
+	}
 	return nil
 }
 
@@ -583,6 +1258,48 @@ func (h *Hub) HandleRtcIceCandidate(userID string, candidate string, sdpMid *str
 	return nil
 }
 
+// StartVoiceRelay moves userID's voice session from WebRTC to audio relayed
+// as binary frames over the websocket, delivered to sink. It is the last
+// resort when neither UDP nor TURN connects, and activates a session that
+// was still negotiating.
+func (h *Hub) StartVoiceRelay(userID string, sink sfu.RelaySink) (*VoiceState, error) {
+	if h.sfu == nil {
+		return nil, fmt.Errorf("SFU not initialized")
+	}
+
+	h.mu.Lock()
+	session, ok := h.voiceSessions[userID]
+	if !ok || (session.State != VoiceLifecycleJoining && session.State != VoiceLifecycleActive) {
+		h.mu.Unlock()
+		return nil, fmt.Errorf("voice relay cannot start outside an active voice session")
+	}
+	h.mu.Unlock()
+
+	h.sfu.RemovePeer(userID)
+	if h.screenShare != nil {
+		h.screenShare.OnUserDisconnect(userID)
+	}
+	if h.cameras != nil {
+		h.cameras.OnUserDisconnect(userID)
+	}
+	if err := h.sfu.AddRelay(userID, sink); err != nil {
+		return nil, err
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	session, ok = h.voiceSessions[userID]
+	if !ok {
+		h.sfu.RemoveRelay(userID)
+		return nil, fmt.Errorf("voice session ended while starting relay")
+	}
+	session.State = VoiceLifecycleActive
+	session.Relay = true
+	// Removing the peer ended any whisper in the SFU
+	session.WhisperTo = nil
+	return session.voiceState(), nil
+}
+
 // handleSfuError processes SFU errors based on their category
 func (h *Hub) handleSfuError(userID string, err error) {
 	var peerErr *sfu.PeerError
@@ -628,7 +1345,13 @@ func (h *Hub) SendDispatchToUser(userID string, eventType string, payload interf
 	h.SendToUser(userID, msg)
 }
 
+// Shutdown saves the member snapshot and stops Run.
 func (h *Hub) Shutdown() {
+	ctx, cancel := context.WithTimeout(context.Background(), memberSnapshotSaveTimeout)
+	defer cancel()
+	if err := h.saveMemberSnapshot(ctx); err != nil {
+		slog.Error("error saving member snapshot", "component", "hub", "error", err)
+	}
 	close(h.shutdown)
 }
 
@@ -639,21 +1362,27 @@ func (h *Hub) GetScreenShareManager() *sfu.ScreenShareManager {
 
 // handleScreenShareUpdate is called when a user's screen share state changes
 func (h *Hub) handleScreenShareUpdate(userID string, streaming bool) {
-	h.BroadcastDispatch(EventScreenShareUpdate, ScreenShareUpdatePayload{
-		UserID:    userID,
-		Streaming: streaming,
-	})
+	payload := ScreenShareUpdatePayload{UserID: userID, Streaming: streaming}
+	if streaming {
+		payload.Layout = LayoutSpotlight
+	}
+	h.BroadcastDispatch(EventScreenShareUpdate, payload)
 }
 
-// cleanupVoiceForUser tears down SFU peer, screen share, and broadcasts voice-leave.
+// cleanupVoiceForUser tears down SFU peer, screen share, camera, and broadcasts voice-leave.
 // Must be called outside of h.mu lock.
 func (h *Hub) cleanupVoiceForUser(userID string) {
 	if h.sfu != nil {
 		h.sfu.RemovePeer(userID)
+		h.sfu.RemoveRelay(userID)
 	}
+	h.removeExternalParticipant(userID)
 	if h.screenShare != nil {
 		h.screenShare.OnUserDisconnect(userID)
 	}
+	if h.cameras != nil {
+		h.cameras.OnUserDisconnect(userID)
+	}
 	h.BroadcastDispatch(EventVoiceStateUpdate, VoiceStateUpdatePayload{
 		UserID:   userID,
 		InVoice:  false,
@@ -676,6 +1405,8 @@ func (h *Hub) removeVoiceSessionLocked(userID string) (*VoiceSession, bool) {
 		return nil, false
 	}
 	delete(h.voiceSessions, userID)
+	h.syncAudioSuppressionLocked(userID)
+	h.wakeVoiceCascadeLocked()
 	copy := *session
 	return &copy, true
 }
@@ -684,7 +1415,7 @@ func (h *Hub) collectStaleJoiningUsers() []string {
 	h.mu.RLock()
 	defer h.mu.RUnlock()
 
-	now := time.Now()
+	now := h.now()
 	staleUsers := make([]string, 0)
 	for userID, session := range h.voiceSessions {
 		if session.State != VoiceLifecycleJoining {
@@ -705,10 +1436,15 @@ func (h *Hub) forceCleanupVoiceSession(userID string) {
 
 	if h.sfu != nil {
 		h.sfu.RemovePeer(userID)
+		h.sfu.RemoveRelay(userID)
 	}
+	h.removeExternalParticipant(userID)
 	if h.screenShare != nil {
 		h.screenShare.OnUserDisconnect(userID)
 	}
+	if h.cameras != nil {
+		h.cameras.OnUserDisconnect(userID)
+	}
 
 	if !hadSession {
 		return
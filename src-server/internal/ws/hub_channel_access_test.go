@@ -0,0 +1,183 @@
+package ws
+
+import (
+	"testing"
+	"time"
+
+	"lobby/internal/models"
+)
+
+func TestBroadcastChannelDispatchSkipsUninvitedClients(t *testing.T) {
+	h := &Hub{
+		clients:        make(map[*Client]bool),
+		channelPrivate: true,
+		channelMembers: map[string]struct{}{"usr_invited": {}},
+	}
+
+	invited := newIdentifiedTestClient(h, "usr_invited")
+	outsider := newIdentifiedTestClient(h, "usr_outsider")
+	moderator := newIdentifiedTestClient(h, "usr_mod")
+	moderator.user.Role = models.RoleModerator
+	sender := newIdentifiedTestClient(h, "usr_sender")
+	for _, c := range []*Client{invited, outsider, moderator, sender} {
+		h.clients[c] = true
+	}
+
+	h.BroadcastChannelDispatch(EventTypingStart, TypingStartPayload{UserID: "usr_sender"}, sender)
+
+	for _, c := range []*Client{invited, moderator} {
+		if msg := nextSent(c); msg != nil {
+			if msg.Type != EventTypingStart {
+				t.Fatalf("expected %s for %s, got %s", EventTypingStart, c.user.ID, msg.Type)
+			}
+		} else {
+			t.Fatalf("expected %s to receive dispatch", c.user.ID)
+		}
+	}
+
+	for _, c := range []*Client{outsider, sender} {
+		if msg := nextSent(c); msg != nil {
+			t.Fatalf("unexpected dispatch for %s: type=%s", c.user.ID, msg.Type)
+		}
+	}
+}
+
+func TestCanAccessChannelPublicAllowsMembers(t *testing.T) {
+	h := &Hub{}
+	if !h.CanAccessChannel(&models.User{ID: "usr_1", Role: models.RoleMember}) {
+		t.Fatal("expected public channel to be accessible")
+	}
+	if h.CanAccessChannel(nil) {
+		t.Fatal("expected nil user to be denied")
+	}
+}
+
+func TestHandleMessageSendRejectsArchivedChannel(t *testing.T) {
+	h := &Hub{
+		clients:         make(map[*Client]bool),
+		channelArchived: true,
+	}
+	c := newIdentifiedTestClient(h, "usr_1")
+
+	c.handleMessageSend(&WSMessage{
+		Op:   OpDispatch,
+		Type: CmdMessageSend,
+		Data: map[string]interface{}{"content": "hello", "nonce": "n1"},
+	})
+
+	if msg := nextSent(c); msg != nil {
+		payload, ok := msg.Data.(ErrorPayload)
+		if msg.Type != EventError || !ok {
+			t.Fatalf("expected %s, got type=%s data=%T", EventError, msg.Type, msg.Data)
+		}
+		if payload.Code != ErrCodeChannelArchived || payload.Nonce != "n1" {
+			t.Fatalf("unexpected error payload: %+v", payload)
+		}
+	} else {
+		t.Fatal("expected error to be sent")
+	}
+}
+
+func TestCheckSlowModeEnforcesPerUserCooldown(t *testing.T) {
+	h := &Hub{channelSlowMode: 10 * time.Second}
+	member := &models.User{ID: "usr_1", Role: models.RoleMember}
+	now := time.Unix(1_700_000_000, 0)
+
+	if ok, _ := h.CheckSlowMode(member, now); !ok {
+		t.Fatal("expected first message to be allowed")
+	}
+	if ok, _ := h.CheckSlowMode(member, now.Add(time.Second)); !ok {
+		t.Fatal("expected a check alone not to start the cooldown")
+	}
+	h.RecordSlowMode(member, now)
+	ok, retryAt := h.CheckSlowMode(member, now.Add(3*time.Second))
+	if ok {
+		t.Fatal("expected second message within cooldown to be rejected")
+	}
+	if want := now.Add(10 * time.Second); !retryAt.Equal(want) {
+		t.Fatalf("retryAt = %v, want %v", retryAt, want)
+	}
+	if ok, _ := h.CheckSlowMode(&models.User{ID: "usr_2", Role: models.RoleMember}, now.Add(3*time.Second)); !ok {
+		t.Fatal("expected cooldown to be per user")
+	}
+	if ok, _ := h.CheckSlowMode(member, now.Add(10*time.Second)); !ok {
+		t.Fatal("expected message after cooldown to be allowed")
+	}
+
+	moderator := &models.User{ID: "usr_mod", Role: models.RoleModerator}
+	for i := 0; i < 2; i++ {
+		if ok, _ := h.CheckSlowMode(moderator, now); !ok {
+			t.Fatal("expected moderators to be exempt")
+		}
+		h.RecordSlowMode(moderator, now)
+	}
+	if _, ok := h.slowModeLastMsg[moderator.ID]; ok {
+		t.Fatal("expected no cooldown recorded for moderators")
+	}
+}
+
+func TestPruneSlowModeForgetsEndedCooldowns(t *testing.T) {
+	now := time.Now()
+	h := &Hub{
+		channelSlowMode: 10 * time.Second,
+		slowModeLastMsg: map[string]time.Time{
+			"usr_1": now.Add(-time.Minute),
+			"usr_2": now,
+		},
+	}
+
+	h.pruneSlowMode()
+
+	if _, ok := h.slowModeLastMsg["usr_1"]; ok {
+		t.Fatal("expected the ended cooldown to be pruned")
+	}
+	if _, ok := h.slowModeLastMsg["usr_2"]; !ok {
+		t.Fatal("expected the running cooldown to be kept")
+	}
+}
+
+func TestHandleMessageSendDroppedMessageKeepsSlowModeOpen(t *testing.T) {
+	h := &Hub{
+		clients:         make(map[*Client]bool),
+		channelSlowMode: time.Minute,
+	}
+	c := newIdentifiedTestClient(h, "usr_1")
+
+	// Sanitizes to nothing, so it is dropped before it is stored
+	c.handleMessageSend(&WSMessage{
+		Op:   OpDispatch,
+		Type: CmdMessageSend,
+		Data: map[string]interface{}{"content": "<script>alert(1)</script>", "nonce": "n1"},
+	})
+
+	if ok, _ := h.CheckSlowMode(c.user, time.Now()); !ok {
+		t.Fatal("expected a dropped message not to start the cooldown")
+	}
+}
+
+func TestHandleMessageSendRejectsDuringSlowMode(t *testing.T) {
+	h := &Hub{
+		clients:         make(map[*Client]bool),
+		channelSlowMode: time.Minute,
+		slowModeLastMsg: map[string]time.Time{"usr_1": time.Now()},
+	}
+	c := newIdentifiedTestClient(h, "usr_1")
+
+	c.handleMessageSend(&WSMessage{
+		Op:   OpDispatch,
+		Type: CmdMessageSend,
+		Data: map[string]interface{}{"content": "hello", "nonce": "n1"},
+	})
+
+	if msg := nextSent(c); msg != nil {
+		payload, ok := msg.Data.(ErrorPayload)
+		if msg.Type != EventError || !ok {
+			t.Fatalf("expected %s, got type=%s data=%T", EventError, msg.Type, msg.Data)
+		}
+		if payload.Code != ErrCodeRateLimited || payload.Nonce != "n1" || payload.RetryAfter <= time.Now().UnixMilli() {
+			t.Fatalf("unexpected error payload: %+v", payload)
+		}
+	} else {
+		t.Fatal("expected error to be sent")
+	}
+}
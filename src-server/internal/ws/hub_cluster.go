@@ -0,0 +1,307 @@
+package ws
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"time"
+
+	"lobby/internal/cluster"
+)
+
+const (
+	clusterQueueSize         = 1024
+	clusterHeartbeatInterval = 15 * time.Second
+	clusterOpTimeout         = 2 * time.Second
+)
+
+// clusterTask is queued work for the backplane goroutine: either forward an
+// envelope or refresh one local user's member record.
+type clusterTask struct {
+	env        *cluster.Envelope
+	syncUserID string
+}
+
+// AttachBackplane connects the hub to other lobby instances sharing bp. Locally
+// published events are forwarded to the backplane and remote events are
+// delivered to local clients. Must be called before Run.
+func (h *Hub) AttachBackplane(bp cluster.Backplane) {
+	h.backplane = bp
+	h.instanceID = cluster.NewInstanceID()
+	h.clusterTasks = make(chan clusterTask, clusterQueueSize)
+	h.events.Subscribe(SubscriberFunc(h.forwardToCluster))
+	slog.Info("cluster backplane attached", "component", "hub", "instance_id", h.instanceID)
+}
+
+// forwardToCluster is the event bus subscriber that mirrors local events to the
+// backplane. Remote events are delivered with deliverToClients only, so they
+// never reach this subscriber and cannot echo.
+func (h *Hub) forwardToCluster(e Event) {
+	data, err := json.Marshal(e.Data)
+	if err != nil {
+		slog.Error("error encoding cluster event", "component", "hub", "type", e.Type, "error", err)
+		return
+	}
+	var maskedData json.RawMessage
+	if e.MaskedData != nil {
+		if maskedData, err = json.Marshal(e.MaskedData); err != nil {
+			slog.Error("error encoding cluster event", "component", "hub", "type", e.Type, "error", err)
+			return
+		}
+	}
+	h.enqueueClusterTask(clusterTask{env: &cluster.Envelope{
+		Instance:    h.instanceID,
+		Type:        e.Type,
+		Data:        data,
+		MaskedData:  maskedData,
+		ChannelOnly: e.Audience == AudienceChannel,
+		Moderators:  e.Audience == AudienceModerators,
+		UserID:      e.UserID,
+		AuthorID:    e.AuthorID,
+	}})
+
+	var userID string
+	switch payload := e.Data.(type) {
+	case PresenceUpdatePayload:
+		userID = payload.UserID
+	case VoiceStateUpdatePayload:
+		userID = payload.UserID
+	case ScreenShareUpdatePayload:
+		userID = payload.UserID
+	case VideoStatePayload:
+		userID = payload.UserID
+	}
+	if userID != "" {
+		h.enqueueClusterTask(clusterTask{syncUserID: userID})
+	}
+}
+
+func (h *Hub) enqueueClusterTask(task clusterTask) {
+	select {
+	case h.clusterTasks <- task:
+	default:
+		slog.Warn("cluster queue full, dropping task", "component", "hub")
+	}
+}
+
+// runCluster drains the cluster queue and refreshes member records until shutdown.
+func (h *Hub) runCluster() {
+	h.backplane.Subscribe(h.handleClusterEnvelope)
+
+	ticker := time.NewTicker(clusterHeartbeatInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-h.shutdown:
+			for _, userID := range h.localUserIDs() {
+				h.removeClusterMember(userID)
+			}
+			h.backplane.Close()
+			return
+
+		case task := <-h.clusterTasks:
+			if task.env != nil {
+				ctx, cancel := context.WithTimeout(context.Background(), clusterOpTimeout)
+				if err := h.backplane.Publish(ctx, *task.env); err != nil {
+					slog.Warn("error publishing cluster event", "component", "hub", "type", task.env.Type, "error", err)
+				}
+				cancel()
+				continue
+			}
+			h.syncClusterMember(task.syncUserID)
+
+		case <-ticker.C:
+			for _, userID := range h.localUserIDs() {
+				h.syncClusterMember(userID)
+			}
+			h.pruneStaleClusterMembers()
+			h.pruneRemoteVoice()
+		}
+	}
+}
+
+func (h *Hub) handleClusterEnvelope(env cluster.Envelope) {
+	if env.Instance == h.instanceID {
+		return
+	}
+
+	if env.Type == cluster.TypeChannelAccess {
+		if err := h.reloadChannelAccess(context.Background()); err != nil {
+			slog.Error("error reloading text channel access", "component", "hub", "error", err)
+		}
+		return
+	}
+
+	if env.Type == cluster.TypeAutomodRules {
+		if err := h.reloadAutomodRules(context.Background()); err != nil {
+			slog.Error("error reloading automod rules", "component", "hub", "error", err)
+		}
+		return
+	}
+
+	if env.Type == cluster.TypeUserMutes {
+		if err := h.reloadUserMutes(context.Background(), env.UserID); err != nil {
+			slog.Error("error reloading user mutes", "component", "hub", "error", err)
+		}
+		return
+	}
+
+	if env.Type == cluster.TypeUserTimeouts {
+		if err := h.reloadUserTimeouts(context.Background()); err != nil {
+			slog.Error("error reloading user timeouts", "component", "hub", "error", err)
+		}
+		return
+	}
+
+	audience := AudienceAll
+	if env.ChannelOnly {
+		audience = AudienceChannel
+	}
+	if env.Moderators {
+		audience = AudienceModerators
+	}
+	if env.UserID != "" {
+		audience = AudienceUser
+	}
+	e := Event{
+		Topic:    TopicForEvent(env.Type),
+		Type:     env.Type,
+		Data:     env.Data,
+		Audience: audience,
+		UserID:   env.UserID,
+		AuthorID: env.AuthorID,
+	}
+	if len(env.MaskedData) > 0 {
+		e.MaskedData = env.MaskedData
+	}
+	h.deliverToClients(e)
+
+	switch e.Topic {
+	case TopicPresence, TopicMember, TopicVoice, TopicScreenShare, TopicVideo:
+		h.recordMemberEvent(e)
+	}
+	if env.Type == EventVoiceStateUpdate {
+		h.handleRemoteVoiceState(env.Data)
+	}
+}
+
+// syncClusterMember writes the user's local presence/voice state to the
+// backplane, or removes it once the user is no longer connected here.
+func (h *Hub) syncClusterMember(userID string) {
+	rec, ok := h.localMemberRecord(userID)
+	if !ok {
+		h.removeClusterMember(userID)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), clusterOpTimeout)
+	defer cancel()
+	if err := h.backplane.SetMember(ctx, rec); err != nil {
+		slog.Warn("error syncing cluster member", "component", "hub", "user_id", userID, "error", err)
+	}
+}
+
+func (h *Hub) removeClusterMember(userID string) {
+	ctx, cancel := context.WithTimeout(context.Background(), clusterOpTimeout)
+	defer cancel()
+	if err := h.backplane.RemoveMember(ctx, h.instanceID, userID); err != nil {
+		slog.Warn("error removing cluster member", "component", "hub", "user_id", userID, "error", err)
+	}
+}
+
+func (h *Hub) pruneStaleClusterMembers() {
+	ctx, cancel := context.WithTimeout(context.Background(), clusterOpTimeout)
+	defer cancel()
+
+	records, err := h.backplane.Members(ctx)
+	if err != nil {
+		slog.Warn("error listing cluster members", "component", "hub", "error", err)
+		return
+	}
+	now := h.now()
+	for _, rec := range records {
+		if rec.Stale(now) {
+			if err := h.backplane.RemoveMember(ctx, rec.Instance, rec.UserID); err != nil {
+				slog.Warn("error pruning cluster member", "component", "hub", "user_id", rec.UserID, "error", err)
+			}
+		}
+	}
+}
+
+func (h *Hub) localMemberRecord(userID string) (cluster.MemberRecord, bool) {
+	h.mu.RLock()
+	client, ok := h.userClients[userID]
+	if !ok || !client.IsIdentified() {
+		h.mu.RUnlock()
+		return cluster.MemberRecord{}, false
+	}
+	presence := client.presenceUpdate()
+	rec := cluster.MemberRecord{
+		UserID:      userID,
+		Instance:    h.instanceID,
+		Status:      presence.Status,
+		StatusText:  presence.StatusText,
+		StatusEmoji: presence.StatusEmoji,
+		SeenAt:      h.now().Unix(),
+	}
+	if session, ok := h.voiceSessions[userID]; ok {
+		if session.State == VoiceLifecycleJoining || session.State == VoiceLifecycleActive {
+			rec.InVoice = true
+			rec.Muted = session.Muted
+			rec.Deafened = session.Deafened
+			rec.ServerMuted = session.ServerMuted
+			rec.ServerDeafened = session.ServerDeafened
+			rec.PushToTalk = session.PushToTalk
+		}
+	}
+	h.mu.RUnlock()
+
+	if h.screenShare != nil {
+		rec.Streaming = h.screenShare.IsStreaming(userID)
+	}
+	if h.cameras != nil {
+		rec.Camera = h.cameras.IsPublishing(userID)
+	}
+	return rec, true
+}
+
+func (h *Hub) localUserIDs() []string {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	userIDs := make([]string, 0, len(h.userClients))
+	for userID := range h.userClients {
+		userIDs = append(userIDs, userID)
+	}
+	return userIDs
+}
+
+// remoteMembers returns the freshest member record per user held by other
+// instances, or nil when no backplane is attached.
+func (h *Hub) remoteMembers() map[string]cluster.MemberRecord {
+	if h.backplane == nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), clusterOpTimeout)
+	defer cancel()
+	records, err := h.backplane.Members(ctx)
+	if err != nil {
+		slog.Warn("error listing cluster members", "component", "hub", "error", err)
+		return nil
+	}
+
+	now := h.now()
+	members := make(map[string]cluster.MemberRecord, len(records))
+	for _, rec := range records {
+		if rec.Instance == h.instanceID || rec.Stale(now) {
+			continue
+		}
+		if existing, ok := members[rec.UserID]; ok && existing.SeenAt >= rec.SeenAt {
+			continue
+		}
+		members[rec.UserID] = rec
+	}
+	return members
+}
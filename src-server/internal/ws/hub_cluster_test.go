@@ -0,0 +1,195 @@
+package ws
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"lobby/internal/cluster"
+	"lobby/internal/sfu"
+)
+
+type fakeBackplane struct {
+	members     []cluster.MemberRecord
+	voice       []cluster.VoiceFrame
+	voiceSubs   int
+	voiceActive bool
+}
+
+func (f *fakeBackplane) Publish(context.Context, cluster.Envelope) error { return nil }
+func (f *fakeBackplane) Subscribe(func(cluster.Envelope))                {}
+func (f *fakeBackplane) SetMember(context.Context, cluster.MemberRecord) error {
+	return nil
+}
+func (f *fakeBackplane) RemoveMember(context.Context, string, string) error { return nil }
+func (f *fakeBackplane) Members(context.Context) ([]cluster.MemberRecord, error) {
+	return f.members, nil
+}
+func (f *fakeBackplane) PublishVoice(_ context.Context, frame cluster.VoiceFrame) error {
+	f.voice = append(f.voice, frame)
+	return nil
+}
+func (f *fakeBackplane) SubscribeVoice(func(cluster.VoiceFrame)) func() {
+	f.voiceSubs++
+	f.voiceActive = true
+	return func() { f.voiceActive = false }
+}
+func (f *fakeBackplane) Close() error { return nil }
+
+func TestHandleClusterEnvelopeDeliversRemoteEvents(t *testing.T) {
+	h := &Hub{
+		clients:    make(map[*Client]bool),
+		instanceID: "self",
+	}
+	c := newIdentifiedTestClient(h, "usr_1")
+	h.clients[c] = true
+
+	h.handleClusterEnvelope(cluster.Envelope{Instance: "self", Type: EventPresenceUpdate})
+	if msg := nextSent(c); msg != nil {
+		t.Fatalf("unexpected delivery of own envelope: type=%s", msg.Type)
+	}
+
+	data := json.RawMessage(`{"user_id":"usr_2","status":"idle"}`)
+	h.handleClusterEnvelope(cluster.Envelope{Instance: "other", Type: EventPresenceUpdate, Data: data})
+	if msg := nextSent(c); msg != nil {
+		if msg.Type != EventPresenceUpdate {
+			t.Fatalf("expected %s, got %s", EventPresenceUpdate, msg.Type)
+		}
+		if raw, ok := msg.Data.(json.RawMessage); !ok || string(raw) != string(data) {
+			t.Fatalf("expected raw payload to be forwarded, got %#v", msg.Data)
+		}
+	} else {
+		t.Fatal("expected remote event to be delivered")
+	}
+}
+
+func TestRemoteMembersSkipsSelfAndStaleRecords(t *testing.T) {
+	now := time.Now()
+	h := &Hub{
+		instanceID: "self",
+		backplane: &fakeBackplane{members: []cluster.MemberRecord{
+			{UserID: "usr_1", Instance: "self", Status: "online", SeenAt: now.Unix()},
+			{UserID: "usr_2", Instance: "other", Status: "dnd", SeenAt: now.Unix()},
+			{UserID: "usr_3", Instance: "dead", Status: "online", SeenAt: now.Add(-2 * cluster.MemberTTL).Unix()},
+		}},
+	}
+
+	members := h.remoteMembers()
+
+	if len(members) != 1 {
+		t.Fatalf("expected 1 remote member, got %+v", members)
+	}
+	if rec, ok := members["usr_2"]; !ok || rec.Status != "dnd" {
+		t.Fatalf("expected usr_2 dnd, got %+v", members)
+	}
+}
+
+func TestVoiceCascadeBetweenInstances(t *testing.T) {
+	s, err := sfu.New(&sfu.Config{})
+	if err != nil {
+		t.Fatalf("sfu.New() error = %v", err)
+	}
+	t.Cleanup(s.Close)
+	h := &Hub{
+		clients:       make(map[*Client]bool),
+		voiceSessions: make(map[string]*VoiceSession),
+		sfu:           s,
+		backplane:     &fakeBackplane{},
+		instanceID:    "self",
+	}
+	h.ConfigureVoiceCluster("eu-west", true)
+	if node := h.voiceNode(); node == nil || node.ID != "self" || node.Region != "eu-west" {
+		t.Fatalf("voiceNode() = %+v, want self in eu-west", node)
+	}
+
+	var heard []string
+	if err := s.AddRelay("usr_local", func(frame sfu.RelayFrame) {
+		heard = append(heard, frame.SourceUserID)
+	}); err != nil {
+		t.Fatalf("AddRelay() error = %v", err)
+	}
+	if err := s.WriteRelayAudio("usr_local", sfu.RelayFrame{Sequence: 1, Payload: []byte{0xfc}}); err != nil {
+		t.Fatalf("WriteRelayAudio() error = %v", err)
+	}
+	select {
+	case frame := <-h.voiceFrames:
+		if frame.Instance != "self" || frame.UserID != "usr_local" {
+			t.Fatalf("cascaded %+v, want usr_local's frame from self", frame)
+		}
+	default:
+		t.Fatal("local audio was not cascaded")
+	}
+
+	h.receiveVoiceFrame(cluster.VoiceFrame{Instance: "self", UserID: "usr_echo", Payload: []byte{0xfc}})
+	if s.IsRemoteParticipant("usr_echo") {
+		t.Fatal("own frame was played as a remote participant")
+	}
+
+	h.receiveVoiceFrame(cluster.VoiceFrame{Instance: "other", UserID: "usr_remote", Sequence: 9, Payload: []byte{0xfc}})
+	if !s.IsRemoteParticipant("usr_remote") {
+		t.Fatal("remote speaker was not added as a remote participant")
+	}
+	if len(heard) != 1 || heard[0] != "usr_remote" {
+		t.Fatalf("local relay heard %v, want usr_remote", heard)
+	}
+	if len(h.voiceFrames) != 0 {
+		t.Fatal("remote audio was cascaded back to the backplane")
+	}
+
+	left := json.RawMessage(`{"user_id":"usr_remote","in_voice":false}`)
+	h.handleClusterEnvelope(cluster.Envelope{Instance: "other", Type: EventVoiceStateUpdate, Data: left})
+	if s.IsRelay("usr_remote") {
+		t.Fatal("remote participant kept after leaving voice")
+	}
+}
+
+func TestVoiceCascadeListensOnlyWithVoiceSessions(t *testing.T) {
+	s, err := sfu.New(&sfu.Config{})
+	if err != nil {
+		t.Fatalf("sfu.New() error = %v", err)
+	}
+	t.Cleanup(s.Close)
+	backplane := &fakeBackplane{}
+	h := &Hub{
+		clients:       make(map[*Client]bool),
+		voiceSessions: make(map[string]*VoiceSession),
+		sfu:           s,
+		backplane:     backplane,
+		instanceID:    "self",
+	}
+	h.ConfigureVoiceCluster("", true)
+
+	h.syncVoiceSubscription()
+	if backplane.voiceSubs != 0 {
+		t.Fatal("subscribed to voice with nobody in voice")
+	}
+
+	if err := h.BeginVoiceJoin("usr_1", false, false); err != nil {
+		t.Fatalf("BeginVoiceJoin() error = %v", err)
+	}
+	select {
+	case <-h.voiceListen:
+	default:
+		t.Fatal("joining voice did not wake the cascade")
+	}
+	h.syncVoiceSubscription()
+	h.syncVoiceSubscription()
+	if backplane.voiceSubs != 1 || !backplane.voiceActive {
+		t.Fatalf("voice subscriptions = %d (active %v), want one active", backplane.voiceSubs, backplane.voiceActive)
+	}
+
+	h.receiveVoiceFrame(cluster.VoiceFrame{Instance: "other", UserID: "usr_remote", Payload: []byte{0xfc}})
+	if !s.IsRemoteParticipant("usr_remote") {
+		t.Fatal("remote speaker was not added while listening")
+	}
+
+	h.RemoveUserFromVoice("usr_1")
+	h.syncVoiceSubscription()
+	if backplane.voiceActive {
+		t.Fatal("still subscribed to voice after the last session ended")
+	}
+	if s.IsRelay("usr_remote") {
+		t.Fatal("remote participant kept after unsubscribing")
+	}
+}
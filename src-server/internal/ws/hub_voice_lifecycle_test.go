@@ -1,6 +1,12 @@
 package ws
 
-import "testing"
+import (
+	"testing"
+
+	"lobby/internal/config"
+	"lobby/internal/models"
+	"lobby/internal/sfu"
+)
 
 func TestVoiceLifecycleTransitionTable(t *testing.T) {
 	testCases := []struct {
@@ -57,6 +63,76 @@ func TestBeginJoinActivateAndLeave(t *testing.T) {
 	}
 }
 
+func TestPrioritySpeakerFlaggedByRole(t *testing.T) {
+	h := &Hub{
+		voiceSessions: make(map[string]*VoiceSession),
+		userClients: map[string]*Client{
+			"usr_1": {user: &models.User{ID: "usr_1", Role: models.RoleModerator}},
+			"usr_2": {user: &models.User{ID: "usr_2", Role: models.RoleMember}},
+		},
+		permissions: config.PermissionsConfig{PrioritySpeakerRole: models.RoleModerator},
+	}
+
+	for userID, want := range map[string]bool{"usr_1": true, "usr_2": false} {
+		if err := h.BeginVoiceJoin(userID, false, false); err != nil {
+			t.Fatalf("BeginVoiceJoin(%s) failed: %v", userID, err)
+		}
+		state, err := h.ActivateVoiceSession(userID)
+		if err != nil {
+			t.Fatalf("ActivateVoiceSession(%s) failed: %v", userID, err)
+		}
+		if state.PrioritySpeaker != want {
+			t.Fatalf("%s PrioritySpeaker = %v, want %v", userID, state.PrioritySpeaker, want)
+		}
+	}
+}
+
+func TestMuteSuppressesAudioInSFU(t *testing.T) {
+	s, err := sfu.New(&sfu.Config{})
+	if err != nil {
+		t.Fatalf("sfu.New() error = %v", err)
+	}
+	t.Cleanup(s.Close)
+	h := &Hub{
+		voiceSessions: make(map[string]*VoiceSession),
+		sfu:           s,
+		broadcast:     make(chan *WSMessage, 4),
+	}
+
+	if err := h.BeginVoiceJoin("usr_1", true, false); err != nil {
+		t.Fatalf("BeginVoiceJoin failed: %v", err)
+	}
+	if _, err := h.ActivateVoiceSession("usr_1"); err != nil {
+		t.Fatalf("ActivateVoiceSession failed: %v", err)
+	}
+	if !s.IsAudioSuppressed("usr_1") {
+		t.Fatal("audio of a user who joined muted is forwarded")
+	}
+
+	unmuted, deafened := false, true
+	h.UpdateUserVoiceState("usr_1", &unmuted, nil, nil)
+	if s.IsAudioSuppressed("usr_1") {
+		t.Fatal("audio still dropped after unmuting")
+	}
+	h.UpdateUserVoiceState("usr_1", nil, &deafened, nil)
+	if !s.IsAudioSuppressed("usr_1") {
+		t.Fatal("audio of a deafened user is forwarded")
+	}
+
+	// A moderator mute outlasts the self mute it overlaps
+	muted := true
+	h.SetServerVoiceState("usr_1", &muted, nil)
+	notDeafened := false
+	h.UpdateUserVoiceState("usr_1", nil, &notDeafened, nil)
+	if !s.IsAudioSuppressed("usr_1") {
+		t.Fatal("audio forwarded while server muted")
+	}
+	h.SetServerVoiceState("usr_1", &unmuted, nil)
+	if s.IsAudioSuppressed("usr_1") {
+		t.Fatal("audio still dropped after every mute was lifted")
+	}
+}
+
 func TestInvalidJoinFromActiveState(t *testing.T) {
 	h := &Hub{voiceSessions: make(map[string]*VoiceSession)}
 
@@ -72,3 +148,52 @@ func TestInvalidJoinFromActiveState(t *testing.T) {
 		t.Fatal("expected BeginVoiceJoin to fail when already active")
 	}
 }
+
+func TestServerMuteBroadcastsAndSurvivesRejoin(t *testing.T) {
+	h := &Hub{
+		voiceSessions: map[string]*VoiceSession{
+			"usr_1": {State: VoiceLifecycleActive, Muted: true},
+		},
+		broadcast: make(chan *WSMessage, 4),
+	}
+
+	muted := true
+	h.SetServerVoiceState("usr_1", &muted, nil)
+
+	select {
+	case msg := <-h.broadcast:
+		payload, ok := msg.Data.(VoiceStateUpdatePayload)
+		if msg.Type != EventVoiceStateUpdate || !ok {
+			t.Fatalf("expected %s, got type=%s data=%T", EventVoiceStateUpdate, msg.Type, msg.Data)
+		}
+		if !payload.InVoice || !payload.Muted || !payload.ServerMuted || payload.ServerDeafened {
+			t.Fatalf("unexpected payload: %+v", payload)
+		}
+	default:
+		t.Fatal("expected voice state broadcast")
+	}
+
+	unmuted := false
+	if state := h.UpdateUserVoiceState("usr_1", &unmuted, nil, nil); state == nil || state.Muted || !state.ServerMuted {
+		t.Fatalf("self unmute should keep server mute, got %+v", state)
+	}
+
+	if !h.DisconnectUserFromVoice("usr_1") {
+		t.Fatal("expected user to be disconnected from voice")
+	}
+	if h.DisconnectUserFromVoice("usr_1") {
+		t.Fatal("expected second disconnect to report not in voice")
+	}
+
+	if err := h.BeginVoiceJoin("usr_1", false, false); err != nil {
+		t.Fatalf("BeginVoiceJoin() error = %v", err)
+	}
+	if state := h.GetUserVoiceState("usr_1"); state == nil || !state.ServerMuted {
+		t.Fatalf("expected server mute to survive rejoin, got %+v", state)
+	}
+
+	h.SetServerVoiceState("usr_1", &unmuted, nil)
+	if state := h.GetUserVoiceState("usr_1"); state == nil || state.ServerMuted {
+		t.Fatalf("expected server mute to be lifted, got %+v", state)
+	}
+}
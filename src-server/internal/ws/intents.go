@@ -0,0 +1,92 @@
+package ws
+
+import "github.com/frisksitron/lobby/src-server/pkg/lobbyclient"
+
+const (
+	IntentMessages      = lobbyclient.IntentMessages
+	IntentTyping        = lobbyclient.IntentTyping
+	IntentPresence      = lobbyclient.IntentPresence
+	IntentMembers       = lobbyclient.IntentMembers
+	IntentVoice         = lobbyclient.IntentVoice
+	IntentVoiceSpeaking = lobbyclient.IntentVoiceSpeaking
+	IntentScreenShare   = lobbyclient.IntentScreenShare
+	IntentChannel       = lobbyclient.IntentChannel
+	IntentServer        = lobbyclient.IntentServer
+	IntentNotifications = lobbyclient.IntentNotifications
+	IntentModeration    = lobbyclient.IntentModeration
+	IntentDrafts        = lobbyclient.IntentDrafts
+	IntentVideo         = lobbyclient.IntentVideo
+)
+
+// intentMask holds one bit per intent. Clients store the intents they did
+// not ask for, so the zero value receives everything.
+type intentMask uint32
+
+var intentBits = map[string]intentMask{
+	IntentMessages:      1 << 0,
+	IntentTyping:        1 << 1,
+	IntentPresence:      1 << 2,
+	IntentMembers:       1 << 3,
+	IntentVoice:         1 << 4,
+	IntentVoiceSpeaking: 1 << 5,
+	IntentScreenShare:   1 << 6,
+	IntentChannel:       1 << 7,
+	IntentServer:        1 << 8,
+	IntentNotifications: 1 << 9,
+	IntentModeration:    1 << 10,
+	IntentDrafts:        1 << 11,
+	IntentVideo:         1 << 12,
+}
+
+var eventIntents = map[string]intentMask{
+	EventMessageCreate:     intentBits[IntentMessages],
+	EventMessagesPurged:    intentBits[IntentMessages],
+	EventTypingStart:       intentBits[IntentTyping],
+	EventTypingStop:        intentBits[IntentTyping],
+	EventPresenceUpdate:    intentBits[IntentPresence],
+	EventUserJoined:        intentBits[IntentMembers],
+	EventUserLeft:          intentBits[IntentMembers],
+	EventUserUpdate:        intentBits[IntentMembers],
+	EventVoiceStateUpdate:  intentBits[IntentVoice],
+	EventVoiceSpeaking:     intentBits[IntentVoiceSpeaking],
+	EventScreenShareUpdate: intentBits[IntentScreenShare],
+	EventChannelUpdate:     intentBits[IntentChannel],
+	EventServerUpdate:      intentBits[IntentServer],
+	EventNotification:      intentBits[IntentNotifications],
+	EventAutomodAlert:      intentBits[IntentModeration],
+	EventModAlert:          intentBits[IntentModeration],
+	EventDraftUpdate:       intentBits[IntentDrafts],
+	EventVideoState:        intentBits[IntentVideo],
+}
+
+// parseIntents returns the mask of intents missing from names, which
+// must all be known. Empty names filters nothing.
+func parseIntents(names []string) (intentMask, bool) {
+	if len(names) == 0 {
+		return 0, true
+	}
+	var wanted intentMask
+	for _, name := range names {
+		bit, ok := intentBits[name]
+		if !ok {
+			return 0, false
+		}
+		wanted |= bit
+	}
+	var all intentMask
+	for _, bit := range intentBits {
+		all |= bit
+	}
+	return all &^ wanted, true
+}
+
+// allows reports whether an event of eventType passes a filtered mask.
+// Events outside every intent always pass.
+func (filtered intentMask) allows(eventType string) bool {
+	return eventIntents[eventType]&filtered == 0
+}
+
+// wantsEvent reports whether the client asked for events of eventType.
+func (c *Client) wantsEvent(eventType string) bool {
+	return intentMask(c.filteredIntents.Load()).allows(eventType)
+}
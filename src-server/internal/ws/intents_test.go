@@ -0,0 +1,53 @@
+package ws
+
+import "testing"
+
+func TestParseIntents(t *testing.T) {
+	if filtered, ok := parseIntents(nil); !ok || filtered != 0 {
+		t.Fatalf("parseIntents(nil) = %b, %v; want nothing filtered", filtered, ok)
+	}
+	if _, ok := parseIntents([]string{IntentMessages, "reactions"}); ok {
+		t.Fatal("parseIntents accepted an unknown intent")
+	}
+
+	filtered, ok := parseIntents([]string{IntentMessages, IntentVoice})
+	if !ok {
+		t.Fatal("parseIntents rejected known intents")
+	}
+	for eventType, want := range map[string]bool{
+		EventMessageCreate:    true,
+		EventVoiceStateUpdate: true,
+		EventVoiceSpeaking:    false,
+		EventTypingStart:      false,
+		EventError:            true,
+		EventResumed:          true,
+	} {
+		if got := filtered.allows(eventType); got != want {
+			t.Errorf("allows(%s) = %v, want %v", eventType, got, want)
+		}
+	}
+}
+
+func TestDeliverSkipsFilteredIntents(t *testing.T) {
+	h := &Hub{clients: make(map[*Client]bool)}
+	quiet := newIdentifiedTestClient(h, "usr_1")
+	quiet.resume = newResumeSession("sess_1", "usr_1")
+	filtered, _ := parseIntents([]string{IntentMessages})
+	quiet.filteredIntents.Store(uint32(filtered))
+	all := newIdentifiedTestClient(h, "usr_2")
+	h.clients[quiet] = true
+	h.clients[all] = true
+
+	h.deliverToClients(Event{Type: EventTypingStart, AuthorID: "usr_3"})
+	h.deliverToClients(Event{Type: EventMessageCreate, Data: MessageCreatePayload{ID: "msg_1"}})
+
+	if all.queuedLen() != 2 {
+		t.Fatalf("unfiltered client got %d events, want 2", all.queuedLen())
+	}
+	if quiet.queuedLen() != 1 {
+		t.Fatalf("filtered client got %d events, want 1", quiet.queuedLen())
+	}
+	if msg := nextSent(quiet); msg.Type != EventMessageCreate || msg.Seq != 1 {
+		t.Fatalf("filtered client got %s seq %d, want MESSAGE_CREATE seq 1", msg.Type, msg.Seq)
+	}
+}
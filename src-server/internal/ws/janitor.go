@@ -0,0 +1,73 @@
+package ws
+
+import (
+	"log/slog"
+	"time"
+)
+
+// The janitor closes half-open connections: peers that stopped reading or
+// vanished while the TCP connection stays up. The pumps' deadlines usually
+// catch these, but only by failing inside ReadPump, which then has to get
+// its unregister through to Run. The janitor runs on the Run goroutine and
+// unregisters stalled clients itself, so their voice sessions and presence
+// are cleaned up within one sweep.
+const (
+	janitorInterval = 5 * time.Second
+
+	// Queued output that has not drained for this long means the peer
+	// stopped reading; a healthy WritePump either writes or hits writeWait
+	// well before it.
+	stalledWriteTimeout = 2 * writeWait
+
+	// Any frame, including a pong, counts as a read. Pings go out every
+	// pingPeriod, so silence this long means they are going unanswered.
+	staleReadTimeout = pongWait + writeWait
+)
+
+// markRead records that a frame arrived from the peer.
+func (c *Client) markRead() {
+	c.lastRead.Store(time.Now().UnixNano())
+}
+
+// markWritten records that a frame was handed to the connection.
+func (c *Client) markWritten() {
+	c.lastWrite.Store(time.Now().UnixNano())
+}
+
+// stalled reports why the connection looks half-open at now, or "" when it
+// does not.
+func (c *Client) stalled(now time.Time) string {
+	if lastRead := c.lastRead.Load(); lastRead != 0 && now.Sub(time.Unix(0, lastRead)) >= staleReadTimeout {
+		return "read"
+	}
+	pending := c.queuedLen() + len(c.relaySend)
+	if lastWrite := c.lastWrite.Load(); pending > 0 && lastWrite != 0 && now.Sub(time.Unix(0, lastWrite)) >= stalledWriteTimeout {
+		return "write"
+	}
+	return ""
+}
+
+// closeStalledClients closes and unregisters every stalled client. It must
+// run on the Run goroutine.
+func (h *Hub) closeStalledClients() {
+	now := time.Now()
+
+	type stalledClient struct {
+		client *Client
+		reason string
+	}
+	h.mu.RLock()
+	var stalled []stalledClient
+	for client := range h.clients {
+		if reason := client.stalled(now); reason != "" {
+			stalled = append(stalled, stalledClient{client: client, reason: reason})
+		}
+	}
+	h.mu.RUnlock()
+
+	for _, s := range stalled {
+		slog.Warn("closing half-open client", "component", "hub", "user_id", s.client.getUserID(), "stalled", s.reason, "pending", len(s.client.send))
+		s.client.Close()
+		h.handleUnregister(s.client)
+	}
+}
@@ -0,0 +1,37 @@
+package ws
+
+import (
+	"testing"
+	"time"
+)
+
+func TestClientStalled(t *testing.T) {
+	now := time.Now()
+
+	fresh := newIdentifiedTestClient(&Hub{}, "usr_1")
+	if reason := fresh.stalled(now); reason != "" {
+		t.Fatalf("fresh client stalled = %q, want none", reason)
+	}
+
+	silent := newIdentifiedTestClient(&Hub{}, "usr_2")
+	silent.lastRead.Store(now.Add(-staleReadTimeout).UnixNano())
+	if reason := silent.stalled(now); reason != "read" {
+		t.Fatalf("silent client stalled = %q, want read", reason)
+	}
+
+	idle := newIdentifiedTestClient(&Hub{}, "usr_3")
+	idle.lastWrite.Store(now.Add(-stalledWriteTimeout).UnixNano())
+	if reason := idle.stalled(now); reason != "" {
+		t.Fatalf("idle client with nothing queued stalled = %q, want none", reason)
+	}
+
+	backedUp := newIdentifiedTestClient(&Hub{}, "usr_4")
+	backedUp.send <- &WSMessage{Op: OpDispatch, Type: EventTypingStart}
+	if reason := backedUp.stalled(now); reason != "" {
+		t.Fatalf("client with recent write stalled = %q, want none", reason)
+	}
+	backedUp.lastWrite.Store(now.Add(-stalledWriteTimeout).UnixNano())
+	if reason := backedUp.stalled(now); reason != "write" {
+		t.Fatalf("backed-up client stalled = %q, want write", reason)
+	}
+}
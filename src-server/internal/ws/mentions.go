@@ -0,0 +1,101 @@
+package ws
+
+import (
+	"context"
+	"log/slog"
+
+	"lobby/internal/constants"
+	"lobby/internal/models"
+)
+
+// dispatchMentionNotifications sends NOTIFICATION to every user message
+// mentions, directly or through @here, @everyone, or a role, and marks them
+// in notified. Group mentions only count when the author's role reaches
+// permissions.mention_everyone_role.
+func (h *Hub) dispatchMentionNotifications(message MessageCreatePayload, notified map[string]struct{}) {
+	mentions := models.ParseMentions(message.Content)
+	if mentions.IsEmpty() {
+		return
+	}
+
+	candidates, err := h.queries.ListMentionCandidates(context.Background(), constants.TextChannelID)
+	if err != nil {
+		slog.Error("error loading mention candidates", "component", "hub", "message_id", message.ID, "error", err)
+		return
+	}
+
+	groupsAllowed := false
+	if mentions.HasGroup() {
+		for _, candidate := range candidates {
+			if candidate.ID == message.Author.ID {
+				groupsAllowed = models.RoleAtLeast(candidate.Role, h.permissions.MentionEveryoneRole)
+				break
+			}
+		}
+	}
+	var online map[string]bool
+	if groupsAllowed && mentions.Here {
+		online = h.onlineUserIDs()
+	}
+
+	for _, candidate := range candidates {
+		if candidate.ID == message.Author.ID {
+			continue
+		}
+		if _, done := notified[candidate.ID]; done {
+			continue
+		}
+
+		kind := mentions.ResolveMention(models.MentionRecipient{
+			Username:         candidate.Username,
+			Role:             candidate.Role,
+			Online:           online[candidate.ID],
+			SuppressEveryone: candidate.SuppressEveryone,
+			SuppressRoles:    candidate.SuppressRoles,
+		}, groupsAllowed)
+		if kind == "" || !models.ShouldNotify(candidate.Level, true) {
+			continue
+		}
+		recipient := &models.User{ID: candidate.ID, Role: candidate.Role}
+		if !h.CanAccessChannel(recipient) {
+			continue
+		}
+
+		notified[candidate.ID] = struct{}{}
+		recipientMessage := message
+		recipientMessage.Content = h.maskContentFor(recipient, message.Content)
+		recipientMessage.Embeds = h.messageEmbeds(recipientMessage.Content)
+		h.Publish(Event{
+			Topic:    TopicNotification,
+			Type:     EventNotification,
+			Audience: AudienceUser,
+			UserID:   candidate.ID,
+			AuthorID: message.Author.ID,
+			Data: NotificationPayload{
+				ChannelID: constants.TextChannelID,
+				Mention:   kind,
+				Message:   recipientMessage,
+			},
+		})
+	}
+}
+
+// onlineUserIDs returns the users connected to this or another instance with
+// a status other than offline.
+func (h *Hub) onlineUserIDs() map[string]bool {
+	online := make(map[string]bool)
+	for userID, rec := range h.remoteMembers() {
+		if rec.Status != "offline" {
+			online[userID] = true
+		}
+	}
+
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	for userID, client := range h.userClients {
+		if client.IsIdentified() && client.GetStatus() != "offline" {
+			online[userID] = true
+		}
+	}
+	return online
+}
@@ -0,0 +1,125 @@
+package ws
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"lobby/internal/config"
+	"lobby/internal/constants"
+	sqldb "lobby/internal/db/sqlc"
+	"lobby/internal/models"
+)
+
+func TestParseMentions(t *testing.T) {
+	m := models.ParseMentions("<p>@Bob and @here, ping @moderators (mail carol@example.com) @bob</p>")
+	if !slices.Equal(m.Usernames, []string{"bob"}) || !m.Here || m.Everyone || !slices.Equal(m.Roles, []string{models.RoleModerator}) {
+		t.Fatalf("ParseMentions() = %+v", m)
+	}
+	if m := models.ParseMentions("no mentions, just alice@example.com"); !m.IsEmpty() {
+		t.Fatalf("expected no mentions, got %+v", m)
+	}
+}
+
+func TestResolveMention(t *testing.T) {
+	everyone := models.ParseMentions("@everyone")
+	roles := models.ParseMentions("@moderators")
+	here := models.ParseMentions("@here")
+
+	tests := []struct {
+		name      string
+		mentions  models.Mentions
+		recipient models.MentionRecipient
+		allowed   bool
+		want      string
+	}{
+		{name: "direct beats suppression", mentions: models.ParseMentions("@everyone @bob"), recipient: models.MentionRecipient{Username: "Bob", SuppressEveryone: true}, want: models.MentionUser},
+		{name: "everyone", mentions: everyone, recipient: models.MentionRecipient{Username: "bob"}, allowed: true, want: models.MentionEveryone},
+		{name: "everyone not permitted", mentions: everyone, recipient: models.MentionRecipient{Username: "bob"}},
+		{name: "everyone suppressed", mentions: everyone, recipient: models.MentionRecipient{Username: "bob", SuppressEveryone: true}, allowed: true},
+		{name: "role reaches higher roles", mentions: roles, recipient: models.MentionRecipient{Role: models.RoleAdmin}, allowed: true, want: models.MentionRole},
+		{name: "role skips lower roles", mentions: roles, recipient: models.MentionRecipient{Role: models.RoleMember}, allowed: true},
+		{name: "role suppressed", mentions: roles, recipient: models.MentionRecipient{Role: models.RoleModerator, SuppressRoles: true}, allowed: true},
+		{name: "here online", mentions: here, recipient: models.MentionRecipient{Online: true}, allowed: true, want: models.MentionHere},
+		{name: "here offline", mentions: here, recipient: models.MentionRecipient{}, allowed: true},
+	}
+	for _, tt := range tests {
+		if got := tt.mentions.ResolveMention(tt.recipient, tt.allowed); got != tt.want {
+			t.Errorf("%s: ResolveMention() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestDispatchNotificationsExpandsGroupMentions(t *testing.T) {
+	h := openBotTestHub(t)
+	h.permissions = config.PermissionsConfig{MentionEveryoneRole: models.RoleModerator}
+	ctx := context.Background()
+	now := time.Now().UTC()
+	if err := h.queries.CreateUser(ctx, sqldb.CreateUserParams{
+		ID: "usr_3", Username: "carol", Email: "carol@example.com", CreatedAt: now,
+	}); err != nil {
+		t.Fatalf("CreateUser() error = %v", err)
+	}
+	if _, err := h.queries.SetUserRole(ctx, sqldb.SetUserRoleParams{Role: models.RoleModerator, UpdatedAt: &now, ID: "usr_1"}); err != nil {
+		t.Fatalf("SetUserRole() error = %v", err)
+	}
+	if err := h.queries.UpsertChannelNotificationSettings(ctx, sqldb.UpsertChannelNotificationSettingsParams{
+		UserID:           "usr_3",
+		ChannelID:        constants.TextChannelID,
+		Level:            models.NotificationAll,
+		SuppressEveryone: true,
+		UpdatedAt:        now,
+	}); err != nil {
+		t.Fatalf("UpsertChannelNotificationSettings() error = %v", err)
+	}
+
+	clients := map[string]*Client{}
+	for _, userID := range []string{"usr_1", "usr_2", "usr_3"} {
+		c := newIdentifiedTestClient(h, userID)
+		h.clients[c] = true
+		clients[userID] = c
+	}
+
+	h.dispatchNotifications(MessageCreatePayload{
+		ID:      "msg_1",
+		Author:  &MessageAuthor{ID: "usr_1", Username: "alice"},
+		Content: "@everyone standup in 5",
+	})
+	if payload := receiveNotification(t, clients["usr_2"]); payload.Mention != models.MentionEveryone || payload.RuleID != "" {
+		t.Fatalf("unexpected payload: %+v", payload)
+	}
+	for _, userID := range []string{"usr_1", "usr_3"} {
+		if clients[userID].queuedLen() != 0 {
+			t.Fatalf("expected no notification for %s", userID)
+		}
+	}
+
+	// Members may not ping everyone, but direct mentions still work.
+	h.dispatchNotifications(MessageCreatePayload{
+		ID:      "msg_2",
+		Author:  &MessageAuthor{ID: "usr_2", Username: "bob"},
+		Content: "@everyone look, @Carol",
+	})
+	if payload := receiveNotification(t, clients["usr_3"]); payload.Mention != models.MentionUser {
+		t.Fatalf("unexpected payload: %+v", payload)
+	}
+	if clients["usr_1"].queuedLen() != 0 {
+		t.Fatal("expected @everyone from a member to be ignored")
+	}
+}
+
+func receiveNotification(t *testing.T, c *Client) NotificationPayload {
+	t.Helper()
+
+	if msg := nextSent(c); msg != nil {
+		payload, ok := msg.Data.(NotificationPayload)
+		if msg.Type != EventNotification || !ok {
+			t.Fatalf("expected %s, got type=%s data=%T", EventNotification, msg.Type, msg.Data)
+		}
+		return payload
+	} else {
+		t.Fatal("expected notification")
+	}
+	return NotificationPayload{}
+}
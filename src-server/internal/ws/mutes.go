@@ -0,0 +1,73 @@
+package ws
+
+import (
+	"context"
+
+	"lobby/internal/cluster"
+)
+
+// ReloadUserMutes refreshes the cached text mutes of viewerID and tells other
+// instances to do the same.
+func (h *Hub) ReloadUserMutes(ctx context.Context, viewerID string) error {
+	if err := h.reloadUserMutes(ctx, viewerID); err != nil {
+		return err
+	}
+	if h.backplane != nil {
+		h.enqueueClusterTask(clusterTask{env: &cluster.Envelope{
+			Instance: h.instanceID,
+			Type:     cluster.TypeUserMutes,
+			UserID:   viewerID,
+		}})
+	}
+	return nil
+}
+
+func (h *Hub) reloadUserMutes(ctx context.Context, viewerID string) error {
+	rows, err := h.queries.ListUserMutes(ctx, viewerID)
+	if err != nil {
+		return err
+	}
+
+	muted := make(map[string]struct{}, len(rows))
+	for _, row := range rows {
+		muted[row.MutedUserID] = struct{}{}
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if len(muted) == 0 {
+		delete(h.userMutes, viewerID)
+		return nil
+	}
+	if h.userMutes == nil {
+		h.userMutes = make(map[string]map[string]struct{})
+	}
+	h.userMutes[viewerID] = muted
+	return nil
+}
+
+func (h *Hub) loadAllUserMutes(ctx context.Context) error {
+	rows, err := h.queries.ListAllUserMutes(ctx)
+	if err != nil {
+		return err
+	}
+
+	mutes := make(map[string]map[string]struct{})
+	for _, row := range rows {
+		if mutes[row.UserID] == nil {
+			mutes[row.UserID] = make(map[string]struct{})
+		}
+		mutes[row.UserID][row.MutedUserID] = struct{}{}
+	}
+
+	h.mu.Lock()
+	h.userMutes = mutes
+	h.mu.Unlock()
+	return nil
+}
+
+// hasMutedLocked reports whether viewerID muted authorID. h.mu must be held.
+func (h *Hub) hasMutedLocked(viewerID, authorID string) bool {
+	_, ok := h.userMutes[viewerID][authorID]
+	return ok
+}
@@ -0,0 +1,79 @@
+package ws
+
+import (
+	"context"
+	"log/slog"
+
+	"lobby/internal/constants"
+	"lobby/internal/models"
+)
+
+// routeNotifications is the event bus subscriber that evaluates notification
+// rules for new messages. Rules are loaded from the database, so evaluation
+// runs off the publishing goroutine.
+func (h *Hub) routeNotifications(e Event) {
+	if e.Type != EventMessageCreate {
+		return
+	}
+	message, ok := e.Data.(MessageCreatePayload)
+	if !ok || message.Author == nil {
+		return
+	}
+	go h.dispatchNotifications(message)
+}
+
+// dispatchNotifications sends NOTIFICATION to every user message mentions or
+// with a rule matching it, at most once per user. Recipients must be able to
+// read the channel and must not have muted it.
+func (h *Hub) dispatchNotifications(message MessageCreatePayload) {
+	notified := make(map[string]struct{})
+	h.dispatchMentionNotifications(message, notified)
+
+	rules, err := h.queries.ListNotificationRulesForChannel(context.Background(), constants.TextChannelID)
+	if err != nil {
+		slog.Error("error loading notification rules", "component", "hub", "message_id", message.ID, "error", err)
+		return
+	}
+
+	for _, rule := range rules {
+		if rule.UserID == message.Author.ID {
+			continue
+		}
+		if _, done := notified[rule.UserID]; done {
+			continue
+		}
+
+		authorFilter := ""
+		if rule.AuthorID != nil {
+			authorFilter = *rule.AuthorID
+		}
+		if !models.NotificationRuleMatches(rule.Keyword, authorFilter, message.Content, message.Author.ID) {
+			continue
+		}
+		// Rule matches count as mentions for the channel notification level
+		if !models.ShouldNotify(rule.Level, true) {
+			continue
+		}
+		if !h.CanAccessChannel(&models.User{ID: rule.UserID, Role: rule.Role}) {
+			continue
+		}
+
+		notified[rule.UserID] = struct{}{}
+		recipientMessage := message
+		recipientMessage.Content = h.maskContentFor(&models.User{ID: rule.UserID, Role: rule.Role}, message.Content)
+		recipientMessage.Embeds = h.messageEmbeds(recipientMessage.Content)
+		h.Publish(Event{
+			Topic:    TopicNotification,
+			Type:     EventNotification,
+			Audience: AudienceUser,
+			UserID:   rule.UserID,
+			AuthorID: message.Author.ID,
+			Data: NotificationPayload{
+				RuleID:    rule.ID,
+				ChannelID: constants.TextChannelID,
+				Keyword:   rule.Keyword,
+				Message:   recipientMessage,
+			},
+		})
+	}
+}
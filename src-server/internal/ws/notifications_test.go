@@ -0,0 +1,154 @@
+package ws
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"lobby/internal/constants"
+	sqldb "lobby/internal/db/sqlc"
+	"lobby/internal/models"
+)
+
+func TestDispatchNotificationsTargetsMatchingRuleOwners(t *testing.T) {
+	h := openBotTestHub(t)
+	ctx := context.Background()
+	now := time.Now().UTC()
+	if err := h.queries.CreateUser(ctx, sqldb.CreateUserParams{
+		ID: "usr_3", Username: "carol", Email: "carol@example.com", CreatedAt: now,
+	}); err != nil {
+		t.Fatalf("CreateUser() error = %v", err)
+	}
+
+	alice := "usr_1"
+	channelID := constants.TextChannelID
+	for _, rule := range []sqldb.CreateNotificationRuleParams{
+		{ID: "ntr_bob", UserID: "usr_2", Keyword: "deploy", CreatedAt: now},
+		{ID: "ntr_bob_author", UserID: "usr_2", AuthorID: &alice, ChannelID: &channelID, CreatedAt: now},
+		{ID: "ntr_carol", UserID: "usr_3", Keyword: "deploy", CreatedAt: now},
+		{ID: "ntr_alice", UserID: "usr_1", Keyword: "deploy", CreatedAt: now},
+	} {
+		if err := h.queries.CreateNotificationRule(ctx, rule); err != nil {
+			t.Fatalf("CreateNotificationRule() error = %v", err)
+		}
+	}
+	if err := h.queries.UpsertChannelNotificationLevel(ctx, sqldb.UpsertChannelNotificationLevelParams{
+		UserID:    "usr_3",
+		ChannelID: constants.TextChannelID,
+		Level:     models.NotificationMuted,
+		UpdatedAt: now,
+	}); err != nil {
+		t.Fatalf("UpsertChannelNotificationLevel() error = %v", err)
+	}
+
+	clients := map[string]*Client{}
+	for _, userID := range []string{"usr_1", "usr_2", "usr_3"} {
+		c := newIdentifiedTestClient(h, userID)
+		h.clients[c] = true
+		clients[userID] = c
+	}
+
+	h.dispatchNotifications(MessageCreatePayload{
+		ID:      "msg_1",
+		Author:  &MessageAuthor{ID: "usr_1", Username: "alice"},
+		Content: "Deploy is done, redeploying tomorrow",
+	})
+
+	if msg := nextSent(clients["usr_2"]); msg != nil {
+		payload, ok := msg.Data.(NotificationPayload)
+		if msg.Type != EventNotification || !ok {
+			t.Fatalf("expected %s, got type=%s data=%T", EventNotification, msg.Type, msg.Data)
+		}
+		if payload.RuleID != "ntr_bob" || payload.Message.ID != "msg_1" {
+			t.Fatalf("unexpected payload: %+v", payload)
+		}
+	} else {
+		t.Fatal("expected notification for rule owner")
+	}
+	for userID, c := range clients {
+		if msg := nextSent(c); msg != nil {
+			t.Fatalf("unexpected message for %s: type=%s", userID, msg.Type)
+		}
+	}
+}
+
+func TestDispatchNotificationsSkipsMutedAuthors(t *testing.T) {
+	h := openBotTestHub(t)
+	ctx := context.Background()
+	now := time.Now().UTC()
+	if err := h.queries.CreateUser(ctx, sqldb.CreateUserParams{
+		ID: "usr_3", Username: "carol", Email: "carol@example.com", CreatedAt: now,
+	}); err != nil {
+		t.Fatalf("CreateUser() error = %v", err)
+	}
+	if err := h.queries.CreateNotificationRule(ctx, sqldb.CreateNotificationRuleParams{
+		ID: "ntr_bob", UserID: "usr_2", Keyword: "deploy", CreatedAt: now,
+	}); err != nil {
+		t.Fatalf("CreateNotificationRule() error = %v", err)
+	}
+	// Bob is notified by a rule and Carol by a mention; both muted Alice.
+	for _, viewerID := range []string{"usr_2", "usr_3"} {
+		if err := h.queries.AddUserMute(ctx, sqldb.AddUserMuteParams{UserID: viewerID, MutedUserID: "usr_1", CreatedAt: now}); err != nil {
+			t.Fatalf("AddUserMute() error = %v", err)
+		}
+		if err := h.ReloadUserMutes(ctx, viewerID); err != nil {
+			t.Fatalf("ReloadUserMutes() error = %v", err)
+		}
+	}
+
+	clients := map[string]*Client{}
+	for _, userID := range []string{"usr_2", "usr_3"} {
+		c := newIdentifiedTestClient(h, userID)
+		h.clients[c] = true
+		clients[userID] = c
+	}
+
+	h.dispatchNotifications(MessageCreatePayload{
+		ID:      "msg_1",
+		Author:  &MessageAuthor{ID: "usr_1", Username: "alice"},
+		Content: "deploy is done @Carol",
+	})
+	for userID, c := range clients {
+		if msg := nextSent(c); msg != nil {
+			t.Fatalf("unexpected message for %s: type=%s", userID, msg.Type)
+		}
+	}
+
+	if _, err := h.queries.DeleteUserMute(ctx, sqldb.DeleteUserMuteParams{UserID: "usr_3", MutedUserID: "usr_1"}); err != nil {
+		t.Fatalf("DeleteUserMute() error = %v", err)
+	}
+	if err := h.ReloadUserMutes(ctx, "usr_3"); err != nil {
+		t.Fatalf("ReloadUserMutes() error = %v", err)
+	}
+	h.dispatchNotifications(MessageCreatePayload{
+		ID:      "msg_2",
+		Author:  &MessageAuthor{ID: "usr_1", Username: "alice"},
+		Content: "deploy is done @Carol",
+	})
+	if payload := receiveNotification(t, clients["usr_3"]); payload.Mention != models.MentionUser {
+		t.Fatalf("unexpected payload after unmute: %+v", payload)
+	}
+	if clients["usr_2"].queuedLen() != 0 {
+		t.Fatal("expected no notification for usr_2, who still mutes the author")
+	}
+}
+
+func TestNotificationRuleMatches(t *testing.T) {
+	tests := []struct {
+		keyword, author, content, authorID string
+		want                               bool
+	}{
+		{keyword: "deploy", content: "time to DEPLOY!", authorID: "usr_1", want: true},
+		{keyword: "deploy", content: "redeploy later", authorID: "usr_1", want: false},
+		{keyword: "on call", content: "who is on call today", authorID: "usr_1", want: true},
+		{author: "usr_1", content: "anything", authorID: "usr_1", want: true},
+		{keyword: "deploy", author: "usr_2", content: "deploy", authorID: "usr_1", want: false},
+		{content: "deploy", authorID: "usr_1", want: false},
+	}
+	for _, tt := range tests {
+		if got := models.NotificationRuleMatches(tt.keyword, tt.author, tt.content, tt.authorID); got != tt.want {
+			t.Fatalf("NotificationRuleMatches(%q, %q, %q, %q) = %v, want %v",
+				tt.keyword, tt.author, tt.content, tt.authorID, got, tt.want)
+		}
+	}
+}
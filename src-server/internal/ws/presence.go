@@ -0,0 +1,73 @@
+package ws
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Custom status limits. The emoji is one emoji, possibly a multi-codepoint
+// ZWJ sequence, or a :shortcode:, so it is capped in bytes rather than runes.
+const (
+	maxStatusTextLength  = 128
+	maxStatusEmojiLength = 32
+)
+
+// normalizeStatusText trims text and reports whether it is a valid custom
+// status text. "" is valid and clears it.
+func normalizeStatusText(text string) (string, bool) {
+	text = strings.TrimSpace(text)
+	if !utf8.ValidString(text) || utf8.RuneCountInString(text) > maxStatusTextLength {
+		return "", false
+	}
+	for _, r := range text {
+		if unicode.IsControl(r) {
+			return "", false
+		}
+	}
+	return text, true
+}
+
+// normalizeStatusEmoji trims emoji and reports whether it is a valid custom
+// status emoji. "" is valid and clears it.
+func normalizeStatusEmoji(emoji string) (string, bool) {
+	emoji = strings.TrimSpace(emoji)
+	if !utf8.ValidString(emoji) || len(emoji) > maxStatusEmojiLength {
+		return "", false
+	}
+	for _, r := range emoji {
+		if unicode.IsControl(r) || unicode.IsSpace(r) {
+			return "", false
+		}
+	}
+	return emoji, true
+}
+
+// CustomStatus returns the client's custom status text and emoji.
+func (c *Client) CustomStatus() (text, emoji string) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.statusText, c.statusEmoji
+}
+
+// SetCustomStatus sets the client's custom status text and emoji, which must
+// already be normalized.
+func (c *Client) SetCustomStatus(text, emoji string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.statusText = text
+	c.statusEmoji = emoji
+}
+
+// presenceUpdate returns the client's current presence. Invisible users
+// (status offline) do not show their custom status.
+func (c *Client) presenceUpdate() PresenceUpdatePayload {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	presence := PresenceUpdatePayload{UserID: c.getUserID(), Status: c.status}
+	if c.status != "offline" {
+		presence.StatusText = c.statusText
+		presence.StatusEmoji = c.statusEmoji
+	}
+	return presence
+}
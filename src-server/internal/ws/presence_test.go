@@ -0,0 +1,81 @@
+package ws
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNormalizeCustomStatus(t *testing.T) {
+	if text, ok := normalizeStatusText("  in a meeting  "); !ok || text != "in a meeting" {
+		t.Fatalf("normalizeStatusText() = %q, %v", text, ok)
+	}
+	for _, bad := range []string{strings.Repeat("a", maxStatusTextLength+1), "two\nlines"} {
+		if _, ok := normalizeStatusText(bad); ok {
+			t.Fatalf("normalizeStatusText(%q) accepted", bad)
+		}
+	}
+
+	for _, good := range []string{"", "🎧", "👩‍💻", ":coffee:"} {
+		if _, ok := normalizeStatusEmoji(good); !ok {
+			t.Fatalf("normalizeStatusEmoji(%q) rejected", good)
+		}
+	}
+	for _, bad := range []string{"🎧 🎧", strings.Repeat("🎧", 9)} {
+		if _, ok := normalizeStatusEmoji(bad); ok {
+			t.Fatalf("normalizeStatusEmoji(%q) accepted", bad)
+		}
+	}
+}
+
+func TestHandlePresenceSetCustomStatus(t *testing.T) {
+	h := &Hub{broadcast: make(chan *WSMessage, 4)}
+	c := newIdentifiedTestClient(h, "usr_1")
+
+	c.handlePresenceSet(&WSMessage{
+		Op:   OpDispatch,
+		Type: CmdPresenceSet,
+		Data: map[string]interface{}{"status": "dnd", "status_text": " focusing ", "status_emoji": "🎧"},
+	})
+	update := (<-h.broadcast).Data.(PresenceUpdatePayload)
+	if update.Status != "dnd" || update.StatusText != "focusing" || update.StatusEmoji != "🎧" {
+		t.Fatalf("PRESENCE_UPDATE = %+v", update)
+	}
+
+	// Omitted fields keep the custom status; offline hides it.
+	c.handlePresenceSet(&WSMessage{Op: OpDispatch, Type: CmdPresenceSet, Data: map[string]interface{}{"status": "offline"}})
+	if update := (<-h.broadcast).Data.(PresenceUpdatePayload); update.StatusText != "" || update.StatusEmoji != "" {
+		t.Fatalf("offline PRESENCE_UPDATE = %+v, want no custom status", update)
+	}
+	if text, emoji := c.CustomStatus(); text != "focusing" || emoji != "🎧" {
+		t.Fatalf("CustomStatus() = %q, %q after going offline", text, emoji)
+	}
+
+	c.handlePresenceSet(&WSMessage{Op: OpDispatch, Type: CmdPresenceSet, Data: map[string]interface{}{"status": "online", "status_text": ""}})
+	if update := (<-h.broadcast).Data.(PresenceUpdatePayload); update.StatusText != "" || update.StatusEmoji != "🎧" {
+		t.Fatalf("PRESENCE_UPDATE = %+v, want text cleared and emoji kept", update)
+	}
+}
+
+func TestHandlePresenceSetRejectsInvalidCustomStatus(t *testing.T) {
+	h := &Hub{broadcast: make(chan *WSMessage, 4)}
+	c := newIdentifiedTestClient(h, "usr_1")
+	c.SetStatus("online")
+
+	c.handlePresenceSet(&WSMessage{
+		Op:   OpDispatch,
+		Type: CmdPresenceSet,
+		Data: map[string]interface{}{"status": "idle", "status_emoji": "not an emoji", "nonce": "n1"},
+	})
+
+	msg := <-c.send
+	payload, ok := msg.Data.(ErrorPayload)
+	if msg.Type != EventError || !ok || payload.Code != ErrCodeInvalidRequest || payload.Nonce != "n1" {
+		t.Fatalf("expected INVALID_REQUEST error, got type=%s data=%+v", msg.Type, msg.Data)
+	}
+	if c.GetStatus() != "online" {
+		t.Fatalf("status = %s, want it unchanged after a rejected PRESENCE_SET", c.GetStatus())
+	}
+	if len(h.broadcast) != 0 {
+		t.Fatal("rejected PRESENCE_SET was broadcast")
+	}
+}
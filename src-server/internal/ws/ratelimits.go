@@ -0,0 +1,218 @@
+package ws
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/frisksitron/lobby/src-server/pkg/lobbyclient"
+
+	"lobby/internal/config"
+)
+
+type (
+	RateLimitStatus        = lobbyclient.RateLimitStatus
+	RateLimitStatusPayload = lobbyclient.RateLimitStatusPayload
+)
+
+const (
+	EventRateLimitStatus = lobbyclient.EventRateLimitStatus
+	CmdRateLimitStatus   = lobbyclient.CmdRateLimitStatus
+
+	RateLimitMessageSend          = lobbyclient.RateLimitMessageSend
+	RateLimitVoiceJoin            = lobbyclient.RateLimitVoiceJoin
+	RateLimitVoiceToggle          = lobbyclient.RateLimitVoiceToggle
+	RateLimitRTCSignaling         = lobbyclient.RateLimitRTCSignaling
+	RateLimitScreenShareSignaling = lobbyclient.RateLimitScreenShareSignaling
+)
+
+// Built-in command budgets, used for fields the config leaves at 0
+const (
+	// 5 messages per second
+	messageSendLimit  = 1
+	messageSendWindow = 200 * time.Millisecond
+
+	// 3 joins in 15s triggers a 15s cooldown
+	voiceJoinLimit    = 3
+	voiceJoinWindow   = 15 * time.Second
+	voiceJoinCooldown = 15 * time.Second
+
+	// 5 unmute/undeafen toggles in 5s triggers a 10s cooldown
+	voiceToggleLimit      = 5
+	voiceToggleWindow     = 5 * time.Second
+	voiceCooldownDuration = 10 * time.Second
+
+	rtcSignalingLimit  = 300
+	rtcSignalingWindow = 10 * time.Second
+
+	screenShareSignalingLimit  = 40
+	screenShareSignalingWindow = 10 * time.Second
+)
+
+// rateLimitRule allows limit commands per window. With a cooldown, reaching
+// limit blocks the bucket for that long.
+type rateLimitRule struct {
+	limit    int
+	window   time.Duration
+	cooldown time.Duration
+}
+
+// builtinRateLimits are the buckets RATE_LIMIT_STATUS reports, in order.
+var builtinRateLimits = []struct {
+	bucket string
+	rule   rateLimitRule
+}{
+	{RateLimitMessageSend, rateLimitRule{messageSendLimit, messageSendWindow, 0}},
+	{RateLimitVoiceJoin, rateLimitRule{voiceJoinLimit, voiceJoinWindow, voiceJoinCooldown}},
+	{RateLimitVoiceToggle, rateLimitRule{voiceToggleLimit, voiceToggleWindow, voiceCooldownDuration}},
+	{RateLimitRTCSignaling, rateLimitRule{rtcSignalingLimit, rtcSignalingWindow, 0}},
+	{RateLimitScreenShareSignaling, rateLimitRule{screenShareSignalingLimit, screenShareSignalingWindow, 0}},
+}
+
+// SetRateLimits overrides the built-in command budgets with the non-zero
+// fields of limits. Must be called before Run.
+func (h *Hub) SetRateLimits(limits config.CommandRateLimits) {
+	h.rateLimits = map[string]config.CommandRateLimit{
+		RateLimitMessageSend:          limits.MessageSend,
+		RateLimitVoiceJoin:            limits.VoiceJoin,
+		RateLimitVoiceToggle:          limits.VoiceToggle,
+		RateLimitRTCSignaling:         limits.RTCSignaling,
+		RateLimitScreenShareSignaling: limits.ScreenShareSignaling,
+	}
+}
+
+// rateLimitRule returns the budget of a built-in bucket.
+func (h *Hub) rateLimitRule(bucket string) rateLimitRule {
+	var rule rateLimitRule
+	for _, builtin := range builtinRateLimits {
+		if builtin.bucket == bucket {
+			rule = builtin.rule
+		}
+	}
+	override := h.rateLimits[bucket]
+	if override.Limit > 0 {
+		rule.limit = override.Limit
+	}
+	if override.Window > 0 {
+		rule.window = override.Window
+	}
+	if override.Cooldown > 0 {
+		rule.cooldown = override.Cooldown
+	}
+	return rule
+}
+
+// takeRateLimit counts a command against bucket. When rule rejects it, it
+// returns false and the bucket's status for the error.
+func (c *Client) takeRateLimit(bucket string, rule rateLimitRule) (bool, *RateLimitStatus) {
+	now := c.hub.now()
+	if now.Before(c.rateCooldowns[bucket]) {
+		return false, c.rateLimitStatus(bucket, rule)
+	}
+	if c.commandRates == nil {
+		c.commandRates = make(map[string][]time.Time)
+	}
+	times := c.commandRates[bucket]
+
+	if rule.cooldown > 0 {
+		// The command that reaches the limit is rejected and starts the cooldown.
+		times = append(recentCommands(times, now, rule.window), now)
+		if len(times) >= rule.limit {
+			if c.rateCooldowns == nil {
+				c.rateCooldowns = make(map[string]time.Time)
+			}
+			c.rateCooldowns[bucket] = now.Add(rule.cooldown)
+			c.commandRates[bucket] = times[:0]
+			return false, c.rateLimitStatus(bucket, rule)
+		}
+		c.commandRates[bucket] = times
+		return true, nil
+	}
+
+	ok, _ := c.allowCommandRateLimit(&times, rule.limit, rule.window)
+	c.commandRates[bucket] = times
+	if !ok {
+		return false, c.rateLimitStatus(bucket, rule)
+	}
+	return true, nil
+}
+
+// rateLimitStatus reports bucket's remaining budget without counting a
+// command.
+func (c *Client) rateLimitStatus(bucket string, rule rateLimitRule) *RateLimitStatus {
+	now := c.hub.now()
+	status := &RateLimitStatus{
+		Bucket:   bucket,
+		Limit:    rule.limit,
+		Window:   rule.window.Milliseconds(),
+		Cooldown: rule.cooldown.Milliseconds(),
+	}
+	if cooldownAt := c.rateCooldowns[bucket]; now.Before(cooldownAt) {
+		status.ResetAt = cooldownAt.UnixMilli()
+		return status
+	}
+
+	var recent int
+	for _, t := range c.commandRates[bucket] {
+		if t.After(now.Add(-rule.window)) {
+			if recent == 0 {
+				status.ResetAt = t.Add(rule.window).UnixMilli()
+			}
+			recent++
+		}
+	}
+	allowed := rule.limit
+	if rule.cooldown > 0 {
+		allowed--
+	}
+	status.Remaining = max(allowed-recent, 0)
+	return status
+}
+
+// recentCommands drops the times in place that are outside window.
+func recentCommands(times []time.Time, now time.Time, window time.Duration) []time.Time {
+	cutoff := now.Add(-window)
+	recent := times[:0]
+	for _, t := range times {
+		if t.After(cutoff) {
+			recent = append(recent, t)
+		}
+	}
+	return recent
+}
+
+// hubRateLimit is RateLimit for a built-in bucket, read from the hub's
+// config when the command runs.
+func hubRateLimit(bucket string) CommandMiddleware {
+	return func(command string, next CommandHandler) CommandHandler {
+		return func(c *Client, msg *WSMessage) {
+			if !c.allowSignaling(command, bucket, c.hub.rateLimitRule(bucket)) {
+				return
+			}
+			next(c, msg)
+		}
+	}
+}
+
+// allowSignaling counts a command against bucket and answers a rejection
+// with SIGNALING_RATE_LIMITED.
+func (c *Client) allowSignaling(command, bucket string, rule rateLimitRule) bool {
+	ok, status := c.takeRateLimit(bucket, rule)
+	if !ok {
+		c.sendError(ErrorPayload{
+			Code:       ErrCodeSignalingRateLimited,
+			Message:    "",
+			RetryAfter: status.ResetAt,
+			RateLimit:  status,
+		})
+		slog.Warn("signaling command rate limited", "component", "ws", "user_id", c.getUserID(), "command", command, "retry_after", status.ResetAt)
+	}
+	return ok
+}
+
+func (c *Client) handleRateLimitStatus() {
+	payload := RateLimitStatusPayload{Buckets: make([]RateLimitStatus, 0, len(builtinRateLimits))}
+	for _, builtin := range builtinRateLimits {
+		payload.Buckets = append(payload.Buckets, *c.rateLimitStatus(builtin.bucket, c.hub.rateLimitRule(builtin.bucket)))
+	}
+	c.send <- &WSMessage{Op: OpDispatch, Type: EventRateLimitStatus, Data: payload}
+}
@@ -0,0 +1,79 @@
+package ws
+
+import (
+	"testing"
+	"time"
+
+	"lobby/internal/clock"
+	"lobby/internal/config"
+)
+
+func TestTakeRateLimitWindow(t *testing.T) {
+	fake := clock.NewFake(time.Now())
+	c := newIdentifiedTestClient(&Hub{clock: fake}, "usr_1")
+	rule := rateLimitRule{limit: 2, window: 10 * time.Second}
+
+	for i := 0; i < 2; i++ {
+		if ok, _ := c.takeRateLimit("test", rule); !ok {
+			t.Fatalf("command %d rejected within the limit", i+1)
+		}
+		fake.Advance(time.Second)
+	}
+	ok, status := c.takeRateLimit("test", rule)
+	if ok || status.Remaining != 0 || status.ResetAt != fake.Now().Add(8*time.Second).UnixMilli() {
+		t.Fatalf("third command ok=%v status=%+v, want rejected until the first leaves the window", ok, status)
+	}
+
+	fake.Advance(8 * time.Second)
+	if status := c.rateLimitStatus("test", rule); status.Remaining != 1 {
+		t.Fatalf("remaining = %d after the first left the window, want 1", status.Remaining)
+	}
+	if ok, _ := c.takeRateLimit("test", rule); !ok {
+		t.Fatal("command rejected after the window moved on")
+	}
+}
+
+func TestTakeRateLimitCooldown(t *testing.T) {
+	fake := clock.NewFake(time.Now())
+	c := newIdentifiedTestClient(&Hub{clock: fake}, "usr_1")
+	rule := rateLimitRule{limit: 3, window: 10 * time.Second, cooldown: 30 * time.Second}
+
+	for i := 0; i < 2; i++ {
+		if ok, _ := c.takeRateLimit("test", rule); !ok {
+			t.Fatalf("command %d rejected before the limit", i+1)
+		}
+	}
+	ok, status := c.takeRateLimit("test", rule)
+	if ok || status.ResetAt != fake.Now().Add(rule.cooldown).UnixMilli() {
+		t.Fatalf("command reaching the limit ok=%v status=%+v, want a cooldown", ok, status)
+	}
+
+	fake.Advance(rule.cooldown - time.Second)
+	if ok, _ := c.takeRateLimit("test", rule); ok {
+		t.Fatal("command allowed during the cooldown")
+	}
+	fake.Advance(time.Second)
+	if status := c.rateLimitStatus("test", rule); status.Remaining != 2 || status.ResetAt != 0 {
+		t.Fatalf("status after the cooldown = %+v, want the full budget", status)
+	}
+}
+
+func TestRateLimitStatusUsesConfiguredLimits(t *testing.T) {
+	h := &Hub{}
+	h.SetRateLimits(config.CommandRateLimits{MessageSend: config.CommandRateLimit{Limit: 5}})
+	c := newIdentifiedTestClient(h, "usr_1")
+
+	c.handleRateLimitStatus()
+	msg := <-c.send
+	payload, ok := msg.Data.(RateLimitStatusPayload)
+	if msg.Type != EventRateLimitStatus || !ok || len(payload.Buckets) != len(builtinRateLimits) {
+		t.Fatalf("got %s %+v, want RATE_LIMIT_STATUS with every bucket", msg.Type, msg.Data)
+	}
+	messages := payload.Buckets[0]
+	if messages.Bucket != RateLimitMessageSend || messages.Limit != 5 || messages.Window != messageSendWindow.Milliseconds() || messages.Remaining != 5 {
+		t.Fatalf("message_send = %+v, want the configured limit with the default window", messages)
+	}
+	if joins := payload.Buckets[1]; joins.Bucket != RateLimitVoiceJoin || joins.Remaining != voiceJoinLimit-1 {
+		t.Fatalf("voice_join = %+v, want %d joins before the cooldown", joins, voiceJoinLimit-1)
+	}
+}
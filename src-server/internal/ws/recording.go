@@ -0,0 +1,205 @@
+package ws
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/frisksitron/lobby/src-server/pkg/lobbyclient"
+
+	"lobby/internal/config"
+	"lobby/internal/db"
+	sqldb "lobby/internal/db/sqlc"
+	"lobby/internal/models"
+	"lobby/internal/recording"
+)
+
+type RecordingStatePayload = lobbyclient.RecordingStatePayload
+
+const (
+	EventRecordingState = lobbyclient.EventRecordingState
+	CmdRecordingStart   = lobbyclient.CmdRecordingStart
+	CmdRecordingStop    = lobbyclient.CmdRecordingStop
+)
+
+var errRecordingActive = errors.New("a recording is already running")
+
+// SetRecording lets users with permissions.recording_role record voice into
+// files, when cfg enables it. Must be called before Run.
+func (h *Hub) SetRecording(files recording.FileCreator, cfg config.RecordingConfig) {
+	h.recordingFiles = files
+	h.recordingCfg = cfg
+}
+
+// RecordingState returns the running recording, or nil if there is none.
+func (h *Hub) RecordingState() *RecordingStatePayload {
+	h.recordingMu.Lock()
+	defer h.recordingMu.Unlock()
+	if h.recording == nil {
+		return nil
+	}
+	return recordingState(h.recording)
+}
+
+func recordingState(session *recording.Session) *RecordingStatePayload {
+	startedAt := session.StartedAt
+	return &RecordingStatePayload{
+		Active:      true,
+		RecordingID: session.ID,
+		StartedBy:   session.StartedBy,
+		StartedAt:   &startedAt,
+	}
+}
+
+// startRecording starts recording everyone's voice on behalf of userID.
+func (h *Hub) startRecording(userID string) (*RecordingStatePayload, error) {
+	h.recordingMu.Lock()
+	defer h.recordingMu.Unlock()
+	if h.recording != nil {
+		return nil, errRecordingActive
+	}
+
+	id, err := h.newRecordingID()
+	if err != nil {
+		return nil, fmt.Errorf("generating recording ID: %w", err)
+	}
+	now := h.now().UTC()
+	if err := h.queries.CreateRecording(context.Background(), sqldb.CreateRecordingParams{
+		ID:        id,
+		StartedBy: &userID,
+		StartedAt: now,
+		// Kept even if the server stops before the recording is finished
+		ExpiresAt: now.Add(h.recordingCfg.MaxDuration + h.recordingCfg.Retention),
+	}); err != nil {
+		return nil, fmt.Errorf("creating recording: %w", err)
+	}
+
+	h.recording = recording.Start(h.recordingFiles, id, userID, now)
+	if h.sfu != nil {
+		h.sfu.SetAudioTap(h.recording.WriteAudio)
+	}
+	slog.Info("recording started", "component", "ws", "recording_id", id, "user_id", userID)
+	return recordingState(h.recording), nil
+}
+
+// stopRecording finishes the running recording and returns the
+// RECORDING_STATE telling everyone it stopped, or nil if none was running.
+func (h *Hub) stopRecording(reason string) *RecordingStatePayload {
+	h.recordingMu.Lock()
+	session := h.recording
+	h.recording = nil
+	if session != nil && h.sfu != nil {
+		h.sfu.SetAudioTap(nil)
+	}
+	h.recordingMu.Unlock()
+	if session == nil {
+		return nil
+	}
+
+	tracks := session.Stop()
+	ctx := context.Background()
+	for _, track := range tracks {
+		if err := h.queries.AddRecordingTrack(ctx, sqldb.AddRecordingTrackParams{
+			RecordingID: session.ID,
+			UserID:      track.UserID,
+			StoragePath: track.StoragePath,
+			SizeBytes:   track.SizeBytes,
+			OffsetMs:    track.Offset.Milliseconds(),
+		}); err != nil {
+			slog.Error("error saving recording track", "component", "ws", "recording_id", session.ID, "user_id", track.UserID, "error", err)
+		}
+	}
+	endedAt := h.now().UTC()
+	if err := h.queries.FinishRecording(ctx, sqldb.FinishRecordingParams{
+		EndedAt:   &endedAt,
+		ExpiresAt: endedAt.Add(h.recordingCfg.Retention),
+		ID:        session.ID,
+	}); err != nil {
+		slog.Error("error finishing recording", "component", "ws", "recording_id", session.ID, "error", err)
+	}
+
+	slog.Info("recording stopped", "component", "ws", "recording_id", session.ID, "reason", reason, "tracks", len(tracks))
+	return &RecordingStatePayload{RecordingID: session.ID}
+}
+
+// expireRecording stops a recording that has run for recording.max_duration.
+// Must run on the Run goroutine.
+func (h *Hub) expireRecording() {
+	h.recordingMu.Lock()
+	expired := h.recording != nil && !h.now().Before(h.recording.StartedAt.Add(h.recordingCfg.MaxDuration))
+	h.recordingMu.Unlock()
+	if !expired {
+		return
+	}
+	if state := h.stopRecording("max_duration"); state != nil {
+		h.publishFromRun(Event{Topic: TopicForEvent(EventRecordingState), Type: EventRecordingState, Data: *state})
+	}
+}
+
+func (h *Hub) newRecordingID() (string, error) {
+	if h.messageIDs == nil {
+		return db.GenerateID("rec")
+	}
+	return h.messageIDs("rec")
+}
+
+func (c *Client) handleRecordingStart() {
+	if !c.IsIdentified() {
+		return
+	}
+
+	if !c.hub.recordingCfg.Enabled || c.hub.recordingFiles == nil {
+		c.sendError(ErrorPayload{
+			Code:    ErrCodeForbidden,
+			Message: "Recording is disabled on this server",
+		})
+		return
+	}
+
+	if !models.RoleAtLeast(c.user.Role, c.hub.permissions.RecordingRole) {
+		c.sendError(ErrorPayload{
+			Code:    ErrCodeForbidden,
+			Message: "You do not have permission to record voice",
+		})
+		return
+	}
+
+	state, err := c.hub.startRecording(c.user.ID)
+	if errors.Is(err, errRecordingActive) {
+		c.sendError(ErrorPayload{
+			Code:    ErrCodeConflict,
+			Message: "Voice is already being recorded",
+		})
+		return
+	}
+	if err != nil {
+		slog.Error("error starting recording", "component", "ws", "user_id", c.user.ID, "error", err)
+		c.sendError(ErrorPayload{
+			Code:    ErrCodeInternal,
+			Message: "Failed to start recording",
+		})
+		return
+	}
+
+	c.hub.BroadcastDispatch(EventRecordingState, *state)
+}
+
+func (c *Client) handleRecordingStop() {
+	if !c.IsIdentified() {
+		return
+	}
+
+	if !models.RoleAtLeast(c.user.Role, c.hub.permissions.RecordingRole) {
+		c.sendError(ErrorPayload{
+			Code:    ErrCodeForbidden,
+			Message: "You do not have permission to record voice",
+		})
+		return
+	}
+
+	if state := c.hub.stopRecording("command"); state != nil {
+		c.hub.BroadcastDispatch(EventRecordingState, *state)
+		slog.Info("user stopped recording", "component", "ws", "user_id", c.user.ID)
+	}
+}
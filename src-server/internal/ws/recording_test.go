@@ -0,0 +1,119 @@
+package ws
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"lobby/internal/blob"
+	"lobby/internal/clock"
+	"lobby/internal/config"
+	sqldb "lobby/internal/db/sqlc"
+	"lobby/internal/models"
+)
+
+func openRecordingTestHub(t *testing.T) (*Hub, *clock.Fake) {
+	t.Helper()
+
+	h := openBotTestHub(t)
+	h.broadcast = make(chan *WSMessage, 4)
+	h.permissions = config.PermissionsConfig{RecordingRole: models.RoleModerator}
+	files, err := blob.NewService(t.TempDir(), 1<<20)
+	if err != nil {
+		t.Fatalf("blob.NewService() error = %v", err)
+	}
+	h.SetRecording(files, config.RecordingConfig{Enabled: true, MaxDuration: time.Hour, Retention: 24 * time.Hour})
+	clk := clock.NewFake(time.Now())
+	h.SetClock(clk)
+	return h, clk
+}
+
+func newRecordingTestClient(h *Hub, userID, role string) *Client {
+	c := newIdentifiedTestClient(h, userID)
+	c.user.Role = role
+	return c
+}
+
+func expectErrorCode(t *testing.T, c *Client, code string) {
+	t.Helper()
+
+	msg := nextSent(c)
+	payload, ok := msg.Data.(ErrorPayload)
+	if msg.Type != EventError || !ok || payload.Code != code {
+		t.Fatalf("expected %s error, got type=%s data=%+v", code, msg.Type, msg.Data)
+	}
+}
+
+func TestRecordingStartRequiresPermission(t *testing.T) {
+	h, _ := openRecordingTestHub(t)
+
+	c := newRecordingTestClient(h, "usr_1", models.RoleMember)
+	c.handleRecordingStart()
+	expectErrorCode(t, c, ErrCodeForbidden)
+
+	h.recordingCfg.Enabled = false
+	mod := newRecordingTestClient(h, "usr_2", models.RoleModerator)
+	mod.handleRecordingStart()
+	expectErrorCode(t, mod, ErrCodeForbidden)
+
+	if h.RecordingState() != nil {
+		t.Fatal("recording started without permission")
+	}
+}
+
+func TestRecordingStartAndStop(t *testing.T) {
+	h, _ := openRecordingTestHub(t)
+	c := newRecordingTestClient(h, "usr_1", models.RoleModerator)
+
+	c.handleRecordingStart()
+	started := (<-h.broadcast).Data.(RecordingStatePayload)
+	if !started.Active || started.RecordingID == "" || started.StartedBy != "usr_1" || started.StartedAt == nil {
+		t.Fatalf("RECORDING_STATE on start = %+v", started)
+	}
+	if state := h.RecordingState(); state == nil || state.RecordingID != started.RecordingID {
+		t.Fatalf("RecordingState() = %+v, want the running recording", state)
+	}
+
+	c.handleRecordingStart()
+	expectErrorCode(t, c, ErrCodeConflict)
+
+	c.handleRecordingStop()
+	stopped := (<-h.broadcast).Data.(RecordingStatePayload)
+	if stopped.Active || stopped.RecordingID != started.RecordingID {
+		t.Fatalf("RECORDING_STATE on stop = %+v", stopped)
+	}
+	if h.RecordingState() != nil {
+		t.Fatal("RecordingState() after stop, want nil")
+	}
+
+	// Finished recordings expire after the retention period
+	expired, err := h.queries.ListExpiredRecordings(context.Background(), sqldb.ListExpiredRecordingsParams{
+		Now:       h.now().Add(25 * time.Hour),
+		LimitRows: 10,
+	})
+	if err != nil {
+		t.Fatalf("ListExpiredRecordings() error = %v", err)
+	}
+	if len(expired) != 1 || expired[0] != started.RecordingID {
+		t.Fatalf("expired recordings = %v, want [%s]", expired, started.RecordingID)
+	}
+}
+
+func TestRecordingStopsAtMaxDuration(t *testing.T) {
+	h, clk := openRecordingTestHub(t)
+	if _, err := h.startRecording("usr_1"); err != nil {
+		t.Fatalf("startRecording() error = %v", err)
+	}
+
+	clk.Advance(59 * time.Minute)
+	h.expireRecording()
+	if h.RecordingState() == nil {
+		t.Fatal("recording stopped before max_duration")
+	}
+
+	clk.Advance(time.Minute)
+	h.expireRecording()
+	if h.RecordingState() != nil {
+		t.Fatal("recording still running after max_duration")
+	}
+}
@@ -0,0 +1,152 @@
+package ws
+
+import (
+	"encoding/binary"
+	"errors"
+	"log/slog"
+	"time"
+
+	"lobby/internal/sfu"
+)
+
+// Binary websocket frames carry relayed voice audio for clients that could
+// not connect over UDP or TURN. Every frame starts with an 8-byte header:
+//
+//	byte 0     frame kind (relayFrameAudio)
+//	bytes 1-2  RTP sequence number, big endian
+//	bytes 3-6  RTP timestamp at 48 kHz, big endian
+//	byte 7     length n of the source user ID
+//
+// followed by n bytes of user ID and one Opus packet. Clients send n = 0;
+// the server fills in the speaker on frames it sends.
+const (
+	relayFrameAudio byte = 1
+
+	relayHeaderBytes = 8
+
+	// Largest Opus packet per RFC 6716.
+	maxRelayPayloadBytes = 1275
+
+	// Frames queued per client before new audio is dropped.
+	relaySendBuffer = 64
+
+	// Inbound audio allowance: 20 ms Opus frames arrive at 50/s, leave
+	// headroom for jitter bursts.
+	relayFrameLimit  = 100
+	relayFrameWindow = time.Second
+)
+
+var errInvalidRelayFrame = errors.New("invalid relay frame")
+
+func encodeRelayFrame(frame sfu.RelayFrame) []byte {
+	buf := make([]byte, relayHeaderBytes+len(frame.SourceUserID)+len(frame.Payload))
+	buf[0] = relayFrameAudio
+	binary.BigEndian.PutUint16(buf[1:3], frame.Sequence)
+	binary.BigEndian.PutUint32(buf[3:7], frame.Timestamp)
+	buf[7] = byte(len(frame.SourceUserID))
+	n := copy(buf[relayHeaderBytes:], frame.SourceUserID)
+	copy(buf[relayHeaderBytes+n:], frame.Payload)
+	return buf
+}
+
+// isRelayFrame reports whether a binary frame is relay audio rather than a
+// MessagePack-encoded message.
+func isRelayFrame(data []byte) bool {
+	return len(data) > 0 && data[0] == relayFrameAudio
+}
+
+func decodeRelayFrame(data []byte) (sfu.RelayFrame, error) {
+	if len(data) < relayHeaderBytes || data[0] != relayFrameAudio {
+		return sfu.RelayFrame{}, errInvalidRelayFrame
+	}
+	idLen := int(data[7])
+	payload := data[relayHeaderBytes:]
+	if len(payload) < idLen {
+		return sfu.RelayFrame{}, errInvalidRelayFrame
+	}
+	payload = payload[idLen:]
+	if len(payload) == 0 || len(payload) > maxRelayPayloadBytes {
+		return sfu.RelayFrame{}, errInvalidRelayFrame
+	}
+	return sfu.RelayFrame{
+		SourceUserID: string(data[relayHeaderBytes : relayHeaderBytes+idLen]),
+		Sequence:     binary.BigEndian.Uint16(data[1:3]),
+		Timestamp:    binary.BigEndian.Uint32(data[3:7]),
+		Payload:      payload,
+	}, nil
+}
+
+// handleVoiceRelayStart switches the caller's voice session to degraded mode:
+// its WebRTC peer is dropped and audio flows as binary frames on this
+// connection instead.
+func (c *Client) handleVoiceRelayStart() {
+	state := c.hub.GetVoiceLifecycleState(c.user.ID)
+	if state != VoiceLifecycleJoining && state != VoiceLifecycleActive {
+		c.sendError(ErrorPayload{
+			Code:    ErrCodeVoiceNegotiationInvalidState,
+			Message: "Voice relay rejected in current voice state",
+		})
+		return
+	}
+
+	voiceState, err := c.hub.StartVoiceRelay(c.user.ID, c.queueRelayFrame)
+	if err != nil {
+		slog.Error("error starting voice relay", "component", "ws", "user_id", c.user.ID, "error", err)
+		c.sendError(ErrorPayload{
+			Code:    ErrCodeVoiceJoinFailed,
+			Message: "Failed to start voice relay",
+		})
+		return
+	}
+
+	c.hub.BroadcastDispatch(EventVoiceStateUpdate, VoiceStateUpdatePayload{
+		UserID:          c.user.ID,
+		InVoice:         true,
+		Muted:           voiceState.Muted,
+		Deafened:        voiceState.Deafened,
+		ServerMuted:     voiceState.ServerMuted,
+		ServerDeafened:  voiceState.ServerDeafened,
+		PushToTalk:      voiceState.PushToTalk,
+		Relay:           voiceState.Relay,
+		PrioritySpeaker: voiceState.PrioritySpeaker,
+		WhisperTo:       voiceState.WhisperTo,
+	})
+
+	slog.Warn("voice running in degraded relay mode", "component", "ws", "user_id", c.user.ID)
+}
+
+// handleRelayFrame publishes one binary audio frame from a relay client.
+// Frames from clients that are not relaying, malformed frames, and frames over
+// the rate limit are dropped without a reply.
+func (c *Client) handleRelayFrame(data []byte) {
+	if !c.IsIdentified() {
+		return
+	}
+	if ok, _ := c.allowCommandRateLimit(&c.relayFrames, relayFrameLimit, relayFrameWindow); !ok {
+		return
+	}
+	frame, err := decodeRelayFrame(data)
+	if err != nil {
+		slog.Debug("dropping invalid relay frame", "component", "ws", "user_id", c.user.ID)
+		return
+	}
+	sfuInstance := c.hub.GetSFU()
+	if sfuInstance == nil {
+		return
+	}
+	if err := sfuInstance.WriteRelayAudio(c.user.ID, frame); err != nil {
+		slog.Debug("dropping relay frame", "component", "ws", "user_id", c.user.ID, "error", err)
+	}
+}
+
+// queueRelayFrame is the client's sfu.RelaySink. Audio is dropped rather than
+// blocking the media path when the connection falls behind.
+func (c *Client) queueRelayFrame(frame sfu.RelayFrame) {
+	if c.IsClosed() {
+		return
+	}
+	select {
+	case c.relaySend <- encodeRelayFrame(frame):
+	default:
+	}
+}
@@ -0,0 +1,51 @@
+package ws
+
+import (
+	"bytes"
+	"testing"
+
+	"lobby/internal/sfu"
+)
+
+func TestRelayFrameRoundTrip(t *testing.T) {
+	frame := sfu.RelayFrame{SourceUserID: "usr_1", Sequence: 65535, Timestamp: 960 * 7, Payload: []byte{0xfc, 0x01, 0x02}}
+
+	got, err := decodeRelayFrame(encodeRelayFrame(frame))
+	if err != nil {
+		t.Fatalf("decodeRelayFrame() error = %v", err)
+	}
+	if got.SourceUserID != frame.SourceUserID || got.Sequence != frame.Sequence || got.Timestamp != frame.Timestamp || !bytes.Equal(got.Payload, frame.Payload) {
+		t.Fatalf("decoded = %+v, want %+v", got, frame)
+	}
+}
+
+func TestDecodeRelayFrameRejectsMalformed(t *testing.T) {
+	valid := encodeRelayFrame(sfu.RelayFrame{Payload: []byte{0xfc}})
+	wrongKind := append([]byte{}, valid...)
+	wrongKind[0] = 9
+	longID := append([]byte{}, valid...)
+	longID[7] = 200
+
+	cases := map[string][]byte{
+		"short header":  valid[:relayHeaderBytes-1],
+		"wrong kind":    wrongKind,
+		"empty payload": valid[:relayHeaderBytes],
+		"id overflow":   longID,
+		"oversized":     encodeRelayFrame(sfu.RelayFrame{Payload: make([]byte, maxRelayPayloadBytes+1)}),
+	}
+	for name, data := range cases {
+		if _, err := decodeRelayFrame(data); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+func TestQueueRelayFrameDropsWhenFull(t *testing.T) {
+	c := newIdentifiedTestClient(&Hub{}, "usr_1")
+	for i := 0; i < relaySendBuffer+5; i++ {
+		c.queueRelayFrame(sfu.RelayFrame{SourceUserID: "usr_2", Sequence: uint16(i), Payload: []byte{0xfc}})
+	}
+	if len(c.relaySend) != relaySendBuffer {
+		t.Fatalf("queued = %d, want %d", len(c.relaySend), relaySendBuffer)
+	}
+}
@@ -0,0 +1,83 @@
+package ws
+
+import (
+	"log/slog"
+	"time"
+)
+
+// commandReplayWindow is how long an applied command's nonce is remembered.
+// Clients retrying after a timeout or reconnect reuse the nonce, so a retry
+// inside the window is acknowledged without being applied again.
+const commandReplayWindow = 2 * time.Minute
+
+// replayProtectedCommands are the DISPATCH commands whose nonce identifies a
+// single attempt. Other commands are either idempotent or carry no nonce.
+var replayProtectedCommands = map[string]bool{
+	CmdMessageSend:   true,
+	CmdPresenceSet:   true,
+	CmdVoiceJoin:     true,
+	CmdVoiceStateSet: true,
+}
+
+type commandKey struct {
+	userID  string
+	command string
+	nonce   string
+}
+
+// rememberCommand records that userID's command with nonce was applied. It is
+// a no-op without a nonce.
+func (h *Hub) rememberCommand(userID, command, nonce string) {
+	if nonce == "" || !replayProtectedCommands[command] {
+		return
+	}
+
+	now := h.now()
+	h.replayMu.Lock()
+	defer h.replayMu.Unlock()
+	if h.appliedCommands == nil {
+		h.appliedCommands = make(map[commandKey]time.Time)
+	}
+	if now.Sub(h.replayPrunedAt) >= commandReplayWindow {
+		for key, appliedAt := range h.appliedCommands {
+			if now.Sub(appliedAt) >= commandReplayWindow {
+				delete(h.appliedCommands, key)
+			}
+		}
+		h.replayPrunedAt = now
+	}
+	h.appliedCommands[commandKey{userID: userID, command: command, nonce: nonce}] = now
+}
+
+// isReplayedCommand reports whether userID already applied command with nonce
+// within commandReplayWindow, on this or an earlier connection.
+func (h *Hub) isReplayedCommand(userID, command, nonce string) bool {
+	if nonce == "" {
+		return false
+	}
+
+	h.replayMu.Lock()
+	defer h.replayMu.Unlock()
+	appliedAt, ok := h.appliedCommands[commandKey{userID: userID, command: command, nonce: nonce}]
+	return ok && h.now().Sub(appliedAt) < commandReplayWindow
+}
+
+// dropReplayedCommand answers a retried command with COMMAND_ACK instead of
+// applying it twice, and reports whether it did so.
+func (c *Client) dropReplayedCommand(msg *WSMessage) bool {
+	if !replayProtectedCommands[msg.Type] || !c.IsIdentified() {
+		return false
+	}
+	data, ok := msg.Data.(map[string]interface{})
+	if !ok {
+		return false
+	}
+	nonce, _ := data["nonce"].(string)
+	if !c.hub.isReplayedCommand(c.user.ID, msg.Type, nonce) {
+		return false
+	}
+
+	slog.Debug("dropping replayed command", "component", "ws", "user_id", c.user.ID, "type", msg.Type, "nonce", nonce)
+	c.sendCommandAck(msg.Type, nonce)
+	return true
+}
@@ -0,0 +1,79 @@
+package ws
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDispatchDropsReplayedCommand(t *testing.T) {
+	h := &Hub{broadcast: make(chan *WSMessage, 4)}
+	c := newIdentifiedTestClient(h, "usr_1")
+
+	presence := func(status, nonce string) {
+		c.handleDispatch(&WSMessage{
+			Op:   OpDispatch,
+			Type: CmdPresenceSet,
+			Data: map[string]interface{}{"status": status, "nonce": nonce},
+		})
+	}
+	expectAck := func(nonce string) {
+		t.Helper()
+		select {
+		case msg := <-c.send:
+			ack, ok := msg.Data.(CommandAckPayload)
+			if msg.Type != EventCommandAck || !ok || ack.Nonce != nonce {
+				t.Fatalf("expected ack for %q, got type=%s data=%+v", nonce, msg.Type, msg.Data)
+			}
+		default:
+			t.Fatalf("expected ack for %q", nonce)
+		}
+	}
+
+	presence("dnd", "n1")
+	expectAck("n1")
+
+	// A retry is acknowledged again but not applied.
+	presence("idle", "n1")
+	expectAck("n1")
+	if status := c.GetStatus(); status != "dnd" {
+		t.Fatalf("status = %q, want replay to be dropped", status)
+	}
+
+	// A reconnected client shares the user's replay history.
+	reconnected := newIdentifiedTestClient(h, "usr_1")
+	reconnected.handleDispatch(&WSMessage{
+		Op:   OpDispatch,
+		Type: CmdPresenceSet,
+		Data: map[string]interface{}{"status": "idle", "nonce": "n1"},
+	})
+	if status := reconnected.GetStatus(); status == "idle" {
+		t.Fatal("expected replay on a new connection to be dropped")
+	}
+
+	presence("idle", "n2")
+	expectAck("n2")
+	if status := c.GetStatus(); status != "idle" {
+		t.Fatalf("status = %q, want new nonce to apply", status)
+	}
+}
+
+func TestReplayedCommandExpires(t *testing.T) {
+	h := &Hub{}
+	h.rememberCommand("usr_1", CmdMessageSend, "n1")
+	if !h.isReplayedCommand("usr_1", CmdMessageSend, "n1") {
+		t.Fatal("expected remembered command to be a replay")
+	}
+	if h.isReplayedCommand("usr_2", CmdMessageSend, "n1") || h.isReplayedCommand("usr_1", CmdVoiceJoin, "n1") {
+		t.Fatal("replays are scoped to user and command")
+	}
+
+	h.appliedCommands[commandKey{userID: "usr_1", command: CmdMessageSend, nonce: "n1"}] = time.Now().Add(-commandReplayWindow)
+	if h.isReplayedCommand("usr_1", CmdMessageSend, "n1") {
+		t.Fatal("expected command outside the window to be accepted")
+	}
+
+	h.rememberCommand("usr_1", CmdTyping, "n2")
+	if h.isReplayedCommand("usr_1", CmdTyping, "n2") {
+		t.Fatal("only replay-protected commands are remembered")
+	}
+}
@@ -0,0 +1,181 @@
+package ws
+
+import (
+	"slices"
+	"sync"
+	"time"
+
+	"lobby/internal/models"
+)
+
+// Session resume. Every event the hub fans out to an identified client is
+// stamped with a per-session seq and kept in a short buffer. When the
+// connection drops the session is parked for resumeWindow; a new connection
+// that sends RESUME with the session ID and the last seq it saw gets the
+// missed events replayed instead of a fresh READY. Direct replies (ERROR,
+// COMMAND_ACK, RTC signaling, REQUEST responses) are not sequenced.
+const (
+	resumeWindow = 2 * time.Minute
+
+	// resumeBufferSize bounds the events kept per session. It stays under the
+	// client send buffer so a full replay plus RESUMED never drops.
+	resumeBufferSize = 128
+)
+
+// resumeSession is the replayable state of one READY session.
+type resumeSession struct {
+	id     string
+	userID string
+
+	mu     sync.Mutex
+	seq    uint64
+	events []*WSMessage
+
+	// Set while parked (protected by the hub's mu)
+	user        *models.User
+	scopes      []string
+	status      string
+	statusText  string
+	statusEmoji string
+	filtered    intentMask
+	batching    bool
+	detachedAt  time.Time
+}
+
+func newResumeSession(id, userID string) *resumeSession {
+	return &resumeSession{id: id, userID: userID}
+}
+
+// recordLocked stamps a copy of msg with the next seq and buffers it. Caller
+// must hold s.mu.
+func (s *resumeSession) recordLocked(msg *WSMessage) *WSMessage {
+	s.seq++
+	stamped := *msg
+	stamped.Seq = s.seq
+	s.events = append(s.events, &stamped)
+	if len(s.events) > resumeBufferSize {
+		s.events = slices.Delete(s.events, 0, len(s.events)-resumeBufferSize)
+	}
+	return &stamped
+}
+
+// record buffers msg for a parked session unless its intents leave it out.
+func (s *resumeSession) record(msg *WSMessage) {
+	if !s.filtered.allows(msg.Type) {
+		return
+	}
+	s.mu.Lock()
+	s.recordLocked(msg)
+	s.mu.Unlock()
+}
+
+// sinceLocked returns the buffered events after seq. It reports false when
+// some of them were already evicted or seq is ahead of the session. Caller
+// must hold s.mu.
+func (s *resumeSession) sinceLocked(seq uint64) ([]*WSMessage, bool) {
+	if seq > s.seq {
+		return nil, false
+	}
+	missed := int(s.seq - seq)
+	if missed > len(s.events) {
+		return nil, false
+	}
+	return s.events[len(s.events)-missed:], true
+}
+
+func (s *resumeSession) hasScope(scope string) bool {
+	return s.scopes == nil || slices.Contains(s.scopes, scope)
+}
+
+// resumeRequest is what a RESUME asks registerSync to claim.
+type resumeRequest struct {
+	sessionID string
+	seq       uint64
+}
+
+// deliverLocked queues fan-out for client unless its intents leave it out.
+// It is sequenced when written if the client has a resumable session.
+// Caller must hold at least a read lock on h.mu.
+func (h *Hub) deliverLocked(client *Client, msg *WSMessage) {
+	if !client.IsIdentified() || !client.wantsEvent(msg.Type) {
+		return
+	}
+	client.enqueueEvent(msg, client.resume)
+}
+
+// detachSessionLocked parks client's session for a later RESUME. Caller must
+// hold h.mu.
+func (h *Hub) detachSessionLocked(client *Client) {
+	sess := client.resume
+	if sess == nil || client.user == nil {
+		return
+	}
+	client.mu.RLock()
+	sess.scopes = client.scopes
+	sess.status = client.status
+	sess.statusText = client.statusText
+	sess.statusEmoji = client.statusEmoji
+	client.mu.RUnlock()
+	sess.filtered = intentMask(client.filteredIntents.Load())
+	sess.batching = client.batching.Load()
+	client.drainEventsTo(sess)
+	sess.user = client.user
+	sess.detachedAt = h.now()
+	if h.detachedSessions == nil {
+		h.detachedSessions = make(map[string]*resumeSession)
+	}
+	h.detachedSessions[sess.userID] = sess
+}
+
+// claimSessionLocked hands a parked session to a resuming client, marks it
+// identified, and queues the missed events followed by RESUMED. Holding h.mu
+// keeps live events from slipping in ahead of the replay. It reports false
+// when the session is unknown, expired, or has evicted events the client
+// needs. Caller must hold h.mu.
+func (h *Hub) claimSessionLocked(client *Client, req *resumeRequest) bool {
+	sess := h.detachedSessions[client.user.ID]
+	if sess == nil || sess.id != req.sessionID {
+		return false
+	}
+	delete(h.detachedSessions, client.user.ID)
+	if h.now().Sub(sess.detachedAt) > resumeWindow {
+		return false
+	}
+
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+	missed, ok := sess.sinceLocked(req.seq)
+	if !ok || !client.transitionTo(ClientStateIdentified) {
+		return false
+	}
+
+	client.resume = sess
+	client.sessionID = sess.id
+	client.SetStatus(sess.status)
+	client.SetCustomStatus(sess.statusText, sess.statusEmoji)
+	client.filteredIntents.Store(uint32(sess.filtered))
+	client.batching.Store(sess.batching)
+	sess.user, sess.scopes = nil, nil
+	for _, msg := range missed {
+		h.sendToClientLocked(client, msg)
+	}
+	h.sendToClientLocked(client, &WSMessage{
+		Op:   OpDispatch,
+		Type: EventResumed,
+		Data: ResumedPayload{SessionID: sess.id, Replayed: len(missed)},
+	})
+	return true
+}
+
+// pruneDetachedSessions drops parked sessions past resumeWindow. It runs on
+// the janitor tick.
+func (h *Hub) pruneDetachedSessions() {
+	now := h.now()
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for userID, sess := range h.detachedSessions {
+		if now.Sub(sess.detachedAt) > resumeWindow {
+			delete(h.detachedSessions, userID)
+		}
+	}
+}
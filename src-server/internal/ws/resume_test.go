@@ -0,0 +1,134 @@
+package ws
+
+import (
+	"testing"
+	"time"
+
+	"lobby/internal/clock"
+)
+
+func TestResumeSessionSince(t *testing.T) {
+	s := newResumeSession("sess_1", "usr_1")
+	for i := 0; i < 3; i++ {
+		s.record(&WSMessage{Op: OpDispatch, Type: EventTypingStart})
+	}
+
+	missed, ok := s.sinceLocked(1)
+	if !ok || len(missed) != 2 || missed[0].Seq != 2 || missed[1].Seq != 3 {
+		t.Fatalf("since(1) = %d events ok=%v, want seqs 2 and 3", len(missed), ok)
+	}
+	if missed, ok := s.sinceLocked(3); !ok || len(missed) != 0 {
+		t.Fatalf("since(3) = %d events ok=%v, want none", len(missed), ok)
+	}
+	if _, ok := s.sinceLocked(4); ok {
+		t.Fatal("since(4) succeeded for a session at seq 3")
+	}
+
+	for i := 0; i < resumeBufferSize; i++ {
+		s.record(&WSMessage{Op: OpDispatch, Type: EventTypingStart})
+	}
+	if _, ok := s.sinceLocked(2); ok {
+		t.Fatal("since(2) succeeded after its events were evicted")
+	}
+	if missed, ok := s.sinceLocked(3); !ok || len(missed) != resumeBufferSize {
+		t.Fatalf("since(3) = %d events ok=%v, want the full buffer", len(missed), ok)
+	}
+}
+
+func TestDeliverStampsSequencedClients(t *testing.T) {
+	h := &Hub{clients: make(map[*Client]bool)}
+	sequenced := newIdentifiedTestClient(h, "usr_1")
+	sequenced.resume = newResumeSession("sess_1", "usr_1")
+	plain := newIdentifiedTestClient(h, "usr_2")
+	h.clients[sequenced] = true
+	h.clients[plain] = true
+
+	h.deliverToClients(Event{Type: EventTypingStart, AuthorID: "usr_3"})
+	h.deliverToClients(Event{Type: EventTypingStop, AuthorID: "usr_3"})
+
+	for want := uint64(1); want <= 2; want++ {
+		if msg := nextSent(sequenced); msg.Seq != want {
+			t.Fatalf("sequenced client got seq %d, want %d", msg.Seq, want)
+		}
+	}
+	if msg := nextSent(plain); msg.Seq != 0 {
+		t.Fatalf("client without a session got seq %d", msg.Seq)
+	}
+}
+
+func TestClaimSessionReplaysMissedEvents(t *testing.T) {
+	fake := clock.NewFake(time.Now())
+	h := &Hub{clients: make(map[*Client]bool), clock: fake}
+
+	old := newIdentifiedTestClient(h, "usr_1")
+	old.resume = newResumeSession("sess_1", "usr_1")
+	h.clients[old] = true
+	h.deliverToClients(Event{Type: EventTypingStart, AuthorID: "usr_2"})
+	nextSent(old)
+
+	h.mu.Lock()
+	delete(h.clients, old)
+	h.detachSessionLocked(old)
+	h.mu.Unlock()
+
+	h.deliverToClients(Event{Type: EventTypingStop, AuthorID: "usr_2"})
+	h.deliverToClients(Event{Type: EventTypingStart, AuthorID: "usr_2"})
+
+	wrong := newIdentifiedTestClient(h, "usr_1")
+	wrong.state.Store(int32(ClientStateConnected))
+	h.mu.Lock()
+	ok := h.claimSessionLocked(wrong, &resumeRequest{sessionID: "sess_other", seq: 1})
+	h.mu.Unlock()
+	if ok || wrong.IsIdentified() {
+		t.Fatal("claimed a session with the wrong ID")
+	}
+
+	resumed := newIdentifiedTestClient(h, "usr_1")
+	resumed.state.Store(int32(ClientStateConnected))
+	h.mu.Lock()
+	ok = h.claimSessionLocked(resumed, &resumeRequest{sessionID: "sess_1", seq: 1})
+	h.mu.Unlock()
+	if !ok || !resumed.IsIdentified() || resumed.sessionID != "sess_1" {
+		t.Fatalf("claim ok=%v identified=%v session=%q", ok, resumed.IsIdentified(), resumed.sessionID)
+	}
+
+	for _, want := range []struct {
+		seq uint64
+		typ string
+	}{{2, EventTypingStop}, {3, EventTypingStart}} {
+		msg := nextSent(resumed)
+		if msg.Seq != want.seq || msg.Type != want.typ {
+			t.Fatalf("replayed %s seq %d, want %s seq %d", msg.Type, msg.Seq, want.typ, want.seq)
+		}
+	}
+	msg := nextSent(resumed)
+	payload, ok := msg.Data.(ResumedPayload)
+	if msg.Type != EventResumed || !ok || payload.Replayed != 2 {
+		t.Fatalf("expected RESUMED with 2 replayed, got type=%s data=%+v", msg.Type, msg.Data)
+	}
+}
+
+func TestClaimSessionRejectsExpired(t *testing.T) {
+	fake := clock.NewFake(time.Now())
+	h := &Hub{clients: make(map[*Client]bool), clock: fake}
+
+	old := newIdentifiedTestClient(h, "usr_1")
+	old.resume = newResumeSession("sess_1", "usr_1")
+	h.mu.Lock()
+	h.detachSessionLocked(old)
+	h.mu.Unlock()
+
+	fake.Advance(resumeWindow + time.Second)
+
+	resumed := newIdentifiedTestClient(h, "usr_1")
+	resumed.state.Store(int32(ClientStateConnected))
+	h.mu.Lock()
+	ok := h.claimSessionLocked(resumed, &resumeRequest{sessionID: "sess_1"})
+	h.mu.Unlock()
+	if ok {
+		t.Fatal("claimed a session past the resume window")
+	}
+	if _, parked := h.detachedSessions["usr_1"]; parked {
+		t.Fatal("expired session is still parked")
+	}
+}
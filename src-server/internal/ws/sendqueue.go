@@ -0,0 +1,135 @@
+package ws
+
+import (
+	"log/slog"
+	"sync/atomic"
+)
+
+// Outbound priorities. Client.send carries direct replies and signaling
+// (READY, ERROR, acks, RTC offers and candidates, RESUME replays) and is
+// always written first. Hub fan-out goes to one queue per class below and is
+// written in class order, so a backlog of chat never holds up signaling or
+// state. Each class has its own drop policy when its queue is full:
+//
+//   - signaling: never dropped; the client is disconnected instead so it
+//     reconnects and renegotiates
+//   - state and chat: dropped and counted; the client is disconnected after
+//     maxDroppedMessagesBeforeDisconnect drops
+//   - typing: dropped silently, as the next one supersedes it
+//
+// Fan-out is sequenced when written rather than when queued, so seqs always
+// reach the client in order despite the reordering.
+type sendClass int
+
+const (
+	sendState sendClass = iota
+	sendChat
+	sendTyping
+	numSendClasses
+)
+
+var sendQueueSizes = [numSendClasses]int{
+	sendState:  256,
+	sendChat:   256,
+	sendTyping: 32,
+}
+
+// eventSendClass returns the fan-out queue for eventType. Anything not
+// listed counts as state.
+func eventSendClass(eventType string) sendClass {
+	switch eventType {
+	case EventMessageCreate, EventMessagesPurged, EventNotification, EventDraftUpdate, EventAutomodAlert, EventModAlert:
+		return sendChat
+	case EventTypingStart, EventTypingStop, EventVoiceSpeaking:
+		return sendTyping
+	}
+	return sendState
+}
+
+// queuedEvent is fan-out waiting in a class queue. sess is the session to
+// sequence it with, captured when it was queued; nil leaves it unsequenced.
+type queuedEvent struct {
+	msg  *WSMessage
+	sess *resumeSession
+}
+
+func newEventQueues() [numSendClasses]chan queuedEvent {
+	var queues [numSendClasses]chan queuedEvent
+	for class, size := range sendQueueSizes {
+		queues[class] = make(chan queuedEvent, size)
+	}
+	return queues
+}
+
+// stamp sequences the event, recording it for RESUME.
+func (e queuedEvent) stamp() *WSMessage {
+	if e.sess == nil {
+		return e.msg
+	}
+	e.sess.mu.Lock()
+	defer e.sess.mu.Unlock()
+	return e.sess.recordLocked(e.msg)
+}
+
+// enqueueEvent queues fan-out for the client, applying its class's drop
+// policy when the queue is full.
+func (c *Client) enqueueEvent(msg *WSMessage, sess *resumeSession) {
+	class := eventSendClass(msg.Type)
+	select {
+	case c.events[class] <- queuedEvent{msg: msg, sess: sess}:
+		return
+	default:
+	}
+	if class == sendTyping {
+		return
+	}
+
+	dropped := atomic.AddInt64(&c.DroppedMessages, 1)
+	// Log warning periodically (every 10 drops)
+	if dropped%10 == 1 {
+		slog.Warn("dropped messages for slow client", "component", "hub", "dropped", dropped, "user_id", c.getUserID(), "type", msg.Type)
+	}
+	// Disconnect clients that fall too far behind
+	if dropped >= maxDroppedMessagesBeforeDisconnect {
+		slog.Warn("disconnecting slow client", "component", "hub", "user_id", c.getUserID(), "dropped", dropped)
+		// Close will be handled by the client's pumps
+		c.Close()
+	}
+}
+
+// nextEvent takes the highest-priority queued fan-out without blocking and
+// sequences it.
+func (c *Client) nextEvent() (*WSMessage, bool) {
+	for _, queue := range c.events {
+		select {
+		case e := <-queue:
+			return e.stamp(), true
+		default:
+		}
+	}
+	return nil, false
+}
+
+// queuedLen returns how many messages wait in c.send and the fan-out queues.
+func (c *Client) queuedLen() int {
+	n := len(c.send)
+	for _, queue := range c.events {
+		n += len(queue)
+	}
+	return n
+}
+
+// drainEventsTo records fan-out still queued when the client went away into
+// its parked session, in the order WritePump would have written it, so RESUME
+// replays it.
+func (c *Client) drainEventsTo(sess *resumeSession) {
+	for {
+		msg, ok := c.nextEvent()
+		if !ok {
+			return
+		}
+		if msg.Seq == 0 {
+			sess.record(msg)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package ws
+
+import "testing"
+
+// nextSent returns the next message WritePump would write, sequenced, or nil
+// when nothing is queued.
+func nextSent(c *Client) *WSMessage {
+	select {
+	case msg := <-c.send:
+		return msg
+	default:
+	}
+	msg, _ := c.nextEvent()
+	return msg
+}
+
+func TestNextEventPrefersHigherClasses(t *testing.T) {
+	h := &Hub{}
+	c := newIdentifiedTestClient(h, "usr_1")
+	c.enqueueEvent(&WSMessage{Op: OpDispatch, Type: EventTypingStart}, nil)
+	c.enqueueEvent(&WSMessage{Op: OpDispatch, Type: EventMessageCreate}, nil)
+	c.enqueueEvent(&WSMessage{Op: OpDispatch, Type: EventPresenceUpdate}, nil)
+	c.send <- &WSMessage{Op: OpDispatch, Type: EventRtcOffer}
+
+	for _, want := range []string{EventRtcOffer, EventPresenceUpdate, EventMessageCreate, EventTypingStart} {
+		if msg := nextSent(c); msg == nil || msg.Type != want {
+			t.Fatalf("next message = %+v, want %s", msg, want)
+		}
+	}
+}
+
+func TestEnqueueEventDropPolicies(t *testing.T) {
+	h := &Hub{}
+	c := newIdentifiedTestClient(h, "usr_1")
+	for range sendQueueSizes[sendTyping] + 5 {
+		c.enqueueEvent(&WSMessage{Op: OpDispatch, Type: EventTypingStart}, nil)
+	}
+	if c.DroppedMessages != 0 || c.IsClosed() {
+		t.Fatalf("typing overflow counted %d drops, closed=%v; want silent drops", c.DroppedMessages, c.IsClosed())
+	}
+
+	for range sendQueueSizes[sendChat] + 1 {
+		c.enqueueEvent(&WSMessage{Op: OpDispatch, Type: EventMessageCreate}, nil)
+	}
+	if c.DroppedMessages != 1 {
+		t.Fatalf("chat overflow counted %d drops, want 1", c.DroppedMessages)
+	}
+	// A full chat queue leaves room for state.
+	c.enqueueEvent(&WSMessage{Op: OpDispatch, Type: EventPresenceUpdate}, nil)
+	if c.DroppedMessages != 1 {
+		t.Fatal("state dropped behind a chat backlog")
+	}
+}
+
+func TestDrainEventsToParkedSession(t *testing.T) {
+	h := &Hub{}
+	c := newIdentifiedTestClient(h, "usr_1")
+	sess := newResumeSession("sess_1", "usr_1")
+	c.enqueueEvent(&WSMessage{Op: OpDispatch, Type: EventMessageCreate}, sess)
+	c.enqueueEvent(&WSMessage{Op: OpDispatch, Type: EventPresenceUpdate}, sess)
+
+	c.drainEventsTo(sess)
+	missed, ok := sess.sinceLocked(0)
+	if !ok || len(missed) != 2 || missed[0].Type != EventPresenceUpdate || missed[1].Type != EventMessageCreate {
+		t.Fatalf("parked session holds %d events ok=%v, want state then chat", len(missed), ok)
+	}
+}
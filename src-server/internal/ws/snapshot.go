@@ -0,0 +1,183 @@
+package ws
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"lobby/internal/cluster"
+	sqldb "lobby/internal/db/sqlc"
+)
+
+const (
+	// memberSnapshotGrace is how long restored members are shown after a
+	// restart while their clients reconnect.
+	memberSnapshotGrace = 20 * time.Second
+	// memberSnapshotMaxAge ignores snapshots too old to describe who is still
+	// around, e.g. after a long outage.
+	memberSnapshotMaxAge      = 2 * time.Minute
+	memberSnapshotSaveTimeout = 5 * time.Second
+)
+
+// saveMemberSnapshot persists the presence, voice, and screen-share state of
+// local members so the next start can show them until they reconnect.
+func (h *Hub) saveMemberSnapshot(ctx context.Context) error {
+	savedAt := h.now().UTC()
+	records := make([]cluster.MemberRecord, 0)
+	for _, userID := range h.localUserIDs() {
+		if rec, ok := h.localMemberRecord(userID); ok {
+			records = append(records, rec)
+		}
+	}
+
+	tx, err := h.database.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	qtx := h.queries.WithTx(tx)
+	if err := qtx.DeleteMemberSnapshots(ctx); err != nil {
+		return fmt.Errorf("clearing member snapshot: %w", err)
+	}
+	for _, rec := range records {
+		if err := qtx.CreateMemberSnapshot(ctx, sqldb.CreateMemberSnapshotParams{
+			UserID:         rec.UserID,
+			Status:         rec.Status,
+			InVoice:        rec.InVoice,
+			Muted:          rec.Muted,
+			Deafened:       rec.Deafened,
+			ServerMuted:    rec.ServerMuted,
+			ServerDeafened: rec.ServerDeafened,
+			PushToTalk:     rec.PushToTalk,
+			Streaming:      rec.Streaming,
+			SavedAt:        savedAt,
+		}); err != nil {
+			return fmt.Errorf("saving member snapshot: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("committing member snapshot: %w", err)
+	}
+
+	slog.Info("member snapshot saved", "component", "hub", "members", len(records))
+	return nil
+}
+
+// restoreMemberSnapshot loads the snapshot saved at the last shutdown and
+// deletes it, so a later crash never replays it.
+func (h *Hub) restoreMemberSnapshot(ctx context.Context, now time.Time) error {
+	rows, err := h.queries.ListMemberSnapshots(ctx)
+	if err != nil {
+		return err
+	}
+	if err := h.queries.DeleteMemberSnapshots(ctx); err != nil {
+		return err
+	}
+
+	restored := make(map[string]cluster.MemberRecord, len(rows))
+	for _, row := range rows {
+		if now.Sub(row.SavedAt) > memberSnapshotMaxAge {
+			continue
+		}
+		restored[row.UserID] = cluster.MemberRecord{
+			UserID:         row.UserID,
+			Status:         row.Status,
+			InVoice:        row.InVoice,
+			Muted:          row.Muted,
+			Deafened:       row.Deafened,
+			ServerMuted:    row.ServerMuted,
+			ServerDeafened: row.ServerDeafened,
+			PushToTalk:     row.PushToTalk,
+			Streaming:      row.Streaming,
+			SeenAt:         row.SavedAt.Unix(),
+		}
+	}
+	if len(restored) == 0 {
+		return nil
+	}
+
+	h.mu.Lock()
+	h.restoredMembers = restored
+	h.restoredUntil = now.Add(memberSnapshotGrace)
+	h.mu.Unlock()
+
+	slog.Info("member snapshot restored", "component", "hub", "members", len(restored), "grace", memberSnapshotGrace)
+	return nil
+}
+
+// restoredMemberLocked returns userID's restored record while the grace
+// period lasts. Caller must hold at least a read lock on h.mu.
+func (h *Hub) restoredMemberLocked(userID string, now time.Time) (cluster.MemberRecord, bool) {
+	if !now.Before(h.restoredUntil) {
+		return cluster.MemberRecord{}, false
+	}
+	rec, ok := h.restoredMembers[userID]
+	return rec, ok
+}
+
+// takeRestoredMemberLocked removes and returns userID's restored record.
+// Caller must hold h.mu.
+func (h *Hub) takeRestoredMemberLocked(userID string) (cluster.MemberRecord, bool) {
+	rec, ok := h.restoredMembers[userID]
+	if ok {
+		delete(h.restoredMembers, userID)
+	}
+	return rec, ok
+}
+
+// endRestoreGrace expires the restored members once the grace period has
+// passed on the hub clock. It runs on the janitor tick.
+func (h *Hub) endRestoreGrace() {
+	h.mu.RLock()
+	ended := len(h.restoredMembers) > 0 && !h.now().Before(h.restoredUntil)
+	h.mu.RUnlock()
+	if ended {
+		h.expireRestoredMembers()
+	}
+}
+
+// expireRestoredMembers ends the grace period and corrects every restored
+// member that did not come back. Must be called from Run.
+func (h *Hub) expireRestoredMembers() {
+	h.mu.Lock()
+	restored := h.restoredMembers
+	h.restoredMembers = nil
+	h.mu.Unlock()
+	if len(restored) == 0 {
+		return
+	}
+
+	remote := h.remoteMembers()
+	for userID, rec := range restored {
+		live, online := h.localMemberRecord(userID)
+		if !online {
+			live, online = remote[userID]
+		}
+		h.retireRestoredMember(rec, live, online)
+	}
+}
+
+// retireRestoredMember publishes whatever part of a restored record the live
+// state no longer matches, so clients that got it in READY catch up. Must be
+// called from Run.
+func (h *Hub) retireRestoredMember(rec, live cluster.MemberRecord, online bool) {
+	if !online && rec.Status != "offline" {
+		h.broadcastPresenceUpdate(PresenceUpdatePayload{UserID: rec.UserID, Status: "offline"}, nil)
+	}
+	if rec.InVoice && !live.InVoice {
+		h.publishFromRun(Event{
+			Topic: TopicVoice,
+			Type:  EventVoiceStateUpdate,
+			Data:  VoiceStateUpdatePayload{UserID: rec.UserID},
+		})
+	}
+	if rec.Streaming && !live.Streaming {
+		h.publishFromRun(Event{
+			Topic: TopicScreenShare,
+			Type:  EventScreenShareUpdate,
+			Data:  ScreenShareUpdatePayload{UserID: rec.UserID},
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package ws
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestMemberSnapshotRestoresUntilGraceEnds(t *testing.T) {
+	h := openBotTestHub(t)
+	alice := newIdentifiedTestClient(h, "usr_1")
+	alice.SetStatus("dnd")
+	h.userClients["usr_1"] = alice
+	h.voiceSessions["usr_1"] = &VoiceSession{State: VoiceLifecycleActive, Muted: true}
+
+	if err := h.saveMemberSnapshot(context.Background()); err != nil {
+		t.Fatalf("saveMemberSnapshot() error = %v", err)
+	}
+
+	// Simulate a restart: a fresh hub on the same database.
+	restarted := &Hub{
+		clients:       make(map[*Client]bool),
+		userClients:   make(map[string]*Client),
+		voiceSessions: make(map[string]*VoiceSession),
+		database:      h.database,
+		queries:       h.queries,
+	}
+	if err := restarted.restoreMemberSnapshot(context.Background(), time.Now().UTC()); err != nil {
+		t.Fatalf("restoreMemberSnapshot() error = %v", err)
+	}
+	if rows, err := restarted.queries.ListMemberSnapshots(context.Background()); err != nil || len(rows) != 0 {
+		t.Fatalf("expected snapshot consumed on restore, got %d rows, err=%v", len(rows), err)
+	}
+
+	member := findMember(t, restarted.GetMemberSnapshot(), "usr_1")
+	if member.Status != "dnd" || !member.InVoice || !member.Muted {
+		t.Fatalf("restored member = %+v, want dnd, in voice, muted", member)
+	}
+
+	bob := newIdentifiedTestClient(restarted, "usr_2")
+	restarted.clients[bob] = true
+	restarted.userClients["usr_2"] = bob
+	restarted.expireRestoredMembers()
+
+	if member := findMember(t, restarted.GetMemberSnapshot(), "usr_1"); member.Status != "offline" || member.InVoice {
+		t.Fatalf("member after grace = %+v, want offline and out of voice", member)
+	}
+	var gotPresence, gotVoice bool
+	for msg := nextSent(bob); msg != nil; msg = nextSent(bob) {
+		switch payload := msg.Data.(type) {
+		case PresenceUpdatePayload:
+			gotPresence = payload.UserID == "usr_1" && payload.Status == "offline"
+		case VoiceStateUpdatePayload:
+			gotVoice = payload.UserID == "usr_1" && !payload.InVoice
+		}
+	}
+	if !gotPresence || !gotVoice {
+		t.Fatalf("expected offline presence and voice leave for usr_1, got presence=%v voice=%v", gotPresence, gotVoice)
+	}
+}
+
+func TestMemberSnapshotIgnoresStaleSnapshot(t *testing.T) {
+	h := openBotTestHub(t)
+	h.userClients["usr_1"] = newIdentifiedTestClient(h, "usr_1")
+	if err := h.saveMemberSnapshot(context.Background()); err != nil {
+		t.Fatalf("saveMemberSnapshot() error = %v", err)
+	}
+	delete(h.userClients, "usr_1")
+
+	if err := h.restoreMemberSnapshot(context.Background(), time.Now().UTC().Add(memberSnapshotMaxAge+time.Minute)); err != nil {
+		t.Fatalf("restoreMemberSnapshot() error = %v", err)
+	}
+	if member := findMember(t, h.GetMemberSnapshot(), "usr_1"); member.Status != "offline" {
+		t.Fatalf("member from stale snapshot = %+v, want offline", member)
+	}
+}
+
+func findMember(t *testing.T, members []MemberState, userID string) MemberState {
+	t.Helper()
+
+	for _, member := range members {
+		if member.ID == userID {
+			return member
+		}
+	}
+	t.Fatalf("member %s not found", userID)
+	return MemberState{}
+}
@@ -0,0 +1,68 @@
+package ws
+
+import (
+	"context"
+	"time"
+
+	"lobby/internal/cluster"
+)
+
+// ReloadUserTimeouts refreshes the cached moderator timeouts and tells other
+// instances to do the same.
+func (h *Hub) ReloadUserTimeouts(ctx context.Context) error {
+	if err := h.reloadUserTimeouts(ctx); err != nil {
+		return err
+	}
+	if h.backplane != nil {
+		h.enqueueClusterTask(clusterTask{env: &cluster.Envelope{
+			Instance: h.instanceID,
+			Type:     cluster.TypeUserTimeouts,
+		}})
+	}
+	return nil
+}
+
+func (h *Hub) reloadUserTimeouts(ctx context.Context) error {
+	rows, err := h.queries.ListActiveUserTimeouts(ctx, h.now().UTC())
+	if err != nil {
+		return err
+	}
+
+	timeouts := make(map[string]time.Time, len(rows))
+	for _, row := range rows {
+		timeouts[row.UserID] = row.ExpiresAt
+	}
+
+	h.mu.Lock()
+	h.userTimeouts = timeouts
+	h.mu.Unlock()
+	return nil
+}
+
+// TimedOutUntil reports whether userID is timed out at now and when the
+// timeout ends.
+func (h *Hub) TimedOutUntil(userID string, now time.Time) (time.Time, bool) {
+	h.mu.RLock()
+	until, ok := h.userTimeouts[userID]
+	h.mu.RUnlock()
+	if !ok || !now.Before(until) {
+		return time.Time{}, false
+	}
+	return until, true
+}
+
+// rejectIfTimedOut sends TIMEOUT with retry_after and returns true when the
+// client's user is timed out.
+func (c *Client) rejectIfTimedOut(nonce string) bool {
+	until, timedOut := c.hub.TimedOutUntil(c.getUserID(), c.hub.now())
+	if !timedOut {
+		return false
+	}
+	c.sendError(ErrorPayload{
+		Code:       ErrCodeTimeout,
+		Message:    "You are timed out",
+		Nonce:      nonce,
+		RetryAfter: until.UnixMilli(),
+	})
+	return true
+}
@@ -0,0 +1,44 @@
+package ws
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTimedOutUserCannotSendOrJoinVoice(t *testing.T) {
+	until := time.Now().Add(10 * time.Minute).Truncate(time.Millisecond)
+	h := &Hub{
+		clients:      make(map[*Client]bool),
+		userTimeouts: map[string]time.Time{"usr_1": until},
+	}
+	client := newIdentifiedTestClient(h, "usr_1")
+	h.clients[client] = true
+
+	client.handleMessageSend(&WSMessage{
+		Op:   OpDispatch,
+		Type: CmdMessageSend,
+		Data: map[string]interface{}{"content": "hello", "nonce": "n1"},
+	})
+	client.handleVoiceJoin(&WSMessage{
+		Op:   OpDispatch,
+		Type: CmdVoiceJoin,
+		Data: map[string]interface{}{},
+	})
+
+	for _, nonce := range []string{"n1", ""} {
+		if len(client.send) == 0 {
+			t.Fatalf("expected TIMEOUT error (nonce %q)", nonce)
+		}
+		payload, ok := (<-client.send).Data.(ErrorPayload)
+		if !ok || payload.Code != ErrCodeTimeout || payload.Nonce != nonce || payload.RetryAfter != until.UnixMilli() {
+			t.Fatalf("unexpected error payload: %+v", payload)
+		}
+	}
+	if len(client.send) != 0 {
+		t.Fatalf("expected nothing else to be sent, got %d messages", len(client.send))
+	}
+
+	if _, timedOut := h.TimedOutUntil("usr_1", until.Add(time.Second)); timedOut {
+		t.Fatal("expected timeout to lapse at its expiry")
+	}
+}
@@ -1,65 +1,90 @@
 package ws
 
 import (
-	"time"
+	"github.com/frisksitron/lobby/src-server/pkg/lobbyclient"
 
 	"lobby/internal/constants"
+	sqldb "lobby/internal/db/sqlc"
 	"lobby/internal/models"
 )
 
+// The wire types live in pkg/lobbyclient so bots and tools can share them;
+// these aliases keep the server's names.
+
 // Operation codes for WebSocket messages
-type OpCode int
+type OpCode = lobbyclient.OpCode
 
 // ProtocolVersion is the exact server/client WS protocol version.
-// Bump this only for breaking wire-contract changes.
-const ProtocolVersion = 1
+const ProtocolVersion = lobbyclient.ProtocolVersion
 
 const (
-	// DISPATCH - Events and commands with type field
-	OpDispatch OpCode = 0
-
-	// Lifecycle ops (Server -> Client)
-	OpHello          OpCode = 1 // Sent on connection
-	OpReady          OpCode = 2 // Sent after successful identify, contains initial state
-	OpInvalidSession OpCode = 3 // Session invalid, must re-identify
+	OpDispatch       = lobbyclient.OpDispatch
+	OpHello          = lobbyclient.OpHello
+	OpReady          = lobbyclient.OpReady
+	OpInvalidSession = lobbyclient.OpInvalidSession
+	OpRequest        = lobbyclient.OpRequest
+	OpResponse       = lobbyclient.OpResponse
+	OpHeartbeat      = lobbyclient.OpHeartbeat
+	OpHeartbeatAck   = lobbyclient.OpHeartbeatAck
+	OpAck            = lobbyclient.OpAck
+	OpNack           = lobbyclient.OpNack
+	OpBatch          = lobbyclient.OpBatch
 )
 
 // Event types (Server -> Client via DISPATCH)
 const (
-	EventPresenceUpdate    = "PRESENCE_UPDATE"
-	EventMessageCreate     = "MESSAGE_CREATE"
-	EventTypingStart       = "TYPING_START"
-	EventTypingStop        = "TYPING_STOP"
-	EventUserUpdate        = "USER_UPDATE"
-	EventServerUpdate      = "SERVER_UPDATE"
-	EventVoiceStateUpdate  = "VOICE_STATE_UPDATE"
-	EventRtcReady          = "RTC_READY"
-	EventRtcOffer          = "RTC_OFFER"
-	EventRtcAnswer         = "RTC_ANSWER"
-	EventRtcIceCandidate   = "RTC_ICE_CANDIDATE"
-	EventVoiceSpeaking     = "VOICE_SPEAKING"
-	EventUserJoined        = "USER_JOINED"
-	EventUserLeft          = "USER_LEFT"
-	EventError             = "ERROR"
-	EventScreenShareUpdate = "SCREEN_SHARE_UPDATE"
+	EventPresenceUpdate    = lobbyclient.EventPresenceUpdate
+	EventAuthExpiring      = lobbyclient.EventAuthExpiring
+	EventMessageCreate     = lobbyclient.EventMessageCreate
+	EventTypingStart       = lobbyclient.EventTypingStart
+	EventTypingStop        = lobbyclient.EventTypingStop
+	EventUserUpdate        = lobbyclient.EventUserUpdate
+	EventServerUpdate      = lobbyclient.EventServerUpdate
+	EventVoiceStateUpdate  = lobbyclient.EventVoiceStateUpdate
+	EventRtcReady          = lobbyclient.EventRtcReady
+	EventRtcOffer          = lobbyclient.EventRtcOffer
+	EventRtcAnswer         = lobbyclient.EventRtcAnswer
+	EventRtcIceCandidate   = lobbyclient.EventRtcIceCandidate
+	EventVoiceSpeaking     = lobbyclient.EventVoiceSpeaking
+	EventUserJoined        = lobbyclient.EventUserJoined
+	EventUserLeft          = lobbyclient.EventUserLeft
+	EventError             = lobbyclient.EventError
+	EventScreenShareUpdate = lobbyclient.EventScreenShareUpdate
+	EventChannelUpdate     = lobbyclient.EventChannelUpdate
+	EventCommandAck        = lobbyclient.EventCommandAck
+	EventMessagesPurged    = lobbyclient.EventMessagesPurged
+	EventNotification      = lobbyclient.EventNotification
+	EventAutomodAlert      = lobbyclient.EventAutomodAlert
+	EventModAlert          = lobbyclient.EventModAlert
+	EventDraftUpdate       = lobbyclient.EventDraftUpdate
+	EventResumed           = lobbyclient.EventResumed
 )
 
 // Command types (Client -> Server via DISPATCH)
 const (
-	CmdIdentify               = "IDENTIFY"
-	CmdPresenceSet            = "PRESENCE_SET"
-	CmdMessageSend            = "MESSAGE_SEND"
-	CmdTyping                 = "TYPING"
-	CmdVoiceJoin              = "VOICE_JOIN"
-	CmdVoiceLeave             = "VOICE_LEAVE"
-	CmdRtcOffer               = "RTC_OFFER"
-	CmdRtcAnswer              = "RTC_ANSWER"
-	CmdRtcIceCandidate        = "RTC_ICE_CANDIDATE"
-	CmdVoiceStateSet          = "VOICE_STATE_SET"
-	CmdScreenShareStart       = "SCREEN_SHARE_START"
-	CmdScreenShareStop        = "SCREEN_SHARE_STOP"
-	CmdScreenShareSubscribe   = "SCREEN_SHARE_SUBSCRIBE"
-	CmdScreenShareUnsubscribe = "SCREEN_SHARE_UNSUBSCRIBE"
+	CmdIdentify               = lobbyclient.CmdIdentify
+	CmdPresenceSet            = lobbyclient.CmdPresenceSet
+	CmdMessageSend            = lobbyclient.CmdMessageSend
+	CmdTyping                 = lobbyclient.CmdTyping
+	CmdTypingStop             = lobbyclient.CmdTypingStop
+	CmdVoiceJoin              = lobbyclient.CmdVoiceJoin
+	CmdVoiceLeave             = lobbyclient.CmdVoiceLeave
+	CmdRtcOffer               = lobbyclient.CmdRtcOffer
+	CmdRtcAnswer              = lobbyclient.CmdRtcAnswer
+	CmdRtcIceCandidate        = lobbyclient.CmdRtcIceCandidate
+	CmdVoiceStateSet          = lobbyclient.CmdVoiceStateSet
+	CmdScreenShareStart       = lobbyclient.CmdScreenShareStart
+	CmdScreenShareStop        = lobbyclient.CmdScreenShareStop
+	CmdScreenShareSubscribe   = lobbyclient.CmdScreenShareSubscribe
+	CmdScreenShareUnsubscribe = lobbyclient.CmdScreenShareUnsubscribe
+	CmdVoiceRelayStart        = lobbyclient.CmdVoiceRelayStart
+	CmdResume                 = lobbyclient.CmdResume
+)
+
+// Request types (Client -> Server via REQUEST)
+const (
+	ReqHistoryGet = lobbyclient.ReqHistoryGet
+	ReqMembersGet = lobbyclient.ReqMembersGet
 )
 
 // Error codes sent in EventError payloads.
@@ -67,6 +92,10 @@ const (
 	ErrCodeAuthFailed                   = constants.ErrCodeAuthFailed
 	ErrCodeAuthExpired                  = constants.ErrCodeAuthExpired
 	ErrCodeRateLimited                  = constants.ErrCodeRateLimited
+	ErrCodeInvalidRequest               = constants.ErrCodeInvalidRequest
+	ErrCodeConflict                     = constants.ErrCodeConflict
+	ErrCodeInternal                     = constants.ErrCodeInternal
+	ErrCodeForbidden                    = constants.ErrCodeForbidden
 	ErrCodeMessageTooLong               = constants.ErrCodeMessageTooLong
 	ErrCodeAttachmentInvalid            = constants.ErrCodeAttachmentInvalid
 	ErrCodeVoiceJoinCooldown            = constants.ErrCodeVoiceJoinCooldown
@@ -78,33 +107,72 @@ const (
 	ErrCodeVoiceNegotiationFailed       = constants.ErrCodeVoiceNegotiationFailed
 	ErrCodeVoiceNegotiationTimeout      = constants.ErrCodeVoiceNegotiationTimeout
 	ErrCodeSignalingRateLimited         = constants.ErrCodeSignalingRateLimited
+	ErrCodeScreenShareInUse             = constants.ErrCodeScreenShareInUse
+	ErrCodeVideoCodecUnsupported        = constants.ErrCodeVideoCodecUnsupported
+	ErrCodeChannelArchived              = constants.ErrCodeChannelArchived
+	ErrCodeAutomodBlocked               = constants.ErrCodeAutomodBlocked
+	ErrCodeAutomodRemoved               = constants.ErrCodeAutomodRemoved
+	ErrCodeTimeout                      = constants.ErrCodeTimeout
+	ErrCodeConnectionLimit              = constants.ErrCodeConnectionLimit
 )
 
-type WSMessage struct {
-	Op   OpCode      `json:"op"`
-	Type string      `json:"t,omitempty"` // Event/command type (only for DISPATCH)
-	Data interface{} `json:"d,omitempty"`
-}
-
-// Server -> Client payloads
-
-type HelloPayload struct{}
-
-type ReadyPayload struct {
-	ProtocolVersion int           `json:"protocol_version"`
-	SessionID       string        `json:"session_id"`
-	User            *ReadyUser    `json:"user"`
-	Members         []MemberState `json:"members"`
-}
-
-type ReadyUser struct {
-	ID        string     `json:"id"`
-	Username  string     `json:"username"`
-	Email     string     `json:"email,omitempty"`
-	AvatarURL string     `json:"avatar_url,omitempty"`
-	CreatedAt time.Time  `json:"created_at"`
-	UpdatedAt *time.Time `json:"updated_at,omitempty"`
-}
+type (
+	WSMessage                   = lobbyclient.WSMessage
+	HelloPayload                = lobbyclient.HelloPayload
+	ReadyPayload                = lobbyclient.ReadyPayload
+	ReadyUser                   = lobbyclient.ReadyUser
+	MemberState                 = lobbyclient.MemberState
+	InvalidSessionPayload       = lobbyclient.InvalidSessionPayload
+	HeartbeatPayload            = lobbyclient.HeartbeatPayload
+	MessageCreatePayload        = lobbyclient.MessageCreatePayload
+	MessageAttachment           = lobbyclient.MessageAttachment
+	MessageEmbed                = lobbyclient.MessageEmbed
+	MessageAuthor               = lobbyclient.MessageAuthor
+	PresenceUpdatePayload       = lobbyclient.PresenceUpdatePayload
+	TypingStartPayload          = lobbyclient.TypingStartPayload
+	TypingStopPayload           = lobbyclient.TypingStopPayload
+	UserUpdatePayload           = lobbyclient.UserUpdatePayload
+	ServerUpdatePayload         = lobbyclient.ServerUpdatePayload
+	ChannelInfo                 = lobbyclient.ChannelInfo
+	ChannelUpdatePayload        = lobbyclient.ChannelUpdatePayload
+	IdentifyPayload             = lobbyclient.IdentifyPayload
+	PresenceOptions             = lobbyclient.PresenceOptions
+	ResumePayload               = lobbyclient.ResumePayload
+	ResumedPayload              = lobbyclient.ResumedPayload
+	MessageSendPayload          = lobbyclient.MessageSendPayload
+	PresenceSetPayload          = lobbyclient.PresenceSetPayload
+	VoiceStateUpdatePayload     = lobbyclient.VoiceStateUpdatePayload
+	VoiceJoinPayload            = lobbyclient.VoiceJoinPayload
+	RtcReadyPayload             = lobbyclient.RtcReadyPayload
+	ICEServerInfo               = lobbyclient.ICEServerInfo
+	ExternalSFUInfo             = lobbyclient.ExternalSFUInfo
+	VoiceNodeInfo               = lobbyclient.VoiceNodeInfo
+	RtcOfferPayload             = lobbyclient.RtcOfferPayload
+	RtcAnswerPayload            = lobbyclient.RtcAnswerPayload
+	RtcIceCandidatePayload      = lobbyclient.RtcIceCandidatePayload
+	VoiceStateSetPayload        = lobbyclient.VoiceStateSetPayload
+	UserJoinedPayload           = lobbyclient.UserJoinedPayload
+	UserLeftPayload             = lobbyclient.UserLeftPayload
+	MessagesPurgedPayload       = lobbyclient.MessagesPurgedPayload
+	DraftUpdatePayload          = lobbyclient.DraftUpdatePayload
+	AutomodAlertPayload         = lobbyclient.AutomodAlertPayload
+	ModAlertPayload             = lobbyclient.ModAlertPayload
+	NotificationPayload         = lobbyclient.NotificationPayload
+	VoiceSpeakingPayload        = lobbyclient.VoiceSpeakingPayload
+	ErrorPayload                = lobbyclient.ErrorPayload
+	AttachmentViolation         = lobbyclient.AttachmentViolation
+	CommandAckPayload           = lobbyclient.CommandAckPayload
+	AuthExpiringPayload         = lobbyclient.AuthExpiringPayload
+	AckPayload                  = lobbyclient.AckPayload
+	NackPayload                 = lobbyclient.NackPayload
+	ResponsePayload             = lobbyclient.ResponsePayload
+	HistoryGetPayload           = lobbyclient.HistoryGetPayload
+	HistoryResult               = lobbyclient.HistoryResult
+	MembersGetPayload           = lobbyclient.MembersGetPayload
+	MembersResult               = lobbyclient.MembersResult
+	ScreenShareUpdatePayload    = lobbyclient.ScreenShareUpdatePayload
+	ScreenShareSubscribePayload = lobbyclient.ScreenShareSubscribePayload
+)
 
 func NewReadyUser(user *models.User) *ReadyUser {
 	if user == nil {
@@ -112,193 +180,25 @@ func NewReadyUser(user *models.User) *ReadyUser {
 	}
 
 	return &ReadyUser{
-		ID:        user.ID,
-		Username:  user.Username,
-		Email:     user.Email,
-		AvatarURL: user.GetAvatarURL(),
-		CreatedAt: user.CreatedAt,
-		UpdatedAt: user.UpdatedAt,
+		ID:                user.ID,
+		Username:          user.Username,
+		Email:             user.Email,
+		AvatarURL:         user.GetAvatarURL(),
+		AvatarAnimatedURL: user.GetAvatarAnimatedURL(),
+		Role:              user.Role,
+		CreatedAt:         user.CreatedAt,
+		UpdatedAt:         user.UpdatedAt,
+		Bot:               user.Bot,
 	}
 }
 
-type MemberState struct {
-	ID        string    `json:"id"`
-	Username  string    `json:"username"`
-	Avatar    string    `json:"avatar_url,omitempty"`
-	Status    string    `json:"status"` // online, idle, dnd, offline
-	InVoice   bool      `json:"in_voice"`
-	Muted     bool      `json:"muted"`
-	Deafened  bool      `json:"deafened"`
-	Streaming bool      `json:"streaming"`
-	CreatedAt time.Time `json:"created_at"`
-}
-
-// InvalidSessionPayload sent when session is invalid
-type InvalidSessionPayload struct {
-	Resumable bool `json:"resumable"`
-}
-
-// MessageCreatePayload sent when a new message is created (via DISPATCH)
-type MessageCreatePayload struct {
-	ID          string              `json:"id"`
-	Author      *MessageAuthor      `json:"author"`
-	Content     string              `json:"content"`
-	Attachments []MessageAttachment `json:"attachments,omitempty"`
-	CreatedAt   string              `json:"created_at"`
-	Nonce       string              `json:"nonce,omitempty"` // Echo back for optimistic updates
-}
-
-type MessageAttachment struct {
-	ID            string `json:"id"`
-	Name          string `json:"name"`
-	MimeType      string `json:"mime_type"`
-	Size          int64  `json:"size"`
-	URL           string `json:"url"`
-	PreviewURL    string `json:"preview_url,omitempty"`
-	PreviewWidth  int64  `json:"preview_width,omitempty"`
-	PreviewHeight int64  `json:"preview_height,omitempty"`
-}
-
-type MessageAuthor struct {
-	ID       string `json:"id"`
-	Username string `json:"username,omitempty"`
-	Avatar   string `json:"avatar_url,omitempty"`
-}
-
-type PresenceUpdatePayload struct {
-	UserID string `json:"user_id"`
-	Status string `json:"status"`
-}
-
-type TypingStartPayload struct {
-	UserID    string `json:"user_id"`
-	Username  string `json:"username"`
-	Timestamp string `json:"timestamp"`
-}
-
-type TypingStopPayload struct {
-	UserID string `json:"user_id"`
-}
-
-type UserUpdatePayload struct {
-	ID       string `json:"id"`
-	Username string `json:"username,omitempty"`
-	Avatar   string `json:"avatar_url,omitempty"`
-}
-
-type ServerUpdatePayload struct {
-	Name    string `json:"name,omitempty"`
-	IconURL string `json:"icon_url,omitempty"`
-}
-
-// Client -> Server payloads (via DISPATCH)
-
-// IdentifyPayload sent by client to authenticate
-type IdentifyPayload struct {
-	Token    string           `json:"token"`
-	Presence *PresenceOptions `json:"presence,omitempty"`
-}
-
-// PresenceOptions for initial presence on IDENTIFY
-type PresenceOptions struct {
-	Status string `json:"status"` // online, idle, dnd (not offline)
-}
-
-// MessageSendPayload sent by client to send a message
-type MessageSendPayload struct {
-	Content       string   `json:"content"`
-	AttachmentIDs []string `json:"attachment_ids,omitempty"`
-	Nonce         string   `json:"nonce,omitempty"` // Client-generated ID for tracking
-}
-
-// PresenceSetPayload sent by client to set presence
-type PresenceSetPayload struct {
-	Status string `json:"status"` // online, idle, dnd, offline
-}
-
-// VoiceStateUpdatePayload sent when a user's voice state changes (via DISPATCH)
-type VoiceStateUpdatePayload struct {
-	UserID   string `json:"user_id"`
-	InVoice  bool   `json:"in_voice"`
-	Muted    bool   `json:"muted"`
-	Deafened bool   `json:"deafened"`
-}
-
-// VoiceJoinPayload sent by client to join voice
-type VoiceJoinPayload struct {
-	Muted    bool `json:"muted"`
-	Deafened bool `json:"deafened"`
-}
-
-// RTC Payload types
-
-// RtcReadyPayload sent when client joins voice and should start WebRTC
-type RtcReadyPayload struct {
-	ICEServers []ICEServerInfo `json:"ice_servers"`
-}
-
-// ICEServerInfo for client configuration
-type ICEServerInfo struct {
-	URLs       []string `json:"urls"`
-	Username   string   `json:"username,omitempty"`
-	Credential string   `json:"credential,omitempty"`
-}
-
-// RtcOfferPayload contains SDP offer
-type RtcOfferPayload struct {
-	SDP string `json:"sdp"`
-}
-
-// RtcAnswerPayload contains SDP answer
-type RtcAnswerPayload struct {
-	SDP string `json:"sdp"`
-}
-
-// RtcIceCandidatePayload contains ICE candidate
-type RtcIceCandidatePayload struct {
-	Candidate     string  `json:"candidate"`
-	SDPMid        *string `json:"sdp_mid,omitempty"`
-	SDPMLineIndex *uint16 `json:"sdp_mline_index,omitempty"`
-}
-
-// VoiceStateSetPayload for mute/deafen/speaking changes
-type VoiceStateSetPayload struct {
-	Muted    *bool `json:"muted,omitempty"`
-	Deafened *bool `json:"deafened,omitempty"`
-	Speaking *bool `json:"speaking,omitempty"`
-}
-
-// UserJoinedPayload sent when server membership is created or restored.
-type UserJoinedPayload struct {
-	Member MemberState `json:"member"`
-}
-
-// UserLeftPayload sent when a user leaves the server (account deactivated)
-type UserLeftPayload struct {
-	UserID string `json:"user_id"`
-}
-
-// VoiceSpeakingPayload broadcast when speaking state changes
-type VoiceSpeakingPayload struct {
-	UserID   string `json:"user_id"`
-	Speaking bool   `json:"speaking"`
-}
-
-// ErrorPayload sent when the server rejects a client action
-type ErrorPayload struct {
-	Code       string `json:"code"`
-	Message    string `json:"message"`
-	Nonce      string `json:"nonce,omitempty"`
-	RetryAfter int64  `json:"retry_after,omitempty"` // Unix ms timestamp
-}
-
-// ScreenShareUpdatePayload sent when a user's screen share state changes
-type ScreenShareUpdatePayload struct {
-	UserID    string `json:"user_id"`
-	Streaming bool   `json:"streaming"`
-}
-
-// ScreenShareSubscribePayload sent by client to subscribe to a stream
-type ScreenShareSubscribePayload struct {
-	StreamerID string `json:"streamer_id"`
+func NewChannelInfo(row sqldb.TextChannel) *ChannelInfo {
+	return &ChannelInfo{
+		Name:        row.Name,
+		Topic:       row.Topic,
+		Description: row.Description,
+		Private:     row.Private,
+		Archived:    row.Archived,
+		SlowMode:    row.SlowModeSeconds,
+	}
 }
@@ -0,0 +1,69 @@
+package ws
+
+import "time"
+
+// typingTTL is how long a TYPING keeps a user shown as typing. Clients resend
+// TYPING every few seconds while typing; once it lapses the hub broadcasts
+// TYPING_STOP itself, on the janitor tick.
+const typingTTL = 8 * time.Second
+
+// typingState is a user's unexpired TYPING.
+type typingState struct {
+	client    *Client
+	expiresAt time.Time
+}
+
+// startTyping marks c's user as typing for typingTTL.
+func (h *Hub) startTyping(c *Client) {
+	h.typingMu.Lock()
+	defer h.typingMu.Unlock()
+	if h.typing == nil {
+		h.typing = make(map[string]typingState)
+	}
+	h.typing[c.user.ID] = typingState{client: c, expiresAt: h.now().Add(typingTTL)}
+}
+
+// endTyping clears c's typing state and reports whether c was typing.
+func (h *Hub) endTyping(c *Client) bool {
+	h.typingMu.Lock()
+	defer h.typingMu.Unlock()
+	state, ok := h.typing[c.getUserID()]
+	if !ok || state.client != c {
+		return false
+	}
+	delete(h.typing, c.getUserID())
+	return true
+}
+
+// stopTyping broadcasts TYPING_STOP for c if it was typing.
+func (h *Hub) stopTyping(c *Client) {
+	if h.endTyping(c) {
+		h.broadcastTyping(EventTypingStop, TypingStopPayload{UserID: c.getUserID()}, c)
+	}
+}
+
+// expireTyping broadcasts TYPING_STOP for users whose TYPING lapsed. It runs
+// on the janitor tick.
+func (h *Hub) expireTyping() {
+	now := h.now()
+	var expired []*Client
+	h.typingMu.Lock()
+	for userID, state := range h.typing {
+		if !now.Before(state.expiresAt) {
+			delete(h.typing, userID)
+			expired = append(expired, state.client)
+		}
+	}
+	h.typingMu.Unlock()
+
+	for _, c := range expired {
+		h.broadcastTyping(EventTypingStop, TypingStopPayload{UserID: c.getUserID()}, c)
+	}
+}
+
+func (c *Client) handleTypingStop() {
+	if !c.IsIdentified() {
+		return
+	}
+	c.hub.stopTyping(c)
+}
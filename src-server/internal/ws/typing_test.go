@@ -0,0 +1,73 @@
+package ws
+
+import (
+	"testing"
+	"time"
+
+	"lobby/internal/clock"
+)
+
+func TestTypingExpiresAfterTTL(t *testing.T) {
+	fake := clock.NewFake(time.Now())
+	h := &Hub{clients: make(map[*Client]bool), events: NewEventBus(), clock: fake}
+	typist := newIdentifiedTestClient(h, "usr_1")
+
+	var stops []string
+	h.Events().Subscribe(SubscriberFunc(func(e Event) {
+		if e.Type == EventTypingStop {
+			stops = append(stops, e.AuthorID)
+		}
+	}), TopicTyping)
+
+	h.startTyping(typist)
+	fake.Advance(typingTTL - time.Second)
+	h.expireTyping()
+	if len(stops) != 0 {
+		t.Fatalf("TYPING_STOP before the TTL: %v", stops)
+	}
+
+	// A repeated TYPING extends the indicator.
+	h.startTyping(typist)
+	fake.Advance(typingTTL - time.Second)
+	h.expireTyping()
+	if len(stops) != 0 {
+		t.Fatalf("TYPING_STOP despite a refreshed TYPING: %v", stops)
+	}
+
+	fake.Advance(time.Second)
+	h.expireTyping()
+	h.expireTyping()
+	if len(stops) != 1 || stops[0] != "usr_1" {
+		t.Fatalf("TYPING_STOP authors = %v, want one for usr_1", stops)
+	}
+}
+
+func TestStopTypingOnlyWhenTyping(t *testing.T) {
+	h := &Hub{clients: make(map[*Client]bool), events: NewEventBus()}
+	typist := newIdentifiedTestClient(h, "usr_1")
+
+	stops := 0
+	h.Events().Subscribe(SubscriberFunc(func(e Event) {
+		if e.Type == EventTypingStop {
+			stops++
+		}
+	}), TopicTyping)
+
+	typist.handleTypingStop()
+	if stops != 0 {
+		t.Fatal("TYPING_STOP broadcast for a user who was not typing")
+	}
+
+	h.startTyping(typist)
+	other := newIdentifiedTestClient(h, "usr_1")
+	h.stopTyping(other)
+	if stops != 0 {
+		t.Fatal("another connection of the user stopped its typing")
+	}
+
+	typist.handleTypingStop()
+	typist.handleTypingStop()
+	if stops != 1 {
+		t.Fatalf("got %d TYPING_STOP, want 1", stops)
+	}
+}
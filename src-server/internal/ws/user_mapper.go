@@ -7,13 +7,16 @@ import (
 
 func modelUserFromDBUser(row sqldb.User) *models.User {
 	return &models.User{
-		ID:             row.ID,
-		Username:       row.Username,
-		Email:          row.Email,
-		AvatarURL:      row.AvatarUrl,
-		CreatedAt:      row.CreatedAt,
-		UpdatedAt:      row.UpdatedAt,
-		DeactivatedAt:  row.DeactivatedAt,
-		SessionVersion: int(row.SessionVersion),
+		ID:                row.ID,
+		Username:          row.Username,
+		Email:             row.Email,
+		AvatarURL:         row.AvatarUrl,
+		AvatarAnimatedURL: row.AvatarAnimatedUrl,
+		Role:              row.Role,
+		CreatedAt:         row.CreatedAt,
+		UpdatedAt:         row.UpdatedAt,
+		DeactivatedAt:     row.DeactivatedAt,
+		SessionVersion:    int(row.SessionVersion),
+		Bot:               row.Bot,
 	}
 }
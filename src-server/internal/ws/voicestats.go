@@ -0,0 +1,74 @@
+package ws
+
+import (
+	"time"
+
+	"github.com/frisksitron/lobby/src-server/pkg/lobbyclient"
+
+	"lobby/internal/sfu"
+)
+
+type VoiceStatsPayload = lobbyclient.VoiceStatsPayload
+
+const EventVoiceStats = lobbyclient.EventVoiceStats
+
+// Thresholds past which a connection is rated fair or poor. Either loss
+// direction counts.
+const (
+	fairRTT    = 200 * time.Millisecond
+	poorRTT    = 400 * time.Millisecond
+	fairJitter = 30 * time.Millisecond
+	poorJitter = 100 * time.Millisecond
+	fairLoss   = 0.02
+	poorLoss   = 0.08
+)
+
+// VoiceQuality rates stats as one of the lobbyclient.VoiceQuality values.
+func VoiceQuality(stats sfu.PeerStats) string {
+	loss := max(stats.UplinkLoss, stats.DownlinkLoss)
+	switch {
+	case stats.RTT >= poorRTT || stats.Jitter >= poorJitter || loss >= poorLoss:
+		return lobbyclient.VoiceQualityPoor
+	case stats.RTT >= fairRTT || stats.Jitter >= fairJitter || loss >= fairLoss:
+		return lobbyclient.VoiceQualityFair
+	default:
+		return lobbyclient.VoiceQualityGood
+	}
+}
+
+func voiceStatsPayload(stats sfu.PeerStats) VoiceStatsPayload {
+	return VoiceStatsPayload{
+		RTTMs:        stats.RTT.Milliseconds(),
+		JitterMs:     stats.Jitter.Milliseconds(),
+		UplinkLoss:   stats.UplinkLoss,
+		DownlinkLoss: stats.DownlinkLoss,
+		UploadKbps:   stats.InboundBitrate / 1000,
+		DownloadKbps: stats.OutboundBitrate / 1000,
+		Quality:      VoiceQuality(stats),
+	}
+}
+
+// VoiceStats returns the connection stats of every voice peer as of the
+// last janitor tick, sorted by user ID.
+func (h *Hub) VoiceStats() []sfu.PeerStats {
+	h.voiceStatsMu.Lock()
+	defer h.voiceStatsMu.Unlock()
+	return h.voiceStats
+}
+
+// sendVoiceStats samples every voice peer and sends each user theirs as
+// VOICE_STATS. Runs on the janitor tick, the only caller of PeerStats.
+func (h *Hub) sendVoiceStats() {
+	if h.sfu == nil {
+		return
+	}
+	stats := h.sfu.PeerStats()
+
+	h.voiceStatsMu.Lock()
+	h.voiceStats = stats
+	h.voiceStatsMu.Unlock()
+
+	for _, s := range stats {
+		h.SendDispatchToUser(s.UserID, EventVoiceStats, voiceStatsPayload(s))
+	}
+}
@@ -0,0 +1,28 @@
+package ws
+
+import (
+	"testing"
+	"time"
+
+	"github.com/frisksitron/lobby/src-server/pkg/lobbyclient"
+
+	"lobby/internal/sfu"
+)
+
+func TestVoiceQuality(t *testing.T) {
+	tests := []struct {
+		stats sfu.PeerStats
+		want  string
+	}{
+		{sfu.PeerStats{RTT: 40 * time.Millisecond, UplinkLoss: 0.005}, lobbyclient.VoiceQualityGood},
+		{sfu.PeerStats{RTT: 250 * time.Millisecond}, lobbyclient.VoiceQualityFair},
+		{sfu.PeerStats{RTT: 40 * time.Millisecond, DownlinkLoss: 0.03}, lobbyclient.VoiceQualityFair},
+		{sfu.PeerStats{RTT: 40 * time.Millisecond, UplinkLoss: 0.1}, lobbyclient.VoiceQualityPoor},
+		{sfu.PeerStats{Jitter: 150 * time.Millisecond}, lobbyclient.VoiceQualityPoor},
+	}
+	for _, tt := range tests {
+		if got := VoiceQuality(tt.stats); got != tt.want {
+			t.Errorf("VoiceQuality(%+v) = %q, want %q", tt.stats, got, tt.want)
+		}
+	}
+}
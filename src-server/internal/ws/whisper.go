@@ -0,0 +1,146 @@
+package ws
+
+import (
+	"errors"
+	"log/slog"
+	"slices"
+
+	"github.com/frisksitron/lobby/src-server/pkg/lobbyclient"
+)
+
+type VoiceWhisperStartPayload = lobbyclient.VoiceWhisperStartPayload
+
+const (
+	CmdVoiceWhisperStart = lobbyclient.CmdVoiceWhisperStart
+	CmdVoiceWhisperStop  = lobbyclient.CmdVoiceWhisperStop
+)
+
+// maxWhisperRecipients bounds one whisper, which is meant for a few people.
+const maxWhisperRecipients = 16
+
+var (
+	errWhisperNotInVoice     = errors.New("whispering requires an active voice session")
+	errWhisperNoRecipients   = errors.New("no whisper recipient is in voice")
+	errWhisperTooManyTargets = errors.New("too many whisper recipients")
+)
+
+// startWhisper limits userID's audio to the recipients in voice on this
+// instance. Unknown recipients, duplicates and userID itself are skipped.
+func (h *Hub) startWhisper(userID string, recipientIDs []string) (*VoiceState, error) {
+	h.mu.Lock()
+	session, ok := h.voiceSessions[userID]
+	if !ok || session.State != VoiceLifecycleActive {
+		h.mu.Unlock()
+		return nil, errWhisperNotInVoice
+	}
+	var recipients []string
+	for _, recipientID := range recipientIDs {
+		if recipientID == userID || slices.Contains(recipients, recipientID) {
+			continue
+		}
+		if _, inVoice := h.voiceSessions[recipientID]; inVoice {
+			recipients = append(recipients, recipientID)
+		}
+	}
+	if len(recipients) == 0 {
+		h.mu.Unlock()
+		return nil, errWhisperNoRecipients
+	}
+	if len(recipients) > maxWhisperRecipients {
+		h.mu.Unlock()
+		return nil, errWhisperTooManyTargets
+	}
+	session.WhisperTo = recipients
+	state := session.voiceState()
+	h.mu.Unlock()
+
+	h.sfu.SetWhisper(userID, recipients)
+	return state, nil
+}
+
+// stopWhisper sends userID's audio to everyone again. It returns nil when
+// userID was not whispering.
+func (h *Hub) stopWhisper(userID string) *VoiceState {
+	h.mu.Lock()
+	session, ok := h.voiceSessions[userID]
+	if !ok || session.WhisperTo == nil {
+		h.mu.Unlock()
+		return nil
+	}
+	session.WhisperTo = nil
+	state := session.voiceState()
+	h.mu.Unlock()
+
+	h.sfu.ClearWhisper(userID)
+	return state
+}
+
+func (c *Client) handleVoiceWhisperStart(msg *WSMessage) {
+	if !c.IsIdentified() {
+		return
+	}
+
+	var data VoiceWhisperStartPayload
+	if !c.decodeDispatchData(msg, &data) {
+		return
+	}
+
+	if c.hub.GetSFU() == nil {
+		c.sendError(ErrorPayload{
+			Code:    ErrCodeInvalidRequest,
+			Message: "Whispering is not available on this server",
+		})
+		return
+	}
+
+	state, err := c.hub.startWhisper(c.user.ID, data.RecipientIDs)
+	switch {
+	case errors.Is(err, errWhisperNotInVoice):
+		c.sendError(ErrorPayload{
+			Code:    ErrCodeVoiceNotInChannel,
+			Message: "Must be in voice to whisper",
+		})
+		return
+	case errors.Is(err, errWhisperNoRecipients):
+		c.sendError(ErrorPayload{
+			Code:    ErrCodeInvalidRequest,
+			Message: "None of the whisper recipients are in voice",
+		})
+		return
+	case errors.Is(err, errWhisperTooManyTargets):
+		c.sendError(ErrorPayload{
+			Code:    ErrCodeInvalidRequest,
+			Message: "Too many whisper recipients",
+		})
+		return
+	}
+
+	c.broadcastWhisperState(state)
+	slog.Debug("user started whispering", "component", "ws", "user_id", c.user.ID, "recipients", len(state.WhisperTo))
+}
+
+func (c *Client) handleVoiceWhisperStop() {
+	if !c.IsIdentified() {
+		return
+	}
+
+	if state := c.hub.stopWhisper(c.user.ID); state != nil {
+		c.broadcastWhisperState(state)
+		slog.Debug("user stopped whispering", "component", "ws", "user_id", c.user.ID)
+	}
+}
+
+func (c *Client) broadcastWhisperState(state *VoiceState) {
+	c.hub.BroadcastDispatch(EventVoiceStateUpdate, VoiceStateUpdatePayload{
+		UserID:          c.user.ID,
+		InVoice:         true,
+		Muted:           state.Muted,
+		Deafened:        state.Deafened,
+		ServerMuted:     state.ServerMuted,
+		ServerDeafened:  state.ServerDeafened,
+		PushToTalk:      state.PushToTalk,
+		Relay:           state.Relay,
+		PrioritySpeaker: state.PrioritySpeaker,
+		WhisperTo:       state.WhisperTo,
+	})
+}
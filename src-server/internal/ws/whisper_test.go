@@ -0,0 +1,53 @@
+package ws
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"lobby/internal/sfu"
+)
+
+func TestStartWhisperKeepsRecipientsInVoice(t *testing.T) {
+	s, err := sfu.New(&sfu.Config{})
+	if err != nil {
+		t.Fatalf("sfu.New() error = %v", err)
+	}
+	t.Cleanup(s.Close)
+	h := &Hub{
+		voiceSessions: map[string]*VoiceSession{
+			"usr_1": {State: VoiceLifecycleActive},
+			"usr_2": {State: VoiceLifecycleActive},
+			"usr_3": {State: VoiceLifecycleJoining},
+		},
+		sfu: s,
+	}
+
+	state, err := h.startWhisper("usr_1", []string{"usr_2", "usr_1", "usr_2", "usr_gone", "usr_3"})
+	if err != nil {
+		t.Fatalf("startWhisper() error = %v", err)
+	}
+	if !slices.Equal(state.WhisperTo, []string{"usr_2", "usr_3"}) {
+		t.Fatalf("WhisperTo = %v, want usr_2 and usr_3", state.WhisperTo)
+	}
+	if !s.IsWhispering("usr_1") {
+		t.Fatal("SFU is not limiting usr_1's audio")
+	}
+
+	if _, err := h.startWhisper("usr_1", []string{"usr_gone"}); !errors.Is(err, errWhisperNoRecipients) {
+		t.Fatalf("startWhisper() without recipients in voice error = %v", err)
+	}
+	if _, err := h.startWhisper("usr_3", []string{"usr_1"}); !errors.Is(err, errWhisperNotInVoice) {
+		t.Fatalf("startWhisper() while joining error = %v", err)
+	}
+
+	if state := h.stopWhisper("usr_1"); state == nil || state.WhisperTo != nil {
+		t.Fatalf("stopWhisper() = %+v, want a state without recipients", state)
+	}
+	if s.IsWhispering("usr_1") {
+		t.Fatal("SFU still limiting usr_1's audio after stopping")
+	}
+	if state := h.stopWhisper("usr_1"); state != nil {
+		t.Fatalf("second stopWhisper() = %+v, want nil", state)
+	}
+}
@@ -0,0 +1,40 @@
+package ws
+
+import "lobby/internal/models"
+
+// SetWordMask sets the words masked in messages shown to members. Must be
+// called before Run.
+func (h *Hub) SetWordMask(mask *models.WordMask) {
+	h.wordMask = mask
+}
+
+// maskContentFor returns content as user should see it: unchanged for
+// moderators and admins, with masked words replaced for everyone else.
+func (h *Hub) maskContentFor(user *models.User, content string) string {
+	if user != nil && models.RoleAtLeast(user.Role, models.RoleModerator) {
+		return content
+	}
+	return h.wordMask.Mask(content)
+}
+
+// publishMessageCreate broadcasts a new message to the text channel, with
+// masked words replaced for clients below moderator. Viewers who muted the
+// author are skipped.
+func (h *Hub) publishMessageCreate(message MessageCreatePayload) {
+	e := Event{
+		Topic:    TopicMessage,
+		Type:     EventMessageCreate,
+		Data:     message,
+		Audience: AudienceChannel,
+	}
+	if message.Author != nil {
+		e.AuthorID = message.Author.ID
+	}
+	if masked := h.wordMask.Mask(message.Content); masked != message.Content {
+		maskedMessage := message
+		maskedMessage.Content = masked
+		maskedMessage.Embeds = h.messageEmbeds(masked)
+		e.MaskedData = maskedMessage
+	}
+	h.Publish(e)
+}
@@ -0,0 +1,60 @@
+package ws
+
+import (
+	"testing"
+
+	"lobby/internal/models"
+)
+
+func TestWordMask(t *testing.T) {
+	mask := models.NewWordMask([]string{"darn", " heck ", "dang it", ""})
+
+	tests := []struct {
+		content string
+		want    string
+	}{
+		{content: "well darn", want: "well ****"},
+		{content: "DARN, Heck!", want: "****, ****!"},
+		{content: "darned heckler", want: "darned heckler"},
+		{content: "oh dang it all", want: "oh ******* all"},
+		{content: "clean message", want: "clean message"},
+	}
+	for _, tt := range tests {
+		if got := mask.Mask(tt.content); got != tt.want {
+			t.Fatalf("Mask(%q) = %q, want %q", tt.content, got, tt.want)
+		}
+	}
+
+	if models.NewWordMask([]string{" "}) != nil {
+		t.Fatal("expected nil mask for blank word list")
+	}
+	var none *models.WordMask
+	if got := none.Mask("darn"); got != "darn" {
+		t.Fatalf("nil Mask() = %q, want unchanged", got)
+	}
+}
+
+func TestPublishMessageCreateMasksForMembers(t *testing.T) {
+	h := &Hub{
+		clients:  make(map[*Client]bool),
+		wordMask: models.NewWordMask([]string{"darn"}),
+	}
+	member := newIdentifiedTestClient(h, "usr_member")
+	member.user.Role = models.RoleMember
+	moderator := newIdentifiedTestClient(h, "usr_mod")
+	moderator.user.Role = models.RoleModerator
+	h.clients[member] = true
+	h.clients[moderator] = true
+
+	h.publishMessageCreate(MessageCreatePayload{ID: "msg_1", Content: "darn it"})
+
+	for client, want := range map[*Client]string{member: "**** it", moderator: "darn it"} {
+		if client.queuedLen() != 1 {
+			t.Fatalf("%s received %d messages, want 1", client.user.ID, client.queuedLen())
+		}
+		payload, ok := nextSent(client).Data.(MessageCreatePayload)
+		if !ok || payload.Content != want {
+			t.Fatalf("%s content = %q, want %q", client.user.ID, payload.Content, want)
+		}
+	}
+}
@@ -0,0 +1,324 @@
+package lobbyclient
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/bits"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Client calls the REST API of one Lobby server. It is safe for concurrent
+// use.
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+
+	mu    sync.RWMutex
+	token string
+}
+
+// New returns a client for the server at baseURL, e.g.
+// "https://lobby.example.com".
+func New(baseURL string) *Client {
+	return &Client{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// SetHTTPClient replaces the HTTP client used for REST calls.
+func (c *Client) SetHTTPClient(httpClient *http.Client) {
+	c.httpClient = httpClient
+}
+
+// SetToken sets the access token sent with REST calls. Sign-in methods set
+// it themselves.
+func (c *Client) SetToken(accessToken string) {
+	c.mu.Lock()
+	c.token = accessToken
+	c.mu.Unlock()
+}
+
+// Token returns the current access token.
+func (c *Client) Token() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.token
+}
+
+// APIError is a REST error response.
+type APIError struct {
+	Status  int
+	Code    string
+	Message string
+	// RetryAfter is set from the Retry-After header of 429 responses.
+	RetryAfter time.Duration
+}
+
+func (e *APIError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("lobby: %d %s", e.Status, e.Code)
+	}
+	return fmt.Sprintf("lobby: %d %s: %s", e.Status, e.Code, e.Message)
+}
+
+// User is the REST user representation.
+type User struct {
+	ID                string     `json:"id"`
+	Username          string     `json:"username"`
+	Email             string     `json:"email,omitempty"`
+	AvatarURL         *string    `json:"avatarUrl,omitempty"`
+	AvatarAnimatedURL *string    `json:"avatarAnimatedUrl,omitempty"`
+	Role              string     `json:"role"`
+	CreatedAt         time.Time  `json:"createdAt"`
+	UpdatedAt         *time.Time `json:"updatedAt,omitempty"`
+	Bot               bool       `json:"bot,omitempty"`
+}
+
+// Message is a text channel message as returned by GET /api/v1/messages.
+type Message struct {
+	ID                      string                  `json:"id"`
+	AuthorID                string                  `json:"authorId"`
+	AuthorName              string                  `json:"authorName"`
+	AuthorAvatarURL         *string                 `json:"authorAvatarUrl,omitempty"`
+	AuthorAvatarAnimatedURL *string                 `json:"authorAvatarAnimatedUrl,omitempty"`
+	AuthorBot               bool                    `json:"authorBot,omitempty"`
+	Content                 string                  `json:"content"`
+	Attachments             []RESTMessageAttachment `json:"attachments,omitempty"`
+	Embeds                  []RESTMessageEmbed      `json:"embeds,omitempty"`
+	CreatedAt               time.Time               `json:"createdAt"`
+	EditedAt                *time.Time              `json:"editedAt,omitempty"`
+}
+
+// RESTMessageEmbed is the camelCase REST form of MessageEmbed.
+type RESTMessageEmbed struct {
+	URL      string `json:"url"`
+	ProxyURL string `json:"proxyUrl"`
+}
+
+// RESTMessageAttachment is the camelCase REST form of MessageAttachment.
+type RESTMessageAttachment struct {
+	ID                string `json:"id"`
+	Name              string `json:"name"`
+	MimeType          string `json:"mimeType"`
+	Size              int64  `json:"size"`
+	URL               string `json:"url"`
+	PreviewURL        string `json:"previewUrl,omitempty"`
+	PreviewWidth      int64  `json:"previewWidth,omitempty"`
+	PreviewHeight     int64  `json:"previewHeight,omitempty"`
+	PreviewText       string `json:"previewText,omitempty"`
+	PreviewLanguage   string `json:"previewLanguage,omitempty"`
+	PreviewFrameCount int64  `json:"previewFrameCount,omitempty"`
+	PreviewDurationMs int64  `json:"previewDurationMs,omitempty"`
+	ScanStatus        string `json:"scanStatus,omitempty"`
+	AudioDurationMs   int64  `json:"audioDurationMs,omitempty"`
+	AudioWaveform     string `json:"audioWaveform,omitempty"`
+}
+
+// Session is a signed-in session.
+type Session struct {
+	User         *User  `json:"user"`
+	AccessToken  string `json:"accessToken"`
+	RefreshToken string `json:"refreshToken"`
+	ExpiresAt    string `json:"expiresAt"`
+}
+
+// VerifyResult is the outcome of VerifyMagicCode. Existing accounts get a
+// Session; new addresses get a RegistrationToken to pass to Register.
+type VerifyResult struct {
+	Next                  string   `json:"next"`
+	RegistrationToken     string   `json:"registrationToken,omitempty"`
+	RegistrationExpiresAt string   `json:"registrationExpiresAt,omitempty"`
+	Session               *Session `json:"session,omitempty"`
+}
+
+// Tokens is a rotated token pair from Refresh.
+type Tokens struct {
+	AccessToken  string `json:"accessToken"`
+	RefreshToken string `json:"refreshToken"`
+	ExpiresAt    string `json:"expiresAt"`
+}
+
+type magicCodeChallenge struct {
+	Required  bool   `json:"required"`
+	Challenge string `json:"challenge,omitempty"`
+	Bits      int    `json:"bits,omitempty"`
+}
+
+// RequestMagicCode mails a sign-in code to email, solving the server's
+// proof-of-work challenge first when it requires one.
+func (c *Client) RequestMagicCode(ctx context.Context, email string) error {
+	var challenge magicCodeChallenge
+	if err := c.do(ctx, http.MethodGet, "/api/v1/auth/login/challenge", nil, &challenge); err != nil {
+		return err
+	}
+	req := map[string]string{"email": email}
+	if challenge.Required {
+		req["challenge"] = challenge.Challenge
+		req["nonce"] = SolveChallenge(challenge.Challenge, strings.ToLower(strings.TrimSpace(email)), challenge.Bits)
+	}
+	return c.do(ctx, http.MethodPost, "/api/v1/auth/login/magic-code", req, nil)
+}
+
+// VerifyMagicCode exchanges a mailed code. When it signs in, the session's
+// access token is set on the client.
+func (c *Client) VerifyMagicCode(ctx context.Context, email, code string) (*VerifyResult, error) {
+	var result VerifyResult
+	if err := c.do(ctx, http.MethodPost, "/api/v1/auth/login/magic-code/verify", map[string]string{
+		"email": email,
+		"code":  code,
+	}, &result); err != nil {
+		return nil, err
+	}
+	if result.Session != nil {
+		c.SetToken(result.Session.AccessToken)
+	}
+	return &result, nil
+}
+
+// Register creates the account for a registration token from
+// VerifyMagicCode and sets its access token on the client.
+func (c *Client) Register(ctx context.Context, registrationToken, username string) (*Session, error) {
+	var session Session
+	if err := c.do(ctx, http.MethodPost, "/api/v1/auth/register", map[string]string{
+		"registrationToken": registrationToken,
+		"username":          username,
+	}, &session); err != nil {
+		return nil, err
+	}
+	c.SetToken(session.AccessToken)
+	return &session, nil
+}
+
+// Refresh rotates refreshToken and sets the new access token on the client.
+// The old refresh token stops working.
+func (c *Client) Refresh(ctx context.Context, refreshToken string) (*Tokens, error) {
+	var tokens Tokens
+	if err := c.do(ctx, http.MethodPost, "/api/v1/auth/refresh", map[string]string{
+		"refreshToken": refreshToken,
+	}, &tokens); err != nil {
+		return nil, err
+	}
+	c.SetToken(tokens.AccessToken)
+	return &tokens, nil
+}
+
+// Me returns the signed-in user.
+func (c *Client) Me(ctx context.Context) (*User, error) {
+	var user User
+	if err := c.do(ctx, http.MethodGet, "/api/v1/users/me", nil, &user); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// ListMessages returns text channel history, newest first. before is a
+// message ID cursor and limit 0 uses the server default.
+func (c *Client) ListMessages(ctx context.Context, before string, limit int) ([]Message, error) {
+	query := url.Values{}
+	if before != "" {
+		query.Set("before", before)
+	}
+	if limit > 0 {
+		query.Set("limit", strconv.Itoa(limit))
+	}
+	path := "/api/v1/messages"
+	if len(query) > 0 {
+		path += "?" + query.Encode()
+	}
+
+	var messages []Message
+	if err := c.do(ctx, http.MethodGet, path, nil, &messages); err != nil {
+		return nil, err
+	}
+	return messages, nil
+}
+
+func (c *Client) do(ctx context.Context, method, path string, body, out any) error {
+	var reader io.Reader
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("encoding request: %w", err)
+		}
+		reader = bytes.NewReader(encoded)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, reader)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if token := c.Token(); token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	raw, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("reading %s %s response: %w", method, path, err)
+	}
+	if resp.StatusCode >= http.StatusBadRequest {
+		apiErr := &APIError{Status: resp.StatusCode}
+		var decoded struct {
+			Error struct {
+				Code    string `json:"code"`
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		if json.Unmarshal(raw, &decoded) == nil {
+			apiErr.Code = decoded.Error.Code
+			apiErr.Message = decoded.Error.Message
+		}
+		if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+			apiErr.RetryAfter = time.Duration(seconds) * time.Second
+		}
+		return apiErr
+	}
+	if out != nil && len(raw) > 0 {
+		if err := json.Unmarshal(raw, out); err != nil {
+			return fmt.Errorf("decoding %s %s response: %w", method, path, err)
+		}
+	}
+	return nil
+}
+
+// SolveChallenge finds a nonce such that SHA-256 of "token:email:nonce" has
+// difficulty leading zero bits. email must be normalized (trimmed,
+// lowercase).
+func SolveChallenge(token, email string, difficulty int) string {
+	for n := 0; ; n++ {
+		nonce := strconv.Itoa(n)
+		sum := sha256.Sum256([]byte(token + ":" + email + ":" + nonce))
+		if leadingZeroBits(sum[:]) >= difficulty {
+			return nonce
+		}
+	}
+}
+
+func leadingZeroBits(sum []byte) int {
+	n := 0
+	for _, b := range sum {
+		if b != 0 {
+			return n + bits.LeadingZeros8(b)
+		}
+		n += 8
+	}
+	return n
+}
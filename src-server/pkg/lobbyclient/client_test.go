@@ -0,0 +1,44 @@
+package lobbyclient
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestAPIErrorFromResponse(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("Authorization"); got != "Bearer token" {
+			t.Errorf("Authorization = %q, want bearer token", got)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Retry-After", "60")
+		w.WriteHeader(http.StatusTooManyRequests)
+		_, _ = w.Write([]byte(`{"error":{"code":"RATE_LIMITED","message":"slow down"}}`))
+	}))
+	defer server.Close()
+
+	client := New(server.URL + "/")
+	client.SetToken("token")
+	_, err := client.Me(context.Background())
+
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("Me() error = %v, want *APIError", err)
+	}
+	if apiErr.Status != http.StatusTooManyRequests || apiErr.Code != ErrCodeRateLimited || apiErr.Message != "slow down" || apiErr.RetryAfter != time.Minute {
+		t.Fatalf("APIError = %+v", apiErr)
+	}
+}
+
+func TestSolveChallenge(t *testing.T) {
+	nonce := SolveChallenge("token", "alice@example.com", 8)
+	sum := sha256.Sum256([]byte("token:alice@example.com:" + nonce))
+	if sum[0] != 0 {
+		t.Fatalf("SolveChallenge() nonce %q gives %x, want a leading zero byte", nonce, sum[:2])
+	}
+}
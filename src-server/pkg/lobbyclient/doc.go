@@ -0,0 +1,24 @@
+// Package lobbyclient is a Go client for Lobby servers.
+//
+// It holds the WebSocket wire types the server itself uses (see protocol.go),
+// a REST Client for sign-in and message history, and a Gateway for the
+// WebSocket session. Bots connect with a bot token:
+//
+//	client := lobbyclient.New("https://lobby.example.com")
+//	gw, err := client.Connect(ctx, botToken, lobbyclient.GatewayOptions{Bot: true})
+//	if err != nil {
+//		return err
+//	}
+//	defer gw.Close()
+//	for frame := range gw.Frames() {
+//		if frame.Type == lobbyclient.EventMessageCreate {
+//			var msg lobbyclient.MessageCreatePayload
+//			if err := frame.Decode(&msg); err == nil && msg.Content == "!ping" {
+//				_ = gw.Send(lobbyclient.CmdMessageSend, lobbyclient.MessageSendPayload{Content: "pong"})
+//			}
+//		}
+//	}
+//
+// REST payloads use camelCase JSON and WebSocket payloads snake_case, as on
+// the wire.
+package lobbyclient
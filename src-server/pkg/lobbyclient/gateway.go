@@ -0,0 +1,371 @@
+package lobbyclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gorilla/websocket"
+)
+
+// frameBuffer is how many received frames Frames buffers before the read
+// loop blocks.
+const frameBuffer = 64
+
+// Frame is a received websocket message with its payload left raw.
+type Frame struct {
+	Op   OpCode          `json:"op"`
+	Type string          `json:"t,omitempty"`
+	Data json.RawMessage `json:"d,omitempty"`
+	Seq  uint64          `json:"s,omitempty"`
+}
+
+// Decode unmarshals the frame's payload into target.
+func (f *Frame) Decode(target any) error {
+	return json.Unmarshal(f.Data, target)
+}
+
+// GatewayError is an ERROR the server sent in reply to IDENTIFY or a
+// REQUEST.
+type GatewayError struct {
+	ErrorPayload
+}
+
+func (e *GatewayError) Error() string {
+	return fmt.Sprintf("lobby gateway: %s: %s", e.Code, e.Message)
+}
+
+// ErrGatewayClosed is returned by Request when the connection closes first.
+var ErrGatewayClosed = errors.New("lobby gateway: connection closed")
+
+// GatewayOptions configure Connect.
+type GatewayOptions struct {
+	// Bot negotiates BotSubprotocol, which Request needs.
+	Bot bool
+	// Presence is the initial presence; nil means online.
+	Presence *PresenceOptions
+	// Header is sent with the upgrade request, e.g. an Origin.
+	Header http.Header
+	// Intents limits the events the server sends, e.g. IntentMessages;
+	// nil means all.
+	Intents []string
+	// Batch asks the server to coalesce bursts into BATCH frames. Frames
+	// still delivers them one by one.
+	Batch bool
+}
+
+// Gateway is an identified websocket session. Frames delivers everything the
+// server sends after READY except REQUEST responses and the ACK/NACK of
+// Command, which go to their callers.
+type Gateway struct {
+	conn   *websocket.Conn
+	frames chan *Frame
+	ready  ReadyPayload
+	opts   GatewayOptions
+
+	// Unread frames from the last BATCH (read goroutine only)
+	batched []*Frame
+
+	writeMu sync.Mutex
+
+	mu        sync.Mutex
+	pending   map[string]chan *requestResponse
+	commands  map[string]chan *NackPayload
+	nextReqID uint64
+	closed    bool
+	err       error
+	done      chan struct{}
+}
+
+type requestResponse struct {
+	RequestID string          `json:"request_id"`
+	Result    json.RawMessage `json:"result,omitempty"`
+	Error     *ErrorPayload   `json:"error,omitempty"`
+}
+
+// Connect dials the server's /ws endpoint and identifies with token, an
+// access token or a bot token, returning once READY arrives.
+func (c *Client) Connect(ctx context.Context, token string, opts GatewayOptions) (*Gateway, error) {
+	wsURL := "ws" + strings.TrimPrefix(c.baseURL, "http") + "/ws"
+	dialer := *websocket.DefaultDialer
+	if opts.Bot {
+		dialer.Subprotocols = []string{BotSubprotocol}
+	}
+	conn, _, err := dialer.DialContext(ctx, wsURL, opts.Header)
+	if err != nil {
+		return nil, fmt.Errorf("dialing gateway: %w", err)
+	}
+	if opts.Bot && conn.Subprotocol() != BotSubprotocol {
+		conn.Close()
+		return nil, fmt.Errorf("server did not accept the %s subprotocol", BotSubprotocol)
+	}
+
+	g := &Gateway{
+		conn:     conn,
+		opts:     opts,
+		frames:   make(chan *Frame, frameBuffer),
+		pending:  make(map[string]chan *requestResponse),
+		commands: make(map[string]chan *NackPayload),
+		done:     make(chan struct{}),
+	}
+	if err := g.identify(ctx, token, opts); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	go g.readLoop()
+	return g, nil
+}
+
+func (g *Gateway) identify(ctx context.Context, token string, opts GatewayOptions) error {
+	// Unblock the reads below if ctx ends first.
+	stop := context.AfterFunc(ctx, func() { g.conn.Close() })
+	defer stop()
+
+	for {
+		frame, err := g.read()
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return fmt.Errorf("waiting for READY: %w", err)
+		}
+		switch {
+		case frame.Op == OpHello:
+			identify := IdentifyPayload{Token: token, Presence: opts.Presence, Intents: opts.Intents, Capabilities: opts.capabilities()}
+			if err := g.Send(CmdIdentify, identify); err != nil {
+				return err
+			}
+		case frame.Op == OpReady:
+			if err := frame.Decode(&g.ready); err != nil {
+				return fmt.Errorf("decoding READY: %w", err)
+			}
+			return nil
+		case frame.Op == OpInvalidSession:
+			return &GatewayError{ErrorPayload{Code: ErrCodeAuthFailed, Message: "invalid session"}}
+		case frame.Op == OpDispatch && frame.Type == EventError:
+			var payload ErrorPayload
+			if err := frame.Decode(&payload); err != nil {
+				return fmt.Errorf("decoding ERROR: %w", err)
+			}
+			return &GatewayError{payload}
+		}
+	}
+}
+
+func (o GatewayOptions) capabilities() []string {
+	if o.Batch {
+		return []string{CapabilityBatch}
+	}
+	return nil
+}
+
+// Ready returns the READY payload received when the session was identified.
+func (g *Gateway) Ready() ReadyPayload {
+	return g.ready
+}
+
+// Frames returns the received frames. The channel is closed when the
+// connection ends; Err then reports why.
+func (g *Gateway) Frames() <-chan *Frame {
+	return g.frames
+}
+
+// Err returns the error that ended the connection, or nil while it is open
+// or after Close.
+func (g *Gateway) Err() error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.err
+}
+
+// Send writes a DISPATCH command, such as CmdMessageSend.
+func (g *Gateway) Send(command string, data any) error {
+	return g.write(WSMessage{Op: OpDispatch, Type: command, Data: data})
+}
+
+// Command writes a DISPATCH command with a correlation ID and waits for the
+// server to handle it. A NACK is returned as a *GatewayError.
+func (g *Gateway) Command(ctx context.Context, command string, data any) error {
+	g.mu.Lock()
+	if g.err != nil || g.closed {
+		g.mu.Unlock()
+		return ErrGatewayClosed
+	}
+	g.nextReqID++
+	id := strconv.FormatUint(g.nextReqID, 10)
+	reply := make(chan *NackPayload, 1)
+	g.commands[id] = reply
+	g.mu.Unlock()
+
+	defer func() {
+		g.mu.Lock()
+		delete(g.commands, id)
+		g.mu.Unlock()
+	}()
+
+	if err := g.write(WSMessage{Op: OpDispatch, Type: command, Data: data, ID: id}); err != nil {
+		return err
+	}
+
+	select {
+	case outcome := <-reply:
+		if outcome.Code != "" {
+			return &GatewayError{ErrorPayload{Code: outcome.Code, Message: outcome.Message, RetryAfter: outcome.RetryAfter, RateLimit: outcome.RateLimit}}
+		}
+		return nil
+	case <-g.done:
+		return ErrGatewayClosed
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Reidentify re-sends IDENTIFY with a refreshed token, e.g. after
+// EventAuthExpiring, keeping the session, its intents and capabilities.
+func (g *Gateway) Reidentify(token string) error {
+	return g.Send(CmdIdentify, IdentifyPayload{Token: token, Intents: g.opts.Intents, Capabilities: g.opts.capabilities()})
+}
+
+// History fetches text channel history over the gateway (bot sessions only).
+func (g *Gateway) History(ctx context.Context, before string, limit int) ([]MessageCreatePayload, error) {
+	var result HistoryResult
+	if err := g.request(ctx, ReqHistoryGet, func(id string) any {
+		return HistoryGetPayload{RequestID: id, Before: before, Limit: limit}
+	}, &result); err != nil {
+		return nil, err
+	}
+	return result.Messages, nil
+}
+
+// Members fetches members changed since the Seq of a previous result, or all
+// of them for since 0 (bot sessions only).
+func (g *Gateway) Members(ctx context.Context, since uint64) (*MembersResult, error) {
+	var result MembersResult
+	if err := g.request(ctx, ReqMembersGet, func(id string) any {
+		return MembersGetPayload{RequestID: id, Since: since}
+	}, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// Close closes the connection.
+func (g *Gateway) Close() error {
+	g.mu.Lock()
+	g.closed = true
+	g.mu.Unlock()
+	return g.conn.Close()
+}
+
+func (g *Gateway) request(ctx context.Context, requestType string, payload func(id string) any, out any) error {
+	g.mu.Lock()
+	if g.err != nil || g.closed {
+		g.mu.Unlock()
+		return ErrGatewayClosed
+	}
+	g.nextReqID++
+	id := strconv.FormatUint(g.nextReqID, 10)
+	reply := make(chan *requestResponse, 1)
+	g.pending[id] = reply
+	g.mu.Unlock()
+
+	defer func() {
+		g.mu.Lock()
+		delete(g.pending, id)
+		g.mu.Unlock()
+	}()
+
+	if err := g.write(WSMessage{Op: OpRequest, Type: requestType, Data: payload(id)}); err != nil {
+		return err
+	}
+
+	select {
+	case resp := <-reply:
+		if resp.Error != nil {
+			return &GatewayError{*resp.Error}
+		}
+		if err := json.Unmarshal(resp.Result, out); err != nil {
+			return fmt.Errorf("decoding %s result: %w", requestType, err)
+		}
+		return nil
+	case <-g.done:
+		return ErrGatewayClosed
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (g *Gateway) readLoop() {
+	defer close(g.frames)
+	defer close(g.done)
+
+	for {
+		frame, err := g.read()
+		if err != nil {
+			g.mu.Lock()
+			if !g.closed {
+				g.err = err
+			}
+			g.mu.Unlock()
+			return
+		}
+		if frame.Op == OpResponse {
+			var resp requestResponse
+			if err := frame.Decode(&resp); err != nil {
+				continue
+			}
+			g.mu.Lock()
+			reply, ok := g.pending[resp.RequestID]
+			g.mu.Unlock()
+			if ok {
+				reply <- &resp
+			}
+			continue
+		}
+		if frame.Op == OpAck || frame.Op == OpNack {
+			// An ACK decodes as a NACK without a code.
+			var outcome NackPayload
+			if err := frame.Decode(&outcome); err != nil {
+				continue
+			}
+			g.mu.Lock()
+			reply, ok := g.commands[outcome.ID]
+			g.mu.Unlock()
+			if ok {
+				reply <- &outcome
+			}
+			continue
+		}
+		g.frames <- frame
+	}
+}
+
+// read returns the next frame, unpacking BATCH frames.
+func (g *Gateway) read() (*Frame, error) {
+	for len(g.batched) == 0 {
+		var frame Frame
+		if err := g.conn.ReadJSON(&frame); err != nil {
+			return nil, err
+		}
+		if frame.Op != OpBatch {
+			return &frame, nil
+		}
+		if err := frame.Decode(&g.batched); err != nil {
+			return nil, fmt.Errorf("decoding BATCH: %w", err)
+		}
+	}
+	frame := g.batched[0]
+	g.batched = g.batched[1:]
+	return frame, nil
+}
+
+func (g *Gateway) write(msg WSMessage) error {
+	g.writeMu.Lock()
+	defer g.writeMu.Unlock()
+	return g.conn.WriteJSON(msg)
+}
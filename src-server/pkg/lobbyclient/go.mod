@@ -0,0 +1,5 @@
+module github.com/frisksitron/lobby/src-server/pkg/lobbyclient
+
+go 1.24.0
+
+require github.com/gorilla/websocket v1.5.3
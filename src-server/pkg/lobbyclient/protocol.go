@@ -0,0 +1,758 @@
+package lobbyclient
+
+import "time"
+
+// Operation codes for WebSocket messages
+type OpCode int
+
+// ProtocolVersion is the exact server/client WS protocol version.
+// Bump this only for breaking wire-contract changes.
+const ProtocolVersion = 1
+
+// BotSubprotocol is the Sec-WebSocket-Protocol value that enables REQUEST
+// frames, letting bots fetch history and member deltas without the REST API.
+const BotSubprotocol = "lobby.bot.v1"
+
+// Gateway encodings, picked with the encoding query parameter on /ws. Under
+// EncodingMsgpack every message is a binary MessagePack frame with the same
+// keys as the JSON form; timestamps stay RFC 3339 strings.
+const (
+	EncodingJSON    = "json"
+	EncodingMsgpack = "msgpack"
+)
+
+// Gateway intents, the event classes a client asks for in IDENTIFY. Events
+// outside every intent (ERROR, COMMAND_ACK, RESUMED, RTC signaling) are
+// always sent.
+const (
+	IntentMessages      = "messages"       // MESSAGE_CREATE, MESSAGES_PURGED
+	IntentTyping        = "typing"         // TYPING_START, TYPING_STOP
+	IntentPresence      = "presence"       // PRESENCE_UPDATE
+	IntentMembers       = "members"        // USER_JOINED, USER_LEFT, USER_UPDATE
+	IntentVoice         = "voice"          // VOICE_STATE_UPDATE
+	IntentVoiceSpeaking = "voice_speaking" // VOICE_SPEAKING
+	IntentScreenShare   = "screen_share"   // SCREEN_SHARE_UPDATE
+	IntentChannel       = "channel"        // CHANNEL_UPDATE
+	IntentServer        = "server"         // SERVER_UPDATE
+	IntentNotifications = "notifications"  // NOTIFICATION
+	IntentModeration    = "moderation"     // AUTOMOD_ALERT, MOD_ALERT
+	IntentDrafts        = "drafts"         // DRAFT_UPDATE
+	IntentVideo         = "video"          // VIDEO_STATE
+)
+
+const (
+	// DISPATCH - Events and commands with type field
+	OpDispatch OpCode = 0
+
+	// Lifecycle ops (Server -> Client)
+	OpHello          OpCode = 1 // Sent on connection
+	OpReady          OpCode = 2 // Sent after successful identify, contains initial state
+	OpInvalidSession OpCode = 3 // Session invalid, must re-identify
+
+	// Request/response ops (BotSubprotocol only)
+	OpRequest  OpCode = 4 // Client -> Server, d carries request_id
+	OpResponse OpCode = 5 // Server -> Client, echoes t and request_id
+
+	// Latency probes (either direction); the receiver answers HEARTBEAT
+	// with HEARTBEAT_ACK echoing ts
+	OpHeartbeat    OpCode = 6
+	OpHeartbeatAck OpCode = 7
+
+	// Command outcome (Server -> Client), sent for every DISPATCH command
+	// that carried an id
+	OpAck  OpCode = 8 // d is AckPayload
+	OpNack OpCode = 9 // d is NackPayload; replaces the ERROR the command caused
+
+	// Server -> Client with CapabilityBatch: d is an array of messages, each
+	// with its own op, t, d, and s, to handle in order
+	OpBatch OpCode = 10
+)
+
+// Capabilities a client can ask for in IDENTIFY. The server ignores unknown
+// ones and lists those it enabled in READY.
+const (
+	// CapabilityBatch lets the server coalesce messages that are already
+	// queued during a burst into one OpBatch frame.
+	CapabilityBatch = "batch"
+)
+
+// Event types (Server -> Client via DISPATCH)
+const (
+	EventPresenceUpdate    = "PRESENCE_UPDATE"
+	EventAuthExpiring      = "AUTH_EXPIRING"
+	EventMessageCreate     = "MESSAGE_CREATE"
+	EventTypingStart       = "TYPING_START"
+	EventTypingStop        = "TYPING_STOP"
+	EventUserUpdate        = "USER_UPDATE"
+	EventServerUpdate      = "SERVER_UPDATE"
+	EventVoiceStateUpdate  = "VOICE_STATE_UPDATE"
+	EventRtcReady          = "RTC_READY"
+	EventRtcOffer          = "RTC_OFFER"
+	EventRtcAnswer         = "RTC_ANSWER"
+	EventRtcIceCandidate   = "RTC_ICE_CANDIDATE"
+	EventVoiceSpeaking     = "VOICE_SPEAKING"
+	EventUserJoined        = "USER_JOINED"
+	EventUserLeft          = "USER_LEFT"
+	EventError             = "ERROR"
+	EventScreenShareUpdate = "SCREEN_SHARE_UPDATE"
+	EventChannelUpdate     = "CHANNEL_UPDATE"
+	EventCommandAck        = "COMMAND_ACK"
+	EventMessagesPurged    = "MESSAGES_PURGED"
+	EventNotification      = "NOTIFICATION"
+	EventAutomodAlert      = "AUTOMOD_ALERT"
+	EventModAlert          = "MOD_ALERT"
+	EventDraftUpdate       = "DRAFT_UPDATE"
+	EventResumed           = "RESUMED"
+	EventRateLimitStatus   = "RATE_LIMIT_STATUS"
+	EventVideoState        = "VIDEO_STATE"
+	EventRecordingState    = "RECORDING_STATE" // outside every intent, so everyone is told
+	EventVoiceStats        = "VOICE_STATS"     // sent only to the voice user it describes
+)
+
+// Command types (Client -> Server via DISPATCH)
+const (
+	CmdIdentify               = "IDENTIFY"
+	CmdPresenceSet            = "PRESENCE_SET"
+	CmdMessageSend            = "MESSAGE_SEND"
+	CmdTyping                 = "TYPING"
+	CmdTypingStop             = "TYPING_STOP"
+	CmdVoiceJoin              = "VOICE_JOIN"
+	CmdVoiceLeave             = "VOICE_LEAVE"
+	CmdRtcOffer               = "RTC_OFFER"
+	CmdRtcAnswer              = "RTC_ANSWER"
+	CmdRtcIceCandidate        = "RTC_ICE_CANDIDATE"
+	CmdVoiceStateSet          = "VOICE_STATE_SET"
+	CmdScreenShareStart       = "SCREEN_SHARE_START"
+	CmdScreenShareStop        = "SCREEN_SHARE_STOP"
+	CmdScreenShareSubscribe   = "SCREEN_SHARE_SUBSCRIBE"
+	CmdScreenShareUnsubscribe = "SCREEN_SHARE_UNSUBSCRIBE"
+	CmdVoiceRelayStart        = "VOICE_RELAY_START"
+	CmdResume                 = "RESUME"
+	CmdRateLimitStatus        = "RATE_LIMIT_STATUS"
+	CmdCameraStart            = "CAMERA_START"
+	CmdCameraStop             = "CAMERA_STOP"
+	CmdCameraSubscribe        = "CAMERA_SUBSCRIBE"
+	CmdCameraUnsubscribe      = "CAMERA_UNSUBSCRIBE"
+	CmdRecordingStart         = "RECORDING_START"
+	CmdRecordingStop          = "RECORDING_STOP"
+	CmdVoiceWhisperStart      = "VOICE_WHISPER_START"
+	CmdVoiceWhisperStop       = "VOICE_WHISPER_STOP"
+)
+
+// Per-connection command rate limit buckets, as reported in RateLimitStatus.
+const (
+	RateLimitMessageSend          = "message_send"
+	RateLimitVoiceJoin            = "voice_join"
+	RateLimitVoiceToggle          = "voice_toggle" // unmute/undeafen outside push-to-talk
+	RateLimitRTCSignaling         = "rtc_signaling"
+	RateLimitScreenShareSignaling = "screen_share_signaling" // screen share and camera commands
+)
+
+// Request types (Client -> Server via REQUEST)
+const (
+	ReqHistoryGet = "HISTORY_GET"
+	ReqMembersGet = "MEMBERS_GET"
+)
+
+// Error codes sent in ERROR payloads and REST error responses.
+const (
+	ErrCodeAuthFailed                   = "AUTH_FAILED"
+	ErrCodeAuthExpired                  = "AUTH_EXPIRED"
+	ErrCodeRateLimited                  = "RATE_LIMITED"
+	ErrCodeInvalidRequest               = "INVALID_REQUEST"
+	ErrCodePayloadTooLarge              = "PAYLOAD_TOO_LARGE"
+	ErrCodeNotFound                     = "NOT_FOUND"
+	ErrCodeConflict                     = "CONFLICT"
+	ErrCodeInternal                     = "INTERNAL_ERROR"
+	ErrCodeAttachmentInvalid            = "ATTACHMENT_INVALID"
+	ErrCodeForbidden                    = "FORBIDDEN"
+	ErrCodeBanned                       = "BANNED"
+	ErrCodeRegistrationClosed           = "REGISTRATION_CLOSED"
+	ErrCodeInviteInvalid                = "INVITE_INVALID"
+	ErrCodeTimeout                      = "TIMEOUT"
+	ErrCodeConnectionLimit              = "CONNECTION_LIMIT"
+	ErrCodeChallengeFailed              = "CHALLENGE_FAILED"
+	ErrCodeMessageTooLong               = "MESSAGE_TOO_LONG"
+	ErrCodeVoiceJoinCooldown            = "VOICE_JOIN_COOLDOWN"
+	ErrCodeVoiceStateCooldown           = "VOICE_STATE_COOLDOWN"
+	ErrCodeVoiceJoinFailed              = "VOICE_JOIN_FAILED"
+	ErrCodeVoiceNotInChannel            = "NOT_IN_VOICE"
+	ErrCodeVoiceStateInvalidTransition  = "VOICE_STATE_INVALID_TRANSITION"
+	ErrCodeVoiceNegotiationInvalidState = "VOICE_NEGOTIATION_INVALID_STATE"
+	ErrCodeVoiceNegotiationFailed       = "VOICE_NEGOTIATION_FAILED"
+	ErrCodeVoiceNegotiationTimeout      = "VOICE_NEGOTIATION_TIMEOUT"
+	ErrCodeSignalingRateLimited         = "SIGNALING_RATE_LIMITED"
+	ErrCodeScreenShareInUse             = "SCREEN_SHARE_IN_USE"
+	ErrCodeVideoCodecUnsupported        = "VIDEO_CODEC_UNSUPPORTED"
+	ErrCodeChannelArchived              = "CHANNEL_ARCHIVED"
+	ErrCodeAutomodBlocked               = "AUTOMOD_BLOCKED"
+	ErrCodeAutomodRemoved               = "AUTOMOD_REMOVED"
+)
+
+type WSMessage struct {
+	Op   OpCode      `json:"op"`
+	Type string      `json:"t,omitempty"` // Event/command type (only for DISPATCH)
+	Data interface{} `json:"d,omitempty"`
+	// Seq numbers the events fanned out to a session, starting at 1. Direct
+	// replies (ERROR, COMMAND_ACK, RTC signaling) carry none and are never
+	// replayed by RESUME.
+	Seq uint64 `json:"s,omitempty"`
+	// ID is an optional client-chosen correlation ID on a DISPATCH command.
+	// The server answers the command with ACK or NACK echoing it.
+	ID string `json:"id,omitempty"`
+}
+
+// Server -> Client payloads
+
+type HelloPayload struct{}
+
+type ReadyPayload struct {
+	ProtocolVersion int                    `json:"protocol_version"`
+	SessionID       string                 `json:"session_id"`
+	User            *ReadyUser             `json:"user"`
+	Members         []MemberState          `json:"members"`
+	Channel         *ChannelInfo           `json:"channel,omitempty"`
+	Capabilities    []string               `json:"capabilities,omitempty"` // Enabled from IDENTIFY
+	Recording       *RecordingStatePayload `json:"recording,omitempty"`    // set while voice is being recorded
+}
+
+type ReadyUser struct {
+	ID        string `json:"id"`
+	Username  string `json:"username"`
+	Email     string `json:"email,omitempty"`
+	AvatarURL string `json:"avatar_url,omitempty"`
+	// AvatarAnimatedURL is set for GIF and WebP avatars; AvatarURL is then
+	// their still first frame. The same goes for the fields below.
+	AvatarAnimatedURL string     `json:"avatar_animated_url,omitempty"`
+	Role              string     `json:"role"`
+	CreatedAt         time.Time  `json:"created_at"`
+	UpdatedAt         *time.Time `json:"updated_at,omitempty"`
+	Bot               bool       `json:"bot,omitempty"`
+}
+
+type MemberState struct {
+	ID              string    `json:"id"`
+	Username        string    `json:"username"`
+	Avatar          string    `json:"avatar_url,omitempty"`
+	AvatarAnimated  string    `json:"avatar_animated_url,omitempty"`
+	Status          string    `json:"status"` // online, idle, dnd, offline
+	StatusText      string    `json:"status_text,omitempty"`
+	StatusEmoji     string    `json:"status_emoji,omitempty"`
+	InVoice         bool      `json:"in_voice"`
+	Muted           bool      `json:"muted"`
+	Deafened        bool      `json:"deafened"`
+	ServerMuted     bool      `json:"server_muted"`
+	ServerDeafened  bool      `json:"server_deafened"`
+	PushToTalk      bool      `json:"push_to_talk"`
+	Relay           bool      `json:"relay,omitempty"`            // degraded: audio relayed over the websocket
+	PrioritySpeaker bool      `json:"priority_speaker,omitempty"` // as in VoiceStateUpdatePayload
+	Streaming       bool      `json:"streaming"`
+	Camera          bool      `json:"camera,omitempty"`
+	Role            string    `json:"role"`
+	CreatedAt       time.Time `json:"created_at"`
+	Bot             bool      `json:"bot,omitempty"`
+}
+
+// HeartbeatPayload is the d of HEARTBEAT and HEARTBEAT_ACK. Ts is the
+// sender's clock in Unix milliseconds, echoed unchanged in the ack. The
+// server's acks also carry its own clock as ServerTs.
+type HeartbeatPayload struct {
+	Ts       int64 `json:"ts"`
+	ServerTs int64 `json:"server_ts,omitempty"`
+}
+
+// InvalidSessionPayload sent when session is invalid
+type InvalidSessionPayload struct {
+	Resumable bool `json:"resumable"`
+}
+
+// MessageCreatePayload sent when a new message is created (via DISPATCH)
+type MessageCreatePayload struct {
+	ID          string              `json:"id"`
+	Author      *MessageAuthor      `json:"author"`
+	Content     string              `json:"content"`
+	Attachments []MessageAttachment `json:"attachments,omitempty"`
+	Embeds      []MessageEmbed      `json:"embeds,omitempty"`
+	CreatedAt   string              `json:"created_at"`
+	EditedAt    string              `json:"edited_at,omitempty"`
+	Nonce       string              `json:"nonce,omitempty"` // Echo back for optimistic updates
+}
+
+// MessageEmbed is an image a message links to, with the URL the server's
+// media proxy serves it from. Only sent while the proxy is enabled.
+type MessageEmbed struct {
+	URL      string `json:"url"`
+	ProxyURL string `json:"proxy_url"`
+}
+
+type MessageAttachment struct {
+	ID            string `json:"id"`
+	Name          string `json:"name"`
+	MimeType      string `json:"mime_type"`
+	Size          int64  `json:"size"`
+	URL           string `json:"url"`
+	PreviewURL    string `json:"preview_url,omitempty"`
+	PreviewWidth  int64  `json:"preview_width,omitempty"`
+	PreviewHeight int64  `json:"preview_height,omitempty"`
+	// Text attachments: the first lines and a syntax highlighting hint.
+	PreviewText     string `json:"preview_text,omitempty"`
+	PreviewLanguage string `json:"preview_language,omitempty"`
+	// Animated GIF and WebP attachments: the preview is the first frame.
+	PreviewFrameCount int64 `json:"preview_frame_count,omitempty"`
+	PreviewDurationMs int64 `json:"preview_duration_ms,omitempty"`
+	// ScanStatus is "clean" or "unscanned" when the server scans uploads.
+	ScanStatus string `json:"scan_status,omitempty"`
+	// Audio attachments: the length and a base64 waveform of up to 64 peaks
+	// from 0 to 255.
+	AudioDurationMs int64  `json:"audio_duration_ms,omitempty"`
+	AudioWaveform   string `json:"audio_waveform,omitempty"`
+}
+
+type MessageAuthor struct {
+	ID             string `json:"id"`
+	Username       string `json:"username,omitempty"`
+	Avatar         string `json:"avatar_url,omitempty"`
+	AvatarAnimated string `json:"avatar_animated_url,omitempty"`
+	Bot            bool   `json:"bot,omitempty"`
+}
+
+type PresenceUpdatePayload struct {
+	UserID      string `json:"user_id"`
+	Status      string `json:"status"`
+	StatusText  string `json:"status_text,omitempty"`
+	StatusEmoji string `json:"status_emoji,omitempty"`
+}
+
+type TypingStartPayload struct {
+	UserID    string `json:"user_id"`
+	Username  string `json:"username"`
+	Timestamp string `json:"timestamp"`
+}
+
+type TypingStopPayload struct {
+	UserID string `json:"user_id"`
+}
+
+type UserUpdatePayload struct {
+	ID             string `json:"id"`
+	Username       string `json:"username,omitempty"`
+	Avatar         string `json:"avatar_url,omitempty"`
+	AvatarAnimated string `json:"avatar_animated_url,omitempty"`
+}
+
+type ServerUpdatePayload struct {
+	Name    string `json:"name,omitempty"`
+	IconURL string `json:"icon_url,omitempty"`
+}
+
+// ChannelInfo describes the server's text channel metadata.
+type ChannelInfo struct {
+	Name        string `json:"name"`
+	Topic       string `json:"topic"`
+	Description string `json:"description"`
+	Private     bool   `json:"private"`
+	Archived    bool   `json:"archived"`
+	SlowMode    int64  `json:"slow_mode_seconds"` // per-user message cooldown; 0 disables
+}
+
+// ChannelUpdatePayload sent when text channel metadata is edited
+type ChannelUpdatePayload struct {
+	ChannelInfo
+	UpdatedBy string `json:"updated_by,omitempty"`
+}
+
+// Client -> Server payloads (via DISPATCH)
+
+// IdentifyPayload sent by client to authenticate
+type IdentifyPayload struct {
+	Token    string           `json:"token"`
+	Presence *PresenceOptions `json:"presence,omitempty"`
+	// IncludeArchived opts into receiving an archived channel in READY.
+	IncludeArchived bool `json:"include_archived,omitempty"`
+	// Intents limits the events sent to the session; empty means all.
+	Intents []string `json:"intents,omitempty"`
+	// Capabilities opts into optional protocol features, e.g.
+	// CapabilityBatch.
+	Capabilities []string `json:"capabilities,omitempty"`
+}
+
+// ResumePayload sent by a reconnecting client instead of IDENTIFY to pick up
+// the session from READY where it left off.
+type ResumePayload struct {
+	Token     string `json:"token"`
+	SessionID string `json:"session_id"`
+	Seq       uint64 `json:"seq"` // Last seq the client received
+}
+
+// ResumedPayload sent after the missed events have been replayed.
+type ResumedPayload struct {
+	SessionID string `json:"session_id"`
+	Replayed  int    `json:"replayed"`
+}
+
+// PresenceOptions for initial presence on IDENTIFY
+type PresenceOptions struct {
+	Status      string `json:"status"` // online, idle, dnd (not offline)
+	StatusText  string `json:"status_text,omitempty"`
+	StatusEmoji string `json:"status_emoji,omitempty"`
+}
+
+// MessageSendPayload sent by client to send a message
+type MessageSendPayload struct {
+	Content       string   `json:"content"`
+	AttachmentIDs []string `json:"attachment_ids,omitempty"`
+	Nonce         string   `json:"nonce,omitempty"` // Client-generated ID for tracking
+}
+
+// PresenceSetPayload sent by client to set presence
+type PresenceSetPayload struct {
+	Status string `json:"status"` // online, idle, dnd, offline
+	// Custom status; nil keeps the current value and "" clears it
+	StatusText  *string `json:"status_text,omitempty"`
+	StatusEmoji *string `json:"status_emoji,omitempty"`
+	Nonce       string  `json:"nonce,omitempty"` // Echoed in COMMAND_ACK / ERROR
+}
+
+// VoiceStateUpdatePayload sent when a user's voice state changes (via DISPATCH)
+// Muted and Deafened are the user's own toggles; the Server* flags are set by
+// moderators and cannot be cleared by the user.
+type VoiceStateUpdatePayload struct {
+	UserID          string `json:"user_id"`
+	InVoice         bool   `json:"in_voice"`
+	Muted           bool   `json:"muted"`
+	Deafened        bool   `json:"deafened"`
+	ServerMuted     bool   `json:"server_muted"`
+	ServerDeafened  bool   `json:"server_deafened"`
+	PushToTalk      bool   `json:"push_to_talk"`
+	Relay           bool   `json:"relay,omitempty"`            // degraded: audio relayed over the websocket
+	PrioritySpeaker bool   `json:"priority_speaker,omitempty"` // clients lower other voice audio while this user speaks
+	// WhisperTo is set while the user's audio only reaches these users
+	WhisperTo []string `json:"whisper_to,omitempty"`
+}
+
+// VoiceJoinPayload sent by client to join voice
+type VoiceJoinPayload struct {
+	Muted       bool     `json:"muted"`
+	Deafened    bool     `json:"deafened"`
+	Nonce       string   `json:"nonce,omitempty"`        // Retries with the same nonce are not applied twice
+	VideoCodecs []string `json:"video_codecs,omitempty"` // MIME types it can send and decode, preferred first; empty means video/VP9 only
+}
+
+// RTC Payload types
+
+// RtcReadyPayload sent when client joins voice and should start WebRTC
+type RtcReadyPayload struct {
+	ICEServers []ICEServerInfo `json:"ice_servers"`
+	// External is set when the server hands voice to an external SFU. The
+	// client connects there instead, and no RTC_OFFER follows.
+	External *ExternalSFUInfo `json:"external,omitempty"`
+	// Node is the instance serving the user's voice media, when clustered.
+	// It is always the instance the websocket is connected to.
+	Node *VoiceNodeInfo `json:"node,omitempty"`
+}
+
+// VoiceNodeInfo identifies the instance whose SFU carries a user's voice.
+// Media uses the same host as the websocket; there are no separate media
+// endpoints to connect to.
+type VoiceNodeInfo struct {
+	ID     string `json:"id"`
+	Region string `json:"region,omitempty"`
+}
+
+// ExternalSFUInfo is how to join voice on an external SFU.
+type ExternalSFUInfo struct {
+	Provider string `json:"provider"` // "livekit"
+	URL      string `json:"url"`
+	Token    string `json:"token"`
+	Room     string `json:"room"`
+}
+
+// ICEServerInfo for client configuration
+type ICEServerInfo struct {
+	URLs       []string `json:"urls"`
+	Username   string   `json:"username,omitempty"`
+	Credential string   `json:"credential,omitempty"`
+}
+
+// RtcOfferPayload contains SDP offer
+type RtcOfferPayload struct {
+	SDP string `json:"sdp"`
+}
+
+// RtcAnswerPayload contains SDP answer
+type RtcAnswerPayload struct {
+	SDP string `json:"sdp"`
+}
+
+// RtcIceCandidatePayload contains ICE candidate
+type RtcIceCandidatePayload struct {
+	Candidate     string  `json:"candidate"`
+	SDPMid        *string `json:"sdp_mid,omitempty"`
+	SDPMLineIndex *uint16 `json:"sdp_mline_index,omitempty"`
+}
+
+// VoiceStateSetPayload for mute/deafen/speaking changes
+type VoiceStateSetPayload struct {
+	Muted      *bool  `json:"muted,omitempty"`
+	Deafened   *bool  `json:"deafened,omitempty"`
+	Speaking   *bool  `json:"speaking,omitempty"`
+	PushToTalk *bool  `json:"push_to_talk,omitempty"` // Exempts unmutes from the toggle cooldown
+	Nonce      string `json:"nonce,omitempty"`        // Echoed in COMMAND_ACK / ERROR
+}
+
+// UserJoinedPayload sent when server membership is created or restored.
+type UserJoinedPayload struct {
+	Member MemberState `json:"member"`
+}
+
+// UserLeftPayload sent when a user leaves the server (account deactivated)
+type UserLeftPayload struct {
+	UserID string `json:"user_id"`
+}
+
+// MessagesPurgedPayload sent when a moderator deletes all of a user's messages
+type MessagesPurgedPayload struct {
+	AuthorID string `json:"author_id"`
+}
+
+// DraftUpdatePayload sent to the draft owner's connections when a draft is
+// saved or cleared (empty content)
+type DraftUpdatePayload struct {
+	ChannelID int64  `json:"channel_id"`
+	Content   string `json:"content"`
+	UpdatedAt string `json:"updated_at"` // RFC3339Nano
+}
+
+// AutomodAlertPayload sent to moderators when a message trips an automod rule
+type AutomodAlertPayload struct {
+	RuleID    string         `json:"rule_id"`
+	Action    string         `json:"action"`
+	Matched   string         `json:"matched"`
+	MessageID string         `json:"message_id,omitempty"` // set when the message was delivered (flag)
+	Author    *MessageAuthor `json:"author"`
+	Content   string         `json:"content"`
+}
+
+// ModAlertPayload sent to moderators when a user reports a message or user
+type ModAlertPayload struct {
+	Kind        string `json:"kind"` // "report"
+	ReportID    string `json:"report_id"`
+	ReporterID  string `json:"reporter_id"`
+	TargetType  string `json:"target_type"` // "message" or "user"
+	TargetID    string `json:"target_id"`
+	Reason      string `json:"reason"`
+	ReportCount int64  `json:"report_count"` // open reports on the target, including this one
+}
+
+// NotificationPayload sent to a single user when a message mentions them or
+// matches one of their notification rules
+type NotificationPayload struct {
+	RuleID    string               `json:"rule_id,omitempty"` // matching rule, unless the user was mentioned
+	ChannelID int64                `json:"channel_id"`
+	Keyword   string               `json:"keyword,omitempty"` // matched keyword, if the rule has one
+	Mention   string               `json:"mention,omitempty"` // user, role, everyone, or here
+	Message   MessageCreatePayload `json:"message"`
+}
+
+// VoiceSpeakingPayload broadcast when speaking state changes
+type VoiceSpeakingPayload struct {
+	UserID   string `json:"user_id"`
+	Speaking bool   `json:"speaking"`
+}
+
+// ErrorPayload sent when the server rejects a client action
+type ErrorPayload struct {
+	Code       string           `json:"code"`
+	Message    string           `json:"message"`
+	Nonce      string           `json:"nonce,omitempty"`
+	RetryAfter int64            `json:"retry_after,omitempty"` // Unix ms timestamp
+	RateLimit  *RateLimitStatus `json:"rate_limit,omitempty"`  // The bucket that rejected the command
+	// Attachments lists every attachment that kept a message from sending.
+	Attachments []AttachmentViolation `json:"attachments,omitempty"`
+}
+
+// Reasons an attachment is refused
+const (
+	AttachmentTooMany     = "too_many"    // past the per-message count; Limit is the count
+	AttachmentTooLarge    = "too_large"   // Limit is the byte cap for its kind
+	AttachmentUnavailable = "unavailable" // unknown, expired, infected or already sent
+)
+
+// AttachmentViolation names one attachment and the limit it broke. It is
+// shared by MESSAGE_SEND errors, which identify attachments by ID, and REST
+// upload errors, which give the file name and kind.
+type AttachmentViolation struct {
+	ID     string `json:"id,omitempty"`
+	Name   string `json:"name,omitempty"`
+	Kind   string `json:"kind,omitempty"`
+	Reason string `json:"reason"`
+	Limit  int64  `json:"limit,omitempty"`
+	Size   int64  `json:"size,omitempty"`
+}
+
+// RateLimitStatus is the state of one per-connection command budget: Limit
+// commands per Window, and with a Cooldown, reaching Limit blocks the bucket
+// for that long. ResetAt is when the oldest counted command leaves the window
+// or the cooldown ends.
+type RateLimitStatus struct {
+	Bucket    string `json:"bucket"`
+	Limit     int    `json:"limit"`
+	Window    int64  `json:"window_ms"`
+	Cooldown  int64  `json:"cooldown_ms,omitempty"`
+	Remaining int    `json:"remaining"`
+	ResetAt   int64  `json:"reset_at,omitempty"` // Unix ms timestamp
+}
+
+// RateLimitStatusPayload answers CmdRateLimitStatus with every built-in
+// bucket.
+type RateLimitStatusPayload struct {
+	Buckets []RateLimitStatus `json:"buckets"`
+}
+
+// CommandAckPayload confirms a state-changing command was applied.
+// Only sent when the command carried a nonce and no id; commands with an id
+// get OpAck instead, which new clients should use.
+type CommandAckPayload struct {
+	Command string `json:"command"`
+	Nonce   string `json:"nonce"`
+}
+
+// AuthExpiringPayload warns that the session's token expires at ExpiresAt
+// (Unix ms). Re-IDENTIFY with a refreshed token before then to stay
+// connected.
+type AuthExpiringPayload struct {
+	ExpiresAt int64 `json:"expires_at"`
+}
+
+// AckPayload reports that a DISPATCH command with an id was handled without
+// error.
+type AckPayload struct {
+	ID      string `json:"id"`
+	Command string `json:"command"`
+}
+
+// NackPayload reports that a DISPATCH command with an id failed. Code and
+// Message are those of the ERROR it would otherwise have caused.
+type NackPayload struct {
+	ID         string           `json:"id"`
+	Command    string           `json:"command"`
+	Code       string           `json:"code"`
+	Message    string           `json:"message"`
+	RetryAfter int64            `json:"retry_after,omitempty"` // Unix ms timestamp
+	RateLimit  *RateLimitStatus `json:"rate_limit,omitempty"`
+}
+
+// ResponsePayload answers a REQUEST frame. Exactly one of Result and Error is set.
+type ResponsePayload struct {
+	RequestID string        `json:"request_id"`
+	Result    interface{}   `json:"result,omitempty"`
+	Error     *ErrorPayload `json:"error,omitempty"`
+}
+
+// HistoryGetPayload requests text channel history, newest first
+type HistoryGetPayload struct {
+	RequestID string `json:"request_id"`
+	Before    string `json:"before,omitempty"` // Message ID cursor
+	Limit     int    `json:"limit,omitempty"`  // Defaults to 50
+}
+
+type HistoryResult struct {
+	Messages []MessageCreatePayload `json:"messages"`
+}
+
+// MembersGetPayload requests members changed since a previous MembersResult.Seq.
+// Since 0 (or a cursor that is too old) returns a full snapshot.
+type MembersGetPayload struct {
+	RequestID string `json:"request_id"`
+	Since     uint64 `json:"since,omitempty"`
+}
+
+type MembersResult struct {
+	Seq     uint64        `json:"seq"`
+	Full    bool          `json:"full"`
+	Members []MemberState `json:"members"`
+	Removed []string      `json:"removed,omitempty"` // Deactivated users
+}
+
+// ScreenShareUpdatePayload sent when a user's screen share state changes
+type ScreenShareUpdatePayload struct {
+	UserID    string `json:"user_id"`
+	Streaming bool   `json:"streaming"`
+	Layout    string `json:"layout,omitempty"` // LayoutSpotlight while streaming
+}
+
+// ScreenShareSubscribePayload sent by client to subscribe to a stream
+type ScreenShareSubscribePayload struct {
+	StreamerID     string `json:"streamer_id"`
+	MaxBitrateKbps uint32 `json:"max_bitrate_kbps,omitempty"` // cap on what this viewer is sent; sfu.maxViewerBitrateKbps still applies
+}
+
+// Video sources and the layout hints sent with them. Clients show spotlight
+// video large, one at a time, and grid video tiled alongside the voice
+// participants.
+const (
+	VideoSourceCamera = "camera"
+
+	LayoutGrid      = "grid"
+	LayoutSpotlight = "spotlight"
+)
+
+// VideoStatePayload is sent when a user's camera goes live, changes size,
+// or stops. The camera arrives on subscribed peers as a video track with id
+// "camera" in the publisher's stream.
+type VideoStatePayload struct {
+	UserID  string `json:"user_id"`
+	Source  string `json:"source"` // VideoSourceCamera
+	Enabled bool   `json:"enabled"`
+	Width   int    `json:"width,omitempty"`
+	Height  int    `json:"height,omitempty"`
+	Layout  string `json:"layout,omitempty"` // LayoutGrid
+}
+
+// CameraStartPayload is the d of CAMERA_START. Width and height are the
+// captured size, passed on to viewers in VIDEO_STATE.
+type CameraStartPayload struct {
+	Width  int `json:"width,omitempty"`
+	Height int `json:"height,omitempty"`
+}
+
+// VoiceWhisperStartPayload is the d of VOICE_WHISPER_START. Recipients must
+// be in voice; the whisper lasts until VOICE_WHISPER_STOP or leaving voice.
+type VoiceWhisperStartPayload struct {
+	RecipientIDs []string `json:"recipient_ids"`
+}
+
+// CameraSubscribePayload is the d of CAMERA_SUBSCRIBE and
+// CAMERA_UNSUBSCRIBE.
+type CameraSubscribePayload struct {
+	PublisherID string `json:"publisher_id"`
+}
+
+// Voice connection quality in VoiceStatsPayload.
+const (
+	VoiceQualityGood = "good"
+	VoiceQualityFair = "fair"
+	VoiceQualityPoor = "poor"
+)
+
+// VoiceStatsPayload is sent to each voice user every few seconds with how
+// well their connection to the SFU is doing. Loss is a fraction from 0 to
+// 1, upload and download are from the user's side.
+type VoiceStatsPayload struct {
+	RTTMs        int64   `json:"rtt_ms"`
+	JitterMs     int64   `json:"jitter_ms"`
+	UplinkLoss   float64 `json:"uplink_loss"`
+	DownlinkLoss float64 `json:"downlink_loss"`
+	UploadKbps   uint64  `json:"upload_kbps"`
+	DownloadKbps uint64  `json:"download_kbps"`
+	Quality      string  `json:"quality"` // VoiceQualityGood, VoiceQualityFair or VoiceQualityPoor
+}
+
+// RecordingStatePayload is sent to everyone when a voice recording starts
+// or stops, so clients can tell voice participants they are being recorded.
+type RecordingStatePayload struct {
+	Active      bool       `json:"active"`
+	RecordingID string     `json:"recording_id"`
+	StartedBy   string     `json:"started_by,omitempty"`
+	StartedAt   *time.Time `json:"started_at,omitempty"`
+}